@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	repocontext "github.com/miltonparedes/lazywork/internal/context"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/ignore"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/redact"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/provider"
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// noRedact is set by --no-redact on the commands that send repository
+// content to a provider
+var noRedact bool
+
+// newAIRequest returns the provider named providerName and a request for
+// modelID with the settings resolved by provider.NewRequest. When neither
+// is given the models setting for command picks them; otherwise the
+// provider defaults to default_provider and the model to default_model,
+// then the provider's first model. The provider records its token usage
+// under command, and the monthly budget is checked first. Secrets in the
+// messages are masked unless --no-redact is set.
+func newAIRequest(out *output.Output, cfg *config.Config, command, providerName, modelID string, messages []types.Message) (types.Provider, types.CompletionRequest, error) {
+	if providerName == "" && modelID == "" {
+		providerName, modelID = cfg.CommandModel(command)
+	}
+	if providerName == "" {
+		providerName = cfg.DefaultProvider
+	}
+	if err := checkBudget(out, cfg); err != nil {
+		return nil, types.CompletionRequest{}, err
+	}
+	messages, err := redactMessages(out, cfg, messages)
+	if err != nil {
+		return nil, types.CompletionRequest{}, err
+	}
+	p, err := provider.NewFromConfig(cfg, providerName)
+	if err != nil {
+		return nil, types.CompletionRequest{}, lazyerr.Wrap(lazyerr.InvalidProvider, err).WithDetail("provider", providerName)
+	}
+	p = &trackedProvider{Provider: p, name: providerName, command: command}
+
+	if modelID == "" && providerName == cfg.DefaultProvider {
+		modelID = cfg.DefaultModel
+	}
+	if modelID == "" && len(p.Models()) > 0 {
+		modelID = p.Models()[0]
+	}
+	if modelID == "" {
+		return nil, types.CompletionRequest{}, lazyerr.New(lazyerr.InvalidProvider, "no model configured for provider '%s'", providerName).
+			WithDetail("provider", providerName)
+	}
+
+	req := provider.NewRequest(cfg, providerName, modelID, command, messages)
+	return p, req, nil
+}
+
+// newEmbedder returns the provider named providerName (default: the
+// default_provider) if it can compute embeddings. The monthly budget is
+// checked first.
+func newEmbedder(out *output.Output, cfg *config.Config, providerName string) (types.Embedder, error) {
+	if providerName == "" {
+		providerName = cfg.DefaultProvider
+	}
+	if err := checkBudget(out, cfg); err != nil {
+		return nil, err
+	}
+	p, err := provider.NewFromConfig(cfg, providerName)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.InvalidProvider, err).WithDetail("provider", providerName)
+	}
+	e, ok := types.AsEmbedder(p)
+	if !ok {
+		return nil, lazyerr.New(lazyerr.InvalidProvider, "provider '%s' doesn't support embeddings", providerName).
+			WithDetail("provider", providerName)
+	}
+	return e, nil
+}
+
+// withRepoContext adds the context of the current repository (its file
+// tree, recent commits and README) to the first user message, so the
+// model picks up the project's terminology. Nothing is added outside a
+// repository or with context.disabled.
+func withRepoContext(ctx context.Context, cfg *config.Config, messages []types.Message) []types.Message {
+	tokens := cfg.Context.GetMaxTokens()
+	if tokens == 0 {
+		return messages
+	}
+	root, err := git.GetRepoRoot(ctx)
+	if err != nil {
+		return messages
+	}
+	text := repocontext.Collect(ctx, root).Render(tokens)
+
+	messages = slices.Clone(messages)
+	for i, m := range messages {
+		if m.Role == "user" {
+			messages[i].Content = text + "\n" + m.Content
+			break
+		}
+	}
+	return messages
+}
+
+// maxIgnoredNames bounds the ignored files named in a prompt
+const maxIgnoredNames = 20
+
+// ignoreFiles drops the files matched by the repository's .lazyworkignore
+// from diff, naming them first so the model still knows they changed
+func ignoreFiles(ctx context.Context, out *output.Output, diff string) string {
+	root, err := git.GetRepoRoot(ctx)
+	if err != nil {
+		return diff
+	}
+	m, err := ignore.Load(root)
+	if err != nil {
+		out.Warning(err.Error())
+		return diff
+	}
+	filtered, removed := m.FilterDiff(diff)
+	if len(removed) == 0 {
+		return diff
+	}
+
+	names := strings.Join(removed, ", ")
+	if len(removed) > maxIgnoredNames {
+		names = fmt.Sprintf("%s and %d more", strings.Join(removed[:maxIgnoredNames], ", "), len(removed)-maxIgnoredNames)
+	}
+	return fmt.Sprintf("Changes to these files are not shown (%s): %s\n\n", ignore.File, names) + filtered
+}
+
+// redactTexts masks secrets in texts like redactMessages
+func redactTexts(out *output.Output, cfg *config.Config, texts []string) ([]string, error) {
+	messages := make([]types.Message, len(texts))
+	for i, t := range texts {
+		messages[i] = types.Message{Role: "user", Content: t}
+	}
+	messages, err := redactMessages(out, cfg, messages)
+	if err != nil {
+		return nil, err
+	}
+	redacted := make([]string, len(messages))
+	for i, m := range messages {
+		redacted[i] = m.Content
+	}
+	return redacted, nil
+}
+
+// redactMessages masks secrets in everything but the system prompt and
+// says what was masked
+func redactMessages(out *output.Output, cfg *config.Config, messages []types.Message) ([]types.Message, error) {
+	if noRedact {
+		return messages, nil
+	}
+	r, err := redact.New(cfg.Redact.Patterns)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.ConfigInvalid, err)
+	}
+
+	var findings []redact.Finding
+	redacted := make([]types.Message, len(messages))
+	for i, m := range messages {
+		redacted[i] = m
+		if m.Role == "system" {
+			continue
+		}
+		var found []redact.Finding
+		redacted[i].Content, found = r.Redact(m.Content)
+		findings = append(findings, found...)
+	}
+	if n := redact.Total(findings); n > 0 {
+		out.Warning(fmt.Sprintf("Masked %d possible secret(s) before sending: %s (use --no-redact to send them)", n, redact.Summary(findings)))
+	}
+	return redacted, nil
+}
+
+// checkBudget warns, or refuses with budget.action block, once this
+// month's estimated spend reaches budget.monthly
+func checkBudget(out *output.Output, cfg *config.Config) error {
+	if cfg.Budget.Monthly <= 0 {
+		return nil
+	}
+	usage, err := state.LoadUsage(state.UserDir())
+	if err != nil {
+		return nil
+	}
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	spent, _ := usageCost(cfg, usage.Since(monthStart))
+	if spent < cfg.Budget.Monthly {
+		return nil
+	}
+
+	msg := fmt.Sprintf("monthly AI budget of $%.2f reached ($%.2f spent)", cfg.Budget.Monthly, spent)
+	if cfg.Budget.Blocks() {
+		return lazyerr.New(lazyerr.BudgetExceeded, "%s", msg).
+			WithDetail("budget", cfg.Budget.Monthly).
+			WithDetail("spent", spent)
+	}
+	out.Warning(msg)
+	return nil
+}
+
+// trackedProvider records the token usage of each request in the usage
+// log and its output in the repository's generation log. Failing to
+// record is not an error for the request.
+type trackedProvider struct {
+	types.Provider
+	name    string
+	command string
+	// noLog keeps generations out of the repository's generation log
+	noLog bool
+}
+
+// skipGenerationLog keeps the generations of p, a provider from
+// newAIRequest, out of the repository's generation log, for output that
+// isn't about the repository
+func skipGenerationLog(p types.Provider) {
+	if t, ok := p.(*trackedProvider); ok {
+		t.noLog = true
+	}
+}
+
+func (p *trackedProvider) Complete(ctx context.Context, req types.CompletionRequest) (*types.CompletionResponse, error) {
+	resp, err := p.Provider.Complete(ctx, req)
+	if err == nil {
+		p.record(req.Model, resp.Usage)
+		p.remember(ctx, req, resp.Content)
+	}
+	return resp, err
+}
+
+func (p *trackedProvider) Stream(ctx context.Context, req types.CompletionRequest) (<-chan types.StreamChunk, error) {
+	upstream, err := p.Provider.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make(chan types.StreamChunk)
+	go func() {
+		defer close(chunks)
+		var content strings.Builder
+		for c := range upstream {
+			content.WriteString(c.Content)
+			if c.Usage != nil {
+				p.record(req.Model, *c.Usage)
+			}
+			if c.Done && c.Error == nil {
+				p.remember(ctx, req, content.String())
+			}
+			select {
+			case chunks <- c:
+			case <-ctx.Done():
+				types.Drain(upstream)
+				return
+			}
+		}
+	}()
+	return chunks, nil
+}
+
+// Unwrap returns the provider being tracked, so optional capabilities such
+// as types.Embedder can be found
+func (p *trackedProvider) Unwrap() types.Provider {
+	return p.Provider
+}
+
+func (p *trackedProvider) record(model string, usage types.Usage) {
+	state.UpdateUsage(state.UserDir(), func(u *state.UsageLog) {
+		u.Add(time.Now(), p.name, model, p.command, usage.PromptTokens, usage.CompletionTokens)
+	})
+}
+
+// remember saves output in the generation log of the current repository,
+// if there is one, and adds it to the known repositories
+func (p *trackedProvider) remember(ctx context.Context, req types.CompletionRequest, output string) {
+	if p.noLog {
+		return
+	}
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return
+	}
+	state.RecordRepo(state.UserDir(), commonDir, time.Now())
+	state.UpdateGenerations(state.Dir(commonDir), func(g *state.GenerationLog) {
+		g.Add(state.Generation{
+			Time:       time.Now(),
+			Command:    p.command,
+			Provider:   p.name,
+			Model:      req.Model,
+			PromptHash: promptHash(req.Messages),
+			Output:     output,
+		})
+	})
+}
+
+// promptHash identifies the messages of a request without storing them
+func promptHash(messages []types.Message) string {
+	h := sha256.New()
+	for _, m := range messages {
+		fmt.Fprintf(h, "%s\x00%s\x00", m.Role, m.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// usageCost estimates the spend of records from the model prices in cfg.
+// complete is false if some records are for models without a price.
+func usageCost(cfg *config.Config, records []state.UsageRecord) (cost float64, complete bool) {
+	complete = true
+	for _, r := range records {
+		model, ok := findModel(cfg, r.Provider, r.Model)
+		if !ok || (model.InputPrice == 0 && model.OutputPrice == 0) {
+			// Mock and local Ollama models are free
+			if t := cfg.Providers[r.Provider].Type; r.PromptTokens+r.CompletionTokens > 0 && t != "mock" && t != "ollama" {
+				complete = false
+			}
+			continue
+		}
+		cost += (float64(r.PromptTokens)*model.InputPrice + float64(r.CompletionTokens)*model.OutputPrice) / 1e6
+	}
+	return cost, complete
+}
+
+func findModel(cfg *config.Config, providerName, modelID string) (config.Model, bool) {
+	for _, m := range cfg.Providers[providerName].Models {
+		if m.ID == modelID {
+			return m, true
+		}
+	}
+	return config.Model{}, false
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/archive"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/paths"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/miltonparedes/lazywork/pkg/worktree"
+	"github.com/spf13/cobra"
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "List and restore archived worktrees",
+	Long: `List and restore the worktrees archived by 'worktree remove --archive'.
+
+An archive keeps what removing a worktree loses: the commits not merged into
+the main branch (as a git bundle), the uncommitted changes (as a patch) and
+the untracked files, leaving ignored ones out. Archives are kept under
+~/.local/share/lazywork/archive ($XDG_DATA_HOME/lazywork/archive if set,
+%LOCALAPPDATA%\lazywork\archive on Windows).`,
+}
+
+var archiveListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List archived worktrees of this repository",
+	Args:  cobra.NoArgs,
+	RunE:  runArchiveList,
+}
+
+var archiveRestoreCmd = &cobra.Command{
+	Use:   "restore [id]",
+	Short: "Recreate an archived worktree",
+	Long: `Recreate an archived worktree: its branch is checked out again (and
+recreated from the archived commits if it was deleted), then the
+uncommitted changes and untracked files are put back. The archive is
+deleted once restored, unless --keep is given.
+
+Without an id you'll be prompted to pick an archive interactively.
+
+Example:
+  lazywork archive list
+  lazywork archive restore feature-auth-20260102-150405`,
+	Args:              cobra.MaximumNArgs(1),
+	RunE:              runArchiveRestore,
+	ValidArgsFunction: completeArchives,
+}
+
+var (
+	archiveListAll bool
+	archiveKeep    bool
+)
+
+func init() {
+	rootCmd.AddCommand(archiveCmd)
+	archiveCmd.AddCommand(archiveListCmd)
+	archiveCmd.AddCommand(archiveRestoreCmd)
+
+	archiveListCmd.Flags().BoolVarP(&archiveListAll, "all", "a", false, "List the archives of every repository")
+	archiveRestoreCmd.Flags().BoolVar(&archiveKeep, "keep", false, "Keep the archive after restoring it")
+}
+
+func runArchiveList(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	var archives []*archive.Archive
+	var err error
+	switch {
+	case archiveListAll:
+		if archives, err = archive.List(archive.Dir()); err != nil {
+			return lazyerr.Wrap(lazyerr.ArchiveError, err)
+		}
+	case !git.IsInsideWorkTree(ctx):
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository").
+			WithHint("Run it from inside a repository, or list every archive with --all")
+	default:
+		if archives, err = repoArchives(cmd); err != nil {
+			return err
+		}
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.ArchiveList{Archives: archives, Count: len(archives)})
+	}
+
+	if len(archives) == 0 {
+		out.Info("No archived worktrees")
+		return nil
+	}
+	rows := make([][]string, 0, len(archives))
+	for _, a := range archives {
+		branch := a.Branch
+		if branch == "" {
+			branch = "(detached)"
+		}
+		rows = append(rows, []string{
+			a.ID,
+			branch,
+			archiveContents(a),
+			a.Created.Local().Format("2006-01-02 15:04"),
+		})
+	}
+	out.Table([]string{"ID", "BRANCH", "KEPT", "ARCHIVED"}, rows)
+	return nil
+}
+
+func runArchiveRestore(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	var id string
+	switch {
+	case len(args) > 0:
+		id = args[0]
+	case out.IsTTY():
+		archives, err := repoArchives(cmd)
+		if err != nil {
+			return err
+		}
+		if len(archives) == 0 {
+			return lazyerr.New(lazyerr.ArchiveNotFound, "no archived worktrees in this repository")
+		}
+		ids := make([]string, 0, len(archives))
+		for _, a := range archives {
+			ids = append(ids, a.ID)
+		}
+		if err := tui.SelectForm("Restore archive", ids, &id).Run(); err != nil {
+			return err
+		}
+	default:
+		return lazyerr.New(lazyerr.NameRequired, "archive id required")
+	}
+
+	a, err := archive.Load(archive.Dir(), id)
+	if errors.Is(err, archive.ErrNotFound) {
+		return lazyerr.New(lazyerr.ArchiveNotFound, "archive '%s' not found", id).WithDetail("id", id)
+	}
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ArchiveError, err)
+	}
+	if commonDir, _ := git.GetCommonDir(ctx); !paths.Same(runtime.GOOS, commonDir, a.Repo) {
+		return lazyerr.New(lazyerr.ArchiveError, "archive '%s' belongs to another repository", id).
+			WithDetail("repo", a.Repo).
+			WithHint("Restore it from inside " + a.Repo)
+	}
+
+	branch, err := restoreBranch(cmd, a)
+	if err != nil {
+		return err
+	}
+	added, err := newManager(cmd, out, cfg).Add(ctx, worktree.AddOptions{Name: a.Name, Branch: branch})
+	if err != nil {
+		return err
+	}
+	if err := a.Restore(ctx, added.Path); err != nil {
+		return lazyerr.Wrap(lazyerr.ArchiveError, err).
+			WithDetail("id", a.ID).
+			WithDetail("path", added.Path).
+			WithHint("The worktree was recreated and the archive kept; the rest is in " + a.Location())
+	}
+
+	deleted := false
+	if !archiveKeep {
+		if err := a.Delete(); err != nil {
+			out.Warning(fmt.Sprintf("Could not delete the archive: %v", err))
+		} else {
+			deleted = true
+		}
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.ArchiveRestore{ID: a.ID, Path: added.Path, Branch: branch, Deleted: deleted})
+	}
+	out.Success(fmt.Sprintf("Restored %s on branch %s", a.Name, branch))
+	out.Dim("  " + added.Path)
+	return nil
+}
+
+// restoreBranch returns the branch to check out for a, creating it at the
+// archived commit if it no longer exists. A detached worktree gets a branch
+// named after it.
+func restoreBranch(cmd *cobra.Command, a *archive.Archive) (string, error) {
+	ctx := cmd.Context()
+	if err := a.FetchCommits(ctx); err != nil {
+		return "", lazyerr.Wrap(lazyerr.ArchiveError, err).WithDetail("id", a.ID)
+	}
+
+	branch := a.Branch
+	if branch == "" {
+		branch = a.Name
+	}
+	if git.BranchExists(ctx, branch) {
+		if !git.IsAncestor(ctx, a.Head, branch) {
+			return "", lazyerr.New(lazyerr.BranchExists, "branch %s exists but doesn't have the archived commits", branch).
+				WithDetail("branch", branch).
+				WithHint("Rename or delete the branch, then restore again")
+		}
+		return branch, nil
+	}
+	if err := git.CreateBranch(ctx, branch, a.Head); err != nil {
+		return "", lazyerr.Wrap(lazyerr.BranchError, err).WithDetail("branch", branch)
+	}
+	return branch, nil
+}
+
+// repoArchives returns the archives of the current repository
+func repoArchives(cmd *cobra.Command) ([]*archive.Archive, error) {
+	archives, err := archive.List(archive.Dir())
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.ArchiveError, err)
+	}
+	commonDir, err := git.GetCommonDir(cmd.Context())
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.NotGitRepo, err)
+	}
+	mine := []*archive.Archive{}
+	for _, a := range archives {
+		if paths.Same(runtime.GOOS, commonDir, a.Repo) {
+			mine = append(mine, a)
+		}
+	}
+	return mine, nil
+}
+
+// archiveContents describes what an archive kept, e.g. "2 commits, changes"
+func archiveContents(a *archive.Archive) string {
+	var parts []string
+	if a.Commits > 0 {
+		parts = append(parts, fmt.Sprintf("%d commit(s)", a.Commits))
+	}
+	if a.Changes {
+		parts = append(parts, "changes")
+	}
+	if n := len(a.Untracked); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d untracked", n))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// archiveWorktree archives wt before it is removed. It returns nil, and
+// writes nothing, if removing it would lose nothing.
+func archiveWorktree(cmd *cobra.Command, out *output.Output, m *worktree.Manager, wt *worktree.Worktree) (*archive.Archive, error) {
+	ctx := cmd.Context()
+	a, err := archive.Inspect(ctx, *wt, m.MainBranch(ctx))
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.ArchiveError, err).WithDetail("worktree", wt.Path)
+	}
+	if a.Empty() {
+		if !jsonOutput {
+			out.Dim(fmt.Sprintf("Nothing to archive: %s has no unmerged commits or changes", a.Name))
+		}
+		return nil, nil
+	}
+	if err := archive.Create(ctx, archive.Dir(), a, time.Now()); err != nil {
+		return nil, lazyerr.Wrap(lazyerr.ArchiveError, err).WithDetail("worktree", wt.Path)
+	}
+	return a, nil
+}
+
+// completeArchives completes archive ids of the current repository
+func completeArchives(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	archives, err := repoArchives(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	ids := make([]string, 0, len(archives))
+	for _, a := range archives {
+		ids = append(ids, a.ID+"\t"+archiveContents(a))
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/miltonparedes/lazywork/pkg/worktree"
+	"github.com/spf13/cobra"
+)
+
+var branchCmd = &cobra.Command{
+	Use:   "branch",
+	Short: "Manage local branches",
+}
+
+var branchCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Delete branches that are merged or gone from the remote",
+	Long: `List the local branches that are merged into the main branch, or whose
+upstream was deleted from the remote, and delete the ones you pick.
+
+Branches checked out in a worktree and the main branch itself are never
+touched. Branches whose upstream is gone but that are not merged (for
+example after a squash merge) are only selected with --force.
+
+Upstreams are only known to be gone after 'git fetch --prune'; --fetch,
+or auto_fetch in the config, runs it first.
+
+Example:
+  lazywork branch clean
+  lazywork branch clean --dry-run
+  lazywork branch clean --yes --force`,
+	Args: cobra.NoArgs,
+	RunE: runBranchClean,
+}
+
+var (
+	branchCleanDryRun bool
+	branchCleanYes    bool
+	branchCleanForce  bool
+)
+
+func init() {
+	rootCmd.AddCommand(branchCmd)
+	branchCmd.AddCommand(branchCleanCmd)
+
+	branchCleanCmd.Flags().BoolVar(&branchCleanDryRun, "dry-run", false, "List the branches without deleting them")
+	branchCleanCmd.Flags().BoolVarP(&branchCleanYes, "yes", "y", false, "Delete without prompting")
+	branchCleanCmd.Flags().BoolVarP(&branchCleanForce, "force", "f", false, "Also delete unmerged branches whose upstream is gone")
+	branchCleanCmd.Flags().BoolVar(&fetchFirst, "fetch", false, "Run 'git fetch --prune' first (default from auto_fetch)")
+}
+
+func runBranchClean(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	cfg, _ := loadConfig(ctx)
+	fetchRemote(ctx, out, cfg)
+
+	base := statusBase(ctx)
+	branches, err := git.LocalBranches(ctx, base)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+	inUse := map[string]bool{base: true}
+	for _, wt := range worktrees {
+		inUse[wt.Branch] = true
+	}
+
+	candidates := []git.LocalBranch{}
+	for _, b := range branches {
+		if !inUse[b.Name] && (b.Merged || b.Gone) {
+			candidates = append(candidates, b)
+		}
+	}
+
+	if len(candidates) == 0 || branchCleanDryRun {
+		if jsonOutput {
+			return out.JSON(schema.BranchClean{Base: base, Branches: candidates, Deleted: []string{}})
+		}
+		if len(candidates) == 0 {
+			out.Info(fmt.Sprintf("No branches merged into %s or gone from the remote", base))
+			return nil
+		}
+		rows := make([][]string, 0, len(candidates))
+		for _, b := range candidates {
+			rows = append(rows, []string{b.Name, branchCleanReason(b)})
+		}
+		out.Table([]string{"BRANCH", "REASON"}, rows)
+		return nil
+	}
+
+	var selected []string
+	if out.IsTTY() && !branchCleanYes {
+		if err := tui.BranchCleanForm(candidates, branchCleanForce, &selected).Run(); err != nil {
+			return err
+		}
+		if len(selected) == 0 {
+			return lazyerr.New(lazyerr.Cancelled, "no branches selected")
+		}
+	} else {
+		for _, b := range candidates {
+			if b.Merged || branchCleanForce {
+				selected = append(selected, b.Name)
+			} else {
+				out.Warning(fmt.Sprintf("Keeping %s: not merged into %s (use --force to delete it)", b.Name, base))
+			}
+		}
+	}
+
+	// Every candidate was checked against base rather than HEAD, which is
+	// what 'git branch -d' would check, so deletion is forced
+	deleted := []string{}
+	var failed []string
+	var refs []state.BranchRef
+	for _, name := range selected {
+		commit, _ := git.ResolveCommit(ctx, name)
+		if err := git.DeleteBranch(ctx, name, true); err != nil {
+			out.Warning(fmt.Sprintf("Failed to delete %s: %v", name, err))
+			failed = append(failed, name)
+			continue
+		}
+		deleted = append(deleted, name)
+		refs = append(refs, state.BranchRef{Name: name, Commit: commit})
+		if !jsonOutput {
+			out.Success("Deleted branch " + name)
+		}
+	}
+	if len(refs) > 0 {
+		newManager(cmd, out, cfg).Record(ctx, worktree.Operation{
+			Kind:     state.OpBranchDelete,
+			Summary:  "delete branches " + strings.Join(deleted, ", "),
+			Branches: refs,
+		})
+	}
+
+	if len(failed) > 0 {
+		return lazyerr.New(lazyerr.BranchError, "failed to delete %s", strings.Join(failed, ", ")).
+			WithDetail("deleted", deleted).
+			WithDetail("failed", failed)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.BranchClean{Base: base, Branches: candidates, Deleted: deleted})
+	}
+
+	return nil
+}
+
+// branchCleanReason says why b can be cleaned up
+func branchCleanReason(b git.LocalBranch) string {
+	switch {
+	case b.Merged && b.Gone:
+		return "merged, upstream gone"
+	case b.Merged:
+		return "merged"
+	default:
+		return "upstream gone, not merged"
+	}
+}
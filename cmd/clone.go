@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone <url> [dir]",
+	Short: "Clone a repository, optionally in the bare worktree layout",
+	Long: `Clone a repository into dir (default: the repository name).
+
+With --bare the repository is set up for a worktree-only workflow: the
+bare repository lives in <dir>/.bare, <dir>/.git points to it, and the
+default branch is checked out as a worktree in <dir>/<branch>. New
+worktrees from 'lazywork worktree add' are created next to it, and
+'worktree finish' runs from the default branch's worktree.
+
+Example:
+  lazywork clone --bare git@github.com:org/app.git
+  # app/.bare, app/main
+
+  cd app/main && lazywork worktree add feature-auth
+  # app/feature-auth`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runClone,
+}
+
+var cloneBare bool
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+	cloneCmd.Flags().BoolVar(&cloneBare, "bare", false, "Clone bare into <dir>/.bare and check out the default branch as a worktree")
+}
+
+func runClone(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	url := args[0]
+	dir := git.RepoNameFromURL(url)
+	if len(args) > 1 {
+		dir = args[1]
+	}
+	if dir == "" {
+		return lazyerr.New(lazyerr.InvalidArgument, "cannot derive a directory name from '%s'; pass one as the second argument", url)
+	}
+
+	if !cloneBare {
+		out.Progress(fmt.Sprintf("Cloning %s into %s", url, dir))
+		if err := git.Clone(ctx, url, dir); err != nil {
+			return lazyerr.Wrap(lazyerr.CloneError, err)
+		}
+		path, _ := filepath.Abs(dir)
+		if jsonOutput {
+			return out.JSON(schema.Clone{Path: path})
+		}
+		out.Success("Cloned into " + path)
+		return nil
+	}
+
+	out.Progress(fmt.Sprintf("Cloning %s into %s/%s", url, dir, git.BareDir))
+	result, err := git.CloneBare(ctx, url, dir)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.CloneError, err)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.Clone{
+			Path:     result.Path,
+			Bare:     true,
+			BareDir:  result.BareDir,
+			Branch:   result.Branch,
+			Worktree: result.Worktree,
+		})
+	}
+
+	out.Success("Cloned bare repository into " + result.BareDir)
+	out.Success(fmt.Sprintf("Checked out %s in %s", result.Branch, result.Worktree))
+	out.Println()
+	out.Info("Start working with: cd " + result.Worktree)
+
+	return nil
+}
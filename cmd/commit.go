@@ -0,0 +1,438 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/miltonparedes/lazywork/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var commitCmd = &cobra.Command{
+	Use:   "commit",
+	Short: "Commit staged changes with an AI-generated message",
+	Long: `Generate a commit message for the staged changes with the configured AI
+provider, review it in an editor and commit.
+
+With --amend the last commit's message and the newly staged changes (if
+any) are sent instead, and the proposed message replaces the old one.
+Trailers such as Signed-off-by and Co-authored-by are kept as they were.
+
+With --all, changes to tracked files are staged first, as with 'git commit
+-a', and --include-untracked adds new files as well. The index is put
+back as it was if no commit is made.
+
+Without a terminal, or with --yes, the message is committed as generated.
+With --message-only it is printed alone instead, for scripts and editors.
+To get drafts from a plain 'git commit', see 'lazywork hook install'.
+
+With --offline, or when no AI provider is set up, the message is built
+from the diffstat instead, e.g. "Update auth middleware (+120/-34 in 4
+files)", so committing works without an API key.
+
+With --stdin the diff is read from standard input instead of the index,
+and the message is only printed, so other tools can get a message for
+any diff without lazywork touching the repository.
+
+Example:
+  lazywork commit
+  lazywork commit --amend
+  lazywork commit -a --include-untracked
+  lazywork commit --dry-run --provider openai
+  lazywork commit --offline
+  git diff main... | lazywork commit --stdin --message-only`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if commitHook {
+			return cobra.RangeArgs(1, 3)(cmd, args)
+		}
+		return cobra.NoArgs(cmd, args)
+	},
+	RunE: runCommit,
+}
+
+var (
+	commitAmend            bool
+	commitYes              bool
+	commitDryRun           bool
+	commitConventional     bool
+	commitNoEmoji          bool
+	commitMessageOnly      bool
+	commitAll              bool
+	commitIncludeUntracked bool
+	commitHook             bool
+	commitStdin            bool
+	commitProvider         string
+	commitModel            string
+	commitOffline          bool
+)
+
+func init() {
+	rootCmd.AddCommand(commitCmd)
+
+	commitCmd.Flags().BoolVar(&commitAmend, "amend", false, "Rewrite the last commit's message, adding the staged changes")
+	commitCmd.Flags().BoolVarP(&commitYes, "yes", "y", false, "Commit the generated message without review")
+	commitCmd.Flags().BoolVar(&commitDryRun, "dry-run", false, "Print the generated message without committing")
+	commitCmd.Flags().BoolVarP(&commitAll, "all", "a", false, "Stage changes to tracked files before generating the message")
+	commitCmd.Flags().BoolVar(&commitIncludeUntracked, "include-untracked", false, "Stage new files too (implies --all)")
+	commitCmd.Flags().BoolVar(&commitMessageOnly, "message-only", false, "Print only the generated message, without committing")
+	commitCmd.Flags().BoolVar(&commitStdin, "stdin", false, "Read the diff from standard input and print a message for it, without committing")
+	commitCmd.Flags().BoolVar(&commitHook, "hook", false, "Fill the message file of git's prepare-commit-msg hook (see 'lazywork hook')")
+	commitCmd.Flags().MarkHidden("hook")
+	commitCmd.Flags().BoolVar(&commitConventional, "conventional", false, "Ask for a Conventional Commits subject")
+	commitCmd.Flags().BoolVar(&commitNoEmoji, "no-emoji", false, "Don't start the subject with a gitmoji, even if commit.emoji is set")
+	commitCmd.Flags().StringVar(&commitProvider, "provider", "", "AI provider to use (default: models.commit, then default_provider)")
+	commitCmd.Flags().StringVar(&commitModel, "model", "", "Model to use (default: models.commit, then default_model)")
+	commitCmd.Flags().BoolVar(&commitOffline, "offline", false, "Build the message from the diffstat without an AI provider")
+	commitCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in the changes to the AI provider unmasked")
+	commitCmd.MarkFlagsMutuallyExclusive("stdin", "amend")
+	commitCmd.MarkFlagsMutuallyExclusive("stdin", "all")
+	commitCmd.MarkFlagsMutuallyExclusive("stdin", "include-untracked")
+	commitCmd.RegisterFlagCompletionFunc("provider", completeProviders)
+}
+
+func runCommit(cmd *cobra.Command, args []string) (err error) {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if commitHook {
+		return runCommitHook(ctx, out, args)
+	}
+	if commitStdin {
+		return runCommitStdin(cmd, out)
+	}
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	if commitAll || commitIncludeUntracked {
+		tree, err := git.WriteTree(ctx)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.CommitError, err).WithHint("Resolve conflicts before committing")
+		}
+		if err := git.StageAll(ctx, commitIncludeUntracked); err != nil {
+			return lazyerr.Wrap(lazyerr.CommitError, err)
+		}
+		// Like 'git commit -a', leave the index as it was unless a commit
+		// is made
+		defer func() {
+			if err != nil || commitDryRun || commitMessageOnly {
+				git.ReadTree(context.WithoutCancel(ctx), tree)
+			}
+		}()
+	}
+
+	message, style, err := draftCommitMessage(ctx, out, cfg, commitAmend)
+	if err != nil {
+		return err
+	}
+
+	if commitDryRun || commitMessageOnly {
+		if jsonOutput {
+			return out.JSON(schema.Message{Message: message, Amend: commitAmend})
+		}
+		if !commitMessageOnly {
+			for _, problem := range style.Lint.Check(message, style.Emoji) {
+				out.Warning(problem.String())
+			}
+		}
+		out.Println(message)
+		return nil
+	}
+
+	return commitWithMessage(ctx, out, message, commitAmend, !commitYes, style)
+}
+
+// draftCommitMessage asks the AI provider for a message for the staged
+// changes, or for the last commit with amend
+func draftCommitMessage(ctx context.Context, out *output.Output, cfg *config.Config, amend bool) (string, commitmsg.Style, error) {
+	var style commitmsg.Style
+	diff, err := git.GetStagedDiff(ctx)
+	if err != nil {
+		return "", style, lazyerr.Wrap(lazyerr.CommitError, err)
+	}
+
+	var previous string
+	var trailers []string
+	if amend {
+		previous, err = git.CommitMessage(ctx, "HEAD")
+		if err != nil {
+			return "", style, lazyerr.New(lazyerr.CommitError, "there is no commit to amend")
+		}
+		// Trailers are put back verbatim rather than trusted to the model
+		previous, trailers = commitmsg.SplitTrailers(previous)
+	} else if strings.TrimSpace(diff) == "" {
+		return "", style, lazyerr.New(lazyerr.NothingStaged, "no staged changes to commit")
+	}
+
+	return generateCommitMessage(ctx, out, cfg, diff, previous, trailers, true)
+}
+
+// generateCommitMessage asks the AI provider for a message for diff, or
+// for a new version of previous when it is set, and for fixes to the
+// commitlint problems it has. trailers are added back to the result. With
+// --offline, or when no provider is set up and none was asked for, the
+// message comes from offlineCommitMessage instead. The generation is kept
+// out of the repository's generation log unless logGeneration is set.
+func generateCommitMessage(ctx context.Context, out *output.Output, cfg *config.Config, diff, previous string, trailers []string, logGeneration bool) (string, commitmsg.Style, error) {
+	style := commitStyle(ctx, out, cfg, commitConventional)
+	if commitNoEmoji {
+		style.Emoji = false
+	}
+	if commitOffline {
+		return offlineCommitMessage(diff, previous, trailers, style), style, nil
+	}
+
+	full := diff
+	diff = ignoreFiles(ctx, out, diff)
+	messages := commitmsg.Messages(diff, style)
+	if previous != "" {
+		messages = commitmsg.AmendMessages(previous, diff, style)
+	}
+	messages = withRepoContext(ctx, cfg, messages)
+	p, req, err := newAIRequest(out, cfg, "commit", commitProvider, commitModel, messages)
+	if err != nil {
+		if e := lazyerr.From(err); e.Code == lazyerr.InvalidProvider && commitProvider == "" && commitModel == "" {
+			out.Warning(fmt.Sprintf("No AI provider is set up (%s); using an offline message", e.Message))
+			return offlineCommitMessage(full, previous, trailers, style), style, nil
+		}
+		return "", style, err
+	}
+	if !logGeneration {
+		skipGenerationLog(p)
+	}
+
+	out.Progress(fmt.Sprintf("Generating commit message with %s (%s)", p.Name(), req.Model))
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return "", style, lazyerr.Wrap(lazyerr.ProviderError, err).WithDetail("provider", p.Name())
+	}
+
+	message := commitmsg.AddTrailers(style.Clean(resp.Content), trailers)
+	if strings.TrimSpace(message) == "" {
+		return "", style, lazyerr.New(lazyerr.ProviderError, "%s returned an empty message", p.Name())
+	}
+
+	// Ask the model to correct a message that breaks commitlint rules
+	for attempt := 0; attempt < maxLintFixes; attempt++ {
+		problems := style.Lint.Errors(message, style.Emoji)
+		if len(problems) == 0 {
+			break
+		}
+		out.Progress(fmt.Sprintf("Fixing %d commitlint problem(s) with %s", len(problems), p.Name()))
+		req.Messages = append(req.Messages,
+			types.Message{Role: "assistant", Content: resp.Content},
+			types.Message{Role: "user", Content: commitmsg.LintFixMessage(problems)},
+		)
+		resp, err = p.Complete(ctx, req)
+		if err != nil {
+			return "", style, lazyerr.Wrap(lazyerr.ProviderError, err).WithDetail("provider", p.Name())
+		}
+		message = commitmsg.AddTrailers(style.Clean(resp.Content), trailers)
+	}
+
+	return message, style, nil
+}
+
+// offlineCommitMessage returns a message for diff built from its
+// diffstat, or previous unchanged when amending, with trailers added back
+func offlineCommitMessage(diff, previous string, trailers []string, style commitmsg.Style) string {
+	if previous != "" {
+		return commitmsg.AddTrailers(previous, trailers)
+	}
+	return commitmsg.AddTrailers(commitmsg.Offline(diff, style), trailers)
+}
+
+// runCommitStdin prints a message for the diff read from standard input,
+// without reading or changing the repository's index or history
+func runCommitStdin(cmd *cobra.Command, out *output.Output) error {
+	ctx := cmd.Context()
+
+	diff, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ReadError, err)
+	}
+	if strings.TrimSpace(string(diff)) == "" {
+		return lazyerr.New(lazyerr.InvalidArgument, "no diff on standard input").
+			WithHint("Pipe a diff in, e.g. git diff | lazywork commit --stdin --message-only")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	// The message is for someone else's diff, so it stays out of the
+	// generation log for 'lazywork redo' not to commit it
+	message, style, err := generateCommitMessage(ctx, out, cfg, string(diff), "", nil, false)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.StdinMessage{Message: message})
+	}
+	if !commitMessageOnly {
+		for _, problem := range style.Lint.Check(message, style.Emoji) {
+			out.Warning(problem.String())
+		}
+	}
+	out.Println(message)
+	return nil
+}
+
+// runCommitHook fills the message file of git's prepare-commit-msg hook,
+// given in args with the message source and commit. Nothing is written
+// when git already has a message, and problems are only warnings so the
+// commit goes on.
+func runCommitHook(ctx context.Context, out *output.Output, args []string) error {
+	// -m, -F, -C, -t, merges, squashes and amends
+	if len(args) > 1 && args[1] != "" {
+		return nil
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		out.Warning(fmt.Sprintf("lazywork could not draft the message: %v", err))
+		return nil
+	}
+
+	message, _, err := draftCommitMessage(ctx, out, cfg, false)
+	if err != nil {
+		if lazyerr.From(err).Code == lazyerr.NothingStaged {
+			return nil
+		}
+		out.Warning(fmt.Sprintf("lazywork could not draft the message: %v", err))
+		return nil
+	}
+
+	// Keep the help comments git already wrote
+	existing, err := os.ReadFile(args[0])
+	if err != nil && !os.IsNotExist(err) {
+		out.Warning(fmt.Sprintf("lazywork could not read %s: %v", args[0], err))
+		return nil
+	}
+	if err := os.WriteFile(args[0], []byte(message+"\n"+string(existing)), 0o644); err != nil {
+		out.Warning(fmt.Sprintf("lazywork could not write %s: %v", args[0], err))
+	}
+	return nil
+}
+
+// maxLintFixes bounds the requests made to fix commitlint problems
+const maxLintFixes = 2
+
+// commitStyle returns the message style set in the commit section of cfg,
+// with the rules of the repository's commitlint config overlaid by
+// commit.lint and its recent messages as examples
+func commitStyle(ctx context.Context, out *output.Output, cfg *config.Config, conventional bool) commitmsg.Style {
+	style := commitmsg.Style{
+		Conventional: conventional,
+		Language:     cfg.Commit.Language,
+		Tone:         cfg.Commit.Tone,
+		MaxSubject:   cfg.Commit.MaxSubjectLength,
+		Emoji:        cfg.Commit.Emoji,
+	}
+
+	if root, err := git.GetRepoRoot(ctx); err == nil {
+		rules, _, err := commitmsg.LoadCommitlint(root)
+		if err != nil {
+			out.Warning(fmt.Sprintf("Ignoring commitlint config: %v", err))
+		}
+		style.Lint = rules
+	}
+	if len(cfg.Commit.Lint) > 0 {
+		rules, err := commitmsg.ParseLintRules(cfg.Commit.Lint)
+		if err != nil {
+			out.Warning(fmt.Sprintf("Ignoring commit.lint: %v", err))
+		}
+		style.Lint = style.Lint.Overlay(rules)
+	}
+	if n := cfg.Commit.ExampleCount(); n > 0 {
+		// Read more than needed: merges and fixups are skipped
+		if commits, err := git.RecentMessages(ctx, n*3); err == nil {
+			messages := make([]string, len(commits))
+			for i, c := range commits {
+				messages[i] = c.Message
+			}
+			style.Examples = commitmsg.Examples(messages, n)
+		}
+	}
+	return style
+}
+
+// commitWithMessage commits the staged changes, or amends HEAD, with
+// message, opening it in the commit editor first when review is set and
+// there is a terminal
+func commitWithMessage(ctx context.Context, out *output.Output, message string, amend, review bool, style commitmsg.Style) error {
+	message, err := reviewMessage(out, message, review, style)
+	if err != nil {
+		return err
+	}
+
+	if amend {
+		err = git.Amend(ctx, message)
+	} else {
+		err = git.Commit(ctx, message)
+	}
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.CommitError, err)
+	}
+
+	hash, _ := git.ShortHash(ctx, "HEAD")
+	subject, _ := tui.SplitMessage(message)
+
+	if jsonOutput {
+		return out.JSON(schema.Commit{Commit: hash, Message: message, Amend: amend})
+	}
+
+	verb := "Committed"
+	if amend {
+		verb = "Amended"
+	}
+	out.Success(fmt.Sprintf("%s %s: %s", verb, hash, subject))
+
+	return nil
+}
+
+// reviewMessage opens message in the commit editor when review is set and
+// there is a terminal, and returns the edited message. Otherwise a message
+// breaking commitlint rules is refused, as nobody had the chance to fix it.
+func reviewMessage(out *output.Output, message string, review bool, style commitmsg.Style) (string, error) {
+	if out.IsTTY() && review {
+		edited, ok, err := tui.RunCommitEditor(message, style)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", lazyerr.New(lazyerr.Cancelled, "commit cancelled")
+		}
+		return edited, nil
+	}
+
+	if problems := style.Lint.Errors(message, style.Emoji); len(problems) > 0 {
+		broken := make([]string, len(problems))
+		for i, p := range problems {
+			broken[i] = p.String()
+			out.Warning(broken[i])
+		}
+		return "", lazyerr.New(lazyerr.LintFailed, "message breaks %d commitlint rule(s)", len(problems)).
+			WithDetail("message", message).
+			WithDetail("problems", broken)
+	}
+	return message, nil
+}
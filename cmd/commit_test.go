@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/internal/state"
+)
+
+const stdinDiff = `diff --git a/login.go b/login.go
+new file mode 100644
+--- /dev/null
++++ b/login.go
+@@ -0,0 +1 @@
++package login
+`
+
+// newCommitRepo creates a repository with one commit, changes into it and
+// returns a config whose default provider answers with message
+func newCommitRepo(t *testing.T, message string) (dir, config string) {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@test.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@test.com")
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"commit", "--allow-empty", "-m", "Initial commit"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	config = filepath.Join(t.TempDir(), "config.json")
+	data := `{"default_provider": "mock", "providers": {"mock": {"type": "mock", "response": "` + message + `"}}}`
+	if err := os.WriteFile(config, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		commitStdin = false
+		commitMessageOnly = false
+		rootCmd.SetIn(nil)
+	})
+	return dir, config
+}
+
+func TestCommitStdin(t *testing.T) {
+	dir, config := newCommitRepo(t, "Add login package")
+
+	rootCmd.SetIn(strings.NewReader(stdinDiff))
+	stdout, _, err := execute(t, "commit", "--stdin", "--message-only", "--config", config)
+	if err != nil {
+		t.Fatalf("commit --stdin failed: %v", err)
+	}
+	if stdout != "Add login package\n" {
+		t.Errorf("stdout = %q, want the message for the diff", stdout)
+	}
+	if out, _ := exec.Command("git", "rev-list", "--count", "HEAD").Output(); strings.TrimSpace(string(out)) != "1" {
+		t.Errorf("commits = %s, want the repository left alone", out)
+	}
+	commonDir := filepath.Join(dir, ".git")
+	if log, _ := state.LoadGenerations(state.Dir(commonDir)); log != nil {
+		if _, ok := log.Last("commit"); ok {
+			t.Error("message for a diff from stdin was kept in the generation log")
+		}
+	}
+
+	// Later generations in the same process are logged again
+	commitStdin = false
+	rootCmd.SetIn(nil)
+	if err := os.WriteFile("login.go", []byte("package login\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "add", "login.go").CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, out)
+	}
+	if _, _, err := execute(t, "commit", "--message-only", "--config", config); err != nil {
+		t.Fatalf("commit --message-only failed: %v", err)
+	}
+	log, err := state.LoadGenerations(state.Dir(commonDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen, ok := log.Last("commit"); !ok || gen.Output != "Add login package" {
+		t.Errorf("last commit generation = %+v, %v, want it logged", gen, ok)
+	}
+}
+
+func TestCommitStdinEmpty(t *testing.T) {
+	_, config := newCommitRepo(t, "unused")
+
+	rootCmd.SetIn(strings.NewReader("\n"))
+	_, _, err := execute(t, "commit", "--stdin", "--message-only", "--config", config)
+	if code := ExitCode(err); code != 2 {
+		t.Errorf("exit code = %d (%v), want 2 for empty input", code, err)
+	}
+}
@@ -1,13 +1,17 @@
 package cmd
 
 import (
-	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/pkg/config"
 	"github.com/spf13/cobra"
 )
 
 var completionCmd = &cobra.Command{
-	Use:   "completion [bash|zsh|fish]",
+	Use:   "completion [bash|zsh|fish|powershell]",
 	Short: "Generate shell completion script",
 	Long: `Generate shell completion script for LazyWork.
 
@@ -30,9 +34,13 @@ Fish:
   lazywork completion fish | source
 
   # Or save to completions folder:
-  lazywork completion fish > ~/.config/fish/completions/lazywork.fish`,
+  lazywork completion fish > ~/.config/fish/completions/lazywork.fish
+
+PowerShell:
+  # Add to $PROFILE:
+  lazywork completion powershell | Out-String | Invoke-Expression`,
 	DisableFlagsInUseLine: true,
-	ValidArgs:             []string{"bash", "zsh", "fish"},
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
 	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 	RunE:                  runCompletion,
 }
@@ -44,11 +52,212 @@ func init() {
 func runCompletion(cmd *cobra.Command, args []string) error {
 	switch args[0] {
 	case "bash":
-		return rootCmd.GenBashCompletion(os.Stdout)
+		return rootCmd.GenBashCompletion(cmd.OutOrStdout())
 	case "zsh":
-		return rootCmd.GenZshCompletion(os.Stdout)
+		return rootCmd.GenZshCompletion(cmd.OutOrStdout())
 	case "fish":
-		return rootCmd.GenFishCompletion(os.Stdout, true)
+		return rootCmd.GenFishCompletion(cmd.OutOrStdout(), true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(cmd.OutOrStdout())
 	}
 	return nil
 }
+
+// configKeys lists the common top-level keys accepted by 'config set'
+var configKeys = []string{"default_provider", "default_model", "worktree_dir", "main_branch", "envrc_template", "git_timeout", "worktree_submodules", "worktree_sparse", "worktree_push", "worktree_include_main", "worktree_archive", "worktree_stale_after", "port_base", "port_block_size", "auto_fetch", "forge"}
+
+// completeBranches completes local and remote-tracking branch names
+func completeBranches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ctx := cmd.Context()
+	branches, err := git.ListBranches(ctx, true)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return branches, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWorktreeNames completes the first argument with worktree names
+func completeWorktreeNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ctx := cmd.Context()
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, wt := range git.SecondaryWorktrees(worktrees) {
+		names = append(names, filepath.Base(wt.Path))
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeGoTargets completes 'worktree go' with worktree names and the
+// shortcut for the main checkout
+func completeGoTargets(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, directive := completeWorktreeNames(cmd, args, toComplete)
+	if len(args) == 0 {
+		names = append(names, "main")
+	}
+	return names, directive
+}
+
+// completeProviders completes provider names from the loaded config
+func completeProviders(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.LoadFrom(cfgFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]string, 0, len(cfg.Providers))
+	for name := range cfg.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfiles completes config profile names
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.LoadFrom(cfgFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return cfg.ProfileNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeModels completes model IDs for the provider selected with
+// --provider, or for the default provider
+func completeModels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.LoadFrom(cfgFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	providerName := cfg.DefaultProvider
+	if f := cmd.Flags().Lookup("provider"); f != nil && f.Value.String() != "" {
+		providerName = f.Value.String()
+	}
+
+	var models []string
+	for _, model := range cfg.Providers[providerName].Models {
+		models = append(models, model.ID+"\t"+model.Name)
+	}
+	return models, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigSet completes 'config set' keys and, for keys with a known
+// set of values, their values
+func completeConfigSet(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completeConfigKeys(cmd, args, toComplete)
+	case 1:
+		if strings.ToLower(args[0]) == "default_provider" {
+			return completeProviders(cmd, args, toComplete)
+		}
+		if strings.ToLower(args[0]) == "forge" {
+			return config.ForgeTypes, cobra.ShellCompDirectiveNoFileComp
+		}
+		if strings.ToLower(args[0]) == "worktree_dir" {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		}
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigKeys completes dotted config keys. Nested keys are only
+// offered once the user starts typing them, to keep the top level short.
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if !strings.Contains(toComplete, ".") {
+		return append(configKeys, "providers.", "profiles.", "hooks.", "forges.", "tickets.", "budget.", "commit."), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+	if strings.HasPrefix(toComplete, "forges.") {
+		keys := make([]string, 0, 2*len(config.ForgeTypes))
+		for _, name := range config.ForgeTypes {
+			keys = append(keys, "forges."+name+".token", "forges."+name+".base_url")
+		}
+		return keys, cobra.ShellCompDirectiveNoFileComp
+	}
+	if strings.HasPrefix(toComplete, "tickets.") {
+		keys := make([]string, 0, 4*len(config.TicketTrackers))
+		for _, name := range config.TicketTrackers {
+			for _, field := range []string{"token", "email", "base_url", "projects"} {
+				keys = append(keys, "tickets."+name+"."+field)
+			}
+		}
+		return keys, cobra.ShellCompDirectiveNoFileComp
+	}
+	if strings.HasPrefix(toComplete, "models.") {
+		keys := make([]string, 0, len(config.AICommands))
+		for _, command := range config.AICommands {
+			keys = append(keys, "models."+command)
+		}
+		return keys, cobra.ShellCompDirectiveNoFileComp
+	}
+	if strings.HasPrefix(toComplete, "generation.") {
+		keys := make([]string, 0, 2*len(config.AICommands))
+		for _, command := range config.AICommands {
+			keys = append(keys, "generation."+command+".temperature", "generation."+command+".max_tokens")
+		}
+		return keys, cobra.ShellCompDirectiveNoFileComp
+	}
+	if strings.HasPrefix(toComplete, "budget.") {
+		return []string{"budget.monthly", "budget.action"}, cobra.ShellCompDirectiveNoFileComp
+	}
+	if strings.HasPrefix(toComplete, "commit.") {
+		return []string{"commit.language", "commit.tone", "commit.max_subject_length", "commit.emoji", "commit.examples", "commit.no_examples"}, cobra.ShellCompDirectiveNoFileComp
+	}
+	if strings.HasPrefix(toComplete, "hooks.") {
+		keys := make([]string, 0, len(config.HookEvents))
+		for _, event := range config.HookEvents {
+			keys = append(keys, "hooks."+event)
+		}
+		return keys, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.LoadRaw(cfgFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var keys []string
+	for _, key := range cfg.Keys() {
+		if strings.HasPrefix(key, toComplete) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, cobra.ShellCompDirectiveNoFileComp
+}
+
+// registerAIFlagCompletions walks the command tree and registers provider
+// and model completions on every command that defines those flags, so new
+// AI commands get completion without extra wiring
+func registerAIFlagCompletions(cmd *cobra.Command) {
+	if cmd.Flags().Lookup("provider") != nil {
+		cmd.RegisterFlagCompletionFunc("provider", completeProviders)
+	}
+	if cmd.Flags().Lookup("model") != nil {
+		cmd.RegisterFlagCompletionFunc("model", completeModels)
+	}
+	for _, child := range cmd.Commands() {
+		registerAIFlagCompletions(child)
+	}
+}
+
+// completeStashes completes the first argument with stash refs
+func completeStashes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	stashes, err := git.ListStashes(cmd.Context())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	refs := make([]string, 0, len(stashes))
+	for _, s := range stashes {
+		refs = append(refs, s.Ref+"\t"+s.Message)
+	}
+	return refs, cobra.ShellCompDirectiveNoFileComp
+}
@@ -1,12 +1,23 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
 	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/internal/tui"
 	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/schema"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +30,12 @@ var configCmd = &cobra.Command{
 var configShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show current configuration",
-	RunE:  runConfigShow,
+	Long: `Show the effective configuration.
+
+Inside a repository, settings from the repository's .lazywork.json are
+merged over the user config. API keys and providers always come from
+the user config.`,
+	RunE: runConfigShow,
 }
 
 var configPathCmd = &cobra.Command{
@@ -31,25 +47,229 @@ var configPathCmd = &cobra.Command{
 var configInitCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Create default configuration file",
-	RunE:  runConfigInit,
+	Long: `Create the default configuration file.
+
+The config can be written as JSON (default), YAML or TOML. YAML and TOML
+are easier to edit by hand and allow comments; note that 'config set'
+rewrites the file and does not preserve comments.
+
+Example:
+  lazywork config init
+  lazywork config init --format yaml`,
+	RunE: runConfigInit,
 }
 
+var configFormat string
+
 var configSetCmd = &cobra.Command{
 	Use:   "set <key> <value>",
 	Short: "Set a configuration value",
-	Long: `Set a configuration value. Supported keys:
+	Long: `Set a configuration value. Common keys:
   - default_provider: Set the default AI provider (openai, anthropic)
-  - worktree_dir: Set the directory for worktrees (default: .worktrees)`,
-	Args: cobra.ExactArgs(2),
-	RunE: runConfigSet,
+  - default_model: Model used when --model is not given
+  - worktree_dir: Set the directory for worktrees (default: .worktrees).
+    Relative to the repo root unless absolute; ~ and {repo} are expanded
+  - main_branch: Branch that 'worktree finish' merges into (default: main or master)
+  - envrc_template: Template for the .envrc generated by 'worktree add'
+  - git_timeout: Limit for a single git command, e.g. 30s or 2m (default: none)
+  - worktree_submodules: Initialize submodules in new worktrees (true/false)
+  - worktree_sparse: Directories to check out in new worktrees, as a JSON
+    list such as '["apps/web", "libs"]' (default: the full tree)
+  - worktree_push: Push new worktree branches to origin and set their
+    upstream, as 'worktree add --push' does (true/false)
+  - worktree_include_main: List the main checkout in the 'worktree go'
+    selector and resolve names against it (true/false)
+  - worktree_archive: Archive worktrees before 'worktree remove' deletes
+    them, as --archive does (true/false)
+  - worktree_stale_after: How long a worktree goes without commits or
+    visits before list warns it is stale, e.g. 30d or 2w; 0 turns it off
+    (default: 30d). See 'worktree clean --stale'
+  - port_base, port_block_size: Ports handed to worktrees as PORT, LW_PORT
+    and LW_PORT_LAST, one block each (default: blocks of 10 from 3000)
+  - auto_fetch: Run 'git fetch --prune' before list --status, status, finish
+    and branch clean, as --fetch does (true/false)
+  - models.<command>: Model used by one AI command, e.g. models.commit;
+    commands are commit, log, release, resolve, standup, wip, worktree_add,
+    worktree_diff and worktree_finish
+  - generation.<command>.temperature, generation.<command>.max_tokens:
+    Override the model's settings for one AI command
+  - providers.<name>.max_event_size: Largest event of a streamed response
+    the provider may send, in bytes (default: 4194304)
+  - hooks.<event>: Shell commands run around worktree operations; events are
+    pre_/post_ add, remove, use, return and finish
+  - forge: Forge hosting the repository (github, gitlab), when it can't be told
+    from the origin remote
+  - forges.<forge>.token: API token for 'pr create', 'worktree add --issue'
+    and 'worktree list --checks'; prefer 'config set-key --forge <forge>'
+  - tickets.<tracker>.token: API token of a ticket tracker (linear, jira) used
+    to show the tickets named in branch names; prefer 'config set-key --tracker'
+  - tickets.<tracker>.projects: Ticket prefixes the tracker handles, e.g. '["ENG"]'
+    (default: any); Jira also needs tickets.jira.base_url, and tickets.jira.email
+    for Jira Cloud
+
+Nested keys use dotted paths, with list items addressed by index:
+
+Example:
+  lazywork config set worktree_dir .worktrees
+  lazywork config set hooks.post_add "npm install"
+  lazywork config set providers.anthropic.base_url https://proxy.example.com/v1
+  lazywork config set providers.openai.models.0.temperature 0.2`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeConfigSet,
+	RunE:              runConfigSet,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a configuration value",
+	Long: `Print a single value from the effective configuration, for use in
+scripts. Keys are dotted paths such as worktree_dir or
+providers.anthropic.base_url.
+
+API keys are masked; use --raw to read the value stored in the user config
+file instead, without resolving $ENV or keyring references.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigKeys,
+	RunE:              runConfigGet,
+}
+
+var configGetRaw bool
+
+var (
+	setKeyForge   bool
+	setKeyTracker bool
+)
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Revert a configuration value to its default",
+	Long: `Remove a value from the user config file, reverting it to the built-in
+default. Keys are dotted paths such as main_branch or
+providers.anthropic.base_url.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigKeys,
+	RunE:              runConfigUnset,
+}
+
+var configSetKeyCmd = &cobra.Command{
+	Use:   "set-key <provider> [key]",
+	Short: "Store a provider API key in the OS keyring",
+	Long: `Store a provider's API key in the OS keyring (macOS Keychain,
+Secret Service on Linux, Windows Credential Manager) instead of in
+plaintext in the config file.
+
+The provider's api_key is set to "keyring:<provider>", which is resolved
+from the keyring whenever the config is loaded. With --forge the token of
+a forge (github, gitlab) is stored instead, as forges.<forge>.token, and
+with --tracker that of a ticket tracker (linear, jira), as
+tickets.<tracker>.token.
+
+If the key is not given as an argument, you'll be prompted for it (or it
+is read from stdin when not running interactively).
+
+Example:
+  lazywork config set-key anthropic
+  echo "$KEY" | lazywork config set-key openai
+  lazywork config set-key --forge github
+  lazywork config set-key --tracker linear`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeProviders,
+	RunE:              runConfigSetKey,
 }
 
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration file for problems",
+	Long: `Check the configuration file for unknown keys, missing base URLs,
+unresolvable API keys ($ENV or keyring), models without IDs and a
+default_provider that doesn't exist.
+
+Exits with a non-zero status if any errors are found; warnings are
+reported but don't fail validation.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+var configTrustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Allow the hooks of this repository's config to run",
+	Long: `Show the hooks set in the repository's .lazywork.json and allow them to
+run around worktree operations. Until then they are skipped with a
+warning, so cloning a repository never runs its commands by itself.
+
+Trust is kept in the user state directory for this repository and these
+exact commands: when the hooks change, they are skipped again until you
+trust them anew. --revoke takes the trust back.
+
+Example:
+  lazywork config trust
+  lazywork config trust --revoke`,
+	Args: cobra.NoArgs,
+	RunE: runConfigTrust,
+}
+
+var (
+	trustRevoke bool
+	trustYes    bool
+)
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(configShowCmd)
 	configCmd.AddCommand(configPathCmd)
 	configCmd.AddCommand(configInitCmd)
 	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configSetKeyCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configTrustCmd)
+
+	configTrustCmd.Flags().BoolVar(&trustRevoke, "revoke", false, "Stop running the repository's hooks")
+	configTrustCmd.Flags().BoolVarP(&trustYes, "yes", "y", false, "Trust the hooks without asking")
+
+	configSetKeyCmd.Flags().BoolVar(&setKeyForge, "forge", false, "Store the API token of a forge instead of a provider")
+	configSetKeyCmd.Flags().BoolVar(&setKeyTracker, "tracker", false, "Store the API token of a ticket tracker instead of a provider")
+	configSetKeyCmd.MarkFlagsMutuallyExclusive("forge", "tracker")
+	configGetCmd.Flags().BoolVar(&configGetRaw, "raw", false, "Read the user config file without resolving API keys")
+	configInitCmd.Flags().StringVar(&configFormat, "format", "", "Config file format (json, yaml, toml)")
+	configInitCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(config.Formats(), cobra.ShellCompDirectiveNoFileComp))
+}
+
+// loadConfig loads the user config merged with the current repository's
+// .lazywork.json, then applies the profile selected with --profile or
+// $LAZYWORK_PROFILE and any LAZYWORK_* environment overrides. Commands that
+// modify the user config file must use config.LoadRaw instead so these
+// overlays (and resolved API keys) are not written back.
+func loadConfig(ctx context.Context) (*config.Config, error) {
+	var repoRoot string
+	if git.IsInsideWorkTree(ctx) {
+		repoRoot, _ = git.GetRepoRoot(ctx)
+	}
+	cfg, err := config.LoadWithRepo(cfgFile, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := profileName
+	if profile == "" {
+		profile = os.Getenv("LAZYWORK_PROFILE")
+	}
+	if profile != "" {
+		if err := cfg.ApplyProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.ApplyEnv(os.LookupEnv)
+
+	timeout, err := cfg.GetGitTimeout()
+	if err != nil {
+		return nil, err
+	}
+	git.SetTimeout(timeout)
+
+	return cfg, nil
 }
 
 func getConfigPath() string {
@@ -60,12 +280,12 @@ func getConfigPath() string {
 }
 
 func runConfigShow(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
+	out := newOutput(cmd)
+	ctx := cmd.Context()
 
-	cfg, err := config.LoadFrom(cfgFile)
+	cfg, err := loadConfig(ctx)
 	if err != nil {
-		out.ErrorResult(err, "CONFIG_LOAD_ERROR")
-		return err
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
 	}
 
 	configPath := getConfigPath()
@@ -79,14 +299,25 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 		for name := range cfg.Providers {
 			providers = append(providers, name)
 		}
+		slices.Sort(providers)
+
+		envOverrides := cfg.EnvOverrides
+		if envOverrides == nil {
+			envOverrides = []string{}
+		}
 
-		return out.JSON(map[string]interface{}{
-			"path":             configPath,
-			"exists":           exists,
-			"default_provider": cfg.DefaultProvider,
-			"worktree_dir":     cfg.GetWorktreeDir(),
-			"providers":        providers,
-			"config":           cfg,
+		return out.JSON(schema.ConfigShow{
+			Path:            configPath,
+			Exists:          exists,
+			RepoConfig:      cfg.RepoConfigPath,
+			Profile:         cfg.ActiveProfile,
+			Profiles:        cfg.ProfileNames(),
+			EnvOverrides:    envOverrides,
+			DefaultProvider: cfg.DefaultProvider,
+			DefaultModel:    cfg.DefaultModel,
+			WorktreeDir:     cfg.GetWorktreeDir(),
+			Providers:       providers,
+			Config:          cfg.Redacted(),
 		})
 	}
 
@@ -99,10 +330,27 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 	} else {
 		out.Dim("  (using defaults, no config file)")
 	}
+	if cfg.RepoConfigPath != "" {
+		out.Print("  Repo: %s\n", cfg.RepoConfigPath)
+	}
 	out.Println()
 
 	out.Print("  Default Provider: %s\n", cfg.DefaultProvider)
+	if cfg.DefaultModel != "" {
+		out.Print("  Default Model:    %s\n", cfg.DefaultModel)
+	}
 	out.Print("  Worktree Dir:     %s\n", cfg.GetWorktreeDir())
+	if len(cfg.Profiles) > 0 {
+		active := cfg.ActiveProfile
+		if active == "" {
+			active = "(none)"
+		}
+		out.Print("  Profile:          %s\n", active)
+		out.Dim("  Available: " + strings.Join(cfg.ProfileNames(), ", "))
+	}
+	if len(cfg.EnvOverrides) > 0 {
+		out.Print("  Env Overrides:    %s\n", strings.Join(cfg.EnvOverrides, ", "))
+	}
 	out.Println()
 
 	out.Bold("Providers:")
@@ -121,7 +369,7 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 }
 
 func runConfigPath(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
+	out := newOutput(cmd)
 	configPath := getConfigPath()
 
 	exists := true
@@ -130,10 +378,7 @@ func runConfigPath(cmd *cobra.Command, args []string) error {
 	}
 
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"path":   configPath,
-			"exists": exists,
-		})
+		return out.JSON(schema.ConfigPath{Path: configPath, Exists: exists})
 	}
 
 	out.Println(configPath)
@@ -145,31 +390,44 @@ func runConfigPath(cmd *cobra.Command, args []string) error {
 }
 
 func runConfigInit(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
+	out := newOutput(cmd)
 	configPath := getConfigPath()
 
 	if _, err := os.Stat(configPath); err == nil {
-		err := fmt.Errorf("config file already exists at %s", configPath)
-		out.ErrorResult(err, "CONFIG_EXISTS")
-		return err
+		return lazyerr.New(lazyerr.ConfigExists, "config file already exists at %s", configPath)
+	}
+
+	if configFormat != "" {
+		format := strings.ToLower(configFormat)
+		if format == "yml" {
+			format = config.FormatYAML
+		}
+		valid := false
+		for _, f := range config.Formats() {
+			valid = valid || f == format
+		}
+		if !valid {
+			return lazyerr.New(lazyerr.InvalidFormat, "unsupported format '%s'. Supported: %s", configFormat, strings.Join(config.Formats(), ", "))
+		}
+
+		if cfgFile == "" {
+			configPath = filepath.Join(config.DefaultConfigDir(), "config"+config.Extension(format))
+		} else if config.FormatFromPath(cfgFile) != format {
+			return lazyerr.New(lazyerr.InvalidFormat, "--format %s does not match the extension of %s", format, cfgFile)
+		}
 	}
 
-	cfg, err := config.LoadFrom(cfgFile)
+	cfg, err := config.LoadRaw(cfgFile)
 	if err != nil {
-		out.ErrorResult(err, "CONFIG_LOAD_ERROR")
-		return err
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
 	}
 
-	if err := cfg.SaveTo(cfgFile); err != nil {
-		out.ErrorResult(err, "CONFIG_SAVE_ERROR")
-		return err
+	if err := cfg.SaveTo(configPath); err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigSaveError, err)
 	}
 
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"path":    configPath,
-			"created": true,
-		})
+		return out.JSON(schema.ConfigInit{Path: configPath, Format: config.FormatFromPath(configPath), Created: true})
 	}
 
 	out.Success(fmt.Sprintf("Created config file at %s", configPath))
@@ -179,53 +437,366 @@ func runConfigInit(cmd *cobra.Command, args []string) error {
 }
 
 func runConfigSet(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
+	out := newOutput(cmd)
 
-	key := args[0]
+	key := config.CanonicalKey(args[0])
 	value := args[1]
 
-	cfg, err := config.LoadFrom(cfgFile)
+	cfg, err := config.LoadRaw(cfgFile)
 	if err != nil {
-		out.ErrorResult(err, "CONFIG_LOAD_ERROR")
-		return err
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
 	}
 
-	switch strings.ToLower(key) {
-	case "default_provider":
+	if key == "default_provider" {
 		if _, exists := cfg.Providers[value]; !exists {
 			validProviders := make([]string, 0, len(cfg.Providers))
 			for name := range cfg.Providers {
 				validProviders = append(validProviders, name)
 			}
-			err := fmt.Errorf("unknown provider '%s'. Valid providers: %s", value, strings.Join(validProviders, ", "))
-			out.ErrorResult(err, "INVALID_PROVIDER")
-			return err
+			return lazyerr.New(lazyerr.InvalidProvider, "unknown provider '%s'. Valid providers: %s", value, strings.Join(validProviders, ", "))
 		}
-		cfg.DefaultProvider = value
+	}
+
+	if err := cfg.Set(key, value); err != nil {
+		return lazyerr.Wrap(lazyerr.InvalidKey, err)
+	}
+
+	if err := cfg.SaveTo(cfgFile); err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigSaveError, err)
+	}
 
-	case "worktree_dir":
-		cfg.WorktreeDir = value
+	if jsonOutput {
+		return out.JSON(schema.ConfigSet{Key: key, Value: value, Updated: true})
+	}
+
+	out.Success(fmt.Sprintf("Set %s = %s", key, value))
+	if !strings.HasPrefix(value, "$") && !strings.HasPrefix(value, config.KeyringPrefix) {
+		switch {
+		case strings.HasSuffix(key, ".api_key"):
+			out.Dim("API key stored in plaintext; consider 'lazywork config set-key' instead")
+		case strings.HasPrefix(key, "forges.") && strings.HasSuffix(key, ".token"):
+			out.Dim("Token stored in plaintext; consider 'lazywork config set-key --forge' instead")
+		case strings.HasPrefix(key, "tickets.") && strings.HasSuffix(key, ".token"):
+			out.Dim("Token stored in plaintext; consider 'lazywork config set-key --tracker' instead")
+		case strings.Contains(key, ".http.headers."):
+			out.Dim("Header stored in plaintext; use a $ENV reference if it is a secret")
+		}
+	}
 
+	return nil
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+	key := config.CanonicalKey(args[0])
+
+	var cfg *config.Config
+	var err error
+	if configGetRaw {
+		cfg, err = config.LoadRaw(cfgFile)
+	} else {
+		cfg, err = loadConfig(ctx)
+	}
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+	if !configGetRaw {
+		cfg = cfg.Redacted()
+	}
+
+	value, err := cfg.Get(key)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.InvalidKey, err)
+	}
+	if value == nil && key == "worktree_dir" {
+		value = cfg.GetWorktreeDir()
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.ConfigGet{Key: key, Value: value})
+	}
+
+	switch v := value.(type) {
+	case nil:
+	case string:
+		out.Println(v)
+	case map[string]interface{}, []interface{}:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		out.Println(string(data))
 	default:
-		err := fmt.Errorf("unknown config key '%s'. Supported keys: default_provider, worktree_dir", key)
-		out.ErrorResult(err, "INVALID_KEY")
-		return err
+		out.Println(v)
+	}
+
+	return nil
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	key := config.CanonicalKey(args[0])
+
+	cfg, err := config.LoadRaw(cfgFile)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	if err := cfg.Unset(key); err != nil {
+		return lazyerr.Wrap(lazyerr.InvalidKey, err)
 	}
 
 	if err := cfg.SaveTo(cfgFile); err != nil {
-		out.ErrorResult(err, "CONFIG_SAVE_ERROR")
-		return err
+		return lazyerr.Wrap(lazyerr.ConfigSaveError, err)
 	}
 
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"key":     key,
-			"value":   value,
-			"updated": true,
-		})
+		return out.JSON(schema.ConfigUnset{Key: key, Unset: true})
 	}
 
-	out.Success(fmt.Sprintf("Set %s = %s", key, value))
+	out.Success(fmt.Sprintf("Unset %s", key))
+
+	return nil
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	configPath := getConfigPath()
+
+	_, statErr := os.Stat(configPath)
+	exists := statErr == nil
 
+	issues, err := config.ValidateFile(configPath)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigParseError, err)
+	}
+	if issues == nil {
+		issues = []config.Issue{}
+	}
+
+	valid := !config.HasErrors(issues)
+
+	if jsonOutput {
+		if err := out.JSON(schema.ConfigValidate{Path: configPath, Exists: exists, Valid: valid, Issues: issues}); err != nil {
+			return err
+		}
+	} else {
+		if !exists {
+			out.Dim(fmt.Sprintf("%s does not exist; using defaults", configPath))
+			return nil
+		}
+
+		for _, issue := range issues {
+			if issue.Severity == config.SeverityError {
+				out.Error(issue.String())
+			} else {
+				out.Warning(issue.String())
+			}
+		}
+
+		if valid {
+			out.Success(fmt.Sprintf("%s is valid", configPath))
+		}
+	}
+
+	if !valid {
+		errorCount := 0
+		for _, issue := range issues {
+			if issue.Severity == config.SeverityError {
+				errorCount++
+			}
+		}
+		return lazyerr.New(lazyerr.ConfigInvalid, "%s: %d error(s) found", configPath, errorCount).
+			WithDetail("errors", errorCount).
+			MarkReported()
+	}
+
+	return nil
+}
+
+func runConfigSetKey(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	providerName := args[0]
+
+	cfg, err := config.LoadRaw(cfgFile)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	provider, exists := cfg.Providers[providerName]
+	switch {
+	case setKeyForge:
+		if !slices.Contains(config.ForgeTypes, providerName) {
+			return lazyerr.New(lazyerr.InvalidArgument, "unknown forge '%s'. Valid forges: %s", providerName, strings.Join(config.ForgeTypes, ", "))
+		}
+	case setKeyTracker:
+		if !slices.Contains(config.TicketTrackers, providerName) {
+			return lazyerr.New(lazyerr.InvalidArgument, "unknown tracker '%s'. Valid trackers: %s", providerName, strings.Join(config.TicketTrackers, ", "))
+		}
+	case !exists:
+		return lazyerr.New(lazyerr.InvalidProvider, "unknown provider '%s'", providerName)
+	}
+
+	what := "API key"
+	if setKeyForge || setKeyTracker {
+		what = "Token"
+	}
+
+	var key string
+	if len(args) > 1 {
+		key = args[1]
+	} else if out.IsTTY() {
+		form := tui.SecretForm(fmt.Sprintf("%s for %s", what, providerName), &key)
+		if err := form.Run(); err != nil {
+			return err
+		}
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return lazyerr.Wrap(lazyerr.ReadError, err)
+		}
+		key = line
+	}
+
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return lazyerr.New(lazyerr.EmptyKey, "%s cannot be empty", what)
+	}
+
+	if setKeyForge {
+		return setServiceToken(out, cfg, "forge", providerName, key)
+	}
+	if setKeyTracker {
+		return setServiceToken(out, cfg, "tracker", providerName, key)
+	}
+
+	if err := config.SetKeyringSecret(providerName, key); err != nil {
+		return lazyerr.Wrap(lazyerr.KeyringError, err)
+	}
+
+	provider.APIKey = config.KeyringRef(providerName)
+	cfg.Providers[providerName] = provider
+
+	if err := cfg.SaveTo(cfgFile); err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigSaveError, err)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.ConfigKey{Provider: providerName, APIKey: provider.APIKey, Stored: true})
+	}
+
+	out.Success(fmt.Sprintf("Stored API key for %s in the OS keyring", providerName))
+	out.Dim(fmt.Sprintf("  api_key = %s", provider.APIKey))
+
+	return nil
+}
+
+// setServiceToken stores the token of a forge or tracker (kind) in the
+// keyring, under "<kind>-<name>" so it cannot clash with a provider's key
+func setServiceToken(out *output.Output, cfg *config.Config, kind, name, token string) error {
+	secret := kind + "-" + name
+	if err := config.SetKeyringSecret(secret, token); err != nil {
+		return lazyerr.Wrap(lazyerr.KeyringError, err)
+	}
+
+	ref := config.KeyringRef(secret)
+	switch kind {
+	case "forge":
+		if cfg.Forges == nil {
+			cfg.Forges = map[string]config.ForgeConfig{}
+		}
+		f := cfg.Forges[name]
+		f.Token = ref
+		cfg.Forges[name] = f
+	case "tracker":
+		if cfg.Tickets == nil {
+			cfg.Tickets = map[string]config.TicketConfig{}
+		}
+		t := cfg.Tickets[name]
+		t.Token = ref
+		cfg.Tickets[name] = t
+	}
+
+	if err := cfg.SaveTo(cfgFile); err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigSaveError, err)
+	}
+
+	if jsonOutput {
+		result := schema.ConfigToken{Token: ref, Stored: true}
+		if kind == "forge" {
+			result.Forge = name
+		} else {
+			result.Tracker = name
+		}
+		return out.JSON(result)
+	}
+
+	out.Success(fmt.Sprintf("Stored token for %s in the OS keyring", name))
+	out.Dim(fmt.Sprintf("  token = %s", ref))
+
+	return nil
+}
+
+func runConfigTrust(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.NotGitRepo, err)
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+	result := schema.ConfigTrust{Path: cfg.RepoConfigPath, Hooks: cfg.RepoHooks}
+	if result.Hooks == nil {
+		result.Hooks = map[string][]string{}
+	}
+
+	if trustRevoke {
+		if err := state.TrustHooks(state.UserDir(), commonDir, ""); err != nil {
+			return lazyerr.Wrap(lazyerr.StateSaveError, err)
+		}
+		if jsonOutput {
+			return out.JSON(result)
+		}
+		out.Success("The repository's hooks will be skipped")
+		return nil
+	}
+
+	hash := cfg.RepoHooksHash()
+	if hash == "" {
+		return lazyerr.New(lazyerr.InvalidArgument, "the repository config sets no hooks")
+	}
+
+	if out.IsTTY() && !trustYes && !jsonOutput {
+		out.Info(fmt.Sprintf("Hooks of %s:", cfg.RepoConfigPath))
+		for _, event := range config.HookEvents {
+			for _, command := range cfg.RepoHookCommands(event) {
+				out.Println(fmt.Sprintf("  %-12s %s", event, command))
+			}
+		}
+		var proceed bool
+		if err := tui.ConfirmForm("Run these commands with your environment, API keys included?", &proceed).Run(); err != nil {
+			return err
+		}
+		if !proceed {
+			return lazyerr.New(lazyerr.Cancelled, "trust cancelled")
+		}
+	}
+
+	if err := state.TrustHooks(state.UserDir(), commonDir, hash); err != nil {
+		return lazyerr.Wrap(lazyerr.StateSaveError, err)
+	}
+	result.Trusted = true
+	if jsonOutput {
+		return out.JSON(result)
+	}
+	out.Success("The repository's hooks will run")
 	return nil
 }
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var debugCmd = &cobra.Command{
+	Use:    "debug",
+	Short:  "Debugging and diagnostics tools",
+	Hidden: true,
+}
+
+var debugProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Profile common operations in the current repository",
+	Long: `Run common operations (worktree list, status enrichment, selector render)
+under pprof and write CPU and heap profiles to disk.
+
+Attach the generated files to issue reports about slow repositories.
+
+Example:
+  lazywork debug profile
+  lazywork debug profile --iterations 20 --cpu cpu.pprof --heap heap.pprof`,
+	Args: cobra.NoArgs,
+	RunE: runDebugProfile,
+}
+
+var (
+	profileCPUFile    string
+	profileHeapFile   string
+	profileIterations int
+)
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+	debugCmd.AddCommand(debugProfileCmd)
+
+	debugProfileCmd.Flags().StringVar(&profileCPUFile, "cpu", "lazywork-cpu.pprof", "CPU profile output file")
+	debugProfileCmd.Flags().StringVar(&profileHeapFile, "heap", "lazywork-heap.pprof", "Heap profile output file")
+	debugProfileCmd.Flags().IntVarP(&profileIterations, "iterations", "n", 5, "Number of times to run each operation")
+}
+
+func runDebugProfile(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	if profileIterations < 1 {
+		return lazyerr.New(lazyerr.InvalidArgument, "iterations must be at least 1")
+	}
+
+	cpuFile, err := os.Create(profileCPUFile)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ProfileError, err)
+	}
+	defer cpuFile.Close()
+
+	if err := pprof.StartCPUProfile(cpuFile); err != nil {
+		return lazyerr.Wrap(lazyerr.ProfileError, err)
+	}
+
+	var worktrees []git.Worktree
+	operations := []struct {
+		name string
+		run  func() error
+	}{
+		{"list", func() error {
+			var err error
+			worktrees, err = git.ListWorktrees(ctx)
+			return err
+		}},
+		{"status", func() error {
+			git.StatusAll(ctx, worktrees, "", git.DefaultStatusWorkers)
+			return nil
+		}},
+		{"selector", func() error {
+			var selected string
+			_ = tui.WorktreeSelectForm(worktrees, nil, &selected).View()
+			return nil
+		}},
+	}
+
+	timings := make([]schema.ProfileTiming, 0, len(operations))
+	for _, op := range operations {
+		start := time.Now()
+		for i := 0; i < profileIterations; i++ {
+			if err := op.run(); err != nil {
+				pprof.StopCPUProfile()
+				return lazyerr.Wrap(lazyerr.ProfileError, err)
+			}
+		}
+		total := time.Since(start)
+		timings = append(timings, schema.ProfileTiming{
+			Operation: op.name,
+			Runs:      profileIterations,
+			Total:     total,
+			Average:   total / time.Duration(profileIterations),
+		})
+	}
+
+	pprof.StopCPUProfile()
+
+	heapFile, err := os.Create(profileHeapFile)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ProfileError, err)
+	}
+	defer heapFile.Close()
+
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(heapFile); err != nil {
+		return lazyerr.Wrap(lazyerr.ProfileError, err)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.Profile{
+			Worktrees:   len(worktrees),
+			Timings:     timings,
+			CPUProfile:  profileCPUFile,
+			HeapProfile: profileHeapFile,
+		})
+	}
+
+	out.Bold(fmt.Sprintf("Profiled %d worktrees (%d runs each):", len(worktrees), profileIterations))
+	out.Println()
+	for _, t := range timings {
+		out.Print("  %-10s avg %-12s total %s\n", t.Operation, t.Average, t.Total)
+	}
+	out.Println()
+	out.Success(fmt.Sprintf("CPU profile:  %s", profileCPUFile))
+	out.Success(fmt.Sprintf("Heap profile: %s", profileHeapFile))
+	out.Dim("Inspect with: go tool pprof " + profileCPUFile)
+
+	return nil
+}
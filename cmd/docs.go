@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/docs"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var docsCmd = &cobra.Command{
+	Use:   "docs [--man | --markdown] <dir>",
+	Short: "Generate man pages or Markdown docs for every command",
+	Long: `Write a page for every lazywork command into dir: Markdown pages by
+default, or man pages (section 1) with --man. The pages are generated from
+the commands themselves, so they always list the actual flags.
+
+Man pages are dated from SOURCE_DATE_EPOCH when it is set, for
+reproducible package builds.
+
+Example:
+  lazywork docs --man ./man
+  lazywork docs --markdown ./docs/cli`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDocs,
+}
+
+var (
+	docsMan      bool
+	docsMarkdown bool
+)
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+
+	docsCmd.Flags().BoolVar(&docsMan, "man", false, "Write man pages")
+	docsCmd.Flags().BoolVar(&docsMarkdown, "markdown", false, "Write Markdown pages (default)")
+	docsCmd.MarkFlagsMutuallyExclusive("man", "markdown")
+}
+
+func runDocs(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	dir := args[0]
+
+	var files []string
+	var err error
+	if docsMan {
+		files, err = docs.WriteMan(rootCmd, dir, "lazywork "+Version, docsDate())
+	} else {
+		files, err = docs.WriteMarkdown(rootCmd, dir)
+	}
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.PathError, err).WithDetail("dir", dir)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.Docs{Dir: dir, Files: files})
+	}
+
+	out.Success(fmt.Sprintf("Wrote %d pages to %s", len(files), dir))
+	return nil
+}
+
+// docsDate returns the date man pages carry: SOURCE_DATE_EPOCH if set
+// (https://reproducible-builds.org/specs/source-date-epoch/), else today
+func docsDate() time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(epoch, 0).UTC()
+	}
+	return time.Now()
+}
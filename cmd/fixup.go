@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/miltonparedes/lazywork/pkg/worktree"
+	"github.com/spf13/cobra"
+)
+
+// maxFixupCommits bounds the commits offered by 'fixup'
+const maxFixupCommits = 20
+
+var fixupCmd = &cobra.Command{
+	Use:   "fixup [commit]",
+	Short: "Commit staged changes as a fixup for an earlier commit",
+	Long: `Commit the staged changes as a "fixup!" commit for an earlier commit, and
+fold it into that commit with an autosquash rebase.
+
+Without a commit you'll pick one from the commits of the current branch
+that are not on the main branch (the last 20 on the main branch itself).
+
+On a terminal you'll be asked whether to rebase right away; --rebase does
+so without asking and --no-rebase leaves the fixup commit for later. The
+rebase needs no editor, and uncommitted changes are stashed meanwhile. If
+it stops on conflicts, resolve them with 'lazywork resolve' and run 'git
+rebase --continue', or give up with 'git rebase --abort'.
+
+Example:
+  lazywork fixup
+  lazywork fixup HEAD~2 --rebase
+  lazywork fixup a1b2c3d --no-rebase`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runFixup,
+}
+
+var (
+	fixupRebase   bool
+	fixupNoRebase bool
+)
+
+func init() {
+	rootCmd.AddCommand(fixupCmd)
+
+	fixupCmd.Flags().BoolVar(&fixupRebase, "rebase", false, "Run the autosquash rebase without asking")
+	fixupCmd.Flags().BoolVar(&fixupNoRebase, "no-rebase", false, "Only make the fixup commit")
+	fixupCmd.MarkFlagsMutuallyExclusive("rebase", "no-rebase")
+}
+
+func runFixup(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	if diff, err := git.GetStagedDiff(ctx); err != nil {
+		return lazyerr.Wrap(lazyerr.CommitError, err)
+	} else if strings.TrimSpace(diff) == "" {
+		return lazyerr.New(lazyerr.NothingStaged, "no staged changes for a fixup")
+	}
+
+	cfg, _ := loadConfig(ctx)
+	target, err := fixupTarget(ctx, out, worktree.New(cfg).MainBranch(ctx), args)
+	if err != nil {
+		return err
+	}
+
+	if err := git.CommitFixup(ctx, target.Hash); err != nil {
+		return lazyerr.Wrap(lazyerr.CommitError, err)
+	}
+	hash, _ := git.ShortHash(ctx, "HEAD")
+	result := schema.Fixup{Commit: hash, Target: target.Hash, Subject: target.Subject}
+
+	rebase := fixupRebase
+	if !fixupRebase && !fixupNoRebase && out.IsTTY() && !jsonOutput {
+		if err := tui.ConfirmForm(fmt.Sprintf("Squash it into %.7s %s now?", target.Hash, target.Subject), &rebase).Run(); err != nil {
+			return err
+		}
+	}
+	if rebase {
+		if err := rebaseFixup(ctx, out, target.Hash); err != nil {
+			return err
+		}
+		result.Rebased = true
+		result.Commit, _ = git.ShortHash(ctx, "HEAD")
+	}
+
+	if jsonOutput {
+		return out.JSON(result)
+	}
+	if result.Rebased {
+		out.Success(fmt.Sprintf("Squashed the fixup into %.7s %s", target.Hash, target.Subject))
+		return nil
+	}
+	out.Success(fmt.Sprintf("Committed %s: fixup! %s", hash, target.Subject))
+	out.Dim(fmt.Sprintf("  Squash it later with: git rebase -i --autosquash %.7s^", target.Hash))
+	return nil
+}
+
+// fixupTarget returns the commit named in args, or the one picked from the
+// recent commits of the current branch
+func fixupTarget(ctx context.Context, out *output.Output, base string, args []string) (*git.LogEntry, error) {
+	if len(args) > 0 {
+		commits, err := git.Log(ctx, args[0], 1)
+		if err != nil || len(commits) == 0 {
+			return nil, lazyerr.New(lazyerr.InvalidArgument, "unknown commit '%s'", args[0]).WithDetail("commit", args[0])
+		}
+		return &commits[0], nil
+	}
+	if !out.IsTTY() {
+		return nil, lazyerr.New(lazyerr.NameRequired, "commit required (use: lazywork fixup <commit>)")
+	}
+
+	rev := "HEAD"
+	if branch, _ := git.CurrentBranch(ctx); branch != base {
+		rev = base + "..HEAD"
+	}
+	commits, err := git.Log(ctx, rev, maxFixupCommits)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	if len(commits) == 0 {
+		return nil, lazyerr.New(lazyerr.NameRequired, "no commits on this branch that aren't on %s", base).
+			WithHint("Name the commit: lazywork fixup <commit>")
+	}
+
+	var hash string
+	if err := tui.CommitSelectForm("Fix up which commit?", commits, &hash).Run(); err != nil {
+		return nil, err
+	}
+	for i := range commits {
+		if commits[i].Hash == hash {
+			return &commits[i], nil
+		}
+	}
+	return nil, lazyerr.New(lazyerr.Cancelled, "no commit selected")
+}
+
+// rebaseFixup folds the fixup commits for target into it
+func rebaseFixup(ctx context.Context, out *output.Output, target string) error {
+	// A root commit has no parent to rebase onto
+	upstream := ""
+	if parent, err := git.ResolveCommit(ctx, target+"^"); err == nil {
+		upstream = parent
+	}
+
+	stop := out.Spinner("Rebasing with --autosquash")
+	err := git.RebaseAutosquash(ctx, upstream)
+	stop()
+	if err == nil {
+		return nil
+	}
+	if git.RebaseInProgress(ctx) {
+		return lazyerr.Wrap(lazyerr.MergeConflict, err).
+			WithHint("Resolve the conflicts with 'lazywork resolve', then run 'git rebase --continue' (or 'git rebase --abort')")
+	}
+	return lazyerr.Wrap(lazyerr.CommitError, err).
+		WithHint(fmt.Sprintf("The fixup commit is kept; squash it with: git rebase -i --autosquash %.7s^", target))
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/miltonparedes/lazywork/internal/forge"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"golang.org/x/sync/errgroup"
+)
+
+// forgeRemote is the remote whose URL identifies the repository on its forge
+const forgeRemote = "origin"
+
+// checksWorkers limits the concurrent CI status requests of 'worktree list'
+const checksWorkers = 4
+
+// detectForge returns the forge hosting the current repository
+func detectForge(ctx context.Context, cfg *config.Config) (forge.Forge, error) {
+	remote, err := git.RemoteURL(ctx, forgeRemote)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.NoForge, err).WithDetail("remote", forgeRemote)
+	}
+	f, err := forge.Detect(ctx, cfg, remote)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.NoForge, err).WithDetail("remote", remote)
+	}
+	return f, nil
+}
+
+// fetchIssue reads issue number from the forge
+func fetchIssue(ctx context.Context, f forge.Forge, number int) (*forge.Issue, error) {
+	issue, err := f.Issue(ctx, number)
+	if errors.Is(err, forge.ErrNotFound) {
+		return nil, lazyerr.New(lazyerr.IssueNotFound, "issue #%d not found", number).WithDetail("issue", number)
+	}
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.ForgeError, err)
+	}
+	return issue, nil
+}
+
+// worktreeChecks reads the CI status of every worktree branch. Entries for
+// bare and detached worktrees, and for failed requests, are nil; the first
+// failure is returned alongside.
+func worktreeChecks(ctx context.Context, f forge.Forge, worktrees []git.Worktree) ([]*forge.Checks, error) {
+	checks := make([]*forge.Checks, len(worktrees))
+	errs := make([]error, len(worktrees))
+
+	var g errgroup.Group
+	g.SetLimit(checksWorkers)
+	for i, wt := range worktrees {
+		if wt.Bare || wt.Branch == "" {
+			continue
+		}
+		g.Go(func() error {
+			checks[i], errs[i] = f.Checks(ctx, wt.Branch)
+			return nil
+		})
+	}
+	g.Wait()
+
+	return checks, firstError(errs)
+}
+
+// mergedPullRequests finds the merged pull request from every worktree
+// branch into base. Entries for branches without one, and for failed
+// requests, are nil; the first failure is returned alongside.
+func mergedPullRequests(ctx context.Context, f forge.Forge, worktrees []git.Worktree, base string) ([]*forge.PullRequest, error) {
+	prs := make([]*forge.PullRequest, len(worktrees))
+	errs := make([]error, len(worktrees))
+
+	var g errgroup.Group
+	g.SetLimit(checksWorkers)
+	for i, wt := range worktrees {
+		if wt.Bare || wt.Branch == "" {
+			continue
+		}
+		g.Go(func() error {
+			pr, err := f.MergedPullRequest(ctx, wt.Branch, base)
+			if !errors.Is(err, forge.ErrNotFound) {
+				prs[i], errs[i] = pr, err
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	return prs, firstError(errs)
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checksColumn formats a CI status for the CHECKS column of a listing
+func checksColumn(c *forge.Checks) string {
+	if c == nil {
+		return ""
+	}
+	switch c.State {
+	case forge.ChecksSuccess:
+		return fmt.Sprintf("✓ %d passed", c.Passed)
+	case forge.ChecksFailure:
+		return fmt.Sprintf("✗ %d/%d failed", c.Failed, c.Total)
+	case forge.ChecksPending:
+		return fmt.Sprintf("● %d pending", c.Pending)
+	default:
+		return "-"
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/githook"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/spf13/cobra"
+)
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Manage the git hook that drafts commit messages",
+	Long: `Manage the prepare-commit-msg git hook that fills the message of a
+plain 'git commit' with an AI draft, so the editor opens with a message
+ready to review.
+
+The hook runs 'lazywork commit --message-only --hook'. It does nothing
+when a message is given with -m, -F or -C, for merges, squashes and
+amends, and it never makes the commit fail.`,
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the prepare-commit-msg hook",
+	Long: `Install the prepare-commit-msg hook in the repository's hooks directory.
+
+The hooks directory is shared by the repository and all of its worktrees
+and honours core.hooksPath. An existing hook that lazywork did not write
+is only replaced with --force, after backing it up to
+prepare-commit-msg.lazywork.bak. Running install again is a no-op.
+
+Example:
+  lazywork hook install
+  lazywork hook install --force`,
+	Args: cobra.NoArgs,
+	RunE: runHookInstall,
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the prepare-commit-msg hook",
+	Long: `Remove the prepare-commit-msg hook written by 'lazywork hook install',
+putting back the hook it replaced, if any. Hooks lazywork did not write
+are left alone.`,
+	Args: cobra.NoArgs,
+	RunE: runHookUninstall,
+}
+
+var hookForce bool
+
+func init() {
+	rootCmd.AddCommand(hookCmd)
+	hookCmd.AddCommand(hookInstallCmd)
+	hookCmd.AddCommand(hookUninstallCmd)
+
+	hookInstallCmd.Flags().BoolVar(&hookForce, "force", false, "Replace an existing hook, backing it up first")
+}
+
+func runHookInstall(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	dir, err := git.HooksDir(ctx)
+	if err != nil {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "lazywork"
+	}
+
+	result, err := githook.Install(dir, exe, hookForce)
+	if errors.Is(err, githook.ErrForeignHook) {
+		return lazyerr.Wrap(lazyerr.GitHookExists, err)
+	}
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.GitHookError, err)
+	}
+
+	if jsonOutput {
+		return out.JSON(result)
+	}
+
+	if !result.Changed {
+		out.Success("The lazywork hook is already installed in " + result.Hook)
+		return nil
+	}
+	out.Success("Installed the lazywork hook in " + result.Hook)
+	if result.Backup != "" {
+		out.Dim("  backup: " + result.Backup)
+	}
+	out.Info("Run 'git commit' to start from an AI-drafted message")
+	return nil
+}
+
+func runHookUninstall(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	dir, err := git.HooksDir(ctx)
+	if err != nil {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	result, err := githook.Uninstall(dir)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.GitHookError, err)
+	}
+
+	if jsonOutput {
+		return out.JSON(result)
+	}
+
+	if !result.Removed {
+		out.Info("No lazywork hook installed in " + dir)
+		return nil
+	}
+	out.Success("Removed the lazywork hook from " + result.Hook)
+	if result.Restored != "" {
+		out.Dim("  restored the previous hook")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/miltonparedes/lazywork/internal/env"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/worktree"
+	"github.com/spf13/cobra"
+)
+
+var noHooks bool
+
+func init() {
+	worktreeCmd.PersistentFlags().BoolVar(&noHooks, "no-hooks", false, "Skip the hooks configured for this operation")
+}
+
+// newManager returns the manager running worktree operations for cmd. Hook
+// output goes to stderr so --json output stays parseable.
+func newManager(cmd *cobra.Command, out *output.Output, cfg *config.Config) *worktree.Manager {
+	return &worktree.Manager{
+		Config:     cfg,
+		Reporter:   out,
+		HookOutput: cmd.ErrOrStderr(),
+		NoHooks:    noHooks,
+	}
+}
+
+// hookWorktree describes the worktree at path for hook environments,
+// reserving its port block if it has none
+func hookWorktree(ctx context.Context, cfg *config.Config, path, branch string) env.Worktree {
+	return worktree.New(cfg).Env(ctx, path, branch)
+}
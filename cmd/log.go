@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/miltonparedes/lazywork/pkg/worktree"
+	"github.com/spf13/cobra"
+)
+
+// defaultLogCommits is how many commits 'log' shows on the main branch,
+// which has no base to stop at
+const defaultLogCommits = 20
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show the commits of the current branch",
+	Long: `Show the commits of the current branch that are not on the main branch
+(or on --base), newest first. On the main branch itself the last 20 are
+shown.
+
+With --summarize the AI provider groups the commits into themes, each with
+a short description and its commits, which is a good start for a pull
+request description.
+
+Example:
+  lazywork log
+  lazywork log --summarize
+  lazywork log -n 50 --base develop`,
+	Args: cobra.NoArgs,
+	RunE: runLog,
+}
+
+var (
+	logMax       int
+	logBase      string
+	logSummarize bool
+	logProvider  string
+	logModel     string
+)
+
+func init() {
+	rootCmd.AddCommand(logCmd)
+
+	logCmd.Flags().IntVarP(&logMax, "max-count", "n", 0, "Show at most this many commits (default: all of the branch, 20 on the main branch)")
+	logCmd.Flags().StringVar(&logBase, "base", "", "Branch the current branch started from (default: the main branch)")
+	logCmd.Flags().BoolVar(&logSummarize, "summarize", false, "Ask the AI provider to group the commits into themes")
+	logCmd.Flags().StringVar(&logProvider, "provider", "", "AI provider to use (default: models.log, then default_provider)")
+	logCmd.Flags().StringVar(&logModel, "model", "", "Model to use (default: models.log, then default_model)")
+	logCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in commit messages to the AI provider unmasked")
+	logCmd.RegisterFlagCompletionFunc("provider", completeProviders)
+}
+
+func runLog(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if logMax < 0 {
+		return lazyerr.New(lazyerr.InvalidArgument, "--max-count must not be negative")
+	}
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+	branch, err := git.CurrentBranch(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	base := logBase
+	if base == "" {
+		base = worktree.New(cfg).MainBranch(ctx)
+	} else if _, err := git.ResolveCommit(ctx, base); err != nil {
+		return lazyerr.New(lazyerr.BranchNotFound, "branch '%s' not found", base).WithDetail("branch", base)
+	}
+
+	rev, max := "HEAD", logMax
+	if branch != base {
+		rev = base + "..HEAD"
+	} else if max == 0 {
+		max = defaultLogCommits
+	}
+	commits, err := git.Log(ctx, rev, max)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	if commits == nil {
+		commits = []git.LogEntry{}
+	}
+
+	var summary string
+	if logSummarize && len(commits) > 0 {
+		lines := make([]string, len(commits))
+		for i, c := range commits {
+			lines[i] = fmt.Sprintf("%.7s %s", c.Hash, c.Subject)
+		}
+		p, req, err := newAIRequest(out, cfg, "log", logProvider, logModel,
+			commitmsg.LogMessages(branch, lines, cfg.Commit.Language))
+		if err != nil {
+			return err
+		}
+		out.Progress(fmt.Sprintf("Grouping %d commits with %s (%s)", len(commits), p.Name(), req.Model))
+		resp, err := p.Complete(ctx, req)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.ProviderError, err).WithDetail("provider", p.Name())
+		}
+		summary = commitmsg.Clean(resp.Content)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.Log{Branch: branch, Base: base, Commits: commits, Summary: summary})
+	}
+
+	if len(commits) == 0 {
+		out.Info(fmt.Sprintf("No commits on %s that aren't on %s", branch, base))
+		return nil
+	}
+	if summary != "" {
+		out.Println(summary)
+		return nil
+	}
+	rows := make([][]string, len(commits))
+	for i, c := range commits {
+		rows[i] = []string{fmt.Sprintf("%.7s", c.Hash), c.Date.Local().Format("2006-01-02 15:04"), c.Author, c.Subject}
+	}
+	out.Table([]string{"COMMIT", "DATE", "AUTHOR", "SUBJECT"}, rows)
+	return nil
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/forge"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Work with pull requests",
+	Long: `Work with pull requests (merge requests on GitLab) on the forge hosting
+the repository.
+
+The forge is detected from the origin remote (a host with "github" or
+"gitlab" in its name); set forge in the config for other hosts. The token
+is read from forges.<forge>.token, then $GITHUB_TOKEN or $GH_TOKEN for
+GitHub and $GITLAB_TOKEN for GitLab, then the login of the gh or glab CLI.`,
+}
+
+var prCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Push a worktree branch and open a pull request",
+	Long: `Push a worktree's branch to origin and open a pull request for it.
+
+Without a name the current branch is used. The title defaults to the
+subject of the branch's latest commit, and the pull request targets the
+main branch (main/master, or the main_branch setting) unless --base is
+given. An issue linked with 'worktree add --issue' or 'worktree note
+--issue' is closed by the pull request.
+
+Example:
+  lazywork pr create feature-auth
+  lazywork pr create --draft --title "Add OAuth login"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPRCreate,
+}
+
+var (
+	prTitle  string
+	prBody   string
+	prBase   string
+	prDraft  bool
+	prNoPush bool
+)
+
+func init() {
+	rootCmd.AddCommand(prCmd)
+	prCmd.AddCommand(prCreateCmd)
+
+	prCreateCmd.Flags().StringVarP(&prTitle, "title", "t", "", "Pull request title (default: the latest commit subject)")
+	prCreateCmd.Flags().StringVar(&prBody, "body", "", "Pull request description")
+	prCreateCmd.Flags().StringVar(&prBase, "base", "", "Branch to merge into (default: the main branch)")
+	prCreateCmd.Flags().BoolVar(&prDraft, "draft", false, "Open the pull request as a draft")
+	prCreateCmd.Flags().BoolVar(&prNoPush, "no-push", false, "Don't push the branch first")
+	prCreateCmd.RegisterFlagCompletionFunc("base", completeBranches)
+	prCreateCmd.ValidArgsFunction = completeWorktreeNames
+}
+
+func runPRCreate(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	var branch, path string
+	if len(args) > 0 {
+		worktrees, err := git.ListWorktrees(ctx)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.WorktreeListError, err)
+		}
+		target := matchWorktree(worktrees, args[0])
+		if target == nil {
+			return lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", args[0]).WithDetail("name", args[0])
+		}
+		branch, path = target.Branch, target.Path
+	} else {
+		branch, err = git.CurrentBranch(ctx)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.BranchError, err)
+		}
+		path, _ = git.GetRepoRoot(ctx)
+	}
+	if branch == "" {
+		return lazyerr.New(lazyerr.DetachedHead, "worktree is in detached HEAD state, cannot open a pull request")
+	}
+
+	base := prBase
+	if base == "" {
+		base = cfg.MainBranch
+	}
+	if base == "" {
+		base = git.GetMainBranch(ctx)
+	}
+	if branch == base {
+		return lazyerr.New(lazyerr.InvalidArgument, "cannot open a pull request from %s into itself", branch)
+	}
+
+	f, err := detectForge(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	pr, err := openPullRequest(ctx, out, cfg, f, path, forge.PullRequestInput{
+		Title: prTitle,
+		Body:  prBody,
+		Head:  branch,
+		Base:  base,
+		Draft: prDraft,
+	}, !prNoPush)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.PullRequest{
+			Forge:  f.Name(),
+			Number: pr.Number,
+			Ref:    pr.Ref,
+			URL:    pr.URL,
+			Title:  pr.Title,
+			Branch: branch,
+			Base:   base,
+			Draft:  pr.Draft,
+		})
+	}
+
+	printPullRequest(out, f, pr)
+
+	return nil
+}
+
+// openPullRequest pushes in.Head to origin if push is set and opens a pull
+// request for it. An empty title defaults to the subject of the branch's
+// latest commit, an issue linked to the worktree at path is closed by the
+// pull request, and a tracker ticket named in the branch is linked.
+func openPullRequest(ctx context.Context, out *output.Output, cfg *config.Config, f forge.Forge, path string, in forge.PullRequestInput, push bool) (*forge.PullRequest, error) {
+	if in.Title == "" {
+		title, err := git.CommitSubject(ctx, in.Head)
+		if err != nil {
+			return nil, lazyerr.Wrap(lazyerr.BranchError, err)
+		}
+		in.Title = title
+	}
+
+	if meta := loadMeta(ctx); meta != nil && path != "" {
+		if issue := meta.Get(path).Issue; strings.Contains(issue, "/issues/") && !strings.Contains(in.Body, issue) {
+			in.Body = strings.TrimSpace(in.Body + "\n\nCloses " + issue)
+		}
+	}
+
+	branchTicket, err := branchTickets(ctx, cfg, []string{in.Head})
+	if err != nil {
+		out.Warning(fmt.Sprintf("Could not read ticket: %v", err))
+	}
+	if t := branchTicket[in.Head]; t != nil && !strings.Contains(in.Body, t.URL) {
+		in.Body = strings.TrimSpace(fmt.Sprintf("%s\n\nTicket: [%s](%s) %s", in.Body, t.Key, t.URL, t.Title))
+	}
+
+	if push {
+		out.Progress(fmt.Sprintf("Pushing %s to %s", in.Head, forgeRemote))
+		if err := git.Push(ctx, forgeRemote, in.Head); err != nil {
+			return nil, lazyerr.Wrap(lazyerr.PushError, err).WithDetail("branch", in.Head)
+		}
+	}
+
+	out.Progress(fmt.Sprintf("Opening %s %s → %s", forge.RequestNoun(f.Name()), in.Head, in.Base))
+	pr, err := f.CreatePullRequest(ctx, in)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.ForgeError, err).WithDetail("branch", in.Head)
+	}
+	return pr, nil
+}
+
+// printPullRequest reports an opened pull request
+func printPullRequest(out *output.Output, f forge.Forge, pr *forge.PullRequest) {
+	out.Success(fmt.Sprintf("Opened %s %s: %s", forge.RequestNoun(f.Name()), pr.Ref, pr.Title))
+	out.Dim("  " + pr.URL)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/miltonparedes/lazywork/internal/prompt"
+	"github.com/spf13/cobra"
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print a shell prompt segment for the current worktree",
+	Long: `Print a compact segment showing the current worktree, branch and
+'worktree use' state, for use in PS1 or starship.
+
+Repository paths are cached per directory, so after the first call in a
+directory the segment is built without running git at all. Outside a git
+repository nothing is printed.
+
+Formats: plain (default), powerline, json
+
+Examples:
+  # Bash
+  PS1='$(lazywork prompt) \$ '
+
+  # Starship (~/.config/starship.toml)
+  [custom.lazywork]
+  command = "lazywork prompt"
+  when = "git rev-parse --git-dir"`,
+	Args: cobra.NoArgs,
+	RunE: runPrompt,
+}
+
+var promptFormat string
+
+func init() {
+	rootCmd.AddCommand(promptCmd)
+	promptCmd.Flags().StringVar(&promptFormat, "format", prompt.FormatPlain, "Segment format (plain, powerline, json)")
+}
+
+func runPrompt(cmd *cobra.Command, args []string) error {
+	format := promptFormat
+	if jsonOutput {
+		format = prompt.FormatJSON
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+
+	// A prompt segment must never fail the prompt: outside a repository
+	// (or on any git error) print nothing
+	info, err := prompt.Gather(cmd.Context(), cwd)
+	if err != nil {
+		return nil
+	}
+
+	segment, err := prompt.Render(info, format)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), segment)
+	return nil
+}
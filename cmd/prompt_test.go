@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/internal/git/gittest"
+)
+
+func TestPromptOutput(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	gitDir := filepath.Join(dir, ".git")
+	if err := os.MkdirAll(gitDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	gittest.New(t).
+		On("rev-parse --show-toplevel --absolute-git-dir --git-common-dir", dir+"\n"+gitDir+"\n"+gitDir+"\n")
+
+	stdout, _, err := execute(t, "prompt")
+	if err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if stdout != "main\n" {
+		t.Errorf("stdout = %q, want the segment for main", stdout)
+	}
+}
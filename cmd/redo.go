@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var lastCmd = &cobra.Command{
+	Use:   "last",
+	Short: "Show the most recent AI-generated message",
+	Long: `Show the most recent message generated by an AI provider in this
+repository, without making a new request.
+
+Every generation is kept in the repository's lazywork state with the
+command that asked for it, the provider and model, a hash of the prompt
+and the output. The last 50 are kept.
+
+Example:
+  lazywork last
+  lazywork last --command resolve`,
+	Args: cobra.NoArgs,
+	RunE: runLast,
+}
+
+var redoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Commit again with the last generated commit message",
+	Long: `Commit the staged changes with the most recent message generated by
+'lazywork commit', without making a new request. Useful when a commit
+was cancelled, failed in a hook or was undone.
+
+The message is opened in the editor first, as with 'lazywork commit'.
+With --amend it replaces the last commit's message instead, keeping its
+trailers.
+
+Example:
+  lazywork redo
+  lazywork redo --amend --yes`,
+	Args: cobra.NoArgs,
+	RunE: runRedo,
+}
+
+var (
+	lastCommand string
+
+	redoAmend  bool
+	redoYes    bool
+	redoDryRun bool
+)
+
+func init() {
+	rootCmd.AddCommand(lastCmd)
+	rootCmd.AddCommand(redoCmd)
+
+	lastCmd.Flags().StringVar(&lastCommand, "command", "", "Only consider generations made by this command, e.g. commit")
+
+	redoCmd.Flags().BoolVar(&redoAmend, "amend", false, "Rewrite the last commit's message, adding the staged changes")
+	redoCmd.Flags().BoolVarP(&redoYes, "yes", "y", false, "Commit the message without review")
+	redoCmd.Flags().BoolVar(&redoDryRun, "dry-run", false, "Print the message without committing")
+}
+
+// lastGeneration returns the most recent generation made by command in
+// the current repository
+func lastGeneration(cmd *cobra.Command, command string) (state.Generation, error) {
+	commonDir, err := git.GetCommonDir(cmd.Context())
+	if err != nil {
+		return state.Generation{}, lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	log, err := state.LoadGenerations(state.Dir(commonDir))
+	if err != nil {
+		return state.Generation{}, lazyerr.Wrap(lazyerr.StateReadError, err)
+	}
+	gen, ok := log.Last(command)
+	if !ok {
+		if command != "" {
+			return state.Generation{}, lazyerr.New(lazyerr.NoGeneration, "no messages generated by '%s' in this repository", command)
+		}
+		return state.Generation{}, lazyerr.New(lazyerr.NoGeneration, "no generated messages in this repository")
+	}
+	return gen, nil
+}
+
+func runLast(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+
+	gen, err := lastGeneration(cmd, lastCommand)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return out.JSON(gen)
+	}
+
+	out.Dim(fmt.Sprintf("%s · %s/%s · %s", gen.Command, gen.Provider, gen.Model, gen.Time.Local().Format("2006-01-02 15:04")))
+	out.Println(strings.TrimSpace(gen.Output))
+	return nil
+}
+
+func runRedo(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	gen, err := lastGeneration(cmd, "commit")
+	if err != nil {
+		return err
+	}
+
+	var trailers []string
+	if redoAmend {
+		previous, err := git.CommitMessage(ctx, "HEAD")
+		if err != nil {
+			return lazyerr.New(lazyerr.CommitError, "there is no commit to amend")
+		}
+		_, trailers = commitmsg.SplitTrailers(previous)
+	} else {
+		diff, err := git.GetStagedDiff(ctx)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.CommitError, err)
+		}
+		if strings.TrimSpace(diff) == "" {
+			return lazyerr.New(lazyerr.NothingStaged, "no staged changes to commit").
+				WithHint("Stage changes with 'git add', or use --amend to reword the last commit")
+		}
+	}
+
+	style := commitStyle(ctx, out, cfg, false)
+	message := commitmsg.AddTrailers(style.Clean(gen.Output), trailers)
+
+	if redoDryRun {
+		if jsonOutput {
+			return out.JSON(schema.Message{Message: message, Amend: redoAmend})
+		}
+		out.Println(message)
+		return nil
+	}
+
+	return commitWithMessage(ctx, out, message, redoAmend, !redoYes, style)
+}
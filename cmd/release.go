@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+	"github.com/miltonparedes/lazywork/internal/forge"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/release"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var releaseCmd = &cobra.Command{
+	Use:   "release [version]",
+	Short: "Tag a release with AI-written release notes",
+	Long: `Tag HEAD as the next release, with release notes written by the AI
+provider from the commits since the last release.
+
+The last release is the highest version tag (v1.2.3 or 1.2.3) reachable
+from HEAD. Without a version the next one is suggested from the commits:
+a breaking change (type! or a BREAKING CHANGE footer) bumps the major
+version, feat the minor version and anything else the patch version.
+Gitmojis such as 💥, ✨ and 🐛 count as well. Before 1.0.0 breaking
+changes bump the minor version, and the first release is v0.1.0.
+
+The notes are shown for confirmation and become the message of an
+annotated tag. With --publish the tag is pushed to origin and a release
+is created on the forge (GitHub or GitLab) with the same notes.
+
+Example:
+  lazywork release --dry-run
+  lazywork release --bump major
+  lazywork release v2.0.0 --publish`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRelease,
+}
+
+var (
+	releaseBump     string
+	releaseDryRun   bool
+	releaseYes      bool
+	releasePublish  bool
+	releaseDraft    bool
+	releaseNoAI     bool
+	releaseProvider string
+	releaseModel    string
+)
+
+func init() {
+	rootCmd.AddCommand(releaseCmd)
+
+	releaseCmd.Flags().StringVar(&releaseBump, "bump", "", "Version part to bump instead of the suggested one: major, minor or patch")
+	releaseCmd.Flags().BoolVar(&releaseDryRun, "dry-run", false, "Print the version and notes without tagging")
+	releaseCmd.Flags().BoolVarP(&releaseYes, "yes", "y", false, "Tag without confirmation")
+	releaseCmd.Flags().BoolVar(&releasePublish, "publish", false, "Push the tag to origin and create a release on the forge")
+	releaseCmd.Flags().BoolVar(&releaseDraft, "draft", false, "Create the forge release as a draft (GitHub only, implies --publish)")
+	releaseCmd.Flags().BoolVar(&releaseNoAI, "no-ai", false, "List the commit subjects as notes instead of asking the AI provider")
+	releaseCmd.Flags().StringVar(&releaseProvider, "provider", "", "AI provider to use (default: models.release, then default_provider)")
+	releaseCmd.Flags().StringVar(&releaseModel, "model", "", "Model to use (default: models.release, then default_model)")
+	releaseCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in commit messages to the AI provider unmasked")
+	releaseCmd.RegisterFlagCompletionFunc("bump", cobra.FixedCompletions(release.Bumps, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func runRelease(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	tags, err := git.MergedTags(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.TagError, err)
+	}
+	var previousTag string
+	previous := release.Version{Prefix: "v"}
+	for _, tag := range tags {
+		if v, ok := release.ParseVersion(tag); ok {
+			previousTag, previous = tag, v
+			break
+		}
+	}
+
+	messages, err := git.CommitMessages(ctx, previousTag, "HEAD")
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.TagError, err)
+	}
+	if len(messages) == 0 {
+		return lazyerr.New(lazyerr.NothingToRelease, "nothing was committed since %s", previousTag).
+			WithDetail("previous", previousTag)
+	}
+
+	version, bump := release.Propose(previous, messages)
+	if releaseBump != "" {
+		if bump, err = release.ParseBump(releaseBump); err != nil {
+			return lazyerr.Wrap(lazyerr.InvalidArgument, err)
+		}
+		version = previous.Next(bump)
+	}
+	plan := schema.Release{Version: version.String(), Previous: previousTag, Bump: bump.String(), Commits: len(messages)}
+	if len(args) > 0 {
+		if releaseBump != "" {
+			return lazyerr.New(lazyerr.Usage, "give either a version or --bump")
+		}
+		if _, ok := release.ParseVersion(args[0]); !ok {
+			return lazyerr.New(lazyerr.InvalidArgument, "'%s' is not a version such as v1.2.3", args[0])
+		}
+		plan.Version, plan.Bump = args[0], ""
+	}
+	tag := plan.Version
+	if git.TagExists(ctx, tag) {
+		return lazyerr.New(lazyerr.TagExists, "tag %s already exists", tag).WithDetail("tag", tag)
+	}
+
+	publish := releasePublish || releaseDraft
+	var f forge.Forge
+	if publish && !releaseDryRun {
+		// Fail before tagging when the release can't be published
+		if f, err = detectForge(ctx, cfg); err != nil {
+			return err
+		}
+	}
+
+	var notes string
+	if releaseNoAI {
+		notes = subjectNotes(messages)
+	} else {
+		p, req, err := newAIRequest(out, cfg, "release", releaseProvider, releaseModel,
+			release.NotesMessages(tag, previousTag, messages, cfg.Commit.Language))
+		if err != nil {
+			return err
+		}
+		out.Progress(fmt.Sprintf("Writing release notes with %s (%s)", p.Name(), req.Model))
+		resp, err := p.Complete(ctx, req)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.ProviderError, err).WithDetail("provider", p.Name())
+		}
+		notes = commitmsg.Clean(resp.Content)
+	}
+	plan.Notes = notes
+
+	if releaseDryRun {
+		if jsonOutput {
+			return out.JSON(plan)
+		}
+		printReleasePlan(out, plan)
+		return nil
+	}
+
+	if out.IsTTY() && !releaseYes {
+		printReleasePlan(out, plan)
+		out.Println()
+		confirmed := false
+		if err := tui.ConfirmForm(fmt.Sprintf("Tag HEAD as %s?", tag), &confirmed).Run(); err != nil {
+			return err
+		}
+		if !confirmed {
+			return lazyerr.New(lazyerr.Cancelled, "release cancelled")
+		}
+	}
+
+	if err := git.CreateTag(ctx, tag, "Release "+tag+"\n\n"+notes); err != nil {
+		return lazyerr.Wrap(lazyerr.TagError, err).WithDetail("tag", tag)
+	}
+
+	if publish {
+		out.Progress(fmt.Sprintf("Pushing %s to %s", tag, forgeRemote))
+		if err := git.PushTag(ctx, forgeRemote, tag); err != nil {
+			return lazyerr.Wrap(lazyerr.PushError, err).WithDetail("tag", tag)
+		}
+		out.Progress(fmt.Sprintf("Creating the %s release", tag))
+		plan.Release, err = f.CreateRelease(ctx, forge.ReleaseInput{Tag: tag, Name: tag, Notes: notes, Draft: releaseDraft})
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.ForgeError, err).WithDetail("tag", tag).
+				WithHint("The tag was pushed; create the release on the forge by hand")
+		}
+	}
+
+	if jsonOutput {
+		return out.JSON(plan)
+	}
+
+	out.Success(fmt.Sprintf("Tagged %s", tag))
+	if plan.Release != nil {
+		out.Success("Published the release")
+		out.Dim("  " + plan.Release.URL)
+	} else {
+		out.Dim(fmt.Sprintf("  push it with: git push %s %s", forgeRemote, tag))
+	}
+	return nil
+}
+
+// printReleasePlan describes the release about to be tagged
+func printReleasePlan(out *output.Output, plan schema.Release) {
+	if plan.Previous == "" {
+		out.Bold(fmt.Sprintf("%s (first release, %d commits)", plan.Version, plan.Commits))
+	} else {
+		out.Bold(fmt.Sprintf("%s (%d commits since %s)", plan.Version, plan.Commits, plan.Previous))
+	}
+	out.Println()
+	out.Println(plan.Notes)
+}
+
+// subjectNotes lists the subjects of messages as Markdown bullets
+func subjectNotes(messages []string) string {
+	lines := make([]string, len(messages))
+	for i, m := range messages {
+		subject, _, _ := strings.Cut(m, "\n")
+		lines[i] = "- " + subject
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/conflict"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/miltonparedes/lazywork/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve [file...]",
+	Short: "Resolve merge conflicts with AI suggestions",
+	Long: `Walk through the conflicted files left by a merge or rebase (or only the
+given ones). For each conflict both sides are shown along with a
+resolution suggested by the AI provider, and you choose to accept it, edit
+it, keep either side or both, or skip the conflict. Files without
+remaining conflicts are staged.
+
+Nothing is committed unless you confirm it once every conflict is
+resolved. Without a terminal the suggestions are only printed, unless
+--yes accepts them all; they are still not committed.
+
+Example:
+  lazywork worktree finish feature-auth   # merge stops with conflicts
+  lazywork resolve
+  lazywork resolve src/app.go --no-ai`,
+	RunE: runResolve,
+}
+
+var (
+	resolveYes      bool
+	resolveNoAI     bool
+	resolveProvider string
+	resolveModel    string
+)
+
+// Choices offered for each conflict
+const (
+	choiceAccept = "Accept suggestion"
+	choiceEdit   = "Edit"
+	choiceOurs   = "Keep ours"
+	choiceTheirs = "Keep theirs"
+	choiceBoth   = "Keep both (ours first)"
+	choiceSkip   = "Skip"
+)
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+
+	resolveCmd.Flags().BoolVarP(&resolveYes, "yes", "y", false, "Accept every suggestion and stage the files, without prompting")
+	resolveCmd.Flags().BoolVar(&resolveNoAI, "no-ai", false, "Don't ask the AI provider for suggestions")
+	resolveCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in the changes to the AI provider unmasked")
+	resolveCmd.Flags().StringVar(&resolveProvider, "provider", "", "AI provider to use (default: models.resolve, then default_provider)")
+	resolveCmd.Flags().StringVar(&resolveModel, "model", "", "Model to use (default: models.resolve, then default_model)")
+	resolveCmd.MarkFlagsMutuallyExclusive("yes", "no-ai")
+	resolveCmd.RegisterFlagCompletionFunc("provider", completeProviders)
+}
+
+func runResolve(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	root, err := git.GetRepoRoot(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.NotGitRepo, err)
+	}
+
+	files, err := git.ConflictedFiles(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.MergeConflict, err)
+	}
+	if len(files) == 0 {
+		return lazyerr.New(lazyerr.NoConflicts, "no conflicted files")
+	}
+	if len(args) > 0 {
+		var selected []string
+		for _, arg := range args {
+			rel := repoRelative(root, arg)
+			if !slices.Contains(files, rel) {
+				return lazyerr.New(lazyerr.InvalidArgument, "%s has no conflicts", arg).WithDetail("file", arg)
+			}
+			selected = append(selected, rel)
+		}
+		files = selected
+	}
+
+	var p types.Provider
+	var baseReq types.CompletionRequest
+	if !resolveNoAI {
+		cfg, err := loadConfig(ctx)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+		}
+		p, baseReq, err = newAIRequest(out, cfg, "resolve", resolveProvider, resolveModel, nil)
+		if err != nil {
+			if resolveYes || resolveProvider != "" {
+				return err
+			}
+			out.Warning(fmt.Sprintf("No suggestions: %v", err))
+			p = nil
+		}
+	}
+	suggest := func(path string, h *conflict.Hunk) (string, error) {
+		if p == nil {
+			return "", nil
+		}
+		req := baseReq
+		req.Messages = conflict.Messages(path, h)
+		out.Progress(fmt.Sprintf("Asking %s to resolve a conflict in %s", p.Name(), path))
+		resp, err := p.Complete(ctx, req)
+		if err != nil {
+			return "", lazyerr.Wrap(lazyerr.ProviderError, err).WithDetail("provider", p.Name())
+		}
+		return conflict.CleanSuggestion(resp.Content), nil
+	}
+
+	// Without a terminal, only report unless told to accept everything
+	if !out.IsTTY() && !resolveYes {
+		return reportConflicts(out, root, files, suggest)
+	}
+
+	results := []schema.ResolvedFile{}
+	for _, path := range files {
+		result, err := resolveFile(ctx, out, root, path, suggest)
+		if err != nil {
+			return err
+		}
+		results = append(results, *result)
+	}
+
+	committed := false
+	remaining, _ := git.ConflictedFiles(ctx)
+	if remaining == nil {
+		remaining = []string{}
+	}
+	if len(remaining) == 0 && out.IsTTY() && git.MergeInProgress(ctx) {
+		if err := tui.ConfirmForm("All conflicts resolved. Commit the merge now?", &committed).Run(); err != nil {
+			return err
+		}
+		if committed {
+			if err := git.CommitMerge(ctx); err != nil {
+				return lazyerr.Wrap(lazyerr.CommitError, err)
+			}
+		}
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.Resolve{Files: results, Remaining: remaining, Committed: committed})
+	}
+
+	switch {
+	case committed:
+		out.Success("Committed the merge")
+	case len(remaining) > 0:
+		out.Info(fmt.Sprintf("%d file(s) still have conflicts; run 'lazywork resolve' again", len(remaining)))
+	case git.MergeInProgress(ctx):
+		out.Info("All conflicts resolved; commit with: git commit")
+	default:
+		out.Info("All conflicts resolved; continue with: git rebase --continue (or git cherry-pick --continue)")
+	}
+
+	return nil
+}
+
+// resolveFile walks the conflicts in path, writes the chosen resolutions
+// and stages the file if none are left
+func resolveFile(ctx context.Context, out *output.Output, root, path string, suggest func(string, *conflict.Hunk) (string, error)) (*schema.ResolvedFile, error) {
+	fullPath := filepath.Join(root, path)
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.ReadError, err).WithDetail("file", path)
+	}
+
+	f := conflict.Parse(string(data))
+	hunks := f.Hunks()
+	result := &schema.ResolvedFile{Path: path, Hunks: len(hunks)}
+	if len(hunks) == 0 {
+		// Conflicts without markers (e.g. deleted on one side) need git
+		out.Warning(fmt.Sprintf("%s has no conflict markers; resolve it with git", path))
+		return result, nil
+	}
+
+	resolutions := make([]*string, len(hunks))
+	for i, h := range hunks {
+		suggestion, err := suggest(path, h)
+		if err != nil {
+			if resolveYes {
+				return nil, err
+			}
+			out.Warning(err.Error())
+		}
+
+		if resolveYes {
+			resolutions[i] = &suggestion
+			continue
+		}
+
+		showHunk(out, path, i+1, len(hunks), h, suggestion)
+		resolution, err := chooseResolution(h, suggestion)
+		if err != nil {
+			return nil, err
+		}
+		resolutions[i] = resolution
+	}
+
+	for _, r := range resolutions {
+		if r != nil {
+			result.Resolved++
+		}
+	}
+	if result.Resolved == 0 {
+		return result, nil
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.ReadError, err).WithDetail("file", path)
+	}
+	if err := os.WriteFile(fullPath, []byte(f.Resolve(resolutions)), info.Mode().Perm()); err != nil {
+		return nil, lazyerr.Wrap(lazyerr.PathError, err).WithDetail("file", path)
+	}
+
+	if result.Resolved == result.Hunks {
+		if err := git.Add(ctx, fullPath); err != nil {
+			return nil, lazyerr.Wrap(lazyerr.MergeConflict, err).WithDetail("file", path)
+		}
+		result.Staged = true
+		out.Success(fmt.Sprintf("Resolved and staged %s", path))
+	} else {
+		out.Warning(fmt.Sprintf("%s: %d of %d conflicts left", path, result.Hunks-result.Resolved, result.Hunks))
+	}
+	return result, nil
+}
+
+// chooseResolution asks how to resolve h; nil means skip
+func chooseResolution(h *conflict.Hunk, suggestion string) (*string, error) {
+	options := []string{choiceOurs, choiceTheirs, choiceBoth, choiceEdit, choiceSkip}
+	if suggestion != "" {
+		options = append([]string{choiceAccept}, options...)
+	}
+
+	var choice string
+	if err := tui.SelectForm("Resolve with", options, &choice).Run(); err != nil {
+		return nil, err
+	}
+
+	var resolution string
+	switch choice {
+	case choiceAccept:
+		resolution = suggestion
+	case choiceOurs:
+		resolution = h.Ours
+	case choiceTheirs:
+		resolution = h.Theirs
+	case choiceBoth:
+		resolution = h.Both()
+	case choiceEdit:
+		resolution = suggestion
+		if resolution == "" {
+			resolution = h.Both()
+		}
+		if err := tui.TextForm("Resolution", &resolution).Run(); err != nil {
+			return nil, err
+		}
+		if resolution != "" && !strings.HasSuffix(resolution, "\n") {
+			resolution += "\n"
+		}
+	default:
+		return nil, nil
+	}
+	return &resolution, nil
+}
+
+func showHunk(out *output.Output, path string, n, total int, h *conflict.Hunk, suggestion string) {
+	out.Println()
+	out.Bold(fmt.Sprintf("%s — conflict %d/%d", path, n, total))
+	showSide(out, "ours", h.OursLabel, h.Ours)
+	if h.Base != "" {
+		showSide(out, "base", "", h.Base)
+	}
+	showSide(out, "theirs", h.TheirsLabel, h.Theirs)
+	if suggestion != "" {
+		showSide(out, "suggestion", "", suggestion)
+	}
+}
+
+func showSide(out *output.Output, name, label, text string) {
+	if label != "" {
+		name += " (" + label + ")"
+	}
+	out.Print("  %s:\n", name)
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		out.Dim("    │ " + line)
+	}
+}
+
+// reportConflicts prints each conflict and its suggestion without changing
+// anything
+func reportConflicts(out *output.Output, root string, files []string, suggest func(string, *conflict.Hunk) (string, error)) error {
+	report := map[string][]schema.SuggestedHunk{}
+	for _, path := range files {
+		data, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.ReadError, err).WithDetail("file", path)
+		}
+		hunks := conflict.Parse(string(data)).Hunks()
+		report[path] = []schema.SuggestedHunk{}
+		for i, h := range hunks {
+			suggestion, err := suggest(path, h)
+			if err != nil {
+				return err
+			}
+			report[path] = append(report[path], schema.SuggestedHunk{Ours: h.Ours, Base: h.Base, Theirs: h.Theirs, Suggestion: suggestion})
+			if !jsonOutput {
+				showHunk(out, path, i+1, len(hunks), h, suggestion)
+			}
+		}
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.ResolveReport{Files: report})
+	}
+	out.Println()
+	out.Dim("Nothing was changed; run in a terminal to choose resolutions, or pass --yes to accept the suggestions")
+	return nil
+}
+
+// repoRelative returns path relative to the repository root, as git
+// reports it
+func repoRelative(root, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if real, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		abs = filepath.Join(real, filepath.Base(abs))
+	}
+	if realRoot, err := filepath.EvalSymlinks(root); err == nil {
+		root = realRoot
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
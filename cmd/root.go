@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
 	"github.com/spf13/cobra"
 )
 
@@ -14,8 +19,11 @@ var (
 
 	// Global flags
 	jsonOutput  bool
+	jsonStream  bool
 	noColor     bool
+	quiet       bool
 	cfgFile     string
+	profileName string
 	shellHelper bool
 )
 
@@ -25,19 +33,73 @@ var rootCmd = &cobra.Command{
 	Long: `LazyWork automates your Git workflow using AI.
 
 Generate commit messages, manage worktrees, separate features,
-and more - all powered by AI providers like OpenAI and Anthropic.`,
+and more - all powered by AI providers like OpenAI and Anthropic.
+
+Exit codes:
+  0    success
+  1    error
+  2    invalid flags or arguments
+  3    not inside a git repository
+  4    worktree, branch or saved state not found
+  5    uncommitted changes
+  6    merge conflict
+  7    AI provider error
+  8    config error
+  130  cancelled`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
 }
 
+// Execute runs the root command. Errors returned by commands are printed
+// here, once, as structured lazyerr errors. Interrupting lazywork cancels
+// the command context, which stops any running git command.
 func Execute() error {
-	return rootCmd.Execute()
+	registerAIFlagCompletions(rootCmd)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return lazyerr.Wrap(lazyerr.Usage, err)
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
+	if err != nil {
+		newOutput(rootCmd).ErrorResult(err)
+	}
+	return err
+}
+
+// newOutput returns the Output for a command. It writes to the command's
+// writers, os.Stdout and os.Stderr unless replaced with SetOut/SetErr, and
+// can be swapped in tests to force options such as output.WithTTY.
+var newOutput = func(cmd *cobra.Command) *output.Output {
+	return output.NewWithWriters(cmd.OutOrStdout(), cmd.ErrOrStderr(),
+		output.WithJSON(jsonOutput),
+		output.WithNoColor(noColor),
+		output.WithQuiet(quiet),
+	)
+}
+
+// ExitCode returns the process exit code for an error returned by Execute
+func ExitCode(err error) int {
+	return lazyerr.ExitCode(err)
 }
 
 func init() {
+	cobra.OnInitialize(func() {
+		output.SetStream(jsonStream)
+		if jsonStream {
+			jsonOutput = true
+		}
+	})
+
 	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output in JSON format (agent-friendly)")
+	rootCmd.PersistentFlags().BoolVar(&jsonStream, "json-stream", false, "Output newline-delimited JSON events (progress, content, result) as the command runs")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print errors and command results (for scripts and hooks)")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable color output")
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file path (default ~/.config/lazywork/config.json)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file path: .json, .yaml or .toml (default ~/.config/lazywork/config.json)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Config profile to use (default $LAZYWORK_PROFILE)")
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
 	rootCmd.PersistentFlags().BoolVar(&shellHelper, "shell-helper", false, "Output for shell function evaluation (used by lw function)")
 	rootCmd.PersistentFlags().MarkHidden("shell-helper")
 }
@@ -46,6 +108,10 @@ func IsJSONOutput() bool {
 	return jsonOutput
 }
 
+func IsQuiet() bool {
+	return quiet
+}
+
 func IsNoColor() bool {
 	return noColor
 }
@@ -57,11 +123,3 @@ func ConfigFile() string {
 func IsShellHelper() bool {
 	return shellHelper
 }
-
-func Stdout() *os.File {
-	return os.Stdout
-}
-
-func Stderr() *os.File {
-	return os.Stderr
-}
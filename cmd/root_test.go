@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+// execute runs the root command with args, capturing its output
+func execute(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetArgs(args)
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+		jsonOutput = false
+		quiet = false
+		cfgFile = ""
+	})
+
+	err := rootCmd.Execute()
+	return stdout.String(), stderr.String(), err
+}
+
+func TestConfigPathJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	stdout, _, err := execute(t, "config", "path", "--json", "--config", path)
+	if err != nil {
+		t.Fatalf("config path failed: %v", err)
+	}
+
+	var result struct {
+		Path   string `json:"path"`
+		Exists bool   `json:"exists"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if result.Path != path || result.Exists {
+		t.Errorf("result = %+v, want path %s that does not exist", result, path)
+	}
+}
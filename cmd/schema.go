@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema [command...]",
+	Short: "Print the JSON Schema of a command's --json output",
+	Long: `Print the JSON Schema (draft 2020-12) of what a command prints with
+--json, so agents and scripts can validate against a stable contract.
+Commands with several modes, e.g. 'worktree finish --check', list one
+alternative per mode. 'lazywork schema error' describes the object printed
+when a command fails.
+
+Without a command, list the commands that have a schema.
+
+The contract is versioned: within a version fields are only added, never
+renamed, removed or retyped. The version is part of each schema's $id.
+
+Example:
+  lazywork schema worktree add
+  lazywork schema worktree rm
+  lazywork schema error`,
+	RunE: runSchema,
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
+
+func runSchema(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+
+	if len(args) == 0 {
+		commands := schema.Commands()
+		if jsonOutput {
+			return out.JSON(schema.SchemaList{Version: schema.Version, Commands: commands})
+		}
+		out.Dim("Output schema " + schema.Version)
+		for _, name := range commands {
+			out.Println(name)
+		}
+		return nil
+	}
+
+	name := strings.Join(args, " ")
+	var description string
+	if name != schema.ErrorName {
+		// Resolve aliases such as 'wt ls'
+		if c, rest, err := rootCmd.Find(args); err == nil && len(rest) == 0 && c != rootCmd {
+			name = strings.TrimPrefix(c.CommandPath(), rootCmd.Name()+" ")
+			description = c.Short
+		}
+	}
+
+	s, ok := schema.For(name)
+	if !ok {
+		return lazyerr.New(lazyerr.NoSchema, "no JSON output schema for '%s'", name).WithDetail("command", name)
+	}
+	s.Description = description
+
+	// The schema is the output, with or without --json
+	return out.JSON(s)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/pkg/schema"
+)
+
+func TestSchemaCommandsExist(t *testing.T) {
+	for _, name := range schema.Commands() {
+		c, rest, err := rootCmd.Find(strings.Fields(name))
+		if err != nil || len(rest) > 0 || c.CommandPath() != "lazywork "+name {
+			t.Errorf("schema for %q has no matching command", name)
+		}
+	}
+}
+
+func TestSchemaAlias(t *testing.T) {
+	stdout, _, err := execute(t, "schema", "worktree", "rm")
+	if err != nil {
+		t.Fatalf("schema failed: %v", err)
+	}
+
+	var s schema.Schema
+	if err := json.Unmarshal([]byte(stdout), &s); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if s.ID != "urn:lazywork:schema:"+schema.Version+":worktree-remove" || s.Description == "" {
+		t.Errorf("schema = %+v, want the one of 'worktree remove' with its description", s)
+	}
+}
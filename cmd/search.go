@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/search"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// searchCandidates is how many recent commits --semantic ranks, on
+	// top of those matching the query
+	searchCandidates = 500
+	// embedBatch bounds the texts sent in one embedding request
+	embedBatch = 100
+	// maxEmbedText bounds the characters of a commit message embedded
+	maxEmbedText = 2000
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search commit messages and diffs",
+	Long: `Find the commits reachable from HEAD whose message contains the query,
+ignoring case, and print each with the line that matched. --diffs also
+finds the commits adding or removing a line that contains it.
+
+With --semantic the AI provider's embeddings rank the commits by meaning
+instead, so "flaky login" also finds "Retry the auth request on timeout".
+The candidates are the last 500 commits plus any older ones containing
+the query. It needs a provider that supports embeddings: OpenAI and
+Ollama providers use their embedding_model.
+
+Example:
+  lazywork search timeout
+  lazywork search "rate limit" --diffs -n 20
+  lazywork search "why did we drop sessions" --semantic`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSearch,
+}
+
+var (
+	searchMax      int
+	searchDiffs    bool
+	searchSemantic bool
+	searchProvider string
+)
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().IntVarP(&searchMax, "max-count", "n", 10, "Show at most this many commits")
+	searchCmd.Flags().BoolVar(&searchDiffs, "diffs", false, "Search the changes of each commit too")
+	searchCmd.Flags().BoolVar(&searchSemantic, "semantic", false, "Rank commits by meaning with the AI provider's embeddings")
+	searchCmd.Flags().StringVar(&searchProvider, "provider", "", "AI provider to use for --semantic (default: default_provider)")
+	searchCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in commit messages to the AI provider unmasked")
+	searchCmd.RegisterFlagCompletionFunc("provider", completeProviders)
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+	query := args[0]
+
+	if searchMax <= 0 {
+		return lazyerr.New(lazyerr.InvalidArgument, "--max-count must be positive")
+	}
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	var results []schema.SearchResult
+	if searchSemantic {
+		cfg, err := loadConfig(ctx)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+		}
+		if results, err = semanticSearch(ctx, out, cfg, query); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if results, err = grepSearch(ctx, query); err != nil {
+			return err
+		}
+	}
+	if results == nil {
+		results = []schema.SearchResult{}
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.Search{Query: query, Semantic: searchSemantic, Results: results})
+	}
+	if len(results) == 0 {
+		out.Info(fmt.Sprintf("No commits match '%s'", query))
+		return nil
+	}
+	for _, r := range results {
+		out.Println(fmt.Sprintf("%.7s  %s  %s", r.Hash, r.Date.Local().Format("2006-01-02"), r.Subject))
+		if r.Snippet != "" && r.Snippet != r.Subject {
+			out.Dim("         " + r.Snippet)
+		}
+	}
+	return nil
+}
+
+// grepSearch returns the newest commits whose message, or diff with
+// --diffs, contains query
+func grepSearch(ctx context.Context, query string) ([]schema.SearchResult, error) {
+	commits, err := git.SearchMessages(ctx, query, searchMax)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	seen := map[string]bool{}
+	var results []schema.SearchResult
+	for _, c := range commits {
+		seen[c.Hash] = true
+		results = append(results, searchResult(c, "message", search.Snippet(c.Message, query)))
+	}
+
+	if searchDiffs {
+		commits, err := git.SearchDiffs(ctx, query, searchMax)
+		if err != nil {
+			return nil, lazyerr.Wrap(lazyerr.BranchError, err)
+		}
+		for _, c := range commits {
+			if seen[c.Hash] {
+				continue
+			}
+			patch, _ := git.CommitPatch(ctx, c.Hash)
+			results = append(results, searchResult(c, "diff", search.DiffSnippet(patch, query)))
+		}
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Date.After(results[j].Date) })
+	}
+
+	if len(results) > searchMax {
+		results = results[:searchMax]
+	}
+	return results, nil
+}
+
+// semanticSearch ranks the recent commits and those matching query by the
+// similarity of their message's embedding to the query's
+func semanticSearch(ctx context.Context, out *output.Output, cfg *config.Config, query string) ([]schema.SearchResult, error) {
+	e, err := newEmbedder(out, cfg, searchProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := git.RecentMessages(ctx, searchCandidates)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	seen := map[string]bool{}
+	for _, c := range commits {
+		seen[c.Hash] = true
+	}
+	matches, err := git.SearchMessages(ctx, query, searchCandidates)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	for _, c := range matches {
+		if !seen[c.Hash] {
+			commits = append(commits, c)
+		}
+	}
+	if len(commits) == 0 {
+		return nil, nil
+	}
+
+	texts := make([]string, 0, len(commits)+1)
+	texts = append(texts, query)
+	for _, c := range commits {
+		text := c.Message
+		if len(text) > maxEmbedText {
+			text = text[:maxEmbedText]
+		}
+		texts = append(texts, text)
+	}
+	if texts, err = redactTexts(out, cfg, texts); err != nil {
+		return nil, err
+	}
+
+	stop := out.Spinner(fmt.Sprintf("Ranking %d commits", len(commits)))
+	var vectors [][]float32
+	for start := 0; start < len(texts) && err == nil; start += embedBatch {
+		var batch [][]float32
+		batch, err = e.Embed(ctx, texts[start:min(start+embedBatch, len(texts))])
+		vectors = append(vectors, batch...)
+	}
+	stop()
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.ProviderError, err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, lazyerr.New(lazyerr.ProviderError, "got %d embeddings for %d texts", len(vectors), len(texts))
+	}
+
+	order, scores := search.Rank(vectors[0], vectors[1:])
+	var results []schema.SearchResult
+	for _, i := range order[:min(searchMax, len(order))] {
+		c := commits[i]
+		snippet := search.Snippet(c.Message, query)
+		if snippet == "" {
+			snippet = c.Subject
+		}
+		r := searchResult(c, "semantic", snippet)
+		r.Score = scores[i]
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func searchResult(c git.CommitText, match, snippet string) schema.SearchResult {
+	return schema.SearchResult{Hash: c.Hash, Date: c.Date, Author: c.Author, Subject: c.Subject, Match: match, Snippet: snippet}
+}
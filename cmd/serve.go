@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/server"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve --http <addr>",
+	Short: "Serve lazywork over a local HTTP JSON API",
+	Long: `Serve lazywork commands over HTTP for editor plugins and GUIs. Each
+request runs one command with --json and returns its result, so the API
+matches the CLI exactly:
+
+  GET    /v1/health                      server version (no token needed)
+  POST   /v1/run                         {"dir": "/repo", "args": ["worktree", "list"]}
+  GET    /v1/worktrees?dir=/repo         worktree list (&status=1 for --status)
+  POST   /v1/worktrees                   {"dir", "name", "branch", "push"}: worktree add
+  GET    /v1/worktrees/<name>?dir=/repo  worktree status
+  DELETE /v1/worktrees/<name>?dir=/repo  worktree remove (&force=1 for --force)
+
+Responses carry {"exit_code", "result"} on success (HTTP 200) and
+{"exit_code", "error"} when the command fails (HTTP 422). Commands run in
+dir, an absolute path inside a repository, and never prompt.
+
+Requests need the token from the token file, created on first start and
+readable only by you:
+
+  Authorization: Bearer <token>
+
+The server listens on localhost; a bare :port means 127.0.0.1:port. Other
+addresses are refused unless --allow-remote is given, since anyone with
+the token can run commands as you.
+
+Example:
+  lazywork serve --http :7777
+  curl -H "Authorization: Bearer $(cat ~/.local/state/lazywork/serve-token)" \
+    "localhost:7777/v1/worktrees?dir=$PWD"`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+var (
+	serveAddr        string
+	serveTokenFile   string
+	serveAllowRemote bool
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "http", "127.0.0.1:7777", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveTokenFile, "token-file", "", "Token file (default: serve-token in the lazywork state directory)")
+	serveCmd.Flags().BoolVar(&serveAllowRemote, "allow-remote", false, "Allow listening on addresses other than localhost")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if os.Getenv(server.ChildEnv) != "" {
+		return lazyerr.New(lazyerr.InvalidArgument, "serve can't be run through the API")
+	}
+
+	addr, err := serveAddress(serveAddr, serveAllowRemote)
+	if err != nil {
+		return err
+	}
+
+	tokenFile := serveTokenFile
+	if tokenFile == "" {
+		tokenFile = filepath.Join(state.UserDir(), server.TokenFile)
+	}
+	token, err := server.LoadToken(tokenFile)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ServeError, err).WithDetail("token_file", tokenFile).
+			WithHint("Check that the token file is readable, or pass another one with --token-file")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ServeError, err)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ServeError, err).WithDetail("addr", addr)
+	}
+
+	srv := &http.Server{
+		Handler:           (&server.Server{Token: token, Version: Version, Run: server.ExecRunner(exe)}).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	url := "http://" + ln.Addr().String()
+	if jsonOutput {
+		out.JSON(schema.Serve{URL: url, TokenFile: tokenFile})
+	} else {
+		out.Success("Serving on " + url)
+		out.Dim("  token: " + tokenFile)
+	}
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return lazyerr.Wrap(lazyerr.ServeError, err).WithDetail("addr", addr)
+	}
+	return nil
+}
+
+// serveAddress resolves the address to listen on, defaulting the host to
+// 127.0.0.1 and refusing non-loopback hosts unless allowRemote is set
+func serveAddress(addr string, allowRemote bool) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", lazyerr.New(lazyerr.InvalidArgument, "invalid --http address %q", addr).
+			WithHint("Use host:port or :port, e.g. --http :7777")
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if !allowRemote && !isLoopback(host) {
+		return "", lazyerr.New(lazyerr.InvalidArgument, "refusing to listen on %s: not a localhost address", host).
+			WithHint("Pass --allow-remote to serve other hosts; anyone with the token can run commands as you")
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
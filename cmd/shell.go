@@ -1,22 +1,25 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
 	"github.com/miltonparedes/lazywork/internal/output"
 	"github.com/miltonparedes/lazywork/internal/shell"
+	"github.com/miltonparedes/lazywork/pkg/schema"
 	"github.com/spf13/cobra"
 )
 
 var shellCmd = &cobra.Command{
 	Use:   "shell",
 	Short: "Shell integration commands",
-	Long:  "Commands for integrating LazyWork with your shell (bash, zsh, fish).",
+	Long:  "Commands for integrating LazyWork with your shell (bash, zsh, fish, powershell).",
 }
 
 var shellInitCmd = &cobra.Command{
-	Use:   "init [bash|zsh|fish]",
+	Use:   "init [bash|zsh|fish|powershell]",
 	Short: "Print shell initialization script",
 	Long: `Print the shell initialization script for LazyWork.
 
@@ -42,7 +45,10 @@ Setup:
   eval "$(lazywork shell init zsh)"
 
   # Fish - add to ~/.config/fish/config.fish:
-  lazywork shell init fish | source`,
+  lazywork shell init fish | source
+
+  # PowerShell - add to $PROFILE:
+  Invoke-Expression (& lazywork shell init powershell | Out-String)`,
 	Args:      cobra.MaximumNArgs(1),
 	ValidArgs: shell.SupportedShells(),
 	RunE:      runShellInit,
@@ -51,43 +57,118 @@ Setup:
 var shellStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Check shell integration status",
-	RunE:  runShellStatus,
+	Long: `Check whether LazyWork shell integration is set up in your RC file.
+
+Use --fix to install it when missing (same as 'lazywork shell install').`,
+	RunE: runShellStatus,
+}
+
+var shellInstallCmd = &cobra.Command{
+	Use:   "install [bash|zsh|fish|powershell]",
+	Short: "Add shell integration to your RC file",
+	Long: `Add the LazyWork init line to your shell RC file.
+
+The RC file is backed up to <rc>.lazywork.bak before it is modified, and
+the added lines are wrapped in marker comments so they can be removed
+later with 'lazywork shell uninstall'. Running install again is a no-op,
+except that --completions adds completions to an earlier install.
+
+Usage:
+  # Auto-detect shell
+  lazywork shell install
+
+  # Also install shell completions
+  lazywork shell install zsh --completions`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: shell.SupportedShells(),
+	RunE:      runShellInstall,
 }
 
+var shellUninstallCmd = &cobra.Command{
+	Use:   "uninstall [bash|zsh|fish|powershell]",
+	Short: "Remove shell integration from your RC files",
+	Long: `Remove the LazyWork block added by 'lazywork shell install'.
+
+Only lines between the lazywork marker comments are removed; the RC file
+is backed up to <rc>.lazywork.bak first. Installed completion files are
+deleted as well.
+
+Without an argument, all supported shells are checked.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: shell.SupportedShells(),
+	RunE:      runShellUninstall,
+}
+
+var (
+	installCompletions bool
+	fixShellStatus     bool
+)
+
 func init() {
 	rootCmd.AddCommand(shellCmd)
 	shellCmd.AddCommand(shellInitCmd)
 	shellCmd.AddCommand(shellStatusCmd)
+	shellCmd.AddCommand(shellInstallCmd)
+	shellCmd.AddCommand(shellUninstallCmd)
+
+	shellInstallCmd.Flags().BoolVar(&installCompletions, "completions", false, "Also install shell completions")
+	shellStatusCmd.Flags().BoolVar(&fixShellStatus, "fix", false, "Install shell integration if it is missing")
 }
 
-func runShellInit(cmd *cobra.Command, args []string) error {
-	var shellType string
+// resolveShellArg returns the shell named in args, or the detected shell
+func resolveShellArg(args []string) (string, error) {
+	if len(args) == 0 {
+		return shell.DetectShell(), nil
+	}
+	shellType := strings.ToLower(args[0])
+	if !shell.IsValidShell(shellType) {
+		return "", fmt.Errorf("unsupported shell '%s'. Supported: %s",
+			shellType, strings.Join(shell.SupportedShells(), ", "))
+	}
+	return shellType, nil
+}
 
-	if len(args) > 0 {
-		shellType = strings.ToLower(args[0])
-		if !shell.IsValidShell(shellType) {
-			return fmt.Errorf("unsupported shell '%s'. Supported: %s",
-				shellType, strings.Join(shell.SupportedShells(), ", "))
-		}
-	} else {
-		shellType = shell.DetectShell()
+func completionScript(shellType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch shellType {
+	case shell.Bash:
+		err = rootCmd.GenBashCompletion(&buf)
+	case shell.Zsh:
+		err = rootCmd.GenZshCompletion(&buf)
+	case shell.Fish:
+		err = rootCmd.GenFishCompletion(&buf, true)
+	case shell.PowerShell:
+		err = rootCmd.GenPowerShellCompletionWithDesc(&buf)
+	}
+	return buf.Bytes(), err
+}
+
+func runShellInit(cmd *cobra.Command, args []string) error {
+	shellType, err := resolveShellArg(args)
+	if err != nil {
+		return err
 	}
 
 	script := shell.InitScript(shellType)
-	fmt.Print(script)
+	fmt.Fprint(cmd.OutOrStdout(), script)
 
 	return nil
 }
 
 func runShellStatus(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
+	out := newOutput(cmd)
 	shellType := shell.DetectShell()
 
+	if fixShellStatus && !shell.HasInitLine(shellType) {
+		return installShell(out, shellType)
+	}
+
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"shell":     shellType,
-			"rc_file":   shell.RcFile(shellType),
-			"installed": shell.HasInitLine(shellType),
+		return out.JSON(schema.ShellStatus{
+			Shell:     shellType,
+			RcFile:    shell.RcFile(shellType),
+			Installed: shell.HasInitLine(shellType),
 		})
 	}
 
@@ -108,3 +189,102 @@ func runShellStatus(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runShellInstall(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+
+	shellType, err := resolveShellArg(args)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.InvalidShell, err)
+	}
+
+	return installShell(out, shellType)
+}
+
+func installShell(out *output.Output, shellType string) error {
+	var script []byte
+	if installCompletions {
+		var err error
+		script, err = completionScript(shellType)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.CompletionError, err)
+		}
+	}
+
+	result, err := shell.Install(shellType, installCompletions, script)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ShellInstallError, err)
+	}
+
+	if jsonOutput {
+		return out.JSON(result)
+	}
+
+	if !result.Changed {
+		out.Success("LazyWork integration is already installed in " + result.RcFile)
+		return nil
+	}
+
+	out.Success("Installed LazyWork integration in " + result.RcFile)
+	if result.Backup != "" {
+		out.Dim("  backup: " + result.Backup)
+	}
+	if result.CompletionFile != "" {
+		out.Dim("  completions: " + result.CompletionFile)
+	}
+	out.Println()
+	out.Info("Restart your shell or run: source " + result.RcFile)
+
+	return nil
+}
+
+func runShellUninstall(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+
+	shells := shell.SupportedShells()
+	if len(args) > 0 {
+		shellType, err := resolveShellArg(args)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.InvalidShell, err)
+		}
+		shells = []string{shellType}
+	}
+
+	results := make([]shell.UninstallResult, 0, len(shells))
+	for _, shellType := range shells {
+		result, err := shell.Uninstall(shellType)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.ShellUninstallError, err)
+		}
+		results = append(results, *result)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.ShellUninstall{Results: results})
+	}
+
+	changed := false
+	for _, result := range results {
+		if len(result.RemovedLines) > 0 {
+			changed = true
+			out.Success(fmt.Sprintf("Removed %d lines from %s", len(result.RemovedLines), result.RcFile))
+			for _, line := range result.RemovedLines {
+				out.Dim("  - " + line)
+			}
+			out.Dim("  backup: " + result.Backup)
+		}
+		for _, path := range result.RemovedFiles {
+			changed = true
+			out.Success("Deleted " + path)
+		}
+		if result.Unmanaged {
+			out.Warning(fmt.Sprintf("%s still contains a lazywork init line outside the managed block; remove it manually", result.RcFile))
+		}
+	}
+
+	if !changed {
+		out.Dim("No LazyWork shell integration found")
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var stageCmd = &cobra.Command{
+	Use:   "stage",
+	Short: "Pick the hunks to stage, then commit them",
+	Long: `Pick the changes to stage from a list of changed files and their hunks,
+like 'git add -p' but all at once, then commit them with an AI-generated
+message as 'lazywork commit' does.
+
+Move with the arrow keys (or j/k), toggle a hunk with space, or every hunk
+of a file on its line, and toggle everything with a. Enter stages the
+selection and esc cancels without touching the index. New files and binary
+files are staged whole.
+
+With --no-commit the selection is only staged. Changes staged before are
+kept and committed along.
+
+Example:
+  lazywork stage
+  lazywork stage --no-commit
+  lazywork stage --provider ollama`,
+	Args: cobra.NoArgs,
+	RunE: runStage,
+}
+
+var stageNoCommit bool
+
+func init() {
+	rootCmd.AddCommand(stageCmd)
+
+	stageCmd.Flags().BoolVar(&stageNoCommit, "no-commit", false, "Only stage the selection")
+	stageCmd.Flags().BoolVarP(&commitYes, "yes", "y", false, "Commit the generated message without review")
+	stageCmd.Flags().StringVar(&commitProvider, "provider", "", "AI provider to use (default: models.commit, then default_provider)")
+	stageCmd.Flags().StringVar(&commitModel, "model", "", "Model to use (default: models.commit, then default_model)")
+	stageCmd.Flags().BoolVar(&commitOffline, "offline", false, "Build the message from the diffstat without an AI provider")
+	stageCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in the changes to the AI provider unmasked")
+	stageCmd.RegisterFlagCompletionFunc("provider", completeProviders)
+}
+
+func runStage(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	if !out.IsTTY() {
+		return lazyerr.New(lazyerr.Usage, "stage needs a terminal").
+			WithHint("Stage with 'git add -p', then run 'lazywork commit'")
+	}
+
+	files, err := git.UnstagedChanges(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.CommitError, err)
+	}
+	if len(files) == 0 {
+		return lazyerr.New(lazyerr.NothingStaged, "no unstaged changes").
+			WithHint("Commit what is staged with: lazywork commit")
+	}
+
+	selected, ok, err := tui.RunStager(files)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return lazyerr.New(lazyerr.Cancelled, "staging cancelled")
+	}
+
+	root, err := git.GetRepoRoot(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.NotGitRepo, err)
+	}
+	result := schema.Stage{Files: []string{}}
+	var untracked []string
+	for i, f := range files {
+		n := 0
+		for _, sel := range selected[i] {
+			if sel {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		result.Files = append(result.Files, f.Path)
+		result.Hunks += n
+		if f.Untracked {
+			untracked = append(untracked, filepath.Join(root, f.Path))
+		}
+	}
+	if len(result.Files) == 0 {
+		return lazyerr.New(lazyerr.Cancelled, "nothing selected")
+	}
+
+	if err := git.ApplyCached(ctx, git.BuildPatch(files, selected)); err != nil {
+		return lazyerr.Wrap(lazyerr.CommitError, err)
+	}
+	if len(untracked) > 0 {
+		if err := git.Add(ctx, untracked...); err != nil {
+			return lazyerr.Wrap(lazyerr.CommitError, err)
+		}
+	}
+
+	if stageNoCommit {
+		if jsonOutput {
+			return out.JSON(result)
+		}
+		out.Success(fmt.Sprintf("Staged %d change(s) in %d file(s)", result.Hunks, len(result.Files)))
+		return nil
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+	message, style, err := draftCommitMessage(ctx, out, cfg, false)
+	if err != nil {
+		return err
+	}
+	return commitWithMessage(ctx, out, message, false, !commitYes, style)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/standup"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var standupCmd = &cobra.Command{
+	Use:   "standup",
+	Short: "Summarize your recent commits for a standup",
+	Long: `Collect the commits you made recently on every branch of the repository,
+and so in all of its worktrees, and ask the AI provider for a standup
+update in Markdown.
+
+By default it covers the last working day: since yesterday, or since
+Friday on a Monday. Your commits are those whose author matches
+user.email, or --author.
+
+With --all-repos every repository lazywork has been used in (to switch
+worktrees or generate messages) is included as well.
+
+Example:
+  lazywork standup
+  lazywork standup --days 7 --all-repos
+  lazywork standup --no-ai --json`,
+	Args: cobra.NoArgs,
+	RunE: runStandup,
+}
+
+var (
+	standupDays     int
+	standupAllRepos bool
+	standupAuthor   string
+	standupNoAI     bool
+	standupProvider string
+	standupModel    string
+)
+
+func init() {
+	rootCmd.AddCommand(standupCmd)
+
+	standupCmd.Flags().IntVar(&standupDays, "days", 0, "Number of days to cover (default: since the last working day)")
+	standupCmd.Flags().BoolVar(&standupAllRepos, "all-repos", false, "Include every repository lazywork has been used in")
+	standupCmd.Flags().StringVar(&standupAuthor, "author", "", "Author to collect commits of (default: user.email)")
+	standupCmd.Flags().BoolVar(&standupNoAI, "no-ai", false, "List the commits instead of asking the AI provider for a summary")
+	standupCmd.Flags().StringVar(&standupProvider, "provider", "", "AI provider to use (default: models.standup, then default_provider)")
+	standupCmd.Flags().StringVar(&standupModel, "model", "", "Model to use (default: models.standup, then default_model)")
+	standupCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in commit messages to the AI provider unmasked")
+}
+
+func runStandup(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if standupDays < 0 {
+		return lazyerr.New(lazyerr.InvalidArgument, "--days must not be negative")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	var dirs []string
+	if commonDir, err := git.GetCommonDir(ctx); err == nil {
+		dirs = append(dirs, commonDir)
+		_ = state.RecordRepo(state.UserDir(), commonDir, time.Now())
+	} else if !standupAllRepos {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository").
+			WithHint("Use --all-repos to summarize every repository lazywork knows")
+	}
+	if standupAllRepos {
+		known, err := state.LoadRepos(state.UserDir())
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.StateReadError, err)
+		}
+		for _, r := range known.Existing() {
+			if len(dirs) == 0 || r.CommonDir != dirs[0] {
+				dirs = append(dirs, r.CommonDir)
+			}
+		}
+	}
+
+	since := standup.Since(time.Now(), standupDays)
+	repos := []standup.Repo{}
+	for _, dir := range dirs {
+		name, path := repoName(dir)
+		author := standupAuthor
+		if author == "" {
+			if author, _ = git.UserEmail(ctx, dir); author == "" {
+				out.Warning(fmt.Sprintf("Skipping %s: user.email is not set", name))
+				continue
+			}
+		}
+		commits, err := git.AuthoredCommits(ctx, dir, author, since)
+		if err != nil {
+			out.Warning(fmt.Sprintf("Skipping %s: %v", name, err))
+			continue
+		}
+		if len(commits) == 0 {
+			continue
+		}
+		repos = append(repos, standup.Repo{Name: name, Path: path, Commits: commits})
+	}
+
+	var summary string
+	count := standup.Count(repos)
+	switch {
+	case count == 0:
+	case standupNoAI:
+		summary = standup.Markdown(repos)
+	default:
+		p, req, err := newAIRequest(out, cfg, "standup", standupProvider, standupModel,
+			standup.Messages(repos, since, cfg.Commit.Language))
+		if err != nil {
+			return err
+		}
+		out.Progress(fmt.Sprintf("Summarizing %d commits with %s (%s)", count, p.Name(), req.Model))
+		resp, err := p.Complete(ctx, req)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.ProviderError, err).WithDetail("provider", p.Name())
+		}
+		summary = commitmsg.Clean(resp.Content)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.Standup{Since: since, Commits: count, Repos: repos, Summary: summary})
+	}
+
+	if count == 0 {
+		out.Info(fmt.Sprintf("No commits since %s", since.Format("Monday, January 2")))
+		return nil
+	}
+	out.Println(summary)
+	return nil
+}
+
+// repoName returns the name of the repository with the given git common
+// dir and the directory holding it
+func repoName(commonDir string) (name, path string) {
+	path = commonDir
+	switch filepath.Base(commonDir) {
+	case ".git", git.BareDir:
+		path = filepath.Dir(commonDir)
+	}
+	return strings.TrimSuffix(filepath.Base(path), ".git"), path
+}
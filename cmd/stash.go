@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var stashCmd = &cobra.Command{
+	Use:   "stash",
+	Short: "List, inspect and recover stashes",
+	Long: `List, inspect and recover stashes, including the auto-stashes created by
+'worktree use'.
+
+Auto-stashes have messages starting with "lazywork:". One that is not
+waiting for 'worktree return' is reported as orphaned: its changes were
+never restored and can be recovered with 'lazywork stash apply'.
+
+Stashes are selected by ref (stash@{2}) or index (2); without one you'll be
+prompted to pick a stash interactively.`,
+}
+
+var stashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stashes",
+	Args:  cobra.NoArgs,
+	RunE:  runStashList,
+}
+
+var stashShowCmd = &cobra.Command{
+	Use:   "show [stash]",
+	Short: "Show the changes in a stash",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runStashShow,
+}
+
+var stashApplyCmd = &cobra.Command{
+	Use:   "apply [stash]",
+	Short: "Apply a stash to the working tree",
+	Long: `Apply a stash to the working tree, keeping it in the stash list unless
+--pop is given.
+
+Example:
+  lazywork stash list --auto
+  lazywork stash apply 1 --pop`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStashApply,
+}
+
+var stashDropCmd = &cobra.Command{
+	Use:   "drop [stash]",
+	Short: "Delete a stash",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runStashDrop,
+}
+
+var (
+	stashAutoOnly bool
+	stashPop      bool
+)
+
+func init() {
+	rootCmd.AddCommand(stashCmd)
+	stashCmd.AddCommand(stashListCmd)
+	stashCmd.AddCommand(stashShowCmd)
+	stashCmd.AddCommand(stashApplyCmd)
+	stashCmd.AddCommand(stashDropCmd)
+
+	stashListCmd.Flags().BoolVar(&stashAutoOnly, "auto", false, "Only list stashes created by lazywork")
+	stashApplyCmd.Flags().BoolVar(&stashPop, "pop", false, "Remove the stash after applying it")
+
+	for _, c := range []*cobra.Command{stashShowCmd, stashApplyCmd, stashDropCmd} {
+		c.ValidArgsFunction = completeStashes
+	}
+}
+
+// stashState describes a stash for listings: "pending" for auto-stashes
+// 'worktree return' restores, "orphaned" for other auto-stashes and empty
+// for stashes lazywork did not create
+func stashState(s git.StashEntry, pending []string) string {
+	if !s.IsAuto() {
+		return ""
+	}
+	for _, id := range pending {
+		if s.Matches(id) {
+			return "pending"
+		}
+	}
+	return "orphaned"
+}
+
+func runStashList(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	stashes, err := git.ListStashes(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.StashError, err)
+	}
+	if stashAutoOnly {
+		var auto []git.StashEntry
+		for _, s := range stashes {
+			if s.IsAuto() {
+				auto = append(auto, s)
+			}
+		}
+		stashes = auto
+	}
+	pending := git.PendingUseStashes(ctx)
+
+	if jsonOutput {
+		items := make([]schema.StashItem, 0, len(stashes))
+		for _, s := range stashes {
+			items = append(items, schema.StashItem{StashEntry: s, Auto: s.IsAuto(), State: stashState(s, pending)})
+		}
+		return out.JSON(schema.StashList{Stashes: items, Count: len(items)})
+	}
+
+	if len(stashes) == 0 {
+		out.Dim("No stashes found")
+		return nil
+	}
+
+	orphaned := 0
+	rows := make([][]string, 0, len(stashes))
+	for _, s := range stashes {
+		state := stashState(s, pending)
+		if state == "orphaned" {
+			orphaned++
+		}
+		rows = append(rows, []string{
+			s.Ref,
+			s.Branch,
+			s.Created.Local().Format("2006-01-02 15:04"),
+			state,
+			s.Message,
+		})
+	}
+	out.Table([]string{"REF", "BRANCH", "CREATED", "STATE", "MESSAGE"}, rows)
+
+	if orphaned > 0 {
+		out.Println()
+		out.Info(fmt.Sprintf("%d orphaned auto-stash(es); recover with 'lazywork stash apply <ref>'", orphaned))
+	}
+
+	return nil
+}
+
+// selectStash resolves the stash named by args, prompting for one when no
+// argument is given in a terminal
+func selectStash(ctx context.Context, out *output.Output, args []string, action string) (*git.StashEntry, error) {
+	stashes, err := git.ListStashes(ctx)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.StashError, err)
+	}
+	if len(stashes) == 0 {
+		return nil, lazyerr.New(lazyerr.NoStashes, "no stashes found")
+	}
+
+	var ref string
+	if len(args) > 0 {
+		ref = git.StashRef(args[0])
+	} else if out.IsTTY() {
+		pending := git.PendingUseStashes(ctx)
+		notes := make(map[string]string)
+		for _, s := range stashes {
+			notes[s.Ref] = stashState(s, pending)
+		}
+		form := tui.StashSelectForm(stashes, notes, &ref)
+		if err := form.Run(); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, lazyerr.New(lazyerr.NameRequired, "stash required (use: lazywork stash %s <stash>)", action)
+	}
+
+	if stash := git.FindStash(stashes, ref); stash != nil {
+		return stash, nil
+	}
+	return nil, lazyerr.New(lazyerr.StashNotFound, "stash '%s' not found", ref).WithDetail("ref", ref)
+}
+
+// releasePendingStash forgets a stash saved by 'worktree use' once it has
+// been popped or dropped, so 'worktree return' does not look for it
+func releasePendingStash(ctx context.Context, out *output.Output, stash git.StashEntry) {
+	if err := git.ForgetUseStash(ctx, stash); err != nil {
+		out.Warning(fmt.Sprintf("Could not update 'worktree use' state: %v", err))
+	}
+}
+
+func runStashShow(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	stash, err := selectStash(ctx, out, args, "show")
+	if err != nil {
+		return err
+	}
+
+	diff, err := git.StashShow(ctx, stash.Ref)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.StashError, err)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.StashShow{Stash: *stash, Diff: diff})
+	}
+
+	out.Bold(fmt.Sprintf("%s (%s) %s", stash.Ref, stash.Branch, stash.Message))
+	out.Println(strings.TrimRight(diff, "\n"))
+
+	return nil
+}
+
+func runStashApply(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	stash, err := selectStash(ctx, out, args, "apply")
+	if err != nil {
+		return err
+	}
+
+	if err := git.StashApply(ctx, stash.Ref, stashPop); err != nil {
+		return lazyerr.Wrap(lazyerr.StashError, err).WithDetail("ref", stash.Ref)
+	}
+	if stashPop {
+		releasePendingStash(ctx, out, *stash)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.StashApply{Stash: *stash, Applied: true, Dropped: stashPop})
+	}
+
+	out.Success(fmt.Sprintf("Applied %s: %s", stash.Ref, stash.Message))
+	if !stashPop {
+		out.Dim(fmt.Sprintf("  Stash kept; remove it with 'lazywork stash drop %s'", stash.Ref))
+	}
+
+	return nil
+}
+
+func runStashDrop(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	stash, err := selectStash(ctx, out, args, "drop")
+	if err != nil {
+		return err
+	}
+
+	if out.IsTTY() {
+		confirmed := false
+		form := tui.ConfirmForm(fmt.Sprintf("Drop %s (%s)?", stash.Ref, stash.Message), &confirmed)
+		if err := form.Run(); err != nil {
+			return err
+		}
+		if !confirmed {
+			return lazyerr.New(lazyerr.Cancelled, "cancelled")
+		}
+	}
+
+	if err := git.StashDrop(ctx, stash.Ref); err != nil {
+		return lazyerr.Wrap(lazyerr.StashError, err).WithDetail("ref", stash.Ref)
+	}
+	releasePendingStash(ctx, out, *stash)
+
+	if jsonOutput {
+		return out.JSON(schema.StashDrop{Stash: *stash, Dropped: true})
+	}
+
+	out.Success(fmt.Sprintf("Dropped %s (%s)", stash.Ref, stash.Hash[:min(7, len(stash.Hash))]))
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/internal/git/gittest"
+)
+
+func TestStashListJSON(t *testing.T) {
+	commonDir := t.TempDir()
+	os.WriteFile(filepath.Join(commonDir, "LAZYWORK_PREVIOUS_BRANCH"), []byte("main"), 0o644)
+	os.WriteFile(filepath.Join(commonDir, "LAZYWORK_STASH_REF"), []byte("stash@{1}"), 0o644)
+
+	gittest.New(t).
+		On("rev-parse --is-inside-work-tree", "true\n").
+		On("rev-parse --show-toplevel --absolute-git-dir --git-common-dir", filepath.Dir(commonDir)+"\n"+commonDir+"\n"+commonDir+"\n").
+		On("stash list --format=%gd%x00%H%x00%ct%x00%gs",
+			"stash@{0}\x00aaa\x001700000000\x00WIP on main: 1234567 initial\n"+
+				"stash@{1}\x00bbb\x001700000100\x00On main: lazywork: auto-stash before worktree use\n"+
+				"stash@{2}\x00ccc\x001700000200\x00On main: lazywork: auto-stash before worktree use\n")
+
+	stdout, _, err := execute(t, "stash", "list", "--json")
+	if err != nil {
+		t.Fatalf("stash list failed: %v", err)
+	}
+
+	var result struct {
+		Count   int `json:"count"`
+		Stashes []struct {
+			Ref   string `json:"ref"`
+			Auto  bool   `json:"auto"`
+			State string `json:"state"`
+		} `json:"stashes"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if result.Count != 3 {
+		t.Fatalf("count = %d, want 3", result.Count)
+	}
+
+	want := []string{"", "pending", "orphaned"}
+	for i, s := range result.Stashes {
+		if s.State != want[i] || s.Auto != (i > 0) {
+			t.Errorf("stash %s = %+v, want state %q", s.Ref, s, want[i])
+		}
+	}
+}
+
+func TestStashApplyNotFound(t *testing.T) {
+	gittest.New(t).
+		On("rev-parse --is-inside-work-tree", "true\n").
+		On("stash list --format=%gd%x00%H%x00%ct%x00%gs", "stash@{0}\x00aaa\x001700000000\x00On main: wip\n")
+
+	_, _, err := execute(t, "stash", "apply", "3")
+	if code := ExitCode(err); code != 4 {
+		t.Errorf("exit code = %d (%v), want 4", code, err)
+	}
+}
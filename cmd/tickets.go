@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/internal/tickets"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"golang.org/x/sync/errgroup"
+)
+
+// ticketTimeout bounds the tracker requests made to decorate a listing
+const ticketTimeout = 3 * time.Second
+
+// branchTickets returns the ticket named in each branch, keyed by branch.
+// Tickets are cached in the state directory for state.TicketTTL; the rest
+// are fetched concurrently, and the first failure is returned alongside
+// whatever was found. Nothing is looked up if no tracker is configured.
+func branchTickets(ctx context.Context, cfg *config.Config, branches []string) (map[string]*tickets.Ticket, error) {
+	trackers := tickets.New(cfg)
+	if trackers.Empty() {
+		return nil, nil
+	}
+
+	var dir string
+	cache := &state.TicketCache{Tickets: map[string]state.CachedTicket{}}
+	if commonDir, err := git.GetCommonDir(ctx); err == nil {
+		dir = state.Dir(commonDir)
+		if loaded, err := state.LoadTickets(dir); err == nil {
+			cache = loaded
+		}
+	}
+
+	now := time.Now()
+	found := map[string]*tickets.Ticket{}
+	missing := map[string]tickets.Tracker{}
+	for _, branch := range branches {
+		key, tracker := trackers.Find(branch)
+		if tracker == nil {
+			continue
+		}
+		if c, ok := cache.Get(key, now); ok {
+			found[key] = &tickets.Ticket{Key: key, Title: c.Title, URL: c.URL, State: c.State, Tracker: c.Tracker}
+		} else {
+			missing[key] = tracker
+		}
+	}
+
+	var firstErr error
+	if len(missing) > 0 {
+		fetchCtx, cancel := context.WithTimeout(ctx, ticketTimeout)
+		defer cancel()
+
+		keys := make([]string, 0, len(missing))
+		for key := range missing {
+			keys = append(keys, key)
+		}
+		fetched := make([]*tickets.Ticket, len(keys))
+		errs := make([]error, len(keys))
+
+		var g errgroup.Group
+		g.SetLimit(checksWorkers)
+		for i, key := range keys {
+			g.Go(func() error {
+				fetched[i], errs[i] = missing[key].Ticket(fetchCtx, key)
+				return nil
+			})
+		}
+		g.Wait()
+
+		for _, err := range errs {
+			if err != nil && !errors.Is(err, tickets.ErrNotFound) && firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		if dir != "" {
+			state.UpdateTickets(dir, func(c *state.TicketCache) {
+				for i, key := range keys {
+					if t := fetched[i]; t != nil {
+						c.Set(key, state.CachedTicket{Title: t.Title, URL: t.URL, State: t.State, Tracker: t.Tracker}, now)
+					}
+				}
+			})
+		}
+		for i, key := range keys {
+			if fetched[i] != nil {
+				found[key] = fetched[i]
+			}
+		}
+	}
+
+	result := map[string]*tickets.Ticket{}
+	for _, branch := range branches {
+		if key, _ := trackers.Find(branch); key != "" && found[key] != nil {
+			result[branch] = found[key]
+		}
+	}
+	return result, firstErr
+}
+
+// ticketColumn formats a ticket for listings, e.g. "ENG-123 Fix login"
+func ticketColumn(t *tickets.Ticket) string {
+	if t == nil {
+		return ""
+	}
+	return t.Key + " " + t.Title
+}
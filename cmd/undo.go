@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/miltonparedes/lazywork/pkg/worktree"
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [id]",
+	Short: "Undo a finish, worktree removal or branch cleanup",
+	Long: `Undo the last destructive operation, or the one with the given id.
+
+'worktree finish', 'worktree remove' and 'branch clean' record what they
+change in a journal kept with the repository's lazywork state: the base
+branch before and after a merge, and the commit of every deleted branch and
+removed worktree. The last 50 operations are kept, and their commits are
+kept from git gc until then.
+
+Undoing a finish resets the base branch to where it was, as long as
+nothing was committed on it since; a merge that was pushed has to be
+reverted on the remote by hand. Deleted branches are recreated at their
+recorded commit and removed worktrees are checked out again, without the
+uncommitted changes they had (see 'worktree remove --archive' for those).
+Parts that can't be restored, such as a branch recreated since, are
+skipped with a warning.
+
+Example:
+  lazywork undo --list
+  lazywork undo
+  lazywork undo 12 --yes`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runUndo,
+}
+
+var (
+	undoList bool
+	undoYes  bool
+)
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+
+	undoCmd.Flags().BoolVarP(&undoList, "list", "l", false, "List the recorded operations")
+	undoCmd.Flags().BoolVarP(&undoYes, "yes", "y", false, "Undo without prompting")
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	if undoList && len(args) > 0 {
+		return lazyerr.New(lazyerr.InvalidArgument, "--list takes no id")
+	}
+	id := 0
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return lazyerr.New(lazyerr.InvalidArgument, "invalid operation id '%s'", args[0]).
+				WithHint("List the recorded operations with: lazywork undo --list")
+		}
+		id = n
+	}
+
+	cfg, _ := loadConfig(ctx)
+	m := newManager(cmd, out, cfg)
+	ops, err := m.Journal(ctx)
+	if err != nil {
+		return err
+	}
+
+	if undoList {
+		if jsonOutput {
+			return out.JSON(schema.UndoList{Operations: ops, Count: len(ops)})
+		}
+		if len(ops) == 0 {
+			out.Info("No recorded operations")
+			return nil
+		}
+		rows := make([][]string, 0, len(ops))
+		for _, op := range ops {
+			summary := op.Summary
+			if op.Undone {
+				summary += " (undone)"
+			}
+			rows = append(rows, []string{strconv.Itoa(op.ID), op.Kind, summary, op.Time.Local().Format("2006-01-02 15:04")})
+		}
+		out.Table([]string{"ID", "KIND", "OPERATION", "TIME"}, rows)
+		return nil
+	}
+
+	if out.IsTTY() && !undoYes && !jsonOutput {
+		op := pendingOperation(ops, id)
+		if op == nil {
+			// Let Undo report why
+			return undoOperation(cmd, m, id)
+		}
+		out.Info(fmt.Sprintf("Operation %d: %s", op.ID, op.Summary))
+		for _, line := range describeOperation(op) {
+			out.Dim("  " + line)
+		}
+		var proceed bool
+		if err := tui.ConfirmForm("Undo it?", &proceed).Run(); err != nil {
+			return err
+		}
+		if !proceed {
+			return lazyerr.New(lazyerr.Cancelled, "undo cancelled")
+		}
+		id = op.ID
+	}
+
+	return undoOperation(cmd, m, id)
+}
+
+func undoOperation(cmd *cobra.Command, m *worktree.Manager, id int) error {
+	out := newOutput(cmd)
+	result, err := m.Undo(cmd.Context(), id)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return out.JSON(schema.Undo{
+			Operation: result.Operation,
+			Reset:     result.Reset,
+			Branches:  result.Branches,
+			Worktrees: result.Worktrees,
+			Skipped:   result.Skipped,
+		})
+	}
+	if len(result.Skipped) > 0 {
+		out.Warning(fmt.Sprintf("Partly undid operation %d: %s", result.Operation.ID, result.Operation.Summary))
+		return nil
+	}
+	out.Success(fmt.Sprintf("Undid operation %d: %s", result.Operation.ID, result.Operation.Summary))
+	return nil
+}
+
+// pendingOperation returns the operation Undo would revert for id, or nil
+// if there is none
+func pendingOperation(ops []worktree.Operation, id int) *worktree.Operation {
+	for i := range ops {
+		if ops[i].Undone {
+			continue
+		}
+		if id == 0 || ops[i].ID == id {
+			return &ops[i]
+		}
+	}
+	return nil
+}
+
+// describeOperation lists what undoing op would do
+func describeOperation(op *worktree.Operation) []string {
+	var lines []string
+	if op.BaseBefore != "" {
+		line := fmt.Sprintf("reset %s to %s", op.Base, shortCommit(op.BaseBefore))
+		if op.Pushed {
+			line += " (locally; the merge was pushed)"
+		}
+		lines = append(lines, line)
+	}
+	for _, b := range op.Branches {
+		lines = append(lines, fmt.Sprintf("recreate branch %s at %s", b.Name, shortCommit(b.Commit)))
+	}
+	for _, wt := range op.Worktrees {
+		lines = append(lines, "recreate worktree "+wt.Path)
+	}
+	return lines
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show AI token usage and estimated spend",
+	Long: `Show the tokens used by AI requests and their estimated cost, for today,
+the last 7 days and this month, followed by a breakdown of the last --days
+days by day, provider, model or command.
+
+Costs are estimated from the input_price and output_price (USD per million
+tokens) of each configured model; models without prices are counted in
+tokens only. Set budget.monthly to be warned, or with budget.action block
+stopped, once this month's estimate reaches it.
+
+Example:
+  lazywork usage
+  lazywork usage --by command --days 30
+  lazywork config set budget.monthly 20`,
+	Args: cobra.NoArgs,
+	RunE: runUsage,
+}
+
+var (
+	usageDays int
+	usageBy   string
+)
+
+// usageGroupings are the accepted --by values
+var usageGroupings = []string{"day", "provider", "model", "command"}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+
+	usageCmd.Flags().IntVar(&usageDays, "days", 7, "Number of days to break down")
+	usageCmd.Flags().StringVar(&usageBy, "by", "day", "Break down by day, provider, model or command")
+	usageCmd.RegisterFlagCompletionFunc("by", cobra.FixedCompletions(usageGroupings, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func summarizeUsage(cfg *config.Config, records []state.UsageRecord) schema.UsageSummary {
+	s := schema.UsageSummary{}
+	for _, r := range records {
+		s.Requests += r.Requests
+		s.PromptTokens += r.PromptTokens
+		s.CompletionTokens += r.CompletionTokens
+	}
+	s.Cost, s.CostComplete = usageCost(cfg, records)
+	return s
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !slices.Contains(usageGroupings, usageBy) {
+		return lazyerr.New(lazyerr.InvalidArgument, "unknown grouping '%s'", usageBy).
+			WithHint("Use --by day, provider, model or command")
+	}
+	if usageDays < 1 {
+		return lazyerr.New(lazyerr.InvalidArgument, "--days must be at least 1")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+	usage, err := state.LoadUsage(state.UserDir())
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.StateReadError, err)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	periods := []struct {
+		label   string
+		key     string
+		summary schema.UsageSummary
+	}{
+		{"Today", "today", summarizeUsage(cfg, usage.Since(today))},
+		{"Last 7 days", "week", summarizeUsage(cfg, usage.Since(today.AddDate(0, 0, -6)))},
+		{"This month", "month", summarizeUsage(cfg, usage.Since(month))},
+	}
+
+	// Group the breakdown period by the chosen dimension
+	groups := map[string][]state.UsageRecord{}
+	for _, r := range usage.Since(today.AddDate(0, 0, 1-usageDays)) {
+		key := map[string]string{"day": r.Date, "provider": r.Provider, "model": r.Model, "command": r.Command}[usageBy]
+		groups[key] = append(groups[key], r)
+	}
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if usageBy == "day" {
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	}
+
+	if jsonOutput {
+		result := schema.Usage{
+			By:    usageBy,
+			Days:  usageDays,
+			Today: periods[0].summary,
+			Week:  periods[1].summary,
+			Month: periods[2].summary,
+			Rows:  make([]schema.UsageRow, 0, len(keys)),
+		}
+		for _, k := range keys {
+			row := schema.UsageRow{Usage: summarizeUsage(cfg, groups[k])}
+			switch usageBy {
+			case "day":
+				row.Day = k
+			case "provider":
+				row.Provider = k
+			case "model":
+				row.Model = k
+			case "command":
+				row.Command = k
+			}
+			result.Rows = append(result.Rows, row)
+		}
+		if cfg.Budget.Monthly > 0 {
+			result.Budget = &schema.Budget{
+				Monthly:   cfg.Budget.Monthly,
+				Action:    budgetAction(cfg.Budget),
+				Remaining: cfg.Budget.Monthly - periods[2].summary.Cost,
+			}
+		}
+		return out.JSON(result)
+	}
+
+	for _, p := range periods {
+		line := fmt.Sprintf("%-12s %s", p.label+":", usageLine(p.summary))
+		if p.key == "month" && cfg.Budget.Monthly > 0 {
+			line += fmt.Sprintf(" of $%.2f budget", cfg.Budget.Monthly)
+		}
+		out.Println(line)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	out.Println()
+	rows := make([][]string, 0, len(keys))
+	for _, k := range keys {
+		s := summarizeUsage(cfg, groups[k])
+		rows = append(rows, []string{
+			k,
+			fmt.Sprint(s.Requests),
+			formatTokens(s.PromptTokens),
+			formatTokens(s.CompletionTokens),
+			formatCost(s),
+		})
+	}
+	out.Table([]string{usageColumn(usageBy), "REQUESTS", "INPUT", "OUTPUT", "COST"}, rows)
+
+	return nil
+}
+
+func usageColumn(by string) string {
+	if by == "day" {
+		return "DATE"
+	}
+	return map[string]string{"provider": "PROVIDER", "model": "MODEL", "command": "COMMAND"}[by]
+}
+
+func usageLine(s schema.UsageSummary) string {
+	return fmt.Sprintf("%d requests, %s tokens, %s", s.Requests, formatTokens(s.PromptTokens+s.CompletionTokens), formatCost(s))
+}
+
+// formatCost prints an estimate, marking it as a lower bound when some
+// models have no price
+func formatCost(s schema.UsageSummary) string {
+	cost := fmt.Sprintf("$%.2f", s.Cost)
+	if !s.CostComplete {
+		cost = "≥" + cost
+	}
+	return cost
+}
+
+// formatTokens abbreviates token counts, e.g. 1.2k or 3.4M
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+func budgetAction(b config.Budget) string {
+	if b.Blocks() {
+		return config.BudgetBlock
+	}
+	return config.BudgetWarn
+}
@@ -1,10 +1,9 @@
 package cmd
 
 import (
-	"encoding/json"
-	"fmt"
 	"runtime"
 
+	"github.com/miltonparedes/lazywork/pkg/schema"
 	"github.com/spf13/cobra"
 )
 
@@ -20,28 +19,27 @@ func init() {
 }
 
 func runVersion(cmd *cobra.Command, args []string) {
-	info := map[string]string{
-		"version":   Version,
-		"commit":    Commit,
-		"buildDate": BuildDate,
-		"go":        runtime.Version(),
-		"os":        runtime.GOOS,
-		"arch":      runtime.GOARCH,
+	info := schema.VersionInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		Go:        runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
 	}
 
+	out := newOutput(cmd)
 	if jsonOutput {
-		enc := json.NewEncoder(Stdout())
-		enc.SetIndent("", "  ")
-		enc.Encode(info)
+		out.JSON(info)
 		return
 	}
 
-	fmt.Printf("lazywork %s\n", Version)
+	out.Print("lazywork %s\n", Version)
 	if Version == "dev" {
-		fmt.Println("  (development build)")
+		out.Println("  (development build)")
 	}
-	fmt.Printf("  commit:  %s\n", Commit)
-	fmt.Printf("  built:   %s\n", BuildDate)
-	fmt.Printf("  go:      %s\n", runtime.Version())
-	fmt.Printf("  os/arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	out.Print("  commit:  %s\n", Commit)
+	out.Print("  built:   %s\n", BuildDate)
+	out.Print("  go:      %s\n", runtime.Version())
+	out.Print("  os/arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
 }
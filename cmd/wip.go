@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/miltonparedes/lazywork/pkg/worktree"
+	"github.com/spf13/cobra"
+)
+
+var wipCmd = &cobra.Command{
+	Use:   "wip",
+	Short: "Quick-save all changes as a wip commit",
+	Long: `Commit everything in the current worktree, new files included, as a
+commit whose subject starts with "wip:", followed by a one-line summary of
+the changes from the AI provider. A failing provider doesn't stop the save;
+the subject is generic instead. Give the summary with --message to skip
+the provider altogether.
+
+--push pushes the branch to origin afterwards and sets its upstream, so
+the work is safe on the remote too.
+
+Before finishing the branch, --squash turns the wip commits at its tip
+into one commit with an AI-generated message, reviewed in the editor like
+'lazywork commit'. Commits already on the main branch are never touched,
+and with --push the squashed branch replaces the one on origin (with
+--force-with-lease). The old commits stay reachable from 'git reflog'.
+
+Example:
+  lazywork wip
+  lazywork wip --push -m "half-done login form"
+  lazywork wip --squash`,
+	Args: cobra.NoArgs,
+	RunE: runWip,
+}
+
+var (
+	wipMessage  string
+	wipPush     bool
+	wipSquash   bool
+	wipYes      bool
+	wipProvider string
+	wipModel    string
+)
+
+func init() {
+	rootCmd.AddCommand(wipCmd)
+
+	wipCmd.Flags().StringVarP(&wipMessage, "message", "m", "", "Summary of the changes, instead of asking the AI provider")
+	wipCmd.Flags().BoolVar(&wipPush, "push", false, "Push the branch to origin afterwards")
+	wipCmd.Flags().BoolVar(&wipSquash, "squash", false, "Squash the wip commits at the tip of the branch into one commit")
+	wipCmd.Flags().BoolVarP(&wipYes, "yes", "y", false, "Commit the squashed message without review")
+	wipCmd.Flags().StringVar(&wipProvider, "provider", "", "AI provider to use (default: models.wip, then default_provider)")
+	wipCmd.Flags().StringVar(&wipModel, "model", "", "Model to use (default: models.wip, then default_model)")
+	wipCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in the changes to the AI provider unmasked")
+}
+
+func runWip(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	branch, err := git.CurrentBranch(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	// rev-parse names a detached HEAD "HEAD"
+	if branch == "HEAD" && (wipPush || wipSquash) {
+		return lazyerr.New(lazyerr.DetachedHead, "HEAD is detached, there is no branch to push or squash")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	if wipSquash {
+		return squashWip(ctx, out, cfg, branch)
+	}
+	return saveWip(ctx, out, cfg, branch)
+}
+
+// saveWip commits every change, untracked files included, as a wip commit
+func saveWip(ctx context.Context, out *output.Output, cfg *config.Config, branch string) error {
+	if !git.HasUncommittedChanges(ctx) {
+		return lazyerr.New(lazyerr.NothingStaged, "no changes to save").
+			WithHint("Squash earlier wip commits with: lazywork wip --squash")
+	}
+
+	tree, err := git.WriteTree(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.CommitError, err).WithHint("Resolve conflicts before saving")
+	}
+	if err := git.StageAll(ctx, true); err != nil {
+		return lazyerr.Wrap(lazyerr.CommitError, err)
+	}
+	subject := commitmsg.WipSubject(wipMessage)
+	if wipMessage == "" {
+		subject = describeWip(ctx, out, cfg)
+	}
+	if err := git.Commit(ctx, subject); err != nil {
+		// Leave the index as it was
+		git.ReadTree(context.WithoutCancel(ctx), tree)
+		return lazyerr.Wrap(lazyerr.CommitError, err)
+	}
+	hash, _ := git.ShortHash(ctx, "HEAD")
+
+	result := schema.Wip{Commit: hash, Message: subject, Branch: branch}
+	if wipPush {
+		if err := pushWip(ctx, out, branch, false); err != nil {
+			return err
+		}
+		result.Pushed = true
+	}
+
+	if jsonOutput {
+		return out.JSON(result)
+	}
+	out.Success(fmt.Sprintf("Saved %s: %s", hash, subject))
+	if result.Pushed {
+		out.Dim(fmt.Sprintf("  pushed: %s/%s", forgeRemote, branch))
+	}
+	return nil
+}
+
+// describeWip asks the AI provider to summarize the staged changes for a
+// wip subject. A quick-save must not fail on the provider, so problems are
+// only warnings and leave the subject generic.
+func describeWip(ctx context.Context, out *output.Output, cfg *config.Config) string {
+	diff, err := git.GetStagedDiff(ctx)
+	if err != nil {
+		return commitmsg.DefaultWipSubject
+	}
+	p, req, err := newAIRequest(out, cfg, "wip", wipProvider, wipModel, commitmsg.WipMessages(ignoreFiles(ctx, out, diff)))
+	if err != nil {
+		out.Warning(fmt.Sprintf("Could not summarize the changes: %v", lazyerr.From(err).Message))
+		return commitmsg.DefaultWipSubject
+	}
+	out.Progress(fmt.Sprintf("Summarizing with %s (%s)", p.Name(), req.Model))
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		out.Warning(fmt.Sprintf("Could not summarize the changes: %v", err))
+		return commitmsg.DefaultWipSubject
+	}
+	return commitmsg.WipSubject(resp.Content)
+}
+
+// squashWip replaces the wip commits at the tip of branch with a single
+// commit with a generated message. Commits on the main branch are left
+// alone.
+func squashWip(ctx context.Context, out *output.Output, cfg *config.Config, branch string) error {
+	if git.HasUncommittedChanges(ctx) {
+		return lazyerr.New(lazyerr.UncommittedChanges, "uncommitted changes would be squashed along").
+			WithHint("Save them first with: lazywork wip")
+	}
+
+	rev := "HEAD"
+	if base := worktree.New(cfg).MainBranch(ctx); base != branch {
+		rev = base + "..HEAD"
+	}
+	commits, err := git.Log(ctx, rev, 0)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	n := 0
+	for n < len(commits) && commitmsg.IsWip(commits[n].Subject) {
+		n++
+	}
+	if n == 0 {
+		if jsonOutput {
+			return out.JSON(schema.WipSquash{Branch: branch})
+		}
+		out.Info(fmt.Sprintf("No wip commits at the tip of %s", branch))
+		return nil
+	}
+
+	parent, err := git.ResolveCommit(ctx, commits[n-1].Hash+"^")
+	if err != nil {
+		return lazyerr.New(lazyerr.BranchError, "the first wip commit has no parent to squash onto")
+	}
+	head := commits[0].Hash
+
+	out.Progress(fmt.Sprintf("Squashing %d wip commit(s)", n))
+	if err := git.ResetSoft(ctx, parent); err != nil {
+		return lazyerr.Wrap(lazyerr.CommitError, err)
+	}
+	hash, message, err := commitSquashed(ctx, out, cfg)
+	if err != nil {
+		// Put the wip commits back
+		git.ResetSoft(context.WithoutCancel(ctx), head)
+		return err
+	}
+
+	result := schema.WipSquash{Commit: hash, Message: message, Branch: branch, Squashed: n}
+	if wipPush {
+		if err := pushWip(ctx, out, branch, true); err != nil {
+			return err
+		}
+		result.Pushed = true
+	}
+
+	if jsonOutput {
+		return out.JSON(result)
+	}
+	subject, _ := tui.SplitMessage(message)
+	out.Success(fmt.Sprintf("Squashed %d wip commit(s) into %s: %s", n, hash, subject))
+	if result.Pushed {
+		out.Dim(fmt.Sprintf("  pushed: %s/%s", forgeRemote, branch))
+	}
+	return nil
+}
+
+// commitSquashed commits the staged changes of the squashed wip commits
+// with a generated message, reviewed unless --yes is set
+func commitSquashed(ctx context.Context, out *output.Output, cfg *config.Config) (hash, message string, err error) {
+	message, style, err := draftCommitMessage(ctx, out, cfg, false)
+	if err != nil {
+		return "", "", err
+	}
+	if message, err = reviewMessage(out, message, !wipYes, style); err != nil {
+		return "", "", err
+	}
+	if err := git.Commit(ctx, message); err != nil {
+		return "", "", lazyerr.Wrap(lazyerr.CommitError, err)
+	}
+	hash, _ = git.ShortHash(ctx, "HEAD")
+	return hash, message, nil
+}
+
+// pushWip pushes branch to origin, replacing its history there when force
+// is set
+func pushWip(ctx context.Context, out *output.Output, branch string, force bool) error {
+	stop := out.Spinner(fmt.Sprintf("Pushing %s to %s", branch, forgeRemote))
+	var err error
+	if force {
+		err = git.PushForce(ctx, forgeRemote, branch)
+	} else {
+		err = git.Push(ctx, forgeRemote, branch)
+	}
+	stop()
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.PushError, err).WithDetail("branch", branch)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/internal/workspace"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage the editor workspace of the repository",
+}
+
+var workspaceGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Write a VS Code workspace with every worktree",
+	Long: `Write a VS Code multi-root workspace (.code-workspace) with a folder for
+the main repository and one for each worktree, so every branch in flight
+shows up in one editor window.
+
+The file is written to <repo>.code-workspace in the repository root (the
+directory holding .bare in a bare layout) and ignored through
+.git/info/exclude; use --output to put it elsewhere. Once generated, it is
+refreshed whenever 'worktree add', 'remove' or 'finish' changes the
+worktrees. Settings and other keys already in the file are kept.
+
+Example:
+  lazywork workspace generate
+  code app.code-workspace`,
+	Args: cobra.NoArgs,
+	RunE: runWorkspaceGenerate,
+}
+
+var workspaceOutput string
+
+func init() {
+	rootCmd.AddCommand(workspaceCmd)
+	workspaceCmd.AddCommand(workspaceGenerateCmd)
+
+	workspaceGenerateCmd.Flags().StringVarP(&workspaceOutput, "output", "o", "", "Workspace file to write (default: <repo>.code-workspace in the repository root)")
+}
+
+func runWorkspaceGenerate(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	root, err := git.MainWorktreePath(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.PathError, err)
+	}
+	path := filepath.Join(root, filepath.Base(root)+workspace.Ext)
+	if workspaceOutput != "" {
+		if path, err = filepath.Abs(workspaceOutput); err != nil {
+			return lazyerr.Wrap(lazyerr.PathError, err)
+		}
+	}
+
+	folders, err := writeWorkspace(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	// Keep the default file out of 'git status' in the main checkout
+	if workspaceOutput == "" && !isBareLayout(ctx) {
+		if err := git.ExcludeLocal(ctx, "/"+filepath.Base(path)); err != nil {
+			out.Warning(fmt.Sprintf("Could not add %s to .git/info/exclude: %v", filepath.Base(path), err))
+		}
+	}
+
+	if commonDir, err := git.GetCommonDir(ctx); err == nil {
+		if err := state.SaveWorkspace(state.Dir(commonDir), state.Workspace{Path: path}); err != nil {
+			out.Warning(fmt.Sprintf("Workspace will not be refreshed: %v", err))
+		}
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.Workspace{Path: path, Folders: folders})
+	}
+
+	out.Success(fmt.Sprintf("Wrote %s with %d folder(s)", path, len(folders)))
+	out.Dim("  open it with: code " + path)
+	return nil
+}
+
+// writeWorkspace writes the workspace file at path with every worktree
+func writeWorkspace(ctx context.Context, path string) ([]workspace.Folder, error) {
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+	folders := workspace.Folders(worktrees, filepath.Dir(path))
+	if err := workspace.Write(path, folders); err != nil {
+		return nil, lazyerr.Wrap(lazyerr.WorkspaceError, err).WithDetail("path", path)
+	}
+	return folders, nil
+}
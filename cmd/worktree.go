@@ -1,14 +1,27 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/miltonparedes/lazywork/internal/archive"
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+	"github.com/miltonparedes/lazywork/internal/forge"
 	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
 	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/shell"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/internal/tickets"
 	"github.com/miltonparedes/lazywork/internal/tui"
 	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/miltonparedes/lazywork/pkg/worktree"
 	"github.com/spf13/cobra"
 )
 
@@ -21,23 +34,86 @@ var worktreeCmd = &cobra.Command{
 var worktreeListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all worktrees",
-	RunE:  runWorktreeList,
+	Long: `List all worktrees with their branch and path.
+
+Notes, tags and issue links set with 'lazywork worktree note' are shown in
+the NOTES column. Use --status to add uncommitted changes and
+ahead/behind counts, and --checks to add the CI status of each branch from
+the forge (see 'lazywork pr'). --fetch runs 'git fetch --prune' first;
+auto_fetch in the config does so whenever --status is given.
+
+Filters narrow the list, --json output included; given several, a
+worktree must match them all:
+  --tag <tag>             tagged with 'worktree note --tag' (repeatable)
+  --branch-prefix <p>     branch starts with p
+  --dirty                 uncommitted or untracked changes
+  --merged                branch merged into the main branch
+  --stale <age>           no commits or 'worktree go' visits for age
+                          (e.g. 30d, 2w, 12h); adds a LAST ACTIVE column
+
+Example:
+  lazywork worktree list --stale 30d --merged
+  lazywork worktree list --dirty --branch-prefix fix/ --json`,
+	RunE: runWorktreeList,
 }
 
 var worktreeAddCmd = &cobra.Command{
 	Use:   "add [name]",
 	Short: "Create a new worktree",
 	Long: `Create a new worktree with the specified name.
-The worktree will be created in .worktrees/<name> by default.
+The worktree will be created in .worktrees/<name> by default; set
+worktree_dir (e.g. "~/worktrees/{repo}") to place it elsewhere.
 
 If no name is provided, you'll be prompted to enter one interactively.
 
+If envrc_template is set in the config, an .envrc is generated in the new
+worktree and 'direnv allow' is run for it (skip with --no-envrc).
+
+Submodules are initialized in the new worktree when worktree_submodules is
+true in the config or --submodules is given.
+
+Use --sparse to only check out some directories of a large repository
+(files at the root are always included); worktree_sparse in the config sets
+the default and --no-sparse checks out the full tree.
+
+Use --issue to start work on an issue from the forge (see 'lazywork pr'):
+without a name the branch is named after the issue, and the issue is linked
+to the worktree so 'pr create' closes it.
+
+With --push, or worktree_push in the config, the branch is pushed to origin
+and set as its upstream right away, so CI and collaborators see it and
+'status' can tell when it falls behind. A failed push only warns.
+
+Started the work on the wrong branch? --from-stash moves the uncommitted
+changes of the current checkout, untracked files included, into the new
+worktree. Without changes it prompts for a stash to apply instead, and
+--from-stash=<stash> applies that stash; applied stashes are kept. If the
+changes cannot be applied they stay in the stash, so nothing is lost.
+
+Started hacking before naming the work? --from-changes sends the
+uncommitted diff to the AI provider for a branch name and a one-line
+summary, creates the worktree under that name (offered for editing on a
+terminal, or replaced by the name given) and moves the changes into it, as
+--from-stash does. The summary becomes the worktree's note.
+
 Example:
   lazywork worktree add feature-auth
   # Creates .worktrees/feature-auth with branch feature-auth
 
   lazywork worktree add
-  # Prompts for branch name interactively`,
+  # Prompts for branch name interactively
+
+  lazywork worktree add feature-web --sparse apps/web --sparse libs/ui
+  # Only materializes apps/web, libs/ui and the files at the root
+
+  lazywork worktree add --issue 42
+  # Creates .worktrees/42-fix-login-redirect for issue #42
+
+  lazywork worktree add fix-typo --from-stash
+  # Moves the current changes into .worktrees/fix-typo
+
+  lazywork worktree add --from-changes
+  # Names a worktree after the current changes and moves them into it`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runWorktreeAdd,
 }
@@ -46,8 +122,15 @@ var worktreeRemoveCmd = &cobra.Command{
 	Use:     "remove <name>",
 	Aliases: []string{"rm"},
 	Short:   "Remove a worktree",
-	Args:    cobra.ExactArgs(1),
-	RunE:    runWorktreeRemove,
+	Long: `Remove a worktree. Its branch is kept.
+
+With --archive, or worktree_archive in the config, what removing it would
+lose is archived first: the commits not merged into the main branch, the
+uncommitted changes and the untracked files. As they are kept, a worktree
+with uncommitted changes is removed without --force. See 'lazywork archive'
+to list and restore archives.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorktreeRemove,
 }
 
 var worktreePruneCmd = &cobra.Command{
@@ -62,6 +145,17 @@ var worktreeGoCmd = &cobra.Command{
 	Long: `Navigate to a worktree directory.
 
 If no name is provided, you'll be prompted to select one interactively.
+Worktrees are ordered by frecency (how often and how recently you visited
+them). A number jumps to that position in the list, e.g. 'lwt go 1' to the
+most frecent worktree. A partial name resolves when it matches a single
+worktree; if it matches several, you pick one (or, without a terminal, the
+command fails listing them).
+Use 'main' or 'root' to go back to the main checkout, and '-' to go back to
+the previously visited worktree.
+
+Worktrees anywhere on disk are listed, not only those under worktree_dir.
+Set worktree_include_main to also list the main checkout, marked as such,
+alongside them.
 
 Setup shell integration for automatic cd:
   # Bash/Zsh
@@ -70,6 +164,9 @@ Setup shell integration for automatic cd:
   # Fish
   lazywork shell init fish | source
 
+  # PowerShell
+  Invoke-Expression (& lazywork shell init powershell | Out-String)
+
 Then use: lwt go [name]`,
 	Aliases: []string{"cd"},
 	Args:    cobra.MaximumNArgs(1),
@@ -87,7 +184,18 @@ only works in the main repository directory.
 The command will:
 1. Stash any uncommitted changes (with your permission)
 2. Checkout the worktree's branch
-3. Save state so you can return later with 'worktree return'`,
+3. Save state so you can return later with 'worktree return'
+
+'use' can be repeated to switch to another worktree branch; each 'return'
+then goes back one step. Show the stack with --status, from the main
+checkout or any worktree. Auto-stashes that were never restored are listed
+by 'lazywork stash list'.
+
+If 'use' was interrupted, or 'return' fails on the saved state, --repair
+checks the state against the branches, the stash list and HEAD: a 'use'
+stopped before switching branches is rolled back, frames returning to
+deleted branches or stashes are fixed, and unreadable state is discarded.
+Stashes are only removed once restored.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runWorktreeUse,
 }
@@ -95,7 +203,7 @@ The command will:
 var worktreeReturnCmd = &cobra.Command{
 	Use:   "return",
 	Short: "Return to previous branch after 'use'",
-	Long: `Return to the branch you were on before using 'worktree use'.
+	Long: `Return to the branch you were on before the last 'worktree use'.
 
 This will:
 1. Checkout the previous branch
@@ -108,16 +216,87 @@ var worktreeFinishCmd = &cobra.Command{
 	Short: "Merge worktree branch and cleanup",
 	Long: `Merge a worktree's branch into the current branch and optionally clean up.
 
-This command must be run from the main branch (main/master).
-After a successful merge, you'll be asked if you want to delete
-the worktree and its branch.`,
+This command must be run from the main branch (main/master, or the
+main_branch setting). Before anything changes you'll be asked if you want to
+also delete the worktree and its branch; --cleanup does so without asking.
+
+Finishing runs in steps: fetch, conflict check, merge (or --squash into a
+single commit), push of the main branch (--push-base) and cleanup. If a
+step fails, the ones before it are undone, so the repository is left either
+finished or as it was; a push is never undone, so a failed cleanup after it
+keeps the merge. --dry-run prints the steps without running them.
+
+With --push the branch is pushed to origin and a pull request (a merge
+request on GitLab) into the main branch is opened instead of merging
+locally; the worktree is kept until it is merged. See 'lazywork pr'.
+
+Before merging, the files that would conflict are listed and you're asked
+whether to go ahead; without a terminal finish stops instead. --check only
+reports them (exit code 6 if there are any).
+
+When the merge needs a merge commit and an AI provider is configured, its
+message is written from the branch's commits and diffstat; pass
+--no-ai-message to keep git's "Merge branch ..." message.
+
+--all-merged finishes every worktree whose branch is already merged into
+the main branch, locally or through a merged pull request on the forge, by
+removing it and deleting its branch. A worktree with uncommitted changes,
+or whose branch has commits the pull request doesn't, is kept; failures are
+reported per worktree without stopping the others.
+
+--fetch, or auto_fetch in the config, runs 'git fetch --prune' first.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runWorktreeFinish,
 }
 
+// addStashChanges is the value of a bare --from-stash: move the uncommitted
+// changes
+const addStashChanges = "changes"
+
 var (
 	forceRemove bool
-	fromBranch  string
+	// archiveRemove archives a worktree before removing it
+	archiveRemove bool
+	fromBranch    string
+	noEnvrc       bool
+	listTags      []string
+	listStatus    bool
+	submodules    bool
+	sparse        []string
+	noSparse      bool
+	useStatus     bool
+	useRepair     bool
+	addPush       bool
+	addIssue      int
+	addStash      string
+	listChecks    bool
+	finishPush    bool
+	finishNoAI    bool
+	finishCheck   bool
+	// finishPushBase pushes the main branch after merging, unlike
+	// finishPush which opens a pull request instead
+	finishPushBase bool
+	finishSquash   bool
+	finishCleanup  bool
+	finishDryRun   bool
+	finishMerged   bool
+
+	// Filters of 'worktree list' besides --tag
+	listDirty        bool
+	listStale        string
+	listMerged       bool
+	listBranchPrefix string
+
+	// addFromChanges names the worktree after the uncommitted changes and
+	// moves them into it
+	addFromChanges bool
+
+	// AI provider and model of 'worktree add --from-changes' and of the
+	// merge message of 'worktree finish'
+	addProvider    string
+	addModel       string
+	finishProvider string
+	finishModel    string
 )
 
 func init() {
@@ -132,212 +311,644 @@ func init() {
 	worktreeCmd.AddCommand(worktreeFinishCmd)
 
 	worktreeRemoveCmd.Flags().BoolVarP(&forceRemove, "force", "f", false, "Force removal even with uncommitted changes")
+	worktreeRemoveCmd.Flags().BoolVar(&archiveRemove, "archive", false, "Archive unmerged commits, changes and untracked files first (default from worktree_archive)")
 	worktreeAddCmd.Flags().StringVarP(&fromBranch, "branch", "b", "", "Create worktree from existing branch instead of new branch")
+	worktreeAddCmd.Flags().BoolVar(&noEnvrc, "no-envrc", false, "Skip .envrc generation even if envrc_template is configured")
+	worktreeAddCmd.Flags().BoolVar(&submodules, "submodules", false, "Initialize submodules in the new worktree (default from worktree_submodules)")
+	worktreeAddCmd.Flags().BoolVar(&addPush, "push", false, "Push the branch to origin and set its upstream (default from worktree_push)")
+	worktreeAddCmd.Flags().StringSliceVar(&sparse, "sparse", nil, "Only check out these directories (repeatable, default from worktree_sparse)")
+	worktreeAddCmd.Flags().BoolVar(&noSparse, "no-sparse", false, "Check out the full tree even if worktree_sparse is configured")
+	worktreeAddCmd.Flags().IntVar(&addIssue, "issue", 0, "Start work on this forge issue, naming the branch after it")
+	worktreeAddCmd.Flags().StringVar(&addStash, "from-stash", "", "Move the uncommitted changes into the worktree, or apply --from-stash=<stash>")
+	worktreeAddCmd.Flags().Lookup("from-stash").NoOptDefVal = addStashChanges
+	worktreeAddCmd.Flags().BoolVar(&addFromChanges, "from-changes", false, "Name the worktree after the uncommitted changes with AI and move them into it")
+	worktreeAddCmd.Flags().StringVar(&addProvider, "provider", "", "AI provider for --from-changes (default: models.worktree_add, then default_provider)")
+	worktreeAddCmd.Flags().StringVar(&addModel, "model", "", "Model for --from-changes (default: models.worktree_add, then default_model)")
+	worktreeAddCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in the changes to the AI provider unmasked")
+	worktreeAddCmd.MarkFlagsMutuallyExclusive("from-changes", "from-stash")
+	worktreeAddCmd.MarkFlagsMutuallyExclusive("from-changes", "issue")
+	worktreeAddCmd.RegisterFlagCompletionFunc("branch", completeBranches)
+	worktreeFinishCmd.Flags().BoolVar(&finishPush, "push", false, "Push the branch and open a pull request instead of merging locally")
+	worktreeFinishCmd.Flags().BoolVar(&finishCheck, "check", false, "Only report the files that would conflict, without merging")
+	worktreeFinishCmd.Flags().BoolVar(&fetchFirst, "fetch", false, "Run 'git fetch --prune' first (default from auto_fetch)")
+	worktreeFinishCmd.Flags().BoolVar(&finishSquash, "squash", false, "Commit the branch's changes as a single commit instead of merging it")
+	worktreeFinishCmd.Flags().BoolVar(&finishPushBase, "push-base", false, "Push the main branch to origin after merging; a failed push undoes the merge")
+	worktreeFinishCmd.Flags().BoolVar(&finishCleanup, "cleanup", false, "Remove the worktree and delete its branch without asking")
+	worktreeFinishCmd.Flags().BoolVar(&finishDryRun, "dry-run", false, "Print the steps finishing would run, without running them")
+	worktreeFinishCmd.Flags().BoolVar(&finishMerged, "all-merged", false, "Remove every worktree whose branch is already merged, locally or through a pull request")
+	worktreeFinishCmd.MarkFlagsMutuallyExclusive("check", "push")
+	for _, flag := range []string{"check", "push", "squash", "push-base"} {
+		worktreeFinishCmd.MarkFlagsMutuallyExclusive("all-merged", flag)
+	}
+	worktreeFinishCmd.MarkFlagsMutuallyExclusive("push", "push-base")
+	worktreeFinishCmd.MarkFlagsMutuallyExclusive("push", "squash")
+	worktreeFinishCmd.MarkFlagsMutuallyExclusive("check", "dry-run")
+	worktreeFinishCmd.MarkFlagsMutuallyExclusive("push", "dry-run")
+	worktreeFinishCmd.Flags().BoolVar(&finishNoAI, "no-ai-message", false, "Use git's default merge commit message")
+	worktreeFinishCmd.Flags().StringVar(&finishProvider, "provider", "", "AI provider for the merge message (default: models.worktree_finish, then default_provider)")
+	worktreeFinishCmd.Flags().StringVar(&finishModel, "model", "", "Model for the merge message (default: models.worktree_finish, then default_model)")
+	worktreeFinishCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in the changes to the AI provider unmasked")
+	worktreeUseCmd.Flags().BoolVar(&useStatus, "status", false, "Show the stack of branches in use instead of switching")
+	worktreeUseCmd.Flags().BoolVar(&useRepair, "repair", false, "Check the saved state against the branches, stashes and HEAD, and fix it")
+	worktreeUseCmd.MarkFlagsMutuallyExclusive("status", "repair")
+	worktreeListCmd.Flags().StringSliceVar(&listTags, "tag", nil, "Only list worktrees with this tag (repeatable)")
+	worktreeListCmd.Flags().BoolVarP(&listStatus, "status", "s", false, "Show uncommitted changes and ahead/behind counts")
+	worktreeListCmd.Flags().BoolVar(&listDirty, "dirty", false, "Only list worktrees with uncommitted or untracked changes")
+	worktreeListCmd.Flags().StringVar(&listStale, "stale", "", "Only list worktrees without commits or visits for this long (e.g. 30d, 2w, 12h)")
+	worktreeListCmd.Flags().BoolVar(&listMerged, "merged", false, "Only list worktrees whose branch is merged into the main branch")
+	worktreeListCmd.Flags().StringVar(&listBranchPrefix, "branch-prefix", "", "Only list worktrees whose branch starts with this prefix (e.g. fix/)")
+	worktreeListCmd.Flags().BoolVar(&fetchFirst, "fetch", false, "Run 'git fetch --prune' first (default from auto_fetch with --status)")
+	worktreeListCmd.Flags().BoolVar(&listChecks, "checks", false, "Show the CI status of each branch from the forge")
+
+	for _, c := range []*cobra.Command{worktreeRemoveCmd, worktreeUseCmd, worktreeFinishCmd} {
+		c.ValidArgsFunction = completeWorktreeNames
+	}
+	worktreeGoCmd.ValidArgsFunction = completeGoTargets
 }
 
 func runWorktreeList(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
+	out := newOutput(cmd)
+	ctx := cmd.Context()
 
-	if !git.IsInsideWorkTree() {
-		err := fmt.Errorf("not inside a git repository")
-		out.ErrorResult(err, "NOT_GIT_REPO")
-		return err
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
 	}
 
-	worktrees, err := git.ListWorktrees()
+	worktrees, err := git.ListWorktrees(ctx)
 	if err != nil {
-		out.ErrorResult(err, "WORKTREE_LIST_ERROR")
-		return err
+		return lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+
+	var staleAge time.Duration
+	if listStale != "" {
+		if staleAge, err = config.ParseAge(listStale); err != nil {
+			return lazyerr.Wrap(lazyerr.InvalidArgument, err).WithDetail("stale", listStale)
+		}
+		if staleAge == 0 {
+			return lazyerr.New(lazyerr.InvalidArgument, "--stale must be longer than zero")
+		}
+	}
+
+	meta := loadMeta(ctx)
+	if meta == nil {
+		meta = &state.MetaStore{}
+	}
+	mainPath := ""
+	if len(worktrees) > 0 {
+		mainPath = worktrees[0].Path
+	}
+	if len(listTags) > 0 {
+		worktrees = filterByTags(worktrees, meta, listTags)
+	}
+	if listBranchPrefix != "" {
+		worktrees = filterByBranchPrefix(worktrees, listBranchPrefix)
+	}
+
+	cfg, cfgErr := loadConfig(ctx)
+	m := newManager(cmd, out, cfg)
+
+	// Worktrees idle for worktree_stale_after are pointed out after the
+	// table; a bad value is reported by 'config validate'
+	staleAfter := config.DefaultStaleAfter
+	if cfgErr == nil {
+		staleAfter, _ = cfg.GetStaleAfter()
+	}
+
+	// auto_fetch only applies when there is remote data to show
+	if fetchFirst || listStatus {
+		fetchRemote(ctx, out, cfg)
+	}
+
+	var base string
+	if listStatus || listDirty || listMerged {
+		base = statusBase(ctx)
+	}
+	var statuses []git.Status
+	if listStatus || listDirty {
+		statuses = git.StatusAll(ctx, worktrees, base, git.DefaultStatusWorkers)
+	}
+	var activity []time.Time
+	if staleAge > 0 || staleAfter > 0 {
+		activity = m.LastActivity(ctx, worktrees)
+	}
+	now := time.Now()
+	filtered := len(listTags) > 0 || listBranchPrefix != ""
+	if f := (worktreeFilter{m: m, base: base, mainPath: mainPath, dirty: listDirty, merged: listMerged, stale: staleAge, now: now}); f.active() {
+		worktrees, statuses, activity = f.apply(ctx, worktrees, statuses, activity)
+		filtered = true
+	}
+	if !listStatus {
+		statuses = nil
+	}
+	stale := make([]bool, len(worktrees))
+	var staleNames []string
+	for i, wt := range worktrees {
+		if activity != nil && wt.Path != mainPath && !wt.Bare && worktree.Stale(activity[i], staleAfter, now) {
+			stale[i] = true
+			staleNames = append(staleNames, filepath.Base(wt.Path))
+		}
+	}
+
+	var checks []*forge.Checks
+	if listChecks {
+		if cfgErr != nil {
+			return lazyerr.Wrap(lazyerr.ConfigLoadError, cfgErr)
+		}
+		f, err := detectForge(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		out.Progress("Reading CI status from " + f.Name())
+		checks, err = worktreeChecks(ctx, f, worktrees)
+		if err != nil {
+			out.Warning(fmt.Sprintf("Could not read CI status: %v", err))
+		}
+	}
+
+	var branchTicket map[string]*tickets.Ticket
+	if cfgErr == nil {
+		branches := make([]string, 0, len(worktrees))
+		for _, wt := range worktrees {
+			branches = append(branches, wt.Branch)
+		}
+		branchTicket, err = branchTickets(ctx, cfg, branches)
+		if err != nil {
+			out.Warning(fmt.Sprintf("Could not read tickets: %v", err))
+		}
 	}
 
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"worktrees": worktrees,
-			"count":     len(worktrees),
-		})
+		items := make([]schema.WorktreeItem, 0, len(worktrees))
+		for i, wt := range worktrees {
+			item := schema.WorktreeItem{Worktree: wt}
+			if m := meta.Get(wt.Path); !m.IsEmpty() {
+				item.Meta = &m
+			}
+			if statuses != nil && !wt.Bare {
+				item.Status = &statuses[i]
+			}
+			if checks != nil {
+				item.Checks = checks[i]
+			}
+			if activity != nil && !activity[i].IsZero() {
+				item.LastActivity = &activity[i]
+			}
+			item.Stale = stale[i]
+			item.Ticket = branchTicket[wt.Branch]
+			items = append(items, item)
+		}
+		return out.JSON(schema.WorktreeList{Worktrees: items, Count: len(items)})
 	}
 
 	if len(worktrees) == 0 {
-		out.Dim("No worktrees found")
+		if filtered {
+			out.Dim("No worktrees match the filters")
+		} else {
+			out.Dim("No worktrees found")
+		}
 		return nil
 	}
 
-	out.Bold(fmt.Sprintf("Worktrees (%d):", len(worktrees)))
-	out.Println()
-
+	showNotes := false
 	for _, wt := range worktrees {
-		if wt.Bare {
-			out.Print("  %s (bare)\n", wt.Path)
-		} else {
-			branch := wt.Branch
-			if branch == "" {
-				branch = fmt.Sprintf("(detached at %s)", wt.Head[:7])
+		if !meta.Get(wt.Path).IsEmpty() {
+			showNotes = true
+			break
+		}
+	}
+
+	rows := make([][]string, 0, len(worktrees))
+	for i, wt := range worktrees {
+		branch := wt.Branch
+		switch {
+		case wt.Bare:
+			branch = "(bare)"
+		case branch == "":
+			branch = fmt.Sprintf("(detached at %s)", wt.Head[:7])
+		}
+		row := []string{filepath.Base(wt.Path), branch, wt.Path}
+		if statuses != nil {
+			if wt.Bare {
+				row = append(row, "", "")
+			} else {
+				row = append(row, statusChanges(statuses[i]), statusSync(statuses[i]))
 			}
-			out.Print("  %s\n", filepath.Base(wt.Path))
-			out.Dim(fmt.Sprintf("    branch: %s", branch))
-			out.Dim(fmt.Sprintf("    path:   %s", wt.Path))
 		}
+		if checks != nil {
+			row = append(row, checksColumn(checks[i]))
+		}
+		if staleAge > 0 {
+			row = append(row, ageColumn(activity[i], now))
+		}
+		if len(branchTicket) > 0 {
+			row = append(row, ticketColumn(branchTicket[wt.Branch]))
+		}
+		if showNotes {
+			row = append(row, metaColumn(meta.Get(wt.Path)))
+		}
+		rows = append(rows, row)
+	}
+
+	headers := []string{"NAME", "BRANCH", "PATH"}
+	if statuses != nil {
+		headers = append(headers, "CHANGES", "SYNC")
+	}
+	if checks != nil {
+		headers = append(headers, "CHECKS")
+	}
+	if staleAge > 0 {
+		headers = append(headers, "LAST ACTIVE")
+	}
+	if len(branchTicket) > 0 {
+		headers = append(headers, "TICKET")
+	}
+	if showNotes {
+		headers = append(headers, "NOTES")
+	}
+	out.Table(headers, rows)
+
+	if len(staleNames) > 0 {
 		out.Println()
+		out.Warning(fmt.Sprintf("%d worktree(s) without commits or visits for %s: %s", len(staleNames), formatAge(staleAfter), strings.Join(staleNames, ", ")))
+		out.Dim("  Remove them with: lazywork worktree clean --stale")
 	}
 
 	return nil
 }
 
+// filterByTags keeps the worktrees tagged with every tag in tags
+func filterByTags(worktrees []git.Worktree, meta *state.MetaStore, tags []string) []git.Worktree {
+	var filtered []git.Worktree
+	for _, wt := range worktrees {
+		m := meta.Get(wt.Path)
+		keep := true
+		for _, tag := range tags {
+			if !m.HasTag(tag) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			filtered = append(filtered, wt)
+		}
+	}
+	return filtered
+}
+
+// filterByBranchPrefix keeps the worktrees whose branch starts with prefix
+func filterByBranchPrefix(worktrees []git.Worktree, prefix string) []git.Worktree {
+	var filtered []git.Worktree
+	for _, wt := range worktrees {
+		if strings.HasPrefix(wt.Branch, prefix) {
+			filtered = append(filtered, wt)
+		}
+	}
+	return filtered
+}
+
+// worktreeFilter applies the --dirty, --merged and --stale filters of
+// 'worktree list'
+type worktreeFilter struct {
+	m    *worktree.Manager
+	base string
+	// mainPath is the main checkout, which is never merged nor stale
+	mainPath string
+	dirty    bool
+	merged   bool
+	// stale is the --stale age, zero without it
+	stale time.Duration
+	now   time.Time
+}
+
+func (f worktreeFilter) active() bool {
+	return f.dirty || f.merged || f.stale > 0
+}
+
+// apply keeps the worktrees matching every filter given, along with their
+// statuses and activity when those were read
+func (f worktreeFilter) apply(ctx context.Context, worktrees []git.Worktree, statuses []git.Status, activity []time.Time) ([]git.Worktree, []git.Status, []time.Time) {
+	var keptWorktrees []git.Worktree
+	var keptStatuses []git.Status
+	var keptActivity []time.Time
+	for i, wt := range worktrees {
+		switch {
+		case f.dirty && (wt.Bare || !statuses[i].Dirty()):
+			continue
+		case f.merged && (wt.Path == f.mainPath || wt.Branch == f.base || !f.m.Merged(ctx, wt, f.base)):
+			continue
+		case f.stale > 0 && (wt.Path == f.mainPath || !worktree.Stale(activity[i], f.stale, f.now)):
+			continue
+		}
+		keptWorktrees = append(keptWorktrees, wt)
+		if statuses != nil {
+			keptStatuses = append(keptStatuses, statuses[i])
+		}
+		if activity != nil {
+			keptActivity = append(keptActivity, activity[i])
+		}
+	}
+	return keptWorktrees, keptStatuses, keptActivity
+}
+
+// ageColumn formats the time since t, e.g. "45 days ago"
+func ageColumn(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	switch d := now.Sub(t); {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%d days ago", int(d/(24*time.Hour)))
+	case d >= 2*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(d/time.Hour))
+	default:
+		return "recently"
+	}
+}
+
+// formatAge formats an age such as worktree_stale_after, e.g. "30 days"
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d == day:
+		return "1 day"
+	case d > day && d%day == 0:
+		return fmt.Sprintf("%d days", d/day)
+	}
+	return d.String()
+}
+
+// metaColumn formats worktree metadata for the NOTES column of a listing
+func metaColumn(m state.Meta) string {
+	parts := []string{}
+	if s := m.Summary(); s != "" {
+		parts = append(parts, s)
+	}
+	if m.Issue != "" {
+		parts = append(parts, m.Issue)
+	}
+	return strings.Join(parts, " ")
+}
+
 func runWorktreeAdd(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
+	out := newOutput(cmd)
+	ctx := cmd.Context()
 
-	if !git.IsInsideWorkTree() {
-		err := fmt.Errorf("not inside a git repository")
-		out.ErrorResult(err, "NOT_GIT_REPO")
-		return err
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
 	}
 
-	cfg, err := config.LoadFrom(cfgFile)
+	cfg, err := loadConfig(ctx)
 	if err != nil {
-		out.ErrorResult(err, "CONFIG_LOAD_ERROR")
-		return err
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	var issue *forge.Issue
+	if addIssue > 0 {
+		f, err := detectForge(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		if issue, err = fetchIssue(ctx, f, addIssue); err != nil {
+			return err
+		}
+	}
+
+	var name, summary string
+	if addFromChanges {
+		if !git.HasUncommittedChanges(ctx) {
+			return lazyerr.New(lazyerr.NothingStaged, "no uncommitted changes to start a worktree from").
+				WithHint("Name the worktree instead: lazywork worktree add <name>")
+		}
+		if name, summary, err = suggestBranch(ctx, out, cfg); err != nil {
+			return err
+		}
 	}
 
-	var name string
 	if len(args) > 0 {
 		name = args[0]
+	} else if issue != nil {
+		name = forge.IssueBranch(*issue)
 	} else if out.IsTTY() {
+		// A suggested name is offered for editing
 		form := tui.BranchNameForm(&name)
 		if err := form.Run(); err != nil {
 			return err
 		}
 		name = strings.TrimSpace(name)
 		if name == "" {
-			err := fmt.Errorf("branch name cannot be empty")
-			out.ErrorResult(err, "EMPTY_NAME")
-			return err
+			return lazyerr.New(lazyerr.EmptyName, "branch name cannot be empty")
 		}
-	} else {
-		err := fmt.Errorf("branch name required (use: lazywork worktree add <name>)")
-		out.ErrorResult(err, "NAME_REQUIRED")
-		return err
+	} else if name == "" {
+		return lazyerr.New(lazyerr.NameRequired, "branch name required (use: lazywork worktree add <name>)")
 	}
 
-	worktreePath, err := git.GetWorktreePath(cfg.GetWorktreeDir(), name)
-	if err != nil {
-		out.ErrorResult(err, "PATH_ERROR")
-		return err
+	sparseDirs := cfg.WorktreeSparse
+	if cmd.Flags().Changed("sparse") {
+		sparseDirs = sparse
+	}
+	if noSparse {
+		sparseDirs = nil
+	}
+	withSubmodules := cfg.WorktreeSubmodules
+	if cmd.Flags().Changed("submodules") {
+		withSubmodules = submodules
+	}
+	withPush := cfg.WorktreePush
+	if cmd.Flags().Changed("push") {
+		withPush = addPush
+	}
+	var issueURL string
+	if issue != nil {
+		issueURL = issue.URL
 	}
 
-	var branch string
-	if fromBranch != "" {
-		// Use existing branch
-		if !git.BranchExists(fromBranch) {
-			err := fmt.Errorf("branch '%s' does not exist", fromBranch)
-			out.ErrorResult(err, "BRANCH_NOT_FOUND")
-			return err
-		}
-		branch = fromBranch
-		err = git.AddWorktreeFromBranch(worktreePath, branch)
-	} else {
-		// Create new branch
-		branch = name
-		if git.BranchExists(branch) {
-			err := fmt.Errorf("branch '%s' already exists. Use --branch to checkout existing branch", branch)
-			out.ErrorResult(err, "BRANCH_EXISTS")
-			return err
+	var stashRef string
+	moveChanges := addFromChanges
+	if cmd.Flags().Changed("from-stash") {
+		switch {
+		case addStash != addStashChanges:
+			stashRef = addStash
+		case git.HasUncommittedChanges(ctx):
+			moveChanges = true
+		case out.IsTTY():
+			stash, err := selectStash(ctx, out, nil, "apply")
+			if err != nil {
+				return err
+			}
+			stashRef = stash.Ref
+		default:
+			return lazyerr.New(lazyerr.NameRequired, "no uncommitted changes to move").
+				WithHint("Pass the stash to apply: --from-stash=<stash>")
 		}
-		err = git.AddWorktree(worktreePath, branch)
 	}
 
+	result, err := newManager(cmd, out, cfg).Add(ctx, worktree.AddOptions{
+		Name:        name,
+		Branch:      fromBranch,
+		Sparse:      sparseDirs,
+		Submodules:  withSubmodules,
+		Push:        withPush,
+		Remote:      forgeRemote,
+		NoEnvrc:     noEnvrc,
+		Issue:       issueURL,
+		Note:        summary,
+		Stash:       stashRef,
+		MoveChanges: moveChanges,
+	})
 	if err != nil {
-		out.ErrorResult(err, "WORKTREE_ADD_ERROR")
 		return err
 	}
 
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"path":    worktreePath,
-			"branch":  branch,
-			"created": true,
+		sparse := result.Sparse
+		if sparse == nil {
+			sparse = []string{}
+		}
+		return out.JSON(schema.WorktreeAdd{
+			Path:       result.Path,
+			Branch:     result.Branch,
+			Created:    true,
+			Envrc:      result.Envrc,
+			Submodules: result.Submodules,
+			Sparse:     sparse,
+			Issue:      issueURL,
+			Pushed:     result.Pushed,
+			Stash:      result.Stash,
+			Moved:      result.Moved,
+			Summary:    summary,
 		})
 	}
 
 	out.Success(fmt.Sprintf("Created worktree: %s", name))
-	out.Dim(fmt.Sprintf("  branch: %s", branch))
-	out.Dim(fmt.Sprintf("  path:   %s", worktreePath))
+	out.Dim(fmt.Sprintf("  branch: %s", result.Branch))
+	out.Dim(fmt.Sprintf("  path:   %s", result.Path))
+	if result.Envrc != "" {
+		out.Dim(fmt.Sprintf("  envrc:  %s", result.Envrc))
+	}
+	if issue != nil {
+		out.Dim(fmt.Sprintf("  issue:  #%d %s", issue.Number, issue.Title))
+	}
+	if len(result.Sparse) > 0 {
+		out.Dim(fmt.Sprintf("  sparse: %s", strings.Join(result.Sparse, ", ")))
+	}
+	if result.Submodules {
+		out.Dim("  submodules initialized")
+	}
+	if result.Pushed {
+		out.Dim(fmt.Sprintf("  pushed: %s/%s", forgeRemote, result.Branch))
+	}
+	if summary != "" {
+		out.Dim(fmt.Sprintf("  note:   %s", summary))
+	}
+	if result.Moved {
+		out.Dim("  moved uncommitted changes")
+	}
+	if result.Stash != "" {
+		out.Dim(fmt.Sprintf("  applied: %s (kept; drop it with: lazywork stash drop %s)", result.Stash, result.Stash))
+	}
 	out.Println()
-	out.Info(fmt.Sprintf("cd %s", worktreePath))
+	out.Info(fmt.Sprintf("cd %s", result.Path))
 
 	return nil
 }
 
+// suggestBranch asks the AI provider for a branch name and a one-sentence
+// summary of the uncommitted changes of the current checkout
+func suggestBranch(ctx context.Context, out *output.Output, cfg *config.Config) (name, summary string, err error) {
+	diff, err := git.WorkingTreeDiff(ctx)
+	if err != nil {
+		return "", "", lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	root, _ := git.GetRepoRoot(ctx)
+	untracked, _ := git.UntrackedFiles(ctx, root)
+
+	p, req, err := newAIRequest(out, cfg, "worktree add", addProvider, addModel, commitmsg.BranchMessages(ignoreFiles(ctx, out, diff), untracked))
+	if err != nil {
+		return "", "", err
+	}
+	out.Progress(fmt.Sprintf("Naming the branch with %s (%s)", p.Name(), req.Model))
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return "", "", lazyerr.Wrap(lazyerr.ProviderError, err).WithDetail("provider", p.Name())
+	}
+	if name, summary = commitmsg.ParseBranchSuggestion(resp.Content); name == "" {
+		return "", "", lazyerr.New(lazyerr.ProviderError, "%s returned no branch name", p.Name()).
+			WithHint("Name the worktree yourself: lazywork worktree add <name> --from-stash")
+	}
+	return name, summary, nil
+}
+
 func runWorktreeRemove(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
-	name := args[0]
+	out := newOutput(cmd)
+	ctx := cmd.Context()
 
-	if !git.IsInsideWorkTree() {
-		err := fmt.Errorf("not inside a git repository")
-		out.ErrorResult(err, "NOT_GIT_REPO")
-		return err
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
 	}
 
-	worktrees, err := git.ListWorktrees()
+	cfg, err := loadConfig(ctx)
 	if err != nil {
-		out.ErrorResult(err, "WORKTREE_LIST_ERROR")
-		return err
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
 	}
 
-	var targetPath string
-	for _, wt := range worktrees {
-		// Match by name (basename of path) or full path
-		if filepath.Base(wt.Path) == name || wt.Path == name {
-			targetPath = wt.Path
-			break
+	m := newManager(cmd, out, cfg)
+
+	withArchive := cfg.WorktreeArchive
+	if cmd.Flags().Changed("archive") {
+		withArchive = archiveRemove
+	}
+	var archived *archive.Archive
+	if withArchive {
+		target, err := m.Find(ctx, args[0])
+		if err != nil {
+			return err
 		}
-		// Also match by suffix pattern (repo-name)
-		if matched, _ := filepath.Match("*-"+name, filepath.Base(wt.Path)); matched {
-			targetPath = wt.Path
-			break
+		if archived, err = archiveWorktree(cmd, out, m, target); err != nil {
+			return err
 		}
 	}
 
-	if targetPath == "" {
-		err := fmt.Errorf("worktree '%s' not found", name)
-		out.ErrorResult(err, "WORKTREE_NOT_FOUND")
-		return err
-	}
-
-	if err := git.RemoveWorktree(targetPath, forceRemove); err != nil {
-		out.ErrorResult(err, "WORKTREE_REMOVE_ERROR")
+	// Archived changes are safe to remove
+	removed, err := m.Remove(ctx, args[0], forceRemove || archived != nil)
+	if err != nil {
+		if archived != nil {
+			// The worktree is still there
+			archived.Delete()
+		}
 		return err
 	}
 
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"path":    targetPath,
-			"removed": true,
-		})
+		result := schema.WorktreeRemove{Path: removed.Path, Removed: true}
+		if archived != nil {
+			result.Archive = archived.ID
+		}
+		return out.JSON(result)
 	}
 
-	out.Success(fmt.Sprintf("Removed worktree: %s", filepath.Base(targetPath)))
+	out.Success(fmt.Sprintf("Removed worktree: %s", filepath.Base(removed.Path)))
+	if archived != nil {
+		out.Info(fmt.Sprintf("Archived %s to %s", archiveContents(archived), archived.Location()))
+		out.Dim("  Restore it with: lazywork archive restore " + archived.ID)
+	}
 
 	return nil
 }
 
 func runWorktreePrune(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
+	out := newOutput(cmd)
+	ctx := cmd.Context()
 
-	if !git.IsInsideWorkTree() {
-		err := fmt.Errorf("not inside a git repository")
-		out.ErrorResult(err, "NOT_GIT_REPO")
-		return err
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
 	}
 
-	if err := git.PruneWorktrees(); err != nil {
-		out.ErrorResult(err, "WORKTREE_PRUNE_ERROR")
-		return err
+	if err := git.PruneWorktrees(ctx); err != nil {
+		return lazyerr.Wrap(lazyerr.WorktreePruneError, err)
 	}
 
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"pruned": true,
-		})
+		return out.JSON(schema.WorktreePrune{Pruned: true})
 	}
 
 	out.Success("Pruned stale worktree entries")
@@ -346,401 +957,858 @@ func runWorktreePrune(cmd *cobra.Command, args []string) error {
 }
 
 func runWorktreeGo(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
+	out := newOutput(cmd)
+	ctx := cmd.Context()
 
-	if !git.IsInsideWorkTree() {
-		err := fmt.Errorf("not inside a git repository")
-		out.ErrorResult(err, "NOT_GIT_REPO")
-		return err
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
 	}
 
-	worktrees, err := git.ListWorktrees()
+	worktrees, err := git.ListWorktrees(ctx)
 	if err != nil {
-		out.ErrorResult(err, "WORKTREE_LIST_ERROR")
-		return err
+		return lazyerr.Wrap(lazyerr.WorktreeListError, err)
 	}
 
-	var secondaryWorktrees []git.Worktree
-	for _, wt := range worktrees {
-		if !wt.Bare && strings.Contains(wt.Path, string(filepath.Separator)+".worktrees"+string(filepath.Separator)) {
-			secondaryWorktrees = append(secondaryWorktrees, wt)
-		}
+	candidates := git.SecondaryWorktrees(worktrees)
+	mainPath := ""
+	if cfg, err := loadConfig(ctx); err == nil && cfg.WorktreeIncludeMain && !worktrees[0].Bare {
+		mainPath = worktrees[0].Path
+		candidates = append([]git.Worktree{worktrees[0]}, candidates...)
 	}
+	// A worktree actually named main or root wins over the shortcut
+	toMain := len(args) > 0 && isMainTarget(args[0]) && exactWorktree(candidates, args[0]) == nil
 
-	if len(secondaryWorktrees) == 0 {
-		err := fmt.Errorf("no worktrees found. Create one with: lazywork worktree add <name>")
-		out.ErrorResult(err, "NO_WORKTREES")
-		return err
+	if len(candidates) == 0 && !toMain {
+		return lazyerr.New(lazyerr.NoWorktrees, "no worktrees found. Create one with: lazywork worktree add <name>")
 	}
 
-	var name string
-	if len(args) > 0 {
-		name = args[0]
-	} else if out.IsTTY() {
-		form := tui.WorktreeSelectForm(secondaryWorktrees, &name)
-		if err := form.Run(); err != nil {
-			return err
-		}
-	} else {
-		err := fmt.Errorf("worktree name required (use: lazywork worktree go <name>)")
-		out.ErrorResult(err, "NAME_REQUIRED")
-		return err
-	}
+	history := loadHistory(ctx)
+	sortByFrecency(candidates, history)
 
 	var targetPath string
-	for _, wt := range secondaryWorktrees {
-		if filepath.Base(wt.Path) == name || wt.Branch == name {
-			targetPath = wt.Path
-			break
+	switch {
+	case toMain:
+		targetPath = git.MainPath(worktrees)
+	case len(args) == 0:
+		if !out.IsTTY() {
+			return lazyerr.New(lazyerr.NameRequired, "worktree name required (use: lazywork worktree go <name>)")
+		}
+		targetPath, err = selectGoTarget(ctx, out, candidates, mainPath)
+	case args[0] == "-":
+		if history != nil {
+			current, _ := git.GetRepoRoot(ctx)
+			targetPath, _ = history.Previous(current)
+		}
+		if targetPath == "" {
+			return lazyerr.New(lazyerr.NoHistory, "no previous worktree in history")
+		}
+	default:
+		var matches []git.Worktree
+		matches, err = resolveWorktreeArg(candidates, args[0])
+		switch {
+		case err != nil:
+		case len(matches) == 1:
+			targetPath = matches[0].Path
+		case out.IsTTY():
+			out.Dim(fmt.Sprintf("'%s' matches %d worktrees", args[0], len(matches)))
+			targetPath, err = selectGoTarget(ctx, out, matches, mainPath)
+		default:
+			names := make([]string, len(matches))
+			for i, wt := range matches {
+				names[i] = filepath.Base(wt.Path)
+			}
+			err = lazyerr.New(lazyerr.AmbiguousName, "'%s' matches %d worktrees: %s", args[0], len(matches), strings.Join(names, ", ")).
+				WithDetail("name", args[0]).
+				WithDetail("matches", names)
 		}
 	}
-
-	if targetPath == "" {
-		err := fmt.Errorf("worktree '%s' not found", name)
-		out.ErrorResult(err, "WORKTREE_NOT_FOUND")
+	if err != nil || targetPath == "" {
+		// An empty path without error means the selector showed a diff
 		return err
 	}
 
+	recordVisit(ctx, targetPath)
+
+	cd := shell.CDCommand(shell.Current(), targetPath)
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"path": targetPath,
-			"cd":   fmt.Sprintf("cd '%s'", targetPath),
-		})
+		return out.JSON(schema.WorktreeGo{Path: targetPath, CD: cd})
 	}
 
 	if shellHelper {
-		fmt.Printf("cd '%s'\n", targetPath)
+		out.Print("%s\n", cd)
 		return nil
 	}
 
-	out.Info(fmt.Sprintf("Run: cd %s", targetPath))
+	out.Info("Run: " + cd)
 	out.Dim("Tip: Use 'lwt go' with shell integration for automatic cd")
-	out.Dim("Setup: eval \"$(lazywork shell init)\"")
+	out.Dim("Setup: " + shell.InitLine(shell.DetectShell()))
 
 	return nil
 }
 
-func runWorktreeUse(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
+// isMainTarget reports whether name is a 'worktree go' shortcut for the
+// main checkout
+func isMainTarget(name string) bool {
+	return name == "main" || name == "root"
+}
 
-	if !git.IsInsideWorkTree() {
-		err := fmt.Errorf("not inside a git repository")
-		out.ErrorResult(err, "NOT_GIT_REPO")
-		return err
+// selectGoTarget asks for one of worktrees and returns its path. If the user
+// diffs the highlighted worktree instead, the diff is shown and the path is
+// empty. The worktree at mainPath, if any, is marked as the main checkout.
+func selectGoTarget(ctx context.Context, out *output.Output, worktrees []git.Worktree, mainPath string) (string, error) {
+	notes := worktreeNotes(ctx, worktrees)
+	if mainPath != "" {
+		notes[mainPath] = strings.TrimSpace("main checkout " + notes[mainPath])
+	}
+	name, action, err := tui.RunWorktreeSelector(worktrees, notes)
+	if err != nil {
+		return "", err
 	}
+	wt := exactWorktree(worktrees, name)
+	if action == tui.ActionDiff {
+		cfg, err := loadConfig(ctx)
+		if err != nil {
+			return "", lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+		}
+		return "", showWorktreeDiff(ctx, out, cfg, wt, "")
+	}
+	if wt == nil {
+		return "", lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", name).WithDetail("name", name)
+	}
+	return wt.Path, nil
+}
 
-	if !git.IsMainWorktree() {
-		err := fmt.Errorf("must be in main repository, not a worktree")
-		out.ErrorResult(err, "NOT_MAIN_WORKTREE")
-		return err
+// loadHistory returns the visit history for the current repository, or nil
+// if it cannot be read. Entries for worktrees that no longer exist are
+// pruned. History is best-effort and never fails a command.
+func loadHistory(ctx context.Context) *state.History {
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return nil
 	}
+	history, err := state.LoadHistory(state.Dir(commonDir))
+	if err != nil {
+		return nil
+	}
+	if stale := history.Prune(state.DirExists); len(stale) > 0 {
+		// Re-apply under the lock so a concurrent update is not overwritten
+		_, _ = state.UpdateHistory(state.Dir(commonDir), func(h *state.History) error {
+			h.Prune(state.DirExists)
+			return nil
+		})
+	}
+	return history
+}
 
-	if git.HasSavedState() {
-		err := fmt.Errorf("already using a worktree branch. Run 'lazywork worktree return' first")
-		out.ErrorResult(err, "STATE_EXISTS")
-		return err
+// recordVisit adds a visit to path to the history and the repository to
+// the known repositories. Like loadHistory it is best-effort; a failed
+// write must not break navigation.
+func recordVisit(ctx context.Context, path string) {
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return
 	}
+	_, _ = state.UpdateHistory(state.Dir(commonDir), func(h *state.History) error {
+		h.Record(path, time.Now())
+		return nil
+	})
+	_ = state.RecordRepo(state.UserDir(), commonDir, time.Now())
+}
 
-	worktrees, err := git.ListWorktrees()
+// loadMeta loads the worktree metadata store. Like loadHistory it is best
+// effort and returns nil when the store cannot be read.
+func loadMeta(ctx context.Context) *state.MetaStore {
+	commonDir, err := git.GetCommonDir(ctx)
 	if err != nil {
-		out.ErrorResult(err, "WORKTREE_LIST_ERROR")
-		return err
+		return nil
+	}
+	meta, err := state.LoadMeta(state.Dir(commonDir))
+	if err != nil {
+		return nil
 	}
+	return meta
+}
 
-	var secondaryWorktrees []git.Worktree
-	for _, wt := range worktrees {
-		if !wt.Bare && strings.Contains(wt.Path, string(filepath.Separator)+".worktrees"+string(filepath.Separator)) {
-			secondaryWorktrees = append(secondaryWorktrees, wt)
+// worktreeNotes returns the metadata summary of each annotated worktree,
+// keyed by path, for display next to worktree names
+func worktreeNotes(ctx context.Context, worktrees []git.Worktree) map[string]string {
+	notes := map[string]string{}
+	if meta := loadMeta(ctx); meta != nil {
+		for path, m := range meta.Worktrees {
+			notes[path] = m.Summary()
 		}
 	}
 
-	if len(secondaryWorktrees) == 0 {
-		err := fmt.Errorf("no worktrees found")
-		out.ErrorResult(err, "NO_WORKTREES")
-		return err
+	// Ticket titles come first; lookup failures are not worth a warning
+	// in the middle of a prompt
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return notes
+	}
+	branches := make([]string, 0, len(worktrees))
+	for _, wt := range worktrees {
+		branches = append(branches, wt.Branch)
+	}
+	branchTicket, _ := branchTickets(ctx, cfg, branches)
+	for _, wt := range worktrees {
+		if t := branchTicket[wt.Branch]; t != nil {
+			notes[wt.Path] = strings.TrimSpace(ticketColumn(t) + " " + notes[wt.Path])
+		}
 	}
 
-	var name string
-	if len(args) > 0 {
-		name = args[0]
-	} else if out.IsTTY() {
-		form := tui.WorktreeSelectForm(secondaryWorktrees, &name)
-		if err := form.Run(); err != nil {
-			return err
+	// Stale worktrees are marked last
+	staleAfter, _ := cfg.GetStaleAfter()
+	if staleAfter == 0 {
+		return notes
+	}
+	mainPath, _ := git.MainWorktreePath(ctx)
+	now := time.Now()
+	for i, t := range worktree.New(cfg).LastActivity(ctx, worktrees) {
+		if wt := worktrees[i]; wt.Path != mainPath && worktree.Stale(t, staleAfter, now) {
+			notes[wt.Path] = strings.TrimSpace(fmt.Sprintf("%s (stale, last active %s)", notes[wt.Path], ageColumn(t, now)))
 		}
-	} else {
-		err := fmt.Errorf("worktree name required")
-		out.ErrorResult(err, "NAME_REQUIRED")
-		return err
 	}
+	return notes
+}
+
+// isBareLayout reports whether the current repository is a bare clone
+// whose branches are all checked out as worktrees
+func isBareLayout(ctx context.Context) bool {
+	worktrees, err := git.ListWorktrees(ctx)
+	return err == nil && git.IsBareLayout(worktrees)
+}
+
+// sortByFrecency orders worktrees by descending frecency score
+func sortByFrecency(worktrees []git.Worktree, history *state.History) {
+	if history == nil {
+		return
+	}
+	now := time.Now()
+	sort.SliceStable(worktrees, func(i, j int) bool {
+		return history.Score(worktrees[i].Path, now) > history.Score(worktrees[j].Path, now)
+	})
+}
 
-	var targetWorktree *git.Worktree
-	for _, wt := range secondaryWorktrees {
+// matchWorktree resolves name by exact basename or branch match, falling back
+// to the first worktree whose basename or branch contains name. Callers sort
+// worktrees by frecency first so the fallback picks the best-ranked match.
+func matchWorktree(worktrees []git.Worktree, name string) *git.Worktree {
+	if wt := exactWorktree(worktrees, name); wt != nil {
+		return wt
+	}
+	if matches := partialWorktrees(worktrees, name); len(matches) > 0 {
+		return &matches[0]
+	}
+	return nil
+}
+
+// exactWorktree returns the worktree whose basename or branch is name
+func exactWorktree(worktrees []git.Worktree, name string) *git.Worktree {
+	for i, wt := range worktrees {
 		if filepath.Base(wt.Path) == name || wt.Branch == name {
-			targetWorktree = &wt
-			break
+			return &worktrees[i]
 		}
 	}
+	return nil
+}
 
-	if targetWorktree == nil {
-		err := fmt.Errorf("worktree '%s' not found", name)
-		out.ErrorResult(err, "WORKTREE_NOT_FOUND")
-		return err
+// partialWorktrees returns the worktrees whose basename or branch contains
+// name, in their original order
+func partialWorktrees(worktrees []git.Worktree, name string) []git.Worktree {
+	var matches []git.Worktree
+	for _, wt := range worktrees {
+		if strings.Contains(filepath.Base(wt.Path), name) || strings.Contains(wt.Branch, name) {
+			matches = append(matches, wt)
+		}
 	}
+	return matches
+}
 
-	if targetWorktree.Branch == "" {
-		err := fmt.Errorf("worktree is in detached HEAD state")
-		out.ErrorResult(err, "DETACHED_HEAD")
-		return err
+// resolveWorktreeArg resolves the argument of 'worktree go' against
+// worktrees sorted by frecency: an exact basename or branch, a 1-based
+// position in the list, or a partial name, which may match several
+// worktrees. A number that is out of range is tried as a partial name.
+func resolveWorktreeArg(worktrees []git.Worktree, name string) ([]git.Worktree, error) {
+	if wt := exactWorktree(worktrees, name); wt != nil {
+		return []git.Worktree{*wt}, nil
+	}
+	n, err := strconv.Atoi(name)
+	isNumber := err == nil
+	if isNumber && n >= 1 && n <= len(worktrees) {
+		return worktrees[n-1 : n], nil
+	}
+	if matches := partialWorktrees(worktrees, name); len(matches) > 0 {
+		return matches, nil
+	}
+	if isNumber {
+		return nil, lazyerr.New(lazyerr.WorktreeNotFound, "no worktree at position %d (there are %d)", n, len(worktrees)).WithDetail("name", name)
+	}
+	return nil, lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", name).WithDetail("name", name)
+}
+
+func runWorktreeUse(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	cfg, cfgErr := loadConfig(ctx)
+	m := newManager(cmd, out, cfg)
+
+	if useRepair {
+		if len(args) > 0 {
+			return lazyerr.New(lazyerr.InvalidArgument, "--repair takes no worktree name")
+		}
+		return runUseRepair(cmd, out, m)
 	}
 
-	currentBranch, err := git.CurrentBranch()
+	stack, err := m.UseStack(ctx)
 	if err != nil {
-		out.ErrorResult(err, "BRANCH_ERROR")
 		return err
 	}
+	if useStatus {
+		return printUseStack(out, stack)
+	}
 
-	var stashRef string
-	if git.HasUncommittedChanges() {
-		if out.IsTTY() {
-			var doStash bool
-			form := tui.StashConfirmForm(&doStash)
-			if err := form.Run(); err != nil {
-				return err
-			}
-			if !doStash {
-				err := fmt.Errorf("cancelled: uncommitted changes would be lost")
-				out.ErrorResult(err, "CANCELLED")
-				return err
-			}
-		} else if !jsonOutput {
-			err := fmt.Errorf("uncommitted changes detected. Commit or stash them first")
-			out.ErrorResult(err, "UNCOMMITTED_CHANGES")
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	} else if out.IsTTY() {
+		candidates, err := m.UseCandidates(ctx)
+		if err != nil {
 			return err
 		}
-
-		stashRef, err = git.Stash("lazywork: auto-stash before worktree use")
-		if err != nil {
-			out.ErrorResult(err, "STASH_ERROR")
+		form := tui.WorktreeSelectForm(candidates, worktreeNotes(ctx, candidates), &name)
+		if err := form.Run(); err != nil {
+			return err
+		}
+	} else {
+		if _, err := m.UseCandidates(ctx); err != nil {
 			return err
 		}
+		return lazyerr.New(lazyerr.NameRequired, "worktree name required")
 	}
 
-	if err := git.SaveUseState(currentBranch, stashRef); err != nil {
-		out.ErrorResult(err, "STATE_SAVE_ERROR")
-		return err
+	if cfgErr != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, cfgErr)
 	}
 
-	if err := git.Checkout(targetWorktree.Branch); err != nil {
-		git.ClearUseState()
-		if stashRef != "" {
-			git.StashPop()
+	opts := worktree.UseOptions{Name: name, Stash: !out.IsTTY() && jsonOutput}
+	if out.IsTTY() {
+		opts.ConfirmStash = func() (bool, error) {
+			var doStash bool
+			err := tui.StashConfirmForm(&doStash).Run()
+			return doStash, err
 		}
-		out.ErrorResult(err, "CHECKOUT_ERROR")
+	}
+	result, err := m.Use(ctx, opts)
+	if err != nil {
 		return err
 	}
 
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"branch":          targetWorktree.Branch,
-			"previous_branch": currentBranch,
-			"stashed":         stashRef != "",
+		return out.JSON(schema.WorktreeUse{
+			Branch:         result.Branch,
+			PreviousBranch: result.PreviousBranch,
+			Stashed:        result.Stashed,
+			Depth:          result.Depth,
 		})
 	}
 
-	out.Success(fmt.Sprintf("Switched to branch: %s", targetWorktree.Branch))
-	if stashRef != "" {
+	out.Success(fmt.Sprintf("Switched to branch: %s", result.Branch))
+	if result.Stashed {
 		out.Dim("  Changes stashed automatically")
 	}
+	if result.Depth > 1 {
+		out.Dim(fmt.Sprintf("  Stacked on %d earlier 'use' (see 'worktree use --status')", result.Depth-1))
+	}
 	out.Println()
-	out.Info("Run 'lazywork worktree return' to go back")
+	out.Info(fmt.Sprintf("Run 'lazywork worktree return' to go back to %s", result.PreviousBranch))
 
 	return nil
 }
 
-func runWorktreeReturn(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
-
-	if !git.IsInsideWorkTree() {
-		err := fmt.Errorf("not inside a git repository")
-		out.ErrorResult(err, "NOT_GIT_REPO")
+// runUseRepair runs 'worktree use --repair'
+func runUseRepair(cmd *cobra.Command, out *output.Output, m *worktree.Manager) error {
+	result, err := m.RepairUse(cmd.Context())
+	if err != nil {
 		return err
 	}
 
-	if !git.IsMainWorktree() {
-		err := fmt.Errorf("must be in main repository, not a worktree")
-		out.ErrorResult(err, "NOT_MAIN_WORKTREE")
-		return err
+	if jsonOutput {
+		return out.JSON(schema.UseRepair{
+			Frames:   result.Frames,
+			Depth:    len(result.Frames),
+			Actions:  result.Actions,
+			Restored: result.Restored,
+		})
 	}
 
-	previousBranch, stashRef, err := git.LoadUseState()
-	if err != nil {
-		err := fmt.Errorf("no previous state found. Did you run 'worktree use' first?")
-		out.ErrorResult(err, "NO_STATE")
-		return err
+	if len(result.Actions) == 0 {
+		out.Success("The saved 'worktree use' state is consistent")
+	}
+	for _, action := range result.Actions {
+		out.Success(action)
 	}
+	if n := len(result.Frames); n > 0 {
+		out.Info(fmt.Sprintf("Run 'lazywork worktree return' to go back to %s", result.Frames[n-1].Branch))
+	}
+	return nil
+}
 
-	if git.HasUncommittedChanges() {
-		err := fmt.Errorf("you have uncommitted changes. Commit or stash them before returning")
-		out.ErrorResult(err, "UNCOMMITTED_CHANGES")
-		return err
+// printUseStack shows the 'worktree use' stack, most recent first
+func printUseStack(out *output.Output, stack []git.UseFrame) error {
+	if jsonOutput {
+		if stack == nil {
+			stack = []git.UseFrame{}
+		}
+		if stack == nil {
+			stack = []git.UseFrame{}
+		}
+		return out.JSON(schema.UseStatus{Frames: stack, Depth: len(stack)})
 	}
 
-	if err := git.Checkout(previousBranch); err != nil {
-		out.ErrorResult(err, "CHECKOUT_ERROR")
-		return err
+	if len(stack) == 0 {
+		out.Dim("Not using any worktree branch")
+		return nil
 	}
 
-	if stashRef != "" {
-		if err := git.StashPop(); err != nil {
-			out.Warning(fmt.Sprintf("Could not restore stash: %v", err))
+	rows := make([][]string, 0, len(stack))
+	for i := len(stack) - 1; i >= 0; i-- {
+		f := stack[i]
+		stashed := "no"
+		if f.Stash != "" {
+			stashed = "yes"
+		}
+		since := ""
+		if !f.Created.IsZero() {
+			since = f.Created.Local().Format("2006-01-02 15:04")
 		}
+		rows = append(rows, []string{fmt.Sprintf("%d", i+1), f.Target, f.Branch, stashed, since})
+	}
+	out.Table([]string{"#", "USING", "RETURNS TO", "STASHED", "SINCE"}, rows)
+
+	return nil
+}
+
+func runWorktreeReturn(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
 	}
 
-	if err := git.ClearUseState(); err != nil {
-		out.Warning(fmt.Sprintf("Could not clear state: %v", err))
+	result, err := newManager(cmd, out, cfg).Return(ctx)
+	if err != nil {
+		return err
 	}
 
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"branch":   previousBranch,
-			"restored": stashRef != "",
-		})
+		return out.JSON(schema.WorktreeReturn{Branch: result.Branch, Restored: result.Restored, Depth: result.Depth})
 	}
 
-	out.Success(fmt.Sprintf("Returned to branch: %s", previousBranch))
-	if stashRef != "" {
+	out.Success(fmt.Sprintf("Returned to branch: %s", result.Branch))
+	if result.Restored {
 		out.Dim("  Stashed changes restored")
 	}
+	if result.Next != "" {
+		out.Println()
+		out.Info(fmt.Sprintf("Run 'lazywork worktree return' again to go back to %s", result.Next))
+	}
 
 	return nil
 }
 
 func runWorktreeFinish(cmd *cobra.Command, args []string) error {
-	out := output.New(jsonOutput, noColor)
-
-	if !git.IsInsideWorkTree() {
-		err := fmt.Errorf("not inside a git repository")
-		out.ErrorResult(err, "NOT_GIT_REPO")
-		return err
-	}
+	out := newOutput(cmd)
+	ctx := cmd.Context()
 
-	if !git.IsMainWorktree() {
-		err := fmt.Errorf("must be in main repository, not a worktree")
-		out.ErrorResult(err, "NOT_MAIN_WORKTREE")
-		return err
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
 	}
 
-	currentBranch, err := git.CurrentBranch()
+	cfg, err := loadConfig(ctx)
 	if err != nil {
-		out.ErrorResult(err, "BRANCH_ERROR")
-		return err
-	}
-
-	mainBranch := git.GetMainBranch()
-	if currentBranch != mainBranch {
-		err := fmt.Errorf("must be on %s branch to finish a worktree", mainBranch)
-		out.ErrorResult(err, "NOT_MAIN_BRANCH")
-		return err
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
 	}
+	m := newManager(cmd, out, cfg)
 
-	if git.HasUncommittedChanges() {
-		err := fmt.Errorf("uncommitted changes detected. Commit or stash them first")
-		out.ErrorResult(err, "UNCOMMITTED_CHANGES")
-		return err
+	if finishMerged && len(args) > 0 {
+		return lazyerr.New(lazyerr.InvalidArgument, "pass either a worktree name or --all-merged")
 	}
 
-	worktrees, err := git.ListWorktrees()
+	mainBranch := m.MainBranch(ctx)
+	candidates, err := m.FinishCandidates(ctx, mainBranch)
 	if err != nil {
-		out.ErrorResult(err, "WORKTREE_LIST_ERROR")
 		return err
 	}
-
-	var secondaryWorktrees []git.Worktree
-	for _, wt := range worktrees {
-		if !wt.Bare && strings.Contains(wt.Path, string(filepath.Separator)+".worktrees"+string(filepath.Separator)) {
-			secondaryWorktrees = append(secondaryWorktrees, wt)
-		}
+	if finishMerged {
+		fetchRemote(ctx, out, cfg)
+		return finishAllMerged(ctx, out, cfg, m, candidates, mainBranch)
+	}
+	// Merging fetches as its first step
+	if finishPush || finishCheck {
+		fetchRemote(ctx, out, cfg)
 	}
 
-	if len(secondaryWorktrees) == 0 {
-		err := fmt.Errorf("no worktrees found")
-		out.ErrorResult(err, "NO_WORKTREES")
-		return err
+	// Pushing or checking leaves the main checkout alone, so its state
+	// doesn't matter
+	if !finishPush && !finishCheck {
+		if err := m.CheckMergeable(ctx, mainBranch); err != nil {
+			return err
+		}
 	}
 
 	var name string
 	if len(args) > 0 {
 		name = args[0]
 	} else if out.IsTTY() {
-		form := tui.WorktreeSelectForm(secondaryWorktrees, &name)
+		form := tui.WorktreeSelectForm(candidates, worktreeNotes(ctx, candidates), &name)
 		if err := form.Run(); err != nil {
 			return err
 		}
 	} else {
-		err := fmt.Errorf("worktree name required")
-		out.ErrorResult(err, "NAME_REQUIRED")
+		return lazyerr.New(lazyerr.NameRequired, "worktree name required")
+	}
+
+	target, err := m.FinishTarget(ctx, name, mainBranch)
+	if err != nil {
 		return err
 	}
 
-	var targetWorktree *git.Worktree
-	for _, wt := range secondaryWorktrees {
-		if filepath.Base(wt.Path) == name || wt.Branch == name {
-			targetWorktree = &wt
-			break
+	if finishPush {
+		f, err := detectForge(ctx, cfg)
+		if err != nil {
+			return err
 		}
+		return finishWithPullRequest(cmd, out, cfg, m, f, target, mainBranch)
 	}
 
-	if targetWorktree == nil {
-		err := fmt.Errorf("worktree '%s' not found", name)
-		out.ErrorResult(err, "WORKTREE_NOT_FOUND")
-		return err
+	if finishCheck {
+		return checkMergeConflicts(ctx, out, m, target.Branch, mainBranch)
+	}
+	if !finishSquash {
+		warnWipCommits(ctx, out, target, mainBranch)
 	}
 
-	if targetWorktree.Branch == "" {
-		err := fmt.Errorf("worktree is in detached HEAD state, cannot merge")
-		out.ErrorResult(err, "DETACHED_HEAD")
-		return err
+	opts := worktree.FinishOptions{
+		Fetch:  fetchFirst || cfg.AutoFetch,
+		Squash: finishSquash,
+		Push:   finishPushBase,
+	}
+	opts.Conflicts = func(files []string) (bool, error) {
+		return confirmConflicts(out, target.Branch, mainBranch, files)
+	}
+	if !finishNoAI {
+		opts.Message = func(ctx context.Context, branch, base string) string {
+			return mergeMessage(ctx, out, cfg, branch, base)
+		}
+	}
+	switch {
+	case finishCleanup:
+		opts.Cleanup = func(worktree.Worktree) (bool, error) { return true, nil }
+	case out.IsTTY():
+		opts.Cleanup = func(wt worktree.Worktree) (bool, error) {
+			var doCleanup bool
+			err := tui.CleanupConfirmForm(filepath.Base(wt.Path), &doCleanup).Run()
+			return doCleanup, err
+		}
 	}
 
-	if err := git.Merge(targetWorktree.Branch); err != nil {
-		out.Error(fmt.Sprintf("Merge failed: %v", err))
-		out.Println()
-		out.Info("Resolve conflicts and run 'git commit', then try again")
+	if finishDryRun {
+		return printFinishPlan(out, m, *target, mainBranch, opts)
+	}
+
+	result, err := m.Finish(ctx, *target, mainBranch, opts)
+	if err != nil {
 		return err
 	}
 
-	out.Success(fmt.Sprintf("Merged %s into %s", targetWorktree.Branch, mainBranch))
+	if jsonOutput {
+		return out.JSON(schema.WorktreeFinish{
+			Merged:  true,
+			Branch:  result.Branch,
+			Cleanup: result.Cleanup,
+			Message: result.Message,
+			Squash:  result.Squash,
+			Pushed:  result.Pushed,
+		})
+	}
 
-	var doCleanup bool
-	if out.IsTTY() {
-		form := tui.CleanupConfirmForm(filepath.Base(targetWorktree.Path), &doCleanup)
-		if err := form.Run(); err != nil {
+	return nil
+}
+
+// warnWipCommits points out the wip commits a merge would bring into base
+func warnWipCommits(ctx context.Context, out *output.Output, target *worktree.Worktree, base string) {
+	commits, err := git.Log(ctx, base+".."+target.Branch, 0)
+	if err != nil {
+		return
+	}
+	n := 0
+	for _, c := range commits {
+		if commitmsg.IsWip(c.Subject) {
+			n++
+		}
+	}
+	if n > 0 {
+		out.Warning(fmt.Sprintf("%s has %d wip commit(s); squash them first with 'lazywork wip --squash' in %s, or finish with --squash", target.Branch, n, target.Path))
+	}
+}
+
+// finishAllMerged removes the candidates whose branch is merged into base,
+// locally or through a pull request, reporting failures per worktree
+func finishAllMerged(ctx context.Context, out *output.Output, cfg *config.Config, m *worktree.Manager, candidates []worktree.Worktree, base string) error {
+	// Without a forge only local merges are found
+	var prs []*forge.PullRequest
+	if f, err := detectForge(ctx, cfg); err == nil {
+		stop := out.Spinner(fmt.Sprintf("Looking up merged %ss", forge.RequestNoun(f.Name())))
+		prs, err = mergedPullRequests(ctx, f, candidates, base)
+		stop()
+		if err != nil {
+			out.Warning(fmt.Sprintf("Could not look up pull requests: %v", err))
+		}
+	}
+
+	var merged []worktree.Worktree
+	results := []schema.MergedWorktree{}
+	for i, wt := range candidates {
+		var pr *forge.PullRequest
+		// A pull request only counts if the branch has nothing it doesn't
+		if prs != nil && prs[i] != nil && git.IsAncestor(ctx, wt.Branch, prs[i].Head) {
+			pr = prs[i]
+		}
+		if pr == nil && !m.Merged(ctx, wt, base) {
+			continue
+		}
+		merged = append(merged, wt)
+		results = append(results, schema.MergedWorktree{
+			Name:        filepath.Base(wt.Path),
+			Path:        wt.Path,
+			Branch:      wt.Branch,
+			PullRequest: pr,
+		})
+	}
+
+	if len(merged) == 0 {
+		if jsonOutput {
+			return out.JSON(schema.FinishAllMerged{Into: base, Worktrees: results})
+		}
+		out.Info(fmt.Sprintf("No worktrees merged into %s", base))
+		return nil
+	}
+
+	if finishDryRun || (out.IsTTY() && !finishCleanup && !jsonOutput) {
+		printMergedWorktrees(out, results, false)
+	}
+	if finishDryRun {
+		if jsonOutput {
+			return out.JSON(schema.FinishAllMerged{Into: base, Worktrees: results})
+		}
+		return nil
+	}
+	if out.IsTTY() && !finishCleanup {
+		var proceed bool
+		prompt := fmt.Sprintf("Remove %d merged worktree(s) and delete their branches?", len(merged))
+		if err := tui.ConfirmForm(prompt, &proceed).Run(); err != nil {
 			return err
 		}
+		if !proceed {
+			return lazyerr.New(lazyerr.Cancelled, "finish cancelled")
+		}
+	}
+
+	var failed []string
+	for i, wt := range merged {
+		if _, err := m.FinishMerged(ctx, wt, base); err != nil {
+			results[i].Error = lazyerr.From(err).Message
+			failed = append(failed, results[i].Name)
+			continue
+		}
+		results[i].Cleanup = true
 	}
 
 	if jsonOutput {
-		return out.JSON(map[string]interface{}{
-			"merged":  true,
-			"branch":  targetWorktree.Branch,
-			"cleanup": doCleanup,
-		})
+		if err := out.JSON(schema.FinishAllMerged{Into: base, Worktrees: results, Failed: len(failed)}); err != nil {
+			return err
+		}
+	} else {
+		out.Println()
+		printMergedWorktrees(out, results, true)
 	}
 
-	if doCleanup {
-		if err := git.RemoveWorktree(targetWorktree.Path, false); err != nil {
-			out.Warning(fmt.Sprintf("Could not remove worktree: %v", err))
-		} else {
-			out.Success(fmt.Sprintf("Removed worktree: %s", filepath.Base(targetWorktree.Path)))
+	if len(failed) > 0 {
+		e := lazyerr.New(lazyerr.FinishFailed, "failed to finish %d of %d merged worktree(s)", len(failed), len(merged)).
+			WithDetail("failed", failed)
+		if jsonOutput {
+			e = e.MarkReported()
 		}
+		return e
+	}
+	return nil
+}
 
-		if err := git.DeleteBranch(targetWorktree.Branch, false); err != nil {
-			out.Warning(fmt.Sprintf("Could not delete branch: %v", err))
-		} else {
-			out.Success(fmt.Sprintf("Deleted branch: %s", targetWorktree.Branch))
+// printMergedWorktrees prints the worktrees found by --all-merged, with
+// the outcome once finished
+func printMergedWorktrees(out *output.Output, results []schema.MergedWorktree, finished bool) {
+	headers := []string{"WORKTREE", "BRANCH", "MERGED"}
+	if finished {
+		headers = append(headers, "RESULT")
+	}
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		via := "locally"
+		if r.PullRequest != nil {
+			via = r.PullRequest.Ref
+		}
+		row := []string{r.Name, r.Branch, via}
+		if finished {
+			result := "removed"
+			if r.Error != "" {
+				result = "failed: " + r.Error
+			}
+			row = append(row, result)
+		}
+		rows = append(rows, row)
+	}
+	out.Table(headers, rows)
+}
+
+// printFinishPlan prints the steps finishing target would run. Cleanup is
+// listed only with --cleanup, as otherwise it is asked when finishing.
+func printFinishPlan(out *output.Output, m *worktree.Manager, target worktree.Worktree, base string, opts worktree.FinishOptions) error {
+	plan := m.FinishPlan(target, base, opts, finishCleanup)
+	if jsonOutput {
+		return out.JSON(schema.FinishPlan{Branch: target.Branch, Into: base, Steps: plan})
+	}
+
+	out.Info(fmt.Sprintf("Finishing %s into %s would:", target.Branch, base))
+	for i, s := range plan {
+		out.Println(fmt.Sprintf("  %d. %s", i+1, s.Description))
+	}
+	if !finishCleanup && opts.Cleanup != nil {
+		out.Dim("You'll be asked whether to remove the worktree and delete its branch (--cleanup to include it)")
+	}
+	return nil
+}
+
+// confirmConflicts lists the files merging branch into base would conflict
+// on and asks whether to merge anyway. Without a terminal it doesn't, so
+// finish stops before changing anything.
+func confirmConflicts(out *output.Output, branch, base string, files []string) (bool, error) {
+	if !out.IsTTY() {
+		if !jsonOutput {
+			for _, f := range files {
+				out.Warning(f)
+			}
+		}
+		return false, nil
+	}
+
+	out.Warning(fmt.Sprintf("Merging %s into %s will conflict in:", branch, base))
+	for _, f := range files {
+		out.Dim("  " + f)
+	}
+	var proceed bool
+	if err := tui.ConfirmForm("Merge anyway?", &proceed).Run(); err != nil {
+		return false, err
+	}
+	if !proceed {
+		return false, lazyerr.New(lazyerr.Cancelled, "finish cancelled")
+	}
+	return true, nil
+}
+
+// checkMergeConflicts reports the files that merging branch into base would
+// conflict on, for --check
+func checkMergeConflicts(ctx context.Context, out *output.Output, m *worktree.Manager, branch, base string) error {
+	conflicts, err := m.Conflicts(ctx, branch, base)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchError, err).WithDetail("branch", branch)
+	}
+
+	if len(conflicts) == 0 {
+		if jsonOutput {
+			return out.JSON(schema.FinishCheck{Branch: branch, Into: base, Conflicts: []string{}})
+		}
+		out.Success(fmt.Sprintf("%s merges cleanly into %s", branch, base))
+		return nil
+	}
+
+	if !jsonOutput {
+		for _, f := range conflicts {
+			out.Warning(f)
 		}
 	}
+	return lazyerr.New(lazyerr.MergeConflict, "merging %s into %s would conflict in %d file(s)", branch, base, len(conflicts)).
+		WithDetail("branch", branch).
+		WithDetail("into", base).
+		WithDetail("conflicts", conflicts).
+		WithHint("Nothing was merged; rebase the branch or merge by hand with: git merge " + branch)
+}
+
+// mergeMessage asks the AI provider for the message of the commit merging
+// branch into base. It returns "" when the merge is a fast-forward, no
+// provider is usable or the request fails, so git's default is used.
+func mergeMessage(ctx context.Context, out *output.Output, cfg *config.Config, branch, base string) string {
+	if git.IsAncestor(ctx, "HEAD", branch) {
+		return ""
+	}
+
+	subjects, err := git.CommitSubjects(ctx, "HEAD", branch)
+	if err != nil || len(subjects) == 0 {
+		return ""
+	}
+	diffstat, _ := git.DiffStat(ctx, "HEAD", branch)
+
+	style := commitStyle(ctx, out, cfg, false)
+	messages := withRepoContext(ctx, cfg, commitmsg.MergeMessages(branch, base, subjects, diffstat, style))
+	p, req, err := newAIRequest(out, cfg, "worktree finish", finishProvider, finishModel, messages)
+	if err != nil {
+		// Most likely no provider is set up; that's not worth a warning
+		return ""
+	}
+
+	out.Progress(fmt.Sprintf("Writing merge message with %s (%s)", p.Name(), req.Model))
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		out.Warning(fmt.Sprintf("Could not write merge message, using git's default: %v", err))
+		return ""
+	}
+	return style.Clean(resp.Content)
+}
+
+// finishWithPullRequest pushes the worktree's branch and opens a pull
+// request into mainBranch, for 'finish --push'
+func finishWithPullRequest(cmd *cobra.Command, out *output.Output, cfg *config.Config, m *worktree.Manager, f forge.Forge, wt *git.Worktree, mainBranch string) error {
+	ctx := cmd.Context()
+
+	hookWt := m.Env(ctx, wt.Path, wt.Branch)
+	if err := m.Hook(ctx, "pre_finish", hookWt); err != nil {
+		return err
+	}
+
+	if git.HasUncommittedChangesIn(ctx, wt.Path) {
+		out.Warning(fmt.Sprintf("Uncommitted changes in %s are not included", filepath.Base(wt.Path)))
+	}
+
+	pr, err := openPullRequest(ctx, out, cfg, f, wt.Path, forge.PullRequestInput{
+		Head: wt.Branch,
+		Base: mainBranch,
+	}, true)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Hook(ctx, "post_finish", hookWt); err != nil {
+		out.Warning(err.Error())
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.FinishPush{Branch: wt.Branch, Pushed: true, PullRequest: *pr})
+	}
+
+	printPullRequest(out, f, pr)
+	out.Dim(fmt.Sprintf("  Remove the worktree once it is merged: lazywork worktree remove %s", filepath.Base(wt.Path)))
 
 	return nil
 }
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/archive"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/internal/tui"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/miltonparedes/lazywork/pkg/worktree"
+	"github.com/spf13/cobra"
+)
+
+var worktreeCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove stale worktrees",
+	Long: `Remove the worktrees that went without commits or 'worktree go' visits
+for worktree_stale_after in the config (30 days by default), or for the age
+given to --older-than. The main checkout is never removed, and branches are
+kept. 'worktree list' warns about the same worktrees.
+
+You'll be asked which worktrees to remove; without a terminal, or with
+--yes, they are all removed. Worktrees with uncommitted changes are kept
+unless --force is given, or --archive (or worktree_archive in the config)
+archives them first. Removals can be reverted with 'lazywork undo'.
+
+Example:
+  lazywork worktree clean --stale --dry-run
+  lazywork worktree clean --stale --older-than 2w
+  lazywork worktree clean --stale --yes --archive`,
+	Args: cobra.NoArgs,
+	RunE: runWorktreeClean,
+}
+
+var (
+	cleanStale     bool
+	cleanOlderThan string
+	cleanDryRun    bool
+	cleanYes       bool
+	cleanForce     bool
+	cleanArchive   bool
+)
+
+func init() {
+	worktreeCmd.AddCommand(worktreeCleanCmd)
+
+	worktreeCleanCmd.Flags().BoolVar(&cleanStale, "stale", false, "Remove worktrees without commits or visits for worktree_stale_after")
+	worktreeCleanCmd.Flags().StringVar(&cleanOlderThan, "older-than", "", "Age past which a worktree is stale (e.g. 30d, 2w; default from worktree_stale_after)")
+	worktreeCleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "List the stale worktrees without removing them")
+	worktreeCleanCmd.Flags().BoolVarP(&cleanYes, "yes", "y", false, "Remove without prompting")
+	worktreeCleanCmd.Flags().BoolVarP(&cleanForce, "force", "f", false, "Also remove worktrees with uncommitted changes")
+	worktreeCleanCmd.Flags().BoolVar(&cleanArchive, "archive", false, "Archive unmerged commits, changes and untracked files first (default from worktree_archive)")
+}
+
+func runWorktreeClean(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	if !cleanStale {
+		return lazyerr.New(lazyerr.InvalidArgument, "nothing to clean").
+			WithHint("Remove stale worktrees with: lazywork worktree clean --stale")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+	after, err := cfg.GetStaleAfter()
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigInvalid, err).WithDetail("worktree_stale_after", cfg.WorktreeStaleAfter)
+	}
+	if cleanOlderThan != "" {
+		if after, err = config.ParseAge(cleanOlderThan); err != nil {
+			return lazyerr.Wrap(lazyerr.InvalidArgument, err).WithDetail("older_than", cleanOlderThan)
+		}
+		if after == 0 {
+			return lazyerr.New(lazyerr.InvalidArgument, "--older-than must be longer than zero")
+		}
+	}
+	if after == 0 {
+		return lazyerr.New(lazyerr.InvalidArgument, "stale worktrees are turned off with worktree_stale_after 0").
+			WithHint("Give the age with --older-than, e.g. --older-than 30d")
+	}
+	withArchive := cfg.WorktreeArchive
+	if cmd.Flags().Changed("archive") {
+		withArchive = cleanArchive
+	}
+
+	m := newManager(cmd, out, cfg)
+	worktrees, err := m.List(ctx)
+	if err != nil {
+		return err
+	}
+	candidates, results := staleWorktrees(cmd, m, worktrees, after, time.Now())
+
+	result := schema.WorktreeClean{StaleAfter: after.String(), Worktrees: results}
+	if len(candidates) == 0 || cleanDryRun {
+		if jsonOutput {
+			return out.JSON(result)
+		}
+		if len(candidates) == 0 {
+			out.Info(fmt.Sprintf("No worktrees without commits or visits for %s", formatAge(after)))
+			return nil
+		}
+		printStaleWorktrees(out, results, false)
+		return nil
+	}
+
+	// Dirty worktrees are only removed when nothing is lost
+	var removable []worktree.Worktree
+	labels := map[string]string{}
+	for i, r := range results {
+		if r.Dirty && !cleanForce && !withArchive {
+			if !jsonOutput {
+				out.Warning(fmt.Sprintf("Keeping %s: it has uncommitted changes (use --force or --archive to remove it)", r.Name))
+			}
+			continue
+		}
+		removable = append(removable, candidates[i])
+		labels[r.Path] = fmt.Sprintf("%s (%s) — last active %s", r.Name, staleBranch(r), ageColumn(r.LastActivity, time.Now()))
+	}
+
+	var selected []string
+	switch {
+	case len(removable) == 0:
+	case out.IsTTY() && !cleanYes && !jsonOutput:
+		if err := tui.StaleCleanForm(removable, labels, &selected).Run(); err != nil {
+			return err
+		}
+		if len(selected) == 0 {
+			return lazyerr.New(lazyerr.Cancelled, "no worktrees selected")
+		}
+	default:
+		for _, wt := range removable {
+			selected = append(selected, wt.Path)
+		}
+	}
+
+	chosen := map[string]bool{}
+	for _, path := range selected {
+		chosen[path] = true
+	}
+	var failed []string
+	for i, wt := range candidates {
+		if !chosen[wt.Path] {
+			continue
+		}
+		r := &result.Worktrees[i]
+		if err := removeStaleWorktree(cmd, out, m, wt, r, withArchive); err != nil {
+			r.Error = lazyerr.From(err).Message
+			failed = append(failed, r.Name)
+			continue
+		}
+		r.Removed = true
+	}
+	result.Failed = len(failed)
+
+	if jsonOutput {
+		if err := out.JSON(result); err != nil {
+			return err
+		}
+	} else {
+		printStaleWorktrees(out, result.Worktrees, true)
+	}
+
+	if len(failed) > 0 {
+		e := lazyerr.New(lazyerr.CleanFailed, "failed to remove %d of %d stale worktree(s)", len(failed), len(selected)).
+			WithDetail("failed", failed)
+		if jsonOutput {
+			e = e.MarkReported()
+		}
+		return e
+	}
+	return nil
+}
+
+// staleWorktrees returns the worktrees other than the main checkout that
+// went without activity for after, along with their description
+func staleWorktrees(cmd *cobra.Command, m *worktree.Manager, worktrees []worktree.Worktree, after time.Duration, now time.Time) ([]worktree.Worktree, []schema.StaleWorktree) {
+	ctx := cmd.Context()
+	secondary := git.SecondaryWorktrees(worktrees)
+	var stale []worktree.Worktree
+	results := []schema.StaleWorktree{}
+	for i, t := range m.LastActivity(ctx, secondary) {
+		wt := secondary[i]
+		if !worktree.Stale(t, after, now) {
+			continue
+		}
+		s, err := git.WorktreeStatus(ctx, wt.Path, "")
+		stale = append(stale, wt)
+		results = append(results, schema.StaleWorktree{
+			Name:         filepath.Base(wt.Path),
+			Path:         wt.Path,
+			Branch:       wt.Branch,
+			LastActivity: t,
+			// A worktree whose status can't be read is not assumed clean
+			Dirty: err != nil || s.Dirty(),
+		})
+	}
+	return stale, results
+}
+
+// removeStaleWorktree removes wt, archiving it first if withArchive is set
+func removeStaleWorktree(cmd *cobra.Command, out *output.Output, m *worktree.Manager, wt worktree.Worktree, r *schema.StaleWorktree, withArchive bool) error {
+	var archived *archive.Archive
+	if withArchive {
+		var err error
+		if archived, err = archiveWorktree(cmd, out, m, &wt); err != nil {
+			return err
+		}
+	}
+	// Archived changes are safe to remove
+	if _, err := m.Remove(cmd.Context(), wt.Path, cleanForce || archived != nil); err != nil {
+		if archived != nil {
+			// The worktree is still there
+			archived.Delete()
+		}
+		return err
+	}
+	if archived != nil {
+		r.Archive = archived.ID
+	}
+	return nil
+}
+
+// printStaleWorktrees prints the worktrees found by clean --stale, with the
+// outcome once removed
+func printStaleWorktrees(out *output.Output, results []schema.StaleWorktree, removed bool) {
+	headers := []string{"WORKTREE", "BRANCH", "LAST ACTIVE", "CHANGES"}
+	if removed {
+		headers = append(headers, "RESULT")
+	}
+	now := time.Now()
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		changes := ""
+		if r.Dirty {
+			changes = "uncommitted"
+		}
+		row := []string{r.Name, staleBranch(r), ageColumn(r.LastActivity, now), changes}
+		if removed {
+			switch {
+			case r.Error != "":
+				row = append(row, "failed: "+r.Error)
+			case r.Archive != "":
+				row = append(row, "removed, archived as "+r.Archive)
+			case r.Removed:
+				row = append(row, "removed")
+			default:
+				row = append(row, "kept")
+			}
+		}
+		rows = append(rows, row)
+	}
+	out.Table(headers, rows)
+}
+
+func staleBranch(r schema.StaleWorktree) string {
+	if r.Branch == "" {
+		return "(detached)"
+	}
+	return r.Branch
+}
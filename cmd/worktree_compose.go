@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/env"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/spf13/cobra"
+)
+
+var worktreeComposeCmd = &cobra.Command{
+	Use:   "compose [name] -- <compose args...>",
+	Short: "Run docker compose for a worktree",
+	Long: `Run 'docker compose' inside a worktree (the current one by default) with
+COMPOSE_PROJECT_NAME derived from the worktree name, so each feature
+branch gets its own containers, networks and volumes. The worktree's
+ports (PORT, LW_PORT, LW_PORT_LAST) and the other LW_* variables are set
+too, for use in the compose file.
+
+When a worktree with a compose file is removed, its project is taken
+down first ('docker compose down --remove-orphans'; volumes are kept).
+
+Example:
+  lazywork worktree compose -- up -d
+  lazywork worktree compose feature-auth -- logs -f api`,
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runWorktreeCompose,
+}
+
+func init() {
+	worktreeCmd.AddCommand(worktreeComposeCmd)
+}
+
+func runWorktreeCompose(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 || dash == len(args) {
+		return lazyerr.New(lazyerr.InvalidArgument, "no compose command given").
+			WithHint("Put the compose arguments after --, e.g. lazywork worktree compose -- up -d")
+	}
+	if dash > 1 {
+		return lazyerr.New(lazyerr.InvalidArgument, "compose takes at most one worktree name before --")
+	}
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	name := ""
+	if dash == 1 {
+		name = args[0]
+	}
+	target, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+	if env.ComposeFile(target.Path) == "" {
+		return lazyerr.New(lazyerr.NoComposeFile, "no compose file in %s", target.Path).WithDetail("path", target.Path)
+	}
+	if !env.HasDocker() {
+		return lazyerr.New(lazyerr.CommandFailed, "docker is not installed").
+			WithHint("Install Docker with the compose plugin")
+	}
+
+	cfg, _ := loadConfig(ctx)
+	c := env.ComposeCommand(ctx, hookWorktree(ctx, cfg, target.Path, target.Branch), args[dash:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, cmd.OutOrStdout(), cmd.ErrOrStderr()
+	if err := c.Run(); err != nil {
+		e := lazyerr.New(lazyerr.CommandFailed, "docker compose %s failed in %s", strings.Join(args[dash:], " "), filepath.Base(target.Path))
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			e = e.WithDetail("exit_code", exitErr.ExitCode())
+		}
+		return e.WithHint("See the docker compose output above")
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var worktreeDiffCmd = &cobra.Command{
+	Use:   "diff [name] [base]",
+	Short: "Show what a worktree's branch changes compared to main or another worktree",
+	Long: `Show the changes a worktree's branch has made since it diverged from the
+main branch, or from another worktree's branch (or any branch) given as
+base. Without a name the current worktree is used. Only commits are
+compared; uncommitted changes are not included.
+
+In the 'worktree go' selector, press d to diff the highlighted worktree
+against the main branch.
+
+Example:
+  lazywork worktree diff feature-auth
+  lazywork worktree diff feature-auth feature-api --stat
+  lazywork worktree diff --summary`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: runWorktreeDiff,
+}
+
+var (
+	diffStat     bool
+	diffSummary  bool
+	diffProvider string
+	diffModel    string
+)
+
+func init() {
+	worktreeCmd.AddCommand(worktreeDiffCmd)
+
+	worktreeDiffCmd.Flags().BoolVar(&diffStat, "stat", false, "Show only the files changed")
+	worktreeDiffCmd.Flags().BoolVar(&diffSummary, "summary", false, "Summarize the differences with the AI provider")
+	worktreeDiffCmd.Flags().StringVar(&diffProvider, "provider", "", "AI provider for --summary (default: models.worktree_diff, then default_provider)")
+	worktreeDiffCmd.Flags().StringVar(&diffModel, "model", "", "Model for --summary (default: models.worktree_diff, then default_model)")
+	worktreeDiffCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Send secrets found in the changes to the AI provider unmasked")
+	worktreeDiffCmd.ValidArgsFunction = completeWorktreeNames
+}
+
+func runWorktreeDiff(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.ConfigLoadError, err)
+	}
+
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+	var candidates []git.Worktree
+	for _, wt := range worktrees {
+		if !wt.Bare {
+			candidates = append(candidates, wt)
+		}
+	}
+
+	var target *git.Worktree
+	if len(args) > 0 {
+		target = matchWorktree(candidates, args[0])
+		if target == nil {
+			return lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", args[0]).WithDetail("name", args[0])
+		}
+	} else {
+		root, err := git.GetRepoRoot(ctx)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.NotGitRepo, err)
+		}
+		for i, wt := range candidates {
+			if filepath.Clean(wt.Path) == filepath.Clean(root) {
+				target = &candidates[i]
+			}
+		}
+		if target == nil {
+			return lazyerr.New(lazyerr.WorktreeNotFound, "current worktree not found")
+		}
+	}
+
+	base := ""
+	if len(args) > 1 {
+		if wt := matchWorktree(candidates, args[1]); wt != nil {
+			base = worktreeRef(wt)
+		} else if git.BranchExists(ctx, args[1]) {
+			base = args[1]
+		} else {
+			return lazyerr.New(lazyerr.BranchNotFound, "no worktree or branch named '%s'", args[1]).WithDetail("name", args[1])
+		}
+	}
+
+	return showWorktreeDiff(ctx, out, cfg, target, base)
+}
+
+// worktreeRef returns the branch checked out in wt, or its commit if
+// detached
+func worktreeRef(wt *git.Worktree) string {
+	if wt.Branch != "" {
+		return wt.Branch
+	}
+	return wt.Head
+}
+
+// showWorktreeDiff prints what wt's branch changes compared to base, or
+// the main branch if base is empty
+func showWorktreeDiff(ctx context.Context, out *output.Output, cfg *config.Config, wt *git.Worktree, base string) error {
+	if base == "" {
+		base = cfg.MainBranch
+	}
+	if base == "" {
+		base = git.GetMainBranch(ctx)
+	}
+	head := worktreeRef(wt)
+	name := filepath.Base(wt.Path)
+
+	files, err := git.NumStat(ctx, base, head)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchError, err).WithDetail("base", base).WithDetail("head", head)
+	}
+
+	if len(files) == 0 {
+		if jsonOutput {
+			return out.JSON(schema.WorktreeDiff{Name: name, Head: head, Base: base, Files: []git.FileStat{}})
+		}
+		out.Info(fmt.Sprintf("%s has no changes compared to %s", head, base))
+		return nil
+	}
+
+	var summary string
+	if diffSummary {
+		stat, _ := git.DiffStat(ctx, base, head)
+		patch, err := git.Diff(ctx, base, head, false)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.BranchError, err)
+		}
+		patch = ignoreFiles(ctx, out, patch)
+		p, req, err := newAIRequest(out, cfg, "worktree diff", diffProvider, diffModel, commitmsg.SummaryMessages(base, head, stat, patch))
+		if err != nil {
+			return err
+		}
+		out.Progress(fmt.Sprintf("Summarizing with %s (%s)", p.Name(), req.Model))
+		resp, err := p.Complete(ctx, req)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.ProviderError, err).WithDetail("provider", p.Name())
+		}
+		summary = strings.TrimSpace(resp.Content)
+	}
+
+	showPatch := !diffStat && !diffSummary
+
+	if jsonOutput {
+		result := schema.WorktreeDiff{Name: name, Head: head, Base: base, Files: files, Summary: summary}
+		if showPatch {
+			if result.Patch, err = git.Diff(ctx, base, head, false); err != nil {
+				return lazyerr.Wrap(lazyerr.BranchError, err)
+			}
+		}
+		return out.JSON(result)
+	}
+
+	if showPatch {
+		patch, err := git.Diff(ctx, base, head, out.Color())
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.BranchError, err)
+		}
+		out.Print("%s", patch)
+		return nil
+	}
+
+	out.Bold(fmt.Sprintf("%s compared to %s", head, base))
+	stat, err := git.DiffStat(ctx, base, head)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	out.Println(stat)
+	if summary != "" {
+		out.Println()
+		out.Println(summary)
+	}
+	return nil
+}
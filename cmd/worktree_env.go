@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/miltonparedes/lazywork/internal/env"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/spf13/cobra"
+)
+
+var worktreeEnvCmd = &cobra.Command{
+	Use:   "env [name]",
+	Short: "Print environment variables for a worktree",
+	Long: `Print per-worktree environment variables as shell exports.
+
+Defaults to the current worktree. The variables include a unique
+COMPOSE_PROJECT_NAME so docker-compose stacks of different worktrees
+don't collide, and a block of ports reserved for the worktree: PORT (also
+LW_PORT) is its first port and LW_PORT_LAST its last. Blocks are handed
+out from port_base (3000) in steps of port_block_size (10) and freed when
+the worktree is removed.
+
+Example:
+  eval "$(lazywork worktree env)"
+  lazywork worktree env feature-auth --json`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runWorktreeEnv,
+}
+
+func init() {
+	worktreeCmd.AddCommand(worktreeEnvCmd)
+}
+
+func runWorktreeEnv(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	target, err := findWorktree(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	cfg, _ := loadConfig(ctx)
+	vars := env.Vars(hookWorktree(ctx, cfg, target.Path, target.Branch))
+
+	if jsonOutput {
+		return out.JSON(env.Map(vars))
+	}
+
+	out.Print("%s", env.Exports(vars))
+
+	return nil
+}
+
+// findWorktree returns the non-bare worktree called name, or the current
+// one if name is empty
+func findWorktree(ctx context.Context, name string) (*git.Worktree, error) {
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+
+	var candidates []git.Worktree
+	for _, wt := range worktrees {
+		if !wt.Bare {
+			candidates = append(candidates, wt)
+		}
+	}
+
+	if name != "" {
+		target := matchWorktree(candidates, name)
+		if target == nil {
+			return nil, lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", name).WithDetail("name", name)
+		}
+		return target, nil
+	}
+
+	root, err := git.GetRepoRoot(ctx)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.PathError, err)
+	}
+	for i := range candidates {
+		if candidates[i].Path == root {
+			return &candidates[i], nil
+		}
+	}
+	return nil, lazyerr.New(lazyerr.WorktreeNotFound, "current directory is not a known worktree")
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/env"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+var worktreeExecCmd = &cobra.Command{
+	Use:   "exec [--all | name...] -- <command> [args...]",
+	Short: "Run a command in several worktrees",
+	Long: `Run a command inside each of the named worktrees, or every worktree with
+--all, and report which ones failed. The command is run directly, not
+through a shell; wrap it in sh -c for pipes or globs. It gets the same
+LW_* environment variables as hooks.
+
+Worktrees are visited one at a time unless --parallel is given, in which
+case output lines are prefixed with the worktree name. lazywork exits
+non-zero if the command failed in any worktree.
+
+Example:
+  lazywork worktree exec --all -- git fetch
+  lazywork worktree exec feature-auth feature-api -- npm test
+  lazywork worktree exec --all --parallel -- sh -c 'make lint'`,
+	RunE: runWorktreeExec,
+}
+
+var (
+	execAll      bool
+	execParallel bool
+	execJobs     int
+)
+
+func init() {
+	worktreeCmd.AddCommand(worktreeExecCmd)
+
+	worktreeExecCmd.Flags().BoolVarP(&execAll, "all", "a", false, "Run in every worktree")
+	worktreeExecCmd.Flags().BoolVarP(&execParallel, "parallel", "p", false, "Run in the worktrees in parallel, prefixing output lines")
+	worktreeExecCmd.Flags().IntVar(&execJobs, "jobs", git.DefaultStatusWorkers, "Number of worktrees to run in at once with --parallel")
+	worktreeExecCmd.ValidArgsFunction = completeWorktreeNames
+}
+
+func runWorktreeExec(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 || dash == len(args) {
+		return lazyerr.New(lazyerr.InvalidArgument, "no command given").
+			WithHint("Put the command after --, e.g. lazywork worktree exec --all -- git fetch")
+	}
+	names, command := args[:dash], args[dash:]
+	if execAll == (len(names) > 0) {
+		return lazyerr.New(lazyerr.InvalidArgument, "pass either worktree names or --all")
+	}
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+	var candidates []git.Worktree
+	for _, wt := range worktrees {
+		if !wt.Bare {
+			candidates = append(candidates, wt)
+		}
+	}
+
+	targets := candidates
+	if !execAll {
+		targets = nil
+		for _, name := range names {
+			wt := matchWorktree(candidates, name)
+			if wt == nil {
+				return lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", name).WithDetail("name", name)
+			}
+			targets = append(targets, *wt)
+		}
+	}
+
+	// Port blocks are reserved up front so they follow the worktree order
+	cfg, _ := loadConfig(ctx)
+	width := 0
+	environs := make([][]string, len(targets))
+	for i, wt := range targets {
+		width = max(width, len(filepath.Base(wt.Path)))
+		environs[i] = env.Environ(env.Vars(hookWorktree(ctx, cfg, wt.Path, wt.Branch)))
+	}
+
+	var mu sync.Mutex
+	results := make([]schema.ExecResult, len(targets))
+	run := func(i int) {
+		wt := targets[i]
+		name := filepath.Base(wt.Path)
+		c := exec.CommandContext(ctx, command[0], command[1:]...)
+		c.Dir = wt.Path
+		c.Env = append(os.Environ(), environs[i]...)
+
+		var captured bytes.Buffer
+		var prefixed []*prefixWriter
+		switch {
+		case jsonOutput:
+			c.Stdout, c.Stderr = &captured, &captured
+		case execParallel:
+			prefix := fmt.Sprintf("[%-*s] ", width, name)
+			stdout := &prefixWriter{mu: &mu, w: cmd.OutOrStdout(), prefix: prefix}
+			stderr := &prefixWriter{mu: &mu, w: cmd.ErrOrStderr(), prefix: prefix}
+			prefixed = append(prefixed, stdout, stderr)
+			c.Stdout, c.Stderr = stdout, stderr
+		default:
+			out.Bold(fmt.Sprintf("==> %s", name))
+			c.Stdin, c.Stdout, c.Stderr = os.Stdin, cmd.OutOrStdout(), cmd.ErrOrStderr()
+		}
+
+		start := time.Now()
+		err := c.Run()
+		for _, w := range prefixed {
+			w.Flush()
+		}
+
+		results[i] = schema.ExecResult{Name: name, Path: wt.Path, Seconds: time.Since(start).Round(time.Millisecond).Seconds()}
+		if jsonOutput {
+			output := captured.String()
+			results[i].Output = &output
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			results[i].ExitCode = -1
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				results[i].ExitCode = exitErr.ExitCode()
+			}
+		}
+	}
+
+	if execParallel {
+		var g errgroup.Group
+		g.SetLimit(max(execJobs, 1))
+		for i := range targets {
+			g.Go(func() error {
+				run(i)
+				return nil
+			})
+		}
+		g.Wait()
+	} else {
+		for i := range targets {
+			if ctx.Err() != nil {
+				break
+			}
+			run(i)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, r := range results {
+		if r.Error != "" {
+			failed = append(failed, r.Name)
+		}
+	}
+
+	if jsonOutput {
+		if err := out.JSON(schema.WorktreeExec{Command: command, Worktrees: results, Failed: len(failed)}); err != nil {
+			return err
+		}
+	} else {
+		out.Println()
+		for _, r := range results {
+			if r.Error != "" {
+				out.Error(fmt.Sprintf("%-*s  %s", width, r.Name, r.Error))
+			} else {
+				out.Success(fmt.Sprintf("%-*s  %.1fs", width, r.Name, r.Seconds))
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		e := lazyerr.New(lazyerr.CommandFailed, "'%s' failed in %d of %d worktree(s)", strings.Join(command, " "), len(failed), len(results)).
+			WithDetail("failed", failed)
+		if jsonOutput {
+			e = e.MarkReported()
+		}
+		return e
+	}
+	return nil
+}
+
+// prefixWriter writes each complete line to w with a prefix, holding back
+// a partial line until it is finished or flushed. Writers sharing mu don't
+// interleave their lines.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.writeLine(p.buf[:i+1])
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes any unfinished line
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.writeLine(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	io.WriteString(p.w, p.prefix)
+	p.w.Write(line)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/internal/git/gittest"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		flush  bool
+		want   string
+	}{
+		{"whole lines", []string{"one\ntwo\n"}, false, "> one\n> two\n"},
+		{"split line", []string{"o", "ne\ntw", "o\n"}, false, "> one\n> two\n"},
+		{"partial line held back", []string{"one\ntw"}, false, "> one\n"},
+		{"partial line flushed", []string{"one\ntw"}, true, "> one\n> tw\n"},
+		{"flush with nothing held", []string{"one\n"}, true, "> one\n"},
+		{"empty line", []string{"\n"}, false, "> \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			w := &prefixWriter{mu: &sync.Mutex{}, w: &b, prefix: "> "}
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", s, n, err)
+				}
+			}
+			if tt.flush {
+				w.Flush()
+			}
+			if b.String() != tt.want {
+				t.Errorf("output = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWorktreeExecJSON(t *testing.T) {
+	root := t.TempDir()
+	t.Cleanup(func() { execAll = false })
+	gittest.New(t).
+		On("rev-parse --is-inside-work-tree", "true\n").
+		On("worktree list --porcelain", `worktree `+filepath.Join(root, "app")+`
+HEAD 1111111111111111111111111111111111111111
+branch refs/heads/main
+
+worktree `+filepath.Join(root, "feature-auth")+`
+HEAD 2222222222222222222222222222222222222222
+branch refs/heads/feature-auth
+`).
+		On("rev-parse --git-common-dir", t.TempDir()+"\n")
+	for _, dir := range []string{"app", "feature-auth"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := filepath.Join(t.TempDir(), "config.json")
+	stdout, _, err := execute(t, "worktree", "exec", "--all", "--json", "--config", config, "--", "sh", "-c", "echo $LW_WORKTREE_NAME; exit 3")
+	if code := ExitCode(err); code != 1 || lazyerr.From(err).Code != lazyerr.CommandFailed {
+		t.Errorf("exit code = %d (%v), want 1 for a failed command", code, err)
+	}
+
+	var result struct {
+		Command   []string `json:"command"`
+		Failed    int      `json:"failed"`
+		Worktrees []struct {
+			Name     string `json:"name"`
+			ExitCode int    `json:"exit_code"`
+			Error    string `json:"error"`
+			Output   string `json:"output"`
+		} `json:"worktrees"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if result.Failed != 2 || len(result.Worktrees) != 2 || len(result.Command) != 3 {
+		t.Fatalf("result = %+v, want 2 worktrees, both failed", result)
+	}
+	for _, wt := range result.Worktrees {
+		if wt.ExitCode != 3 || wt.Error == "" || wt.Output != wt.Name+"\n" {
+			t.Errorf("worktree %s = %+v, want exit code 3 and its name as output", wt.Name, wt)
+		}
+	}
+}
+
+func TestWorktreeExecTargets(t *testing.T) {
+	t.Cleanup(func() { execAll = false })
+	gittest.New(t)
+
+	for _, args := range [][]string{
+		{"worktree", "exec", "--", "true"},
+		{"worktree", "exec", "--all", "feature-auth", "--", "true"},
+	} {
+		execAll = false
+		_, _, err := execute(t, args...)
+		if code := ExitCode(err); code != 2 || !strings.Contains(err.Error(), "--all") {
+			t.Errorf("%v: exit code = %d (%v), want 2 asking for names or --all", args, code, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var worktreeHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show recently visited worktrees",
+	Long: `Show the worktree visit history used for frecency ordering and
+'worktree go -', most relevant first.`,
+	Args: cobra.NoArgs,
+	RunE: runWorktreeHistory,
+}
+
+var worktreeHistoryPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove history entries for deleted worktrees",
+	Long: `Remove history entries whose worktree no longer exists.
+
+This also happens automatically whenever the history is read.`,
+	Args: cobra.NoArgs,
+	RunE: runWorktreeHistoryPrune,
+}
+
+func init() {
+	worktreeCmd.AddCommand(worktreeHistoryCmd)
+	worktreeHistoryCmd.AddCommand(worktreeHistoryPruneCmd)
+}
+
+func runWorktreeHistory(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	history := loadHistory(ctx)
+	if history == nil {
+		history = &state.History{}
+	}
+
+	now := time.Now()
+	entries := append([]state.Entry(nil), history.Entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return history.Score(entries[i].Path, now) > history.Score(entries[j].Path, now)
+	})
+
+	if jsonOutput {
+		items := make([]schema.HistoryEntry, 0, len(entries))
+		for _, e := range entries {
+			items = append(items, schema.HistoryEntry{
+				Path:      e.Path,
+				Visits:    e.Visits,
+				LastVisit: e.LastVisit,
+				Score:     history.Score(e.Path, now),
+			})
+		}
+		return out.JSON(schema.WorktreeHistory{Entries: items, Count: len(items)})
+	}
+
+	if len(entries) == 0 {
+		out.Dim("No worktree history yet")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, []string{
+			filepath.Base(e.Path),
+			fmt.Sprintf("%d", e.Visits),
+			e.LastVisit.Local().Format("2006-01-02 15:04"),
+			fmt.Sprintf("%.1f", history.Score(e.Path, now)),
+			e.Path,
+		})
+	}
+	out.Table([]string{"NAME", "VISITS", "LAST VISIT", "SCORE", "PATH"}, rows)
+
+	return nil
+}
+
+func runWorktreeHistoryPrune(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.NotGitRepo, err)
+	}
+
+	var removed []string
+	_, err = state.UpdateHistory(state.Dir(commonDir), func(h *state.History) error {
+		removed = h.Prune(state.DirExists)
+		return nil
+	})
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.StateSaveError, err)
+	}
+
+	if jsonOutput {
+		if removed == nil {
+			removed = []string{}
+		}
+		return out.JSON(schema.HistoryPrune{Removed: removed, Count: len(removed)})
+	}
+
+	if len(removed) == 0 {
+		out.Success("History is clean")
+		return nil
+	}
+
+	for _, path := range removed {
+		out.Dim("  " + path)
+	}
+	out.Success(fmt.Sprintf("Removed %d stale history entries", len(removed)))
+
+	return nil
+}
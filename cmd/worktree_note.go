@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var worktreeNoteCmd = &cobra.Command{
+	Use:   "note <name> [text]",
+	Short: "Attach a note, tags or an issue link to a worktree",
+	Long: `Attach a note, tags or an issue/PR link to a worktree.
+
+Metadata is stored in .git/lazywork/worktrees.json, shown in
+'worktree list' and the worktree selector, and removed together with the
+worktree. Without text or flags the current metadata is printed.
+
+Example:
+  lazywork worktree note feature-auth "waiting on API review"
+  lazywork worktree note feature-auth --tag review --issue https://github.com/org/repo/pull/42
+  lazywork worktree note feature-auth --untag review
+  lazywork worktree list --tag review`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runWorktreeNote,
+}
+
+var (
+	noteTags   []string
+	noteUntags []string
+	noteIssue  string
+	noteClear  bool
+)
+
+func init() {
+	worktreeCmd.AddCommand(worktreeNoteCmd)
+
+	worktreeNoteCmd.Flags().StringSliceVar(&noteTags, "tag", nil, "Add a tag (repeatable)")
+	worktreeNoteCmd.Flags().StringSliceVar(&noteUntags, "untag", nil, "Remove a tag (repeatable)")
+	worktreeNoteCmd.Flags().StringVar(&noteIssue, "issue", "", "Link an issue or PR URL")
+	worktreeNoteCmd.Flags().BoolVar(&noteClear, "clear", false, "Remove all metadata before applying other changes")
+	worktreeNoteCmd.ValidArgsFunction = completeWorktreeNames
+}
+
+func runWorktreeNote(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+
+	name := args[0]
+	target := matchWorktree(worktrees, name)
+	if target == nil {
+		return lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", name).WithDetail("name", name)
+	}
+
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.NotGitRepo, err)
+	}
+	dir := state.Dir(commonDir)
+	changed := noteClear || len(args) > 1 || len(noteTags) > 0 || len(noteUntags) > 0 || cmd.Flags().Changed("issue")
+
+	var m state.Meta
+	if changed {
+		store, err := state.UpdateMeta(dir, func(s *state.MetaStore) error {
+			meta := s.Get(target.Path)
+			if noteClear {
+				meta = state.Meta{}
+			}
+			if len(args) > 1 {
+				meta.Note = args[1]
+			}
+			if cmd.Flags().Changed("issue") {
+				meta.Issue = noteIssue
+			}
+			meta.AddTags(noteTags...)
+			meta.RemoveTags(noteUntags...)
+			s.Set(target.Path, meta, time.Now())
+			return nil
+		})
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.StateSaveError, err)
+		}
+		m = store.Get(target.Path)
+	} else {
+		store, err := state.LoadMeta(dir)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.StateSaveError, err)
+		}
+		m = store.Get(target.Path)
+	}
+
+	if jsonOutput {
+		return out.JSON(schema.WorktreeNote{Path: target.Path, Name: filepath.Base(target.Path), Meta: m, Saved: changed})
+	}
+
+	label := filepath.Base(target.Path)
+	if m.IsEmpty() {
+		if changed {
+			out.Success("Cleared metadata for " + label)
+		} else {
+			out.Dim("No metadata for " + label)
+		}
+		return nil
+	}
+
+	if changed {
+		out.Success("Updated metadata for " + label)
+	} else {
+		out.Bold(label)
+	}
+	if m.Note != "" {
+		out.Print("  note:  %s\n", m.Note)
+	}
+	if len(m.Tags) > 0 {
+		out.Print("  tags:  %s\n", strings.Join(m.Tags, ", "))
+	}
+	if m.Issue != "" {
+		out.Print("  issue: %s\n", m.Issue)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var worktreePickCmd = &cobra.Command{
+	Use:   "pick <commit>... --to <worktree>",
+	Short: "Cherry-pick commits into another worktree's branch",
+	Long: `Cherry-pick commits into the branch of another worktree, running the
+cherry-pick inside that worktree's checkout so your current one is left
+alone. Commits are resolved from the current worktree, so HEAD, HEAD~2 or
+a branch name work as well as hashes.
+
+The target worktree must not have uncommitted changes. On conflicts the
+cherry-pick is left in progress there and the conflicting files are
+listed; resolve them in that worktree (e.g. with 'lazywork resolve') and
+run 'git cherry-pick --continue', or pass --abort-on-conflict to undo it.
+
+Example:
+  lazywork worktree pick HEAD --to release-1.2
+  lazywork worktree pick a1b2c3d e4f5a6b --to hotfix -x`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runWorktreePick,
+}
+
+var (
+	pickTo              string
+	pickRecord          bool
+	pickAbortOnConflict bool
+)
+
+func init() {
+	worktreeCmd.AddCommand(worktreePickCmd)
+
+	worktreePickCmd.Flags().StringVar(&pickTo, "to", "", "Worktree to pick the commits into (required)")
+	worktreePickCmd.Flags().BoolVarP(&pickRecord, "record-origin", "x", false, "Add \"(cherry picked from commit ...)\" to the messages")
+	worktreePickCmd.Flags().BoolVar(&pickAbortOnConflict, "abort-on-conflict", false, "Undo the cherry-pick if it conflicts")
+	worktreePickCmd.MarkFlagRequired("to")
+	worktreePickCmd.RegisterFlagCompletionFunc("to", completeWorktreeNames)
+}
+
+func runWorktreePick(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	commits := make([]string, 0, len(args))
+	for _, rev := range args {
+		commit, err := git.ResolveCommit(ctx, rev)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.InvalidArgument, err).WithDetail("commit", rev)
+		}
+		commits = append(commits, commit)
+	}
+
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+	var candidates []git.Worktree
+	for _, wt := range worktrees {
+		if !wt.Bare {
+			candidates = append(candidates, wt)
+		}
+	}
+	target := matchWorktree(candidates, pickTo)
+	if target == nil {
+		return lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", pickTo).WithDetail("name", pickTo)
+	}
+
+	if root, err := git.GetRepoRoot(ctx); err == nil && filepath.Clean(root) == filepath.Clean(target.Path) {
+		return lazyerr.New(lazyerr.InvalidArgument, "cannot pick into the current worktree; use git cherry-pick")
+	}
+	if target.Branch == "" {
+		return lazyerr.New(lazyerr.DetachedHead, "worktree '%s' is in detached HEAD state", filepath.Base(target.Path))
+	}
+	if git.HasUncommittedChangesIn(ctx, target.Path) {
+		return lazyerr.New(lazyerr.UncommittedChanges, "worktree '%s' has uncommitted changes", filepath.Base(target.Path)).
+			WithDetail("path", target.Path)
+	}
+
+	out.Progress(fmt.Sprintf("Cherry-picking %d commit(s) into %s", len(commits), target.Branch))
+	if err := git.CherryPick(ctx, target.Path, pickRecord, commits...); err != nil {
+		conflicts, _ := git.ConflictedFilesIn(ctx, target.Path)
+		if len(conflicts) == 0 {
+			// Nothing to resolve (e.g. the change is already there), so
+			// don't leave a half-done cherry-pick behind
+			git.CherryPickAbort(ctx, target.Path)
+			return lazyerr.Wrap(lazyerr.CherryPickError, err).WithDetail("branch", target.Branch)
+		}
+
+		conflictErr := lazyerr.New(lazyerr.MergeConflict, "cherry-pick into %s conflicts in %d file(s)", target.Branch, len(conflicts)).
+			WithDetail("branch", target.Branch).
+			WithDetail("path", target.Path).
+			WithDetail("conflicts", conflicts)
+		if pickAbortOnConflict {
+			if err := git.CherryPickAbort(ctx, target.Path); err != nil {
+				return lazyerr.Wrap(lazyerr.CherryPickError, err).WithDetail("path", target.Path)
+			}
+			conflictErr = conflictErr.WithDetail("aborted", true).WithHint("Nothing was changed")
+		} else {
+			conflictErr = conflictErr.WithHint(fmt.Sprintf("Resolve them in %s, then run 'git cherry-pick --continue' (or --abort)", target.Path))
+		}
+		if !jsonOutput {
+			for _, f := range conflicts {
+				out.Warning(f)
+			}
+		}
+		return conflictErr
+	}
+
+	head, _ := git.ShortHash(ctx, target.Branch)
+
+	if jsonOutput {
+		return out.JSON(schema.WorktreePick{Commits: commits, Branch: target.Branch, Path: target.Path, Head: head})
+	}
+
+	short := make([]string, len(commits))
+	for i, c := range commits {
+		short[i] = c[:min(len(c), 7)]
+	}
+	out.Success(fmt.Sprintf("Picked %s into %s", strings.Join(short, ", "), target.Branch))
+	out.Dim(fmt.Sprintf("  %s is now at %s", target.Branch, head))
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/output"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var worktreeStatusCmd = &cobra.Command{
+	Use:   "status [name]",
+	Short: "Show uncommitted changes and ahead/behind counts",
+	Long: `Show uncommitted changes and how far each branch is ahead of or behind
+its upstream, or the main branch if it has no upstream.
+
+Without arguments the current worktree is shown; use --all for every
+worktree. Worktrees are queried in parallel. With --fetch, or auto_fetch
+in the config, the remote is fetched first so the counts are current and
+branches whose upstream was deleted show as gone.
+
+Example:
+  lazywork worktree status
+  lazywork worktree status --all --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runWorktreeStatus,
+}
+
+var (
+	statusAll     bool
+	statusWorkers int
+)
+
+func init() {
+	worktreeCmd.AddCommand(worktreeStatusCmd)
+
+	worktreeStatusCmd.Flags().BoolVarP(&statusAll, "all", "a", false, "Show every worktree")
+	worktreeStatusCmd.Flags().IntVar(&statusWorkers, "jobs", git.DefaultStatusWorkers, "Number of worktrees to query in parallel")
+	worktreeStatusCmd.Flags().BoolVar(&fetchFirst, "fetch", false, "Run 'git fetch --prune' first (default from auto_fetch)")
+	worktreeStatusCmd.ValidArgsFunction = completeWorktreeNames
+}
+
+func runWorktreeStatus(cmd *cobra.Command, args []string) error {
+	out := newOutput(cmd)
+	ctx := cmd.Context()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+
+	cfg, _ := loadConfig(ctx)
+	fetchRemote(ctx, out, cfg)
+
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+
+	switch {
+	case statusAll:
+	case len(args) > 0:
+		wt := matchWorktree(worktrees, args[0])
+		if wt == nil {
+			return lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", args[0]).WithDetail("name", args[0])
+		}
+		worktrees = []git.Worktree{*wt}
+	default:
+		root, err := git.GetRepoRoot(ctx)
+		if err != nil {
+			return lazyerr.Wrap(lazyerr.NotGitRepo, err)
+		}
+		worktrees = []git.Worktree{{Path: root}}
+	}
+
+	statuses := git.StatusAll(ctx, worktrees, statusBase(ctx), statusWorkers)
+
+	if jsonOutput {
+		return out.JSON(schema.WorktreeStatus{Worktrees: statuses, Count: len(statuses)})
+	}
+
+	rows := make([][]string, 0, len(statuses))
+	for i, s := range statuses {
+		if worktrees[i].Bare {
+			continue
+		}
+		branch := s.Branch
+		if branch == "" {
+			branch = "(detached)"
+		}
+		rows = append(rows, []string{filepath.Base(s.Path), branch, statusChanges(s), statusSync(s)})
+	}
+	out.Table([]string{"NAME", "BRANCH", "CHANGES", "SYNC"}, rows)
+
+	return nil
+}
+
+// statusBase returns the branch worktrees without an upstream are compared
+// against
+func statusBase(ctx context.Context) string {
+	if cfg, err := loadConfig(ctx); err == nil && cfg.MainBranch != "" {
+		return cfg.MainBranch
+	}
+	return git.GetMainBranch(ctx)
+}
+
+// fetchFirst is the --fetch flag shared by the commands that read remote
+// state
+var fetchFirst bool
+
+// fetchRemote runs 'git fetch --prune' when --fetch is given or auto_fetch
+// is set, so ahead/behind counts and gone upstreams are current. A failed
+// fetch only warns: the local data is still worth showing.
+func fetchRemote(ctx context.Context, out *output.Output, cfg *config.Config) {
+	if !fetchFirst && (cfg == nil || !cfg.AutoFetch) {
+		return
+	}
+	stop := out.Spinner("Fetching from remote")
+	err := git.FetchPrune(ctx)
+	stop()
+	if err != nil {
+		out.Warning(fmt.Sprintf("Fetch failed, remote data may be stale: %v", err))
+	}
+}
+
+// statusChanges describes uncommitted changes, e.g. "2 staged, 1 untracked"
+func statusChanges(s git.Status) string {
+	if s.Error != "" {
+		return "error: " + s.Error
+	}
+	if !s.Dirty() {
+		return "clean"
+	}
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		what string
+	}{
+		{s.Conflicts, "conflicted"},
+		{s.Staged, "staged"},
+		{s.Modified, "modified"},
+		{s.Untracked, "untracked"},
+	} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.what))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// statusSync describes ahead/behind counts, e.g. "↑2 ↓1 origin/main"
+func statusSync(s git.Status) string {
+	if s.Upstream == "" {
+		return ""
+	}
+	if s.Gone {
+		return "gone " + s.Upstream
+	}
+	if s.Ahead == 0 && s.Behind == 0 {
+		return "= " + s.Upstream
+	}
+	var parts []string
+	if s.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("↑%d", s.Ahead))
+	}
+	if s.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("↓%d", s.Behind))
+	}
+	return strings.Join(parts, " ") + " " + s.Upstream
+}
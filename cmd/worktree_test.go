@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/git/gittest"
+	"github.com/miltonparedes/lazywork/internal/state"
+)
+
+const porcelain = `worktree /src/app
+HEAD 1111111111111111111111111111111111111111
+branch refs/heads/main
+
+worktree /src/app/.worktrees/feature-auth
+HEAD 2222222222222222222222222222222222222222
+branch refs/heads/feature-auth
+`
+
+func TestWorktreeListJSON(t *testing.T) {
+	commonDir := t.TempDir()
+	gittest.New(t).
+		On("rev-parse --is-inside-work-tree", "true\n").
+		On("worktree list --porcelain", porcelain).
+		On("rev-parse --git-common-dir", commonDir+"\n")
+
+	_, err := state.UpdateMeta(state.Dir(commonDir), func(s *state.MetaStore) error {
+		s.Set("/src/app/.worktrees/feature-auth", state.Meta{Note: "login flow", Tags: []string{"wip"}}, time.Now())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err := execute(t, "worktree", "list", "--json")
+	if err != nil {
+		t.Fatalf("worktree list failed: %v", err)
+	}
+
+	var result struct {
+		Count     int `json:"count"`
+		Worktrees []struct {
+			Path   string      `json:"path"`
+			Branch string      `json:"branch"`
+			Meta   *state.Meta `json:"meta"`
+		} `json:"worktrees"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if result.Count != 2 {
+		t.Fatalf("count = %d, want 2", result.Count)
+	}
+	if wt := result.Worktrees[0]; wt.Branch != "main" || wt.Meta != nil {
+		t.Errorf("main worktree = %+v, want branch main without metadata", wt)
+	}
+	if wt := result.Worktrees[1]; wt.Meta == nil || wt.Meta.Note != "login flow" || !wt.Meta.HasTag("wip") {
+		t.Errorf("feature worktree = %+v, want its note and tag", wt)
+	}
+}
+
+func TestWorktreeListFilters(t *testing.T) {
+	commonDir := t.TempDir()
+	now := time.Now()
+	gittest.New(t).
+		On("rev-parse --is-inside-work-tree", "true\n").
+		On("worktree list --porcelain", porcelain+`
+worktree /src/app/.worktrees/fix-typo
+HEAD 3333333333333333333333333333333333333333
+branch refs/heads/fix/typo
+
+worktree /src/app/.worktrees/fix-crash
+HEAD 4444444444444444444444444444444444444444
+branch refs/heads/fix/crash
+`).
+		On("rev-parse --git-common-dir", commonDir+"\n").
+		On("log -1 --format=%ct 3333333333333333333333333333333333333333 --", fmt.Sprintf("%d\n", now.Add(-60*24*time.Hour).Unix())).
+		On("log -1 --format=%ct 4444444444444444444444444444444444444444 --", fmt.Sprintf("%d\n", now.Add(-60*24*time.Hour).Unix()))
+
+	// A recent visit keeps fix-crash from being stale
+	_, err := state.UpdateHistory(state.Dir(commonDir), func(h *state.History) error {
+		h.Record("/src/app/.worktrees/fix-crash", now.Add(-time.Hour))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err := execute(t, "worktree", "list", "--branch-prefix", "fix/", "--stale", "30d", "--json")
+	if err != nil {
+		t.Fatalf("worktree list failed: %v", err)
+	}
+	var result struct {
+		Count     int `json:"count"`
+		Worktrees []struct {
+			Branch       string     `json:"branch"`
+			LastActivity *time.Time `json:"last_activity"`
+			Stale        bool       `json:"stale"`
+		} `json:"worktrees"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if result.Count != 1 || result.Worktrees[0].Branch != "fix/typo" || result.Worktrees[0].LastActivity == nil || !result.Worktrees[0].Stale {
+		t.Errorf("worktrees = %+v, want only fix/typo, stale with its activity", result.Worktrees)
+	}
+
+	if _, _, err := execute(t, "worktree", "list", "--stale", "soon"); ExitCode(err) != 2 {
+		t.Errorf("invalid --stale: exit code = %d (%v), want 2", ExitCode(err), err)
+	}
+}
+
+func TestWorktreeCleanStaleDryRun(t *testing.T) {
+	commonDir := t.TempDir()
+	old := fmt.Sprintf("%d\n", time.Now().Add(-60*24*time.Hour).Unix())
+	gittest.New(t).
+		On("rev-parse --is-inside-work-tree", "true\n").
+		On("worktree list --porcelain", porcelain+`
+worktree /src/app/.worktrees/fix-typo
+HEAD 3333333333333333333333333333333333333333
+branch refs/heads/fix/typo
+`).
+		On("rev-parse --git-common-dir", commonDir+"\n").
+		On("log -1 --format=%ct 2222222222222222222222222222222222222222 --", fmt.Sprintf("%d\n", time.Now().Unix())).
+		On("log -1 --format=%ct 3333333333333333333333333333333333333333 --", old).
+		On("-C /src/app/.worktrees/fix-typo status --porcelain=v2 --branch", "# branch.head fix/typo\n? notes.txt\n")
+
+	config := filepath.Join(t.TempDir(), "config.json")
+	stdout, _, err := execute(t, "worktree", "clean", "--stale", "--older-than", "2w", "--dry-run", "--json", "--config", config)
+	if err != nil {
+		t.Fatalf("worktree clean failed: %v", err)
+	}
+	var result struct {
+		StaleAfter string `json:"stale_after"`
+		Worktrees  []struct {
+			Name    string `json:"name"`
+			Dirty   bool   `json:"dirty"`
+			Removed bool   `json:"removed"`
+		} `json:"worktrees"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if len(result.Worktrees) != 1 || result.Worktrees[0].Name != "fix-typo" || !result.Worktrees[0].Dirty || result.Worktrees[0].Removed {
+		t.Errorf("worktrees = %+v, want only fix-typo, dirty and not removed", result.Worktrees)
+	}
+	if result.StaleAfter != (14 * 24 * time.Hour).String() {
+		t.Errorf("stale_after = %q, want 336h0m0s", result.StaleAfter)
+	}
+
+	if _, _, err := execute(t, "worktree", "clean", "--stale=false", "--config", config); ExitCode(err) != 2 {
+		t.Errorf("clean without --stale: exit code = %d (%v), want 2", ExitCode(err), err)
+	}
+}
+
+func TestWorktreeListNotRepo(t *testing.T) {
+	gittest.New(t)
+
+	_, _, err := execute(t, "worktree", "list")
+	if code := ExitCode(err); code != 3 {
+		t.Errorf("exit code = %d (%v), want 3", code, err)
+	}
+}
+
+func TestResolveWorktreeArg(t *testing.T) {
+	worktrees := []git.Worktree{
+		{Path: "/src/app/.worktrees/feature-auth", Branch: "feature-auth"},
+		{Path: "/src/app/.worktrees/fix-auth", Branch: "fix-auth"},
+		{Path: "/src/app/.worktrees/issue-42", Branch: "42-login"},
+	}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"fix-auth", []string{"fix-auth"}},
+		{"2", []string{"fix-auth"}},
+		{"42", []string{"issue-42"}},
+		{"login", []string{"issue-42"}},
+		{"auth", []string{"feature-auth", "fix-auth"}},
+	}
+	for _, tt := range tests {
+		matches, err := resolveWorktreeArg(worktrees, tt.name)
+		if err != nil {
+			t.Errorf("resolveWorktreeArg(%q) failed: %v", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, wt := range matches {
+			got = append(got, filepath.Base(wt.Path))
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("resolveWorktreeArg(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, name := range []string{"7", "0", "nope"} {
+		if _, err := resolveWorktreeArg(worktrees, name); ExitCode(err) != 4 {
+			t.Errorf("resolveWorktreeArg(%q): err = %v, want not found", name, err)
+		}
+	}
+}
+
+func TestWorktreeGoAmbiguous(t *testing.T) {
+	gittest.New(t).
+		On("rev-parse --is-inside-work-tree", "true\n").
+		On("worktree list --porcelain", porcelain+`
+worktree /src/app/.worktrees/fix-auth
+HEAD 3333333333333333333333333333333333333333
+branch refs/heads/fix-auth
+`).
+		On("rev-parse --git-common-dir", t.TempDir()+"\n")
+
+	_, _, err := execute(t, "worktree", "go", "auth")
+	if code := ExitCode(err); code != 2 {
+		t.Fatalf("exit code = %d (%v), want 2", code, err)
+	}
+	if !strings.Contains(err.Error(), "feature-auth, fix-auth") {
+		t.Errorf("err = %v, want the matching worktrees", err)
+	}
+}
+
+func TestWorktreeGoMain(t *testing.T) {
+	commonDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	gittest.New(t).
+		On("rev-parse --is-inside-work-tree", "true\n").
+		On("worktree list --porcelain", porcelain).
+		On("rev-parse --git-common-dir", commonDir+"\n")
+
+	stdout, _, err := execute(t, "worktree", "go", "main", "--json")
+	if err != nil {
+		t.Fatalf("worktree go main failed: %v", err)
+	}
+	var result struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if result.Path != "/src/app" {
+		t.Errorf("path = %s, want the main checkout", result.Path)
+	}
+
+	history, err := state.LoadHistory(state.Dir(commonDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Entries) != 1 || history.Entries[0].Path != "/src/app" {
+		t.Errorf("history = %+v, want the visit to the main checkout", history.Entries)
+	}
+}
+
+func TestWorktreeGoIncludeMain(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("LAZYWORK_WORKTREE_INCLUDE_MAIN", "true")
+	gittest.New(t).
+		On("rev-parse --is-inside-work-tree", "true\n").
+		On("rev-parse --show-toplevel", "/src/app\n").
+		On("worktree list --porcelain", porcelain).
+		On("rev-parse --git-common-dir", t.TempDir()+"\n")
+
+	stdout, _, err := execute(t, "worktree", "go", "1", "--json", "--config", filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("worktree go failed: %v", err)
+	}
+	var result struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if result.Path != "/src/app" {
+		t.Errorf("path = %s, want the main checkout listed first", result.Path)
+	}
+}
+
+func TestWorktreeAddPush(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@test.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@test.com")
+	remote := filepath.Join(t.TempDir(), "origin.git")
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"commit", "--allow-empty", "-m", "Initial commit"},
+		{"init", "--bare", "-b", "main", remote},
+		{"remote", "add", "origin", remote},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	pushFlag := worktreeAddCmd.Flags().Lookup("push")
+	reset := func() {
+		addPush = false
+		pushFlag.Changed = false
+	}
+	t.Cleanup(reset)
+
+	tests := []struct {
+		name   string
+		config bool
+		args   []string
+		want   bool
+	}{
+		{"config", true, nil, true},
+		{"flag overrides config", true, []string{"--push=false"}, false},
+		{"flag", false, []string{"--push"}, true},
+		{"neither", false, nil, false},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			config := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(config, []byte(fmt.Sprintf(`{"worktree_push": %v}`, tt.config)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			branch := fmt.Sprintf("feature-%d", i)
+			args := append([]string{"worktree", "add", branch, "--json", "--config", config}, tt.args...)
+			stdout, _, err := execute(t, args...)
+			if err != nil {
+				t.Fatalf("worktree add failed: %v", err)
+			}
+			var result struct {
+				Pushed bool `json:"pushed"`
+			}
+			if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+				t.Fatalf("invalid JSON %q: %v", stdout, err)
+			}
+			if result.Pushed != tt.want {
+				t.Errorf("pushed = %v, want %v", result.Pushed, tt.want)
+			}
+			upstream, _ := exec.Command("git", "rev-parse", "--abbrev-ref", branch+"@{upstream}").Output()
+			if got := strings.TrimSpace(string(upstream)); (got == "origin/"+branch) != tt.want {
+				t.Errorf("upstream = %q, want it set only when pushed", got)
+			}
+		})
+	}
+
+	// A failed push warns and still creates the worktree
+	reset()
+	if out, err := exec.Command("git", "remote", "set-url", "origin", filepath.Join(dir, "missing.git")).CombinedOutput(); err != nil {
+		t.Fatalf("git remote set-url: %v\n%s", err, out)
+	}
+	_, stderr, err := execute(t, "worktree", "add", "offline", "--push", "--config", filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("worktree add with a failing push failed: %v", err)
+	}
+	if !strings.Contains(stderr, "Could not push offline") {
+		t.Errorf("stderr = %q, want a push warning", stderr)
+	}
+}
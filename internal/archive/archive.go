@@ -0,0 +1,360 @@
+// Package archive keeps what removing a worktree would lose: the commits
+// not merged into the main branch as a git bundle, the uncommitted changes
+// to tracked files as a patch, and the untracked files as a tarball. Each
+// archive is a directory under Dir.
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/paths"
+)
+
+const (
+	metaFile      = "archive.json"
+	bundleFile    = "commits.bundle"
+	patchFile     = "changes.patch"
+	untrackedFile = "untracked.tar.gz"
+)
+
+// ErrNotFound is returned when no archive has the requested ID
+var ErrNotFound = errors.New("archive not found")
+
+// Archive describes an archived worktree
+type Archive struct {
+	ID string `json:"id"`
+	// Repo is the git common dir of the repository the worktree was in
+	Repo string `json:"repo"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Branch is empty for a worktree in detached HEAD
+	Branch string `json:"branch,omitempty"`
+	Base   string `json:"base"`
+	Head   string `json:"head"`
+	// Commits is the number of commits not in Base, kept in the bundle
+	Commits int `json:"commits"`
+	// Changes reports whether uncommitted changes to tracked files were
+	// kept
+	Changes   bool      `json:"changes"`
+	Untracked []string  `json:"untracked"`
+	Created   time.Time `json:"created"`
+
+	dir string
+}
+
+// Dir returns the directory holding the archives
+func Dir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(paths.DataDir(runtime.GOOS, os.Getenv, home), "archive")
+}
+
+// Empty reports whether the archive would keep nothing
+func (a *Archive) Empty() bool {
+	return a.Commits == 0 && !a.Changes && len(a.Untracked) == 0
+}
+
+// Location returns the directory of the archive
+func (a *Archive) Location() string {
+	return a.dir
+}
+
+// Inspect describes what archiving the worktree at wt.Path would keep,
+// counting commits against base, without writing anything
+func Inspect(ctx context.Context, wt git.Worktree, base string) (*Archive, error) {
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := git.ResolveCommit(ctx, wt.Head)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := git.CountCommits(ctx, base, head)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := git.UncommittedDiff(ctx, wt.Path)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git.UntrackedFiles(ctx, wt.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{
+		Repo:      commonDir,
+		Name:      filepath.Base(wt.Path),
+		Path:      wt.Path,
+		Branch:    wt.Branch,
+		Base:      base,
+		Head:      head,
+		Commits:   commits,
+		Changes:   diff != "",
+		Untracked: untracked,
+	}, nil
+}
+
+// Create archives the worktree a describes (see Inspect) into a new
+// directory under dir. Nothing is left behind if it fails.
+func Create(ctx context.Context, dir string, a *Archive, now time.Time) (err error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	a.Created = now.UTC()
+	a.ID = a.Name + "-" + now.Format("20060102-150405")
+	// Archives of the same name within a second get a counter
+	for n := 2; ; n++ {
+		a.dir = filepath.Join(dir, a.ID)
+		err := os.Mkdir(a.dir, 0o700)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		a.ID = fmt.Sprintf("%s-%s-%d", a.Name, now.Format("20060102-150405"), n)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(a.dir)
+		}
+	}()
+
+	if a.Commits > 0 {
+		if err := git.CreateBundle(ctx, a.Path, a.file(bundleFile), a.Base); err != nil {
+			return err
+		}
+	}
+	if a.Changes {
+		diff, err := git.UncommittedDiff(ctx, a.Path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(a.file(patchFile), []byte(diff), 0o600); err != nil {
+			return err
+		}
+	}
+	if len(a.Untracked) > 0 {
+		if err := writeTarball(a.file(untrackedFile), a.Path, a.Untracked); err != nil {
+			return fmt.Errorf("failed to archive untracked files: %w", err)
+		}
+	}
+
+	// The metadata goes last, so List never sees a half-written archive
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(a.file(metaFile), data, 0o600)
+}
+
+// List returns the archives in dir, newest first
+func List(dir string) ([]*Archive, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []*Archive{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	archives := []*Archive{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		a, err := load(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		archives = append(archives, a)
+	}
+	sort.SliceStable(archives, func(i, j int) bool {
+		return archives[i].Created.After(archives[j].Created)
+	})
+	return archives, nil
+}
+
+// Load returns the archive with the given ID, or ErrNotFound
+func Load(dir, id string) (*Archive, error) {
+	if !filepath.IsLocal(id) || filepath.Base(id) != id {
+		return nil, ErrNotFound
+	}
+	a, err := load(filepath.Join(dir, id))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return a, err
+}
+
+func load(dir string) (*Archive, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metaFile))
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{dir: dir}
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(dir, metaFile), err)
+	}
+	return a, nil
+}
+
+// FetchCommits brings the archived commits back into the repository, so
+// Head resolves again. It does nothing if no commits were archived.
+func (a *Archive) FetchCommits(ctx context.Context) error {
+	if a.Commits == 0 {
+		return nil
+	}
+	return git.FetchBundle(ctx, a.file(bundleFile))
+}
+
+// Restore applies the archived uncommitted changes and untracked files to
+// the worktree at path, which must be checked out at Head. Untracked files
+// that already exist are not overwritten.
+func (a *Archive) Restore(ctx context.Context, path string) error {
+	if a.Changes {
+		if err := git.ApplyPatch(ctx, path, a.file(patchFile)); err != nil {
+			return err
+		}
+	}
+	if len(a.Untracked) > 0 {
+		if err := extractTarball(a.file(untrackedFile), path); err != nil {
+			return fmt.Errorf("failed to restore untracked files: %w", err)
+		}
+	}
+	return nil
+}
+
+// Delete removes the archive
+func (a *Archive) Delete() error {
+	return os.RemoveAll(a.dir)
+}
+
+func (a *Archive) file(name string) string {
+	return filepath.Join(a.dir, name)
+}
+
+// writeTarball writes the files, relative to root, to a gzipped tarball
+func writeTarball(file, root string, files []string) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		var link string
+		switch {
+		case info.Mode().IsRegular():
+		case info.Mode()&os.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		default:
+			// git only tracks files and symlinks
+			continue
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			if err := copyFile(tw, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// extractTarball extracts a tarball written by writeTarball into root
+func extractTarball(file, root string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !filepath.IsLocal(filepath.FromSlash(hdr.Name)) {
+			return fmt.Errorf("refusing to extract %s outside the worktree", hdr.Name)
+		}
+		path := filepath.Join(root, filepath.FromSlash(hdr.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, path); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, hdr.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			if cerr := out.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
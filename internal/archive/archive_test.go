@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+)
+
+func run(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := filepath.Join(dir, "repo")
+	wtPath := filepath.Join(dir, "feature")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@test.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@test.com")
+
+	run(t, dir, "init", "-q", "-b", "main", repo)
+	write(t, filepath.Join(repo, "README"), "hello\n")
+	run(t, repo, "add", "README")
+	run(t, repo, "commit", "-q", "-m", "Initial commit")
+	run(t, repo, "worktree", "add", "-q", "-b", "feature", wtPath)
+	write(t, filepath.Join(wtPath, "feature.txt"), "feature\n")
+	run(t, wtPath, "add", "feature.txt")
+	run(t, wtPath, "commit", "-q", "-m", "Add feature")
+	write(t, filepath.Join(wtPath, "README"), "changed\n")
+	write(t, filepath.Join(wtPath, "notes", "todo.txt"), "todo\n")
+	t.Chdir(repo)
+
+	a, err := Inspect(t.Context(), git.Worktree{Path: wtPath, Head: "feature", Branch: "feature"}, "main")
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if a.Commits != 1 || !a.Changes || len(a.Untracked) != 1 || a.Untracked[0] != "notes/todo.txt" {
+		t.Fatalf("Inspect = %+v", a)
+	}
+
+	store := filepath.Join(dir, "archive")
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := Create(t.Context(), store, a, now); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	again := *a
+	if err := Create(t.Context(), store, &again, now); err != nil || again.ID == a.ID {
+		t.Fatalf("second Create in the same second: id %s, %v", again.ID, err)
+	}
+
+	// Lose everything the archive kept
+	run(t, repo, "worktree", "remove", "--force", wtPath)
+	run(t, repo, "branch", "-D", "feature")
+
+	archives, err := List(store)
+	if err != nil || len(archives) != 2 {
+		t.Fatalf("List = %v, %v", archives, err)
+	}
+	if _, err := Load(store, "../"+a.ID); err != ErrNotFound {
+		t.Errorf("Load outside the store: err = %v, want ErrNotFound", err)
+	}
+	loaded, err := Load(store, a.ID)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := loaded.FetchCommits(t.Context()); err != nil {
+		t.Fatalf("FetchCommits failed: %v", err)
+	}
+	run(t, repo, "worktree", "add", "-q", "-b", "feature", wtPath, loaded.Head)
+	if err := loaded.Restore(t.Context(), wtPath); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	for path, want := range map[string]string{
+		"feature.txt":    "feature\n",
+		"README":         "changed\n",
+		"notes/todo.txt": "todo\n",
+	} {
+		if data, err := os.ReadFile(filepath.Join(wtPath, path)); err != nil || string(data) != want {
+			t.Errorf("%s = %q (%v), want %q", path, data, err, want)
+		}
+	}
+
+	if err := loaded.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if archives, _ := List(store); len(archives) != 1 {
+		t.Errorf("List after Delete = %d archives, want 1", len(archives))
+	}
+}
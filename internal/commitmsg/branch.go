@@ -0,0 +1,59 @@
+package commitmsg
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// maxBranchName bounds the length of a suggested branch name
+const maxBranchName = 40
+
+const branchPrompt = `You name git branches for work in progress. Reply with exactly two parts
+and nothing else:
+
+- First line: a branch name of 2 to 5 lowercase words joined by hyphens,
+  such as fix-login-redirect, describing what the changes are for
+- Then a blank line and a one-sentence summary of the changes`
+
+// BranchMessages returns the prompt for naming a branch after the
+// uncommitted diff and the untracked files next to it
+func BranchMessages(diff string, untracked []string) []types.Message {
+	var b strings.Builder
+	b.WriteString("Suggest a branch name and summary for these uncommitted changes.\n\n")
+	if len(untracked) > 0 {
+		b.WriteString("New files:\n")
+		for _, f := range untracked {
+			b.WriteString("- " + f + "\n")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("Diff:\n" + truncate(diff))
+
+	return []types.Message{
+		{Role: "system", Content: branchPrompt},
+		{Role: "user", Content: b.String()},
+	}
+}
+
+var nonBranch = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ParseBranchSuggestion splits a reply to BranchMessages into a branch
+// name, made safe for a directory and a ref, and its summary. The name is
+// empty if the reply has none.
+func ParseBranchSuggestion(content string) (name, summary string) {
+	content = Clean(content)
+	first, rest, _ := strings.Cut(content, "\n")
+
+	name = strings.Trim(nonBranch.ReplaceAllString(strings.ToLower(first), "-"), "-")
+	if len(name) > maxBranchName {
+		// Cut at a word boundary unless the name is one long word
+		cut := name[:maxBranchName]
+		if i := strings.LastIndex(cut, "-"); i > maxBranchName/2 {
+			cut = cut[:i]
+		}
+		name = strings.TrimRight(cut, "-")
+	}
+	return name, strings.Join(strings.Fields(rest), " ")
+}
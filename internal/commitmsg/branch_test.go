@@ -0,0 +1,20 @@
+package commitmsg
+
+import "testing"
+
+func TestParseBranchSuggestion(t *testing.T) {
+	tests := []struct {
+		in, name, summary string
+	}{
+		{"fix-login-redirect\n\nSends users back to the page they came from.", "fix-login-redirect", "Sends users back to the page they came from."},
+		{"```\nBranch: Add OAuth_Login\n\nAdds Google sign-in\nto the login page.\n```", "branch-add-oauth-login", "Adds Google sign-in to the login page."},
+		{"refactor-the-session-storage-layer-into-its-own-package", "refactor-the-session-storage-layer-into", ""},
+		{"\n", "", ""},
+	}
+	for _, tt := range tests {
+		name, summary := ParseBranchSuggestion(tt.in)
+		if name != tt.name || summary != tt.summary {
+			t.Errorf("ParseBranchSuggestion(%q) = %q, %q; want %q, %q", tt.in, name, summary, tt.name, tt.summary)
+		}
+	}
+}
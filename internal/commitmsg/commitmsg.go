@@ -0,0 +1,230 @@
+// Package commitmsg builds the prompts used to generate commit messages
+// and change summaries with an AI provider, and cleans up what comes back.
+package commitmsg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// MaxDiffBytes bounds the diff sent to the provider; larger diffs are cut
+const MaxDiffBytes = 60000
+
+// DefaultMaxSubject is the subject length asked for when a Style doesn't
+// set one
+const DefaultMaxSubject = 72
+
+const systemPrompt = `You write git commit messages. Reply with the commit message only,
+without quotes or code fences.
+
+- Subject line: imperative mood, at most %d characters, no trailing period
+- Leave a blank line after the subject, then explain what changed and why
+  in a short body wrapped at 72 characters; omit the body for trivial changes
+- Describe the intent of the change, not a file-by-file list`
+
+const conventionalRule = `
+- The subject follows Conventional Commits: type(scope): description, where
+  type is one of feat, fix, docs, style, refactor, perf, test, build, ci,
+  chore or revert`
+
+// Style is how generated commit messages are written
+type Style struct {
+	Conventional bool
+	// Language is a language name or tag such as "es" or "pt-BR"; empty
+	// means English
+	Language string
+	// Tone is a free-form description such as "formal" or "concise"
+	Tone string
+	// MaxSubject bounds the subject line; zero means DefaultMaxSubject
+	MaxSubject int
+	// Emoji starts the subject with a gitmoji
+	Emoji bool
+	// Lint are commitlint rules the message must pass
+	Lint LintRules
+	// Examples are recent messages of the repository whose conventions
+	// the message follows, picked with Examples
+	Examples []string
+}
+
+// SubjectLimit returns the maximum subject length
+func (s Style) SubjectLimit() int {
+	if s.MaxSubject > 0 {
+		return s.MaxSubject
+	}
+	return DefaultMaxSubject
+}
+
+// Messages returns the prompt for a message describing the staged diff
+func Messages(diff string, style Style) []types.Message {
+	return []types.Message{
+		{Role: "system", Content: system(style)},
+		{Role: "user", Content: withExamples("Write a commit message for this diff:\n\n"+truncate(diff), style)},
+	}
+}
+
+// AmendMessages returns the prompt for updating the message of a commit
+// that is being amended with the newly staged diff, which may be empty
+func AmendMessages(previous, diff string, style Style) []types.Message {
+	var b strings.Builder
+	b.WriteString("Update this commit message so it also covers the changes below. ")
+	b.WriteString("Keep what is still accurate and the style of the original.\n\n")
+	b.WriteString("Current message:\n\n")
+	b.WriteString(previous)
+	if strings.TrimSpace(diff) == "" {
+		b.WriteString("\n\nNo changes are being added; improve the wording only.")
+	} else {
+		b.WriteString("\n\nChanges being added to the commit:\n\n")
+		b.WriteString(truncate(diff))
+	}
+
+	return []types.Message{
+		{Role: "system", Content: system(style)},
+		{Role: "user", Content: withExamples(b.String(), style)},
+	}
+}
+
+// MergeMessages returns the prompt for the message of the commit merging
+// branch into base, from the subjects of the branch's commits and its
+// diffstat. Conventional Commits are not asked for, as merge commits
+// rarely follow them.
+func MergeMessages(branch, base string, subjects []string, diffstat string, style Style) []types.Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Write the message for the commit merging branch '%s' into %s. ", branch, base)
+	b.WriteString("The subject summarizes what the branch delivers as a whole; the body ")
+	b.WriteString("may list the notable changes. Do not start with \"Merge branch\".\n\n")
+	b.WriteString("Commits on the branch:\n")
+	for _, s := range subjects {
+		b.WriteString("- " + s + "\n")
+	}
+	if diffstat != "" {
+		b.WriteString("\nFiles changed:\n" + truncate(diffstat))
+	}
+
+	style.Conventional = false
+	return []types.Message{
+		{Role: "system", Content: system(style)},
+		{Role: "user", Content: withExamples(b.String(), style)},
+	}
+}
+
+const summaryPrompt = `You summarize the differences between two git branches for a developer
+deciding what to do with them. Reply in a few short bullet points of plain
+text: what the changes do, anything risky or unfinished, and how they
+relate. No preamble.`
+
+// SummaryMessages returns the prompt for summarizing what head changes
+// relative to base, from its diffstat and patch
+func SummaryMessages(base, head, diffstat, diff string) []types.Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Summarize what %s changes compared to %s.\n\n", head, base)
+	b.WriteString("Files changed:\n" + diffstat + "\n\n")
+	b.WriteString("Diff:\n" + truncate(diff))
+
+	return []types.Message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: b.String()},
+	}
+}
+
+func system(style Style) string {
+	prompt := fmt.Sprintf(systemPrompt, style.SubjectLimit())
+	if style.Conventional {
+		prompt += conventionalRule
+	}
+	if style.Emoji {
+		prompt += gitmojiRule(style.Conventional)
+	}
+	if style.Language != "" {
+		prompt += fmt.Sprintf("\n- Write the subject and body in %s; keep code identifiers as they are", style.Language)
+		if style.Conventional {
+			prompt += " and the\n  Conventional Commits type in English"
+		}
+	}
+	if style.Tone != "" {
+		prompt += fmt.Sprintf("\n- Tone: %s", style.Tone)
+	}
+	if rules := style.Lint.Describe(); rules != "" {
+		prompt += "\n- The message must pass these commitlint rules:\n" + rules
+	}
+	if len(style.Examples) > 0 {
+		prompt += examplesRule
+	}
+	return prompt
+}
+
+func truncate(diff string) string {
+	if len(diff) <= MaxDiffBytes {
+		return diff
+	}
+	return diff[:MaxDiffBytes] + "\n[diff truncated]"
+}
+
+var fence = regexp.MustCompile("^```[a-z]*\n?|\n?```$")
+
+// Clean strips the quotes and code fences models sometimes wrap a message in
+func Clean(content string) string {
+	content = strings.TrimSpace(content)
+	content = strings.TrimSpace(fence.ReplaceAllString(content, ""))
+	if len(content) > 1 && content[0] == '"' && content[len(content)-1] == '"' {
+		content = strings.TrimSpace(content[1 : len(content)-1])
+	}
+	return content
+}
+
+// Clean cleans up a generated message like Clean, and normalizes its
+// gitmoji when the style asks for one
+func (s Style) Clean(content string) string {
+	content = Clean(content)
+	if s.Emoji {
+		content = NormalizeGitmoji(content)
+	}
+	return content
+}
+
+var trailerLine = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*: \S`)
+
+// SplitTrailers splits a message into its text and the trailers in its
+// last paragraph, such as "Signed-off-by: Ann <ann@example.com>". A
+// paragraph only counts as trailers if every line is one.
+func SplitTrailers(message string) (text string, trailers []string) {
+	message = strings.TrimSpace(message)
+	i := strings.LastIndex(message, "\n\n")
+	if i < 0 {
+		return message, nil
+	}
+
+	last := strings.Split(message[i+2:], "\n")
+	for _, line := range last {
+		if !trailerLine.MatchString(line) {
+			return message, nil
+		}
+	}
+	return strings.TrimSpace(message[:i]), last
+}
+
+// AddTrailers appends the trailers missing from message as its last
+// paragraph
+func AddTrailers(message string, trailers []string) string {
+	text, existing := SplitTrailers(message)
+	for _, t := range trailers {
+		if !containsFold(existing, t) {
+			existing = append(existing, t)
+		}
+	}
+	if len(existing) == 0 {
+		return text
+	}
+	return text + "\n\n" + strings.Join(existing, "\n")
+}
+
+func containsFold(items []string, item string) bool {
+	for _, i := range items {
+		if strings.EqualFold(i, item) {
+			return true
+		}
+	}
+	return false
+}
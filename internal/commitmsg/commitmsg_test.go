@@ -0,0 +1,125 @@
+package commitmsg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Add login\n", "Add login"},
+		{"```\nAdd login\n\nBody\n```", "Add login\n\nBody"},
+		{"```text\nAdd login\n```", "Add login"},
+		{`"Add login"`, "Add login"},
+	}
+	for _, tt := range tests {
+		if got := Clean(tt.in); got != tt.want {
+			t.Errorf("Clean(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitTrailers(t *testing.T) {
+	tests := []struct {
+		message  string
+		text     string
+		trailers []string
+	}{
+		{"Add login", "Add login", nil},
+		{"Add login\n\nSome body", "Add login\n\nSome body", nil},
+		{
+			"Add login\n\nBody\n\nSigned-off-by: Ann <ann@example.com>\nCo-authored-by: Bo <bo@example.com>",
+			"Add login\n\nBody",
+			[]string{"Signed-off-by: Ann <ann@example.com>", "Co-authored-by: Bo <bo@example.com>"},
+		},
+		{"Add login\n\nNote: this is prose\nand keeps going", "Add login\n\nNote: this is prose\nand keeps going", nil},
+	}
+	for _, tt := range tests {
+		text, trailers := SplitTrailers(tt.message)
+		if text != tt.text || strings.Join(trailers, "|") != strings.Join(tt.trailers, "|") {
+			t.Errorf("SplitTrailers(%q) = %q, %q", tt.message, text, trailers)
+		}
+	}
+}
+
+func TestAddTrailers(t *testing.T) {
+	trailers := []string{"Signed-off-by: Ann <ann@example.com>", "Co-authored-by: Bo <bo@example.com>"}
+
+	got := AddTrailers("Add login\n\nBody", trailers)
+	want := "Add login\n\nBody\n\nSigned-off-by: Ann <ann@example.com>\nCo-authored-by: Bo <bo@example.com>"
+	if got != want {
+		t.Errorf("AddTrailers = %q, want %q", got, want)
+	}
+
+	// Trailers the model kept are not repeated
+	got = AddTrailers("Add login\n\nsigned-off-by: Ann <ann@example.com>", trailers)
+	want = "Add login\n\nsigned-off-by: Ann <ann@example.com>\nCo-authored-by: Bo <bo@example.com>"
+	if got != want {
+		t.Errorf("AddTrailers = %q, want %q", got, want)
+	}
+}
+
+func TestAmendMessages(t *testing.T) {
+	msgs := AmendMessages("Add login", "", Style{Conventional: true})
+	if len(msgs) != 2 || !strings.Contains(msgs[0].Content, "Conventional Commits") {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	if !strings.Contains(msgs[1].Content, "Add login") || !strings.Contains(msgs[1].Content, "wording only") {
+		t.Errorf("unexpected prompt: %q", msgs[1].Content)
+	}
+}
+
+func TestMergeMessages(t *testing.T) {
+	msgs := MergeMessages("feature-auth", "main", []string{"Add login", "Add logout"}, "2 files changed", Style{})
+	prompt := msgs[len(msgs)-1].Content
+	for _, want := range []string{"'feature-auth' into main", "- Add login\n- Add logout", "2 files changed"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestStyle(t *testing.T) {
+	system := Messages("diff", Style{})[0].Content
+	if !strings.Contains(system, "at most 72 characters") || strings.Contains(system, "Tone") {
+		t.Errorf("default system prompt:\n%s", system)
+	}
+
+	system = Messages("diff", Style{Language: "pt-BR", Tone: "formal", MaxSubject: 50})[0].Content
+	for _, want := range []string{"at most 50 characters", "in pt-BR", "Tone: formal"} {
+		if !strings.Contains(system, want) {
+			t.Errorf("system prompt missing %q:\n%s", want, system)
+		}
+	}
+
+	merge := MergeMessages("b", "main", []string{"x"}, "", Style{Conventional: true, Language: "es"})[0].Content
+	if strings.Contains(merge, "Conventional Commits") || !strings.Contains(merge, "in es") {
+		t.Errorf("merge system prompt:\n%s", merge)
+	}
+}
+
+func TestNormalizeGitmoji(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"✨ Add login\n\nBody", "✨ Add login\n\nBody"},
+		{":bug: Fix redirect", "🐛 Fix redirect"},
+		{"\u26a1 Speed up status", "⚡️ Speed up status"},
+		{"🦄 Add unicorns", "Add unicorns"},
+		{"Add login", "Add login"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeGitmoji(tt.in); got != tt.want {
+			t.Errorf("NormalizeGitmoji(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if g, rest, ok := SplitGitmoji("♻️ refactor: split parser"); !ok || g.Code != ":recycle:" || rest != "refactor: split parser" {
+		t.Errorf("SplitGitmoji = %+v, %q, %v", g, rest, ok)
+	}
+	if !strings.Contains(Messages("diff", Style{Emoji: true})[0].Content, "🐛 Fix a bug") {
+		t.Error("system prompt does not list the gitmojis")
+	}
+}
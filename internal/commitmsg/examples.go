@@ -0,0 +1,66 @@
+package commitmsg
+
+import "strings"
+
+// maxExampleLines bounds the lines kept of each example message
+const maxExampleLines = 6
+
+// examplePrefixes start the subjects of commits that don't show how the
+// repository's messages are written
+var examplePrefixes = []string{"Merge ", "fixup! ", "squash! ", "amend! ", "Revert \""}
+
+// Examples picks up to n of messages, newest first, to show the model as
+// examples of the repository's style. Merges, fixups, reverts and repeated
+// subjects are skipped, and long bodies are cut.
+func Examples(messages []string, n int) []string {
+	seen := map[string]bool{}
+	var examples []string
+	for _, m := range messages {
+		if len(examples) == n {
+			break
+		}
+		m = strings.TrimSpace(m)
+		subject, _, _ := strings.Cut(m, "\n")
+		if subject == "" || seen[subject] || isGenerated(subject) {
+			continue
+		}
+		seen[subject] = true
+		if lines := strings.Split(m, "\n"); len(lines) > maxExampleLines {
+			m = strings.Join(lines[:maxExampleLines], "\n") + "\n[...]"
+		}
+		examples = append(examples, m)
+	}
+	return examples
+}
+
+func isGenerated(subject string) bool {
+	for _, p := range examplePrefixes {
+		if strings.HasPrefix(subject, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// examplesRule asks the model to follow the conventions of the examples,
+// which are sent with the user's message
+const examplesRule = `
+- Follow the conventions of the example commits of the repository: tense,
+  capitalization, scope names and ticket prefixes. The rules above win
+  where they disagree. Don't copy their content.`
+
+// withExamples puts the examples of style before text. They go in the
+// user's message rather than the system prompt so secrets in them are
+// redacted.
+func withExamples(text string, style Style) string {
+	if len(style.Examples) == 0 {
+		return text
+	}
+	var b strings.Builder
+	b.WriteString("Example commits of the repository, newest first:\n\n")
+	for _, e := range style.Examples {
+		b.WriteString("---\n" + e + "\n")
+	}
+	b.WriteString("---\n\n" + text)
+	return b.String()
+}
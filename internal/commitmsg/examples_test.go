@@ -0,0 +1,37 @@
+package commitmsg
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExamples(t *testing.T) {
+	messages := []string{
+		"Merge branch 'auth'",
+		"PROJ-12: add login form\n\nUses the session API.",
+		"fixup! PROJ-12: add login form",
+		"PROJ-12: add login form",
+		"PROJ-9: drop legacy tokens\n\n" + strings.Repeat("line\n", 10),
+		"PROJ-7: bump deps",
+	}
+	got := Examples(messages, 2)
+	want := []string{
+		"PROJ-12: add login form\n\nUses the session API.",
+		"PROJ-9: drop legacy tokens\n\nline\nline\nline\nline\n[...]",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Examples = %q, want %q", got, want)
+	}
+
+	msgs := Messages("diff", Style{Examples: got})
+	if !strings.Contains(msgs[0].Content, "conventions of the example commits") {
+		t.Errorf("system prompt misses the examples rule:\n%s", msgs[0].Content)
+	}
+	if user := msgs[1].Content; !strings.HasPrefix(user, "Example commits") || !strings.Contains(user, "---\nPROJ-9: drop legacy tokens") || !strings.HasSuffix(user, "diff") {
+		t.Errorf("user prompt:\n%s", user)
+	}
+	if strings.Contains(Messages("diff", Style{})[0].Content, "example commits") {
+		t.Error("examples rule without examples")
+	}
+}
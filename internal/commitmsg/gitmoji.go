@@ -0,0 +1,165 @@
+package commitmsg
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Gitmoji is an entry of the gitmoji list (https://gitmoji.dev)
+type Gitmoji struct {
+	Emoji       string
+	Code        string
+	Description string
+}
+
+// Gitmojis is the official gitmoji list
+var Gitmojis = []Gitmoji{
+	{"🎨", ":art:", "Improve structure / format of the code"},
+	{"⚡️", ":zap:", "Improve performance"},
+	{"🔥", ":fire:", "Remove code or files"},
+	{"🐛", ":bug:", "Fix a bug"},
+	{"🚑️", ":ambulance:", "Critical hotfix"},
+	{"✨", ":sparkles:", "Introduce new features"},
+	{"📝", ":memo:", "Add or update documentation"},
+	{"🚀", ":rocket:", "Deploy stuff"},
+	{"💄", ":lipstick:", "Add or update the UI and style files"},
+	{"🎉", ":tada:", "Begin a project"},
+	{"✅", ":white_check_mark:", "Add, update, or pass tests"},
+	{"🔒️", ":lock:", "Fix security or privacy issues"},
+	{"🔐", ":closed_lock_with_key:", "Add or update secrets"},
+	{"🔖", ":bookmark:", "Release / Version tags"},
+	{"🚨", ":rotating_light:", "Fix compiler / linter warnings"},
+	{"🚧", ":construction:", "Work in progress"},
+	{"💚", ":green_heart:", "Fix CI Build"},
+	{"⬇️", ":arrow_down:", "Downgrade dependencies"},
+	{"⬆️", ":arrow_up:", "Upgrade dependencies"},
+	{"📌", ":pushpin:", "Pin dependencies to specific versions"},
+	{"👷", ":construction_worker:", "Add or update CI build system"},
+	{"📈", ":chart_with_upwards_trend:", "Add or update analytics or track code"},
+	{"♻️", ":recycle:", "Refactor code"},
+	{"➕", ":heavy_plus_sign:", "Add a dependency"},
+	{"➖", ":heavy_minus_sign:", "Remove a dependency"},
+	{"🔧", ":wrench:", "Add or update configuration files"},
+	{"🔨", ":hammer:", "Add or update development scripts"},
+	{"🌐", ":globe_with_meridians:", "Internationalization and localization"},
+	{"✏️", ":pencil2:", "Fix typos"},
+	{"💩", ":poop:", "Write bad code that needs to be improved"},
+	{"⏪️", ":rewind:", "Revert changes"},
+	{"🔀", ":twisted_rightwards_arrows:", "Merge branches"},
+	{"📦️", ":package:", "Add or update compiled files or packages"},
+	{"👽️", ":alien:", "Update code due to external API changes"},
+	{"🚚", ":truck:", "Move or rename resources (e.g.: files, paths, routes)"},
+	{"📄", ":page_facing_up:", "Add or update license"},
+	{"💥", ":boom:", "Introduce breaking changes"},
+	{"🍱", ":bento:", "Add or update assets"},
+	{"♿️", ":wheelchair:", "Improve accessibility"},
+	{"💡", ":bulb:", "Add or update comments in source code"},
+	{"🍻", ":beers:", "Write code drunkenly"},
+	{"💬", ":speech_balloon:", "Add or update text and literals"},
+	{"🗃️", ":card_file_box:", "Perform database related changes"},
+	{"🔊", ":loud_sound:", "Add or update logs"},
+	{"🔇", ":mute:", "Remove logs"},
+	{"👥", ":busts_in_silhouette:", "Add or update contributor(s)"},
+	{"🚸", ":children_crossing:", "Improve user experience / usability"},
+	{"🏗️", ":building_construction:", "Make architectural changes"},
+	{"📱", ":iphone:", "Work on responsive design"},
+	{"🤡", ":clown_face:", "Mock things"},
+	{"🥚", ":egg:", "Add or update an easter egg"},
+	{"🙈", ":see_no_evil:", "Add or update a .gitignore file"},
+	{"📸", ":camera_flash:", "Add or update snapshots"},
+	{"⚗️", ":alembic:", "Perform experiments"},
+	{"🔍️", ":mag:", "Improve SEO"},
+	{"🏷️", ":label:", "Add or update types"},
+	{"🌱", ":seedling:", "Add or update seed files"},
+	{"🚩", ":triangular_flag_on_post:", "Add, update, or remove feature flags"},
+	{"🥅", ":goal_net:", "Catch errors"},
+	{"💫", ":dizzy:", "Add or update animations and transitions"},
+	{"🗑️", ":wastebasket:", "Deprecate code that needs to be cleaned up"},
+	{"🛂", ":passport_control:", "Work on code related to authorization, roles and permissions"},
+	{"🩹", ":adhesive_bandage:", "Simple fix for a non-critical issue"},
+	{"🧐", ":monocle_face:", "Data exploration/inspection"},
+	{"⚰️", ":coffin:", "Remove dead code"},
+	{"🧪", ":test_tube:", "Add a failing test"},
+	{"👔", ":necktie:", "Add or update business logic"},
+	{"🩺", ":stethoscope:", "Add or update healthcheck"},
+	{"🧱", ":bricks:", "Infrastructure related changes"},
+	{"🧑‍💻", ":technologist:", "Improve developer experience"},
+	{"💸", ":money_with_wings:", "Add sponsorships or money related infrastructure"},
+	{"🧵", ":thread:", "Add or update code related to multithreading or concurrency"},
+	{"🦺", ":safety_vest:", "Add or update code related to validation"},
+	{"✈️", ":airplane:", "Improve offline support"},
+}
+
+// LookupGitmoji finds a gitmoji by its emoji, with or without the emoji
+// variation selector, or by its :code:
+func LookupGitmoji(s string) (Gitmoji, bool) {
+	bare := stripVariation(s)
+	for _, g := range Gitmojis {
+		if bare == stripVariation(g.Emoji) || s == g.Code {
+			return g, true
+		}
+	}
+	return Gitmoji{}, false
+}
+
+// SplitGitmoji splits the gitmoji a subject starts with from the rest
+func SplitGitmoji(subject string) (Gitmoji, string, bool) {
+	first, rest, _ := strings.Cut(strings.TrimSpace(subject), " ")
+	g, ok := LookupGitmoji(first)
+	if !ok {
+		return Gitmoji{}, subject, false
+	}
+	return g, strings.TrimSpace(rest), true
+}
+
+// NormalizeGitmoji writes the gitmoji a message starts with as its
+// official emoji, turning a :code: into the emoji, and drops a leading
+// emoji that is not on the list
+func NormalizeGitmoji(message string) string {
+	subject, body, hasBody := strings.Cut(message, "\n")
+	if g, rest, ok := SplitGitmoji(subject); ok {
+		subject = g.Emoji + " " + rest
+	} else if first, rest, _ := strings.Cut(strings.TrimSpace(subject), " "); isEmoji(first) {
+		subject = strings.TrimSpace(rest)
+	}
+	if hasBody {
+		return subject + "\n" + body
+	}
+	return subject
+}
+
+// stripVariation removes the emoji variation selector, which models often
+// leave out
+func stripVariation(s string) string {
+	return strings.ReplaceAll(s, "\uFE0F", "")
+}
+
+// isEmoji returns true if s is made only of symbols and emoji joiners
+func isEmoji(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x80 {
+			return false
+		}
+		if !unicode.Is(unicode.So, r) && r != '\uFE0F' && r != '\u200D' && !unicode.Is(unicode.Sk, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// gitmojiRule lists the gitmojis to choose from in the system prompt
+func gitmojiRule(conventional bool) string {
+	var b strings.Builder
+	b.WriteString("\n- Start the subject with the one gitmoji from this list that best fits")
+	if conventional {
+		b.WriteString(",\n  followed by a space and the Conventional Commits type")
+	}
+	b.WriteString(":\n")
+	for _, g := range Gitmojis {
+		b.WriteString("  " + g.Emoji + " " + g.Description + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
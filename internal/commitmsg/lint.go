@@ -0,0 +1,546 @@
+package commitmsg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Lint levels, as in commitlint
+const (
+	LintOff     = 0
+	LintWarning = 1
+	LintError   = 2
+)
+
+// LintRule is a commitlint rule: a level, whether its condition must hold
+// "always" or "never", and the rule's value, such as a length or a list
+// of types
+type LintRule struct {
+	Level int
+	When  string
+	Value interface{}
+}
+
+// LintRules are commitlint rules keyed by name, such as
+// "header-max-length"
+type LintRules map[string]LintRule
+
+// LintProblem is a rule a message breaks
+type LintProblem struct {
+	Rule    string
+	Level   int
+	Message string
+}
+
+func (p LintProblem) String() string {
+	return fmt.Sprintf("%s [%s]", p.Message, p.Rule)
+}
+
+// ConventionalLintRules are the rules of @commitlint/config-conventional
+var ConventionalLintRules = LintRules{
+	"body-leading-blank":     {LintWarning, "always", nil},
+	"body-max-line-length":   {LintError, "always", 100.0},
+	"footer-leading-blank":   {LintWarning, "always", nil},
+	"footer-max-line-length": {LintError, "always", 100.0},
+	"header-max-length":      {LintError, "always", 100.0},
+	"header-trim":            {LintError, "always", nil},
+	"subject-case":           {LintError, "never", []interface{}{"sentence-case", "start-case", "pascal-case", "upper-case"}},
+	"subject-empty":          {LintError, "never", nil},
+	"subject-full-stop":      {LintError, "never", "."},
+	"type-case":              {LintError, "always", "lower-case"},
+	"type-empty":             {LintError, "never", nil},
+	"type-enum": {LintError, "always", []interface{}{
+		"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test",
+	}},
+}
+
+// ParseLintRule reads a rule in commitlint's array form, such as
+// [2, "always", 72]
+func ParseLintRule(v []interface{}) (LintRule, error) {
+	if len(v) == 0 {
+		return LintRule{}, fmt.Errorf("rule is empty")
+	}
+	level, ok := v[0].(float64)
+	if !ok || level < LintOff || level > LintError || level != float64(int(level)) {
+		return LintRule{}, fmt.Errorf("level must be 0, 1 or 2")
+	}
+	r := LintRule{Level: int(level), When: "always"}
+	if len(v) > 1 {
+		when, ok := v[1].(string)
+		if !ok || (when != "always" && when != "never") {
+			return LintRule{}, fmt.Errorf("second item must be \"always\" or \"never\"")
+		}
+		r.When = when
+	}
+	if len(v) > 2 {
+		r.Value = v[2]
+	}
+	return r, nil
+}
+
+// ParseLintRules reads rules in commitlint's array form
+func ParseLintRules(rules map[string][]interface{}) (LintRules, error) {
+	parsed := LintRules{}
+	for name, v := range rules {
+		r, err := ParseLintRule(v)
+		if err != nil {
+			return nil, fmt.Errorf("commitlint rule %s: %w", name, err)
+		}
+		parsed[name] = r
+	}
+	return parsed, nil
+}
+
+// Overlay returns r with the rules of other replacing those of the same
+// name
+func (r LintRules) Overlay(other LintRules) LintRules {
+	merged := LintRules{}
+	for name, rule := range r {
+		merged[name] = rule
+	}
+	for name, rule := range other {
+		merged[name] = rule
+	}
+	return merged
+}
+
+// commitlintFiles are the commitlint config files read, in lookup order
+var commitlintFiles = []string{
+	".commitlintrc", ".commitlintrc.json", ".commitlintrc.yaml", ".commitlintrc.yml",
+	".commitlintrc.js", ".commitlintrc.cjs", ".commitlintrc.mjs", ".commitlintrc.ts",
+	"commitlint.config.js", "commitlint.config.cjs", "commitlint.config.mjs", "commitlint.config.ts",
+}
+
+// commitlintConfig is the part of a commitlint config that is read
+type commitlintConfig struct {
+	Extends interface{}              `json:"extends"`
+	Rules   map[string][]interface{} `json:"rules"`
+}
+
+// LoadCommitlint reads the commitlint config in root, returning its rules
+// and path, or nil rules if there is none. Only @commitlint/config-
+// conventional is understood in extends, and JavaScript configs are read
+// on a best-effort basis: their rules object must be a plain literal.
+func LoadCommitlint(root string) (LintRules, string, error) {
+	for _, name := range commitlintFiles {
+		path := filepath.Join(root, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, path, err
+		}
+
+		var cfg commitlintConfig
+		switch ext := filepath.Ext(name); {
+		case ext == ".json" || (name == ".commitlintrc" && strings.HasPrefix(strings.TrimSpace(string(data)), "{")):
+			err = json.Unmarshal(data, &cfg)
+		case ext == ".yaml" || ext == ".yml" || name == ".commitlintrc":
+			err = yamlToJSON(data, &cfg)
+		default:
+			err = readJSConfig(string(data), &cfg)
+		}
+		if err != nil {
+			return nil, path, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		rules := LintRules{}
+		if strings.Contains(fmt.Sprint(cfg.Extends), "config-conventional") {
+			rules = rules.Overlay(ConventionalLintRules)
+		}
+		own, err := ParseLintRules(cfg.Rules)
+		if err != nil {
+			return nil, path, fmt.Errorf("%s: %w", name, err)
+		}
+		return rules.Overlay(own), path, nil
+	}
+	return nil, "", nil
+}
+
+// yamlToJSON decodes YAML through JSON, so numbers come out as float64
+// like they do in JSON configs
+func yamlToJSON(data []byte, v interface{}) error {
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	jsonData, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+var jsRulesStart = regexp.MustCompile(`\brules\s*:\s*\{`)
+
+// readJSConfig pulls extends and rules out of a JavaScript config without
+// running it
+func readJSConfig(src string, v *commitlintConfig) error {
+	src = stripJSComments(src)
+	if strings.Contains(src, "config-conventional") {
+		v.Extends = "@commitlint/config-conventional"
+	}
+	loc := jsRulesStart.FindStringIndex(src)
+	if loc == nil {
+		return nil
+	}
+	start := loc[1] - 1
+	depth := 0
+	for i := start; i < len(src); i++ {
+		switch src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return json.Unmarshal([]byte(jsObjectToJSON(src[start:i+1])), &v.Rules)
+			}
+		}
+	}
+	return fmt.Errorf("unterminated rules object")
+}
+
+func stripJSComments(src string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				b.WriteByte(src[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+			b.WriteByte(c)
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			b.WriteByte('\n')
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+var (
+	jsBareKey       = regexp.MustCompile(`([{,]\s*)([A-Za-z_$][\w$-]*)\s*:`)
+	jsTrailingComma = regexp.MustCompile(`,(\s*[}\]])`)
+	jsSeverity      = strings.NewReplacer("RuleConfigSeverity.Disabled", "0", "RuleConfigSeverity.Warning", "1", "RuleConfigSeverity.Error", "2")
+)
+
+// jsObjectToJSON turns a plain JavaScript object literal into JSON:
+// single-quoted strings and bare keys are quoted and trailing commas
+// dropped
+func jsObjectToJSON(src string) string {
+	var b strings.Builder
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if c != '\'' && c != '`' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('"')
+		for i++; i < len(src) && src[i] != c; i++ {
+			switch {
+			case src[i] == '\\' && i+1 < len(src):
+				i++
+				if src[i] != c {
+					b.WriteByte('\\')
+				}
+				b.WriteByte(src[i])
+			case src[i] == '"':
+				b.WriteString(`\"`)
+			default:
+				b.WriteByte(src[i])
+			}
+		}
+		b.WriteByte('"')
+	}
+	out := jsSeverity.Replace(b.String())
+	out = jsBareKey.ReplaceAllString(out, `$1"$2":`)
+	return jsTrailingComma.ReplaceAllString(out, "$1")
+}
+
+// parsedMessage is a commit message split the way commitlint's
+// conventional parser splits it
+type parsedMessage struct {
+	header, typ, scope, subject string
+	body, footer                []string
+	bodyBlank, footerBlank      bool
+}
+
+var (
+	conventionalHeader = regexp.MustCompile(`^(\w*)(?:\(([^()]*)\))?!?: (.*)$`)
+	footerStart        = regexp.MustCompile(`^(BREAKING[ -]CHANGE|[\w-]+)(: | #)`)
+)
+
+func parseMessage(message string, emoji bool) parsedMessage {
+	lines := strings.Split(strings.TrimRight(message, "\n"), "\n")
+	p := parsedMessage{header: lines[0]}
+
+	header := p.header
+	if emoji {
+		_, header, _ = SplitGitmoji(header)
+	}
+	if m := conventionalHeader.FindStringSubmatch(header); m != nil {
+		p.typ, p.scope, p.subject = m[1], m[2], m[3]
+	}
+
+	rest := lines[1:]
+	for i, line := range rest {
+		if footerStart.MatchString(line) && (i == 0 || rest[i-1] == "") {
+			p.footer = rest[i:]
+			p.footerBlank = i > 0
+			rest = rest[:i]
+			break
+		}
+	}
+	if len(rest) > 0 {
+		p.bodyBlank = rest[0] == ""
+		body := strings.Trim(strings.Join(rest, "\n"), "\n")
+		if body != "" {
+			p.body = strings.Split(body, "\n")
+		}
+	}
+	return p
+}
+
+// Check returns the rules message breaks, errors first. With emoji set a
+// leading gitmoji is not counted as part of the type.
+func (r LintRules) Check(message string, emoji bool) []LintProblem {
+	p := parseMessage(message, emoji)
+	texts := map[string]string{
+		"header":  p.header,
+		"type":    p.typ,
+		"scope":   p.scope,
+		"subject": p.subject,
+		"body":    strings.Join(p.body, "\n"),
+		"footer":  strings.Join(p.footer, "\n"),
+	}
+	lines := map[string][]string{"body": p.body, "footer": p.footer}
+
+	var problems []LintProblem
+	for _, name := range sortedRuleNames(r) {
+		rule := r[name]
+		if rule.Level == LintOff {
+			continue
+		}
+		part, check, _ := strings.Cut(name, "-")
+		text, known := texts[part]
+		if !known {
+			continue
+		}
+		never := rule.When == "never"
+		fail := func(format string, args ...interface{}) {
+			problems = append(problems, LintProblem{name, rule.Level, fmt.Sprintf(format, args...)})
+		}
+
+		switch check {
+		case "empty":
+			if (text == "") == never {
+				fail("%s %s be empty", part, verb(never))
+			}
+		case "max-length", "min-length", "max-line-length":
+			n, ok := rule.Value.(float64)
+			if !ok || text == "" {
+				continue
+			}
+			switch check {
+			case "max-length":
+				if l := len([]rune(text)); l > int(n) {
+					fail("%s must not be longer than %d characters, current length is %d", part, int(n), l)
+				}
+			case "min-length":
+				if l := len([]rune(text)); l < int(n) {
+					fail("%s must not be shorter than %d characters, current length is %d", part, int(n), l)
+				}
+			default:
+				for _, line := range lines[part] {
+					if len([]rune(line)) > int(n) {
+						fail("%s's lines must not be longer than %d characters", part, int(n))
+						break
+					}
+				}
+			}
+		case "full-stop":
+			stop, _ := rule.Value.(string)
+			if stop == "" {
+				stop = "."
+			}
+			if text != "" && strings.HasSuffix(text, stop) == never {
+				fail("%s %s end with full stop", part, verb(never))
+			}
+		case "trim":
+			if text != strings.TrimSpace(text) {
+				fail("%s must not have leading or trailing whitespace", part)
+			}
+		case "leading-blank":
+			blank := map[string]bool{"body": p.bodyBlank, "footer": p.footerBlank}[part]
+			if len(lines[part]) > 0 && blank == never {
+				fail("%s %s have leading blank line", part, verb(never))
+			}
+		case "case":
+			if text == "" {
+				continue
+			}
+			cases := stringList(rule.Value)
+			matched := false
+			for _, c := range cases {
+				if isCase(text, c) {
+					matched = true
+				}
+			}
+			if matched == never {
+				fail("%s %s be %s", part, verb(never), strings.Join(cases, ", "))
+			}
+		case "enum":
+			allowed := stringList(rule.Value)
+			if text == "" || len(allowed) == 0 {
+				continue
+			}
+			for _, item := range strings.Split(text, ",") {
+				item = strings.TrimSpace(item)
+				if slices.Contains(allowed, item) == never {
+					fail("%s %s be one of [%s]", part, verb(never), strings.Join(allowed, ", "))
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Level > problems[j].Level })
+	return problems
+}
+
+// Errors returns the problems at error level
+func (r LintRules) Errors(message string, emoji bool) []LintProblem {
+	var errs []LintProblem
+	for _, p := range r.Check(message, emoji) {
+		if p.Level == LintError {
+			errs = append(errs, p)
+		}
+	}
+	return errs
+}
+
+// Describe lists the rules that are not off, one per line in commitlint's
+// own notation, for the model to follow
+func (r LintRules) Describe() string {
+	var b strings.Builder
+	for _, name := range sortedRuleNames(r) {
+		rule := r[name]
+		if rule.Level == LintOff {
+			continue
+		}
+		fmt.Fprintf(&b, "  %s: %s", name, rule.When)
+		if rule.Value != nil {
+			value, _ := json.Marshal(rule.Value)
+			fmt.Fprintf(&b, " %s", value)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// LintFixMessage asks the model to correct its message
+func LintFixMessage(problems []LintProblem) string {
+	var b strings.Builder
+	b.WriteString("That message breaks these commitlint rules:\n")
+	for _, p := range problems {
+		b.WriteString("- " + p.String() + "\n")
+	}
+	b.WriteString("\nReply with the corrected commit message only.")
+	return b.String()
+}
+
+func sortedRuleNames(r LintRules) []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// verb phrases a rule for "always" or "never"
+func verb(never bool) string {
+	if never {
+		return "may not"
+	}
+	return "must"
+}
+
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+var (
+	pascalCase = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	camelCase  = regexp.MustCompile(`^[a-z][A-Za-z0-9]*$`)
+	kebabCase  = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	snakeCase  = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+)
+
+// isCase checks text against a commitlint case name
+func isCase(text, name string) bool {
+	switch name {
+	case "lower-case", "lowercase":
+		return text == strings.ToLower(text)
+	case "upper-case", "uppercase":
+		return text == strings.ToUpper(text)
+	case "sentence-case", "sentencecase":
+		first := []rune(text)[0]
+		return unicode.IsUpper(first) && text[len(string(first)):] == strings.ToLower(text[len(string(first)):])
+	case "start-case", "startcase":
+		for _, word := range strings.Fields(text) {
+			if !isCase(word, "sentence-case") {
+				return false
+			}
+		}
+		return true
+	case "pascal-case", "pascalcase":
+		return pascalCase.MatchString(text)
+	case "camel-case", "camelcase":
+		return camelCase.MatchString(text)
+	case "kebab-case", "kebabcase":
+		return kebabCase.MatchString(text)
+	case "snake-case", "snakecase":
+		return snakeCase.MatchString(text)
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package commitmsg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func problemRules(problems []LintProblem) string {
+	rules := make([]string, len(problems))
+	for i, p := range problems {
+		rules[i] = p.Rule
+	}
+	return strings.Join(rules, ",")
+}
+
+func TestLintConventional(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"feat(auth): add login\n\nUses the new session API.\n\nRefs: #42", ""},
+		{"Add login", "subject-empty,type-empty"},
+		{"feature: add login", "type-enum"},
+		{"fix: Fix the redirect loop.", "subject-case,subject-full-stop"},
+		{"fix: handle redirect\nno blank line", "body-leading-blank"},
+		{"docs: " + strings.Repeat("x", 100), "header-max-length"},
+		{"feat!: drop sessions\n\nBREAKING CHANGE: sessions are gone", ""},
+	}
+	for _, tt := range tests {
+		if got := problemRules(ConventionalLintRules.Check(tt.message, false)); got != tt.want {
+			t.Errorf("Check(%q) = %s, want %s", tt.message, got, tt.want)
+		}
+	}
+
+	if got := ConventionalLintRules.Check("✨ feat: add login", true); len(got) != 0 {
+		t.Errorf("gitmoji subject: %v", got)
+	}
+	if got := ConventionalLintRules.Errors("fix: handle redirect\nno blank line", false); len(got) != 0 {
+		t.Errorf("Errors included a warning: %v", got)
+	}
+}
+
+func TestParseLintRules(t *testing.T) {
+	rules, err := ParseLintRules(map[string][]interface{}{
+		"header-max-length": {2.0, "always", 50.0},
+		"scope-enum":        {1.0, "always", []interface{}{"api", "web"}},
+	})
+	if err != nil {
+		t.Fatalf("ParseLintRules failed: %v", err)
+	}
+	got := rules.Check("fix(cli): "+strings.Repeat("x", 45), false)
+	if problemRules(got) != "header-max-length,scope-enum" {
+		t.Errorf("Check = %v", got)
+	}
+
+	for _, bad := range [][]interface{}{{}, {3.0}, {2.0, "sometimes"}} {
+		if _, err := ParseLintRule(bad); err == nil {
+			t.Errorf("ParseLintRule(%v) succeeded", bad)
+		}
+	}
+}
+
+func TestLoadCommitlint(t *testing.T) {
+	dir := t.TempDir()
+	if rules, _, err := LoadCommitlint(dir); err != nil || rules != nil {
+		t.Fatalf("no config: %v, %v", rules, err)
+	}
+
+	js := `// commitlint
+import type { UserConfig } from '@commitlint/types';
+import { RuleConfigSeverity } from '@commitlint/types';
+
+const config: UserConfig = {
+  extends: ['@commitlint/config-conventional'],
+  rules: {
+    'header-max-length': [RuleConfigSeverity.Error, 'always', 60],
+    'type-enum': [2, 'always', ['feat', 'fix', 'chore']], /* trimmed */
+    'body-leading-blank': [0],
+  },
+};
+
+export default config;
+`
+	os.WriteFile(filepath.Join(dir, "commitlint.config.ts"), []byte(js), 0o644)
+	rules, path, err := LoadCommitlint(dir)
+	if err != nil {
+		t.Fatalf("LoadCommitlint failed: %v", err)
+	}
+	if filepath.Base(path) != "commitlint.config.ts" {
+		t.Errorf("path = %s", path)
+	}
+	if r := rules["header-max-length"]; r.Level != LintError || r.Value != 60.0 {
+		t.Errorf("header-max-length = %+v", r)
+	}
+	if r := rules["body-leading-blank"]; r.Level != LintOff {
+		t.Errorf("body-leading-blank = %+v", r)
+	}
+	if _, ok := rules["subject-case"]; !ok {
+		t.Error("config-conventional rules were not included")
+	}
+	if got := problemRules(rules.Check("docs: update readme", false)); got != "type-enum" {
+		t.Errorf("Check = %s", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, ".commitlintrc.yaml"), []byte("rules:\n  subject-empty: [2, never]\n"), 0o644)
+	rules, _, err = LoadCommitlint(dir)
+	if err != nil || len(rules) != 1 || rules["subject-empty"].When != "never" {
+		t.Errorf("yaml config = %+v, %v", rules, err)
+	}
+}
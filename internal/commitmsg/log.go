@@ -0,0 +1,43 @@
+package commitmsg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+const logPrompt = `You group the commits of a git branch into themes, to help write a pull
+request description. Reply in Markdown only: one "### " heading per theme
+with a short name, each followed by one or two sentences on what changed
+and why, then the short hashes of its commits in backticks. Put every
+commit under exactly one theme, folding fixups and follow-ups into the
+change they belong to, and order the themes by importance. No title and
+no preamble.`
+
+// maxLogCommits bounds the commits listed in LogMessages
+const maxLogCommits = 300
+
+// LogMessages returns the prompt for grouping the commits of branch into
+// themes. Each commit is a line such as "a1b2c3d Add login form", newest
+// first; language names the language to write in, empty for English.
+func LogMessages(branch string, commits []string, language string) []types.Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Commits of %s, newest first:\n", branch)
+	for i, c := range commits {
+		if i == maxLogCommits {
+			b.WriteString("... (more commits omitted)\n")
+			break
+		}
+		b.WriteString("- " + c + "\n")
+	}
+
+	system := logPrompt
+	if language != "" {
+		system += "\nWrite in " + language + "."
+	}
+	return []types.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: b.String()},
+	}
+}
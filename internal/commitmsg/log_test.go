@@ -0,0 +1,17 @@
+package commitmsg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogMessages(t *testing.T) {
+	messages := LogMessages("feature", []string{"a1b2c3d Add login form", "d4e5f6a Fix typo"}, "Spanish")
+	if !strings.HasSuffix(messages[0].Content, "Write in Spanish.") {
+		t.Errorf("system prompt doesn't name the language:\n%s", messages[0].Content)
+	}
+	want := "Commits of feature, newest first:\n- a1b2c3d Add login form\n- d4e5f6a Fix typo\n"
+	if messages[1].Content != want {
+		t.Errorf("user prompt =\n%s\nwant\n%s", messages[1].Content, want)
+	}
+}
@@ -0,0 +1,170 @@
+package commitmsg
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxOfflineFiles bounds the files listed in the body of an offline message
+const maxOfflineFiles = 20
+
+// fileChange is what a diff does to one file
+type fileChange struct {
+	path             string
+	added, deleted   int
+	created, removed bool
+}
+
+// Offline returns a message for diff built without a provider: a subject
+// naming what changed with its size, such as "Update auth middleware
+// (+120/-34 in 4 files)", and a body listing the files when there are
+// several. The result is the same for the same diff and style.
+func Offline(diff string, style Style) string {
+	files := parseChanges(diff)
+	if len(files) == 0 {
+		return "Update files"
+	}
+
+	added, deleted := 0, 0
+	allCreated, allRemoved := true, true
+	paths := make([]string, len(files))
+	for i, f := range files {
+		added += f.added
+		deleted += f.deleted
+		allCreated = allCreated && f.created
+		allRemoved = allRemoved && f.removed
+		paths[i] = f.path
+	}
+
+	verb := "Update"
+	switch {
+	case allCreated:
+		verb = "Add"
+	case allRemoved:
+		verb = "Remove"
+	}
+	noun := "file"
+	if len(files) > 1 {
+		noun = "files"
+	}
+	size := fmt.Sprintf("(+%d/-%d in %d %s)", added, deleted, len(files), noun)
+
+	subject := verb + " " + target(paths)
+	if style.Conventional {
+		subject = changeType(paths) + ": " + strings.ToLower(verb) + " " + target(paths)
+	}
+	if len(subject)+1+len(size) <= style.SubjectLimit() {
+		subject += " " + size
+	}
+
+	if len(files) == 1 {
+		return subject
+	}
+	var b strings.Builder
+	b.WriteString(subject + "\n")
+	for i, f := range files {
+		if i == maxOfflineFiles {
+			fmt.Fprintf(&b, "\n- ... and %d more", len(files)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s (+%d/-%d)", f.path, f.added, f.deleted)
+	}
+	return b.String()
+}
+
+// parseChanges counts the lines added and deleted in each file of diff
+func parseChanges(diff string) []fileChange {
+	var files []fileChange
+	var current *fileChange
+	header := false
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			name := line[len("diff --git "):]
+			if i := strings.LastIndex(name, " b/"); i >= 0 {
+				name = name[i+len(" b/"):]
+			}
+			files = append(files, fileChange{path: name})
+			current = &files[len(files)-1]
+			header = true
+		case current == nil:
+		case header:
+			switch {
+			case strings.HasPrefix(line, "new file mode"):
+				current.created = true
+			case strings.HasPrefix(line, "deleted file mode"):
+				current.removed = true
+			case strings.HasPrefix(line, "@@"):
+				header = false
+			}
+		case strings.HasPrefix(line, "+"):
+			current.added++
+		case strings.HasPrefix(line, "-"):
+			current.deleted++
+		}
+	}
+	return files
+}
+
+// target names what paths have in common: the last two components of
+// their common directory, or of the file without its extension when
+// there is one, or their top-level entries
+func target(paths []string) string {
+	dir := path.Dir(paths[0])
+	if len(paths) == 1 {
+		dir = strings.TrimSuffix(paths[0], path.Ext(paths[0]))
+	}
+	for _, p := range paths[1:] {
+		for dir != "." && !strings.HasPrefix(p, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	if dir != "." && dir != "/" {
+		parts := strings.Split(dir, "/")
+		return words(strings.Join(parts[max(0, len(parts)-2):], " "))
+	}
+
+	seen := map[string]bool{}
+	var top []string
+	for _, p := range paths {
+		entry, _, _ := strings.Cut(p, "/")
+		entry = strings.TrimSuffix(entry, path.Ext(entry))
+		if !seen[entry] {
+			seen[entry] = true
+			top = append(top, entry)
+		}
+	}
+	if len(top) > 3 {
+		return "project files"
+	}
+	sort.Strings(top)
+	if len(top) == 1 {
+		return words(top[0])
+	}
+	return words(strings.Join(top[:len(top)-1], ", ") + " and " + top[len(top)-1])
+}
+
+func words(s string) string {
+	return strings.NewReplacer("_", " ", "-", " ").Replace(s)
+}
+
+// changeType picks the Conventional Commits type of a change to paths
+func changeType(paths []string) string {
+	docs, tests := true, true
+	for _, p := range paths {
+		ext := path.Ext(p)
+		docs = docs && (ext == ".md" || ext == ".rst" || ext == ".txt" || strings.HasPrefix(p, "docs/"))
+		base := path.Base(p)
+		tests = tests && (strings.Contains(base, "_test.") || strings.Contains(base, ".test.") ||
+			strings.Contains(base, ".spec.") || strings.HasPrefix(p, "test/") || strings.HasPrefix(p, "tests/"))
+	}
+	switch {
+	case docs:
+		return "docs"
+	case tests:
+		return "test"
+	}
+	return "chore"
+}
@@ -0,0 +1,47 @@
+package commitmsg
+
+import "testing"
+
+func TestOffline(t *testing.T) {
+	diff := `diff --git a/internal/auth/middleware.go b/internal/auth/middleware.go
+index 1111111..2222222 100644
+--- a/internal/auth/middleware.go
++++ b/internal/auth/middleware.go
+@@ -1,3 +1,4 @@
+ package auth
+--- old comment
++++ new comment
++func check() {}
+diff --git a/internal/auth/session.go b/internal/auth/session.go
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/internal/auth/session.go
+@@ -0,0 +1,2 @@
++package auth
++var s int
+`
+	want := "Update internal auth (+4/-1 in 2 files)\n\n- internal/auth/middleware.go (+2/-1)\n- internal/auth/session.go (+2/-0)"
+	if got := Offline(diff, Style{}); got != want {
+		t.Errorf("Offline =\n%s\nwant\n%s", got, want)
+	}
+
+	readme := "diff --git a/README.md b/README.md\nnew file mode 100644\n--- /dev/null\n+++ b/README.md\n@@ -0,0 +1 @@\n+# App\n"
+	if got := Offline(readme, Style{Conventional: true}); got != "docs: add README (+1/-0 in 1 file)" {
+		t.Errorf("conventional Offline = %q", got)
+	}
+
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"cmd/user_profile.go"}, "cmd user profile"},
+		{[]string{"cmd/a.go", "main.go"}, "cmd and main"},
+		{[]string{"a/x", "b/x", "c/x", "d/x"}, "project files"},
+	}
+	for _, tt := range tests {
+		if got := target(tt.paths); got != tt.want {
+			t.Errorf("target(%v) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package commitmsg
+
+import (
+	"strings"
+
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// WipPrefix starts the subject of the commits made by 'lazywork wip'
+const WipPrefix = "wip: "
+
+// DefaultWipSubject is the subject of a wip commit nobody described
+const DefaultWipSubject = WipPrefix + "save work in progress"
+
+const wipPrompt = `You describe work in progress for a quick-save git commit. Reply with a
+single line of at most 60 characters: lowercase, no trailing period, no
+prefix such as "wip:", no quotes. Say what the changes are about rather
+than listing files.`
+
+// WipMessages returns the prompt for the one-line description of the
+// staged diff of a wip commit
+func WipMessages(diff string) []types.Message {
+	return []types.Message{
+		{Role: "system", Content: wipPrompt},
+		{Role: "user", Content: "Describe these changes:\n\n" + truncate(diff)},
+	}
+}
+
+// WipSubject turns a reply to WipMessages, or a description given by
+// hand, into the subject of a wip commit
+func WipSubject(content string) string {
+	line, _, _ := strings.Cut(Clean(content), "\n")
+	line = strings.TrimSpace(line)
+	for IsWip(line) {
+		line = strings.TrimSpace(line[len("wip"):])
+		line = strings.TrimSpace(strings.TrimPrefix(line, ":"))
+	}
+	line = strings.TrimSuffix(line, ".")
+	if line == "" {
+		return DefaultWipSubject
+	}
+	return WipPrefix + line
+}
+
+// IsWip reports whether subject marks a work-in-progress commit, made by
+// 'lazywork wip' or by hand such as "WIP" or "wip: login form"
+func IsWip(subject string) bool {
+	s := strings.ToLower(strings.TrimSpace(subject))
+	return s == "wip" || strings.HasPrefix(s, "wip:") || strings.HasPrefix(s, "wip ")
+}
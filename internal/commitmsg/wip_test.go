@@ -0,0 +1,33 @@
+package commitmsg
+
+import "testing"
+
+func TestWipSubject(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"login form validation\n", "wip: login form validation"},
+		{`"WIP: retry failed uploads."`, "wip: retry failed uploads"},
+		{"wip", DefaultWipSubject},
+		{"", DefaultWipSubject},
+	}
+	for _, tt := range tests {
+		if got := WipSubject(tt.in); got != tt.want {
+			t.Errorf("WipSubject(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsWip(t *testing.T) {
+	for subject, want := range map[string]bool{
+		"wip: login form": true,
+		"WIP":             true,
+		"WIP save":        true,
+		"Wipe the cache":  false,
+		"Add login":       false,
+	} {
+		if got := IsWip(subject); got != want {
+			t.Errorf("IsWip(%q) = %v, want %v", subject, got, want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Package conflict parses the conflict markers git leaves in files after a
+// failed merge or rebase and writes back the chosen resolutions.
+package conflict
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// Hunk is one conflicted region of a file
+type Hunk struct {
+	Ours        string
+	Base        string // only with merge.conflictStyle diff3 or zdiff3
+	Theirs      string
+	OursLabel   string
+	TheirsLabel string
+	// Before and After hold a few lines around the hunk, for context
+	Before string
+	After  string
+
+	raw string
+}
+
+// Segment is either plain text or a conflict hunk
+type Segment struct {
+	Text string
+	Hunk *Hunk
+}
+
+// File is a parsed conflicted file
+type File struct {
+	Segments []Segment
+}
+
+// contextLines is how many lines around a hunk are kept as context
+const contextLines = 10
+
+const (
+	markerOurs   = "<<<<<<<"
+	markerBase   = "|||||||"
+	markerSplit  = "======="
+	markerTheirs = ">>>>>>>"
+)
+
+// Parse splits content into text and conflict hunks. Markers that don't
+// form a complete hunk are kept as text.
+func Parse(content string) *File {
+	lines := strings.SplitAfter(content, "\n")
+	f := &File{}
+
+	var text strings.Builder
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], markerOurs) {
+			text.WriteString(lines[i])
+			continue
+		}
+		h, end := parseHunk(lines, i)
+		if h == nil {
+			text.WriteString(lines[i])
+			continue
+		}
+		if text.Len() > 0 {
+			f.Segments = append(f.Segments, Segment{Text: text.String()})
+			text.Reset()
+		}
+		f.Segments = append(f.Segments, Segment{Hunk: h})
+		i = end
+	}
+	if text.Len() > 0 {
+		f.Segments = append(f.Segments, Segment{Text: text.String()})
+	}
+
+	for i, s := range f.Segments {
+		if s.Hunk == nil {
+			continue
+		}
+		if i > 0 && f.Segments[i-1].Hunk == nil {
+			s.Hunk.Before = lastLines(f.Segments[i-1].Text, contextLines)
+		}
+		if i+1 < len(f.Segments) && f.Segments[i+1].Hunk == nil {
+			s.Hunk.After = firstLines(f.Segments[i+1].Text, contextLines)
+		}
+	}
+	return f
+}
+
+// parseHunk parses the hunk starting at lines[start] and returns it with
+// the index of its closing marker, or nil if it is incomplete
+func parseHunk(lines []string, start int) (*Hunk, int) {
+	h := &Hunk{OursLabel: label(lines[start], markerOurs)}
+	var ours, base, theirs strings.Builder
+	section := &ours
+
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, markerBase) && section == &ours:
+			section = &base
+		case strings.HasPrefix(line, markerSplit) && section != &theirs && strings.TrimRight(line, "\r\n") == markerSplit:
+			section = &theirs
+		case strings.HasPrefix(line, markerTheirs) && section == &theirs:
+			h.Ours, h.Base, h.Theirs = ours.String(), base.String(), theirs.String()
+			h.TheirsLabel = label(line, markerTheirs)
+			h.raw = strings.Join(lines[start:i+1], "")
+			return h, i
+		case strings.HasPrefix(line, markerOurs):
+			// A nested or stray marker; leave the whole thing alone
+			return nil, start
+		default:
+			section.WriteString(line)
+		}
+	}
+	return nil, start
+}
+
+func label(line, marker string) string {
+	return strings.TrimSpace(strings.TrimPrefix(line, marker))
+}
+
+// Hunks returns the file's conflict hunks in order
+func (f *File) Hunks() []*Hunk {
+	var hunks []*Hunk
+	for _, s := range f.Segments {
+		if s.Hunk != nil {
+			hunks = append(hunks, s.Hunk)
+		}
+	}
+	return hunks
+}
+
+// Resolve returns the file's content with each hunk replaced by its
+// resolution, in order. A nil resolution keeps the hunk's markers.
+func (f *File) Resolve(resolutions []*string) string {
+	var b strings.Builder
+	n := 0
+	for _, s := range f.Segments {
+		if s.Hunk == nil {
+			b.WriteString(s.Text)
+			continue
+		}
+		if n < len(resolutions) && resolutions[n] != nil {
+			b.WriteString(*resolutions[n])
+		} else {
+			b.WriteString(s.Hunk.raw)
+		}
+		n++
+	}
+	return b.String()
+}
+
+// Both returns ours followed by theirs
+func (h *Hunk) Both() string {
+	return h.Ours + h.Theirs
+}
+
+const resolvePrompt = `You resolve git merge conflicts. Given both sides of a conflicted hunk
+and the code around it, reply with the resolved code for the hunk only:
+no conflict markers, no explanation, no code fences, and none of the
+surrounding context. Keep the intent of both sides where they are
+compatible; when they truly contradict, prefer the side that looks newer.`
+
+// Messages returns the prompt asking for a resolution of h in path
+func Messages(path string, h *Hunk) []types.Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "File: %s\n\n", path)
+	if h.Before != "" {
+		b.WriteString("Code before the conflict:\n" + h.Before + "\n")
+	}
+	fmt.Fprintf(&b, "Ours (%s):\n%s\n", orDefault(h.OursLabel, "current branch"), h.Ours)
+	if h.Base != "" {
+		b.WriteString("Common ancestor:\n" + h.Base + "\n")
+	}
+	fmt.Fprintf(&b, "Theirs (%s):\n%s\n", orDefault(h.TheirsLabel, "incoming branch"), h.Theirs)
+	if h.After != "" {
+		b.WriteString("Code after the conflict:\n" + h.After)
+	}
+
+	return []types.Message{
+		{Role: "system", Content: resolvePrompt},
+		{Role: "user", Content: b.String()},
+	}
+}
+
+// CleanSuggestion strips code fences from a suggestion and makes it end
+// with a newline like the lines it replaces
+func CleanSuggestion(s string) string {
+	// Leading indentation is significant; only blank lines are dropped
+	s = strings.TrimLeft(strings.TrimRight(s, " \t\r\n"), "\r\n")
+	if strings.HasPrefix(s, "```") {
+		if i := strings.Index(s, "\n"); i >= 0 {
+			s = s[i+1:]
+		} else {
+			s = ""
+		}
+		s = strings.TrimSuffix(strings.TrimRight(s, "\n"), "```")
+	}
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return ""
+	}
+	return s + "\n"
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "")
+}
+
+func firstLines(s string, n int) string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,102 @@
+package conflict
+
+import (
+	"strings"
+	"testing"
+)
+
+const conflicted = `package main
+
+func greet() string {
+<<<<<<< HEAD
+	return "hello"
+=======
+	return "hi"
+>>>>>>> feature
+}
+
+func farewell() string {
+<<<<<<< HEAD
+	return "bye"
+||||||| base
+	return "later"
+=======
+	return "see you"
+>>>>>>> feature
+}
+`
+
+func TestParse(t *testing.T) {
+	f := Parse(conflicted)
+	hunks := f.Hunks()
+	if len(hunks) != 2 {
+		t.Fatalf("got %d hunks, want 2", len(hunks))
+	}
+
+	h := hunks[0]
+	if h.Ours != "\treturn \"hello\"\n" || h.Theirs != "\treturn \"hi\"\n" || h.Base != "" {
+		t.Errorf("first hunk = %+v", h)
+	}
+	if h.OursLabel != "HEAD" || h.TheirsLabel != "feature" {
+		t.Errorf("labels = %q, %q", h.OursLabel, h.TheirsLabel)
+	}
+	if !strings.HasSuffix(h.Before, "func greet() string {\n") || !strings.HasPrefix(h.After, "}\n") {
+		t.Errorf("context = %q / %q", h.Before, h.After)
+	}
+
+	if hunks[1].Base != "\treturn \"later\"\n" || hunks[1].Theirs != "\treturn \"see you\"\n" {
+		t.Errorf("diff3 hunk = %+v", hunks[1])
+	}
+}
+
+func TestResolve(t *testing.T) {
+	f := Parse(conflicted)
+
+	// Leaving every hunk unresolved gives back the original file
+	if got := f.Resolve(nil); got != conflicted {
+		t.Errorf("unresolved round trip:\n%s", got)
+	}
+
+	first := "\treturn \"hello there\"\n"
+	got := f.Resolve([]*string{&first, nil})
+	if !strings.Contains(got, "return \"hello there\"\n}") {
+		t.Errorf("first hunk not resolved:\n%s", got)
+	}
+	if strings.Count(got, "<<<<<<<") != 1 {
+		t.Errorf("expected the second hunk to keep its markers:\n%s", got)
+	}
+}
+
+func TestParseIncomplete(t *testing.T) {
+	content := "a\n<<<<<<< HEAD\nb\n"
+	f := Parse(content)
+	if len(f.Hunks()) != 0 {
+		t.Error("expected an unterminated conflict to be left as text")
+	}
+	if f.Resolve(nil) != content {
+		t.Error("expected content to be preserved")
+	}
+}
+
+func TestCleanSuggestion(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"\treturn 1", "\treturn 1\n"},
+		{"```go\n\treturn 1\n```", "\treturn 1\n"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := CleanSuggestion(tt.in); got != tt.want {
+			t.Errorf("CleanSuggestion(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMessages(t *testing.T) {
+	h := Parse(conflicted).Hunks()[1]
+	prompt := Messages("main.go", h)[1].Content
+	for _, want := range []string{"File: main.go", "Ours (HEAD)", "Common ancestor", "Theirs (feature)", "see you"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package context gathers what a model should know about a repository to
+// use its terminology: the file tree, recent commit subjects and the start
+// of the README, within a token budget.
+package context
+
+import (
+	stdcontext "context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+)
+
+// recentCommits is how many commit subjects are collected
+const recentCommits = 20
+
+// maxTreeDepth is how many path components the file tree shows; deeper
+// files are folded into their directory
+const maxTreeDepth = 2
+
+// readmeNames are the README files looked for, in order
+var readmeNames = []string{"README.md", "README", "README.rst", "README.txt", "readme.md"}
+
+// Repo is the context collected for a repository
+type Repo struct {
+	Name    string
+	Tree    []string
+	Commits []string
+	Readme  string
+}
+
+// Collect gathers the context of the repository whose worktree is at root.
+// Parts that can't be read are left empty.
+func Collect(ctx stdcontext.Context, root string) Repo {
+	r := Repo{Name: filepath.Base(root)}
+	if files, err := git.TrackedFiles(ctx, root); err == nil {
+		r.Tree = Tree(files)
+	}
+	if commits, err := git.Log(ctx, "HEAD", recentCommits); err == nil {
+		for _, c := range commits {
+			r.Commits = append(r.Commits, c.Subject)
+		}
+	}
+	for _, name := range readmeNames {
+		if data, err := os.ReadFile(filepath.Join(root, name)); err == nil {
+			r.Readme = strings.TrimSpace(string(data))
+			break
+		}
+	}
+	return r
+}
+
+// Tree folds files into the entries of a file tree at most maxTreeDepth
+// deep, directories ending in "/", sorted
+func Tree(files []string) []string {
+	seen := map[string]bool{}
+	var entries []string
+	for _, f := range files {
+		parts := strings.Split(f, "/")
+		entry := f
+		if len(parts) > maxTreeDepth {
+			entry = strings.Join(parts[:maxTreeDepth], "/") + "/"
+		}
+		if !seen[entry] {
+			seen[entry] = true
+			entries = append(entries, entry)
+		}
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// Render returns the context as prompt text of about maxTokens tokens at
+// most, counting four characters per token. The README gets up to half of
+// the budget, the file tree and commits share the rest; what doesn't fit
+// is cut.
+func (r Repo) Render(maxTokens int) string {
+	budget := maxTokens * 4
+	if budget <= 0 {
+		return ""
+	}
+	readme := cut(r.Readme, budget/2)
+	rest := budget - len(readme)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Context about the repository %s, for its terminology; don't describe it.\n", r.Name)
+	if len(r.Tree) > 0 {
+		b.WriteString("\nFiles:\n" + cutLines(r.Tree, rest*2/3))
+	}
+	if len(r.Commits) > 0 {
+		b.WriteString("\nRecent commits:\n" + cutLines(r.Commits, rest/3))
+	}
+	if readme != "" {
+		b.WriteString("\nREADME (start):\n" + readme + "\n")
+	}
+	return b.String()
+}
+
+// cut returns the start of text within size bytes, ending at a line break
+// when there is one
+func cut(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+	text = text[:size]
+	if i := strings.LastIndex(text, "\n"); i > 0 {
+		text = text[:i]
+	}
+	return strings.ToValidUTF8(text, "") + "\n..."
+}
+
+// cutLines returns lines, one per line, within about size bytes, saying
+// how many were left out
+func cutLines(lines []string, size int) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if b.Len()+len(line)+1 > size {
+			fmt.Fprintf(&b, "... (%d more)\n", len(lines)-i)
+			break
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package context
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTree(t *testing.T) {
+	got := Tree([]string{"main.go", "cmd/root.go", "internal/git/git.go", "internal/git/runner.go", "internal/tui/forms.go"})
+	want := []string{"cmd/root.go", "internal/git/", "internal/tui/", "main.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Tree = %v, want %v", got, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	r := Repo{
+		Name:    "app",
+		Tree:    []string{"cmd/", "main.go"},
+		Commits: []string{"Add worktree finish", "Fix stash naming"},
+		Readme:  "# App\n\nManages worktrees.\n" + strings.Repeat("More docs.\n", 500),
+	}
+
+	text := r.Render(500)
+	for _, want := range []string{"repository app", "Files:\ncmd/\nmain.go\n", "Recent commits:\nAdd worktree finish\nFix stash naming\n", "README (start):\n# App\n\nManages worktrees.\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Render is missing %q:\n%s", want, text)
+		}
+	}
+	if len(text) > 500*4+200 {
+		t.Errorf("Render is %d bytes, over the budget", len(text))
+	}
+
+	if r.Render(0) != "" {
+		t.Error("Render(0) should be empty")
+	}
+	if got := cutLines([]string{"aaaa", "bbbb", "cccc"}, 10); got != "aaaa\nbbbb\n... (1 more)\n" {
+		t.Errorf("cutLines = %q", got)
+	}
+}
@@ -0,0 +1,186 @@
+// Package docs renders the command tree as Markdown pages and man pages,
+// so shipped documentation always matches the actual commands and flags.
+package docs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Commands returns cmd and every documented command below it, in command
+// path order. Hidden, deprecated and help commands are left out.
+func Commands(cmd *cobra.Command) []*cobra.Command {
+	cmds := []*cobra.Command{cmd}
+	for _, c := range children(cmd) {
+		cmds = append(cmds, Commands(c)...)
+	}
+	return cmds
+}
+
+func children(cmd *cobra.Command) []*cobra.Command {
+	var cmds []*cobra.Command
+	for _, c := range cmd.Commands() {
+		if c.IsAvailableCommand() && !c.IsAdditionalHelpTopicCommand() {
+			cmds = append(cmds, c)
+		}
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name() < cmds[j].Name() })
+	return cmds
+}
+
+// MarkdownName returns the file name of cmd's Markdown page, e.g.
+// lazywork_worktree_add.md
+func MarkdownName(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "_") + ".md"
+}
+
+// ManName returns the file name of cmd's man page, e.g.
+// lazywork-worktree-add.1
+func ManName(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "-") + ".1"
+}
+
+// Markdown renders the page of a single command
+func Markdown(cmd *cobra.Command) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n%s\n\n", cmd.CommandPath(), cmd.Short)
+	if cmd.Long != "" {
+		fmt.Fprintf(&b, "### Synopsis\n\n```\n%s\n```\n\n", strings.TrimSpace(cmd.Long))
+	}
+	if cmd.Runnable() {
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", cmd.UseLine())
+	}
+	if cmd.Example != "" {
+		fmt.Fprintf(&b, "### Examples\n\n```\n%s\n```\n\n", strings.TrimRight(cmd.Example, "\n"))
+	}
+	if flags := cmd.NonInheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, "### Options\n\n```\n%s```\n\n", flags.FlagUsages())
+	}
+	if flags := cmd.InheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, "### Options inherited from parent commands\n\n```\n%s```\n\n", flags.FlagUsages())
+	}
+
+	var seeAlso []string
+	if parent := cmd.Parent(); parent != nil {
+		seeAlso = append(seeAlso, fmt.Sprintf("* [%s](%s) - %s", parent.CommandPath(), MarkdownName(parent), parent.Short))
+	}
+	for _, c := range children(cmd) {
+		seeAlso = append(seeAlso, fmt.Sprintf("* [%s](%s) - %s", c.CommandPath(), MarkdownName(c), c.Short))
+	}
+	if len(seeAlso) > 0 {
+		fmt.Fprintf(&b, "### See also\n\n%s\n", strings.Join(seeAlso, "\n"))
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// Man renders the man page of a single command. source names the
+// software, e.g. "lazywork 1.2.0"; date is the page's last change.
+func Man(cmd *cobra.Command, source string, date time.Time) string {
+	name := strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH %q 1 %q %q \"User Commands\"\n", strings.ToUpper(name), date.Format("Jan 2006"), source)
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", name, roff(cmd.Short))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B %s\n", roff(cmd.UseLine()))
+	if cmd.Long != "" {
+		// Help texts are laid out by hand, so keep their line breaks
+		fmt.Fprintf(&b, ".SH DESCRIPTION\n.nf\n%s\n.fi\n", roff(strings.TrimSpace(cmd.Long)))
+	}
+	if cmd.Example != "" {
+		fmt.Fprintf(&b, ".SH EXAMPLES\n.nf\n%s\n.fi\n", roff(strings.TrimRight(cmd.Example, "\n")))
+	}
+	if flags := cmd.NonInheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, ".SH OPTIONS\n%s", manFlags(flags))
+	}
+	if flags := cmd.InheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, ".SH OPTIONS INHERITED FROM PARENT COMMANDS\n%s", manFlags(flags))
+	}
+
+	var seeAlso []string
+	if parent := cmd.Parent(); parent != nil {
+		seeAlso = append(seeAlso, fmt.Sprintf("\\fB%s\\fP(1)", strings.ReplaceAll(parent.CommandPath(), " ", "-")))
+	}
+	for _, c := range children(cmd) {
+		seeAlso = append(seeAlso, fmt.Sprintf("\\fB%s\\fP(1)", strings.ReplaceAll(c.CommandPath(), " ", "-")))
+	}
+	if len(seeAlso) > 0 {
+		fmt.Fprintf(&b, ".SH SEE ALSO\n%s\n", strings.Join(seeAlso, ", "))
+	}
+	return b.String()
+}
+
+func manFlags(flags *pflag.FlagSet) string {
+	var b bytes.Buffer
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden || f.Deprecated != "" {
+			return
+		}
+		b.WriteString(".TP\n")
+		if f.Shorthand != "" && f.ShorthandDeprecated == "" {
+			fmt.Fprintf(&b, "\\fB\\-%s\\fP, ", f.Shorthand)
+		}
+		fmt.Fprintf(&b, "\\fB\\-\\-%s\\fP", f.Name)
+		if f.Value.Type() != "bool" {
+			fmt.Fprintf(&b, " \\fI%s\\fP", f.Value.Type())
+		}
+		b.WriteString("\n" + roff(f.Usage))
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "[]" && f.DefValue != "0" {
+			fmt.Fprintf(&b, " (default %s)", strings.ReplaceAll(f.DefValue, `\`, `\e`))
+		}
+		b.WriteString("\n")
+	})
+	return b.String()
+}
+
+// roff escapes text for a man page: backslashes, and control characters
+// at the start of a line
+func roff(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteMarkdown writes a Markdown page for root and every command below it
+// into dir, returning the files written
+func WriteMarkdown(root *cobra.Command, dir string) ([]string, error) {
+	return write(root, dir, func(c *cobra.Command) (string, string) {
+		return MarkdownName(c), Markdown(c)
+	})
+}
+
+// WriteMan writes a man page for root and every command below it into
+// dir, returning the files written
+func WriteMan(root *cobra.Command, dir, source string, date time.Time) ([]string, error) {
+	return write(root, dir, func(c *cobra.Command) (string, string) {
+		return ManName(c), Man(c, source, date)
+	})
+}
+
+func write(root *cobra.Command, dir string, page func(*cobra.Command) (name, content string)) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	var files []string
+	for _, c := range Commands(root) {
+		name, content := page(c)
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return files, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
@@ -0,0 +1,76 @@
+package docs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func testTree() *cobra.Command {
+	root := &cobra.Command{Use: "tool", Short: "A tool"}
+	root.PersistentFlags().Bool("json", false, "Output JSON")
+	sub := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Add a thing",
+		Long:  "Add a thing.\n.dot starts this line, and a \\ too.",
+		Run:   func(*cobra.Command, []string) {},
+	}
+	sub.Flags().StringP("dir", "d", ".things", "Directory")
+	root.AddCommand(sub, &cobra.Command{Use: "secret", Hidden: true, Run: func(*cobra.Command, []string) {}})
+	return root
+}
+
+func TestCommands(t *testing.T) {
+	var names []string
+	for _, c := range Commands(testTree()) {
+		names = append(names, c.CommandPath())
+	}
+	if got := strings.Join(names, ","); got != "tool,tool add" {
+		t.Errorf("Commands = %s", got)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	root := testTree()
+	add, _, _ := root.Find([]string{"add"})
+
+	page := Markdown(add)
+	for _, want := range []string{"## tool add\n\nAdd a thing", "tool add <name> [flags]", "-d, --dir string", "### Options inherited from parent commands", "* [tool](tool.md) - A tool"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Markdown page lacks %q:\n%s", want, page)
+		}
+	}
+	if page := Markdown(root); !strings.Contains(page, "* [tool add](tool_add.md) - Add a thing") || strings.Contains(page, "secret") {
+		t.Errorf("root page:\n%s", page)
+	}
+}
+
+func TestMan(t *testing.T) {
+	root := testTree()
+	add, _, _ := root.Find([]string{"add"})
+
+	page := Man(add, "tool 1.0", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	for _, want := range []string{`.TH "TOOL-ADD" 1 "Mar 2026" "tool 1.0"`, `tool-add \- Add a thing`, `\&.dot starts`, `a \e too`, `\fB\-d\fP, \fB\-\-dir\fP \fIstring\fP`, "(default .things)", `\fBtool\fP(1)`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("man page lacks %q:\n%s", want, page)
+		}
+	}
+}
+
+func TestWriteTrees(t *testing.T) {
+	dir := t.TempDir()
+	files, err := WriteMan(testTree(), filepath.Join(dir, "man"), "tool", time.Now())
+	if err != nil || len(files) != 2 {
+		t.Fatalf("WriteMan = %v, %v", files, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "man", "tool-add.1")); err != nil {
+		t.Error(err)
+	}
+	if files, err := WriteMarkdown(testTree(), dir); err != nil || filepath.Base(files[1]) != "tool_add.md" {
+		t.Errorf("WriteMarkdown = %v, %v", files, err)
+	}
+}
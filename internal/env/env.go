@@ -0,0 +1,183 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Worktree holds the values used to build a worktree's environment
+type Worktree struct {
+	Name     string
+	Branch   string
+	Path     string
+	RepoRoot string
+	// Port is the first of PortCount ports reserved for the worktree's dev
+	// servers; 0 when none are
+	Port      int
+	PortCount int
+}
+
+// Var is a single environment variable
+type Var struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+var unsafeProjectChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// ProjectName returns a docker-compose friendly project name for the worktree
+func (w Worktree) ProjectName() string {
+	name := strings.ToLower(filepath.Base(w.RepoRoot) + "-" + w.Name)
+	name = unsafeProjectChars.ReplaceAllString(name, "-")
+	return strings.Trim(name, "-_")
+}
+
+// Vars returns the environment variables describing the worktree. With a
+// port block, PORT and LW_PORT are its first port and LW_PORT_LAST its last.
+func Vars(w Worktree) []Var {
+	vars := []Var{
+		{"LW_WORKTREE_NAME", w.Name},
+		{"LW_WORKTREE_PATH", w.Path},
+		{"LW_BRANCH", w.Branch},
+		{"LW_REPO_ROOT", w.RepoRoot},
+		{"COMPOSE_PROJECT_NAME", w.ProjectName()},
+	}
+	if w.Port > 0 {
+		vars = append(vars,
+			Var{"LW_PORT", strconv.Itoa(w.Port)},
+			Var{"LW_PORT_LAST", strconv.Itoa(w.Port + max(w.PortCount, 1) - 1)},
+			Var{"PORT", strconv.Itoa(w.Port)},
+		)
+	}
+	return vars
+}
+
+// Map converts vars into a map, convenient for JSON output and templates
+func Map(vars []Var) map[string]string {
+	m := make(map[string]string, len(vars))
+	for _, v := range vars {
+		m[v.Key] = v.Value
+	}
+	return m
+}
+
+// Environ returns vars formatted as KEY=value pairs for exec.Cmd.Env
+func Environ(vars []Var) []string {
+	env := make([]string, len(vars))
+	for i, v := range vars {
+		env[i] = v.Key + "=" + v.Value
+	}
+	return env
+}
+
+// Exports formats vars as POSIX shell export statements
+func Exports(vars []Var) string {
+	var b strings.Builder
+	for _, v := range vars {
+		fmt.Fprintf(&b, "export %s='%s'\n", v.Key, strings.ReplaceAll(v.Value, "'", `'\''`))
+	}
+	return b.String()
+}
+
+// RenderEnvrc renders an .envrc template. The template can reference the
+// worktree fields ({{.Name}}, {{.Branch}}, {{.Path}}, {{.RepoRoot}}) and the
+// environment variables via {{.Env.COMPOSE_PROJECT_NAME}}.
+func RenderEnvrc(tmpl string, w Worktree) (string, error) {
+	t, err := template.New("envrc").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid envrc template: %w", err)
+	}
+
+	data := struct {
+		Worktree
+		Env map[string]string
+	}{w, Map(Vars(w))}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render envrc template: %w", err)
+	}
+
+	content := buf.String()
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content, nil
+}
+
+// WriteEnvrc renders the template into <path>/.envrc
+func WriteEnvrc(tmpl string, w Worktree) (string, error) {
+	content, err := RenderEnvrc(tmpl, w)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(w.Path, ".envrc")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write .envrc: %w", err)
+	}
+	return path, nil
+}
+
+// HasDirenv returns true if the direnv binary is available
+func HasDirenv() bool {
+	_, err := exec.LookPath("direnv")
+	return err == nil
+}
+
+// DirenvAllow runs 'direnv allow' for the given directory
+func DirenvAllow(dir string) error {
+	cmd := exec.Command("direnv", "allow", dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("direnv allow: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// composeFiles are the file names docker compose looks for, in its order
+var composeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// ComposeFile returns the path of the docker compose file in dir, or "" if
+// there is none
+func ComposeFile(dir string) string {
+	for _, name := range composeFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// HasDocker returns true if the docker binary is available
+func HasDocker() bool {
+	_, err := exec.LookPath("docker")
+	return err == nil
+}
+
+// ComposeCommand returns 'docker compose args...' set up to run in the
+// worktree with its environment, so COMPOSE_PROJECT_NAME keeps the
+// worktree's containers, networks and volumes apart from other worktrees'
+func ComposeCommand(ctx context.Context, w Worktree, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "docker", append([]string{"compose"}, args...)...)
+	cmd.Dir = w.Path
+	cmd.Env = append(os.Environ(), Environ(Vars(w))...)
+	return cmd
+}
+
+// ComposeDown stops and removes the worktree's compose containers and
+// networks. Volumes are kept.
+func ComposeDown(ctx context.Context, w Worktree) error {
+	cmd := ComposeCommand(ctx, w, "down", "--remove-orphans")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("docker compose down: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProjectName(t *testing.T) {
+	tests := []struct {
+		repoRoot string
+		name     string
+		want     string
+	}{
+		{"/src/lazywork", "feature-auth", "lazywork-feature-auth"},
+		{"/src/My.Repo", "Fix Bug", "my-repo-fix-bug"},
+		{"/src/repo", "feat/login", "repo-feat-login"},
+	}
+
+	for _, tt := range tests {
+		w := Worktree{Name: tt.name, RepoRoot: tt.repoRoot}
+		if got := w.ProjectName(); got != tt.want {
+			t.Errorf("ProjectName(%q, %q) = %q, want %q", tt.repoRoot, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderEnvrc(t *testing.T) {
+	w := Worktree{Name: "auth", Branch: "feature/auth", Path: "/src/repo/.worktrees/auth", RepoRoot: "/src/repo"}
+
+	got, err := RenderEnvrc("export BRANCH={{.Branch}}\nexport PROJECT={{.Env.COMPOSE_PROJECT_NAME}}", w)
+	if err != nil {
+		t.Fatalf("RenderEnvrc failed: %v", err)
+	}
+	want := "export BRANCH=feature/auth\nexport PROJECT=repo-auth\n"
+	if got != want {
+		t.Errorf("RenderEnvrc() = %q, want %q", got, want)
+	}
+
+	if _, err := RenderEnvrc("{{.Missing}}", w); err == nil {
+		t.Error("expected error for unknown template field")
+	}
+}
+
+func TestExportsQuoting(t *testing.T) {
+	got := Exports([]Var{{"A", "it's"}})
+	if !strings.Contains(got, `export A='it'\''s'`) {
+		t.Errorf("Exports() = %q", got)
+	}
+}
+
+func TestVarsPorts(t *testing.T) {
+	w := Worktree{Name: "auth", Path: "/src/repo/.worktrees/auth", RepoRoot: "/src/repo"}
+	if m := Map(Vars(w)); m["PORT"] != "" {
+		t.Errorf("PORT set without a port block: %v", m)
+	}
+
+	w.Port, w.PortCount = 3010, 10
+	m := Map(Vars(w))
+	if m["PORT"] != "3010" || m["LW_PORT"] != "3010" || m["LW_PORT_LAST"] != "3019" {
+		t.Errorf("Vars = %v", m)
+	}
+}
+
+func TestComposeFile(t *testing.T) {
+	dir := t.TempDir()
+	if got := ComposeFile(dir); got != "" {
+		t.Errorf("ComposeFile without a file = %q", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte("services: {}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte("services: {}\n"), 0o644)
+	if got := ComposeFile(dir); got != filepath.Join(dir, "compose.yaml") {
+		t.Errorf("ComposeFile = %q, want compose.yaml first", got)
+	}
+
+	cmd := ComposeCommand(t.Context(), Worktree{Name: "auth", Path: dir, RepoRoot: "/src/repo"}, "up", "-d")
+	if strings.Join(cmd.Args, " ") != "docker compose up -d" || cmd.Dir != dir {
+		t.Errorf("ComposeCommand = %v in %s", cmd.Args, cmd.Dir)
+	}
+	if !slices.Contains(cmd.Env, "COMPOSE_PROJECT_NAME=repo-auth") {
+		t.Error("ComposeCommand does not set COMPOSE_PROJECT_NAME")
+	}
+}
@@ -0,0 +1,266 @@
+// Package forge talks to the service hosting a repository (GitHub, GitLab) to
+// open pull requests, read issues, publish releases and report CI status. Each service
+// implements Forge; Detect picks one from the origin remote or the forge
+// setting.
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+)
+
+// Forge is a code hosting service
+type Forge interface {
+	// Name is the forge type, e.g. "github"
+	Name() string
+	// CreatePullRequest opens a pull request from in.Head into in.Base
+	CreatePullRequest(ctx context.Context, in PullRequestInput) (*PullRequest, error)
+	// MergedPullRequest returns the last pull request from head that was
+	// merged into base, or ErrNotFound
+	MergedPullRequest(ctx context.Context, head, base string) (*PullRequest, error)
+	// Issue returns the issue with the given number
+	Issue(ctx context.Context, number int) (*Issue, error)
+	// Checks summarizes the CI status of ref, a branch or commit
+	Checks(ctx context.Context, ref string) (*Checks, error)
+	// CreateRelease publishes a release for a tag that was already pushed
+	CreateRelease(ctx context.Context, in ReleaseInput) (*Release, error)
+}
+
+// PullRequestInput describes a pull request to open
+type PullRequestInput struct {
+	Title string
+	Body  string
+	Head  string
+	Base  string
+	Draft bool
+}
+
+// PullRequest is an opened pull (or merge) request
+type PullRequest struct {
+	Number int `json:"number"`
+	// Ref is how the forge refers to it, e.g. "#12" or "!12"
+	Ref   string `json:"ref"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	State string `json:"state"`
+	Draft bool   `json:"draft"`
+	// Head is the commit the pull request was merged at; only
+	// MergedPullRequest sets it
+	Head string `json:"head,omitempty"`
+}
+
+// PullRequestMerged is the State of a merged pull request
+const PullRequestMerged = "merged"
+
+// ReleaseInput describes a release to publish
+type ReleaseInput struct {
+	Tag   string
+	Name  string
+	Notes string
+	Draft bool
+}
+
+// Release is a published release
+type Release struct {
+	Tag   string `json:"tag"`
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	Draft bool   `json:"draft"`
+}
+
+// Issue is an issue of the repository
+type Issue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	State  string `json:"state"`
+}
+
+// Check states, from best to worst
+const (
+	ChecksNone    = "none"
+	ChecksSuccess = "success"
+	ChecksPending = "pending"
+	ChecksFailure = "failure"
+)
+
+// Checks summarizes the CI runs reported for a ref
+type Checks struct {
+	State   string `json:"state"`
+	Total   int    `json:"total"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Pending int    `json:"pending"`
+}
+
+// ErrNotFound is returned when a pull request, issue or ref does not exist
+var ErrNotFound = errors.New("not found")
+
+// APIError is a failed request to a forge API
+type APIError struct {
+	Forge   string
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API request failed with status %d: %s", e.Forge, e.Status, e.Message)
+}
+
+// Is makes 404 responses match ErrNotFound
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.Status == 404
+}
+
+// Repo identifies a repository on a forge
+type Repo struct {
+	Host string
+	// Owner is the user or organization, or the group path on forges
+	// with nested groups
+	Owner string
+	Name  string
+}
+
+// Path returns "owner/name"
+func (r Repo) Path() string {
+	return r.Owner + "/" + r.Name
+}
+
+var scpRemote = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// ParseRemote parses a remote URL in https, ssh:// or scp-like
+// (git@host:owner/name.git) form
+func ParseRemote(remote string) (Repo, error) {
+	remote = strings.TrimSpace(remote)
+
+	var host, path string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return Repo{}, fmt.Errorf("invalid remote URL '%s': %w", remote, err)
+		}
+		host, path = u.Hostname(), u.Path
+	} else if m := scpRemote.FindStringSubmatch(remote); m != nil {
+		host, path = m[1], m[2]
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	i := strings.LastIndex(path, "/")
+	if host == "" || i <= 0 || i == len(path)-1 {
+		return Repo{}, fmt.Errorf("cannot find the repository in remote URL '%s'", remote)
+	}
+	return Repo{Host: host, Owner: path[:i], Name: path[i+1:]}, nil
+}
+
+// Detect returns the forge hosting the repository with the given remote
+// URL. The forge setting selects the type explicitly; otherwise it is
+// guessed from the host name.
+func Detect(ctx context.Context, cfg *config.Config, remote string) (Forge, error) {
+	repo, err := ParseRemote(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := cfg.Forge
+	if kind == "" {
+		kind = kindFromHost(repo.Host)
+	}
+	if kind == "" {
+		return nil, fmt.Errorf("cannot tell which forge hosts %s; set forge in the config (%s)", repo.Host, strings.Join(config.ForgeTypes, ", "))
+	}
+
+	settings := cfg.Forges[kind]
+	switch kind {
+	case "github":
+		return NewGitHub(repo, settings.BaseURL, gitHubToken(ctx, repo.Host, settings.Token)), nil
+	case "gitlab":
+		return NewGitLab(repo, settings.BaseURL, gitLabToken(ctx, repo.Host, settings.Token)), nil
+	default:
+		return nil, fmt.Errorf("unsupported forge '%s' (%s)", kind, strings.Join(config.ForgeTypes, ", "))
+	}
+}
+
+func kindFromHost(host string) string {
+	switch {
+	case strings.Contains(host, "github"):
+		return "github"
+	case strings.Contains(host, "gitlab"):
+		return "gitlab"
+	default:
+		return ""
+	}
+}
+
+// gitHubToken returns the configured token, else $GITHUB_TOKEN or
+// $GH_TOKEN, else the token the GitHub CLI is logged in with
+func gitHubToken(ctx context.Context, host, configured string) string {
+	if configured != "" {
+		return configured
+	}
+	for _, name := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	return cliToken(ctx, "gh", "auth", "token", "--hostname", host)
+}
+
+// gitLabToken returns the configured token, else $GITLAB_TOKEN, else the
+// token the GitLab CLI is logged in with
+func gitLabToken(ctx context.Context, host, configured string) string {
+	if configured != "" {
+		return configured
+	}
+	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+		return token
+	}
+	return cliToken(ctx, "glab", "config", "get", "token", "--host", host)
+}
+
+// RequestNoun returns what the forge named name calls a pull request
+func RequestNoun(name string) string {
+	if name == "gitlab" {
+		return "merge request"
+	}
+	return "pull request"
+}
+
+// cliToken runs a forge CLI that prints a token, returning "" if it is not
+// installed or not logged in
+var cliToken = func(ctx context.Context, name string, args ...string) string {
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// IssueBranch suggests a branch name for working on issue, e.g.
+// "42-fix-login-redirect"
+func IssueBranch(issue Issue) string {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(issue.Title), "-"), "-")
+	if len(slug) > 40 {
+		// Cut at a word boundary unless the title is one long word
+		cut := slug[:40]
+		if slug[40] != '-' {
+			if i := strings.LastIndex(cut, "-"); i > 20 {
+				cut = cut[:i]
+			}
+		}
+		slug = strings.TrimRight(cut, "-")
+	}
+	if slug == "" {
+		return fmt.Sprintf("issue-%d", issue.Number)
+	}
+	return fmt.Sprintf("%d-%s", issue.Number, slug)
+}
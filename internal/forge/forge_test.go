@@ -0,0 +1,97 @@
+package forge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   Repo
+	}{
+		{"https://github.com/acme/app.git", Repo{"github.com", "acme", "app"}},
+		{"https://github.com/acme/app", Repo{"github.com", "acme", "app"}},
+		{"git@github.com:acme/app.git", Repo{"github.com", "acme", "app"}},
+		{"ssh://git@github.example.com:2222/acme/app.git", Repo{"github.example.com", "acme", "app"}},
+		{"https://gitlab.com/group/sub/app.git", Repo{"gitlab.com", "group/sub", "app"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRemote(tt.remote)
+		if err != nil {
+			t.Errorf("ParseRemote(%q) failed: %v", tt.remote, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRemote(%q) = %+v, want %+v", tt.remote, got, tt.want)
+		}
+	}
+
+	for _, remote := range []string{"", "/srv/git/app.git", "https://github.com/app"} {
+		if _, err := ParseRemote(remote); err == nil {
+			t.Errorf("ParseRemote(%q) succeeded, want an error", remote)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	orig := cliToken
+	cliToken = func(context.Context, string, ...string) string { return "from-cli" }
+	t.Cleanup(func() { cliToken = orig })
+
+	cfg := &config.Config{}
+	f, err := Detect(t.Context(), cfg, "git@github.com:acme/app.git")
+	if err != nil {
+		t.Fatalf("Detect failed: %v", err)
+	}
+	gh, ok := f.(*GitHub)
+	if !ok || gh.headers["Authorization"] != "Bearer from-cli" || gh.baseURL != "https://api.github.com" {
+		t.Errorf("Detect = %+v, want GitHub with the CLI token", f)
+	}
+
+	t.Setenv("GITLAB_TOKEN", "gl-env")
+	f, err = Detect(t.Context(), cfg, "git@gitlab.example.com:acme/platform/app.git")
+	if err != nil {
+		t.Fatalf("Detect for GitLab failed: %v", err)
+	}
+	gl, ok := f.(*GitLab)
+	if !ok || gl.headers["PRIVATE-TOKEN"] != "gl-env" || gl.baseURL != "https://gitlab.example.com/api/v4" {
+		t.Errorf("Detect = %+v, want GitLab with $GITLAB_TOKEN", f)
+	}
+
+	if _, err := Detect(t.Context(), cfg, "https://git.example.com/acme/app.git"); err == nil {
+		t.Error("Detect succeeded for an unknown host")
+	}
+
+	cfg.Forge = "github"
+	cfg.Forges = map[string]config.ForgeConfig{"github": {Token: "configured"}}
+	f, err = Detect(t.Context(), cfg, "https://git.example.com/acme/app.git")
+	if err != nil {
+		t.Fatalf("Detect with forge set failed: %v", err)
+	}
+	if gh := f.(*GitHub); gh.headers["Authorization"] != "Bearer configured" || gh.baseURL != "https://git.example.com/api/v3" {
+		t.Errorf("Detect = %+v, want GitHub Enterprise with the configured token", gh)
+	}
+}
+
+func TestIssueBranch(t *testing.T) {
+	tests := []struct {
+		issue Issue
+		want  string
+	}{
+		{Issue{Number: 42, Title: "Fix login redirect"}, "42-fix-login-redirect"},
+		{Issue{Number: 7, Title: "Crash on `lw add` (macOS)!"}, "7-crash-on-lw-add-macos"},
+		{Issue{Number: 9, Title: "🚀"}, "issue-9"},
+		{Issue{Number: 1, Title: "Support really long issue titles without producing unwieldy branches"}, "1-support-really-long-issue-titles-without"},
+		{Issue{Number: 2, Title: "Handle extraordinarily unmanageable configuration values"}, "2-handle-extraordinarily-unmanageable"},
+	}
+	for _, tt := range tests {
+		if got := IssueBranch(tt.issue); got != tt.want {
+			t.Errorf("IssueBranch(%q) = %q, want %q", tt.issue.Title, got, tt.want)
+		}
+	}
+}
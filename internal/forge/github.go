@@ -0,0 +1,205 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GitHub is the GitHub REST API, on github.com or GitHub Enterprise
+type GitHub struct {
+	apiClient
+	repo Repo
+}
+
+// NewGitHub returns a client for repo. An empty baseURL uses
+// api.github.com for github.com and https://<host>/api/v3 otherwise.
+func NewGitHub(repo Repo, baseURL, token string) *GitHub {
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+		if repo.Host != "github.com" {
+			baseURL = "https://" + repo.Host + "/api/v3"
+		}
+	}
+	headers := map[string]string{
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	return &GitHub{
+		apiClient: newAPIClient("GitHub", baseURL, headers),
+		repo:      repo,
+	}
+}
+
+func (g *GitHub) Name() string {
+	return "github"
+}
+
+func (g *GitHub) CreatePullRequest(ctx context.Context, in PullRequestInput) (*PullRequest, error) {
+	payload := map[string]interface{}{
+		"title": in.Title,
+		"body":  in.Body,
+		"head":  in.Head,
+		"base":  in.Base,
+		"draft": in.Draft,
+	}
+
+	var result struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+		State   string `json:"state"`
+		Draft   bool   `json:"draft"`
+	}
+	if err := g.do(ctx, "POST", g.repoPath("pulls"), payload, &result); err != nil {
+		return nil, err
+	}
+
+	return &PullRequest{
+		Number: result.Number,
+		Ref:    fmt.Sprintf("#%d", result.Number),
+		Title:  result.Title,
+		URL:    result.HTMLURL,
+		State:  result.State,
+		Draft:  result.Draft,
+	}, nil
+}
+
+// MergedPullRequest looks through the closed pull requests from head, as
+// GitHub has no merged state to filter on
+func (g *GitHub) MergedPullRequest(ctx context.Context, head, base string) (*PullRequest, error) {
+	var results []struct {
+		Number   int     `json:"number"`
+		Title    string  `json:"title"`
+		HTMLURL  string  `json:"html_url"`
+		MergedAt *string `json:"merged_at"`
+		Head     struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	query := url.Values{
+		"state":     {"closed"},
+		"head":      {g.repo.Owner + ":" + head},
+		"base":      {base},
+		"sort":      {"updated"},
+		"direction": {"desc"},
+		"per_page":  {"20"},
+	}
+	if err := g.do(ctx, "GET", g.repoPath("pulls?"+query.Encode()), nil, &results); err != nil {
+		return nil, err
+	}
+
+	for _, r := range results {
+		if r.MergedAt != nil {
+			return &PullRequest{
+				Number: r.Number,
+				Ref:    fmt.Sprintf("#%d", r.Number),
+				Title:  r.Title,
+				URL:    r.HTMLURL,
+				State:  PullRequestMerged,
+				Head:   r.Head.SHA,
+			}, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (g *GitHub) Issue(ctx context.Context, number int) (*Issue, error) {
+	var result struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+		State   string `json:"state"`
+	}
+	if err := g.do(ctx, "GET", g.repoPath(fmt.Sprintf("issues/%d", number)), nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &Issue{
+		Number: result.Number,
+		Title:  result.Title,
+		URL:    result.HTMLURL,
+		State:  result.State,
+	}, nil
+}
+
+func (g *GitHub) Checks(ctx context.Context, ref string) (*Checks, error) {
+	var result struct {
+		CheckRuns []struct {
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+		} `json:"check_runs"`
+	}
+	path := g.repoPath(fmt.Sprintf("commits/%s/check-runs?per_page=100", url.PathEscape(ref)))
+	if err := g.do(ctx, "GET", path, nil, &result); err != nil {
+		// Branches that were never pushed are unknown to GitHub
+		var apiErr *APIError
+		if errors.Is(err, ErrNotFound) || (errors.As(err, &apiErr) && apiErr.Status == http.StatusUnprocessableEntity) {
+			return &Checks{State: ChecksNone}, nil
+		}
+		return nil, err
+	}
+
+	checks := &Checks{Total: len(result.CheckRuns)}
+	for _, run := range result.CheckRuns {
+		switch {
+		case run.Status != "completed":
+			checks.Pending++
+		case run.Conclusion == "success", run.Conclusion == "neutral", run.Conclusion == "skipped":
+			checks.Passed++
+		default:
+			checks.Failed++
+		}
+	}
+	checks.State = checksState(checks)
+	return checks, nil
+}
+
+func (g *GitHub) CreateRelease(ctx context.Context, in ReleaseInput) (*Release, error) {
+	payload := map[string]interface{}{
+		"tag_name": in.Tag,
+		"name":     in.Name,
+		"body":     in.Notes,
+		"draft":    in.Draft,
+	}
+
+	var result struct {
+		TagName string `json:"tag_name"`
+		Name    string `json:"name"`
+		HTMLURL string `json:"html_url"`
+		Draft   bool   `json:"draft"`
+	}
+	if err := g.do(ctx, "POST", g.repoPath("releases"), payload, &result); err != nil {
+		return nil, err
+	}
+
+	return &Release{
+		Tag:   result.TagName,
+		Name:  result.Name,
+		URL:   result.HTMLURL,
+		Draft: result.Draft,
+	}, nil
+}
+
+// checksState derives the overall state from the counts
+func checksState(c *Checks) string {
+	switch {
+	case c.Total == 0:
+		return ChecksNone
+	case c.Failed > 0:
+		return ChecksFailure
+	case c.Pending > 0:
+		return ChecksPending
+	default:
+		return ChecksSuccess
+	}
+}
+
+func (g *GitHub) repoPath(path string) string {
+	return fmt.Sprintf("/repos/%s/%s/%s", g.repo.Owner, g.repo.Name, path)
+}
@@ -0,0 +1,147 @@
+package forge
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestGitHub(t *testing.T, handler http.HandlerFunc) *GitHub {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewGitHub(Repo{Host: "github.com", Owner: "acme", Name: "app"}, srv.URL, "secret")
+}
+
+func TestGitHubCreatePullRequest(t *testing.T) {
+	g := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/repos/acme/app/pulls" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["head"] != "feature" || body["base"] != "main" || body["draft"] != true {
+			t.Errorf("unexpected body %v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"number": 12, "title": "Add feature", "html_url": "https://github.com/acme/app/pull/12", "state": "open", "draft": true}`))
+	})
+
+	pr, err := g.CreatePullRequest(t.Context(), PullRequestInput{Title: "Add feature", Head: "feature", Base: "main", Draft: true})
+	if err != nil {
+		t.Fatalf("CreatePullRequest failed: %v", err)
+	}
+	if pr.Number != 12 || pr.URL != "https://github.com/acme/app/pull/12" || !pr.Draft {
+		t.Errorf("CreatePullRequest = %+v", pr)
+	}
+}
+
+func TestGitHubIssueNotFound(t *testing.T) {
+	g := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Not Found"}`))
+	})
+
+	_, err := g.Issue(t.Context(), 404)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Issue = %v, want ErrNotFound", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "Not Found" {
+		t.Errorf("error = %v, want the API message", err)
+	}
+}
+
+func TestGitHubChecks(t *testing.T) {
+	g := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/app/commits/feature/login/check-runs" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"total_count": 4, "check_runs": [
+			{"status": "completed", "conclusion": "success"},
+			{"status": "completed", "conclusion": "skipped"},
+			{"status": "completed", "conclusion": "failure"},
+			{"status": "in_progress", "conclusion": null}
+		]}`))
+	})
+
+	checks, err := g.Checks(t.Context(), "feature/login")
+	if err != nil {
+		t.Fatalf("Checks failed: %v", err)
+	}
+	want := Checks{State: ChecksFailure, Total: 4, Passed: 2, Failed: 1, Pending: 1}
+	if *checks != want {
+		t.Errorf("Checks = %+v, want %+v", *checks, want)
+	}
+}
+
+func TestGitHubChecksUnpushedBranch(t *testing.T) {
+	g := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message": "No commit found for SHA: local-only"}`))
+	})
+
+	checks, err := g.Checks(t.Context(), "local-only")
+	if err != nil {
+		t.Fatalf("Checks failed: %v", err)
+	}
+	if checks.State != ChecksNone {
+		t.Errorf("State = %q, want %q", checks.State, ChecksNone)
+	}
+}
+
+func TestGitHubCreateRelease(t *testing.T) {
+	g := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/repos/acme/app/releases" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["tag_name"] != "v1.2.0" || body["body"] != "### Features" || body["draft"] != true {
+			t.Errorf("unexpected body %v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"tag_name": "v1.2.0", "name": "v1.2.0", "html_url": "https://github.com/acme/app/releases/tag/v1.2.0", "draft": true}`))
+	})
+
+	release, err := g.CreateRelease(t.Context(), ReleaseInput{Tag: "v1.2.0", Name: "v1.2.0", Notes: "### Features", Draft: true})
+	if err != nil {
+		t.Fatalf("CreateRelease failed: %v", err)
+	}
+	if release.URL != "https://github.com/acme/app/releases/tag/v1.2.0" || !release.Draft {
+		t.Errorf("CreateRelease = %+v", release)
+	}
+}
+
+func TestGitHubMergedPullRequest(t *testing.T) {
+	g := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/repos/acme/app/pulls" || q.Get("head") != "acme:feature" || q.Get("base") != "main" || q.Get("state") != "closed" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`[
+			{"number": 13, "title": "Retry", "html_url": "https://github.com/acme/app/pull/13", "merged_at": null},
+			{"number": 12, "title": "Add feature", "html_url": "https://github.com/acme/app/pull/12", "merged_at": "2026-01-02T03:04:05Z", "head": {"sha": "abc123"}}
+		]`))
+	})
+
+	pr, err := g.MergedPullRequest(t.Context(), "feature", "main")
+	if err != nil {
+		t.Fatalf("MergedPullRequest failed: %v", err)
+	}
+	if pr.Number != 12 || pr.State != PullRequestMerged || pr.Head != "abc123" {
+		t.Errorf("MergedPullRequest = %+v, want the merged #12", pr)
+	}
+
+	g = newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"number": 13, "merged_at": null}]`))
+	})
+	if _, err := g.MergedPullRequest(t.Context(), "feature", "main"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("closed without merging: err = %v, want ErrNotFound", err)
+	}
+}
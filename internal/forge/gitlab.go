@@ -0,0 +1,199 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// GitLab is the GitLab REST API (v4), on gitlab.com or a self-managed
+// instance
+type GitLab struct {
+	apiClient
+	repo Repo
+}
+
+// NewGitLab returns a client for repo. An empty baseURL uses
+// https://<host>/api/v4.
+func NewGitLab(repo Repo, baseURL, token string) *GitLab {
+	if baseURL == "" {
+		baseURL = "https://" + repo.Host + "/api/v4"
+	}
+	return &GitLab{
+		apiClient: newAPIClient("GitLab", baseURL, map[string]string{"PRIVATE-TOKEN": token}),
+		repo:      repo,
+	}
+}
+
+func (g *GitLab) Name() string {
+	return "gitlab"
+}
+
+// gitLabRequest is the subset of a merge request or issue lazywork reads
+type gitLabRequest struct {
+	IID    int    `json:"iid"`
+	Title  string `json:"title"`
+	WebURL string `json:"web_url"`
+	State  string `json:"state"`
+	Draft  bool   `json:"draft"`
+}
+
+func (g *GitLab) CreatePullRequest(ctx context.Context, in PullRequestInput) (*PullRequest, error) {
+	title := in.Title
+	if in.Draft && !strings.HasPrefix(title, "Draft:") {
+		// Marking a merge request as draft through its title works on
+		// every GitLab version
+		title = "Draft: " + title
+	}
+	payload := map[string]interface{}{
+		"source_branch":        in.Head,
+		"target_branch":        in.Base,
+		"title":                title,
+		"description":          in.Body,
+		"remove_source_branch": true,
+	}
+
+	var result gitLabRequest
+	if err := g.do(ctx, "POST", g.projectPath("merge_requests"), payload, &result); err != nil {
+		return nil, err
+	}
+
+	return &PullRequest{
+		Number: result.IID,
+		Ref:    fmt.Sprintf("!%d", result.IID),
+		Title:  result.Title,
+		URL:    result.WebURL,
+		State:  result.State,
+		Draft:  result.Draft || in.Draft,
+	}, nil
+}
+
+func (g *GitLab) MergedPullRequest(ctx context.Context, head, base string) (*PullRequest, error) {
+	query := url.Values{
+		"state":         {"merged"},
+		"source_branch": {head},
+		"target_branch": {base},
+		"order_by":      {"updated_at"},
+		"per_page":      {"1"},
+	}
+	var results []struct {
+		gitLabRequest
+		SHA string `json:"sha"`
+	}
+	if err := g.do(ctx, "GET", g.projectPath("merge_requests?"+query.Encode()), nil, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &PullRequest{
+		Number: results[0].IID,
+		Ref:    fmt.Sprintf("!%d", results[0].IID),
+		Title:  results[0].Title,
+		URL:    results[0].WebURL,
+		State:  PullRequestMerged,
+		Head:   results[0].SHA,
+	}, nil
+}
+
+func (g *GitLab) Issue(ctx context.Context, number int) (*Issue, error) {
+	var result gitLabRequest
+	if err := g.do(ctx, "GET", g.projectPath(fmt.Sprintf("issues/%d", number)), nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &Issue{
+		Number: result.IID,
+		Title:  result.Title,
+		URL:    result.WebURL,
+		State:  result.State,
+	}, nil
+}
+
+// Checks reports the latest pipeline for ref, counting its jobs
+func (g *GitLab) Checks(ctx context.Context, ref string) (*Checks, error) {
+	var pipelines []struct {
+		ID     int    `json:"id"`
+		Status string `json:"status"`
+	}
+	path := g.projectPath("pipelines?per_page=1&ref=" + url.QueryEscape(ref))
+	if err := g.do(ctx, "GET", path, nil, &pipelines); err != nil {
+		return nil, err
+	}
+	if len(pipelines) == 0 {
+		return &Checks{State: ChecksNone}, nil
+	}
+
+	var jobs []struct {
+		Status       string `json:"status"`
+		AllowFailure bool   `json:"allow_failure"`
+	}
+	path = g.projectPath(fmt.Sprintf("pipelines/%d/jobs?per_page=100", pipelines[0].ID))
+	if err := g.do(ctx, "GET", path, nil, &jobs); err != nil {
+		return nil, err
+	}
+
+	checks := &Checks{Total: len(jobs)}
+	for _, job := range jobs {
+		switch job.Status {
+		case "success", "skipped", "manual":
+			checks.Passed++
+		case "failed", "canceled":
+			if job.AllowFailure {
+				checks.Passed++
+			} else {
+				checks.Failed++
+			}
+		default:
+			checks.Pending++
+		}
+	}
+
+	// The pipeline status is authoritative, e.g. for pipelines without jobs
+	switch pipelines[0].Status {
+	case "success", "skipped", "manual":
+		checks.State = ChecksSuccess
+	case "failed", "canceled":
+		checks.State = ChecksFailure
+	default:
+		checks.State = ChecksPending
+	}
+	return checks, nil
+}
+
+// CreateRelease publishes a release. GitLab has no draft releases.
+func (g *GitLab) CreateRelease(ctx context.Context, in ReleaseInput) (*Release, error) {
+	if in.Draft {
+		return nil, fmt.Errorf("GitLab has no draft releases")
+	}
+	payload := map[string]interface{}{
+		"tag_name":    in.Tag,
+		"name":        in.Name,
+		"description": in.Notes,
+	}
+
+	var result struct {
+		TagName string `json:"tag_name"`
+		Name    string `json:"name"`
+		Links   struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	}
+	if err := g.do(ctx, "POST", g.projectPath("releases"), payload, &result); err != nil {
+		return nil, err
+	}
+
+	return &Release{
+		Tag:  result.TagName,
+		Name: result.Name,
+		URL:  result.Links.Self,
+	}, nil
+}
+
+// projectPath returns the API path of a project resource; the project is
+// addressed by its URL-encoded path, e.g. group%2Fsub%2Fapp
+func (g *GitLab) projectPath(path string) string {
+	return fmt.Sprintf("/projects/%s/%s", url.PathEscape(g.repo.Path()), path)
+}
@@ -0,0 +1,146 @@
+package forge
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestGitLab(t *testing.T, handler http.HandlerFunc) *GitLab {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewGitLab(Repo{Host: "gitlab.com", Owner: "acme/platform", Name: "app"}, srv.URL, "secret")
+}
+
+func TestGitLabCreateMergeRequest(t *testing.T) {
+	g := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.EscapedPath() != "/projects/acme%2Fplatform%2Fapp/merge_requests" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "secret" {
+			t.Errorf("PRIVATE-TOKEN = %q", got)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["source_branch"] != "feature" || body["target_branch"] != "main" || body["title"] != "Draft: Add feature" {
+			t.Errorf("unexpected body %v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"iid": 5, "title": "Draft: Add feature", "web_url": "https://gitlab.com/acme/platform/app/-/merge_requests/5", "state": "opened"}`))
+	})
+
+	mr, err := g.CreatePullRequest(t.Context(), PullRequestInput{Title: "Add feature", Head: "feature", Base: "main", Draft: true})
+	if err != nil {
+		t.Fatalf("CreatePullRequest failed: %v", err)
+	}
+	if mr.Number != 5 || mr.Ref != "!5" || !mr.Draft {
+		t.Errorf("CreatePullRequest = %+v", mr)
+	}
+}
+
+func TestGitLabIssue(t *testing.T) {
+	g := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/acme%2Fplatform%2Fapp/issues/42" {
+			t.Errorf("unexpected path %s", r.URL.EscapedPath())
+		}
+		w.Write([]byte(`{"iid": 42, "title": "Fix login redirect", "web_url": "https://gitlab.com/acme/platform/app/-/issues/42", "state": "opened"}`))
+	})
+
+	issue, err := g.Issue(t.Context(), 42)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if issue.Number != 42 || issue.Title != "Fix login redirect" {
+		t.Errorf("Issue = %+v", issue)
+	}
+}
+
+func TestGitLabChecks(t *testing.T) {
+	g := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects/acme/platform/app/pipelines":
+			if got := r.URL.Query().Get("ref"); got != "feature/login" {
+				t.Errorf("ref = %q", got)
+			}
+			w.Write([]byte(`[{"id": 99, "status": "running"}]`))
+		case "/projects/acme/platform/app/pipelines/99/jobs":
+			w.Write([]byte(`[
+				{"status": "success"},
+				{"status": "failed", "allow_failure": true},
+				{"status": "running"}
+			]`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+
+	checks, err := g.Checks(t.Context(), "feature/login")
+	if err != nil {
+		t.Fatalf("Checks failed: %v", err)
+	}
+	want := Checks{State: ChecksPending, Total: 3, Passed: 2, Pending: 1}
+	if *checks != want {
+		t.Errorf("Checks = %+v, want %+v", *checks, want)
+	}
+}
+
+func TestGitLabChecksNoPipeline(t *testing.T) {
+	g := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+
+	checks, err := g.Checks(t.Context(), "local-only")
+	if err != nil {
+		t.Fatalf("Checks failed: %v", err)
+	}
+	if checks.State != ChecksNone {
+		t.Errorf("State = %q, want %q", checks.State, ChecksNone)
+	}
+}
+
+func TestGitLabCreateRelease(t *testing.T) {
+	g := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.EscapedPath() != "/projects/acme%2Fplatform%2Fapp/releases" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["tag_name"] != "v1.2.0" || body["description"] != "### Fixes" {
+			t.Errorf("unexpected body %v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"tag_name": "v1.2.0", "name": "v1.2.0", "_links": {"self": "https://gitlab.com/acme/platform/app/-/releases/v1.2.0"}}`))
+	})
+
+	release, err := g.CreateRelease(t.Context(), ReleaseInput{Tag: "v1.2.0", Name: "v1.2.0", Notes: "### Fixes"})
+	if err != nil {
+		t.Fatalf("CreateRelease failed: %v", err)
+	}
+	if release.URL != "https://gitlab.com/acme/platform/app/-/releases/v1.2.0" {
+		t.Errorf("CreateRelease = %+v", release)
+	}
+
+	if _, err := g.CreateRelease(t.Context(), ReleaseInput{Tag: "v1.2.0", Draft: true}); err == nil {
+		t.Error("draft release succeeded on GitLab")
+	}
+}
+
+func TestGitLabMergedPullRequest(t *testing.T) {
+	g := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != "merged" || q.Get("source_branch") != "feature" || q.Get("target_branch") != "main" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`[{"iid": 5, "title": "Add feature", "web_url": "https://gitlab.com/acme/platform/app/-/merge_requests/5", "state": "merged", "sha": "abc123"}]`))
+	})
+
+	mr, err := g.MergedPullRequest(t.Context(), "feature", "main")
+	if err != nil {
+		t.Fatalf("MergedPullRequest failed: %v", err)
+	}
+	if mr.Ref != "!5" || mr.State != PullRequestMerged || mr.Head != "abc123" {
+		t.Errorf("MergedPullRequest = %+v", mr)
+	}
+}
@@ -0,0 +1,96 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// apiClient sends JSON requests to a forge's REST API
+type apiClient struct {
+	// forge names the service in errors, e.g. "GitHub"
+	forge   string
+	baseURL string
+	// headers are set on every request, e.g. for authentication
+	headers map[string]string
+	client  *http.Client
+}
+
+func newAPIClient(forge, baseURL string, headers map[string]string) apiClient {
+	return apiClient{
+		forge:   forge,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		headers: headers,
+		client:  &http.Client{},
+	}
+}
+
+// do sends a request to the API and decodes the JSON response into result
+func (c *apiClient) do(ctx context.Context, method, path string, payload, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range c.headers {
+		if value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return &APIError{Forge: c.forge, Status: resp.StatusCode, Message: errorMessage(data)}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// errorMessage extracts the message from an error response, which is
+// {"message": "..."} on GitHub and {"message": ...} or {"error": "..."} on
+// GitLab, falling back to the raw body
+func errorMessage(data []byte) string {
+	var body struct {
+		Message interface{} `json:"message"`
+		Error   string      `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil {
+		switch m := body.Message.(type) {
+		case string:
+			return m
+		case nil:
+		default:
+			if encoded, err := json.Marshal(m); err == nil {
+				return string(encoded)
+			}
+		}
+		if body.Error != "" {
+			return body.Error
+		}
+	}
+	return strings.TrimSpace(string(data))
+}
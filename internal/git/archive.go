@@ -0,0 +1,85 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CountCommits returns the number of commits in head that are not in base
+func CountCommits(ctx context.Context, base, head string) (int, error) {
+	output, err := runGit(ctx, "rev-list", "--count", base+".."+head, "--")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected rev-list output: %q", output)
+	}
+	return n, nil
+}
+
+// CreateBundle writes the commits of the worktree at path that are not in
+// base to the bundle file, under the ref HEAD
+func CreateBundle(ctx context.Context, path, file, base string) error {
+	_, err := runGit(ctx, "-C", path, "bundle", "create", "--quiet", file, "HEAD", "^"+base)
+	return err
+}
+
+// FetchBundle fetches the commits of a bundle made by CreateBundle into the
+// repository, without creating any ref
+func FetchBundle(ctx context.Context, file string) error {
+	_, err := runGit(ctx, "fetch", "--quiet", file, "HEAD")
+	return err
+}
+
+// UntrackedFiles returns the untracked files of the worktree at path,
+// relative to it, leaving out ignored ones
+func UntrackedFiles(ctx context.Context, path string) ([]string, error) {
+	output, err := runGit(ctx, "-C", path, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, f := range strings.Split(output, "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// TrackedFiles returns the files git tracks in the worktree at path,
+// relative to it
+func TrackedFiles(ctx context.Context, path string) ([]string, error) {
+	output, err := runGit(ctx, "-C", path, "ls-files", "-z")
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, f := range strings.Split(output, "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// UncommittedDiff returns the staged and unstaged changes to tracked files
+// in the worktree at path, as a binary patch ApplyPatch can apply
+func UncommittedDiff(ctx context.Context, path string) (string, error) {
+	return runGit(ctx, "-C", path, "diff", "HEAD", "--binary")
+}
+
+// ApplyPatch applies the patch file to the worktree at path
+func ApplyPatch(ctx context.Context, path, file string) error {
+	_, err := runGit(ctx, "-C", path, "apply", "--whitespace=nowarn", file)
+	return err
+}
+
+// CreateBranch creates branch name at start without checking it out
+func CreateBranch(ctx context.Context, name, start string) error {
+	_, err := runGit(ctx, "branch", name, start)
+	return err
+}
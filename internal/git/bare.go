@@ -0,0 +1,97 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BareDir is where CloneBare puts the bare repository inside the clone
+// directory
+const BareDir = ".bare"
+
+// IsBareLayout reports whether worktrees, as returned by ListWorktrees,
+// belong to a bare repository. In that layout every branch is checked out
+// in its own worktree and there is no main checkout.
+func IsBareLayout(worktrees []Worktree) bool {
+	return len(worktrees) > 0 && worktrees[0].Bare
+}
+
+// BareRoot returns the directory that holds the worktrees of the bare
+// repository at bareDir: the parent of a "repo/.bare" or "repo.git"
+// directory
+func BareRoot(bareDir string) string {
+	return filepath.Dir(filepath.Clean(bareDir))
+}
+
+// Clone clones url into dir with a regular checkout
+func Clone(ctx context.Context, url, dir string) error {
+	_, err := runGit(ctx, "clone", url, dir)
+	return err
+}
+
+// RepoNameFromURL returns the directory name git would clone url into,
+// e.g. "repo" for "git@github.com:org/repo.git"
+func RepoNameFromURL(url string) string {
+	name := strings.TrimRight(url, "/")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, ".git")
+}
+
+// CloneResult describes a repository set up by CloneBare
+type CloneResult struct {
+	Path     string `json:"path"`
+	BareDir  string `json:"bare_dir"`
+	Branch   string `json:"branch"`
+	Worktree string `json:"worktree"`
+}
+
+// CloneBare clones url into dir using the bare layout: the repository
+// lives in dir/.bare, dir/.git points to it so git commands work from dir,
+// and the default branch is checked out as a worktree in dir/<branch>.
+func CloneBare(ctx context.Context, url, dir string) (*CloneResult, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("destination '%s' already exists and is not empty", dir)
+	}
+
+	bareDir := filepath.Join(dir, BareDir)
+	if _, err := runGit(ctx, "clone", "--bare", url, bareDir); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git"), []byte("gitdir: ./"+BareDir+"\n"), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write .git file: %w", err)
+	}
+
+	// Bare clones don't fetch remote-tracking branches; restore the usual
+	// refspec so worktrees can track origin/<branch>
+	if _, err := runGit(ctx, "-C", dir, "config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(ctx, "-C", dir, "fetch", "origin"); err != nil {
+		return nil, err
+	}
+
+	output, err := runGit(ctx, "-C", dir, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	branch := strings.TrimSpace(output)
+
+	worktree := filepath.Join(dir, strings.ReplaceAll(branch, "/", "-"))
+	if _, err := runGit(ctx, "-C", dir, "worktree", "add", worktree, branch); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(ctx, "-C", worktree, "branch", "--set-upstream-to=origin/"+branch); err != nil {
+		return nil, err
+	}
+
+	return &CloneResult{Path: dir, BareDir: bareDir, Branch: branch, Worktree: worktree}, nil
+}
@@ -0,0 +1,70 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepoNameFromURL(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:org/app.git":     "app",
+		"https://github.com/org/app.git": "app",
+		"https://github.com/org/app/":    "app",
+		"/src/app":                       "app",
+	}
+	for url, want := range tests {
+		if got := RepoNameFromURL(url); got != want {
+			t.Errorf("RepoNameFromURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestCloneBare(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+	branch := GetMainBranch(ctx)
+
+	dir := filepath.Join(t.TempDir(), "clone")
+	result, err := CloneBare(ctx, repo.dir, dir)
+	if err != nil {
+		t.Fatalf("CloneBare failed: %v", err)
+	}
+	if result.Branch != branch || result.Worktree != filepath.Join(dir, branch) {
+		t.Errorf("result = %+v, want %s checked out in %s", result, branch, dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, BareDir, "HEAD")); err != nil {
+		t.Errorf("bare repository missing: %v", err)
+	}
+
+	if err := os.Chdir(result.Worktree); err != nil {
+		t.Fatal(err)
+	}
+
+	worktrees, err := ListWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("ListWorktrees failed: %v", err)
+	}
+	if !IsBareLayout(worktrees) {
+		t.Errorf("expected bare layout, got %+v", worktrees)
+	}
+	if secondary := SecondaryWorktrees(worktrees); len(secondary) != 1 || secondary[0].Branch != branch {
+		t.Errorf("SecondaryWorktrees = %+v, want the %s worktree", secondary, branch)
+	}
+	if IsMainWorktree(ctx) {
+		t.Error("a worktree of a bare repository is not the main worktree")
+	}
+	if !BranchExists(ctx, branch) || HasUncommittedChanges(ctx) {
+		t.Error("expected a clean checkout of the default branch")
+	}
+
+	main, err := MainWorktreePath(ctx)
+	if err != nil || !samePath(main, dir) {
+		t.Errorf("MainWorktreePath = %q, %v; want %s", main, err, dir)
+	}
+	path, err := GetWorktreePath(ctx, ".", "feature")
+	if err != nil || !samePath(filepath.Dir(path), dir) {
+		t.Errorf("GetWorktreePath = %q, %v; want it inside %s", path, err, dir)
+	}
+}
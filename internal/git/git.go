@@ -1,12 +1,16 @@
 package git
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/paths"
 )
 
 type Worktree struct {
@@ -16,29 +20,35 @@ type Worktree struct {
 	Bare   bool   `json:"bare,omitempty"`
 }
 
-func IsInsideWorkTree() bool {
-	_, err := runGit("rev-parse", "--is-inside-work-tree")
+func IsInsideWorkTree(ctx context.Context) bool {
+	_, err := runGit(ctx, "rev-parse", "--is-inside-work-tree")
 	return err == nil
 }
 
-func GetRepoRoot() (string, error) {
-	output, err := runGit("rev-parse", "--show-toplevel")
+func GetRepoRoot(ctx context.Context) (string, error) {
+	output, err := runGit(ctx, "rev-parse", "--show-toplevel")
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(output), nil
+	return nativePath(strings.TrimSpace(output)), nil
+}
+
+// nativePath converts a path printed by git to the platform's form; git
+// for Windows prints C:/src/app
+func nativePath(p string) string {
+	return paths.Normalize(runtime.GOOS, p)
 }
 
-func CurrentBranch() (string, error) {
-	output, err := runGit("rev-parse", "--abbrev-ref", "HEAD")
+func CurrentBranch(ctx context.Context) (string, error) {
+	output, err := runGit(ctx, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(output), nil
 }
 
-func ListWorktrees() ([]Worktree, error) {
-	output, err := runGit("worktree", "list", "--porcelain")
+func ListWorktrees(ctx context.Context) ([]Worktree, error) {
+	output, err := runGit(ctx, "worktree", "list", "--porcelain")
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +68,7 @@ func ListWorktrees() ([]Worktree, error) {
 
 		if strings.HasPrefix(line, "worktree ") {
 			current = &Worktree{
-				Path: strings.TrimPrefix(line, "worktree "),
+				Path: nativePath(strings.TrimPrefix(line, "worktree ")),
 			}
 		} else if strings.HasPrefix(line, "HEAD ") && current != nil {
 			current.Head = strings.TrimPrefix(line, "HEAD ")
@@ -79,123 +89,849 @@ func ListWorktrees() ([]Worktree, error) {
 	return worktrees, nil
 }
 
-func AddWorktree(path, branch string) error {
-	_, err := runGit("worktree", "add", path, "-b", branch)
+// MainWorktreePath returns the path of the main worktree, which git always
+// lists first. In a bare layout, where there is no main checkout, it is the
+// directory holding the bare repository (see BareRoot).
+func MainWorktreePath(ctx context.Context) (string, error) {
+	worktrees, err := ListWorktrees(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(worktrees) == 0 {
+		return "", fmt.Errorf("no worktrees found")
+	}
+	return MainPath(worktrees), nil
+}
+
+// MainPath is MainWorktreePath for an already listed, non-empty set of
+// worktrees
+func MainPath(worktrees []Worktree) string {
+	if IsBareLayout(worktrees) {
+		return BareRoot(worktrees[0].Path)
+	}
+	return worktrees[0].Path
+}
+
+func AddWorktree(ctx context.Context, path, branch string) error {
+	_, err := runGit(ctx, "worktree", "add", path, "-b", branch)
+	return err
+}
+
+func AddWorktreeFromBranch(ctx context.Context, path, branch string) error {
+	_, err := runGit(ctx, "worktree", "add", path, branch)
+	return err
+}
+
+// AddDetachedWorktree creates a worktree at path with rev checked out in
+// detached HEAD
+func AddDetachedWorktree(ctx context.Context, path, rev string) error {
+	_, err := runGit(ctx, "worktree", "add", "--detach", path, rev)
+	return err
+}
+
+// AddSparseWorktree creates a worktree at path for branch (a new branch if
+// newBranch is set) with a cone-mode sparse checkout, so only files at the
+// root and inside dirs are materialized. If the sparse checkout fails the
+// half-created worktree is removed again.
+func AddSparseWorktree(ctx context.Context, path, branch string, newBranch bool, dirs []string) error {
+	args := []string{"worktree", "add", "--no-checkout", path}
+	if newBranch {
+		args = append(args, "-b", branch)
+	} else {
+		args = append(args, branch)
+	}
+	if _, err := runGit(ctx, args...); err != nil {
+		return err
+	}
+
+	err := sparseCheckout(ctx, path, dirs)
+	if err != nil {
+		RemoveWorktree(context.WithoutCancel(ctx), path, true)
+	}
 	return err
 }
 
-func AddWorktreeFromBranch(path, branch string) error {
-	_, err := runGit("worktree", "add", path, branch)
+func sparseCheckout(ctx context.Context, path string, dirs []string) error {
+	args := []string{"-C", path, "sparse-checkout", "set", "--cone", "--"}
+	args = append(args, SparseDirs(dirs)...)
+	if _, err := runGit(ctx, args...); err != nil {
+		return err
+	}
+	_, err := runGit(ctx, "-C", path, "checkout")
 	return err
 }
 
-func RemoveWorktree(path string, force bool) error {
+// SparseDirs normalizes sparse-checkout directories to slash-separated
+// paths relative to the repository root, dropping empty entries
+func SparseDirs(dirs []string) []string {
+	var out []string
+	for _, dir := range dirs {
+		dir = strings.Trim(filepath.ToSlash(strings.TrimSpace(dir)), "/")
+		if dir != "" && dir != "." {
+			out = append(out, dir)
+		}
+	}
+	return out
+}
+
+func RemoveWorktree(ctx context.Context, path string, force bool) error {
 	args := []string{"worktree", "remove", path}
 	if force {
 		args = append(args, "--force")
 	}
-	_, err := runGit(args...)
+	_, err := runGit(ctx, args...)
+	return err
+}
+
+// HasSubmodules reports whether the worktree at path declares submodules
+func HasSubmodules(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".gitmodules"))
+	return err == nil
+}
+
+// UpdateSubmodules initializes and checks out all submodules, recursively,
+// in the worktree at path
+func UpdateSubmodules(ctx context.Context, path string) error {
+	_, err := runGit(ctx, "-C", path, "submodule", "update", "--init", "--recursive")
 	return err
 }
 
-func PruneWorktrees() error {
-	_, err := runGit("worktree", "prune")
+func PruneWorktrees(ctx context.Context) error {
+	_, err := runGit(ctx, "worktree", "prune")
 	return err
 }
 
-func GetStagedDiff() (string, error) {
-	return runGit("diff", "--staged")
+func GetStagedDiff(ctx context.Context) (string, error) {
+	return runGit(ctx, "diff", "--staged")
+}
+
+func GetUnstagedDiff(ctx context.Context) (string, error) {
+	return runGit(ctx, "diff")
 }
 
-func GetUnstagedDiff() (string, error) {
-	return runGit("diff")
+// WorkingTreeDiff returns the staged and unstaged changes to tracked files
+// against HEAD, for reading; see UncommittedDiff for one to apply
+func WorkingTreeDiff(ctx context.Context) (string, error) {
+	return runGit(ctx, "diff", "--color=never", "HEAD", "--")
 }
 
-func Commit(message string) error {
-	_, err := runGit("commit", "-m", message)
+func Commit(ctx context.Context, message string) error {
+	_, err := runGit(ctx, "commit", "-m", message)
 	return err
 }
 
-func BranchExists(name string) bool {
-	_, err := runGit("rev-parse", "--verify", "refs/heads/"+name)
+// Amend replaces the last commit with one including the staged changes
+// and the given message
+func Amend(ctx context.Context, message string) error {
+	_, err := runGit(ctx, "commit", "--amend", "-m", message)
+	return err
+}
+
+// CommitMessage returns the full message of the commit at ref
+func CommitMessage(ctx context.Context, ref string) (string, error) {
+	output, err := runGit(ctx, "log", "-1", "--format=%B", ref, "--")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// ShortHash returns the abbreviated hash of the commit at ref
+func ShortHash(ctx context.Context, ref string) (string, error) {
+	output, err := runGit(ctx, "rev-parse", "--short", ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// CommitSubject returns the subject line of the commit at ref
+func CommitSubject(ctx context.Context, ref string) (string, error) {
+	output, err := runGit(ctx, "log", "-1", "--format=%s", ref, "--")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// RemoteURL returns the URL of the named remote, e.g. origin
+func RemoteURL(ctx context.Context, remote string) (string, error) {
+	output, err := runGit(ctx, "remote", "get-url", remote)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// Push pushes branch to remote and sets it as the branch's upstream
+func Push(ctx context.Context, remote, branch string) error {
+	_, err := runGit(ctx, "push", "--set-upstream", remote, branch)
+	return err
+}
+
+// PushForce pushes branch to remote like Push, replacing its history there
+// unless someone else pushed to it since it was last fetched
+func PushForce(ctx context.Context, remote, branch string) error {
+	_, err := runGit(ctx, "push", "--force-with-lease", "--set-upstream", remote, branch)
+	return err
+}
+
+// FetchPrune fetches from the remote and deletes remote-tracking branches
+// that no longer exist there
+func FetchPrune(ctx context.Context) error {
+	_, err := runGit(ctx, "fetch", "--prune", "--quiet")
+	return err
+}
+
+// ListBranches returns local branch names, plus remote-tracking branches
+// (e.g. origin/feature) when includeRemote is true
+func ListBranches(ctx context.Context, includeRemote bool) ([]string, error) {
+	refs := []string{"refs/heads"}
+	if includeRemote {
+		refs = append(refs, "refs/remotes")
+	}
+	args := append([]string{"for-each-ref", "--format=%(refname)"}, refs...)
+	output, err := runGit(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	for _, ref := range strings.Split(output, "\n") {
+		ref = strings.TrimSpace(ref)
+		switch {
+		case ref == "", strings.HasSuffix(ref, "/HEAD"):
+			continue
+		case strings.HasPrefix(ref, "refs/heads/"):
+			branches = append(branches, strings.TrimPrefix(ref, "refs/heads/"))
+		case strings.HasPrefix(ref, "refs/remotes/"):
+			branches = append(branches, strings.TrimPrefix(ref, "refs/remotes/"))
+		}
+	}
+	return branches, nil
+}
+
+func BranchExists(ctx context.Context, name string) bool {
+	_, err := runGit(ctx, "rev-parse", "--verify", "refs/heads/"+name)
 	return err == nil
 }
 
-// baseDir is relative to repo root (e.g., ".worktrees")
-func GetWorktreePath(baseDir, name string) (string, error) {
-	root, err := GetRepoRoot()
+// GetWorktreePath returns the path for a new worktree called name.
+// baseDir is relative to the repo root (e.g., ".worktrees") unless it is
+// absolute; see ExpandWorktreeDir for the supported placeholders. In a bare
+// layout the root is the directory holding the bare repository.
+func GetWorktreePath(ctx context.Context, baseDir, name string) (string, error) {
+	root, err := GetRepoRoot(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	repoName := filepath.Base(root)
+	if worktrees, err := ListWorktrees(ctx); err == nil && len(worktrees) > 0 {
+		main := worktrees[0].Path
+		if IsBareLayout(worktrees) {
+			main = BareRoot(main)
+			root = main
+		}
+		repoName = filepath.Base(main)
+	}
+
+	dir, err := ExpandWorktreeDir(baseDir, root, repoName)
 	if err != nil {
 		return "", err
 	}
+	return filepath.Join(dir, name), nil
+}
+
+// ExpandWorktreeDir resolves a worktree_dir setting: a leading "~" expands
+// to the home directory and "{repo}" to the repository name, so checkouts
+// can live outside the repository (e.g. "~/worktrees/{repo}"). Relative
+// dirs are joined to root.
+func ExpandWorktreeDir(baseDir, root, repoName string) (string, error) {
+	dir := strings.ReplaceAll(baseDir, "{repo}", repoName)
+
+	if rest, ok := paths.HomeRelative(runtime.GOOS, dir); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot expand ~ in worktree_dir: %w", err)
+		}
+		dir = filepath.Join(home, rest)
+	}
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// SecondaryWorktrees returns the non-bare worktrees other than the main
+// one, wherever they are located
+func SecondaryWorktrees(worktrees []Worktree) []Worktree {
+	var secondary []Worktree
+	for i, wt := range worktrees {
+		if i == 0 || wt.Bare {
+			continue
+		}
+		secondary = append(secondary, wt)
+	}
+	return secondary
+}
 
-	return filepath.Join(root, baseDir, name), nil
+func HasUncommittedChanges(ctx context.Context) bool {
+	output, err := runGit(ctx, "status", "--porcelain")
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(output) != ""
 }
 
-func HasUncommittedChanges() bool {
-	output, err := runGit("status", "--porcelain")
+// HasUncommittedChangesIn reports uncommitted changes for the worktree at path
+func HasUncommittedChangesIn(ctx context.Context, path string) bool {
+	output, err := runGit(ctx, "-C", path, "status", "--porcelain")
 	if err != nil {
 		return false
 	}
 	return strings.TrimSpace(output) != ""
 }
 
-func Checkout(branch string) error {
-	_, err := runGit("checkout", branch)
+// Checkout switches the current worktree to branch. The branch may also be
+// checked out in another worktree, which is what 'worktree use' is for.
+func Checkout(ctx context.Context, branch string) error {
+	_, err := runGit(ctx, "checkout", "--ignore-other-worktrees", branch)
 	return err
 }
 
-// Stash saves uncommitted changes and returns the stash reference
-func Stash(message string) (string, error) {
+// Stash saves uncommitted changes and returns the commit hash of the new
+// stash, which unlike stash@{n} stays valid as other stashes come and go
+func Stash(ctx context.Context, message string) (string, error) {
 	args := []string{"stash", "push"}
 	if message != "" {
 		args = append(args, "-m", message)
 	}
-	_, err := runGit(args...)
+	_, err := runGit(ctx, args...)
 	if err != nil {
 		return "", err
 	}
-	// Get the stash reference
-	output, err := runGit("stash", "list", "-1")
+	output, err := runGit(ctx, "rev-parse", "--verify", "stash@{0}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+func StashPop(ctx context.Context) error {
+	_, err := runGit(ctx, "stash", "pop")
+	return err
+}
+
+func Merge(ctx context.Context, branch string) error {
+	_, err := runGit(ctx, "merge", branch)
+	return err
+}
+
+// MergeWithMessage merges branch into the current branch, using message
+// for the merge commit if one is created
+func MergeWithMessage(ctx context.Context, branch, message string) error {
+	_, err := runGit(ctx, "merge", "-m", message, branch)
+	return err
+}
+
+// MergeSquash stages the changes of branch on top of the current branch,
+// to be committed as a single commit. git prepares the message, which
+// CommitMerge uses.
+func MergeSquash(ctx context.Context, branch string) error {
+	_, err := runGit(ctx, "merge", "--squash", branch)
+	return err
+}
+
+// MergeAbort abandons a merge that stopped on conflicts
+func MergeAbort(ctx context.Context) error {
+	_, err := runGit(ctx, "merge", "--abort")
+	return err
+}
+
+// ResetHard moves the current branch to rev, discarding the changes in the
+// index and the working tree
+func ResetHard(ctx context.Context, rev string) error {
+	_, err := runGit(ctx, "reset", "--hard", "--quiet", rev)
+	return err
+}
+
+// ResetSoft moves the current branch to rev, keeping the changes of the
+// commits left behind staged
+func ResetSoft(ctx context.Context, rev string) error {
+	_, err := runGit(ctx, "reset", "--soft", "--quiet", rev)
+	return err
+}
+
+// ResetKeep moves the branch checked out in the worktree at path to rev,
+// failing rather than losing uncommitted changes
+func ResetKeep(ctx context.Context, path, rev string) error {
+	_, err := runGit(ctx, "-C", path, "reset", "--keep", "--quiet", rev)
+	return err
+}
+
+// MoveBranch points branch, which must not be checked out, at rev
+func MoveBranch(ctx context.Context, branch, rev string) error {
+	_, err := runGit(ctx, "branch", "--force", branch, rev)
+	return err
+}
+
+// UpdateRef points ref at commit, e.g. to keep it from being pruned
+func UpdateRef(ctx context.Context, ref, commit string) error {
+	_, err := runGit(ctx, "update-ref", ref, commit)
+	return err
+}
+
+// DeleteRef deletes ref
+func DeleteRef(ctx context.Context, ref string) error {
+	_, err := runGit(ctx, "update-ref", "-d", ref)
+	return err
+}
+
+// MergeConflicts returns the files that would conflict when merging theirs
+// into ours, without touching the working tree or the index. It needs git
+// 2.38 or later for merge-tree --write-tree.
+func MergeConflicts(ctx context.Context, ours, theirs string) ([]string, error) {
+	output, err := runGit(ctx, "merge-tree", "--write-tree", "--name-only", "--no-messages", ours, theirs)
+	// The first line is the merged tree; conflicted files follow, with a
+	// failing exit status. A real failure prints no tree at all.
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if err != nil && (len(lines) < 2 || lines[0] == "") {
+		return nil, err
+	}
+	var files []string
+	for _, line := range lines[1:] {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// ConflictedFiles returns the unmerged files, relative to the repository
+// root
+func ConflictedFiles(ctx context.Context) ([]string, error) {
+	return ConflictedFilesIn(ctx, ".")
+}
+
+// ConflictedFilesIn returns the unmerged files of the worktree at path
+func ConflictedFilesIn(ctx context.Context, path string) ([]string, error) {
+	output, err := runGit(ctx, "-C", path, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// Add stages the given paths
+func Add(ctx context.Context, paths ...string) error {
+	_, err := runGit(ctx, append([]string{"add", "--"}, paths...)...)
+	return err
+}
+
+// StageAll stages changes to tracked files, including deletions, like
+// 'git commit -a'. With untracked set, new files are staged as well.
+func StageAll(ctx context.Context, untracked bool) error {
+	flag := "--update"
+	if untracked {
+		flag = "--all"
+	}
+	_, err := runGit(ctx, "add", flag)
+	return err
+}
+
+// WriteTree saves the index as a tree and returns its hash, so it can be
+// put back with ReadTree
+func WriteTree(ctx context.Context) (string, error) {
+	output, err := runGit(ctx, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// ReadTree replaces the index with tree, leaving the working tree alone
+func ReadTree(ctx context.Context, tree string) error {
+	_, err := runGit(ctx, "read-tree", tree)
+	return err
+}
+
+// MergeInProgress reports whether a merge is waiting to be committed
+func MergeInProgress(ctx context.Context) bool {
+	_, err := runGit(ctx, "rev-parse", "-q", "--verify", "MERGE_HEAD")
+	return err == nil
+}
+
+// CommitMerge commits a resolved merge with git's prepared message
+func CommitMerge(ctx context.Context) error {
+	_, err := runGit(ctx, "commit", "--no-edit")
+	return err
+}
+
+// CommitFixup commits the staged changes as a "fixup!" commit for commit,
+// for a later autosquash rebase to fold into it
+func CommitFixup(ctx context.Context, commit string) error {
+	_, err := runGit(ctx, "commit", "--fixup="+commit)
+	return err
+}
+
+// RebaseAutosquash rebases the current branch onto upstream, folding
+// "fixup!" and "squash!" commits into the commits they name without
+// opening an editor. An empty upstream rebases the whole history.
+// Uncommitted changes are stashed for the duration.
+func RebaseAutosquash(ctx context.Context, upstream string) error {
+	args := []string{"-c", "sequence.editor=:", "rebase", "--interactive", "--autosquash", "--autostash"}
+	if upstream == "" {
+		args = append(args, "--root")
+	} else {
+		args = append(args, upstream)
+	}
+	_, err := runGit(ctx, args...)
+	return err
+}
+
+// RebaseInProgress reports whether a rebase stopped and waits to be
+// continued or aborted
+func RebaseInProgress(ctx context.Context) bool {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		output, err := runGit(ctx, "rev-parse", "--git-path", dir)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(strings.TrimSpace(output)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveCommit returns the full hash of the commit rev names
+func ResolveCommit(ctx context.Context, rev string) (string, error) {
+	output, err := runGit(ctx, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown commit '%s'", rev)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// CherryPick applies commits onto the branch of the worktree at path. With
+// record set, "(cherry picked from commit ...)" is added to the messages.
+func CherryPick(ctx context.Context, path string, record bool, commits ...string) error {
+	args := []string{"-C", path, "cherry-pick"}
+	if record {
+		args = append(args, "-x")
+	}
+	_, err := runGit(ctx, append(args, commits...)...)
+	return err
+}
+
+// CherryPickAbort abandons a cherry-pick in progress in the worktree at path
+func CherryPickAbort(ctx context.Context, path string) error {
+	_, err := runGit(ctx, "-C", path, "cherry-pick", "--abort")
+	return err
+}
+
+// IsAncestor reports whether commit ancestor is reachable from descendant,
+// i.e. whether merging descendant into ancestor is a fast-forward
+func IsAncestor(ctx context.Context, ancestor, descendant string) bool {
+	_, err := runGit(ctx, "merge-base", "--is-ancestor", ancestor, descendant)
+	return err == nil
+}
+
+// BranchStart returns the commit branch was created at, from its reflog,
+// or "" if the reflog doesn't go back that far (bare repositories keep
+// none by default)
+func BranchStart(ctx context.Context, branch string) string {
+	output, err := runGit(ctx, "reflog", "show", "--format=%H %gs", "refs/heads/"+branch, "--")
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	hash, subject, _ := strings.Cut(lines[len(lines)-1], " ")
+	if !strings.HasPrefix(subject, "branch: Created from") {
+		return ""
+	}
+	return hash
+}
+
+// LastCommitTime returns the committer date of rev
+func LastCommitTime(ctx context.Context, rev string) (time.Time, error) {
+	output, err := runGit(ctx, "log", "-1", "--format=%ct", rev, "--")
+	if err != nil {
+		return time.Time{}, err
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected log output: %q", output)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// CommitSubjects returns the subjects of the commits in head that are not
+// in base, oldest first
+func CommitSubjects(ctx context.Context, base, head string) ([]string, error) {
+	output, err := runGit(ctx, "log", "--reverse", "--format=%s", base+".."+head, "--")
+	if err != nil {
+		return nil, err
+	}
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// CommitMessages returns the full messages of the commits on head that
+// are not on base, oldest first. An empty base lists all of head's history.
+func CommitMessages(ctx context.Context, base, head string) ([]string, error) {
+	rev := head
+	if base != "" {
+		rev = base + ".." + head
+	}
+	output, err := runGit(ctx, "log", "--reverse", "--format=%B%x00", rev, "--")
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for _, m := range strings.Split(output, "\x00") {
+		if m = strings.TrimSpace(m); m != "" {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+// LogEntry is a commit listed by Log
+type LogEntry struct {
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Author  string    `json:"author"`
+	Subject string    `json:"subject"`
+}
+
+// Log returns the commits of rev, which may be a range such as
+// main..HEAD, newest first. max bounds how many are returned; zero
+// returns them all.
+func Log(ctx context.Context, rev string, max int) ([]LogEntry, error) {
+	args := []string{"log", "--format=%H%x1f%aI%x1f%an%x1f%s"}
+	if max > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", max))
+	}
+	output, err := runGit(ctx, append(args, rev, "--")...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []LogEntry
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[1])
+		commits = append(commits, LogEntry{Hash: fields[0], Date: date, Author: fields[2], Subject: fields[3]})
+	}
+	return commits, nil
+}
+
+// AuthoredCommit is a commit listed by AuthoredCommits
+type AuthoredCommit struct {
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+	// Branch is the local or remote-tracking branch the commit was found on
+	Branch string `json:"branch"`
+}
+
+// AuthoredCommits returns the commits by author made since the given
+// time on any local or remote-tracking branch of the repository at dir,
+// newest first. dir can be any worktree or the git directory.
+func AuthoredCommits(ctx context.Context, dir, author string, since time.Time) ([]AuthoredCommit, error) {
+	output, err := runGit(ctx, "-C", dir, "log", "--branches", "--remotes", "--no-merges",
+		"--author="+author, "--since="+since.Format(time.RFC3339),
+		"--format=%h%x1f%aI%x1f%S%x1f%s")
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []AuthoredCommit
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[1])
+		branch := strings.TrimPrefix(strings.TrimPrefix(fields[2], "refs/heads/"), "refs/remotes/")
+		commits = append(commits, AuthoredCommit{Hash: fields[0], Date: date, Subject: fields[3], Branch: branch})
+	}
+	return commits, nil
+}
+
+// UserEmail returns user.email as configured for the repository at dir
+func UserEmail(ctx context.Context, dir string) (string, error) {
+	output, err := runGit(ctx, "-C", dir, "config", "user.email")
 	if err != nil {
 		return "", err
 	}
-	// Extract stash@{0} or similar
-	parts := strings.SplitN(strings.TrimSpace(output), ":", 2)
-	if len(parts) > 0 {
-		return parts[0], nil
+	return strings.TrimSpace(output), nil
+}
+
+// MergedTags returns the tags reachable from HEAD, highest version first
+func MergedTags(ctx context.Context) ([]string, error) {
+	output, err := runGit(ctx, "tag", "--merged", "HEAD", "--sort=-v:refname")
+	if err != nil {
+		return nil, err
+	}
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, nil
 	}
-	return "stash@{0}", nil
+	return strings.Split(output, "\n"), nil
+}
+
+// TagExists reports whether the tag name exists
+func TagExists(ctx context.Context, name string) bool {
+	_, err := runGit(ctx, "rev-parse", "-q", "--verify", "refs/tags/"+name)
+	return err == nil
 }
 
-func StashPop() error {
-	_, err := runGit("stash", "pop")
+// CreateTag creates an annotated tag name at HEAD with message. Lines
+// starting with # are kept, e.g. Markdown headings.
+func CreateTag(ctx context.Context, name, message string) error {
+	_, err := runGit(ctx, "tag", "--annotate", "--cleanup=whitespace", name, "-m", message)
 	return err
 }
 
-func Merge(branch string) error {
-	_, err := runGit("merge", branch)
+// PushTag pushes the tag name to remote
+func PushTag(ctx context.Context, remote, name string) error {
+	_, err := runGit(ctx, "push", remote, "refs/tags/"+name)
 	return err
 }
 
-func DeleteBranch(name string, force bool) error {
+// DiffStat returns the diffstat of head against its merge base with base
+func DiffStat(ctx context.Context, base, head string) (string, error) {
+	output, err := runGit(ctx, "diff", "--stat", base+"..."+head, "--")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(output, "\n"), nil
+}
+
+// Diff returns the patch of head against its merge base with base, with
+// color escapes if color is set
+func Diff(ctx context.Context, base, head string, color bool) (string, error) {
+	colorFlag := "--color=never"
+	if color {
+		colorFlag = "--color=always"
+	}
+	return runGit(ctx, "diff", colorFlag, base+"..."+head, "--")
+}
+
+// FileStat is the number of lines added and deleted in a file; both are -1
+// for binary files
+type FileStat struct {
+	Path    string `json:"path"`
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+}
+
+// NumStat returns the per-file line counts of head against its merge base
+// with base
+func NumStat(ctx context.Context, base, head string) ([]FileStat, error) {
+	output, err := runGit(ctx, "diff", "--numstat", base+"..."+head, "--")
+	if err != nil {
+		return nil, err
+	}
+	return parseNumStat(output), nil
+}
+
+func parseNumStat(output string) []FileStat {
+	var stats []FileStat
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		stat := FileStat{Path: fields[2], Added: -1, Deleted: -1}
+		if n, err := strconv.Atoi(fields[0]); err == nil {
+			stat.Added = n
+		}
+		if n, err := strconv.Atoi(fields[1]); err == nil {
+			stat.Deleted = n
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// LocalBranch is a local branch with how it stands against a base branch
+// and its upstream
+type LocalBranch struct {
+	Name     string `json:"name"`
+	Upstream string `json:"upstream,omitempty"`
+	// Merged is set when the branch is merged into the base branch
+	Merged bool `json:"merged"`
+	// Gone is set when the branch's upstream was deleted from the remote
+	Gone bool `json:"gone"`
+}
+
+// LocalBranches lists the local branches, marking those merged into base
+// and those whose upstream is gone. Gone is only accurate after a fetch
+// with --prune.
+func LocalBranches(ctx context.Context, base string) ([]LocalBranch, error) {
+	output, err := runGit(ctx, "for-each-ref", "--format=%(refname)%1f%(upstream:short)%1f%(upstream:track)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	merged, err := runGit(ctx, "for-each-ref", "--format=%(refname)", "--merged="+base, "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	isMerged := make(map[string]bool)
+	for _, ref := range strings.Fields(merged) {
+		isMerged[ref] = true
+	}
+
+	var branches []LocalBranch
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "\x1f", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		branches = append(branches, LocalBranch{
+			Name:     strings.TrimPrefix(fields[0], "refs/heads/"),
+			Upstream: fields[1],
+			Merged:   isMerged[fields[0]],
+			Gone:     fields[2] == "[gone]",
+		})
+	}
+	return branches, nil
+}
+
+func DeleteBranch(ctx context.Context, name string, force bool) error {
 	flag := "-d"
 	if force {
 		flag = "-D"
 	}
-	_, err := runGit("branch", flag, name)
+	_, err := runGit(ctx, "branch", flag, name)
 	return err
 }
 
 // GetMainBranch returns "main" or "master" depending on what exists
-func GetMainBranch() string {
-	if BranchExists("main") {
+func GetMainBranch(ctx context.Context) string {
+	if BranchExists(ctx, "main") {
 		return "main"
 	}
 	return "master"
 }
 
-func GetGitDir() (string, error) {
-	output, err := runGit("rev-parse", "--git-dir")
+func GetGitDir(ctx context.Context) (string, error) {
+	output, err := runGit(ctx, "rev-parse", "--git-dir")
 	if err != nil {
 		return "", err
 	}
@@ -211,24 +947,110 @@ func GetGitDir() (string, error) {
 	return path, nil
 }
 
-// IsMainWorktree returns true if we're in the main worktree (not a secondary worktree)
-func IsMainWorktree() bool {
-	gitDir, err := GetGitDir()
+// GetCommonDir returns the absolute git common directory, which is shared
+// by the main repository and all of its worktrees
+func GetCommonDir(ctx context.Context) (string, error) {
+	output, err := runGit(ctx, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSpace(output)
+	if !filepath.IsAbs(path) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(cwd, path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// HooksDir returns the absolute directory git runs hooks from. It honours
+// core.hooksPath and is shared by all worktrees.
+func HooksDir(ctx context.Context) (string, error) {
+	output, err := runGit(ctx, "rev-parse", "--path-format=absolute", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Clean(strings.TrimSpace(output)), nil
+}
+
+// ExcludeLocal adds pattern to .git/info/exclude, which ignores files in
+// every worktree without touching .gitignore, unless it is already there
+func ExcludeLocal(ctx context.Context, pattern string) error {
+	output, err := runGit(ctx, "rev-parse", "--path-format=absolute", "--git-path", "info/exclude")
+	if err != nil {
+		return err
+	}
+	path := strings.TrimSpace(output)
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	data = append(data, pattern+"\n"...)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// PathInfo returns the worktree root, git dir and common dir using a single
+// git invocation. All returned paths are absolute.
+func PathInfo(ctx context.Context) (toplevel, gitDir, commonDir string, err error) {
+	output, err := runGit(ctx, "rev-parse", "--show-toplevel", "--absolute-git-dir", "--git-common-dir")
+	if err != nil {
+		return "", "", "", err
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 {
+		return "", "", "", fmt.Errorf("unexpected rev-parse output: %q", output)
+	}
+	toplevel, gitDir, commonDir = nativePath(lines[0]), nativePath(lines[1]), lines[2]
+	if !filepath.IsAbs(commonDir) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", "", "", err
+		}
+		commonDir = filepath.Join(cwd, commonDir)
+	}
+	return toplevel, gitDir, filepath.Clean(commonDir), nil
+}
+
+// IsMainWorktree returns true if we're in the main worktree (not a
+// secondary worktree). Only the main worktree uses the common git dir
+// directly; worktrees of a bare repository are never the main worktree.
+func IsMainWorktree(ctx context.Context) bool {
+	_, gitDir, commonDir, err := PathInfo(ctx)
 	if err != nil {
 		return false
 	}
-	// In secondary worktrees, git-dir contains /worktrees/ path
-	// e.g., /path/to/repo/.git/worktrees/feature-name
-	return !strings.Contains(gitDir, string(filepath.Separator)+"worktrees"+string(filepath.Separator))
+	return samePath(gitDir, commonDir)
 }
 
-const (
-	statePreviousBranch = "LAZYWORK_PREVIOUS_BRANCH"
-	stateStashRef       = "LAZYWORK_STASH_REF"
-)
+// samePath reports whether a and b name the same directory, resolving
+// symlinks such as /tmp on macOS
+func samePath(a, b string) bool {
+	if ra, err := filepath.EvalSymlinks(a); err == nil {
+		a = ra
+	}
+	if rb, err := filepath.EvalSymlinks(b); err == nil {
+		b = rb
+	}
+	return paths.Same(runtime.GOOS, a, b)
+}
 
-func SaveState(key, value string) error {
-	gitDir, err := GetGitDir()
+func SaveState(ctx context.Context, key, value string) error {
+	gitDir, err := GetGitDir(ctx)
 	if err != nil {
 		return err
 	}
@@ -236,8 +1058,8 @@ func SaveState(key, value string) error {
 	return os.WriteFile(path, []byte(value), 0o644)
 }
 
-func LoadState(key string) (string, error) {
-	gitDir, err := GetGitDir()
+func LoadState(ctx context.Context, key string) (string, error) {
+	gitDir, err := GetGitDir(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -249,8 +1071,8 @@ func LoadState(key string) (string, error) {
 	return strings.TrimSpace(string(data)), nil
 }
 
-func ClearState(key string) error {
-	gitDir, err := GetGitDir()
+func ClearState(ctx context.Context, key string) error {
+	gitDir, err := GetGitDir(ctx)
 	if err != nil {
 		return err
 	}
@@ -262,43 +1084,9 @@ func ClearState(key string) error {
 	return err
 }
 
-// HasSavedState returns true if there's saved state from a previous 'use' command
-func HasSavedState() bool {
-	_, err := LoadState(statePreviousBranch)
-	return err == nil
-}
-
-// SaveUseState saves the current branch and optional stash ref for later return
-func SaveUseState(previousBranch, stashRef string) error {
-	if err := SaveState(statePreviousBranch, previousBranch); err != nil {
-		return err
-	}
-	if stashRef != "" {
-		return SaveState(stateStashRef, stashRef)
-	}
-	return nil
-}
-
-func LoadUseState() (previousBranch, stashRef string, err error) {
-	previousBranch, err = LoadState(statePreviousBranch)
-	if err != nil {
-		return "", "", err
-	}
-	stashRef, _ = LoadState(stateStashRef)
-	return previousBranch, stashRef, nil
-}
-
-// ClearUseState removes all saved state from a 'use' command
-func ClearUseState() error {
-	if err := ClearState(statePreviousBranch); err != nil {
-		return err
-	}
-	return ClearState(stateStashRef)
-}
-
 // FindWorktreeByName finds a worktree by name (basename match)
-func FindWorktreeByName(name string) (*Worktree, error) {
-	worktrees, err := ListWorktrees()
+func FindWorktreeByName(ctx context.Context, name string) (*Worktree, error) {
+	worktrees, err := ListWorktrees(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -314,20 +1102,3 @@ func FindWorktreeByName(name string) (*Worktree, error) {
 	}
 	return nil, fmt.Errorf("worktree '%s' not found", name)
 }
-
-func runGit(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		errMsg := strings.TrimSpace(stderr.String())
-		if errMsg == "" {
-			errMsg = err.Error()
-		}
-		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), errMsg)
-	}
-
-	return stdout.String(), nil
-}
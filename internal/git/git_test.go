@@ -1,11 +1,16 @@
 package git
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // testRepo creates a temporary git repository for testing
@@ -72,7 +77,7 @@ func TestIsInsideWorkTree(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
 
-	if !IsInsideWorkTree() {
+	if !IsInsideWorkTree(t.Context()) {
 		t.Error("expected to be inside work tree")
 	}
 }
@@ -81,7 +86,7 @@ func TestGetRepoRoot(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
 
-	root, err := GetRepoRoot()
+	root, err := GetRepoRoot(t.Context())
 	if err != nil {
 		t.Fatalf("GetRepoRoot failed: %v", err)
 	}
@@ -96,7 +101,7 @@ func TestCurrentBranch(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
 
-	branch, err := CurrentBranch()
+	branch, err := CurrentBranch(t.Context())
 	if err != nil {
 		t.Fatalf("CurrentBranch failed: %v", err)
 	}
@@ -113,25 +118,25 @@ func TestHasUncommittedChanges(t *testing.T) {
 	defer repo.cleanup()
 
 	// Clean state - should have no changes
-	if HasUncommittedChanges() {
+	if HasUncommittedChanges(t.Context()) {
 		t.Error("expected no uncommitted changes in clean repo")
 	}
 
 	// Create a new file (untracked)
 	os.WriteFile("newfile.txt", []byte("test"), 0o644)
-	if !HasUncommittedChanges() {
+	if !HasUncommittedChanges(t.Context()) {
 		t.Error("expected uncommitted changes after adding file")
 	}
 
 	// Stage it
 	runCmd("git", "add", "newfile.txt")
-	if !HasUncommittedChanges() {
+	if !HasUncommittedChanges(t.Context()) {
 		t.Error("expected uncommitted changes with staged file")
 	}
 
 	// Commit it
 	runCmd("git", "commit", "-m", "add file")
-	if HasUncommittedChanges() {
+	if HasUncommittedChanges(t.Context()) {
 		t.Error("expected no uncommitted changes after commit")
 	}
 }
@@ -145,11 +150,11 @@ func TestCheckout(t *testing.T) {
 	runCmd("git", "branch", "feature-test")
 
 	// Checkout the new branch
-	if err := Checkout("feature-test"); err != nil {
+	if err := Checkout(t.Context(), "feature-test"); err != nil {
 		t.Fatalf("Checkout failed: %v", err)
 	}
 
-	branch, _ := CurrentBranch()
+	branch, _ := CurrentBranch(t.Context())
 	if branch != "feature-test" {
 		t.Errorf("expected branch=feature-test, got=%s", branch)
 	}
@@ -164,32 +169,33 @@ func TestStash(t *testing.T) {
 	os.WriteFile("test.txt", []byte("changes"), 0o644)
 	runCmd("git", "add", "test.txt")
 
-	if !HasUncommittedChanges() {
+	if !HasUncommittedChanges(t.Context()) {
 		t.Fatal("expected uncommitted changes")
 	}
 
 	// Stash the changes
-	ref, err := Stash("test stash")
+	hash, err := Stash(t.Context(), "test stash")
 	if err != nil {
 		t.Fatalf("Stash failed: %v", err)
 	}
 
-	if !strings.HasPrefix(ref, "stash@{") {
-		t.Errorf("expected stash ref, got=%s", ref)
+	stashes, _ := ListStashes(t.Context())
+	if s := FindStash(stashes, hash); s == nil || s.Ref != "stash@{0}" {
+		t.Errorf("expected stash@{0} with hash %s, got %+v", hash, stashes)
 	}
 
 	// Should be clean now
-	if HasUncommittedChanges() {
+	if HasUncommittedChanges(t.Context()) {
 		t.Error("expected no uncommitted changes after stash")
 	}
 
 	// Pop the stash
-	if err := StashPop(); err != nil {
+	if err := StashPop(t.Context()); err != nil {
 		t.Fatalf("StashPop failed: %v", err)
 	}
 
 	// Changes should be back
-	if !HasUncommittedChanges() {
+	if !HasUncommittedChanges(t.Context()) {
 		t.Error("expected uncommitted changes after stash pop")
 	}
 }
@@ -203,12 +209,12 @@ func TestStateManagement(t *testing.T) {
 	value := "test-value-123"
 
 	// Save state
-	if err := SaveState(key, value); err != nil {
+	if err := SaveState(t.Context(), key, value); err != nil {
 		t.Fatalf("SaveState failed: %v", err)
 	}
 
 	// Load state
-	loaded, err := LoadState(key)
+	loaded, err := LoadState(t.Context(), key)
 	if err != nil {
 		t.Fatalf("LoadState failed: %v", err)
 	}
@@ -217,12 +223,12 @@ func TestStateManagement(t *testing.T) {
 	}
 
 	// Clear state
-	if err := ClearState(key); err != nil {
+	if err := ClearState(t.Context(), key); err != nil {
 		t.Fatalf("ClearState failed: %v", err)
 	}
 
 	// Should fail to load now
-	_, err = LoadState(key)
+	_, err = LoadState(t.Context(), key)
 	if err == nil {
 		t.Error("expected error loading cleared state")
 	}
@@ -232,63 +238,236 @@ func TestStateManagement(t *testing.T) {
 func TestUseState(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
+	ctx := t.Context()
 
 	// Initially no saved state
-	if HasSavedState() {
+	if HasSavedState(ctx) {
 		t.Error("expected no saved state initially")
 	}
 
-	// Save use state
-	if err := SaveUseState("main", "stash@{0}"); err != nil {
-		t.Fatalf("SaveUseState failed: %v", err)
+	// Push two frames, as two stacked 'use' commands do
+	stack := []UseFrame{
+		{Branch: "main", Target: "feature-a", Stash: "abc123"},
+		{Branch: "feature-a", Target: "feature-b"},
+	}
+	if err := SaveUseStack(ctx, stack); err != nil {
+		t.Fatalf("SaveUseStack failed: %v", err)
 	}
 
-	// Should have state now
-	if !HasSavedState() {
-		t.Error("expected saved state after SaveUseState")
+	if !HasSavedState(ctx) {
+		t.Error("expected saved state after SaveUseStack")
 	}
 
-	// Load and verify
-	branch, stashRef, err := LoadUseState()
+	loaded, err := LoadUseStack(ctx)
 	if err != nil {
-		t.Fatalf("LoadUseState failed: %v", err)
-	}
-	if branch != "main" {
-		t.Errorf("expected branch=main, got=%s", branch)
+		t.Fatalf("LoadUseStack failed: %v", err)
 	}
-	if stashRef != "stash@{0}" {
-		t.Errorf("expected stashRef=stash@{0}, got=%s", stashRef)
+	if len(loaded) != 2 || loaded[0].Stash != "abc123" || loaded[1].Branch != "feature-a" {
+		t.Errorf("LoadUseStack = %+v, want %+v", loaded, stack)
 	}
 
-	// Clear
-	if err := ClearUseState(); err != nil {
-		t.Fatalf("ClearUseState failed: %v", err)
+	// Saving an empty stack clears the state
+	if err := SaveUseStack(ctx, nil); err != nil {
+		t.Fatalf("SaveUseStack failed: %v", err)
 	}
 
-	if HasSavedState() {
+	if HasSavedState(ctx) {
 		t.Error("expected no saved state after clear")
 	}
 }
 
-// Test use state without stash
-func TestUseStateNoStash(t *testing.T) {
+// Test state saved before 'use' could be stacked
+func TestUseStateLegacy(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	os.WriteFile(filepath.Join(".git", statePreviousBranch), []byte("feature\n"), 0o644)
+	os.WriteFile(filepath.Join(".git", stateStashRef), []byte("stash@{0}"), 0o644)
+
+	stack, err := LoadUseStack(ctx)
+	if err != nil {
+		t.Fatalf("LoadUseStack failed: %v", err)
+	}
+	if len(stack) != 1 || stack[0].Branch != "feature" || stack[0].Stash != "stash@{0}" {
+		t.Fatalf("LoadUseStack = %+v, want the legacy frame", stack)
+	}
+
+	// Saving migrates to the stack file and removes the legacy files
+	if err := SaveUseStack(ctx, stack); err != nil {
+		t.Fatalf("SaveUseStack failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(".git", statePreviousBranch)); !os.IsNotExist(err) {
+		t.Error("legacy state file not removed")
+	}
+	if stack, _ := LoadUseStack(ctx); len(stack) != 1 || stack[0].Branch != "feature" {
+		t.Errorf("stack after migration = %+v", stack)
+	}
+}
+
+// Test that the stack is shared by every worktree, and that the stack older
+// versions kept in the main git dir is moved there
+func TestUseStateShared(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	os.WriteFile(filepath.Join(".git", stateUseStack), []byte(`[{"branch":"main","target":"feature","stash":"abc123"}]`), 0o644)
+
+	if err := runCmd("git", "worktree", "add", "-q", "-b", "other", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir("other"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadUseStack(ctx)
+	if err != nil || len(loaded) != 1 || loaded[0].Target != "feature" {
+		t.Fatalf("LoadUseStack from a worktree = %+v, %v", loaded, err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.dir, ".git", stateUseStack)); !os.IsNotExist(err) {
+		t.Error("legacy stack not removed")
+	}
+	if _, err := os.Stat(filepath.Join(repo.dir, ".git", "lazywork", useStackFile)); err != nil {
+		t.Errorf("stack not moved to the common dir: %v", err)
+	}
+
+	if err := SaveUseStack(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(repo.dir); err != nil {
+		t.Fatal(err)
+	}
+	if HasSavedState(ctx) {
+		t.Error("stack cleared from a worktree is still seen from the main checkout")
+	}
+}
+
+// Test that migrating keeps the frames older versions left in both the
+// worktree git dir and the common dir, oldest first
+func TestUseStateLegacyMerge(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	if err := runCmd("git", "worktree", "add", "-q", "-b", "other", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir("other"); err != nil {
+		t.Fatal(err)
+	}
+	_, gitDir, commonDir, err := PathInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(commonDir, stateUseStack),
+		[]byte(`[{"branch":"main","target":"a","stash":"aaa","created":"2024-01-01T00:00:00Z"},`+
+			`{"branch":"a","target":"c","created":"2024-01-03T00:00:00Z"}]`), 0o644)
+	os.WriteFile(filepath.Join(gitDir, stateUseStack),
+		[]byte(`[{"branch":"other","target":"b","stash":"bbb","created":"2024-01-02T00:00:00Z"}]`), 0o644)
+
+	loaded, err := LoadUseStack(ctx)
+	if err != nil {
+		t.Fatalf("LoadUseStack failed: %v", err)
+	}
+	var targets []string
+	for _, f := range loaded {
+		targets = append(targets, f.Target)
+	}
+	if strings.Join(targets, ",") != "a,b,c" {
+		t.Errorf("merged stack targets = %v, want [a b c]", targets)
+	}
+	if stashes := PendingUseStashes(ctx); len(stashes) != 2 {
+		t.Errorf("PendingUseStashes = %v, want both legacy stashes", stashes)
+	}
+
+	for _, dir := range []string{gitDir, commonDir} {
+		if _, err := os.Stat(filepath.Join(dir, stateUseStack)); !os.IsNotExist(err) {
+			t.Errorf("legacy stack in %s not removed", dir)
+		}
+	}
+	if stack, _ := ReadUseStack(commonDir, gitDir); len(stack) != 3 {
+		t.Errorf("stack after migration = %+v, want 3 frames", stack)
+	}
+}
+
+// Test that legacy state in the git dir of any linked worktree is merged
+// in, even after the stack file was written
+func TestUseStateLegacyWorktrees(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
+	ctx := t.Context()
 
-	// Save without stash
-	if err := SaveUseState("feature", ""); err != nil {
-		t.Fatalf("SaveUseState failed: %v", err)
+	if err := SaveUseStack(ctx, []UseFrame{{Branch: "main", Target: "a", Created: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"one", "two"} {
+		if err := runCmd("git", "worktree", "add", "-q", "-b", name, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commonDir := filepath.Join(repo.dir, ".git")
+	os.WriteFile(filepath.Join(commonDir, "worktrees", "one", stateUseStack),
+		[]byte(`[{"branch":"a","target":"b","stash":"bbb","created":"2024-01-02T00:00:00Z"}]`), 0o644)
+	os.WriteFile(filepath.Join(commonDir, "worktrees", "two", statePreviousBranch), []byte("develop\n"), 0o644)
+	os.WriteFile(filepath.Join(commonDir, "worktrees", "two", stateStashRef), []byte("ccc"), 0o644)
+
+	// Loaded from the main checkout, not from either worktree
+	loaded, err := LoadUseStack(ctx)
+	if err != nil {
+		t.Fatalf("LoadUseStack failed: %v", err)
 	}
+	var branches []string
+	for _, f := range loaded {
+		branches = append(branches, f.Branch)
+	}
+	if strings.Join(branches, ",") != "develop,main,a" {
+		t.Errorf("merged stack branches = %v, want [develop main a]", branches)
+	}
+	if stashes := PendingUseStashes(ctx); strings.Join(stashes, ",") != "ccc,bbb" {
+		t.Errorf("PendingUseStashes = %v, want both legacy stashes", stashes)
+	}
+	for _, name := range []string{stateUseStack, statePreviousBranch, stateStashRef} {
+		for _, wt := range []string{"one", "two"} {
+			if _, err := os.Stat(filepath.Join(commonDir, "worktrees", wt, name)); !os.IsNotExist(err) {
+				t.Errorf("legacy %s in worktree %s not removed", name, wt)
+			}
+		}
+	}
+}
 
-	branch, stashRef, err := LoadUseState()
+// Test that worktrees updating the stack at once keep each other's frames
+func TestUpdateUseStackConcurrent(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := UpdateUseStack(ctx, func(frames []UseFrame) ([]UseFrame, error) {
+				return append(frames, UseFrame{Branch: fmt.Sprintf("b%d", i)}), nil
+			})
+			if err != nil {
+				t.Errorf("UpdateUseStack failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	loaded, err := LoadUseStack(ctx)
 	if err != nil {
-		t.Fatalf("LoadUseState failed: %v", err)
+		t.Fatalf("LoadUseStack failed: %v", err)
 	}
-	if branch != "feature" {
-		t.Errorf("expected branch=feature, got=%s", branch)
+	if len(loaded) != n {
+		t.Errorf("stack has %d frames, want %d", len(loaded), n)
 	}
-	if stashRef != "" {
-		t.Errorf("expected empty stashRef, got=%s", stashRef)
+	if matches, _ := filepath.Glob(filepath.Join(repo.dir, ".git", "lazywork", "*.tmp")); len(matches) > 0 {
+		t.Errorf("temporary files left behind: %v", matches)
 	}
 }
 
@@ -297,16 +476,50 @@ func TestBranchExists(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
 
-	mainBranch := GetMainBranch()
-	if !BranchExists(mainBranch) {
+	mainBranch := GetMainBranch(t.Context())
+	if !BranchExists(t.Context(), mainBranch) {
 		t.Errorf("expected %s branch to exist", mainBranch)
 	}
 
-	if BranchExists("nonexistent-branch-xyz") {
+	if BranchExists(t.Context(), "nonexistent-branch-xyz") {
 		t.Error("expected nonexistent branch to not exist")
 	}
 }
 
+func TestListBranches(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+
+	runCmd("git", "branch", "feature/login")
+	runCmd("git", "update-ref", "refs/remotes/origin/feature/remote", "HEAD")
+	runCmd("git", "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/feature/remote")
+
+	local, err := ListBranches(t.Context(), false)
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+	if len(local) != 2 || !contains(local, "feature/login") || !contains(local, GetMainBranch(t.Context())) {
+		t.Errorf("local branches = %v", local)
+	}
+
+	all, err := ListBranches(t.Context(), true)
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+	if len(all) != 3 || !contains(all, "origin/feature/remote") {
+		t.Errorf("all branches = %v", all)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func TestDeleteBranch(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
@@ -315,15 +528,15 @@ func TestDeleteBranch(t *testing.T) {
 	runCmd("git", "checkout", "-b", "to-delete")
 	runCmd("git", "checkout", "-") // go back to main
 
-	if !BranchExists("to-delete") {
+	if !BranchExists(t.Context(), "to-delete") {
 		t.Fatal("branch should exist before delete")
 	}
 
-	if err := DeleteBranch("to-delete", false); err != nil {
+	if err := DeleteBranch(t.Context(), "to-delete", false); err != nil {
 		t.Fatalf("DeleteBranch failed: %v", err)
 	}
 
-	if BranchExists("to-delete") {
+	if BranchExists(t.Context(), "to-delete") {
 		t.Error("branch should not exist after delete")
 	}
 }
@@ -333,7 +546,7 @@ func TestMerge(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
 
-	mainBranch := GetMainBranch()
+	mainBranch := GetMainBranch(t.Context())
 
 	// Create a feature branch with changes
 	runCmd("git", "checkout", "-b", "feature-merge")
@@ -345,7 +558,7 @@ func TestMerge(t *testing.T) {
 	runCmd("git", "checkout", mainBranch)
 
 	// Merge feature
-	if err := Merge("feature-merge"); err != nil {
+	if err := Merge(t.Context(), "feature-merge"); err != nil {
 		t.Fatalf("Merge failed: %v", err)
 	}
 
@@ -355,12 +568,255 @@ func TestMerge(t *testing.T) {
 	}
 }
 
+func TestAmend(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	before, _ := ShortHash(ctx, "HEAD")
+	os.WriteFile("extra.txt", []byte("extra"), 0o644)
+	runCmd("git", "add", "extra.txt")
+
+	if err := Amend(ctx, "Initial commit\n\nWith extra.txt"); err != nil {
+		t.Fatalf("Amend failed: %v", err)
+	}
+
+	message, err := CommitMessage(ctx, "HEAD")
+	if err != nil {
+		t.Fatalf("CommitMessage failed: %v", err)
+	}
+	if message != "Initial commit\n\nWith extra.txt" {
+		t.Errorf("message = %q", message)
+	}
+	if after, _ := ShortHash(ctx, "HEAD"); after == before {
+		t.Error("expected HEAD to change after amending")
+	}
+	if HasUncommittedChanges(ctx) {
+		t.Error("expected the staged file to be part of the amended commit")
+	}
+}
+
+func TestLogAndResetSoft(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		os.WriteFile(name, []byte(name), 0o644)
+		runCmd("git", "add", name)
+		runCmd("git", "commit", "-m", "wip: add "+name)
+	}
+
+	commits, err := Log(ctx, "HEAD", 2)
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	if len(commits) != 2 || commits[0].Subject != "wip: add b.txt" || commits[1].Subject != "wip: add a.txt" || commits[0].Author != "Test User" {
+		t.Fatalf("commits = %+v, want the two wip commits, newest first", commits)
+	}
+
+	if err := ResetSoft(ctx, commits[1].Hash+"^"); err != nil {
+		t.Fatalf("ResetSoft failed: %v", err)
+	}
+	if subject, _ := CommitSubject(ctx, "HEAD"); subject != "Initial commit" {
+		t.Errorf("HEAD subject = %q, want Initial commit", subject)
+	}
+	if diff, _ := GetStagedDiff(ctx); !strings.Contains(diff, "a.txt") || !strings.Contains(diff, "b.txt") {
+		t.Errorf("staged diff = %q, want both files", diff)
+	}
+}
+
+func TestFixupAndRebaseAutosquash(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	os.WriteFile("a.txt", []byte("a"), 0o644)
+	runCmd("git", "add", "a.txt")
+	runCmd("git", "commit", "-m", "Add a")
+	target, _ := ResolveCommit(ctx, "HEAD")
+	os.WriteFile("b.txt", []byte("b"), 0o644)
+	runCmd("git", "add", "b.txt")
+	runCmd("git", "commit", "-m", "Add b")
+
+	os.WriteFile("a.txt", []byte("a, fixed"), 0o644)
+	runCmd("git", "add", "a.txt")
+	if err := CommitFixup(ctx, target); err != nil {
+		t.Fatalf("CommitFixup failed: %v", err)
+	}
+	if subject, _ := CommitSubject(ctx, "HEAD"); subject != "fixup! Add a" {
+		t.Errorf("fixup subject = %q", subject)
+	}
+
+	if err := RebaseAutosquash(ctx, target+"^"); err != nil {
+		t.Fatalf("RebaseAutosquash failed: %v", err)
+	}
+	if RebaseInProgress(ctx) {
+		t.Error("expected the rebase to finish")
+	}
+	subjects, _ := CommitSubjects(ctx, "HEAD~2", "HEAD")
+	if strings.Join(subjects, ",") != "Add a,Add b" {
+		t.Errorf("subjects = %v, want the fixup folded into Add a", subjects)
+	}
+	if content, _ := os.ReadFile("a.txt"); string(content) != "a, fixed" {
+		t.Errorf("a.txt = %q", content)
+	}
+}
+
+func TestMergeWithMessage(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	mainBranch := GetMainBranch(ctx)
+
+	runCmd("git", "checkout", "-b", "feature-log")
+	os.WriteFile("one.txt", []byte("one"), 0o644)
+	runCmd("git", "add", "one.txt")
+	runCmd("git", "commit", "-m", "Add one")
+	os.WriteFile("two.txt", []byte("two"), 0o644)
+	runCmd("git", "add", "two.txt")
+	runCmd("git", "commit", "-m", "Add two")
+	runCmd("git", "checkout", mainBranch)
+
+	subjects, err := CommitSubjects(ctx, mainBranch, "feature-log")
+	if err != nil || len(subjects) != 2 || subjects[0] != "Add one" || subjects[1] != "Add two" {
+		t.Fatalf("CommitSubjects = %q, %v", subjects, err)
+	}
+	if stat, err := DiffStat(ctx, mainBranch, "feature-log"); err != nil || !strings.Contains(stat, "2 files changed") {
+		t.Errorf("DiffStat = %q, %v", stat, err)
+	}
+	if !IsAncestor(ctx, mainBranch, "feature-log") {
+		t.Error("expected the main branch to be an ancestor of feature-log")
+	}
+
+	// Diverge so the merge needs a commit
+	os.WriteFile("main.txt", []byte("main"), 0o644)
+	runCmd("git", "add", "main.txt")
+	runCmd("git", "commit", "-m", "Main change")
+	if IsAncestor(ctx, mainBranch, "feature-log") {
+		t.Error("expected diverged branches not to fast-forward")
+	}
+
+	if err := MergeWithMessage(ctx, "feature-log", "Merge feature-log: add one and two"); err != nil {
+		t.Fatalf("MergeWithMessage failed: %v", err)
+	}
+	if subject, _ := CommitSubject(ctx, "HEAD"); subject != "Merge feature-log: add one and two" {
+		t.Errorf("merge subject = %q", subject)
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	mainBranch := GetMainBranch(ctx)
+
+	runCmd("git", "checkout", "-b", "feature-conflict")
+	os.WriteFile("README.md", []byte("# Feature\n"), 0o644)
+	os.WriteFile("clean.txt", []byte("clean"), 0o644)
+	runCmd("git", "add", ".")
+	runCmd("git", "commit", "-m", "feature edits")
+	runCmd("git", "checkout", mainBranch)
+
+	files, err := MergeConflicts(ctx, "HEAD", "feature-conflict")
+	if err != nil || len(files) != 0 {
+		t.Fatalf("before diverging: MergeConflicts = %q, %v", files, err)
+	}
+
+	os.WriteFile("README.md", []byte("# Main\n"), 0o644)
+	runCmd("git", "commit", "-am", "main edits")
+
+	files, err = MergeConflicts(ctx, "HEAD", "feature-conflict")
+	if err != nil {
+		t.Fatalf("MergeConflicts failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "README.md" {
+		t.Errorf("files = %q, want [README.md]", files)
+	}
+	if HasUncommittedChanges(ctx) {
+		t.Error("expected the check to leave the working tree alone")
+	}
+
+	if _, err := MergeConflicts(ctx, "HEAD", "no-such-branch"); err == nil {
+		t.Error("expected an error for an unknown branch")
+	}
+}
+
+func TestCherryPick(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	mainBranch := GetMainBranch(ctx)
+	target := filepath.Join(repo.dir, "target")
+	if err := AddWorktree(ctx, target, "target"); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	os.WriteFile("picked.txt", []byte("picked"), 0o644)
+	runCmd("git", "add", "picked.txt")
+	runCmd("git", "commit", "-m", "Add picked")
+
+	commit, err := ResolveCommit(ctx, mainBranch)
+	if err != nil {
+		t.Fatalf("ResolveCommit failed: %v", err)
+	}
+	if _, err := ResolveCommit(ctx, "no-such-rev"); err == nil {
+		t.Error("expected an error for an unknown revision")
+	}
+
+	if err := CherryPick(ctx, target, true, commit); err != nil {
+		t.Fatalf("CherryPick failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "picked.txt")); err != nil {
+		t.Error("expected picked.txt in the target worktree")
+	}
+	if message, _ := CommitMessage(ctx, "target"); !strings.Contains(message, "cherry picked from commit "+commit) {
+		t.Errorf("message = %q", message)
+	}
+
+	// Picking a conflicting change leaves the conflict in the target
+	os.WriteFile("picked.txt", []byte("main"), 0o644)
+	runCmd("git", "commit", "-am", "Edit on main")
+	os.WriteFile(filepath.Join(target, "picked.txt"), []byte("target"), 0o644)
+	runCmd("git", "-C", target, "commit", "-am", "Edit on target")
+
+	if err := CherryPick(ctx, target, false, mainBranch); err == nil {
+		t.Fatal("expected the cherry-pick to conflict")
+	}
+	files, _ := ConflictedFilesIn(ctx, target)
+	if len(files) != 1 || files[0] != "picked.txt" {
+		t.Errorf("conflicted files = %q", files)
+	}
+	if err := CherryPickAbort(ctx, target); err != nil {
+		t.Errorf("CherryPickAbort failed: %v", err)
+	}
+}
+
+func TestParseNumStat(t *testing.T) {
+	stats := parseNumStat("3\t1\tmain.go\n-\t-\tlogo.png\n")
+	if len(stats) != 2 {
+		t.Fatalf("got %d stats, want 2", len(stats))
+	}
+	if stats[0] != (FileStat{Path: "main.go", Added: 3, Deleted: 1}) {
+		t.Errorf("stats[0] = %+v", stats[0])
+	}
+	if stats[1] != (FileStat{Path: "logo.png", Added: -1, Deleted: -1}) {
+		t.Errorf("stats[1] = %+v", stats[1])
+	}
+	if parseNumStat("") != nil {
+		t.Error("expected no stats for empty output")
+	}
+}
+
 // Test worktree operations
 func TestListWorktrees(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
 
-	worktrees, err := ListWorktrees()
+	worktrees, err := ListWorktrees(t.Context())
 	if err != nil {
 		t.Fatalf("ListWorktrees failed: %v", err)
 	}
@@ -377,12 +833,12 @@ func TestFindWorktreeByName(t *testing.T) {
 
 	// Create a worktree
 	wtPath := filepath.Join(repo.dir, ".worktrees", "test-feature")
-	if err := AddWorktree(wtPath, "test-feature"); err != nil {
+	if err := AddWorktree(t.Context(), wtPath, "test-feature"); err != nil {
 		t.Fatalf("AddWorktree failed: %v", err)
 	}
 
 	// Find by name
-	wt, err := FindWorktreeByName("test-feature")
+	wt, err := FindWorktreeByName(t.Context(), "test-feature")
 	if err != nil {
 		t.Fatalf("FindWorktreeByName failed: %v", err)
 	}
@@ -392,25 +848,73 @@ func TestFindWorktreeByName(t *testing.T) {
 	}
 
 	// Should not find nonexistent
-	_, err = FindWorktreeByName("nonexistent")
+	_, err = FindWorktreeByName(t.Context(), "nonexistent")
 	if err == nil {
 		t.Error("expected error for nonexistent worktree")
 	}
 }
 
+func TestExpandWorktreeDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	tests := []struct {
+		baseDir string
+		want    string
+	}{
+		{".worktrees", "/src/app/.worktrees"},
+		{"../app-worktrees", "/src/app-worktrees"},
+		{"/tmp/wt/{repo}", "/tmp/wt/app"},
+		{"~/worktrees/{repo}", filepath.Join(home, "worktrees", "app")},
+	}
+
+	for _, tt := range tests {
+		got, err := ExpandWorktreeDir(tt.baseDir, "/src/app", "app")
+		if err != nil {
+			t.Errorf("ExpandWorktreeDir(%q) failed: %v", tt.baseDir, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ExpandWorktreeDir(%q) = %q, want %q", tt.baseDir, got, tt.want)
+		}
+	}
+}
+
+func TestSecondaryWorktreesOutsideRepo(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+
+	outside := filepath.Join(t.TempDir(), "outside")
+	if err := AddWorktree(t.Context(), outside, "outside"); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	worktrees, err := ListWorktrees(t.Context())
+	if err != nil {
+		t.Fatalf("ListWorktrees failed: %v", err)
+	}
+
+	secondary := SecondaryWorktrees(worktrees)
+	if len(secondary) != 1 || secondary[0].Branch != "outside" {
+		t.Errorf("SecondaryWorktrees = %+v, want the outside worktree", secondary)
+	}
+}
+
 // Test IsMainWorktree
 func TestIsMainWorktree(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
 
 	// In main repo, should be true
-	if !IsMainWorktree() {
+	if !IsMainWorktree(t.Context()) {
 		t.Error("expected to be in main worktree")
 	}
 
 	// Create and go to a secondary worktree
 	wtPath := filepath.Join(repo.dir, ".worktrees", "secondary")
-	if err := AddWorktree(wtPath, "secondary"); err != nil {
+	if err := AddWorktree(t.Context(), wtPath, "secondary"); err != nil {
 		t.Fatalf("AddWorktree failed: %v", err)
 	}
 
@@ -419,7 +923,7 @@ func TestIsMainWorktree(t *testing.T) {
 	}
 
 	// In secondary worktree, should be false
-	if IsMainWorktree() {
+	if IsMainWorktree(t.Context()) {
 		t.Error("expected NOT to be in main worktree")
 	}
 }
@@ -428,7 +932,7 @@ func TestGetGitDir(t *testing.T) {
 	repo := newTestRepo(t)
 	defer repo.cleanup()
 
-	gitDir, err := GetGitDir()
+	gitDir, err := GetGitDir(t.Context())
 	if err != nil {
 		t.Fatalf("GetGitDir failed: %v", err)
 	}
@@ -438,3 +942,314 @@ func TestGetGitDir(t *testing.T) {
 		t.Errorf("expected gitDir=%s, got=%s", expected, gitDir)
 	}
 }
+
+func TestRunGitCancelled(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := GetRepoRoot(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("GetRepoRoot with cancelled context: err = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunGitTimeout(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+
+	SetTimeout(time.Nanosecond)
+	defer SetTimeout(0)
+
+	_, err := GetRepoRoot(t.Context())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetRepoRoot with expired timeout: err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestUpdateSubmodules(t *testing.T) {
+	// Local submodule URLs need the file protocol, which git blocks by default
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	lib := newTestRepo(t)
+	os.Chdir(lib.orig)
+	defer os.RemoveAll(lib.dir)
+
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	if err := runCmd("git", "submodule", "add", lib.dir, "lib"); err != nil {
+		t.Fatalf("git submodule add failed: %v", err)
+	}
+	runCmd("git", "commit", "-m", "add submodule")
+
+	wtPath := filepath.Join(repo.dir, ".worktrees", "with-lib")
+	if err := AddWorktree(ctx, wtPath, "with-lib"); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if !HasSubmodules(wtPath) {
+		t.Fatal("expected HasSubmodules for the new worktree")
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "lib", "README.md")); err == nil {
+		t.Fatal("submodule checked out before UpdateSubmodules")
+	}
+
+	if err := UpdateSubmodules(ctx, wtPath); err != nil {
+		t.Fatalf("UpdateSubmodules failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "lib", "README.md")); err != nil {
+		t.Errorf("submodule not checked out: %v", err)
+	}
+}
+
+func TestAddSparseWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	for _, dir := range []string{"apps/web", "apps/api", "libs"} {
+		os.MkdirAll(filepath.Join(repo.dir, dir), 0o755)
+		os.WriteFile(filepath.Join(repo.dir, dir, "main.go"), []byte("package main\n"), 0o644)
+	}
+	runCmd("git", "add", ".")
+	runCmd("git", "commit", "-m", "monorepo")
+
+	wtPath := filepath.Join(repo.dir, ".worktrees", "web")
+	if err := AddSparseWorktree(ctx, wtPath, "web", true, []string{"apps/web/", " libs", ""}); err != nil {
+		t.Fatalf("AddSparseWorktree failed: %v", err)
+	}
+
+	for _, path := range []string{"README.md", "apps/web/main.go", "libs/main.go"} {
+		if _, err := os.Stat(filepath.Join(wtPath, path)); err != nil {
+			t.Errorf("%s not checked out: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "apps", "api")); err == nil {
+		t.Error("apps/api checked out outside the sparse cone")
+	}
+	if !BranchExists(ctx, "web") {
+		t.Error("branch web was not created")
+	}
+}
+
+func TestStageAllAndReadTree(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	tree, err := WriteTree(ctx)
+	if err != nil {
+		t.Fatalf("WriteTree failed: %v", err)
+	}
+
+	os.WriteFile("README.md", []byte("# Changed\n"), 0o644)
+	os.WriteFile("new.txt", []byte("new\n"), 0o644)
+
+	if err := StageAll(ctx, false); err != nil {
+		t.Fatalf("StageAll failed: %v", err)
+	}
+	diff, _ := GetStagedDiff(ctx)
+	if !strings.Contains(diff, "README.md") || strings.Contains(diff, "new.txt") {
+		t.Errorf("StageAll(false) staged:\n%s", diff)
+	}
+
+	if err := StageAll(ctx, true); err != nil {
+		t.Fatalf("StageAll failed: %v", err)
+	}
+	if diff, _ := GetStagedDiff(ctx); !strings.Contains(diff, "new.txt") {
+		t.Errorf("StageAll(true) did not stage new.txt:\n%s", diff)
+	}
+
+	if err := ReadTree(ctx, tree); err != nil {
+		t.Fatalf("ReadTree failed: %v", err)
+	}
+	if diff, _ := GetStagedDiff(ctx); diff != "" {
+		t.Errorf("index was not restored:\n%s", diff)
+	}
+	if content, _ := os.ReadFile("README.md"); string(content) != "# Changed\n" {
+		t.Error("ReadTree touched the working tree")
+	}
+}
+
+func TestTagsAndCommitMessages(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	if err := CreateTag(ctx, "v1.9.0", "Release v1.9.0\n\n### Fixes"); err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+	if out, _ := runGit(ctx, "tag", "-l", "--format=%(contents)", "v1.9.0"); !strings.Contains(out, "### Fixes") {
+		t.Errorf("tag message = %q", out)
+	}
+	runCmd("git", "commit", "--allow-empty", "-m", "feat: add export\n\nCSV only.")
+	CreateTag(ctx, "v1.10.0", "Release v1.10.0")
+	runCmd("git", "commit", "--allow-empty", "-m", "fix: typo")
+
+	tags, err := MergedTags(ctx)
+	if err != nil || strings.Join(tags, ",") != "v1.10.0,v1.9.0" {
+		t.Errorf("MergedTags = %v, %v", tags, err)
+	}
+	if !TagExists(ctx, "v1.9.0") || TagExists(ctx, "v2.0.0") {
+		t.Error("TagExists is wrong")
+	}
+
+	messages, err := CommitMessages(ctx, "v1.9.0", "HEAD")
+	if err != nil {
+		t.Fatalf("CommitMessages failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0] != "feat: add export\n\nCSV only." || messages[1] != "fix: typo" {
+		t.Errorf("CommitMessages = %q", messages)
+	}
+	if all, _ := CommitMessages(ctx, "", "HEAD"); len(all) != 3 {
+		t.Errorf("CommitMessages without base = %q", all)
+	}
+}
+
+func TestAuthoredCommits(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	runCmd("git", "checkout", "-b", "feature")
+	runCmd("git", "commit", "--allow-empty", "-m", "Add export")
+	runCmd("git", "-c", "user.email=other@test.com", "commit", "--allow-empty", "-m", "Not mine")
+
+	email, err := UserEmail(ctx, repo.dir)
+	if err != nil || email != "test@test.com" {
+		t.Fatalf("UserEmail = %q, %v", email, err)
+	}
+
+	commits, err := AuthoredCommits(ctx, repo.dir, email, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("AuthoredCommits failed: %v", err)
+	}
+	branches := map[string]string{}
+	for _, c := range commits {
+		branches[c.Subject] = c.Branch
+	}
+	if len(commits) != 2 || branches["Add export"] != "feature" {
+		t.Errorf("AuthoredCommits = %+v, want Initial commit and Add export on feature", commits)
+	}
+
+	if commits, _ := AuthoredCommits(ctx, repo.dir, email, time.Now().Add(time.Hour)); len(commits) != 0 {
+		t.Errorf("AuthoredCommits in the future = %+v", commits)
+	}
+}
+
+func TestLocalBranches(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	base, _ := CurrentBranch(ctx)
+	remote := filepath.Join(t.TempDir(), "origin.git")
+	runCmd("git", "init", "--bare", remote)
+	runCmd("git", "remote", "add", "origin", remote)
+
+	runCmd("git", "branch", "merged")
+	runCmd("git", "checkout", "-b", "unmerged")
+	runCmd("git", "commit", "--allow-empty", "-m", "Work")
+	runCmd("git", "push", "-u", "origin", "unmerged")
+	runCmd("git", "push", "origin", "--delete", "unmerged")
+	runCmd("git", "checkout", base)
+
+	branches, err := LocalBranches(ctx, base)
+	if err != nil {
+		t.Fatalf("LocalBranches failed: %v", err)
+	}
+	got := map[string]LocalBranch{}
+	for _, b := range branches {
+		got[b.Name] = b
+	}
+	if b := got["merged"]; !b.Merged || b.Gone {
+		t.Errorf("merged = %+v", b)
+	}
+	if b := got["unmerged"]; b.Merged || !b.Gone || b.Upstream != "origin/unmerged" {
+		t.Errorf("unmerged = %+v", b)
+	}
+	if len(branches) != 3 {
+		t.Errorf("LocalBranches = %+v", branches)
+	}
+}
+
+func TestExcludeLocal(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	os.WriteFile("app.code-workspace", []byte("{}"), 0o644)
+	for range 2 {
+		if err := ExcludeLocal(ctx, "/app.code-workspace"); err != nil {
+			t.Fatalf("ExcludeLocal failed: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join(".git", "info", "exclude"))
+	if n := strings.Count(string(data), "/app.code-workspace\n"); n != 1 {
+		t.Errorf("pattern written %d times:\n%s", n, data)
+	}
+	if HasUncommittedChanges(ctx) {
+		t.Error("excluded file still shows as untracked")
+	}
+}
+
+type fakeRunner struct{ output string }
+
+func (f fakeRunner) Run(ctx context.Context, args ...string) (string, error) {
+	return f.output, nil
+}
+
+func TestWithRunner(t *testing.T) {
+	prev := SetRunner(fakeRunner{"global\n"})
+	defer SetRunner(prev)
+
+	ctx := WithRunner(t.Context(), fakeRunner{"scoped\n"})
+	if branch, _ := CurrentBranch(ctx); branch != "scoped" {
+		t.Errorf("CurrentBranch with a context runner = %q, want scoped", branch)
+	}
+	if branch, _ := CurrentBranch(t.Context()); branch != "global" {
+		t.Errorf("CurrentBranch = %q, want the active runner's global", branch)
+	}
+}
+
+func TestSearchMessagesAndDiffs(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	os.WriteFile("a.txt", []byte("timeout = 30 (seconds)\n"), 0o644)
+	runCmd("git", "add", "a.txt")
+	runCmd("git", "commit", "-m", "Add settings", "-m", "Raise the Timeout for slow networks")
+	os.WriteFile("b.txt", []byte("b\n"), 0o644)
+	runCmd("git", "add", "b.txt")
+	runCmd("git", "commit", "-m", "Add b")
+
+	commits, err := SearchMessages(ctx, "timeout for", 0)
+	if err != nil {
+		t.Fatalf("SearchMessages failed: %v", err)
+	}
+	if len(commits) != 1 || commits[0].Subject != "Add settings" || !strings.HasSuffix(commits[0].Message, "slow networks") {
+		t.Fatalf("message matches = %+v, want the settings commit", commits)
+	}
+
+	commits, err = SearchDiffs(ctx, "30 (SECONDS)", 0)
+	if err != nil {
+		t.Fatalf("SearchDiffs failed: %v", err)
+	}
+	if len(commits) != 1 || commits[0].Subject != "Add settings" {
+		t.Fatalf("diff matches = %+v, want the settings commit", commits)
+	}
+
+	if commits, _ := RecentMessages(ctx, 2); len(commits) != 2 || commits[0].Subject != "Add b" {
+		t.Errorf("recent = %+v, want the last two commits", commits)
+	}
+	if patch, _ := CommitPatch(ctx, "HEAD~1"); !strings.Contains(patch, "+timeout = 30") {
+		t.Errorf("patch = %q", patch)
+	}
+}
@@ -0,0 +1,86 @@
+// Package gittest provides a fake git.Runner for tests that should not
+// create real repositories.
+package gittest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+)
+
+// Runner is a git.Runner that answers commands from canned responses,
+// keyed by their space-joined arguments (e.g. "worktree list --porcelain"),
+// and records every call. Unknown commands fail like git would.
+type Runner struct {
+	mu        sync.Mutex
+	responses map[string]response
+	calls     []string
+}
+
+type response struct {
+	output string
+	err    error
+}
+
+// New returns an empty Runner and installs it for the duration of the test
+func New(t testing.TB) *Runner {
+	r := &Runner{responses: map[string]response{}}
+	prev := git.SetRunner(r)
+	t.Cleanup(func() { git.SetRunner(prev) })
+	return r
+}
+
+// On makes the command with args succeed with output
+func (r *Runner) On(args string, output string) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses[args] = response{output: output}
+	return r
+}
+
+// Fail makes the command with args fail with err
+func (r *Runner) Fail(args string, err error) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses[args] = response{err: err}
+	return r
+}
+
+// Calls returns the commands run so far
+func (r *Runner) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+// Called reports whether the command with args was run
+func (r *Runner) Called(args string) bool {
+	for _, call := range r.Calls() {
+		if call == args {
+			return true
+		}
+	}
+	return false
+}
+
+// Run implements git.Runner
+func (r *Runner) Run(ctx context.Context, args ...string) (string, error) {
+	key := strings.Join(args, " ")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, key)
+
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("git %s: %w", key, err)
+	}
+	resp, ok := r.responses[key]
+	if !ok {
+		return "", fmt.Errorf("git %s: no response configured", key)
+	}
+	return resp.output, resp.err
+}
@@ -0,0 +1,147 @@
+package git
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// FilePatch is the part of a patch changing one file
+type FilePatch struct {
+	Path string
+	// Header holds the lines before the first hunk ("diff --git", index,
+	// mode and ---/+++ lines), or the whole patch of a file without hunks
+	// such as a binary file or a mode change
+	Header []string
+	Hunks  []Hunk
+	// Untracked marks a new file git doesn't know yet; it has no patch
+	Untracked bool
+}
+
+// Hunk is a "@@" section of a FilePatch
+type Hunk struct {
+	Header string
+	Lines  []string
+}
+
+// Units returns the number of parts of f that can be staged on their own:
+// its hunks, or the whole file if it has none
+func (f FilePatch) Units() int {
+	if len(f.Hunks) == 0 {
+		return 1
+	}
+	return len(f.Hunks)
+}
+
+// ParsePatch splits the output of 'git diff' into one FilePatch per file
+func ParsePatch(patch string) []FilePatch {
+	var files []FilePatch
+	var file *FilePatch
+	for _, line := range strings.SplitAfter(patch, "\n") {
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, FilePatch{Path: patchPath(line), Header: []string{line}})
+			file = &files[len(files)-1]
+		case file == nil:
+			// Nothing before the first file header belongs to a file
+		case strings.HasPrefix(line, "@@"):
+			file.Hunks = append(file.Hunks, Hunk{Header: line})
+		case len(file.Hunks) > 0:
+			h := &file.Hunks[len(file.Hunks)-1]
+			h.Lines = append(h.Lines, line)
+		default:
+			file.Header = append(file.Header, line)
+		}
+	}
+	return files
+}
+
+// patchPath returns the path a "diff --git a/<old> b/<new>" line changes
+func patchPath(line string) string {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "diff --git "), "\n")
+	if i := strings.LastIndex(line, " b/"); i >= 0 {
+		return line[i+3:]
+	}
+	return line
+}
+
+// BuildPatch returns the patch of the selected parts of files, where
+// selected[i][j] selects unit j of files[i] (see Units). Untracked files
+// have no patch and are left out.
+func BuildPatch(files []FilePatch, selected [][]bool) string {
+	var b strings.Builder
+	for i, f := range files {
+		if f.Untracked || i >= len(selected) {
+			continue
+		}
+		if len(f.Hunks) == 0 {
+			if len(selected[i]) > 0 && selected[i][0] {
+				b.WriteString(strings.Join(f.Header, ""))
+			}
+			continue
+		}
+
+		var hunks strings.Builder
+		for j, h := range f.Hunks {
+			if j < len(selected[i]) && selected[i][j] {
+				hunks.WriteString(h.Header)
+				hunks.WriteString(strings.Join(h.Lines, ""))
+			}
+		}
+		if hunks.Len() > 0 {
+			b.WriteString(strings.Join(f.Header, ""))
+			b.WriteString(hunks.String())
+		}
+	}
+	return b.String()
+}
+
+// UnstagedChanges returns the changes to tracked files that are not
+// staged, one FilePatch per file, followed by the untracked files
+func UnstagedChanges(ctx context.Context) ([]FilePatch, error) {
+	output, err := runGit(ctx, "diff", "--binary", "--no-color", "--no-ext-diff")
+	if err != nil {
+		return nil, err
+	}
+	files := ParsePatch(output)
+
+	root, err := GetRepoRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := UntrackedFiles(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range untracked {
+		files = append(files, FilePatch{Path: path, Untracked: true})
+	}
+	return files, nil
+}
+
+// ApplyCached applies patch, made against the working tree by 'git diff',
+// to the index only
+func ApplyCached(ctx context.Context, patch string) error {
+	f, err := os.CreateTemp("", "lazywork-stage-*.patch")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(patch); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	root, err := GetRepoRoot(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = runGit(ctx, "-C", root, "apply", "--cached", "--whitespace=nowarn", f.Name())
+	return err
+}
@@ -0,0 +1,83 @@
+package git
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const twoFilePatch = `diff --git a/app.go b/app.go
+index 1111111..2222222 100644
+--- a/app.go
++++ b/app.go
+@@ -1,3 +1,3 @@
+ package app
+-var a = 1
++var a = 2
+@@ -10,2 +10,3 @@ func b() {
+ 	b()
++	c()
+ }
+diff --git a/logo.png b/logo.png
+index 3333333..4444444 100644
+GIT binary patch
+literal 1
+Ic${AA00001
+
+`
+
+func TestParseAndBuildPatch(t *testing.T) {
+	files := ParsePatch(twoFilePatch)
+	if len(files) != 2 || files[0].Path != "app.go" || files[1].Path != "logo.png" {
+		t.Fatalf("files = %+v, want app.go and logo.png", files)
+	}
+	if files[0].Units() != 2 || len(files[0].Hunks[1].Lines) != 3 || files[1].Units() != 1 {
+		t.Fatalf("units = %d, %d; want app.go's two hunks and logo.png whole", files[0].Units(), files[1].Units())
+	}
+
+	patch := BuildPatch(files, [][]bool{{false, true}, {false}})
+	if !strings.HasPrefix(patch, "diff --git a/app.go b/app.go\n") || strings.Contains(patch, "var a") || !strings.HasSuffix(patch, "+\tc()\n }\n") {
+		t.Errorf("patch = %q, want only the second hunk of app.go", patch)
+	}
+	if BuildPatch(files, [][]bool{{false, false}, {true}}) != strings.Join(files[1].Header, "") {
+		t.Error("selecting logo.png should give its whole patch")
+	}
+	if BuildPatch(files, [][]bool{{false, false}, {false}}) != "" {
+		t.Error("selecting nothing should give an empty patch")
+	}
+}
+
+func TestUnstagedChangesAndApplyCached(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	os.WriteFile("a.txt", []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+	runCmd("git", "add", "a.txt")
+	runCmd("git", "commit", "-m", "Add a")
+
+	lines[0], lines[19] = "first", "last"
+	os.WriteFile("a.txt", []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+	os.WriteFile("new.txt", []byte("new\n"), 0o644)
+
+	files, err := UnstagedChanges(ctx)
+	if err != nil {
+		t.Fatalf("UnstagedChanges failed: %v", err)
+	}
+	if len(files) != 2 || files[0].Units() != 2 || !files[1].Untracked || files[1].Path != "new.txt" {
+		t.Fatalf("files = %+v, want a.txt with two hunks and untracked new.txt", files)
+	}
+
+	if err := ApplyCached(ctx, BuildPatch(files, [][]bool{{false, true}, {true}})); err != nil {
+		t.Fatalf("ApplyCached failed: %v", err)
+	}
+	staged, _ := GetStagedDiff(ctx)
+	unstaged, _ := GetUnstagedDiff(ctx)
+	if !strings.Contains(staged, "+last") || strings.Contains(staged, "+first") || !strings.Contains(unstaged, "+first") {
+		t.Errorf("staged = %q, unstaged = %q; want only the last hunk staged", staged, unstaged)
+	}
+}
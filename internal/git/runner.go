@@ -0,0 +1,94 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Runner runs a git command and returns its standard output, which is
+// also returned alongside the error when git exits non-zero. Every
+// function in this package goes through the context's Runner (see
+// WithRunner) or else the active one, so tests can replace it (see the
+// gittest package) and other backends can slot in.
+type Runner interface {
+	Run(ctx context.Context, args ...string) (string, error)
+}
+
+// ExecRunner runs the git binary found in PATH
+type ExecRunner struct{}
+
+// runner is the active Runner; see SetRunner
+var runner Runner = ExecRunner{}
+
+// SetRunner replaces the Runner used by this package and returns the
+// previous one so it can be restored
+func SetRunner(r Runner) Runner {
+	prev := runner
+	runner = r
+	return prev
+}
+
+type runnerKey struct{}
+
+// WithRunner returns a copy of ctx whose git commands go through r instead
+// of the active Runner, so callers embedding lazywork can bring their own
+// without affecting other goroutines
+func WithRunner(ctx context.Context, r Runner) context.Context {
+	return context.WithValue(ctx, runnerKey{}, r)
+}
+
+// timeout bounds each git invocation; see SetTimeout
+var timeout time.Duration
+
+// SetTimeout limits how long a single git command may run before it is
+// killed, so hung credential prompts or slow fetches cannot block forever.
+// Zero, the default, means no limit; cancelling the context always stops
+// the command.
+func SetTimeout(d time.Duration) {
+	timeout = d
+}
+
+func runGit(ctx context.Context, args ...string) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if r, ok := ctx.Value(runnerKey{}).(Runner); ok {
+		return r.Run(ctx, args...)
+	}
+	return runner.Run(ctx, args...)
+}
+
+// Run implements Runner
+func (ExecRunner) Run(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Give git a moment to exit after being killed before giving up on
+	// its output pipes
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return "", fmt.Errorf("git %s: timed out after %s: %w", strings.Join(args, " "), timeout, ctx.Err())
+		case context.Canceled:
+			return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), ctx.Err())
+		}
+		errMsg := strings.TrimSpace(stderr.String())
+		if errMsg == "" {
+			errMsg = err.Error()
+		}
+		// Some commands, such as merge-tree, report results with a failing
+		// exit status
+		return stdout.String(), fmt.Errorf("git %s: %s", strings.Join(args, " "), errMsg)
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,66 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CommitText is a commit with its whole message
+type CommitText struct {
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Author  string    `json:"author"`
+	Subject string    `json:"subject"`
+	Message string    `json:"message"`
+}
+
+// SearchMessages returns the commits reachable from HEAD whose message
+// contains query, ignoring case, newest first. max bounds how many are
+// returned; zero returns them all.
+func SearchMessages(ctx context.Context, query string, max int) ([]CommitText, error) {
+	return logMessages(ctx, max, "--grep="+query, "--regexp-ignore-case", "--fixed-strings")
+}
+
+// SearchDiffs returns the commits reachable from HEAD whose diff adds or
+// removes a line containing query, ignoring case, newest first
+func SearchDiffs(ctx context.Context, query string, max int) ([]CommitText, error) {
+	// -G always takes an extended regular expression
+	return logMessages(ctx, max, "-G"+regexp.QuoteMeta(query), "--regexp-ignore-case")
+}
+
+// RecentMessages returns the last max commits reachable from HEAD
+func RecentMessages(ctx context.Context, max int) ([]CommitText, error) {
+	return logMessages(ctx, max)
+}
+
+func logMessages(ctx context.Context, max int, filters ...string) ([]CommitText, error) {
+	args := append([]string{"log", "--format=%H%x1f%aI%x1f%an%x1f%B%x1e"}, filters...)
+	if max > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", max))
+	}
+	output, err := runGit(ctx, append(args, "HEAD", "--")...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []CommitText
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[1])
+		message := strings.TrimSpace(fields[3])
+		subject, _, _ := strings.Cut(message, "\n")
+		commits = append(commits, CommitText{Hash: fields[0], Date: date, Author: fields[2], Subject: subject, Message: message})
+	}
+	return commits, nil
+}
+
+// CommitPatch returns the changes made by commit, without context lines
+func CommitPatch(ctx context.Context, commit string) (string, error) {
+	return runGit(ctx, "show", "--format=", "--unified=0", "--no-color", "--no-ext-diff", commit, "--")
+}
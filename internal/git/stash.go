@@ -0,0 +1,136 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AutoStashPrefix starts the message of every stash lazywork creates itself
+const AutoStashPrefix = "lazywork:"
+
+// StashEntry is a single entry of the stash list
+type StashEntry struct {
+	Ref     string    `json:"ref"`
+	Hash    string    `json:"hash"`
+	Branch  string    `json:"branch"`
+	Message string    `json:"message"`
+	Created time.Time `json:"created"`
+}
+
+// IsAuto reports whether the stash was created by lazywork, e.g. by
+// 'worktree use'
+func (s StashEntry) IsAuto() bool {
+	return strings.HasPrefix(s.Message, AutoStashPrefix)
+}
+
+// Matches reports whether id, a stash commit hash or a stash@{n} ref,
+// refers to this stash
+func (s StashEntry) Matches(id string) bool {
+	return id != "" && (id == s.Hash || id == s.Ref)
+}
+
+// FindStash returns the stash identified by id, a commit hash or a
+// stash@{n} ref, or nil if it is not in stashes
+func FindStash(stashes []StashEntry, id string) *StashEntry {
+	for i := range stashes {
+		if stashes[i].Matches(id) {
+			return &stashes[i]
+		}
+	}
+	return nil
+}
+
+// ListStashes returns the stash list, newest first
+func ListStashes(ctx context.Context) ([]StashEntry, error) {
+	output, err := runGit(ctx, "stash", "list", "--format=%gd%x00%H%x00%ct%x00%gs")
+	if err != nil {
+		return nil, err
+	}
+	return parseStashList(output), nil
+}
+
+func parseStashList(output string) []StashEntry {
+	var stashes []StashEntry
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+
+		s := StashEntry{Ref: fields[0], Hash: fields[1]}
+		if sec, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			s.Created = time.Unix(sec, 0)
+		}
+
+		// The subject is "On <branch>: <message>" for stashes with a
+		// message and "WIP on <branch>: <commit> <subject>" otherwise
+		subject := strings.TrimPrefix(fields[3], "WIP ")
+		subject = strings.TrimPrefix(subject, "On ")
+		subject = strings.TrimPrefix(subject, "on ")
+		if branch, message, ok := strings.Cut(subject, ": "); ok {
+			s.Branch, s.Message = branch, message
+		} else {
+			s.Message = fields[3]
+		}
+		stashes = append(stashes, s)
+	}
+	return stashes
+}
+
+// StashRef normalizes a stash selector: a bare index such as "2" becomes
+// "stash@{2}", anything else is returned unchanged
+func StashRef(selector string) string {
+	if n, err := strconv.Atoi(selector); err == nil && n >= 0 {
+		return fmt.Sprintf("stash@{%d}", n)
+	}
+	return selector
+}
+
+// StashApply applies the stash at ref to the working tree, removing it
+// from the stash list if pop is set
+func StashApply(ctx context.Context, ref string, pop bool) error {
+	action := "apply"
+	if pop {
+		action = "pop"
+	}
+	_, err := runGit(ctx, "stash", action, ref)
+	return err
+}
+
+// StashApplyIn applies the stash at ref to the working tree of the
+// worktree at dir, keeping it in the stash list
+func StashApplyIn(ctx context.Context, dir, ref string) error {
+	_, err := runGit(ctx, "-C", dir, "stash", "apply", ref)
+	return err
+}
+
+// StashChanges stashes the uncommitted changes of the current worktree,
+// untracked files included, and returns the stash's hash, or "" if there
+// was nothing to stash. Nested worktrees are left alone.
+func StashChanges(ctx context.Context, message string) (string, error) {
+	before, _ := runGit(ctx, "rev-parse", "-q", "--verify", "refs/stash")
+	if _, err := runGit(ctx, "stash", "push", "--include-untracked", "-m", message); err != nil {
+		return "", err
+	}
+	// git stash succeeds without creating a stash when there is nothing
+	// to save, so compare the top of the stash list
+	after, err := runGit(ctx, "rev-parse", "-q", "--verify", "refs/stash")
+	if err != nil || strings.TrimSpace(after) == strings.TrimSpace(before) {
+		return "", nil
+	}
+	return strings.TrimSpace(after), nil
+}
+
+// StashDrop removes the stash at ref
+func StashDrop(ctx context.Context, ref string) error {
+	_, err := runGit(ctx, "stash", "drop", ref)
+	return err
+}
+
+// StashShow returns the diffstat and patch of the stash at ref
+func StashShow(ctx context.Context, ref string) (string, error) {
+	return runGit(ctx, "stash", "show", "--stat", "--patch", ref)
+}
@@ -0,0 +1,73 @@
+package git
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseStashList(t *testing.T) {
+	output := "stash@{0}\x00aaa\x001700000000\x00WIP on main: 3ed43b1 initial\n" +
+		"stash@{1}\x00bbb\x001700000100\x00On feature/x: lazywork: auto-stash before worktree use\n"
+
+	stashes := parseStashList(output)
+	if len(stashes) != 2 {
+		t.Fatalf("got %d stashes, want 2", len(stashes))
+	}
+
+	if s := stashes[0]; s.Ref != "stash@{0}" || s.Branch != "main" || s.Message != "3ed43b1 initial" || s.IsAuto() {
+		t.Errorf("stash 0 = %+v", s)
+	}
+	if s := stashes[1]; s.Branch != "feature/x" || !s.IsAuto() || s.Created.Unix() != 1700000100 {
+		t.Errorf("stash 1 = %+v", s)
+	}
+}
+
+func TestStashRef(t *testing.T) {
+	for selector, want := range map[string]string{
+		"0":         "stash@{0}",
+		"3":         "stash@{3}",
+		"stash@{1}": "stash@{1}",
+		"-1":        "-1",
+	} {
+		if got := StashRef(selector); got != want {
+			t.Errorf("StashRef(%q) = %q, want %q", selector, got, want)
+		}
+	}
+}
+
+func TestStashApplyDrop(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+	ctx := t.Context()
+
+	os.WriteFile("README.md", []byte("# Changed\n"), 0o644)
+	if _, err := Stash(ctx, AutoStashPrefix+" test"); err != nil {
+		t.Fatalf("Stash failed: %v", err)
+	}
+
+	stashes, err := ListStashes(ctx)
+	if err != nil {
+		t.Fatalf("ListStashes failed: %v", err)
+	}
+	if len(stashes) != 1 || !stashes[0].IsAuto() {
+		t.Fatalf("stashes = %+v", stashes)
+	}
+
+	if diff, err := StashShow(ctx, stashes[0].Ref); err != nil || diff == "" {
+		t.Errorf("StashShow = %q, %v", diff, err)
+	}
+
+	if err := StashApply(ctx, stashes[0].Ref, false); err != nil {
+		t.Fatalf("StashApply failed: %v", err)
+	}
+	if data, _ := os.ReadFile("README.md"); string(data) != "# Changed\n" {
+		t.Errorf("README.md = %q after apply", data)
+	}
+
+	if err := StashDrop(ctx, stashes[0].Ref); err != nil {
+		t.Fatalf("StashDrop failed: %v", err)
+	}
+	if stashes, _ := ListStashes(ctx); len(stashes) != 0 {
+		t.Errorf("stash not dropped: %+v", stashes)
+	}
+}
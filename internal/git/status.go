@@ -0,0 +1,131 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultStatusWorkers bounds how many worktrees StatusAll queries at once
+const DefaultStatusWorkers = 8
+
+// Status summarizes the state of a worktree's checkout
+type Status struct {
+	Path      string `json:"path"`
+	Branch    string `json:"branch,omitempty"`
+	Staged    int    `json:"staged"`
+	Modified  int    `json:"modified"`
+	Untracked int    `json:"untracked"`
+	Conflicts int    `json:"conflicts"`
+	// Upstream is the ref Ahead and Behind are counted against: the
+	// branch's upstream if it has one, otherwise the base branch
+	Upstream string `json:"upstream,omitempty"`
+	Ahead    int    `json:"ahead"`
+	Behind   int    `json:"behind"`
+	// Gone is set when the upstream was deleted from the remote
+	Gone  bool   `json:"gone,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Dirty returns true if the worktree has uncommitted or untracked changes
+func (s Status) Dirty() bool {
+	return s.Staged+s.Modified+s.Untracked+s.Conflicts > 0
+}
+
+// WorktreeStatus returns the status of the worktree at path. When the
+// branch has no upstream, ahead/behind are counted against base (e.g. the
+// main branch); pass "" to skip that comparison.
+func WorktreeStatus(ctx context.Context, path, base string) (Status, error) {
+	output, err := runGit(ctx, "-C", path, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return Status{Path: path}, err
+	}
+	s := parseStatus(output)
+	s.Path = path
+
+	if s.Upstream == "" && base != "" && s.Branch != "" && s.Branch != base {
+		output, err := runGit(ctx, "-C", path, "rev-list", "--left-right", "--count", "HEAD..."+base)
+		if err != nil {
+			return s, err
+		}
+		fields := strings.Fields(output)
+		if len(fields) != 2 {
+			return s, fmt.Errorf("unexpected rev-list output: %q", output)
+		}
+		s.Upstream = base
+		s.Ahead, _ = strconv.Atoi(fields[0])
+		s.Behind, _ = strconv.Atoi(fields[1])
+	}
+
+	return s, nil
+}
+
+// StatusAll returns the status of every non-bare worktree, in order,
+// querying up to workers worktrees concurrently. A worktree that cannot be
+// queried has its Error set rather than failing the whole call.
+func StatusAll(ctx context.Context, worktrees []Worktree, base string, workers int) []Status {
+	if workers < 1 {
+		workers = DefaultStatusWorkers
+	}
+
+	statuses := make([]Status, len(worktrees))
+	var g errgroup.Group
+	g.SetLimit(workers)
+
+	for i, wt := range worktrees {
+		if wt.Bare {
+			statuses[i] = Status{Path: wt.Path}
+			continue
+		}
+		g.Go(func() error {
+			s, err := WorktreeStatus(ctx, wt.Path, base)
+			if err != nil {
+				s.Error = err.Error()
+			}
+			statuses[i] = s
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return statuses
+}
+
+// parseStatus parses 'git status --porcelain=v2 --branch' output
+func parseStatus(output string) Status {
+	var s Status
+	counted := false
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.head "):
+			if head := strings.TrimPrefix(line, "# branch.head "); head != "(detached)" {
+				s.Branch = head
+			}
+		case strings.HasPrefix(line, "# branch.upstream "):
+			s.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &s.Ahead, &s.Behind)
+			counted = true
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "):
+			// "1 XY ..." where X is the staged and Y the unstaged state
+			if len(line) > 3 {
+				if line[2] != '.' {
+					s.Staged++
+				}
+				if line[3] != '.' {
+					s.Modified++
+				}
+			}
+		case strings.HasPrefix(line, "u "):
+			s.Conflicts++
+		case strings.HasPrefix(line, "? "):
+			s.Untracked++
+		}
+	}
+	// git leaves out the counts when the upstream no longer exists
+	s.Gone = s.Upstream != "" && !counted
+	return s
+}
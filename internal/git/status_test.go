@@ -0,0 +1,91 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	output := `# branch.oid 1111111111111111111111111111111111111111
+# branch.head feature
+# branch.upstream origin/feature
+# branch.ab +2 -1
+1 M. N... 100644 100644 100644 aaa bbb staged.go
+1 .M N... 100644 100644 100644 aaa bbb modified.go
+1 MM N... 100644 100644 100644 aaa bbb both.go
+2 R. N... 100644 100644 100644 aaa bbb R100 new.go	old.go
+u UU N... 100644 100644 100644 100644 aaa bbb ccc conflict.go
+? untracked.txt
+`
+	s := parseStatus(output)
+
+	want := Status{Branch: "feature", Upstream: "origin/feature", Ahead: 2, Behind: 1,
+		Staged: 3, Modified: 2, Untracked: 1, Conflicts: 1}
+	if s != want {
+		t.Errorf("parseStatus = %+v, want %+v", s, want)
+	}
+	if !s.Dirty() {
+		t.Error("expected Dirty")
+	}
+
+	if s := parseStatus("# branch.head (detached)\n"); s.Branch != "" || s.Dirty() {
+		t.Errorf("detached clean status = %+v", s)
+	}
+
+	if s := parseStatus("# branch.head feature\n# branch.upstream origin/feature\n"); !s.Gone {
+		t.Errorf("status without counts should be gone: %+v", s)
+	}
+}
+
+func TestStatusAll(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.cleanup()
+
+	base := GetMainBranch(t.Context())
+	os.WriteFile(filepath.Join(".git", "info", "exclude"), []byte(".worktrees/\n"), 0o644)
+	for _, name := range []string{"one", "two", "three"} {
+		path := filepath.Join(repo.dir, ".worktrees", name)
+		if err := AddWorktree(t.Context(), path, name); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+	}
+
+	two := filepath.Join(repo.dir, ".worktrees", "two")
+	os.WriteFile(filepath.Join(two, "wip.txt"), []byte("wip"), 0o644)
+	runCmd("git", "-C", two, "add", "wip.txt")
+	runCmd("git", "-C", two, "commit", "-m", "wip")
+	os.WriteFile(filepath.Join(two, "more.txt"), []byte("more"), 0o644)
+
+	worktrees, err := ListWorktrees(t.Context())
+	if err != nil {
+		t.Fatalf("ListWorktrees failed: %v", err)
+	}
+
+	statuses := StatusAll(t.Context(), worktrees, base, 2)
+	if len(statuses) != len(worktrees) {
+		t.Fatalf("got %d statuses for %d worktrees", len(statuses), len(worktrees))
+	}
+	for i, s := range statuses {
+		if s.Path != worktrees[i].Path {
+			t.Errorf("status %d is for %s, want %s", i, s.Path, worktrees[i].Path)
+		}
+		if s.Error != "" {
+			t.Errorf("%s: %s", s.Path, s.Error)
+		}
+		wantDirty := s.Path == two
+		if s.Dirty() != wantDirty {
+			t.Errorf("%s: Dirty = %v, want %v", s.Path, s.Dirty(), wantDirty)
+		}
+	}
+
+	var s Status
+	for _, st := range statuses {
+		if st.Path == two {
+			s = st
+		}
+	}
+	if s.Branch != "two" || s.Upstream != base || s.Ahead != 1 || s.Behind != 0 || s.Untracked != 1 {
+		t.Errorf("status of two = %+v, want 1 ahead of %s with 1 untracked file", s, base)
+	}
+}
@@ -0,0 +1,279 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/state"
+)
+
+const (
+	// useStackFile holds the stack in the lazywork state directory of the
+	// common dir, shared by every worktree
+	useStackFile = "use-stack.json"
+
+	// Stack written to the git dir of the main worktree by older versions
+	stateUseStack = "LAZYWORK_USE_STACK"
+	// Single-frame state written before 'worktree use' could be stacked
+	statePreviousBranch = "LAZYWORK_PREVIOUS_BRANCH"
+	stateStashRef       = "LAZYWORK_STASH_REF"
+)
+
+// UseFrame records one 'worktree use': the branch to return to and the
+// auto-stash holding the changes that were uncommitted at the time
+type UseFrame struct {
+	Branch  string    `json:"branch"`
+	Target  string    `json:"target,omitempty"`
+	Stash   string    `json:"stash,omitempty"`
+	Created time.Time `json:"created,omitempty"`
+}
+
+// ReadUseStack reads the 'worktree use' stack of the repository whose git
+// common dir is commonDir without running git, oldest frame first. State
+// left by older versions in commonDir, gitDir or the git dir of any
+// linked worktree is merged in.
+func ReadUseStack(commonDir, gitDir string) ([]UseFrame, error) {
+	frames, _, err := readUseStack(commonDir, gitDir)
+	return frames, err
+}
+
+// readUseStack is ReadUseStack, also reporting whether frames were read
+// from state left by older versions
+func readUseStack(commonDir, gitDir string) ([]UseFrame, bool, error) {
+	var frames []UseFrame
+	data, err := os.ReadFile(useStackPath(commonDir))
+	if err == nil {
+		if err := json.Unmarshal(data, &frames); err != nil {
+			return nil, false, fmt.Errorf("failed to parse use state: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, false, err
+	}
+
+	// Older versions wrote to the git dir of whichever checkout ran 'use',
+	// so any of them can hold frames and stash refs; keep them all, even
+	// once the stack file exists
+	legacy := false
+	for _, dir := range legacyUseDirs(commonDir, gitDir) {
+		found, err := readLegacyUseStack(dir)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(found) > 0 {
+			frames = append(frames, found...)
+			legacy = true
+		}
+	}
+	if legacy {
+		sort.SliceStable(frames, func(i, j int) bool { return frames[i].Created.Before(frames[j].Created) })
+	}
+	return frames, legacy, nil
+}
+
+// legacyUseDirs returns the directories older versions may have kept the
+// stack in: commonDir, gitDir and the git dirs of the linked worktrees
+func legacyUseDirs(commonDir, gitDir string) []string {
+	dirs := []string{commonDir}
+	entries, _ := os.ReadDir(filepath.Join(commonDir, "worktrees"))
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs = append(dirs, filepath.Join(commonDir, "worktrees", e.Name()))
+		}
+	}
+	if gitDir != "" && !slices.ContainsFunc(dirs, func(dir string) bool { return samePath(dir, gitDir) }) {
+		dirs = append(dirs, gitDir)
+	}
+	return dirs
+}
+
+// readLegacyUseStack reads the stack older versions wrote to dir. The
+// single-frame state from before 'worktree use' could be stacked is read
+// as one frame.
+func readLegacyUseStack(dir string) ([]UseFrame, error) {
+	data, err := os.ReadFile(filepath.Join(dir, stateUseStack))
+	if err == nil {
+		var frames []UseFrame
+		if err := json.Unmarshal(data, &frames); err != nil {
+			return nil, fmt.Errorf("failed to parse use state: %w", err)
+		}
+		return frames, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	branch, err := os.ReadFile(filepath.Join(dir, statePreviousBranch))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	frame := UseFrame{Branch: strings.TrimSpace(string(branch))}
+	if stash, err := os.ReadFile(filepath.Join(dir, stateStashRef)); err == nil {
+		frame.Stash = strings.TrimSpace(string(stash))
+	}
+	return []UseFrame{frame}, nil
+}
+
+// WriteUseStack replaces the 'worktree use' stack of the repository whose
+// git common dir is commonDir, removing it when frames is empty, along
+// with the state older versions left behind. It does not lock the stack;
+// SaveUseStack and UpdateUseStack do.
+func WriteUseStack(commonDir, gitDir string, frames []UseFrame) error {
+	if err := writeUseStack(useStackPath(commonDir), frames); err != nil {
+		return err
+	}
+
+	// Only once the frames are safe in the stack file
+	for _, dir := range legacyUseDirs(commonDir, gitDir) {
+		for _, legacy := range []string{stateUseStack, statePreviousBranch, stateStashRef} {
+			if err := os.Remove(filepath.Join(dir, legacy)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeUseStack(path string, frames []UseFrame) error {
+	if len(frames) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(frames, "", "  ")
+	if err != nil {
+		return err
+	}
+	return state.WriteFileAtomic(path, data, 0o644)
+}
+
+func useStackPath(commonDir string) string {
+	return filepath.Join(commonDir, "lazywork", useStackFile)
+}
+
+// lockUseStack locks the stack of the repository against the other
+// worktrees, which share it
+func lockUseStack(commonDir string) (*state.Lock, error) {
+	return state.LockFile(useStackPath(commonDir))
+}
+
+// loadUseStack reads the stack while the caller holds its lock, moving
+// state left by older versions to where the stack is kept now
+func loadUseStack(commonDir, gitDir string) ([]UseFrame, error) {
+	frames, legacy, err := readUseStack(commonDir, gitDir)
+	if err != nil {
+		return nil, err
+	}
+	if legacy {
+		if err := WriteUseStack(commonDir, gitDir, frames); err != nil {
+			return nil, fmt.Errorf("failed to migrate use state: %w", err)
+		}
+	}
+	return frames, nil
+}
+
+// LoadUseStack returns the 'worktree use' stack of the repository, the same
+// from every worktree. State left by older versions is moved to where the
+// stack is kept now.
+func LoadUseStack(ctx context.Context) ([]UseFrame, error) {
+	_, gitDir, commonDir, err := PathInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := lockUseStack(commonDir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	return loadUseStack(commonDir, gitDir)
+}
+
+// SaveUseStack replaces the 'worktree use' stack of the repository. Use
+// UpdateUseStack to change the stack as it is now.
+func SaveUseStack(ctx context.Context, frames []UseFrame) error {
+	_, gitDir, commonDir, err := PathInfo(ctx)
+	if err != nil {
+		return err
+	}
+	lock, err := lockUseStack(commonDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return WriteUseStack(commonDir, gitDir, frames)
+}
+
+// UpdateUseStack loads the 'worktree use' stack, applies fn and saves the
+// frames it returns while holding the stack lock, so worktrees changing
+// the stack at the same time do not lose each other's frames. Nothing is
+// saved if fn returns an error.
+func UpdateUseStack(ctx context.Context, fn func([]UseFrame) ([]UseFrame, error)) ([]UseFrame, error) {
+	_, gitDir, commonDir, err := PathInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := lockUseStack(commonDir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	frames, err := loadUseStack(commonDir, gitDir)
+	if err != nil {
+		return nil, err
+	}
+	if frames, err = fn(frames); err != nil {
+		return nil, err
+	}
+	if err := WriteUseStack(commonDir, gitDir, frames); err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// HasSavedState returns true if 'worktree return' has a frame to unwind
+func HasSavedState(ctx context.Context) bool {
+	frames, err := LoadUseStack(ctx)
+	return err == nil && len(frames) > 0
+}
+
+// PendingUseStashes returns the auto-stashes 'worktree return' will
+// restore
+func PendingUseStashes(ctx context.Context) []string {
+	frames, _ := LoadUseStack(ctx)
+
+	var stashes []string
+	for _, f := range frames {
+		if f.Stash != "" {
+			stashes = append(stashes, f.Stash)
+		}
+	}
+	return stashes
+}
+
+// ForgetUseStash removes stash from the 'worktree use' stack, for when it
+// was applied or dropped by other means
+func ForgetUseStash(ctx context.Context, stash StashEntry) error {
+	_, err := UpdateUseStack(ctx, func(frames []UseFrame) ([]UseFrame, error) {
+		for i := range frames {
+			if stash.Matches(frames[i].Stash) {
+				frames[i].Stash = ""
+			}
+		}
+		return frames, nil
+	})
+	return err
+}
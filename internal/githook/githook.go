@@ -0,0 +1,123 @@
+// Package githook installs the git hook that drafts commit messages for
+// a plain 'git commit'.
+package githook
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Name is the git hook lazywork installs
+const Name = "prepare-commit-msg"
+
+// Marker identifies a hook written by lazywork
+const Marker = "# Installed by 'lazywork hook install'"
+
+// ErrForeignHook is returned when a hook not written by lazywork is in
+// the way
+var ErrForeignHook = errors.New("a prepare-commit-msg hook already exists")
+
+// InstallResult describes the changes made by Install
+type InstallResult struct {
+	Hook    string `json:"hook"`
+	Backup  string `json:"backup,omitempty"`
+	Changed bool   `json:"changed"`
+}
+
+// UninstallResult describes the changes made by Uninstall
+type UninstallResult struct {
+	Hook     string `json:"hook"`
+	Removed  bool   `json:"removed"`
+	Restored string `json:"restored,omitempty"`
+}
+
+// Script returns the hook script. It runs the lazywork on PATH, falling
+// back to exe, and never fails the commit.
+func Script(exe string) string {
+	return `#!/bin/sh
+` + Marker + `
+# Drafts the message of a plain 'git commit' with lazywork's AI provider.
+# Remove with: lazywork hook uninstall
+lazywork=$(command -v lazywork 2>/dev/null || echo ` + shellQuote(exe) + `)
+[ -x "$lazywork" ] || exit 0
+"$lazywork" commit --message-only --hook "$@" || true
+exit 0
+`
+}
+
+// IsOwned returns true if the hook at path was written by lazywork
+func IsOwned(path string) bool {
+	content, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(content), Marker)
+}
+
+// Install writes the hook to dir. It is idempotent. A hook written by
+// someone else is only replaced with force, after backing it up to
+// <hook>.lazywork.bak.
+func Install(dir, exe string, force bool) (*InstallResult, error) {
+	path := filepath.Join(dir, Name)
+	result := &InstallResult{Hook: path}
+	script := Script(exe)
+
+	content, err := os.ReadFile(path)
+	switch {
+	case err == nil && string(content) == script:
+		return result, nil
+	case err == nil && !strings.Contains(string(content), Marker):
+		if !force {
+			return nil, fmt.Errorf("%w: %s", ErrForeignHook, path)
+		}
+		backup := path + ".lazywork.bak"
+		if err := os.WriteFile(backup, content, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		result.Backup = backup
+	case err != nil && !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(path, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to make %s executable: %w", path, err)
+	}
+	result.Changed = true
+	return result, nil
+}
+
+// Uninstall removes the hook from dir if lazywork wrote it, putting back
+// the hook Install backed up
+func Uninstall(dir string) (*UninstallResult, error) {
+	path := filepath.Join(dir, Name)
+	result := &UninstallResult{Hook: path}
+	if !IsOwned(path) {
+		return result, nil
+	}
+
+	if err := os.Remove(path); err != nil {
+		return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	result.Removed = true
+
+	backup := path + ".lazywork.bak"
+	if _, err := os.Stat(backup); err == nil {
+		if err := os.Rename(backup, path); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", backup, err)
+		}
+		result.Restored = path
+	}
+	return result, nil
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,65 @@
+package githook
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallUninstall(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hooks")
+
+	result, err := Install(dir, "/opt/it's/lazywork", false)
+	if err != nil || !result.Changed {
+		t.Fatalf("Install = %+v, %v", result, err)
+	}
+	info, err := os.Stat(result.Hook)
+	if err != nil || info.Mode()&0o111 == 0 {
+		t.Fatalf("hook is not executable: %v", err)
+	}
+	content, _ := os.ReadFile(result.Hook)
+	if !strings.Contains(string(content), `'/opt/it'\''s/lazywork'`) {
+		t.Errorf("executable is not quoted:\n%s", content)
+	}
+
+	if result, err := Install(dir, "/opt/it's/lazywork", false); err != nil || result.Changed {
+		t.Errorf("second Install = %+v, %v", result, err)
+	}
+
+	removed, err := Uninstall(dir)
+	if err != nil || !removed.Removed || removed.Restored != "" {
+		t.Fatalf("Uninstall = %+v, %v", removed, err)
+	}
+	if _, err := os.Stat(result.Hook); !os.IsNotExist(err) {
+		t.Error("hook was not removed")
+	}
+}
+
+func TestInstallForeignHook(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, Name)
+	own := "#!/bin/sh\necho mine\n"
+	os.WriteFile(path, []byte(own), 0o755)
+
+	if _, err := Install(dir, "lazywork", false); !errors.Is(err, ErrForeignHook) {
+		t.Fatalf("Install over a foreign hook: %v", err)
+	}
+	if result, err := Uninstall(dir); err != nil || result.Removed {
+		t.Fatalf("Uninstall removed a foreign hook: %+v, %v", result, err)
+	}
+
+	result, err := Install(dir, "lazywork", true)
+	if err != nil || result.Backup == "" {
+		t.Fatalf("Install --force = %+v, %v", result, err)
+	}
+
+	removed, err := Uninstall(dir)
+	if err != nil || removed.Restored != path {
+		t.Fatalf("Uninstall = %+v, %v", removed, err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != own {
+		t.Errorf("restored hook = %q", content)
+	}
+}
@@ -0,0 +1,81 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/miltonparedes/lazywork/internal/env"
+)
+
+// Hook is a run of the commands configured for one event
+type Hook struct {
+	// Event is the hook name, e.g. "pre_add"
+	Event string
+	// Commands are run in order through the shell
+	Commands []string
+	// Worktree is exposed to the commands as LW_* environment variables
+	Worktree env.Worktree
+	// Dir is the working directory; it defaults to the worktree path
+	// when that exists and the repository root otherwise
+	Dir string
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Error reports the hook command that failed
+type Error struct {
+	Event   string
+	Command string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s hook '%s' failed: %v", e.Event, e.Command, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Run runs the hook's commands in order, stopping at the first one that
+// fails
+func (h Hook) Run(ctx context.Context) error {
+	if len(h.Commands) == 0 {
+		return nil
+	}
+
+	dir := h.Dir
+	if dir == "" {
+		dir = h.Worktree.RepoRoot
+		if info, err := os.Stat(h.Worktree.Path); err == nil && info.IsDir() {
+			dir = h.Worktree.Path
+		}
+	}
+
+	environ := append(os.Environ(), env.Environ(env.Vars(h.Worktree))...)
+	environ = append(environ, "LW_HOOK="+h.Event)
+
+	for _, command := range h.Commands {
+		cmd := shellCommand(ctx, command)
+		cmd.Dir = dir
+		cmd.Env = environ
+		cmd.Stdout = h.Stdout
+		cmd.Stderr = h.Stderr
+		if err := cmd.Run(); err != nil {
+			return &Error{Event: h.Event, Command: command, Err: err}
+		}
+	}
+	return nil
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
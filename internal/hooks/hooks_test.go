@@ -0,0 +1,64 @@
+package hooks
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/internal/env"
+)
+
+func TestHookRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+
+	root := t.TempDir()
+	wt := filepath.Join(root, ".worktrees", "feature")
+	os.MkdirAll(wt, 0o755)
+
+	var stdout bytes.Buffer
+	h := Hook{
+		Event:    "post_add",
+		Commands: []string{`echo "$LW_HOOK $LW_BRANCH $(pwd)"`, "touch created"},
+		Worktree: env.Worktree{Name: "feature", Branch: "feature", Path: wt, RepoRoot: root},
+		Stdout:   &stdout,
+	}
+	if err := h.Run(t.Context()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if got := strings.TrimSpace(stdout.String()); !strings.HasPrefix(got, "post_add feature ") || !strings.HasSuffix(got, "feature") {
+		t.Errorf("output = %q, want the hook, branch and worktree directory", got)
+	}
+	if _, err := os.Stat(filepath.Join(wt, "created")); err != nil {
+		t.Errorf("command did not run in the worktree: %v", err)
+	}
+}
+
+func TestHookRunStopsAtFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+
+	root := t.TempDir()
+	h := Hook{
+		Event:    "pre_add",
+		Commands: []string{"exit 3", "touch never"},
+		// The worktree does not exist yet, so the hook runs in the root
+		Worktree: env.Worktree{Path: filepath.Join(root, "missing"), RepoRoot: root},
+	}
+
+	err := h.Run(t.Context())
+	var hookErr *Error
+	if !errors.As(err, &hookErr) || hookErr.Command != "exit 3" {
+		t.Fatalf("Run = %v, want an *Error for 'exit 3'", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "never")); err == nil {
+		t.Error("commands after the failing one were run")
+	}
+}
@@ -0,0 +1,225 @@
+// Package ignore reads .lazyworkignore files, which use gitignore syntax
+// to keep files such as lockfiles, vendored code and snapshots out of the
+// diffs sent to an AI provider.
+package ignore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File is the ignore file read from the repository root
+const File = ".lazyworkignore"
+
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher matches slash-separated paths relative to the repository root
+// against ignore patterns. The zero value ignores nothing.
+type Matcher struct {
+	rules []rule
+}
+
+// Load reads the ignore file in root, returning an empty matcher if there
+// is none
+func Load(root string) (*Matcher, error) {
+	data, err := os.ReadFile(filepath.Join(root, File))
+	if os.IsNotExist(err) {
+		return &Matcher{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", File, err)
+	}
+	return Parse(string(data)), nil
+}
+
+// Parse returns a matcher for patterns in gitignore syntax. Lines that
+// can't be turned into a pattern are skipped, as git does.
+func Parse(text string) *Matcher {
+	m := &Matcher{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var r rule
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		// A slash anywhere but at the end anchors the pattern to the root
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			continue
+		}
+		r.re = re
+		m.rules = append(m.rules, r)
+	}
+	return m
+}
+
+// globToRegexp translates a gitignore glob, where * and ? don't cross
+// directories and ** does
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// Empty returns true if the matcher has no patterns
+func (m *Matcher) Empty() bool {
+	return len(m.rules) == 0
+}
+
+// Match returns true if path, or a directory containing it, is ignored.
+// As with gitignore, a file inside an ignored directory can't be
+// re-included.
+func (m *Matcher) Match(path string) bool {
+	parts := strings.Split(strings.Trim(filepath.ToSlash(path), "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), i < len(parts)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matcher) match(path string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(path) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// FilterDiff removes the sections of a unified git diff for ignored files
+// and returns the paths that were removed
+func (m *Matcher) FilterDiff(diff string) (string, []string) {
+	if m.Empty() {
+		return diff, nil
+	}
+
+	var b strings.Builder
+	var removed []string
+	for _, section := range splitDiff(diff) {
+		if path := diffPath(section); path != "" && m.Match(path) {
+			removed = append(removed, path)
+			continue
+		}
+		b.WriteString(section)
+	}
+	return b.String(), removed
+}
+
+// splitDiff splits a diff into the text before the first file, if any,
+// and one section per file
+func splitDiff(diff string) []string {
+	var sections []string
+	start := 0
+	for i := 0; i < len(diff); {
+		if strings.HasPrefix(diff[i:], "diff --git ") && i > start {
+			sections = append(sections, diff[start:i])
+			start = i
+		}
+		next := strings.IndexByte(diff[i:], '\n')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return append(sections, diff[start:])
+}
+
+// diffPath returns the path of the file a diff section is for, preferring
+// the new path of renamed files
+func diffPath(section string) string {
+	header, rest, _ := strings.Cut(section, "\n")
+	if !strings.HasPrefix(header, "diff --git ") {
+		return ""
+	}
+	var oldPath string
+	for _, line := range strings.Split(rest, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++ b/"):
+			return line[len("+++ b/"):]
+		case strings.HasPrefix(line, "rename to "):
+			return line[len("rename to "):]
+		case strings.HasPrefix(line, "--- a/"):
+			oldPath = line[len("--- a/"):]
+		case strings.HasPrefix(line, "@@"):
+			if oldPath != "" {
+				return oldPath
+			}
+		}
+	}
+	if oldPath != "" {
+		return oldPath
+	}
+
+	// Binary and mode-only changes have just the header, where both
+	// paths are the same
+	paths := strings.TrimPrefix(header, "diff --git a/")
+	if n := len(paths); n > 3 && (n-3)%2 == 0 && paths[:(n-3)/2] == paths[(n+3)/2:] {
+		return paths[:(n-3)/2]
+	}
+	return ""
+}
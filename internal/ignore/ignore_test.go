@@ -0,0 +1,99 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	m := Parse(`# generated
+package-lock.json
+*.snap
+/build/
+vendor/
+docs/**/*.png
+!docs/**/keep.png
+\#notes
+`)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"package-lock.json", true},
+		{"web/package-lock.json", true},
+		{"src/__snapshots__/app.test.ts.snap", true},
+		{"build/out.js", true},
+		{"src/build/out.js", false},
+		{"build", false},
+		{"vendor/github.com/x/y.go", true},
+		{"third_party/vendor/z.go", true},
+		{"docs/img/a.png", true},
+		{"docs/a.png", true},
+		{"docs/img/keep.png", false},
+		{"#notes", true},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFilterDiff(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1 +1 @@
+-package old
++package main
+diff --git a/package-lock.json b/package-lock.json
+index 3333333..4444444 100644
+--- a/package-lock.json
++++ b/package-lock.json
+@@ -1 +1 @@
+-{}
++{"lockfileVersion": 3}
+diff --git a/vendor/x.go b/vendor/x.go
+deleted file mode 100644
+index 5555555..0000000
+--- a/vendor/x.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package x
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..6666666
+Binary files /dev/null and b/logo.png differ
+`
+	m := Parse("package-lock.json\nvendor/\n*.png\n")
+
+	got, removed := m.FilterDiff(diff)
+	if strings.Join(removed, ",") != "package-lock.json,vendor/x.go,logo.png" {
+		t.Errorf("removed = %v", removed)
+	}
+	if !strings.HasPrefix(got, "diff --git a/main.go") || strings.Count(got, "diff --git") != 1 {
+		t.Errorf("filtered diff:\n%s", got)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	m, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !m.Empty() || m.Match("anything") {
+		t.Error("missing ignore file should ignore nothing")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, File), []byte("*.lock\n"), 0o644)
+	m, err = Load(dir)
+	if err != nil || !m.Match("Cargo.lock") {
+		t.Errorf("Load = %v, %v", m, err)
+	}
+}
@@ -0,0 +1,323 @@
+// Package lazyerr defines the structured error returned by lazywork
+// commands. Every error carries a stable code from a fixed namespace, a
+// hint for the user and optional details, and maps to a stable exit code.
+package lazyerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/huh"
+)
+
+// Code identifies a class of error in JSON output
+type Code string
+
+// Error codes
+const (
+	Unknown   Code = "ERROR"
+	Usage     Code = "USAGE_ERROR"
+	Cancelled Code = "CANCELLED"
+	Timeout   Code = "TIMEOUT"
+
+	NotGitRepo         Code = "NOT_GIT_REPO"
+	NotMainWorktree    Code = "NOT_MAIN_WORKTREE"
+	NotMainBranch      Code = "NOT_MAIN_BRANCH"
+	DetachedHead       Code = "DETACHED_HEAD"
+	UncommittedChanges Code = "UNCOMMITTED_CHANGES"
+	MergeConflict      Code = "MERGE_CONFLICT"
+	PathError          Code = "PATH_ERROR"
+	CheckoutError      Code = "CHECKOUT_ERROR"
+	StashError         Code = "STASH_ERROR"
+	StashNotFound      Code = "STASH_NOT_FOUND"
+	NoStashes          Code = "NO_STASHES"
+
+	WorktreeNotFound    Code = "WORKTREE_NOT_FOUND"
+	NoWorktrees         Code = "NO_WORKTREES"
+	WorktreeListError   Code = "WORKTREE_LIST_ERROR"
+	WorktreeAddError    Code = "WORKTREE_ADD_ERROR"
+	WorktreeRemoveError Code = "WORKTREE_REMOVE_ERROR"
+	WorktreePruneError  Code = "WORKTREE_PRUNE_ERROR"
+
+	BranchNotFound Code = "BRANCH_NOT_FOUND"
+	BranchExists   Code = "BRANCH_EXISTS"
+	BranchError    Code = "BRANCH_ERROR"
+	CloneError     Code = "CLONE_ERROR"
+	HookFailed     Code = "HOOK_FAILED"
+	CommandFailed  Code = "COMMAND_FAILED"
+	FinishFailed   Code = "FINISH_FAILED"
+	CleanFailed    Code = "CLEAN_FAILED"
+	NoComposeFile  Code = "NO_COMPOSE_FILE"
+	WorkspaceError Code = "WORKSPACE_ERROR"
+
+	NoState         Code = "NO_STATE"
+	StateExists     Code = "STATE_EXISTS"
+	StateSaveError  Code = "STATE_SAVE_ERROR"
+	StateReadError  Code = "STATE_READ_ERROR"
+	NoHistory       Code = "NO_HISTORY"
+	NoGeneration    Code = "NO_GENERATION"
+	ArchiveError    Code = "ARCHIVE_ERROR"
+	ArchiveNotFound Code = "ARCHIVE_NOT_FOUND"
+	NothingToUndo   Code = "NOTHING_TO_UNDO"
+	UndoError       Code = "UNDO_ERROR"
+
+	InvalidArgument Code = "INVALID_ARGUMENT"
+	InvalidKey      Code = "INVALID_KEY"
+	InvalidShell    Code = "INVALID_SHELL"
+	InvalidFormat   Code = "INVALID_FORMAT"
+	NameRequired    Code = "NAME_REQUIRED"
+	AmbiguousName   Code = "AMBIGUOUS_NAME"
+	EmptyName       Code = "EMPTY_NAME"
+	EmptyKey        Code = "EMPTY_KEY"
+	ReadError       Code = "READ_ERROR"
+
+	ConfigLoadError  Code = "CONFIG_LOAD_ERROR"
+	ConfigParseError Code = "CONFIG_PARSE_ERROR"
+	ConfigSaveError  Code = "CONFIG_SAVE_ERROR"
+	ConfigExists     Code = "CONFIG_EXISTS"
+	ConfigInvalid    Code = "CONFIG_INVALID"
+	KeyringError     Code = "KEYRING_ERROR"
+
+	InvalidProvider  Code = "INVALID_PROVIDER"
+	ProviderError    Code = "PROVIDER_ERROR"
+	BudgetExceeded   Code = "BUDGET_EXCEEDED"
+	ForgeError       Code = "FORGE_ERROR"
+	NoForge          Code = "NO_FORGE"
+	IssueNotFound    Code = "ISSUE_NOT_FOUND"
+	PushError        Code = "PUSH_ERROR"
+	NothingStaged    Code = "NOTHING_STAGED"
+	CommitError      Code = "COMMIT_ERROR"
+	LintFailed       Code = "LINT_FAILED"
+	NoConflicts      Code = "NO_CONFLICTS"
+	CherryPickError  Code = "CHERRY_PICK_ERROR"
+	TagExists        Code = "TAG_EXISTS"
+	TagError         Code = "TAG_ERROR"
+	NothingToRelease Code = "NOTHING_TO_RELEASE"
+
+	ShellInstallError   Code = "SHELL_INSTALL_ERROR"
+	ShellUninstallError Code = "SHELL_UNINSTALL_ERROR"
+	CompletionError     Code = "COMPLETION_ERROR"
+	GitHookExists       Code = "GIT_HOOK_EXISTS"
+	GitHookError        Code = "GIT_HOOK_ERROR"
+	ProfileError        Code = "PROFILE_ERROR"
+	ServeError          Code = "SERVE_ERROR"
+	Unauthorized        Code = "UNAUTHORIZED"
+	NoSchema            Code = "NO_SCHEMA"
+)
+
+// Exit codes returned by lazywork, stable so scripts and agents can branch
+// on $? instead of parsing --json output
+const (
+	ExitOK          = 0
+	ExitError       = 1   // any error without a more specific class
+	ExitUsage       = 2   // invalid flags or arguments
+	ExitNotRepo     = 3   // not inside a git repository
+	ExitNotFound    = 4   // worktree, branch, stash or saved state not found
+	ExitUncommitted = 5   // uncommitted changes block the operation
+	ExitConflict    = 6   // merge conflict
+	ExitProvider    = 7   // AI provider request failed
+	ExitConfig      = 8   // config file could not be loaded, parsed or saved
+	ExitCancelled   = 130 // cancelled by the user
+)
+
+type codeInfo struct {
+	exit int
+	hint string
+}
+
+// codes registers every code with its exit code and default hint
+var codes = map[Code]codeInfo{
+	Unknown:   {ExitError, "Run the command with --help for usage"},
+	Usage:     {ExitUsage, "Run with --help for usage"},
+	Cancelled: {ExitCancelled, "Nothing was changed"},
+	Timeout:   {ExitError, "Raise git_timeout in the config or set LAZYWORK_GIT_TIMEOUT"},
+
+	NotGitRepo:         {ExitNotRepo, "Run lazywork from inside a git repository"},
+	NotMainWorktree:    {ExitError, "Run this from the main repository, not a worktree"},
+	NotMainBranch:      {ExitError, "Switch to the main branch first"},
+	DetachedHead:       {ExitError, "Check out a branch first"},
+	UncommittedChanges: {ExitUncommitted, "Commit or stash your changes first"},
+	MergeConflict:      {ExitConflict, "Resolve conflicts with 'lazywork resolve' (or by hand and 'git commit'), then try again"},
+	PathError:          {ExitError, "Check the worktree_dir setting"},
+	CheckoutError:      {ExitError, "Check 'git status' for conflicting changes"},
+	StashError:         {ExitError, "Check 'git stash list' and 'git status'"},
+	StashNotFound:      {ExitNotFound, "List stashes with: lazywork stash list"},
+	NoStashes:          {ExitNotFound, "There is nothing stashed in this repository"},
+
+	WorktreeNotFound:    {ExitNotFound, "List worktrees with: lazywork worktree list"},
+	NoWorktrees:         {ExitNotFound, "Create one with: lazywork worktree add <name>"},
+	WorktreeListError:   {ExitError, "Check that git can run 'git worktree list'"},
+	WorktreeAddError:    {ExitError, "Check that the path does not already exist"},
+	WorktreeRemoveError: {ExitError, "Use --force to remove a worktree with changes"},
+	WorktreePruneError:  {ExitError, "Run 'git worktree prune' manually for details"},
+
+	BranchNotFound: {ExitNotFound, "List branches with: git branch -a"},
+	BranchExists:   {ExitError, "Use --branch to check out the existing branch"},
+	BranchError:    {ExitError, "Check the branch with: git status"},
+	CloneError:     {ExitError, "Check the URL and that you can access the repository"},
+	HookFailed:     {ExitError, "Fix the hook command or skip hooks with --no-hooks"},
+	CommandFailed:  {ExitError, "Check the command's output in the failing worktrees"},
+	FinishFailed:   {ExitError, "Fix the failed worktrees, then run: lazywork worktree finish --all-merged"},
+	CleanFailed:    {ExitError, "Fix the failed worktrees, or remove them with: lazywork worktree remove --force <name>"},
+	NoComposeFile:  {ExitNotFound, "Add a compose.yaml to the worktree"},
+	WorkspaceError: {ExitError, "Fix or delete the workspace file, then run: lazywork workspace generate"},
+
+	NoState:         {ExitNotFound, "Start one with: lazywork worktree use <name>"},
+	StateExists:     {ExitError, "Check the stack with: lazywork worktree use --status"},
+	StateSaveError:  {ExitError, "Check that the .git directory is writable"},
+	StateReadError:  {ExitError, "Repair the saved 'worktree use' state with: lazywork worktree use --repair"},
+	NoHistory:       {ExitNotFound, "Visit a worktree with: lazywork worktree go <name>"},
+	NoGeneration:    {ExitNotFound, "Generate a commit message with: lazywork commit"},
+	ArchiveError:    {ExitError, "Check the archives with: lazywork archive list"},
+	ArchiveNotFound: {ExitNotFound, "List archives with: lazywork archive list"},
+	NothingToUndo:   {ExitNotFound, "List the recorded operations with: lazywork undo --list"},
+	UndoError:       {ExitError, "List the recorded operations with: lazywork undo --list"},
+
+	InvalidArgument: {ExitUsage, "Run with --help for usage"},
+	InvalidKey:      {ExitUsage, "Run 'lazywork config set --help' for the supported keys"},
+	InvalidShell:    {ExitUsage, "Supported shells: bash, zsh, fish"},
+	InvalidFormat:   {ExitUsage, "Run with --help for the supported formats"},
+	NameRequired:    {ExitUsage, "Pass a name as the first argument"},
+	AmbiguousName:   {ExitUsage, "Pass a longer name, or the full worktree name or branch"},
+	EmptyName:       {ExitUsage, "Enter a non-empty name"},
+	EmptyKey:        {ExitUsage, "Pass the key as an argument or on stdin"},
+	ReadError:       {ExitError, "Pass the value as an argument instead"},
+
+	ConfigLoadError:  {ExitConfig, "Check the file with: lazywork config validate"},
+	ConfigParseError: {ExitConfig, "Fix the syntax error in the config file"},
+	ConfigSaveError:  {ExitConfig, "Check that the config directory is writable"},
+	ConfigExists:     {ExitConfig, "Edit the existing file or remove it first"},
+	ConfigInvalid:    {ExitConfig, "Fix the reported issues in the config file"},
+	KeyringError:     {ExitConfig, "Use an $ENV reference for api_key if no keyring is available"},
+
+	InvalidProvider:  {ExitProvider, "List providers with: lazywork config show"},
+	ProviderError:    {ExitProvider, "Check the provider's API key and base URL"},
+	BudgetExceeded:   {ExitProvider, "Check spend with 'lazywork usage'; raise budget.monthly or set budget.action to warn"},
+	ForgeError:       {ExitError, "Check the forge token: forges.<forge>.token, $GITHUB_TOKEN/$GITLAB_TOKEN or the gh/glab login"},
+	NoForge:          {ExitError, "Add an origin remote or set forge in the config"},
+	IssueNotFound:    {ExitNotFound, "Check the issue number and that the token can read the repository"},
+	PushError:        {ExitError, "Check the remote with: git remote -v"},
+	NothingStaged:    {ExitError, "Stage changes with: git add <paths>"},
+	CommitError:      {ExitError, "Check 'git status' and any commit hooks"},
+	LintFailed:       {ExitError, "Review and fix the message with: lazywork redo"},
+	NoConflicts:      {ExitNotFound, "There is nothing to resolve; check 'git status'"},
+	CherryPickError:  {ExitError, "Check the commits with git log; merge commits and changes already on the branch can't be picked"},
+	TagExists:        {ExitError, "Release another version, or delete the tag with: git tag -d <tag>"},
+	TagError:         {ExitError, "Check the tag name with: git check-ref-format refs/tags/<tag>"},
+	NothingToRelease: {ExitError, "Check the last release with: git describe --tags --abbrev=0"},
+
+	ShellInstallError:   {ExitError, "Check that your shell config file is writable"},
+	ShellUninstallError: {ExitError, "Check that your shell config file is writable"},
+	CompletionError:     {ExitError, "Generate completions manually with: lazywork completion <shell>"},
+	GitHookExists:       {ExitError, "Replace it with 'lazywork hook install --force'; it is backed up first"},
+	GitHookError:        {ExitError, "Check that the git hooks directory is writable"},
+	ProfileError:        {ExitError, "Check that the profile output path is writable"},
+	ServeError:          {ExitError, "Check that the --http address is free, or pick another one"},
+	Unauthorized:        {ExitUsage, "Send the token from the serve token file as: Authorization: Bearer <token>"},
+	NoSchema:            {ExitNotFound, "List the commands with JSON output with: lazywork schema"},
+}
+
+// Codes returns every registered error code, sorted
+func Codes() []Code {
+	list := make([]Code, 0, len(codes))
+	for code := range codes {
+		list = append(list, code)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// Error is a command error with a stable code, a hint and details
+type Error struct {
+	Code    Code                   `json:"code"`
+	Message string                 `json:"error"`
+	Hint    string                 `json:"hint"`
+	Details map[string]interface{} `json:"details"`
+
+	err      error
+	reported bool
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// New creates an error with a formatted message and the code's default hint
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Hint:    codes[code].hint,
+		Details: map[string]interface{}{},
+	}
+}
+
+// Wrap wraps err with a code, keeping err's message
+func Wrap(code Code, err error) *Error {
+	e := New(code, "%s", err.Error())
+	e.err = err
+	return e
+}
+
+// WithHint replaces the default hint
+func (e *Error) WithHint(hint string) *Error {
+	e.Hint = hint
+	return e
+}
+
+// WithDetail adds a detail to the error
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	e.Details[key] = value
+	return e
+}
+
+// MarkReported records that the command already presented this error in
+// its own output, so it only determines the exit code and is not printed
+func (e *Error) MarkReported() *Error {
+	e.reported = true
+	return e
+}
+
+// Reported returns true if the error was marked with MarkReported
+func (e *Error) Reported() bool {
+	return e.reported
+}
+
+// From converts any error into an *Error. An interrupted or timed-out
+// command becomes Cancelled or Timeout whatever code it was wrapped with;
+// other structured errors are returned as is, cancelled prompts become
+// Cancelled and everything else Unknown.
+func From(err error) *Error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Wrap(Cancelled, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(Timeout, err)
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if errors.Is(err, huh.ErrUserAborted) {
+		return Wrap(Cancelled, err)
+	}
+	return Wrap(Unknown, err)
+}
+
+// ExitCode returns the process exit code for err
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if info, ok := codes[From(err).Code]; ok {
+		return info.exit
+	}
+	return ExitError
+}
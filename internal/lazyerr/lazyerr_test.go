@@ -0,0 +1,61 @@
+package lazyerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/charmbracelet/huh"
+)
+
+func TestCodesHaveHints(t *testing.T) {
+	for _, code := range Codes() {
+		if codes[code].hint == "" {
+			t.Errorf("code %s has no default hint", code)
+		}
+		if codes[code].exit == ExitOK {
+			t.Errorf("code %s maps to exit code 0", code)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, ExitOK},
+		{"plain", errors.New("boom"), ExitError},
+		{"not repo", New(NotGitRepo, "not inside a git repository"), ExitNotRepo},
+		{"wrapped", fmt.Errorf("context: %w", New(MergeConflict, "conflict")), ExitConflict},
+		{"aborted", huh.ErrUserAborted, ExitCancelled},
+		{"usage", Wrap(Usage, errors.New("unknown flag")), ExitUsage},
+		// Writing a profile touches no config, so it isn't a config error
+		{"profile", Wrap(ProfileError, errors.New("permission denied")), ExitError},
+		{"config", Wrap(ConfigSaveError, errors.New("permission denied")), ExitConfig},
+		{"interrupted", Wrap(WorktreeListError, fmt.Errorf("git worktree list: %w", context.Canceled)), ExitCancelled},
+	}
+
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("%s: ExitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWrapKeepsCause(t *testing.T) {
+	cause := errors.New("permission denied")
+	err := Wrap(ConfigSaveError, cause).WithDetail("path", "/tmp/x")
+
+	if !errors.Is(err, cause) {
+		t.Error("expected wrapped error to match its cause")
+	}
+	if err.Error() != "permission denied" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if err.Hint == "" || err.Details["path"] != "/tmp/x" {
+		t.Errorf("unexpected hint/details: %+v", err)
+	}
+}
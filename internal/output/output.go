@@ -7,14 +7,19 @@ import (
 	"os"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/muesli/termenv"
 	"golang.org/x/term"
 )
 
 // Output handles dual-mode output (JSON for agents, styled for humans)
 type Output struct {
 	json    bool
+	stream  bool
+	quiet   bool
 	noColor bool
 	isTTY   bool
+	width   int
 	out     io.Writer
 	errOut  io.Writer
 	styles  *Styles
@@ -29,32 +34,116 @@ type Styles struct {
 	Bold    lipgloss.Style
 }
 
+// Option configures an Output created with NewWithWriters
+type Option func(*Output)
+
+// WithJSON enables JSON output
+func WithJSON(enabled bool) Option {
+	return func(o *Output) {
+		o.json = o.json || enabled
+	}
+}
+
+// WithNoColor disables styling
+func WithNoColor(enabled bool) Option {
+	return func(o *Output) {
+		o.noColor = o.noColor || enabled
+	}
+}
+
+// WithQuiet enables quiet mode: success, info, warning, dim and bold
+// messages are dropped so only errors and command data (Print, JSON) are
+// written
+func WithQuiet(enabled bool) Option {
+	return func(o *Output) {
+		o.quiet = o.quiet || enabled
+	}
+}
+
+// WithTTY overrides terminal detection, e.g. to exercise interactive code
+// paths in tests or to force plain output
+func WithTTY(isTTY bool) Option {
+	return func(o *Output) {
+		o.isTTY = isTTY
+	}
+}
+
+// WithWidth sets the width tables are fitted to; 0 disables truncation
+func WithWidth(width int) Option {
+	return func(o *Output) {
+		o.width = width
+	}
+}
+
+// New returns an Output writing to os.Stdout and os.Stderr
 func New(jsonFlag, noColorFlag bool) *Output {
-	isTTY := term.IsTerminal(int(os.Stdin.Fd()))
+	return NewWithWriters(os.Stdout, os.Stderr, WithJSON(jsonFlag), WithNoColor(noColorFlag))
+}
 
+// NewWithWriters returns an Output writing to out and errOut. Unless
+// overridden with WithTTY, it is interactive when stdin is a terminal.
+func NewWithWriters(out, errOut io.Writer, opts ...Option) *Output {
 	o := &Output{
-		json:    jsonFlag,
-		noColor: noColorFlag || !isTTY,
-		isTTY:   isTTY,
-		out:     os.Stdout,
-		errOut:  os.Stderr,
+		json:   stream,
+		stream: stream,
+		isTTY:  term.IsTerminal(int(os.Stdin.Fd())),
+		out:    out,
+		errOut: errOut,
+	}
+	if f, ok := out.(*os.File); ok {
+		if w, _, err := term.GetSize(int(f.Fd())); err == nil {
+			o.width = w
+		}
+	}
+
+	for _, opt := range opts {
+		opt(o)
 	}
 
-	// Note: lipgloss auto-detects color profile based on terminal
-	// The noColor flag is handled by not using styles when printing
+	color, forced := colorMode(o.noColor, o.isTTY, os.Getenv)
+	o.noColor = !color
+
+	// Styles render for out; when color is forced onto a pipe, lipgloss
+	// would otherwise detect a plain profile and drop the colors
+	r := lipgloss.NewRenderer(out)
+	if forced {
+		r.SetColorProfile(termenv.ANSI256)
+	}
 
 	o.styles = &Styles{
-		Error:   lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true),
-		Success: lipgloss.NewStyle().Foreground(lipgloss.Color("10")),
-		Warning: lipgloss.NewStyle().Foreground(lipgloss.Color("11")),
-		Info:    lipgloss.NewStyle().Foreground(lipgloss.Color("12")),
-		Dim:     lipgloss.NewStyle().Foreground(lipgloss.Color("8")),
-		Bold:    lipgloss.NewStyle().Bold(true),
+		Error:   r.NewStyle().Foreground(lipgloss.Color("9")).Bold(true),
+		Success: r.NewStyle().Foreground(lipgloss.Color("10")),
+		Warning: r.NewStyle().Foreground(lipgloss.Color("11")),
+		Info:    r.NewStyle().Foreground(lipgloss.Color("12")),
+		Dim:     r.NewStyle().Foreground(lipgloss.Color("8")),
+		Bold:    r.NewStyle().Bold(true),
 	}
 
 	return o
 }
 
+// colorMode decides whether to style output, following the usual CLI
+// conventions in order of precedence: the --no-color flag, NO_COLOR
+// (https://no-color.org), CLICOLOR_FORCE to color even when piped, and
+// finally terminal detection, where CLICOLOR=0 also disables color.
+// forced reports that color was requested for a non-terminal.
+func colorMode(noColorFlag, isTTY bool, getenv func(string) string) (color, forced bool) {
+	switch {
+	case noColorFlag:
+		return false, false
+	case getenv("NO_COLOR") != "":
+		return false, false
+	case getenv("CLICOLOR_FORCE") != "" && getenv("CLICOLOR_FORCE") != "0":
+		return true, !isTTY
+	case !isTTY:
+		return false, false
+	case getenv("CLICOLOR") == "0":
+		return false, false
+	default:
+		return true, false
+	}
+}
+
 // IsTTY returns true if running interactively (not piped, not --json)
 func (o *Output) IsTTY() bool {
 	return o.isTTY && !o.json
@@ -64,7 +153,21 @@ func (o *Output) IsJSON() bool {
 	return o.json
 }
 
+// IsQuiet returns true if decorative output is suppressed
+func (o *Output) IsQuiet() bool {
+	return o.quiet
+}
+
+// Color returns true if human output is styled, so external output such
+// as git's can be colored to match
+func (o *Output) Color() bool {
+	return !o.noColor && !o.json
+}
+
 func (o *Output) JSON(v interface{}) error {
+	if o.stream {
+		return o.Emit(Event{Type: EventResult, Data: v})
+	}
 	enc := json.NewEncoder(o.out)
 	enc.SetIndent("", "  ")
 	return enc.Encode(v)
@@ -79,7 +182,11 @@ func (o *Output) Println(args ...interface{}) {
 }
 
 func (o *Output) Success(msg string) {
-	if o.json {
+	if o.stream {
+		o.Emit(Event{Type: EventProgress, Level: "success", Message: msg})
+		return
+	}
+	if o.json || o.quiet {
 		return
 	}
 	text := "✓ " + msg
@@ -100,7 +207,11 @@ func (o *Output) Error(msg string) {
 }
 
 func (o *Output) Warning(msg string) {
-	if o.json {
+	if o.stream {
+		o.Emit(Event{Type: EventProgress, Level: "warning", Message: msg})
+		return
+	}
+	if o.json || o.quiet {
 		return
 	}
 	text := "⚠ " + msg
@@ -112,7 +223,11 @@ func (o *Output) Warning(msg string) {
 }
 
 func (o *Output) Info(msg string) {
-	if o.json {
+	if o.stream {
+		o.Emit(Event{Type: EventProgress, Level: "info", Message: msg})
+		return
+	}
+	if o.json || o.quiet {
 		return
 	}
 	text := "ℹ " + msg
@@ -124,7 +239,7 @@ func (o *Output) Info(msg string) {
 }
 
 func (o *Output) Dim(msg string) {
-	if o.json {
+	if o.json || o.quiet {
 		return
 	}
 	if o.noColor {
@@ -135,7 +250,7 @@ func (o *Output) Dim(msg string) {
 }
 
 func (o *Output) Bold(msg string) {
-	if o.json {
+	if o.json || o.quiet {
 		return
 	}
 	if o.noColor {
@@ -154,17 +269,39 @@ func (o *Output) Result(data interface{}, humanMsg string) {
 	}
 }
 
-func (o *Output) ErrorResult(err error, code string) {
+// ErrorResult prints a command error: a JSON object with the code, hint
+// and details under --json, otherwise the message and hint on stderr.
+// Errors the command already reported itself are not printed.
+func (o *Output) ErrorResult(err error) {
+	e := lazyerr.From(err)
+	if e.Reported() {
+		return
+	}
+
+	if o.stream {
+		o.Emit(Event{Type: EventError, Message: e.Message, Data: e})
+		return
+	}
 	if o.json {
-		o.JSON(map[string]string{
-			"error": err.Error(),
-			"code":  code,
-		})
-	} else {
-		o.Error(err.Error())
+		o.JSON(e)
+		return
+	}
+
+	o.Error(e.Message)
+	if e.Hint != "" && !o.quiet {
+		if o.noColor {
+			fmt.Fprintln(o.errOut, "  "+e.Hint)
+		} else {
+			fmt.Fprintln(o.errOut, o.styles.Dim.Render("  "+e.Hint))
+		}
 	}
 }
 
+// Writer returns the writer for command output
+func (o *Output) Writer() io.Writer {
+	return o.out
+}
+
 func (o *Output) Styles() *Styles {
 	return o.styles
 }
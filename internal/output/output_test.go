@@ -0,0 +1,90 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewWithWritersCapturesOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	o := NewWithWriters(&stdout, &stderr, WithTTY(false))
+
+	o.Success("created")
+	o.Warning("careful")
+	o.Error("failed")
+
+	if got := stdout.String(); got != "✓ created\n" {
+		t.Errorf("stdout = %q", got)
+	}
+	if got := stderr.String(); !strings.Contains(got, "⚠ careful") || !strings.Contains(got, "✗ failed") {
+		t.Errorf("stderr = %q", got)
+	}
+}
+
+func TestWithQuiet(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	o := NewWithWriters(&stdout, &stderr, WithTTY(false), WithNoColor(true), WithQuiet(true))
+
+	o.Success("created")
+	o.Info("next step")
+	o.Dim("  path: /tmp")
+	o.Warning("careful")
+	o.Error("failed")
+	o.Println("data")
+
+	if got := stdout.String(); got != "data\n" {
+		t.Errorf("stdout = %q, want only the command data", got)
+	}
+	if got := stderr.String(); got != "✗ failed\n" {
+		t.Errorf("stderr = %q, want only the error", got)
+	}
+
+	stdout.Reset()
+	o = NewWithWriters(&stdout, &stderr, WithJSON(true), WithQuiet(true))
+	if err := o.JSON(map[string]bool{"ok": true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); !strings.Contains(got, `"ok": true`) {
+		t.Errorf("JSON output = %q, want it written", got)
+	}
+}
+
+func TestWithTTY(t *testing.T) {
+	var buf bytes.Buffer
+
+	if !NewWithWriters(&buf, &buf, WithTTY(true)).IsTTY() {
+		t.Error("expected WithTTY(true) to force interactive mode")
+	}
+	if NewWithWriters(&buf, &buf, WithTTY(true), WithJSON(true)).IsTTY() {
+		t.Error("JSON output should never be interactive")
+	}
+}
+
+func TestColorMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		flag, tty  bool
+		env        map[string]string
+		color      bool
+		wantForced bool
+	}{
+		{"tty", false, true, nil, true, false},
+		{"pipe", false, false, nil, false, false},
+		{"flag", true, true, nil, false, false},
+		{"NO_COLOR", false, true, map[string]string{"NO_COLOR": "1"}, false, false},
+		{"CLICOLOR_FORCE on pipe", false, false, map[string]string{"CLICOLOR_FORCE": "1"}, true, true},
+		{"CLICOLOR_FORCE=0", false, false, map[string]string{"CLICOLOR_FORCE": "0"}, false, false},
+		{"NO_COLOR beats force", false, false, map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, false, false},
+		{"flag beats force", true, false, map[string]string{"CLICOLOR_FORCE": "1"}, false, false},
+		{"CLICOLOR=0", false, true, map[string]string{"CLICOLOR": "0"}, false, false},
+	}
+
+	for _, tt := range tests {
+		getenv := func(key string) string { return tt.env[key] }
+		color, forced := colorMode(tt.flag, tt.tty, getenv)
+		if color != tt.color || forced != tt.wantForced {
+			t.Errorf("%s: colorMode = %v, %v; want %v, %v", tt.name, color, forced, tt.color, tt.wantForced)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package output
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const spinnerInterval = 100 * time.Millisecond
+
+// Spinner shows msg next to a spinner on stderr until the returned stop
+// function is called, for work that gives no progress of its own. It is
+// only animated interactively; streaming mode reports msg as a progress
+// event and other modes write nothing.
+func (o *Output) Spinner(msg string) (stop func()) {
+	if o.stream {
+		o.Progress(msg)
+		return func() {}
+	}
+	if o.json || o.quiet || !o.isTTY {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(spinnerInterval)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Fprintf(o.errOut, "\r%s %s", o.styles.Info.Render(spinnerFrames[i%len(spinnerFrames)]), msg)
+			select {
+			case <-done:
+				// Clear the line so later output starts clean
+				fmt.Fprint(o.errOut, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSpinner(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	stop := NewWithWriters(&stdout, &stderr, WithTTY(true), WithNoColor(true)).Spinner("Fetching")
+	stop()
+	stop()
+
+	if got := stderr.String(); !strings.Contains(got, "⠋ Fetching") || !strings.HasSuffix(got, "\r\033[K") {
+		t.Errorf("stderr = %q", got)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+
+	stderr.Reset()
+	NewWithWriters(&stdout, &stderr, WithTTY(false)).Spinner("Fetching")()
+	if stderr.Len() != 0 {
+		t.Errorf("non-interactive spinner wrote %q", stderr.String())
+	}
+}
@@ -0,0 +1,69 @@
+package output
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// stream enables NDJSON event output for every Output; see SetStream
+var stream bool
+
+// SetStream enables streaming mode (--json-stream): instead of a single
+// JSON document, output is written as newline-delimited JSON events so
+// agents can follow a command's progress. It implies JSON mode.
+func SetStream(s bool) {
+	stream = s
+}
+
+// Event types emitted in streaming mode
+const (
+	EventProgress = "progress" // a status message (success, info, warning)
+	EventContent  = "content"  // a chunk of partial AI output
+	EventResult   = "result"   // the command's final result
+	EventError    = "error"    // the command failed
+)
+
+// Event is a single line of --json-stream output
+type Event struct {
+	Type    string      `json:"type"`
+	Time    time.Time   `json:"time"`
+	Level   string      `json:"level,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Text    string      `json:"text,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// IsStream returns true if output is written as NDJSON events
+func (o *Output) IsStream() bool {
+	return o.stream
+}
+
+// Emit writes an event as a single JSON line
+func (o *Output) Emit(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = o.out.Write(data)
+	return err
+}
+
+// Progress reports a status message. It is only written in streaming
+// mode; human output uses Info, Success and friends instead.
+func (o *Output) Progress(msg string) {
+	if o.stream {
+		o.Emit(Event{Type: EventProgress, Level: "info", Message: msg})
+	}
+}
+
+// Content reports a chunk of partial AI output as it is generated. It is
+// only written in streaming mode.
+func (o *Output) Content(text string) {
+	if o.stream {
+		o.Emit(Event{Type: EventContent, Text: text})
+	}
+}
@@ -0,0 +1,41 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestStreamEvents(t *testing.T) {
+	var buf bytes.Buffer
+	o := &Output{json: true, stream: true, out: &buf, errOut: &buf}
+
+	o.Progress("working")
+	o.Success("done")
+	o.Content("partial")
+	o.Dim("decorative")
+	o.JSON(map[string]string{"key": "value"})
+	o.ErrorResult(errors.New("boom"))
+
+	var types []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid event line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, e.Type)
+	}
+
+	want := []string{EventProgress, EventProgress, EventContent, EventResult, EventError}
+	if len(types) != len(want) {
+		t.Fatalf("event types = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("event %d = %s, want %s", i, types[i], want[i])
+		}
+	}
+}
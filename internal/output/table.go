@@ -0,0 +1,99 @@
+package output
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// minColumnWidth is the narrowest a column is truncated to
+const minColumnWidth = 4
+
+// Table prints rows as aligned columns under bold headers. On a terminal,
+// the widest columns are truncated so rows fit the terminal width.
+func (o *Output) Table(headers []string, rows [][]string) {
+	lines := strings.Split(strings.TrimSuffix(RenderTable(headers, rows, o.width), "\n"), "\n")
+	for i, line := range lines {
+		if i == 0 && !o.noColor {
+			line = o.styles.Bold.Render(line)
+		}
+		o.Println(line)
+	}
+}
+
+// RenderTable renders headers and rows as left-aligned columns separated
+// by two spaces. If maxWidth is positive, the widest columns are shrunk
+// (down to minColumnWidth) and their cells truncated with "…" until each
+// line fits.
+func RenderTable(headers []string, rows [][]string, maxWidth int) string {
+	cols := len(headers)
+	widths := make([]int, cols)
+	for i, h := range headers {
+		widths[i] = lipgloss.Width(h)
+	}
+	for _, row := range rows {
+		for i := 0; i < cols && i < len(row); i++ {
+			if w := lipgloss.Width(row[i]); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	if maxWidth > 0 {
+		for total(widths) > maxWidth {
+			widest := 0
+			for i := range widths {
+				if widths[i] > widths[widest] {
+					widest = i
+				}
+			}
+			if widths[widest] <= minColumnWidth {
+				break
+			}
+			widths[widest]--
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		var line strings.Builder
+		for i := 0; i < cols; i++ {
+			var cell string
+			if i < len(cells) {
+				cell = truncate(cells[i], widths[i])
+			}
+			line.WriteString(cell)
+			if i < cols-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-lipgloss.Width(cell)+2))
+			}
+		}
+		b.WriteString(strings.TrimRight(line.String(), " "))
+		b.WriteString("\n")
+	}
+
+	writeRow(headers)
+	for _, row := range rows {
+		writeRow(row)
+	}
+	return b.String()
+}
+
+func total(widths []int) int {
+	sum := 2 * (len(widths) - 1)
+	for _, w := range widths {
+		sum += w
+	}
+	return sum
+}
+
+// truncate shortens s to at most width display columns, ending in "…"
+func truncate(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,38 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestRenderTable(t *testing.T) {
+	got := RenderTable(
+		[]string{"NAME", "BRANCH"},
+		[][]string{{"a", "feature-auth"}, {"longer-name", "x"}},
+		0,
+	)
+	want := "NAME         BRANCH\n" +
+		"a            feature-auth\n" +
+		"longer-name  x\n"
+	if got != want {
+		t.Errorf("RenderTable =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderTableTruncates(t *testing.T) {
+	got := RenderTable(
+		[]string{"NAME", "PATH"},
+		[][]string{{"a", "/very/long/path/to/a/worktree/that/does/not/fit"}},
+		30,
+	)
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if w := lipgloss.Width(line); w > 30 {
+			t.Errorf("line %q is %d columns wide, want <= 30", line, w)
+		}
+	}
+	if !strings.Contains(got, "…") {
+		t.Errorf("expected truncated cell, got:\n%s", got)
+	}
+}
@@ -0,0 +1,111 @@
+// Package paths holds the path handling that differs between platforms.
+// Functions take the target GOOS explicitly, so Windows behavior is tested
+// on any platform; callers pass runtime.GOOS.
+package paths
+
+import (
+	"path"
+	"strings"
+)
+
+// Windows is the GOOS of Windows
+const Windows = "windows"
+
+// Normalize cleans p for goos. On Windows, where git prints paths with
+// forward slashes (C:/src/app), separators become backslashes and the drive
+// letter is upper-cased, so git's paths match the ones from os.Getwd.
+func Normalize(goos, p string) string {
+	if p == "" {
+		return ""
+	}
+	if goos != Windows {
+		return path.Clean(p)
+	}
+
+	p = strings.ReplaceAll(p, `\`, "/")
+	unc := strings.HasPrefix(p, "//")
+	p = path.Clean(p)
+	if unc {
+		// path.Clean collapses the leading // of \\server\share
+		p = "/" + p
+	}
+	if len(p) >= 2 && p[1] == ':' {
+		p = strings.ToUpper(p[:1]) + p[1:]
+		if len(p) == 2 {
+			// C: alone is the current directory on C, not its root
+			p += "/"
+		}
+	}
+	return strings.ReplaceAll(p, "/", `\`)
+}
+
+// Same reports whether a and b name the same path on goos. Windows
+// filesystems are case-insensitive, so case is ignored there.
+func Same(goos, a, b string) bool {
+	a, b = Normalize(goos, a), Normalize(goos, b)
+	if goos == Windows {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// HomeRelative returns the rest of p if it starts with ~, as in ~ or
+// ~/worktrees (or ~\worktrees on Windows)
+func HomeRelative(goos, p string) (string, bool) {
+	switch {
+	case p == "~":
+		return "", true
+	case strings.HasPrefix(p, "~/"), goos == Windows && strings.HasPrefix(p, `~\`):
+		return p[2:], true
+	}
+	return "", false
+}
+
+// ConfigDir returns the directory holding the user config: %APPDATA%\lazywork
+// on Windows and ~/.config/lazywork elsewhere
+func ConfigDir(goos string, getenv func(string) string, home string) string {
+	if goos == Windows {
+		if dir := getenv("APPDATA"); dir != "" {
+			return join(goos, dir, "lazywork")
+		}
+		return join(goos, home, "AppData", "Roaming", "lazywork")
+	}
+	return join(goos, home, ".config", "lazywork")
+}
+
+// StateDir returns the directory holding state shared by all repositories:
+// $XDG_STATE_HOME/lazywork if set, else %LOCALAPPDATA%\lazywork on Windows
+// and ~/.local/state/lazywork elsewhere
+func StateDir(goos string, getenv func(string) string, home string) string {
+	if dir := getenv("XDG_STATE_HOME"); dir != "" {
+		return join(goos, dir, "lazywork")
+	}
+	if goos == Windows {
+		if dir := getenv("LOCALAPPDATA"); dir != "" {
+			return join(goos, dir, "lazywork")
+		}
+		return join(goos, home, "AppData", "Local", "lazywork")
+	}
+	return join(goos, home, ".local", "state", "lazywork")
+}
+
+// DataDir returns the directory holding data kept for the user, such as
+// archives: $XDG_DATA_HOME/lazywork if set, else %LOCALAPPDATA%\lazywork on
+// Windows and ~/.local/share/lazywork elsewhere
+func DataDir(goos string, getenv func(string) string, home string) string {
+	if dir := getenv("XDG_DATA_HOME"); dir != "" {
+		return join(goos, dir, "lazywork")
+	}
+	if goos == Windows {
+		if dir := getenv("LOCALAPPDATA"); dir != "" {
+			return join(goos, dir, "lazywork")
+		}
+		return join(goos, home, "AppData", "Local", "lazywork")
+	}
+	return join(goos, home, ".local", "share", "lazywork")
+}
+
+// join is filepath.Join for goos
+func join(goos string, elem ...string) string {
+	return Normalize(goos, path.Join(elem...))
+}
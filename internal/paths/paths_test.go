@@ -0,0 +1,103 @@
+package paths
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		goos, path, want string
+	}{
+		{"linux", "/src/app/", "/src/app"},
+		{"linux", "/src/app/../app/.worktrees", "/src/app/.worktrees"},
+		{"linux", `/src/a\b`, `/src/a\b`},
+		{"darwin", "", ""},
+		{Windows, "C:/src/app", `C:\src\app`},
+		{Windows, "c:/src/app/", `C:\src\app`},
+		{Windows, `C:\src\app\..\lib`, `C:\src\lib`},
+		{Windows, "C:/", `C:\`},
+		{Windows, "C:", `C:\`},
+		{Windows, `\\server\share\app`, `\\server\share\app`},
+		{Windows, "//server/share/app/", `\\server\share\app`},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.goos, tt.path); got != tt.want {
+			t.Errorf("Normalize(%s, %q) = %q, want %q", tt.goos, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSame(t *testing.T) {
+	tests := []struct {
+		goos, a, b string
+		want       bool
+	}{
+		{"linux", "/src/app", "/src/app/", true},
+		{"linux", "/src/App", "/src/app", false},
+		{Windows, "C:/src/app", `c:\Src\App`, true},
+		{Windows, "C:/src/app", `C:\src\lib`, false},
+	}
+	for _, tt := range tests {
+		if got := Same(tt.goos, tt.a, tt.b); got != tt.want {
+			t.Errorf("Same(%s, %q, %q) = %v, want %v", tt.goos, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestHomeRelative(t *testing.T) {
+	tests := []struct {
+		goos, path, rest string
+		ok               bool
+	}{
+		{"linux", "~", "", true},
+		{"linux", "~/worktrees/{repo}", "worktrees/{repo}", true},
+		{"linux", `~\worktrees`, "", false},
+		{"linux", "~user/worktrees", "", false},
+		{Windows, `~\worktrees`, "worktrees", true},
+		{Windows, "~/worktrees", "worktrees", true},
+		{Windows, ".worktrees", "", false},
+	}
+	for _, tt := range tests {
+		rest, ok := HomeRelative(tt.goos, tt.path)
+		if rest != tt.rest || ok != tt.ok {
+			t.Errorf("HomeRelative(%s, %q) = %q, %v; want %q, %v", tt.goos, tt.path, rest, ok, tt.rest, tt.ok)
+		}
+	}
+}
+
+func TestDirs(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+
+	if got := ConfigDir("linux", getenv, "/home/me"); got != "/home/me/.config/lazywork" {
+		t.Errorf("ConfigDir(linux) = %q", got)
+	}
+	if got := StateDir("darwin", getenv, "/Users/me"); got != "/Users/me/.local/state/lazywork" {
+		t.Errorf("StateDir(darwin) = %q", got)
+	}
+	if got := ConfigDir(Windows, getenv, `C:\Users\me`); got != `C:\Users\me\AppData\Roaming\lazywork` {
+		t.Errorf("ConfigDir(windows) without APPDATA = %q", got)
+	}
+	if got := DataDir("linux", getenv, "/home/me"); got != "/home/me/.local/share/lazywork" {
+		t.Errorf("DataDir(linux) = %q", got)
+	}
+
+	env["APPDATA"] = `C:\Users\me\AppData\Roaming`
+	env["LOCALAPPDATA"] = `C:\Users\me\AppData\Local`
+	if got := ConfigDir(Windows, getenv, `C:\Users\me`); got != `C:\Users\me\AppData\Roaming\lazywork` {
+		t.Errorf("ConfigDir(windows) = %q", got)
+	}
+	if got := StateDir(Windows, getenv, `C:\Users\me`); got != `C:\Users\me\AppData\Local\lazywork` {
+		t.Errorf("StateDir(windows) = %q", got)
+	}
+	if got := DataDir(Windows, getenv, `C:\Users\me`); got != `C:\Users\me\AppData\Local\lazywork` {
+		t.Errorf("DataDir(windows) = %q", got)
+	}
+
+	env["XDG_STATE_HOME"] = "/tmp/state"
+	if got := StateDir("linux", getenv, "/home/me"); got != "/tmp/state/lazywork" {
+		t.Errorf("StateDir with XDG_STATE_HOME = %q", got)
+	}
+	env["XDG_DATA_HOME"] = "/tmp/data"
+	if got := DataDir("linux", getenv, "/home/me"); got != "/tmp/data/lazywork" {
+		t.Errorf("DataDir with XDG_DATA_HOME = %q", got)
+	}
+}
@@ -0,0 +1,180 @@
+package prompt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+)
+
+const (
+	FormatPlain     = "plain"
+	FormatPowerline = "powerline"
+	FormatJSON      = "json"
+)
+
+// maxCacheEntries bounds the cache file; it is reset when exceeded
+const maxCacheEntries = 256
+
+// Formats returns the supported segment formats
+func Formats() []string {
+	return []string{FormatPlain, FormatPowerline, FormatJSON}
+}
+
+// Info describes the current worktree for a prompt segment
+type Info struct {
+	Worktree       string `json:"worktree"`
+	Branch         string `json:"branch"`
+	Main           bool   `json:"main"`
+	Path           string `json:"path"`
+	PreviousBranch string `json:"previous_branch,omitempty"`
+}
+
+// Using returns true while a 'worktree use' is active
+func (i *Info) Using() bool {
+	return i.PreviousBranch != ""
+}
+
+type cachedPaths struct {
+	Toplevel  string `json:"toplevel"`
+	GitDir    string `json:"git_dir"`
+	CommonDir string `json:"common_dir"`
+}
+
+// CachePath returns the location of the prompt path cache
+func CachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "lazywork", "prompt.json")
+}
+
+// Gather collects prompt info for cwd. Repository paths are cached per
+// directory so git is invoked at most once, and only on a cache miss;
+// branch and use state are read directly from files in the git dir.
+func Gather(ctx context.Context, cwd string) (*Info, error) {
+	cache := loadCache()
+
+	paths, ok := cache[cwd]
+	if ok {
+		if _, err := os.Stat(paths.GitDir); err != nil {
+			ok = false
+		}
+	}
+
+	if !ok {
+		toplevel, gitDir, commonDir, err := git.PathInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		paths = cachedPaths{Toplevel: toplevel, GitDir: gitDir, CommonDir: commonDir}
+		if len(cache) >= maxCacheEntries {
+			cache = map[string]cachedPaths{}
+		}
+		cache[cwd] = paths
+		saveCache(cache)
+	}
+
+	info := &Info{
+		Path: paths.Toplevel,
+		Main: filepath.Clean(paths.GitDir) == filepath.Clean(paths.CommonDir),
+	}
+	info.Worktree = filepath.Base(paths.Toplevel)
+	info.Branch = readHead(paths.GitDir)
+
+	// The stack is shared by every worktree; only the checkout 'use'
+	// switched shows where 'return' goes
+	if frames, err := git.ReadUseStack(paths.CommonDir, paths.GitDir); err == nil && len(frames) > 0 {
+		if top := frames[len(frames)-1]; top.Target == info.Branch || (top.Target == "" && info.Main) {
+			info.PreviousBranch = top.Branch
+		}
+	}
+
+	return info, nil
+}
+
+func readHead(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		return strings.TrimPrefix(ref, "refs/heads/")
+	}
+	if len(head) >= 7 {
+		return head[:7]
+	}
+	return head
+}
+
+func loadCache() map[string]cachedPaths {
+	cache := map[string]cachedPaths{}
+	data, err := os.ReadFile(CachePath())
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return map[string]cachedPaths{}
+	}
+	return cache
+}
+
+func saveCache(cache map[string]cachedPaths) {
+	path := CachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0o644)
+}
+
+// Render formats info as a prompt segment
+func Render(info *Info, format string) (string, error) {
+	switch format {
+	case FormatPlain:
+		return renderPlain(info), nil
+	case FormatPowerline:
+		return renderPowerline(info), nil
+	case FormatJSON:
+		data, err := json.Marshal(info)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("unsupported format '%s'. Supported: %s", format, strings.Join(Formats(), ", "))
+	}
+}
+
+func renderPlain(info *Info) string {
+	segment := info.Branch
+	if !info.Main {
+		segment = info.Worktree + ":" + info.Branch
+	}
+	if info.Using() {
+		segment += " ↩ " + info.PreviousBranch
+	}
+	return segment
+}
+
+func renderPowerline(info *Info) string {
+	const (
+		reset     = "\x1b[0m"
+		separator = ""
+	)
+	// Blue segment for the worktree, yellow when a 'use' is active
+	bg, fg := "\x1b[44m\x1b[97m", "\x1b[34m"
+	if info.Using() {
+		bg, fg = "\x1b[43m\x1b[30m", "\x1b[33m"
+	}
+	return bg + " " + renderPlain(info) + " " + reset + fg + separator + reset
+}
@@ -0,0 +1,222 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/internal/git/gittest"
+)
+
+const pathInfo = "rev-parse --show-toplevel --absolute-git-dir --git-common-dir"
+
+// repo lays out the git dirs of a repository with a feature worktree,
+// answers rev-parse from the worktree and keeps the cache in a temporary
+// directory
+type repo struct {
+	git       *gittest.Runner
+	toplevel  string
+	gitDir    string
+	commonDir string
+}
+
+func newRepo(t *testing.T) *repo {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	root := t.TempDir()
+	r := &repo{
+		toplevel:  filepath.Join(root, "feature"),
+		commonDir: filepath.Join(root, "app", ".git"),
+	}
+	r.gitDir = filepath.Join(r.commonDir, "worktrees", "feature")
+	writeFile(t, filepath.Join(r.commonDir, "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(r.gitDir, "HEAD"), "ref: refs/heads/feature/login\n")
+
+	r.git = gittest.New(t).On(pathInfo, r.toplevel+"\n"+r.gitDir+"\n"+r.commonDir+"\n")
+	return r
+}
+
+// pathInfoCalls counts the git invocations made so far
+func (r *repo) pathInfoCalls() int {
+	n := 0
+	for _, call := range r.git.Calls() {
+		if call == pathInfo {
+			n++
+		}
+	}
+	return n
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGatherCachesPaths(t *testing.T) {
+	r := newRepo(t)
+
+	for range 2 {
+		info, err := Gather(t.Context(), r.toplevel)
+		if err != nil {
+			t.Fatalf("Gather failed: %v", err)
+		}
+		if info.Worktree != "feature" || info.Branch != "feature/login" || info.Main || info.Using() {
+			t.Errorf("info = %+v, want the feature worktree", info)
+		}
+	}
+	if n := r.pathInfoCalls(); n != 1 {
+		t.Errorf("git ran %d times, want once", n)
+	}
+	if !strings.HasPrefix(CachePath(), os.Getenv("XDG_CACHE_HOME")) {
+		t.Errorf("CachePath = %s, want it under XDG_CACHE_HOME", CachePath())
+	}
+}
+
+func TestGatherInvalidatesMissingGitDir(t *testing.T) {
+	r := newRepo(t)
+	if _, err := Gather(t.Context(), r.toplevel); err != nil {
+		t.Fatal(err)
+	}
+
+	// The worktree was removed and the directory reused by a new one
+	if err := os.RemoveAll(r.gitDir); err != nil {
+		t.Fatal(err)
+	}
+	gitDir := filepath.Join(r.commonDir, "worktrees", "feature1")
+	writeFile(t, filepath.Join(gitDir, "HEAD"), "ref: refs/heads/feature/signup\n")
+	r.git.On(pathInfo, r.toplevel+"\n"+gitDir+"\n"+r.commonDir+"\n")
+
+	info, err := Gather(t.Context(), r.toplevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := r.pathInfoCalls(); n != 2 {
+		t.Errorf("git ran %d times, want again after the git dir disappeared", n)
+	}
+	if info.Branch != "feature/signup" {
+		t.Errorf("branch = %q, want feature/signup", info.Branch)
+	}
+	if cache := loadCache(); cache[r.toplevel].GitDir != gitDir {
+		t.Errorf("cached paths = %+v, want the new git dir", cache[r.toplevel])
+	}
+}
+
+func TestGatherResetsFullCache(t *testing.T) {
+	r := newRepo(t)
+
+	cache := map[string]cachedPaths{}
+	for i := range maxCacheEntries {
+		cache[fmt.Sprintf("/src/repo%d", i)] = cachedPaths{GitDir: r.commonDir}
+	}
+	saveCache(cache)
+
+	if _, err := Gather(t.Context(), r.toplevel); err != nil {
+		t.Fatal(err)
+	}
+	cache = loadCache()
+	if _, ok := cache[r.toplevel]; len(cache) != 1 || !ok {
+		t.Errorf("cache has %d entries, want only the new one", len(cache))
+	}
+}
+
+func TestGatherUseMarker(t *testing.T) {
+	tests := []struct {
+		name     string
+		main     bool
+		target   string
+		previous string
+	}{
+		{"worktree use switched", false, "feature/login", "develop"},
+		{"other checkout switched", false, "feature/signup", ""},
+		{"main checkout without target", true, "", "develop"},
+		{"worktree without target", false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRepo(t)
+			cwd := r.toplevel
+			if tt.main {
+				cwd = filepath.Dir(r.commonDir)
+				r.git.On(pathInfo, cwd+"\n"+r.commonDir+"\n"+r.commonDir+"\n")
+			}
+			frames, _ := json.Marshal([]map[string]string{{"branch": "develop", "target": tt.target}})
+			writeFile(t, filepath.Join(r.commonDir, "lazywork", "use-stack.json"), string(frames))
+
+			info, err := Gather(t.Context(), cwd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Main != tt.main || info.PreviousBranch != tt.previous {
+				t.Errorf("info = %+v, want main %v and previous branch %q", info, tt.main, tt.previous)
+			}
+		})
+	}
+}
+
+func TestReadHead(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"branch", "ref: refs/heads/feature/login\n", "feature/login"},
+		{"other ref", "ref: refs/remotes/origin/main\n", "refs/remotes/origin/main"},
+		{"detached", "0123456789abcdef0123456789abcdef01234567\n", "0123456"},
+		{"short", "abc\n", "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "HEAD"), tt.head)
+			if got := readHead(dir); got != tt.want {
+				t.Errorf("readHead = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := readHead(t.TempDir()); got != "" {
+		t.Errorf("readHead without HEAD = %q, want empty", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	main := &Info{Worktree: "app", Branch: "main", Main: true}
+	feature := &Info{Worktree: "feature", Branch: "feature/login", PreviousBranch: "main"}
+
+	tests := []struct {
+		name   string
+		info   *Info
+		format string
+		want   string
+	}{
+		{"plain main", main, FormatPlain, "main"},
+		{"plain worktree in use", feature, FormatPlain, "feature:feature/login ↩ main"},
+		{"powerline main", main, FormatPowerline, "\x1b[44m\x1b[97m main \x1b[0m\x1b[34m\ue0b0\x1b[0m"},
+		{"powerline in use", feature, FormatPowerline, "\x1b[43m\x1b[30m feature:feature/login ↩ main \x1b[0m\x1b[33m\ue0b0\x1b[0m"},
+		{"json", feature, FormatJSON, `{"worktree":"feature","branch":"feature/login","main":false,"path":"","previous_branch":"main"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.info, tt.format)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Render(main, "zsh"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
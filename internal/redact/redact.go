@@ -0,0 +1,123 @@
+// Package redact masks secrets such as API keys, cloud credentials and
+// private keys in text before it is sent to an AI provider.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule finds one kind of secret. If the pattern has a group named
+// "secret" only that group is replaced, so the surrounding key name stays
+// readable to the model.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// CustomRule names the findings of user-configured patterns
+const CustomRule = "custom"
+
+// Rules are the built-in secret patterns
+var Rules = []Rule{
+	{"private-key", regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----(?:[\s\S]*?-----END[A-Z ]*PRIVATE KEY( BLOCK)?-----|[^\n]*(?:\n[+\- ]?[A-Za-z0-9+/=]{16,})*)`)},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws-secret-key", regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"gitlab-token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}`)},
+	{"ai-api-key", regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_\-]{20,}`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9\-]{10,}`)},
+	{"stripe-key", regexp.MustCompile(`\b[sr]k_live_[A-Za-z0-9]{20,}`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`)},
+	{"generic-secret", regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|passw(?:or)?d)["']?\s*[:=]\s*["'](?P<secret>[^"'\s]{8,})["']`)},
+}
+
+// Finding counts the secrets of one rule that were replaced
+type Finding struct {
+	Rule  string `json:"rule"`
+	Count int    `json:"count"`
+}
+
+// Redactor replaces secrets with [REDACTED:<rule>]
+type Redactor struct {
+	rules []Rule
+}
+
+// New returns a redactor for the built-in rules plus patterns, which are
+// regular expressions reported as "custom"
+func New(patterns []string) (*Redactor, error) {
+	r := &Redactor{rules: append([]Rule(nil), Rules...)}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern '%s': %w", p, err)
+		}
+		r.rules = append(r.rules, Rule{CustomRule, re})
+	}
+	return r, nil
+}
+
+// Redact returns text with every secret replaced, and how many of each
+// kind were found, ordered by rule name
+func (r *Redactor) Redact(text string) (string, []Finding) {
+	counts := map[string]int{}
+	for _, rule := range r.rules {
+		secret := rule.Pattern.SubexpIndex("secret")
+		text = rule.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			// Only mask the secret group if there is one, keeping the key
+			// name around it
+			start, end := 0, len(match)
+			if secret >= 0 {
+				if loc := rule.Pattern.FindStringSubmatchIndex(match); loc != nil && loc[2*secret] >= 0 {
+					start, end = loc[2*secret], loc[2*secret+1]
+				}
+			}
+			if strings.Contains(match[start:end], "[REDACTED:") {
+				return match
+			}
+			counts[rule.Name]++
+			return match[:start] + "[REDACTED:" + rule.Name + "]" + match[end:]
+		})
+	}
+
+	findings := make([]Finding, 0, len(counts))
+	for name, n := range counts {
+		findings = append(findings, Finding{name, n})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Rule < findings[j].Rule })
+	return text, findings
+}
+
+// Summary describes findings in one line, e.g. "aws-access-key ×2,
+// private-key", adding up repeated rules
+func Summary(findings []Finding) string {
+	counts := map[string]int{}
+	var rules []string
+	for _, f := range findings {
+		if _, ok := counts[f.Rule]; !ok {
+			rules = append(rules, f.Rule)
+		}
+		counts[f.Rule] += f.Count
+	}
+	sort.Strings(rules)
+
+	parts := make([]string, len(rules))
+	for i, rule := range rules {
+		parts[i] = rule
+		if counts[rule] > 1 {
+			parts[i] += fmt.Sprintf(" ×%d", counts[rule])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Total returns the number of secrets in findings
+func Total(findings []Finding) int {
+	n := 0
+	for _, f := range findings {
+		n += f.Count
+	}
+	return n
+}
@@ -0,0 +1,206 @@
+// Package release suggests the next semantic version of a project from the
+// commits since its last release and prompts for release notes.
+package release
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// Bump is the part of a version a release increments
+type Bump int
+
+const (
+	None Bump = iota
+	Patch
+	Minor
+	Major
+)
+
+// Bumps lists the bump names accepted by ParseBump
+var Bumps = []string{"major", "minor", "patch"}
+
+func (b Bump) String() string {
+	switch b {
+	case Major:
+		return "major"
+	case Minor:
+		return "minor"
+	case Patch:
+		return "patch"
+	default:
+		return "none"
+	}
+}
+
+// ParseBump parses "major", "minor" or "patch"
+func ParseBump(s string) (Bump, error) {
+	switch strings.ToLower(s) {
+	case "major":
+		return Major, nil
+	case "minor":
+		return Minor, nil
+	case "patch":
+		return Patch, nil
+	default:
+		return None, fmt.Errorf("invalid bump '%s' (%s)", s, strings.Join(Bumps, ", "))
+	}
+}
+
+// Version is a semantic version, as written in a tag
+type Version struct {
+	// Prefix is what the tag puts before the number, usually "v"
+	Prefix              string
+	Major, Minor, Patch int
+}
+
+var versionTag = regexp.MustCompile(`^(.*?)(\d+)\.(\d+)\.(\d+)$`)
+
+// ParseVersion parses a tag such as v1.2.3 or 1.2.3. Pre-releases such
+// as v1.2.3-rc.1 are not versions to release from.
+func ParseVersion(tag string) (Version, bool) {
+	m := versionTag.FindStringSubmatch(tag)
+	if m == nil || strings.ContainsAny(m[1], "0123456789") {
+		return Version{}, false
+	}
+	major, _ := strconv.Atoi(m[2])
+	minor, _ := strconv.Atoi(m[3])
+	patch, _ := strconv.Atoi(m[4])
+	return Version{Prefix: m[1], Major: major, Minor: minor, Patch: patch}, true
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+}
+
+// Next returns the version after v for bump
+func (v Version) Next(bump Bump) Version {
+	switch bump {
+	case Major:
+		return Version{Prefix: v.Prefix, Major: v.Major + 1}
+	case Minor:
+		return Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor + 1}
+	case Patch:
+		return Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	default:
+		return v
+	}
+}
+
+var (
+	conventionalHeader = regexp.MustCompile(`^(\w+)(?:\([^()]*\))?(!?): `)
+	breakingFooter     = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+)
+
+// Classify returns the bump a commit message calls for: Major for a
+// breaking change, Minor for a feature and Patch for a fix, from its
+// Conventional Commits type or gitmoji
+func Classify(message string) Bump {
+	subject, _, _ := strings.Cut(message, "\n")
+	if breakingFooter.MatchString(message) {
+		return Major
+	}
+
+	g, rest, hasGitmoji := commitmsg.SplitGitmoji(subject)
+	if m := conventionalHeader.FindStringSubmatch(rest); m != nil {
+		switch {
+		case m[2] == "!":
+			return Major
+		case m[1] == "feat":
+			return Minor
+		case m[1] == "fix", m[1] == "perf":
+			return Patch
+		}
+		return None
+	}
+
+	if hasGitmoji {
+		switch g.Code {
+		case ":boom:":
+			return Major
+		case ":sparkles:":
+			return Minor
+		case ":bug:", ":ambulance:", ":adhesive_bandage:", ":lock:", ":zap:":
+			return Patch
+		}
+	}
+	return None
+}
+
+// Suggest returns the bump for a release of messages: the largest any of
+// them calls for, and at least Patch when there is anything to release
+func Suggest(messages []string) Bump {
+	bump := None
+	for _, m := range messages {
+		bump = max(bump, Classify(m))
+	}
+	if bump == None && len(messages) > 0 {
+		return Patch
+	}
+	return bump
+}
+
+// Propose returns the version to release after previous for messages and
+// the bump it makes. Before 1.0.0 breaking changes only bump the minor
+// version, and a first release (previous is the zero version) is 0.1.0.
+func Propose(previous Version, messages []string) (Version, Bump) {
+	bump := Suggest(messages)
+	if previous.Major == 0 && bump == Major {
+		bump = Minor
+	}
+	if previous.Major == 0 && previous.Minor == 0 && previous.Patch == 0 {
+		bump = Minor
+	}
+	return previous.Next(bump), bump
+}
+
+const notesPrompt = `You write release notes for a software project from the messages of the
+commits in the release. Reply in Markdown only. Group the changes under
+"### Breaking changes", "### Features", "### Fixes" and "### Other changes",
+leaving out empty groups and changes that don't matter to users, such as
+CI, formatting or refactoring. One short bullet per change, in plain
+language; merge commits that belong together. No title and no preamble.`
+
+// maxNotesInput bounds the commit messages sent for release notes
+const maxNotesInput = 24000
+
+// NotesMessages returns the prompt for the notes of releasing version
+// after previous (empty for a first release). language names the
+// language to write them in, empty for English.
+func NotesMessages(version, previous string, messages []string, language string) []types.Message {
+	var b strings.Builder
+	if previous != "" {
+		fmt.Fprintf(&b, "Write the release notes for %s, the release after %s.\n\n", version, previous)
+	} else {
+		fmt.Fprintf(&b, "Write the release notes for %s, the first release.\n\n", version)
+	}
+	b.WriteString("Commits, oldest first:\n\n")
+	for _, m := range messages {
+		lines := strings.Split(strings.TrimSpace(m), "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = "  " + lines[i]
+			}
+		}
+		entry := "- " + strings.Join(lines, "\n") + "\n"
+		if b.Len()+len(entry) > maxNotesInput {
+			b.WriteString("... (more commits omitted)\n")
+			break
+		}
+		b.WriteString(entry)
+	}
+
+	system := notesPrompt
+	if language != "" {
+		system += "\nWrite the notes in " + language + "."
+	}
+	return []types.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: b.String()},
+	}
+}
@@ -0,0 +1,95 @@
+package release
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+		ok   bool
+	}{
+		{"v1.2.3", "v1.2.3", true},
+		{"1.0.10", "1.0.10", true},
+		{"app/v0.4.0", "app/v0.4.0", true},
+		{"v1.2.3-rc.1", "", false},
+		{"v1.2", "", false},
+		{"release-2024", "", false},
+	}
+	for _, tt := range tests {
+		v, ok := ParseVersion(tt.tag)
+		if ok != tt.ok || (ok && v.String() != tt.want) {
+			t.Errorf("ParseVersion(%q) = %v, %v", tt.tag, v, ok)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	v := Version{Prefix: "v", Major: 1, Minor: 4, Patch: 2}
+	for bump, want := range map[Bump]string{Major: "v2.0.0", Minor: "v1.5.0", Patch: "v1.4.3", None: "v1.4.2"} {
+		if got := v.Next(bump).String(); got != want {
+			t.Errorf("Next(%s) = %s, want %s", bump, got, want)
+		}
+	}
+}
+
+func TestPropose(t *testing.T) {
+	tests := []struct {
+		previous Version
+		messages []string
+		want     string
+	}{
+		{Version{Prefix: "v", Major: 1, Minor: 2}, []string{"fix: typo", "feat!: drop v1"}, "v2.0.0"},
+		{Version{Minor: 3}, []string{"feat!: drop v1"}, "0.4.0"},
+		{Version{Prefix: "v"}, []string{"fix: typo"}, "v0.1.0"},
+	}
+	for _, tt := range tests {
+		if got, _ := Propose(tt.previous, tt.messages); got.String() != tt.want {
+			t.Errorf("Propose(%s, %q) = %s, want %s", tt.previous, tt.messages, got, tt.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		message string
+		want    Bump
+	}{
+		{"feat(auth): add login", Minor},
+		{"fix: handle redirect", Patch},
+		{"feat!: drop sessions", Major},
+		{"refactor: split config\n\nBREAKING CHANGE: config moved", Major},
+		{"docs: update readme", None},
+		{"✨ feat: add login", Minor},
+		{"🐛 Fix redirect loop", Patch},
+		{"💥 Remove v1 API", Major},
+		{"Update dependencies", None},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.message); got != tt.want {
+			t.Errorf("Classify(%q) = %s, want %s", tt.message, got, tt.want)
+		}
+	}
+
+	if got := Suggest([]string{"docs: readme", "fix: typo", "feat: export"}); got != Minor {
+		t.Errorf("Suggest = %s, want minor", got)
+	}
+	if got := Suggest([]string{"chore: bump deps"}); got != Patch {
+		t.Errorf("Suggest without features or fixes = %s, want patch", got)
+	}
+	if got := Suggest(nil); got != None {
+		t.Errorf("Suggest(nil) = %s, want none", got)
+	}
+}
+
+func TestNotesMessages(t *testing.T) {
+	messages := NotesMessages("v1.1.0", "v1.0.0", []string{"feat: add export\n\nCSV only."}, "Spanish")
+	if !strings.Contains(messages[0].Content, "in Spanish") {
+		t.Errorf("system prompt misses the language:\n%s", messages[0].Content)
+	}
+	if !strings.Contains(messages[1].Content, "after v1.0.0") || !strings.Contains(messages[1].Content, "- feat: add export\n\n  CSV only.") {
+		t.Errorf("unexpected prompt:\n%s", messages[1].Content)
+	}
+}
@@ -0,0 +1,91 @@
+// Package search finds snippets in commits and ranks them by embedding
+// similarity for 'lazywork search'.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSnippet bounds the length of a snippet, in runes
+const maxSnippet = 120
+
+// Snippet returns the first line of text containing query, ignoring case,
+// trimmed and cut around the match to at most maxSnippet runes. It
+// returns "" when no line matches.
+func Snippet(text, query string) string {
+	q := strings.ToLower(query)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(strings.ToLower(line), q); i >= 0 && q != "" {
+			return cut(line, i)
+		}
+	}
+	return ""
+}
+
+// DiffSnippet returns the first added or removed line of patch containing
+// query, like Snippet, keeping its "+" or "-"
+func DiffSnippet(patch, query string) string {
+	var changed []string
+	for _, line := range strings.Split(patch, "\n") {
+		if (strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")) &&
+			!strings.HasPrefix(line, "+++") && !strings.HasPrefix(line, "---") {
+			changed = append(changed, line)
+		}
+	}
+	return Snippet(strings.Join(changed, "\n"), query)
+}
+
+// cut shortens line to maxSnippet runes, keeping the match at byte
+// offset at near the middle
+func cut(line string, at int) string {
+	if utf8.RuneCountInString(line) <= maxSnippet {
+		return line
+	}
+	runes := []rune(line)
+	// Lowercasing may have moved the match in a few scripts
+	at = min(at, len(line))
+	start := max(0, min(utf8.RuneCountInString(line[:at])-maxSnippet/2, len(runes)-maxSnippet))
+	snippet := string(runes[start : start+maxSnippet])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if start+maxSnippet < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// Similarity returns the cosine similarity of a and b, 0 when either is
+// empty or their lengths differ
+func Similarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// Rank returns the indexes of vectors ordered from most to least similar
+// to query, with their scores
+func Rank(query []float32, vectors [][]float32) (order []int, scores []float64) {
+	scores = make([]float64, len(vectors))
+	order = make([]int, len(vectors))
+	for i, v := range vectors {
+		scores[i] = Similarity(query, v)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	return order, scores
+}
@@ -0,0 +1,38 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSnippet(t *testing.T) {
+	text := "Add settings\n\n  Raise the Timeout for slow networks  \n"
+	if got := Snippet(text, "timeout"); got != "Raise the Timeout for slow networks" {
+		t.Errorf("Snippet = %q", got)
+	}
+	if got := Snippet(text, "retry"); got != "" {
+		t.Errorf("Snippet without a match = %q", got)
+	}
+
+	long := strings.Repeat("a", 200) + " needle " + strings.Repeat("b", 200)
+	got := Snippet(long, "needle")
+	if !strings.Contains(got, "needle") || !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("long Snippet = %q, want the match cut out of the middle", got)
+	}
+
+	patch := "diff --git a/timeout.go b/timeout.go\n--- a/timeout.go\n+++ b/timeout.go\n@@ -1 +1 @@\n-timeout = 10\n+timeout = 30\n"
+	if got := DiffSnippet(patch, "TIMEOUT"); got != "-timeout = 10" {
+		t.Errorf("DiffSnippet = %q, want the first changed line", got)
+	}
+}
+
+func TestRank(t *testing.T) {
+	order, scores := Rank([]float32{1, 0}, [][]float32{{0, 1}, {1, 1}, {2, 0}, {}})
+	if want := []int{2, 1, 0, 3}; !slices.Equal(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if scores[2] < 0.999 || scores[0] != 0 || scores[3] != 0 {
+		t.Errorf("scores = %v", scores)
+	}
+}
@@ -0,0 +1,240 @@
+// Package server exposes lazywork commands over a local HTTP+JSON API for
+// editor plugins and GUIs. Every request runs one command, so the API
+// always matches the CLI's --json output.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+)
+
+// ChildEnv is set in the environment of commands run by the server, so
+// they can refuse to start another server
+const ChildEnv = "LAZYWORK_SERVE_CHILD"
+
+// maxBody caps the size of request bodies
+const maxBody = 1 << 20
+
+// Response is the result of running a command. Result holds the command's
+// --json output on success, Error its structured error otherwise; output a
+// command printed that is not JSON is returned in Output.
+type Response struct {
+	ExitCode int             `json:"exit_code"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    json.RawMessage `json:"error,omitempty"`
+	Output   string          `json:"output,omitempty"`
+}
+
+// Runner runs lazywork with args in dir, an empty dir meaning the server's
+// working directory
+type Runner func(ctx context.Context, dir string, args []string) Response
+
+// Server serves the API
+type Server struct {
+	Token   string
+	Version string
+	Run     Runner
+}
+
+// RunRequest is the body of POST /v1/run
+type RunRequest struct {
+	Dir  string   `json:"dir"`
+	Args []string `json:"args"`
+}
+
+// AddRequest is the body of POST /v1/worktrees
+type AddRequest struct {
+	Dir    string `json:"dir"`
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	Push   bool   `json:"push"`
+}
+
+// Handler returns the API's HTTP handler:
+//
+//	GET    /v1/health                    server version, no token needed
+//	POST   /v1/run                       any command: {"dir", "args"}
+//	GET    /v1/worktrees?dir=&status=1   worktree list
+//	POST   /v1/worktrees                 worktree add: {"dir", "name", "branch", "push"}
+//	GET    /v1/worktrees/{name}?dir=     worktree status
+//	DELETE /v1/worktrees/{name}?dir=&force=1  worktree remove
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/health", s.health)
+	mux.Handle("POST /v1/run", s.auth(s.run))
+	mux.Handle("GET /v1/worktrees", s.auth(s.listWorktrees))
+	mux.Handle("POST /v1/worktrees", s.auth(s.addWorktree))
+	mux.Handle("GET /v1/worktrees/{name}", s.auth(s.worktreeStatus))
+	mux.Handle("DELETE /v1/worktrees/{name}", s.auth(s.removeWorktree))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, lazyerr.New(lazyerr.InvalidArgument, "no such endpoint: %s %s", r.Method, r.URL.Path).
+			WithHint("See 'lazywork serve --help' for the endpoints"))
+	})
+	return mux
+}
+
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, lazyerr.New(lazyerr.Unauthorized, "missing or invalid token"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"version": s.Version})
+}
+
+func (s *Server) run(w http.ResponseWriter, r *http.Request) {
+	var req RunRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if len(req.Args) == 0 {
+		writeError(w, http.StatusBadRequest, lazyerr.New(lazyerr.InvalidArgument, "no command given").
+			WithHint(`Pass the command line in "args", e.g. {"args": ["worktree", "list"]}`))
+		return
+	}
+	s.exec(w, r, req.Dir, req.Args)
+}
+
+func (s *Server) listWorktrees(w http.ResponseWriter, r *http.Request) {
+	args := []string{"worktree", "list"}
+	if flag(r, "status") {
+		args = append(args, "--status")
+	}
+	s.exec(w, r, r.URL.Query().Get("dir"), args)
+}
+
+func (s *Server) addWorktree(w http.ResponseWriter, r *http.Request) {
+	var req AddRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, lazyerr.New(lazyerr.NameRequired, "no worktree name given").
+			WithHint(`Pass the name in "name"`))
+		return
+	}
+	args := []string{"worktree", "add", req.Name}
+	if req.Branch != "" {
+		args = append(args, "--branch", req.Branch)
+	}
+	if req.Push {
+		args = append(args, "--push")
+	}
+	s.exec(w, r, req.Dir, args)
+}
+
+func (s *Server) worktreeStatus(w http.ResponseWriter, r *http.Request) {
+	s.exec(w, r, r.URL.Query().Get("dir"), []string{"worktree", "status", r.PathValue("name")})
+}
+
+func (s *Server) removeWorktree(w http.ResponseWriter, r *http.Request) {
+	args := []string{"worktree", "remove", r.PathValue("name")}
+	if flag(r, "force") {
+		args = append(args, "--force")
+	}
+	s.exec(w, r, r.URL.Query().Get("dir"), args)
+}
+
+// exec runs a command and writes its response: 200 if it succeeded, 422
+// with the command's error otherwise
+func (s *Server) exec(w http.ResponseWriter, r *http.Request, dir string, args []string) {
+	if dir != "" {
+		if !filepath.IsAbs(dir) {
+			writeError(w, http.StatusBadRequest, lazyerr.New(lazyerr.InvalidArgument, "dir must be an absolute path").WithDetail("dir", dir))
+			return
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			writeError(w, http.StatusBadRequest, lazyerr.New(lazyerr.PathError, "no such directory: %s", dir).
+				WithDetail("dir", dir).WithHint("Pass the path of a repository or worktree in dir"))
+			return
+		}
+	}
+
+	resp := s.Run(r.Context(), dir, args)
+	status := http.StatusOK
+	if resp.ExitCode != 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, resp)
+}
+
+// ExecRunner returns a Runner that runs the lazywork executable at exe
+// with --json. Commands run without a terminal or stdin, so they never
+// prompt.
+func ExecRunner(exe string) Runner {
+	return func(ctx context.Context, dir string, args []string) Response {
+		var stdout, stderr bytes.Buffer
+		c := exec.CommandContext(ctx, exe, append([]string{"--json"}, args...)...)
+		c.Dir = dir
+		c.Env = append(os.Environ(), ChildEnv+"=1", "NO_COLOR=1")
+		c.Stdout, c.Stderr = &stdout, &stderr
+
+		resp := Response{}
+		if err := c.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				e, _ := json.Marshal(lazyerr.Wrap(lazyerr.CommandFailed, err))
+				return Response{ExitCode: lazyerr.ExitError, Error: e}
+			}
+			resp.ExitCode = exitErr.ExitCode()
+		}
+
+		out := bytes.TrimSpace(stdout.Bytes())
+		switch {
+		case len(out) > 0 && json.Valid(out) && resp.ExitCode == 0:
+			resp.Result = out
+		case len(out) > 0 && json.Valid(out):
+			resp.Error = out
+		default:
+			resp.Output = strings.TrimSpace(string(out) + "\n" + stderr.String())
+		}
+		return resp
+	}
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, lazyerr.New(lazyerr.InvalidArgument, "invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+func flag(r *http.Request, name string) bool {
+	switch r.URL.Query().Get(name) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, e *lazyerr.Error) {
+	data, _ := json.Marshal(e)
+	writeJSON(w, status, Response{ExitCode: lazyerr.ExitCode(e), Error: data})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type call struct {
+	dir  string
+	args []string
+}
+
+func newTestServer(t *testing.T, resp Response) (*httptest.Server, *[]call) {
+	t.Helper()
+	var calls []call
+	s := &Server{
+		Token:   "secret",
+		Version: "1.0.0",
+		Run: func(ctx context.Context, dir string, args []string) Response {
+			calls = append(calls, call{dir, args})
+			return resp
+		},
+	}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts, &calls
+}
+
+func do(t *testing.T, method, url, token, body string) (*http.Response, Response) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var resp Response
+	json.NewDecoder(res.Body).Decode(&resp)
+	return res, resp
+}
+
+func TestAuth(t *testing.T) {
+	ts, calls := newTestServer(t, Response{Result: json.RawMessage(`{}`)})
+
+	for _, token := range []string{"", "wrong"} {
+		res, resp := do(t, "GET", ts.URL+"/v1/worktrees", token, "")
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, want 401", token, res.StatusCode)
+		}
+		if !strings.Contains(string(resp.Error), "UNAUTHORIZED") {
+			t.Errorf("token %q: error = %s", token, resp.Error)
+		}
+	}
+	if len(*calls) != 0 {
+		t.Errorf("commands ran without a valid token: %v", *calls)
+	}
+
+	res, _ := do(t, "GET", ts.URL+"/v1/health", "", "")
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("health status = %d, want 200 without a token", res.StatusCode)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		method, path, body string
+		want               []string
+	}{
+		{"POST", "/v1/run", `{"dir": "` + dir + `", "args": ["stash", "list"]}`, []string{"stash", "list"}},
+		{"GET", "/v1/worktrees?status=1&dir=" + dir, "", []string{"worktree", "list", "--status"}},
+		{"POST", "/v1/worktrees", `{"dir": "` + dir + `", "name": "auth", "branch": "feature/auth", "push": true}`,
+			[]string{"worktree", "add", "auth", "--branch", "feature/auth", "--push"}},
+		{"GET", "/v1/worktrees/auth?dir=" + dir, "", []string{"worktree", "status", "auth"}},
+		{"DELETE", "/v1/worktrees/auth?force=true&dir=" + dir, "", []string{"worktree", "remove", "auth", "--force"}},
+	}
+
+	for _, tt := range tests {
+		ts, calls := newTestServer(t, Response{Result: json.RawMessage(`{"ok":true}`)})
+		res, resp := do(t, tt.method, ts.URL+tt.path, "secret", tt.body)
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%s %s: status = %d, want 200", tt.method, tt.path, res.StatusCode)
+			continue
+		}
+		var result struct{ OK bool }
+		if err := json.Unmarshal(resp.Result, &result); err != nil || !result.OK {
+			t.Errorf("%s %s: result = %s", tt.method, tt.path, resp.Result)
+		}
+		if len(*calls) != 1 || (*calls)[0].dir != dir || !reflect.DeepEqual((*calls)[0].args, tt.want) {
+			t.Errorf("%s %s: ran %v, want %v in %s", tt.method, tt.path, *calls, tt.want, dir)
+		}
+	}
+}
+
+func TestCommandFailure(t *testing.T) {
+	ts, _ := newTestServer(t, Response{ExitCode: 4, Error: json.RawMessage(`{"code":"WORKTREE_NOT_FOUND"}`)})
+
+	res, resp := do(t, "GET", ts.URL+"/v1/worktrees/nope", "secret", "")
+	if res.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", res.StatusCode)
+	}
+	if resp.ExitCode != 4 {
+		t.Errorf("exit_code = %d, want 4", resp.ExitCode)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	ts, calls := newTestServer(t, Response{})
+
+	for _, body := range []string{
+		`{"args": []}`,
+		`{"args": ["worktree", "list"], "dir": "relative"}`,
+		`{"args": ["worktree", "list"], "dir": "/does/not/exist"}`,
+		`{"args": ["worktree", "list"], "cwd": "/"}`,
+		`not json`,
+	} {
+		res, _ := do(t, "POST", ts.URL+"/v1/run", "secret", body)
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("body %s: status = %d, want 400", body, res.StatusCode)
+		}
+	}
+	if len(*calls) != 0 {
+		t.Errorf("commands ran for bad requests: %v", *calls)
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", TokenFile)
+
+	token, err := LoadToken(path)
+	if err != nil {
+		t.Fatalf("LoadToken failed: %v", err)
+	}
+	if len(token) != 64 {
+		t.Errorf("token = %q, want 64 hex characters", token)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	again, err := LoadToken(path)
+	if err != nil || again != token {
+		t.Errorf("LoadToken = %q, %v; want the saved token %q", again, err, token)
+	}
+}
@@ -0,0 +1,235 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Marker comments delimiting the block managed by 'lazywork shell install'
+const (
+	MarkerBegin = "# >>> lazywork >>>"
+	MarkerEnd   = "# <<< lazywork <<<"
+)
+
+// InstallResult describes the changes made by Install
+type InstallResult struct {
+	Shell          string `json:"shell"`
+	RcFile         string `json:"rc_file"`
+	Backup         string `json:"backup,omitempty"`
+	Changed        bool   `json:"changed"`
+	CompletionFile string `json:"completion_file,omitempty"`
+}
+
+// CompletionLine returns the RC line that loads completions for bash, zsh
+// and PowerShell. Fish loads completions from a file instead (see
+// CompletionFile).
+func CompletionLine(shell string) string {
+	switch shell {
+	case Bash, Zsh:
+		return fmt.Sprintf("source <(lazywork completion %s)", shell)
+	case PowerShell:
+		return "lazywork completion powershell | Out-String | Invoke-Expression"
+	default:
+		return ""
+	}
+}
+
+// CompletionFile returns the path where completions are installed as a file,
+// or an empty string when the shell loads them from its RC file.
+func CompletionFile(shell string) string {
+	if shell != Fish {
+		return ""
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "fish", "completions", "lazywork.fish")
+}
+
+// InstallBlock returns the marker-delimited block appended to the RC file
+func InstallBlock(shell string, completions bool) string {
+	lines := []string{MarkerBegin, InitLine(shell)}
+	if completions {
+		if line := CompletionLine(shell); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	lines = append(lines, MarkerEnd)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Install appends the init block to the shell's RC file, backing it up first.
+// It is idempotent: nothing is written if the block is already as wanted.
+// A block from an earlier install is replaced when it differs, such as
+// when completions are added; completions already in it are kept. An init
+// line the user added outside the markers is left alone.
+// completionScript is written to CompletionFile for shells that use one.
+func Install(shell string, completions bool, completionScript []byte) (*InstallResult, error) {
+	rcFile := RcFile(shell)
+	result := &InstallResult{Shell: shell, RcFile: rcFile}
+
+	data, err := os.ReadFile(rcFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", rcFile, err)
+	}
+	content := string(data)
+
+	var updated string
+	if start, end, ok := managedBlock(content); ok {
+		current := content[start:end]
+		if line := CompletionLine(shell); line != "" && strings.Contains(current, line) {
+			completions = true
+		}
+		if block := InstallBlock(shell, completions); current != block {
+			updated = content[:start] + block + content[end:]
+		}
+	} else if !strings.Contains(content, "lazywork shell init") {
+		block := InstallBlock(shell, completions)
+		if len(content) > 0 {
+			prefix := "\n"
+			if !strings.HasSuffix(content, "\n") {
+				prefix = "\n\n"
+			}
+			block = prefix + block
+		}
+		updated = content + block
+	}
+
+	if updated != "" {
+		if err := os.MkdirAll(filepath.Dir(rcFile), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(rcFile), err)
+		}
+
+		if len(content) > 0 {
+			backup := rcFile + ".lazywork.bak"
+			if err := os.WriteFile(backup, data, 0o644); err != nil {
+				return nil, fmt.Errorf("failed to back up %s: %w", rcFile, err)
+			}
+			result.Backup = backup
+		}
+
+		if err := os.WriteFile(rcFile, []byte(updated), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", rcFile, err)
+		}
+		result.Changed = true
+	}
+
+	if completions {
+		if path := CompletionFile(shell); path != "" {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+			}
+			if err := os.WriteFile(path, completionScript, 0o644); err != nil {
+				return nil, fmt.Errorf("failed to write completions: %w", err)
+			}
+			result.CompletionFile = path
+			result.Changed = true
+		}
+	}
+
+	return result, nil
+}
+
+// managedBlock returns the bounds in content of the block between the
+// markers, its trailing newline included
+func managedBlock(content string) (start, end int, ok bool) {
+	start = strings.Index(content, MarkerBegin)
+	if start < 0 {
+		return 0, 0, false
+	}
+	i := strings.Index(content[start:], MarkerEnd)
+	if i < 0 {
+		return 0, 0, false
+	}
+	end = start + i + len(MarkerEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	return start, end, true
+}
+
+// UninstallResult describes the changes made by Uninstall
+type UninstallResult struct {
+	Shell        string   `json:"shell"`
+	RcFile       string   `json:"rc_file"`
+	Backup       string   `json:"backup,omitempty"`
+	RemovedLines []string `json:"removed_lines,omitempty"`
+	RemovedFiles []string `json:"removed_files,omitempty"`
+	// Unmanaged is true when a lazywork init line remains outside the markers
+	Unmanaged bool `json:"unmanaged,omitempty"`
+}
+
+// Changed returns true if Uninstall modified or deleted anything
+func (r *UninstallResult) Changed() bool {
+	return len(r.RemovedLines) > 0 || len(r.RemovedFiles) > 0
+}
+
+// RemoveBlock strips marker-delimited lazywork blocks from content and
+// returns the remaining content along with the removed lines
+func RemoveBlock(content string) (string, []string) {
+	var kept, removed []string
+	inBlock := false
+
+	lines := strings.Split(content, "\n")
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == MarkerBegin:
+			inBlock = true
+			removed = append(removed, line)
+			// Drop the blank separator line Install adds before the block
+			if n := len(kept); n > 0 && strings.TrimSpace(kept[n-1]) == "" {
+				kept = kept[:n-1]
+			}
+		case trimmed == MarkerEnd && inBlock:
+			inBlock = false
+			removed = append(removed, line)
+		case inBlock:
+			removed = append(removed, line)
+		default:
+			kept = append(kept, line)
+		}
+	}
+
+	return strings.Join(kept, "\n"), removed
+}
+
+// Uninstall removes the lazywork block from the shell's RC file (backing it
+// up first) and deletes any installed completion file
+func Uninstall(shell string) (*UninstallResult, error) {
+	rcFile := RcFile(shell)
+	result := &UninstallResult{Shell: shell, RcFile: rcFile}
+
+	content, err := os.ReadFile(rcFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", rcFile, err)
+	}
+
+	if len(content) > 0 {
+		updated, removed := RemoveBlock(string(content))
+		if len(removed) > 0 {
+			backup := rcFile + ".lazywork.bak"
+			if err := os.WriteFile(backup, content, 0o644); err != nil {
+				return nil, fmt.Errorf("failed to back up %s: %w", rcFile, err)
+			}
+			result.Backup = backup
+
+			if err := os.WriteFile(rcFile, []byte(updated), 0o644); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", rcFile, err)
+			}
+			result.RemovedLines = removed
+		}
+		result.Unmanaged = strings.Contains(updated, "lazywork shell init")
+	}
+
+	if path := CompletionFile(shell); path != "" {
+		err := os.Remove(path)
+		if err == nil {
+			result.RemovedFiles = append(result.RemovedFiles, path)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	return result, nil
+}
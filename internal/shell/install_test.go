@@ -0,0 +1,191 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setTestHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	orig := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	t.Cleanup(func() { os.Setenv("HOME", orig) })
+	return home
+}
+
+func TestInstallAppendsBlockWithBackup(t *testing.T) {
+	home := setTestHome(t)
+	rcFile := filepath.Join(home, ".zshrc")
+	os.WriteFile(rcFile, []byte("export FOO=bar"), 0o644)
+
+	result, err := Install(Zsh, true, nil)
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if !result.Changed {
+		t.Error("expected Changed=true")
+	}
+	if result.Backup == "" {
+		t.Fatal("expected a backup file")
+	}
+
+	backup, _ := os.ReadFile(result.Backup)
+	if string(backup) != "export FOO=bar" {
+		t.Errorf("backup content = %q", backup)
+	}
+
+	content, _ := os.ReadFile(rcFile)
+	for _, want := range []string{"export FOO=bar\n\n", MarkerBegin, InitLine(Zsh), CompletionLine(Zsh), MarkerEnd} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("rc file missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestInstallIsIdempotent(t *testing.T) {
+	home := setTestHome(t)
+
+	if _, err := Install(Bash, false, nil); err != nil {
+		t.Fatalf("first Install failed: %v", err)
+	}
+	result, err := Install(Bash, false, nil)
+	if err != nil {
+		t.Fatalf("second Install failed: %v", err)
+	}
+	if result.Changed {
+		t.Error("expected second install to be a no-op")
+	}
+
+	content, _ := os.ReadFile(filepath.Join(home, ".bashrc"))
+	if n := strings.Count(string(content), MarkerBegin); n != 1 {
+		t.Errorf("found %d lazywork blocks, want 1", n)
+	}
+}
+
+func TestInstallAddsCompletions(t *testing.T) {
+	home := setTestHome(t)
+	rcFile := filepath.Join(home, ".bashrc")
+	os.WriteFile(rcFile, []byte("export FOO=bar\n"), 0o644)
+
+	if _, err := Install(Bash, false, nil); err != nil {
+		t.Fatalf("first Install failed: %v", err)
+	}
+	result, err := Install(Bash, true, nil)
+	if err != nil {
+		t.Fatalf("Install with completions failed: %v", err)
+	}
+	if !result.Changed {
+		t.Error("expected adding completions to change the rc file")
+	}
+
+	content, _ := os.ReadFile(rcFile)
+	want := "export FOO=bar\n\n" + InstallBlock(Bash, true)
+	if string(content) != want {
+		t.Errorf("rc file = %q, want %q", content, want)
+	}
+
+	// A plain install keeps the completions
+	result, err = Install(Bash, false, nil)
+	if err != nil {
+		t.Fatalf("third Install failed: %v", err)
+	}
+	content, _ = os.ReadFile(rcFile)
+	if result.Changed || string(content) != want {
+		t.Errorf("plain install after completions: changed = %v, rc file = %q", result.Changed, content)
+	}
+}
+
+func TestInstallFishCompletionFile(t *testing.T) {
+	setTestHome(t)
+
+	result, err := Install(Fish, true, []byte("complete -c lazywork"))
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if result.CompletionFile == "" {
+		t.Fatal("expected fish completion file")
+	}
+
+	data, err := os.ReadFile(result.CompletionFile)
+	if err != nil || string(data) != "complete -c lazywork" {
+		t.Errorf("completion file = %q, err = %v", data, err)
+	}
+
+	if strings.Contains(InstallBlock(Fish, true), "completion") {
+		t.Error("fish block should not contain a completion line")
+	}
+}
+
+func TestUninstallRemovesBlock(t *testing.T) {
+	home := setTestHome(t)
+	rcFile := filepath.Join(home, ".zshrc")
+	original := "export FOO=bar\n"
+	os.WriteFile(rcFile, []byte(original), 0o644)
+
+	if _, err := Install(Zsh, true, nil); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	result, err := Uninstall(Zsh)
+	if err != nil {
+		t.Fatalf("Uninstall failed: %v", err)
+	}
+	if len(result.RemovedLines) != 4 {
+		t.Errorf("removed %d lines, want 4: %v", len(result.RemovedLines), result.RemovedLines)
+	}
+	if result.Unmanaged {
+		t.Error("expected no unmanaged init line")
+	}
+
+	content, _ := os.ReadFile(rcFile)
+	if string(content) != original {
+		t.Errorf("rc file = %q, want %q", content, original)
+	}
+
+	result, err = Uninstall(Zsh)
+	if err != nil {
+		t.Fatalf("second Uninstall failed: %v", err)
+	}
+	if result.Changed() {
+		t.Error("expected second uninstall to be a no-op")
+	}
+}
+
+func TestUninstallReportsUnmanagedLine(t *testing.T) {
+	home := setTestHome(t)
+	rcFile := filepath.Join(home, ".bashrc")
+	os.WriteFile(rcFile, []byte(InitLine(Bash)+"\n"), 0o644)
+
+	result, err := Uninstall(Bash)
+	if err != nil {
+		t.Fatalf("Uninstall failed: %v", err)
+	}
+	if result.Changed() {
+		t.Error("expected unmanaged line to be left alone")
+	}
+	if !result.Unmanaged {
+		t.Error("expected Unmanaged=true")
+	}
+}
+
+func TestUninstallFishCompletionFile(t *testing.T) {
+	setTestHome(t)
+
+	if _, err := Install(Fish, true, []byte("complete -c lazywork")); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	result, err := Uninstall(Fish)
+	if err != nil {
+		t.Fatalf("Uninstall failed: %v", err)
+	}
+	if len(result.RemovedFiles) != 1 {
+		t.Fatalf("removed files = %v, want 1", result.RemovedFiles)
+	}
+	if _, err := os.Stat(CompletionFile(Fish)); !os.IsNotExist(err) {
+		t.Error("expected completion file to be deleted")
+	}
+}
@@ -3,33 +3,75 @@ package shell
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/paths"
 )
 
 const (
-	Bash = "bash"
-	Zsh  = "zsh"
-	Fish = "fish"
+	Bash       = "bash"
+	Zsh        = "zsh"
+	Fish       = "fish"
+	PowerShell = "powershell"
+	// Cmd is the Windows command prompt. It has no shell integration, but
+	// lazywork prints cd commands for it.
+	Cmd = "cmd"
 )
 
+// EnvShell names the shell whose integration is running lazywork. The
+// PowerShell integration sets it so cd commands are printed in its syntax.
+const EnvShell = "LAZYWORK_SHELL"
+
 func DetectShell() string {
-	shell := os.Getenv("SHELL")
-	base := filepath.Base(shell)
+	return detectShell(runtime.GOOS, os.Getenv("SHELL"))
+}
+
+// detectShell picks the shell from $SHELL, which is unset on Windows
+// outside Git Bash and similar environments
+func detectShell(goos, shellEnv string) string {
+	if shellEnv == "" && goos == paths.Windows {
+		return PowerShell
+	}
+	if goos == paths.Windows {
+		shellEnv = strings.ReplaceAll(shellEnv, `\`, "/")
+	}
+	base := strings.TrimSuffix(path.Base(shellEnv), ".exe")
 
 	switch base {
 	case "fish":
 		return Fish
 	case "zsh":
 		return Zsh
+	case "pwsh", "powershell":
+		return PowerShell
 	default:
 		return Bash
 	}
 }
 
+// Current returns the shell that runs the commands lazywork prints, such
+// as the cd of 'worktree go': the shell integration's, else the command
+// prompt on Windows outside a POSIX shell, else DetectShell
+func Current() string {
+	return currentShell(runtime.GOOS, os.Getenv)
+}
+
+func currentShell(goos string, getenv func(string) string) string {
+	if shell := getenv(EnvShell); IsValidShell(shell) || shell == Cmd {
+		return shell
+	}
+	if goos == paths.Windows && getenv("SHELL") == "" {
+		return Cmd
+	}
+	return detectShell(goos, getenv("SHELL"))
+}
+
 func IsValidShell(shell string) bool {
 	switch shell {
-	case Bash, Zsh, Fish:
+	case Bash, Zsh, Fish, PowerShell:
 		return true
 	default:
 		return false
@@ -37,7 +79,22 @@ func IsValidShell(shell string) bool {
 }
 
 func SupportedShells() []string {
-	return []string{Bash, Zsh, Fish}
+	return []string{Bash, Zsh, Fish, PowerShell}
+}
+
+// CDCommand returns the command that changes shell's directory to dir,
+// quoted for that shell
+func CDCommand(shell, dir string) string {
+	switch shell {
+	case PowerShell:
+		return "cd -LiteralPath '" + strings.ReplaceAll(dir, "'", "''") + "'"
+	case Cmd:
+		// /d also switches drives; Windows paths cannot contain double quotes
+		return `cd /d "` + dir + `"`
+	default:
+		// POSIX quoting, which fish also accepts
+		return "cd '" + strings.ReplaceAll(dir, "'", `'\''`) + "'"
+	}
 }
 
 func InitScript(shell string) string {
@@ -46,6 +103,8 @@ func InitScript(shell string) string {
 		return fishScript
 	case Zsh:
 		return zshScript
+	case PowerShell:
+		return powershellScript
 	case Bash:
 		return bashScript
 	default:
@@ -64,6 +123,9 @@ source <(lazywork completion zsh)`
 	case Bash:
 		return `# Add to ~/.bashrc:
 source <(lazywork completion bash)`
+	case PowerShell:
+		return `# Add to $PROFILE:
+lazywork completion powershell | Out-String | Invoke-Expression`
 	default:
 		return ""
 	}
@@ -134,12 +196,44 @@ alias lw='__lazywork_exec'
 alias lwt='__lazywork_exec worktree'
 `
 
+const powershellScript = `# LazyWork shell integration
+# Add to $PROFILE: Invoke-Expression (& lazywork shell init powershell | Out-String)
+
+# Wrapper function that handles cd commands from lazywork
+function __lazywork_exec {
+    $env:LAZYWORK_SHELL = 'powershell'
+    try {
+        $output = (& lazywork @args --shell-helper 2>&1 | Out-String).TrimEnd()
+        $exit_code = $LASTEXITCODE
+    } finally {
+        Remove-Item Env:LAZYWORK_SHELL -ErrorAction SilentlyContinue
+    }
+
+    if ($output -like 'cd *') {
+        Invoke-Expression $output
+    } else {
+        if ($output) { Write-Output $output }
+        $global:LASTEXITCODE = $exit_code
+    }
+}
+
+# Aliases (functions, as PowerShell aliases cannot take arguments)
+function lw { __lazywork_exec @args }
+function lwt { __lazywork_exec worktree @args }
+`
+
 func RcFile(shell string) string {
 	home, _ := os.UserHomeDir()
 
 	switch shell {
 	case Fish:
 		return filepath.Join(home, ".config", "fish", "config.fish")
+	case PowerShell:
+		// $PROFILE of PowerShell 7 for the current user and host
+		if runtime.GOOS == paths.Windows {
+			return filepath.Join(home, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1")
+		}
+		return filepath.Join(home, ".config", "powershell", "Microsoft.PowerShell_profile.ps1")
 	case Zsh:
 		return filepath.Join(home, ".zshrc")
 	case Bash:
@@ -153,6 +247,8 @@ func InitLine(shell string) string {
 	switch shell {
 	case Fish:
 		return "lazywork shell init fish | source"
+	case PowerShell:
+		return "Invoke-Expression (& lazywork shell init powershell | Out-String)"
 	default:
 		return fmt.Sprintf(`eval "$(lazywork shell init %s)"`, shell)
 	}
@@ -37,14 +37,14 @@ func TestDetectShell(t *testing.T) {
 }
 
 func TestIsValidShell(t *testing.T) {
-	valid := []string{"bash", "zsh", "fish"}
+	valid := []string{"bash", "zsh", "fish", "powershell"}
 	for _, s := range valid {
 		if !IsValidShell(s) {
 			t.Errorf("IsValidShell(%q) = false, want true", s)
 		}
 	}
 
-	invalid := []string{"powershell", "cmd", "sh", "tcsh", ""}
+	invalid := []string{"cmd", "sh", "tcsh", ""}
 	for _, s := range invalid {
 		if IsValidShell(s) {
 			t.Errorf("IsValidShell(%q) = true, want false", s)
@@ -55,11 +55,11 @@ func TestIsValidShell(t *testing.T) {
 func TestSupportedShells(t *testing.T) {
 	shells := SupportedShells()
 
-	if len(shells) != 3 {
-		t.Errorf("SupportedShells() returned %d shells, want 3", len(shells))
+	if len(shells) != 4 {
+		t.Errorf("SupportedShells() returned %d shells, want 4", len(shells))
 	}
 
-	expected := map[string]bool{"bash": true, "zsh": true, "fish": true}
+	expected := map[string]bool{"bash": true, "zsh": true, "fish": true, "powershell": true}
 	for _, s := range shells {
 		if !expected[s] {
 			t.Errorf("unexpected shell %q in SupportedShells()", s)
@@ -176,8 +176,12 @@ func TestInitScriptHandlesCd(t *testing.T) {
 			t.Errorf("InitScript(%q) doesn't handle cd command", shell)
 		}
 
-		if !strings.Contains(script, "eval") {
-			t.Errorf("InitScript(%q) doesn't use eval for cd", shell)
+		eval := "eval"
+		if shell == PowerShell {
+			eval = "Invoke-Expression"
+		}
+		if !strings.Contains(script, eval) {
+			t.Errorf("InitScript(%q) doesn't use %s for cd", shell, eval)
 		}
 	}
 }
@@ -186,14 +190,73 @@ func TestInitScriptPreservesExitCode(t *testing.T) {
 	for _, shell := range SupportedShells() {
 		script := InitScript(shell)
 
-		if shell == Fish {
+		switch shell {
+		case Fish:
 			if !strings.Contains(script, "$status") {
 				t.Errorf("Fish script doesn't capture $status")
 			}
-		} else {
+		case PowerShell:
+			if !strings.Contains(script, "$LASTEXITCODE") {
+				t.Errorf("PowerShell script doesn't capture $LASTEXITCODE")
+			}
+		default:
 			if !strings.Contains(script, "$?") || !strings.Contains(script, "exit_code") {
 				t.Errorf("%s script doesn't capture exit code", shell)
 			}
 		}
 	}
 }
+
+func TestDetectShellWindows(t *testing.T) {
+	tests := []struct {
+		shellEnv string
+		expected string
+	}{
+		{"", PowerShell},
+		{`C:\Program Files\Git\usr\bin\bash.exe`, Bash},
+		{"/usr/bin/zsh", Zsh},
+		{`C:\Program Files\PowerShell\7\pwsh.exe`, PowerShell},
+	}
+	for _, tt := range tests {
+		if got := detectShell("windows", tt.shellEnv); got != tt.expected {
+			t.Errorf("detectShell(windows, %q) = %q, want %q", tt.shellEnv, got, tt.expected)
+		}
+	}
+}
+
+func TestCurrentShell(t *testing.T) {
+	tests := []struct {
+		goos     string
+		env      map[string]string
+		expected string
+	}{
+		{"linux", map[string]string{"SHELL": "/bin/zsh"}, Zsh},
+		{"linux", map[string]string{"SHELL": "/bin/bash", EnvShell: "powershell"}, PowerShell},
+		{"windows", map[string]string{}, Cmd},
+		{"windows", map[string]string{EnvShell: "powershell"}, PowerShell},
+		{"windows", map[string]string{"SHELL": "/usr/bin/bash"}, Bash},
+		{"linux", map[string]string{"SHELL": "/bin/fish", EnvShell: "tcsh"}, Fish},
+	}
+	for _, tt := range tests {
+		got := currentShell(tt.goos, func(key string) string { return tt.env[key] })
+		if got != tt.expected {
+			t.Errorf("currentShell(%s, %v) = %q, want %q", tt.goos, tt.env, got, tt.expected)
+		}
+	}
+}
+
+func TestCDCommand(t *testing.T) {
+	tests := []struct {
+		shell, dir, expected string
+	}{
+		{Bash, "/src/app/.worktrees/feature", `cd '/src/app/.worktrees/feature'`},
+		{Fish, "/src/it's", `cd '/src/it'\''s'`},
+		{PowerShell, `C:\src\it's`, `cd -LiteralPath 'C:\src\it''s'`},
+		{Cmd, `D:\src\app`, `cd /d "D:\src\app"`},
+	}
+	for _, tt := range tests {
+		if got := CDCommand(tt.shell, tt.dir); got != tt.expected {
+			t.Errorf("CDCommand(%s, %q) = %q, want %q", tt.shell, tt.dir, got, tt.expected)
+		}
+	}
+}
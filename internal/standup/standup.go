@@ -0,0 +1,106 @@
+// Package standup turns the commits a developer made recently into the
+// prompt for a standup summary.
+package standup
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// Repo is the recent work in one repository
+type Repo struct {
+	Name    string               `json:"name"`
+	Path    string               `json:"path"`
+	Commits []git.AuthoredCommit `json:"commits"`
+}
+
+// Since returns the start of the period a standup covers: midnight days
+// days before now. With days 0 it covers the last working day, so on a
+// Monday it goes back to Friday.
+func Since(now time.Time, days int) time.Time {
+	if days <= 0 {
+		days = 1
+		switch now.Weekday() {
+		case time.Monday:
+			days = 3
+		case time.Sunday:
+			days = 2
+		}
+	}
+	y, m, d := now.Date()
+	return time.Date(y, m, d-days, 0, 0, 0, 0, now.Location())
+}
+
+const prompt = `You write a developer's standup update from the commits they made. Reply
+in Markdown only: a "### Done" section with short bullets of what was
+accomplished, grouped by repository when there are several, merging
+commits that belong together; then a "### Next" section with one or two
+bullets guessed from unfinished work such as WIP or fixup commits, or
+omit it when nothing suggests next steps. Write in the first person, in
+plain language rather than commit jargon. No title and no preamble.`
+
+// maxPromptCommits bounds the commits listed in the prompt
+const maxPromptCommits = 300
+
+// Messages returns the prompt for a standup summary of repos since the
+// given time. language names the language to write in, empty for English.
+func Messages(repos []Repo, since time.Time, language string) []types.Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "My commits since %s:\n", since.Format("Monday, January 2"))
+	listed := 0
+	for _, r := range repos {
+		if len(r.Commits) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\nRepository %s:\n", r.Name)
+		for _, c := range r.Commits {
+			if listed == maxPromptCommits {
+				b.WriteString("... (more commits omitted)\n")
+				break
+			}
+			fmt.Fprintf(&b, "- %s (%s, %s)\n", c.Subject, c.Branch, c.Date.Local().Format("Mon 15:04"))
+			listed++
+		}
+	}
+
+	system := prompt
+	if language != "" {
+		system += "\nWrite the update in " + language + "."
+	}
+	return []types.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: b.String()},
+	}
+}
+
+// Markdown lists the commits of repos by repository, for when no
+// summary is asked for
+func Markdown(repos []Repo) string {
+	var b strings.Builder
+	for _, r := range repos {
+		if len(r.Commits) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "### %s\n\n", r.Name)
+		for _, c := range r.Commits {
+			fmt.Fprintf(&b, "- %s (`%s`, %s)\n", c.Subject, c.Branch, c.Hash)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Count returns the number of commits in repos
+func Count(repos []Repo) int {
+	n := 0
+	for _, r := range repos {
+		n += len(r.Commits)
+	}
+	return n
+}
@@ -0,0 +1,50 @@
+package standup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+)
+
+func TestSince(t *testing.T) {
+	monday := time.Date(2026, 3, 9, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		now  time.Time
+		days int
+		want time.Time
+	}{
+		{monday, 0, time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{monday.AddDate(0, 0, 2), 0, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{monday, 7, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := Since(tt.now, tt.days); !got.Equal(tt.want) {
+			t.Errorf("Since(%s, %d) = %s, want %s", tt.now.Weekday(), tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestMessagesAndMarkdown(t *testing.T) {
+	date := time.Date(2026, 3, 9, 15, 4, 0, 0, time.Local)
+	repos := []Repo{
+		{Name: "api", Commits: []git.AuthoredCommit{{Hash: "abc1234", Date: date, Subject: "Add export", Branch: "feature"}}},
+		{Name: "empty"},
+		{Name: "web", Commits: []git.AuthoredCommit{{Hash: "def5678", Date: date, Subject: "Fix login", Branch: "main"}}},
+	}
+
+	messages := Messages(repos, date, "")
+	user := messages[1].Content
+	if !strings.Contains(user, "Repository api:\n- Add export (feature, Mon 15:04)") || strings.Contains(user, "empty") {
+		t.Errorf("unexpected prompt:\n%s", user)
+	}
+
+	want := "### api\n\n- Add export (`feature`, abc1234)\n\n### web\n\n- Fix login (`main`, def5678)"
+	if got := Markdown(repos); got != want {
+		t.Errorf("Markdown =\n%s\nwant\n%s", got, want)
+	}
+	if Count(repos) != 2 {
+		t.Errorf("Count = %d", Count(repos))
+	}
+}
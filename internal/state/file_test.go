@@ -0,0 +1,113 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateHistoryConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := UpdateHistory(dir, func(h *History) error {
+				h.Record(fmt.Sprintf("/repo/.worktrees/%d", i), now)
+				return nil
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("UpdateHistory failed: %v", err)
+		}
+	}
+
+	h, err := LoadHistory(dir)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(h.Entries) != n {
+		t.Errorf("got %d entries, want %d; concurrent updates were lost", len(h.Entries), n)
+	}
+}
+
+func TestUpdateMetaErrorSkipsSave(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := UpdateMeta(dir, func(s *MetaStore) error {
+		s.Set("/repo/.worktrees/a", Meta{Note: "x"}, time.Now())
+		return fmt.Errorf("abort")
+	})
+	if err == nil {
+		t.Fatal("expected error from UpdateMeta")
+	}
+	if _, err := os.Stat(filepath.Join(dir, metaFile)); !os.IsNotExist(err) {
+		t.Errorf("metadata was saved despite error: %v", err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state", "file.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFileAtomic failed: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Fatalf("read %q, %v; want %q", data, err, content)
+		}
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the state file, found %d entries", len(entries))
+	}
+}
+
+func TestLockFileExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), historyFile)
+
+	lock, err := LockFile(path)
+	if err != nil {
+		t.Fatalf("LockFile failed: %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		second, err := LockFile(path)
+		if err == nil {
+			second.Unlock()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second lock acquired while first was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	select {
+	case <-acquired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("second lock not acquired after unlock")
+	}
+}
@@ -0,0 +1,93 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const generationsFile = "generations.json"
+
+// generationsKept is how many generations are kept, newest last
+const generationsKept = 50
+
+// Generation is one message generated by an AI provider
+type Generation struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model"`
+	PromptHash string    `json:"prompt_hash"`
+	Output     string    `json:"output"`
+}
+
+// GenerationLog holds the most recent AI generations in a repository so
+// they can be shown or applied again without a new request
+type GenerationLog struct {
+	Generations []Generation `json:"generations"`
+
+	path string
+}
+
+// LoadGenerations reads the generation log from dir, returning an empty
+// log if it does not exist yet
+func LoadGenerations(dir string) (*GenerationLog, error) {
+	g := &GenerationLog{path: filepath.Join(dir, generationsFile)}
+
+	data, err := os.ReadFile(g.path)
+	if os.IsNotExist(err) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generation log: %w", err)
+	}
+
+	if err := json.Unmarshal(data, g); err != nil {
+		return nil, fmt.Errorf("failed to parse generation log: %w", err)
+	}
+
+	return g, nil
+}
+
+// UpdateGenerations loads the generation log from dir, applies fn and
+// saves it while holding the log's lock
+func UpdateGenerations(dir string, fn func(*GenerationLog)) error {
+	lock, err := LockFile(filepath.Join(dir, generationsFile))
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	g, err := LoadGenerations(dir)
+	if err != nil {
+		return err
+	}
+	fn(g)
+
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal generation log: %w", err)
+	}
+	return WriteFileAtomic(g.path, data, 0o644)
+}
+
+// Add appends a generation, dropping the oldest beyond the kept count
+func (g *GenerationLog) Add(gen Generation) {
+	g.Generations = append(g.Generations, gen)
+	if n := len(g.Generations) - generationsKept; n > 0 {
+		g.Generations = g.Generations[n:]
+	}
+}
+
+// Last returns the most recent generation made by command, or by any
+// command if command is empty
+func (g *GenerationLog) Last(command string) (Generation, bool) {
+	for i := len(g.Generations) - 1; i >= 0; i-- {
+		if command == "" || g.Generations[i].Command == command {
+			return g.Generations[i], true
+		}
+	}
+	return Generation{}, false
+}
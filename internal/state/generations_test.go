@@ -0,0 +1,44 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGenerationLog(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	err := UpdateGenerations(dir, func(g *GenerationLog) {
+		for i := 0; i < generationsKept+5; i++ {
+			g.Add(Generation{Time: now, Command: "resolve", Output: fmt.Sprint(i)})
+		}
+		g.Add(Generation{Time: now, Command: "commit", Output: "Fix login"})
+		g.Add(Generation{Time: now, Command: "worktree diff", Output: "summary"})
+	})
+	if err != nil {
+		t.Fatalf("UpdateGenerations failed: %v", err)
+	}
+
+	g, err := LoadGenerations(dir)
+	if err != nil {
+		t.Fatalf("LoadGenerations failed: %v", err)
+	}
+	if len(g.Generations) != generationsKept {
+		t.Fatalf("got %d generations, want %d", len(g.Generations), generationsKept)
+	}
+
+	if last, ok := g.Last(""); !ok || last.Command != "worktree diff" {
+		t.Errorf("Last(\"\") = %+v, %v", last, ok)
+	}
+	if last, ok := g.Last("commit"); !ok || last.Output != "Fix login" {
+		t.Errorf("Last(commit) = %+v, %v", last, ok)
+	}
+	if last, ok := g.Last("resolve"); !ok || last.Output != fmt.Sprint(generationsKept+4) {
+		t.Errorf("Last(resolve) = %+v, %v", last, ok)
+	}
+	if _, ok := g.Last("finish"); ok {
+		t.Error("Last(finish) found a generation")
+	}
+}
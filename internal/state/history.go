@@ -0,0 +1,166 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const historyFile = "history.json"
+
+// Entry records visits to a single worktree
+type Entry struct {
+	Path      string    `json:"path"`
+	Visits    int       `json:"visits"`
+	LastVisit time.Time `json:"last_visit"`
+}
+
+// History tracks worktree visits for frecency ordering and 'go -'
+type History struct {
+	Entries []Entry `json:"entries"`
+
+	path string
+}
+
+// Dir returns the lazywork state directory inside a git common dir
+func Dir(commonDir string) string {
+	return filepath.Join(commonDir, "lazywork")
+}
+
+// LoadHistory reads the history file from dir, returning an empty history
+// if it does not exist yet
+func LoadHistory(dir string) (*History, error) {
+	h := &History{path: filepath.Join(dir, historyFile)}
+
+	data, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("failed to parse history: %w", err)
+	}
+
+	return h, nil
+}
+
+// UpdateHistory loads the history from dir, applies fn and saves it while
+// holding the history lock, so concurrent lazywork processes do not lose
+// each other's updates. Nothing is saved if fn returns an error.
+func UpdateHistory(dir string, fn func(*History) error) (*History, error) {
+	lock, err := LockFile(filepath.Join(dir, historyFile))
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	h, err := LoadHistory(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(h); err != nil {
+		return nil, err
+	}
+	if err := h.Save(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Save atomically writes the history back to the file it was loaded from.
+// Use UpdateHistory for read-modify-write cycles.
+func (h *History) Save() error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	return WriteFileAtomic(h.path, data, 0o644)
+}
+
+// Record registers a visit to path at the given time
+func (h *History) Record(path string, now time.Time) {
+	for i := range h.Entries {
+		if h.Entries[i].Path == path {
+			h.Entries[i].Visits++
+			h.Entries[i].LastVisit = now
+			return
+		}
+	}
+	h.Entries = append(h.Entries, Entry{Path: path, Visits: 1, LastVisit: now})
+}
+
+// Get returns the entry for path, if any
+func (h *History) Get(path string) (Entry, bool) {
+	for _, e := range h.Entries {
+		if e.Path == path {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Score returns the zoxide-style frecency score for path: visit count
+// weighted by how recently the worktree was last visited
+func (h *History) Score(path string, now time.Time) float64 {
+	e, ok := h.Get(path)
+	if !ok {
+		return 0
+	}
+
+	age := now.Sub(e.LastVisit)
+	var weight float64
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 0.5
+	default:
+		weight = 0.25
+	}
+
+	return float64(e.Visits) * weight
+}
+
+// Prune removes entries whose path no longer exists according to exists,
+// typically worktrees that were removed, and returns the removed paths
+func (h *History) Prune(exists func(path string) bool) []string {
+	var removed []string
+	kept := h.Entries[:0]
+	for _, e := range h.Entries {
+		if exists(e.Path) {
+			kept = append(kept, e)
+		} else {
+			removed = append(removed, e.Path)
+		}
+	}
+	h.Entries = kept
+	return removed
+}
+
+// DirExists reports whether path exists and is a directory
+func DirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// Previous returns the most recently visited path other than current
+func (h *History) Previous(current string) (string, bool) {
+	var best Entry
+	for _, e := range h.Entries {
+		if e.Path == current {
+			continue
+		}
+		if best.Path == "" || e.LastVisit.After(best.LastVisit) {
+			best = e
+		}
+	}
+	return best.Path, best.Path != ""
+}
@@ -0,0 +1,98 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryRecordAndPersist(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	h, err := LoadHistory(dir)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(h.Entries) != 0 {
+		t.Fatalf("expected empty history, got %d entries", len(h.Entries))
+	}
+
+	h.Record("/repo/.worktrees/a", now)
+	h.Record("/repo/.worktrees/a", now)
+	h.Record("/repo/.worktrees/b", now)
+	if err := h.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadHistory(dir)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+
+	e, ok := loaded.Get("/repo/.worktrees/a")
+	if !ok || e.Visits != 2 {
+		t.Errorf("entry a = %+v, want 2 visits", e)
+	}
+	if _, ok := loaded.Get("/repo/.worktrees/c"); ok {
+		t.Error("unexpected entry for c")
+	}
+}
+
+func TestHistoryScore(t *testing.T) {
+	now := time.Now()
+	h := &History{Entries: []Entry{
+		{Path: "recent", Visits: 1, LastVisit: now.Add(-time.Minute)},
+		{Path: "today", Visits: 1, LastVisit: now.Add(-3 * time.Hour)},
+		{Path: "frequent", Visits: 10, LastVisit: now.Add(-30 * 24 * time.Hour)},
+	}}
+
+	if got := h.Score("recent", now); got != 4 {
+		t.Errorf("Score(recent) = %v, want 4", got)
+	}
+	if got := h.Score("today", now); got != 2 {
+		t.Errorf("Score(today) = %v, want 2", got)
+	}
+	if got := h.Score("frequent", now); got != 2.5 {
+		t.Errorf("Score(frequent) = %v, want 2.5", got)
+	}
+	if got := h.Score("unknown", now); got != 0 {
+		t.Errorf("Score(unknown) = %v, want 0", got)
+	}
+}
+
+func TestHistoryPrevious(t *testing.T) {
+	now := time.Now()
+	h := &History{}
+
+	if _, ok := h.Previous("a"); ok {
+		t.Error("expected no previous entry in empty history")
+	}
+
+	h.Record("a", now.Add(-2*time.Minute))
+	h.Record("b", now.Add(-time.Minute))
+	h.Record("c", now)
+
+	if got, _ := h.Previous("c"); got != "b" {
+		t.Errorf("Previous(c) = %q, want b", got)
+	}
+	if got, _ := h.Previous("elsewhere"); got != "c" {
+		t.Errorf("Previous(elsewhere) = %q, want c", got)
+	}
+}
+
+func TestHistoryPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	h := &History{}
+	h.Record(dir, now.Add(-time.Minute))
+	h.Record(dir+"/removed", now)
+
+	removed := h.Prune(DirExists)
+	if len(removed) != 1 || removed[0] != dir+"/removed" {
+		t.Errorf("Prune removed %v, want the missing path", removed)
+	}
+	if got, _ := h.Previous("elsewhere"); got != dir {
+		t.Errorf("Previous after prune = %q, want %q", got, dir)
+	}
+}
@@ -0,0 +1,148 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const journalFile = "journal.json"
+
+// journalLimit is the number of operations the journal keeps
+const journalLimit = 50
+
+// Kinds of journaled operations
+const (
+	OpFinish       = "finish"
+	OpRemove       = "remove"
+	OpBranchDelete = "branch-delete"
+)
+
+// BranchRef is a branch and the commit it pointed at
+type BranchRef struct {
+	Name   string `json:"name"`
+	Commit string `json:"commit"`
+}
+
+// WorktreeRef is a removed worktree
+type WorktreeRef struct {
+	Path string `json:"path"`
+	// Branch is empty for a worktree in detached HEAD
+	Branch string `json:"branch,omitempty"`
+	Head   string `json:"head"`
+}
+
+// Operation is a destructive operation, recorded so 'lazywork undo' can
+// revert it
+type Operation struct {
+	ID      int       `json:"id"`
+	Kind    string    `json:"kind"`
+	Summary string    `json:"summary"`
+	Time    time.Time `json:"time"`
+	// Base is the branch a finish merged into, and BaseBefore and
+	// BaseAfter the commits it pointed at around the merge
+	Base       string `json:"base,omitempty"`
+	BaseBefore string `json:"base_before,omitempty"`
+	BaseAfter  string `json:"base_after,omitempty"`
+	// Pushed reports whether Base was pushed afterwards
+	Pushed bool `json:"pushed,omitempty"`
+	// Branches are the deleted branches
+	Branches []BranchRef `json:"branches,omitempty"`
+	// Worktrees are the removed worktrees
+	Worktrees []WorktreeRef `json:"worktrees,omitempty"`
+	Undone    bool          `json:"undone,omitempty"`
+}
+
+// Journal is the list of recent destructive operations of a repository,
+// oldest first
+type Journal struct {
+	Operations []Operation `json:"operations"`
+	LastID     int         `json:"last_id"`
+
+	path string
+}
+
+// LoadJournal reads the journal from dir, returning an empty journal if it
+// does not exist yet
+func LoadJournal(dir string) (*Journal, error) {
+	j := &Journal{path: filepath.Join(dir, journalFile)}
+
+	data, err := os.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("failed to parse journal: %w", err)
+	}
+
+	return j, nil
+}
+
+// UpdateJournal loads the journal from dir, applies fn and saves it while
+// holding the journal lock. Nothing is saved if fn returns an error.
+func UpdateJournal(dir string, fn func(*Journal) error) (*Journal, error) {
+	lock, err := LockFile(filepath.Join(dir, journalFile))
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	j, err := LoadJournal(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(j); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal journal: %w", err)
+	}
+	if err := WriteFileAtomic(j.path, data, 0o644); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Add appends op with the next ID and time now, and returns it along with
+// the oldest operations dropped to stay within the limit
+func (j *Journal) Add(op Operation, now time.Time) (Operation, []Operation) {
+	j.LastID++
+	op.ID = j.LastID
+	op.Time = now.UTC()
+	j.Operations = append(j.Operations, op)
+
+	var dropped []Operation
+	if extra := len(j.Operations) - journalLimit; extra > 0 {
+		dropped = append(dropped, j.Operations[:extra]...)
+		j.Operations = append([]Operation(nil), j.Operations[extra:]...)
+	}
+	return op, dropped
+}
+
+// Get returns the operation with the given ID
+func (j *Journal) Get(id int) (*Operation, bool) {
+	for i := range j.Operations {
+		if j.Operations[i].ID == id {
+			return &j.Operations[i], true
+		}
+	}
+	return nil, false
+}
+
+// Last returns the most recent operation not undone yet
+func (j *Journal) Last() (*Operation, bool) {
+	for i := len(j.Operations) - 1; i >= 0; i-- {
+		if !j.Operations[i].Undone {
+			return &j.Operations[i], true
+		}
+	}
+	return nil, false
+}
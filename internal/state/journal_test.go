@@ -0,0 +1,47 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	var dropped []Operation
+	_, err := UpdateJournal(dir, func(j *Journal) error {
+		for i := 0; i < journalLimit+2; i++ {
+			var d []Operation
+			_, d = j.Add(Operation{Kind: OpRemove, Summary: "remove"}, now)
+			dropped = append(dropped, d...)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateJournal failed: %v", err)
+	}
+	if len(dropped) != 2 || dropped[0].ID != 1 || dropped[1].ID != 2 {
+		t.Errorf("dropped = %+v, want operations 1 and 2", dropped)
+	}
+
+	j, err := LoadJournal(dir)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	if len(j.Operations) != journalLimit || j.LastID != journalLimit+2 {
+		t.Fatalf("got %d operations up to %d", len(j.Operations), j.LastID)
+	}
+	if _, ok := j.Get(1); ok {
+		t.Error("Get(1) found a dropped operation")
+	}
+
+	last, ok := j.Last()
+	if !ok || last.ID != journalLimit+2 {
+		t.Fatalf("Last = %+v, %v", last, ok)
+	}
+	last.Undone = true
+	if last, ok := j.Last(); !ok || last.ID != journalLimit+1 {
+		t.Errorf("Last after undo = %+v, %v", last, ok)
+	}
+}
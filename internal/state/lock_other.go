@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package state
+
+import "os"
+
+// Platforms without file locking fall back to atomic writes only
+
+func tryLock(f *os.File) error { return nil }
+
+func unlock(f *os.File) error { return nil }
@@ -0,0 +1,162 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const metaFile = "worktrees.json"
+
+// Meta is user-supplied metadata attached to a worktree
+type Meta struct {
+	Note    string    `json:"note,omitempty"`
+	Tags    []string  `json:"tags,omitempty"`
+	Issue   string    `json:"issue,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// IsEmpty returns true if no metadata is set
+func (m Meta) IsEmpty() bool {
+	return m.Note == "" && len(m.Tags) == 0 && m.Issue == ""
+}
+
+// HasTag returns true if the worktree is tagged with tag
+func (m Meta) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTags adds tags, keeping the list sorted and free of duplicates
+func (m *Meta) AddTags(tags ...string) {
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !m.HasTag(tag) {
+			m.Tags = append(m.Tags, tag)
+		}
+	}
+	sort.Strings(m.Tags)
+}
+
+// RemoveTags removes tags
+func (m *Meta) RemoveTags(tags ...string) {
+	kept := m.Tags[:0]
+	for _, t := range m.Tags {
+		remove := false
+		for _, tag := range tags {
+			if t == tag {
+				remove = true
+				break
+			}
+		}
+		if !remove {
+			kept = append(kept, t)
+		}
+	}
+	m.Tags = kept
+}
+
+// Summary returns a one-line description of the metadata for listings
+func (m Meta) Summary() string {
+	var parts []string
+	for _, tag := range m.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	if m.Note != "" {
+		parts = append(parts, m.Note)
+	}
+	return strings.Join(parts, " ")
+}
+
+// MetaStore holds the metadata of all worktrees of a repository, keyed by
+// worktree path
+type MetaStore struct {
+	Worktrees map[string]Meta `json:"worktrees"`
+
+	path string
+}
+
+// LoadMeta reads the metadata store from dir, returning an empty store if
+// it does not exist yet
+func LoadMeta(dir string) (*MetaStore, error) {
+	s := &MetaStore{Worktrees: map[string]Meta{}, path: filepath.Join(dir, metaFile)}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worktree metadata: %w", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse worktree metadata: %w", err)
+	}
+	if s.Worktrees == nil {
+		s.Worktrees = map[string]Meta{}
+	}
+
+	return s, nil
+}
+
+// UpdateMeta loads the metadata store from dir, applies fn and saves it
+// while holding the store lock. Nothing is saved if fn returns an error.
+func UpdateMeta(dir string, fn func(*MetaStore) error) (*MetaStore, error) {
+	lock, err := LockFile(filepath.Join(dir, metaFile))
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	s, err := LoadMeta(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(s); err != nil {
+		return nil, err
+	}
+	if err := s.Save(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save atomically writes the store back to the file it was loaded from.
+// Use UpdateMeta for read-modify-write cycles.
+func (s *MetaStore) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal worktree metadata: %w", err)
+	}
+
+	return WriteFileAtomic(s.path, data, 0o644)
+}
+
+// Get returns the metadata for the worktree at path
+func (s *MetaStore) Get(path string) Meta {
+	return s.Worktrees[path]
+}
+
+// Set stores the metadata for the worktree at path, removing the entry
+// when it is empty
+func (s *MetaStore) Set(path string, m Meta, now time.Time) {
+	if m.IsEmpty() {
+		delete(s.Worktrees, path)
+		return
+	}
+	m.Updated = now
+	s.Worktrees[path] = m
+}
+
+// Delete removes the metadata for the worktree at path
+func (s *MetaStore) Delete(path string) {
+	delete(s.Worktrees, path)
+}
@@ -0,0 +1,65 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetaTags(t *testing.T) {
+	var m Meta
+	m.AddTags("wip", " review ", "wip", "")
+	if want := []string{"review", "wip"}; !reflect.DeepEqual(m.Tags, want) {
+		t.Fatalf("Tags = %v, want %v", m.Tags, want)
+	}
+	if !m.HasTag("wip") || m.HasTag("other") {
+		t.Errorf("HasTag gave wrong results for %v", m.Tags)
+	}
+
+	m.Note = "waiting on API"
+	if got, want := m.Summary(), "#review #wip waiting on API"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+
+	m.RemoveTags("wip", "missing")
+	if want := []string{"review"}; !reflect.DeepEqual(m.Tags, want) {
+		t.Errorf("Tags after remove = %v, want %v", m.Tags, want)
+	}
+}
+
+func TestMetaStorePersist(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	s, err := LoadMeta(dir)
+	if err != nil {
+		t.Fatalf("LoadMeta failed: %v", err)
+	}
+	if len(s.Worktrees) != 0 {
+		t.Fatalf("expected empty store, got %d entries", len(s.Worktrees))
+	}
+
+	s.Set("/repo/.worktrees/a", Meta{Note: "auth", Tags: []string{"wip"}, Issue: "https://example.com/1"}, now)
+	s.Set("/repo/.worktrees/b", Meta{Note: "temp"}, now)
+	s.Delete("/repo/.worktrees/b")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadMeta(dir)
+	if err != nil {
+		t.Fatalf("LoadMeta failed: %v", err)
+	}
+	a := loaded.Get("/repo/.worktrees/a")
+	if a.Note != "auth" || a.Issue != "https://example.com/1" || !a.HasTag("wip") {
+		t.Errorf("entry a = %+v", a)
+	}
+	if !loaded.Get("/repo/.worktrees/b").IsEmpty() {
+		t.Error("deleted entry b still present")
+	}
+
+	loaded.Set("/repo/.worktrees/a", Meta{}, now)
+	if _, ok := loaded.Worktrees["/repo/.worktrees/a"]; ok {
+		t.Error("setting empty metadata should remove the entry")
+	}
+}
@@ -0,0 +1,116 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const portsFile = "ports.json"
+
+// PortMap holds the port block assigned to each worktree of a repository,
+// keyed by worktree path. Blocks are numbered from 0; block n of size s
+// starting at base covers base+n*s to base+n*s+s-1.
+type PortMap struct {
+	Worktrees map[string]int `json:"worktrees"`
+
+	path string
+}
+
+// LoadPorts reads the port map from dir, returning an empty map if it does
+// not exist yet
+func LoadPorts(dir string) (*PortMap, error) {
+	m := &PortMap{Worktrees: map[string]int{}, path: filepath.Join(dir, portsFile)}
+
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read port map: %w", err)
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse port map: %w", err)
+	}
+	if m.Worktrees == nil {
+		m.Worktrees = map[string]int{}
+	}
+
+	return m, nil
+}
+
+// AllocatePorts returns the port block of the worktree at path, assigning
+// it the lowest free block if it has none yet. Blocks of worktrees that no
+// longer exist are freed first.
+func AllocatePorts(dir, path string) (int, error) {
+	if m, err := LoadPorts(dir); err == nil {
+		if block, ok := m.Worktrees[path]; ok {
+			return block, nil
+		}
+	}
+
+	lock, err := LockFile(filepath.Join(dir, portsFile))
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Unlock()
+
+	m, err := LoadPorts(dir)
+	if err != nil {
+		return 0, err
+	}
+	if block, ok := m.Worktrees[path]; ok {
+		return block, nil
+	}
+	for p := range m.Worktrees {
+		if !DirExists(p) {
+			delete(m.Worktrees, p)
+		}
+	}
+	block := m.Free()
+	m.Worktrees[path] = block
+
+	return block, m.save()
+}
+
+// ReleasePorts frees the port block of the worktree at path
+func ReleasePorts(dir, path string) error {
+	lock, err := LockFile(filepath.Join(dir, portsFile))
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	m, err := LoadPorts(dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.Worktrees[path]; !ok {
+		return nil
+	}
+	delete(m.Worktrees, path)
+	return m.save()
+}
+
+// Free returns the lowest block not assigned to any worktree
+func (m *PortMap) Free() int {
+	used := make(map[int]bool, len(m.Worktrees))
+	for _, block := range m.Worktrees {
+		used[block] = true
+	}
+	block := 0
+	for used[block] {
+		block++
+	}
+	return block
+}
+
+func (m *PortMap) save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal port map: %w", err)
+	}
+	return WriteFileAtomic(m.path, data, 0o644)
+}
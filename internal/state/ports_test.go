@@ -0,0 +1,39 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAllocatePorts(t *testing.T) {
+	dir := t.TempDir()
+	one, two, three := t.TempDir(), t.TempDir(), t.TempDir()
+
+	for i, path := range []string{one, two, one} {
+		block, err := AllocatePorts(dir, path)
+		if err != nil {
+			t.Fatalf("AllocatePorts failed: %v", err)
+		}
+		if want := []int{0, 1, 0}[i]; block != want {
+			t.Errorf("allocation %d = %d, want %d", i, block, want)
+		}
+	}
+
+	// A released block is reused, and so is the block of a deleted worktree
+	if err := ReleasePorts(dir, one); err != nil {
+		t.Fatalf("ReleasePorts failed: %v", err)
+	}
+	if block, _ := AllocatePorts(dir, three); block != 0 {
+		t.Errorf("block after release = %d, want 0", block)
+	}
+	os.RemoveAll(two)
+	if block, _ := AllocatePorts(dir, filepath.Join(three, "other")); block != 1 {
+		t.Errorf("block after removing a worktree = %d, want 1", block)
+	}
+
+	m, err := LoadPorts(dir)
+	if err != nil || len(m.Worktrees) != 2 {
+		t.Errorf("LoadPorts = %+v, %v", m, err)
+	}
+}
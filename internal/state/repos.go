@@ -0,0 +1,108 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const reposFile = "repos.json"
+
+// KnownRepo is a repository lazywork was used in
+type KnownRepo struct {
+	// CommonDir is the repository's git common directory
+	CommonDir string    `json:"common_dir"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+// RepoList is the repositories lazywork was used in, kept in the user
+// state directory so commands can look across them
+type RepoList struct {
+	Repos []KnownRepo `json:"repos"`
+
+	path string
+}
+
+// LoadRepos reads the repository list from dir, returning an empty list
+// if it does not exist yet
+func LoadRepos(dir string) (*RepoList, error) {
+	l := &RepoList{path: filepath.Join(dir, reposFile)}
+
+	data, err := os.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository list: %w", err)
+	}
+
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse repository list: %w", err)
+	}
+
+	return l, nil
+}
+
+// RecordRepo marks the repository with the given common dir as used at
+// now. The list is only written once a day per repository.
+func RecordRepo(dir, commonDir string, now time.Time) error {
+	if l, err := LoadRepos(dir); err == nil {
+		for _, r := range l.Repos {
+			if r.CommonDir == commonDir && sameDay(r.LastUsed, now) {
+				return nil
+			}
+		}
+	}
+
+	lock, err := LockFile(filepath.Join(dir, reposFile))
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	l, err := LoadRepos(dir)
+	if err != nil {
+		return err
+	}
+	l.Add(commonDir, now)
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal repository list: %w", err)
+	}
+	return WriteFileAtomic(l.path, data, 0o644)
+}
+
+// Add marks the repository as used at now, most recently used first
+func (l *RepoList) Add(commonDir string, now time.Time) {
+	for i, r := range l.Repos {
+		if r.CommonDir == commonDir {
+			l.Repos = append(l.Repos[:i], l.Repos[i+1:]...)
+			break
+		}
+	}
+	l.Repos = append(l.Repos, KnownRepo{CommonDir: commonDir, LastUsed: now})
+	sort.SliceStable(l.Repos, func(i, j int) bool {
+		return l.Repos[i].LastUsed.After(l.Repos[j].LastUsed)
+	})
+}
+
+// Existing returns the repositories whose common dir still exists
+func (l *RepoList) Existing() []KnownRepo {
+	var repos []KnownRepo
+	for _, r := range l.Repos {
+		if DirExists(r.CommonDir) {
+			repos = append(repos, r)
+		}
+	}
+	return repos
+}
+
+func sameDay(a, b time.Time) bool {
+	ya, ma, da := a.Local().Date()
+	yb, mb, db := b.Local().Date()
+	return ya == yb && ma == mb && da == db
+}
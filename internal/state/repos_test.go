@@ -0,0 +1,44 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordRepo(t *testing.T) {
+	dir := t.TempDir()
+	one := filepath.Join(t.TempDir(), ".git")
+	two := filepath.Join(t.TempDir(), ".git")
+	os.Mkdir(one, 0o755)
+	os.Mkdir(two, 0o755)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+
+	for _, r := range []struct {
+		path string
+		at   time.Time
+	}{{one, now.AddDate(0, 0, -1)}, {two, now.Add(-time.Hour)}, {one, now}} {
+		if err := RecordRepo(dir, r.path, r.at); err != nil {
+			t.Fatalf("RecordRepo failed: %v", err)
+		}
+	}
+
+	// Same day: not written again
+	if err := RecordRepo(dir, one, now.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordRepo failed: %v", err)
+	}
+
+	l, err := LoadRepos(dir)
+	if err != nil {
+		t.Fatalf("LoadRepos failed: %v", err)
+	}
+	if len(l.Repos) != 2 || l.Repos[0].CommonDir != one || !l.Repos[0].LastUsed.Equal(now) {
+		t.Errorf("Repos = %+v", l.Repos)
+	}
+
+	os.RemoveAll(two)
+	if repos := l.Existing(); len(repos) != 1 || repos[0].CommonDir != one {
+		t.Errorf("Existing = %+v", repos)
+	}
+}
@@ -0,0 +1,98 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const ticketsFile = "tickets.json"
+
+// TicketTTL is how long a cached ticket title is shown before it is
+// fetched again
+const TicketTTL = time.Hour
+
+// CachedTicket is a ticket looked up in an issue tracker
+type CachedTicket struct {
+	Title   string    `json:"title"`
+	URL     string    `json:"url"`
+	State   string    `json:"state,omitempty"`
+	Tracker string    `json:"tracker"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// TicketCache holds the tickets named in branch names, keyed by ticket key
+// such as ENG-123, so listings don't query the tracker every time
+type TicketCache struct {
+	Tickets map[string]CachedTicket `json:"tickets"`
+
+	path string
+}
+
+// LoadTickets reads the ticket cache from dir, returning an empty cache if
+// it does not exist yet
+func LoadTickets(dir string) (*TicketCache, error) {
+	c := &TicketCache{Tickets: map[string]CachedTicket{}, path: filepath.Join(dir, ticketsFile)}
+
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ticket cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse ticket cache: %w", err)
+	}
+	if c.Tickets == nil {
+		c.Tickets = map[string]CachedTicket{}
+	}
+
+	return c, nil
+}
+
+// UpdateTickets loads the ticket cache from dir, applies fn and saves it
+// while holding the cache lock
+func UpdateTickets(dir string, fn func(*TicketCache)) error {
+	lock, err := LockFile(filepath.Join(dir, ticketsFile))
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	c, err := LoadTickets(dir)
+	if err != nil {
+		return err
+	}
+	fn(c)
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal ticket cache: %w", err)
+	}
+	return WriteFileAtomic(c.path, data, 0o644)
+}
+
+// Get returns the cached ticket for key if it was fetched within TicketTTL
+// of now
+func (c *TicketCache) Get(key string, now time.Time) (CachedTicket, bool) {
+	t, ok := c.Tickets[key]
+	if !ok || now.Sub(t.Fetched) > TicketTTL {
+		return CachedTicket{}, false
+	}
+	return t, true
+}
+
+// Set caches the ticket for key, dropping entries that have expired
+func (c *TicketCache) Set(key string, t CachedTicket, now time.Time) {
+	for k, cached := range c.Tickets {
+		if now.Sub(cached.Fetched) > TicketTTL {
+			delete(c.Tickets, k)
+		}
+	}
+	t.Fetched = now
+	c.Tickets[key] = t
+}
@@ -0,0 +1,33 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTicketCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	err := UpdateTickets(dir, func(c *TicketCache) {
+		c.Set("OLD-1", CachedTicket{Title: "stale"}, now.Add(-2*TicketTTL))
+		c.Set("ENG-1", CachedTicket{Title: "Fix login", Tracker: "linear"}, now)
+	})
+	if err != nil {
+		t.Fatalf("UpdateTickets failed: %v", err)
+	}
+
+	c, err := LoadTickets(dir)
+	if err != nil {
+		t.Fatalf("LoadTickets failed: %v", err)
+	}
+	if got, ok := c.Get("ENG-1", now); !ok || got.Title != "Fix login" {
+		t.Errorf("Get(ENG-1) = %+v, %v", got, ok)
+	}
+	if _, ok := c.Get("ENG-1", now.Add(2*TicketTTL)); ok {
+		t.Error("Get returned an expired ticket")
+	}
+	if _, ok := c.Tickets["OLD-1"]; ok {
+		t.Error("Set kept an expired entry")
+	}
+}
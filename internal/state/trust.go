@@ -0,0 +1,73 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const trustFile = "trusted_hooks.json"
+
+// HookTrust records the repository hooks the user allowed to run: the
+// hash of each repository's hooks, by its git common directory. Kept in
+// the user state directory, so a repository can't trust itself.
+type HookTrust struct {
+	Repos map[string]string `json:"repos"`
+
+	path string
+}
+
+// LoadHookTrust reads the trusted hooks from dir, returning an empty
+// record if it does not exist yet
+func LoadHookTrust(dir string) (*HookTrust, error) {
+	t := &HookTrust{Repos: map[string]string{}, path: filepath.Join(dir, trustFile)}
+
+	data, err := os.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted hooks: %w", err)
+	}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("failed to parse trusted hooks: %w", err)
+	}
+	if t.Repos == nil {
+		t.Repos = map[string]string{}
+	}
+	return t, nil
+}
+
+// HooksTrusted returns true if the user trusted the hooks with the given
+// hash in the repository with the given common dir
+func HooksTrusted(dir, commonDir, hash string) bool {
+	t, err := LoadHookTrust(dir)
+	return err == nil && hash != "" && t.Repos[commonDir] == hash
+}
+
+// TrustHooks records that the hooks with the given hash may run in the
+// repository with the given common dir; an empty hash revokes the trust
+func TrustHooks(dir, commonDir, hash string) error {
+	lock, err := LockFile(filepath.Join(dir, trustFile))
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	t, err := LoadHookTrust(dir)
+	if err != nil {
+		return err
+	}
+	if hash == "" {
+		delete(t.Repos, commonDir)
+	} else {
+		t.Repos[commonDir] = hash
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trusted hooks: %w", err)
+	}
+	return WriteFileAtomic(t.path, data, 0o600)
+}
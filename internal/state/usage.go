@@ -0,0 +1,140 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/paths"
+)
+
+const usageFile = "usage.json"
+
+// usageRetention is how long daily usage records are kept
+const usageRetention = 400 * 24 * time.Hour
+
+// dateLayout formats the local calendar day of a usage record
+const dateLayout = "2006-01-02"
+
+// UserDir returns the per-user state directory, for state that is not
+// tied to one repository: $XDG_STATE_HOME/lazywork, or
+// ~/.local/state/lazywork (%LOCALAPPDATA%\lazywork on Windows)
+func UserDir() string {
+	home, _ := os.UserHomeDir()
+	return paths.StateDir(runtime.GOOS, os.Getenv, home)
+}
+
+// UsageRecord totals the AI requests made on one day with one provider,
+// model and command
+type UsageRecord struct {
+	Date             string `json:"date"`
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+	Command          string `json:"command"`
+	Requests         int    `json:"requests"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+}
+
+// Day returns the record's date as local midnight
+func (r UsageRecord) Day() time.Time {
+	t, _ := time.ParseInLocation(dateLayout, r.Date, time.Local)
+	return t
+}
+
+// UsageLog is the token usage of AI requests, aggregated per day
+type UsageLog struct {
+	Records []UsageRecord `json:"records"`
+
+	path string
+}
+
+// LoadUsage reads the usage log from dir, returning an empty log if it
+// does not exist yet
+func LoadUsage(dir string) (*UsageLog, error) {
+	u := &UsageLog{path: filepath.Join(dir, usageFile)}
+
+	data, err := os.ReadFile(u.path)
+	if os.IsNotExist(err) {
+		return u, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage log: %w", err)
+	}
+
+	if err := json.Unmarshal(data, u); err != nil {
+		return nil, fmt.Errorf("failed to parse usage log: %w", err)
+	}
+
+	return u, nil
+}
+
+// UpdateUsage loads the usage log from dir, applies fn and saves it while
+// holding the log's lock
+func UpdateUsage(dir string, fn func(*UsageLog)) error {
+	lock, err := LockFile(filepath.Join(dir, usageFile))
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	u, err := LoadUsage(dir)
+	if err != nil {
+		return err
+	}
+	fn(u)
+
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage log: %w", err)
+	}
+	return WriteFileAtomic(u.path, data, 0o644)
+}
+
+// Add counts one request made at now, dropping records older than the
+// retention period
+func (u *UsageLog) Add(now time.Time, provider, model, command string, promptTokens, completionTokens int) {
+	cutoff := now.Add(-usageRetention).Format(dateLayout)
+	kept := u.Records[:0]
+	for _, r := range u.Records {
+		if r.Date >= cutoff {
+			kept = append(kept, r)
+		}
+	}
+	u.Records = kept
+
+	date := now.Format(dateLayout)
+	for i := range u.Records {
+		r := &u.Records[i]
+		if r.Date == date && r.Provider == provider && r.Model == model && r.Command == command {
+			r.Requests++
+			r.PromptTokens += promptTokens
+			r.CompletionTokens += completionTokens
+			return
+		}
+	}
+	u.Records = append(u.Records, UsageRecord{
+		Date:             date,
+		Provider:         provider,
+		Model:            model,
+		Command:          command,
+		Requests:         1,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	})
+}
+
+// Since returns the records for days on or after the local day of t
+func (u *UsageLog) Since(t time.Time) []UsageRecord {
+	from := t.Format(dateLayout)
+	var records []UsageRecord
+	for _, r := range u.Records {
+		if r.Date >= from {
+			records = append(records, r)
+		}
+	}
+	return records
+}
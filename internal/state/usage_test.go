@@ -0,0 +1,41 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsageLog(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+
+	err := UpdateUsage(dir, func(u *UsageLog) {
+		u.Add(now.AddDate(-2, 0, 0), "anthropic", "m", "commit", 1, 1)
+		u.Add(now.AddDate(0, 0, -1), "anthropic", "m", "commit", 100, 10)
+		u.Add(now, "anthropic", "m", "commit", 200, 20)
+		u.Add(now, "anthropic", "m", "commit", 300, 30)
+		u.Add(now, "openai", "m", "resolve", 5, 5)
+	})
+	if err != nil {
+		t.Fatalf("UpdateUsage failed: %v", err)
+	}
+
+	u, err := LoadUsage(dir)
+	if err != nil {
+		t.Fatalf("LoadUsage failed: %v", err)
+	}
+	if len(u.Records) != 3 {
+		t.Fatalf("got %d records, want 3 (old one pruned, same day merged): %+v", len(u.Records), u.Records)
+	}
+
+	today := u.Since(now)
+	if len(today) != 2 {
+		t.Fatalf("Since(today) = %+v", today)
+	}
+	if r := today[0]; r.Requests != 2 || r.PromptTokens != 500 || r.CompletionTokens != 50 {
+		t.Errorf("merged record = %+v", r)
+	}
+	if !today[0].Day().Equal(time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Day() = %s", today[0].Day())
+	}
+}
@@ -0,0 +1,43 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const workspaceFile = "workspace.json"
+
+// Workspace records the editor workspace file generated for a repository,
+// so it can be refreshed as worktrees are added and removed
+type Workspace struct {
+	Path string `json:"path"`
+}
+
+// LoadWorkspace reads the workspace record from dir, returning nil if no
+// workspace file was generated
+func LoadWorkspace(dir string) (*Workspace, error) {
+	data, err := os.ReadFile(filepath.Join(dir, workspaceFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace record: %w", err)
+	}
+
+	var w Workspace
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace record: %w", err)
+	}
+	return &w, nil
+}
+
+// SaveWorkspace records the workspace file generated for the repository
+func SaveWorkspace(dir string, w Workspace) error {
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal workspace record: %w", err)
+	}
+	return WriteFileAtomic(filepath.Join(dir, workspaceFile), data, 0o644)
+}
@@ -0,0 +1,56 @@
+package tickets
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strings"
+)
+
+// Jira is the Jira REST API of a Jira Cloud or Data Center site
+type Jira struct {
+	site  string
+	email string
+	token string
+}
+
+// NewJira returns a client for the Jira site, e.g.
+// https://acme.atlassian.net. With an email the token is an Atlassian API
+// token sent with basic auth (Cloud); without one it is a personal access
+// token sent as a bearer token (Data Center).
+func NewJira(site, email, token string) *Jira {
+	return &Jira{site: strings.TrimSuffix(site, "/"), email: email, token: token}
+}
+
+func (j *Jira) Name() string {
+	return "jira"
+}
+
+func (j *Jira) Ticket(ctx context.Context, key string) (*Ticket, error) {
+	auth := "Bearer " + j.token
+	if j.email != "" {
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(j.email+":"+j.token))
+	}
+
+	var result struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+			Status  struct {
+				Name string `json:"name"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	endpoint := j.site + "/rest/api/2/issue/" + url.PathEscape(key) + "?fields=summary,status"
+	if err := doJSON(ctx, "GET", endpoint, map[string]string{"Authorization": auth}, nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &Ticket{
+		Key:     result.Key,
+		Title:   result.Fields.Summary,
+		URL:     j.site + "/browse/" + result.Key,
+		State:   result.Fields.Status.Name,
+		Tracker: j.Name(),
+	}, nil
+}
@@ -0,0 +1,36 @@
+package tickets
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJiraTicket(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "me@acme.com" || pass != "token" {
+			t.Errorf("basic auth = %q, %q, %v", user, pass, ok)
+		}
+		if r.URL.Path != "/rest/api/2/issue/PROJ-456" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"key": "PROJ-456", "fields": {"summary": "Export to CSV", "status": {"name": "To Do"}}}`))
+	}))
+	defer srv.Close()
+
+	j := NewJira(srv.URL+"/", "me@acme.com", "token")
+	ticket, err := j.Ticket(t.Context(), "PROJ-456")
+	if err != nil {
+		t.Fatalf("Ticket failed: %v", err)
+	}
+	want := Ticket{Key: "PROJ-456", Title: "Export to CSV", URL: srv.URL + "/browse/PROJ-456", State: "To Do", Tracker: "jira"}
+	if *ticket != want {
+		t.Errorf("Ticket = %+v, want %+v", *ticket, want)
+	}
+
+	if _, err := j.Ticket(t.Context(), "PROJ-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Ticket(PROJ-1) = %v, want ErrNotFound", err)
+	}
+}
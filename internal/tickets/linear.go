@@ -0,0 +1,74 @@
+package tickets
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Linear is the Linear GraphQL API
+type Linear struct {
+	endpoint string
+	token    string
+}
+
+// NewLinear returns a Linear client. An empty endpoint uses
+// https://api.linear.app/graphql.
+func NewLinear(endpoint, token string) *Linear {
+	if endpoint == "" {
+		endpoint = "https://api.linear.app/graphql"
+	}
+	return &Linear{endpoint: endpoint, token: token}
+}
+
+func (l *Linear) Name() string {
+	return "linear"
+}
+
+const linearIssueQuery = `query Issue($id: String!) {
+  issue(id: $id) { identifier title url state { name } }
+}`
+
+func (l *Linear) Ticket(ctx context.Context, key string) (*Ticket, error) {
+	payload := map[string]interface{}{
+		"query":     linearIssueQuery,
+		"variables": map[string]string{"id": key},
+	}
+
+	var result struct {
+		Data struct {
+			Issue *struct {
+				Identifier string `json:"identifier"`
+				Title      string `json:"title"`
+				URL        string `json:"url"`
+				State      struct {
+					Name string `json:"name"`
+				} `json:"state"`
+			} `json:"issue"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	// Personal API keys are sent without an auth scheme
+	headers := map[string]string{"Authorization": l.token}
+	if err := doJSON(ctx, "POST", l.endpoint, headers, payload, &result); err != nil {
+		return nil, err
+	}
+
+	issue := result.Data.Issue
+	if issue == nil {
+		if len(result.Errors) > 0 && !strings.Contains(strings.ToLower(result.Errors[0].Message), "not found") {
+			return nil, errors.New(result.Errors[0].Message)
+		}
+		return nil, ErrNotFound
+	}
+
+	return &Ticket{
+		Key:     issue.Identifier,
+		Title:   issue.Title,
+		URL:     issue.URL,
+		State:   issue.State.Name,
+		Tracker: l.Name(),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package tickets
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLinearTicket(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "lin_api_key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var body struct {
+			Variables map[string]string `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Variables["id"] == "ENG-404" {
+			w.Write([]byte(`{"data": {"issue": null}, "errors": [{"message": "Entity not found"}]}`))
+			return
+		}
+		w.Write([]byte(`{"data": {"issue": {"identifier": "ENG-123", "title": "Fix login", "url": "https://linear.app/acme/issue/ENG-123", "state": {"name": "In Progress"}}}}`))
+	}))
+	defer srv.Close()
+
+	l := NewLinear(srv.URL, "lin_api_key")
+	ticket, err := l.Ticket(t.Context(), "ENG-123")
+	if err != nil {
+		t.Fatalf("Ticket failed: %v", err)
+	}
+	want := Ticket{Key: "ENG-123", Title: "Fix login", URL: "https://linear.app/acme/issue/ENG-123", State: "In Progress", Tracker: "linear"}
+	if *ticket != want {
+		t.Errorf("Ticket = %+v, want %+v", *ticket, want)
+	}
+
+	if _, err := l.Ticket(t.Context(), "ENG-404"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Ticket(ENG-404) = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Package tickets looks up the issue tracker tickets (Linear, Jira) named
+// in branch names, such as ENG-123 in "eng-123-fix-login".
+package tickets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+)
+
+// Ticket is an issue in a tracker
+type Ticket struct {
+	Key     string `json:"key"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	State   string `json:"state,omitempty"`
+	Tracker string `json:"tracker"`
+}
+
+// Tracker is an issue tracker
+type Tracker interface {
+	// Name is the tracker type, e.g. "linear"
+	Name() string
+	// Ticket returns the ticket with the given key, e.g. ENG-123
+	Ticket(ctx context.Context, key string) (*Ticket, error)
+}
+
+// ErrNotFound is returned when a ticket does not exist
+var ErrNotFound = errors.New("ticket not found")
+
+// Trackers routes ticket keys to the configured trackers
+type Trackers struct {
+	entries []entry
+}
+
+type entry struct {
+	tracker  Tracker
+	projects []string
+}
+
+// New returns the trackers configured in cfg. Trackers without a token
+// are skipped.
+func New(cfg *config.Config) *Trackers {
+	t := &Trackers{}
+	names := make([]string, 0, len(cfg.Tickets))
+	for name := range cfg.Tickets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		settings := cfg.Tickets[name]
+		var tracker Tracker
+		switch name {
+		case "linear":
+			if token := tokenOr(settings.Token, "LINEAR_API_KEY"); token != "" {
+				tracker = NewLinear(settings.BaseURL, token)
+			}
+		case "jira":
+			if token := tokenOr(settings.Token, "JIRA_API_TOKEN"); token != "" && settings.BaseURL != "" {
+				tracker = NewJira(settings.BaseURL, settings.Email, token)
+			}
+		}
+		if tracker != nil {
+			t.Add(tracker, settings.Projects...)
+		}
+	}
+	return t
+}
+
+func tokenOr(configured, envName string) string {
+	if configured != "" {
+		return configured
+	}
+	return os.Getenv(envName)
+}
+
+// Add registers tracker for keys with the given project prefixes, or for
+// any key if none are given
+func (t *Trackers) Add(tracker Tracker, projects ...string) {
+	upper := make([]string, len(projects))
+	for i, p := range projects {
+		upper[i] = strings.ToUpper(p)
+	}
+	t.entries = append(t.entries, entry{tracker: tracker, projects: upper})
+}
+
+// Empty reports whether no tracker is configured
+func (t *Trackers) Empty() bool {
+	return len(t.entries) == 0
+}
+
+var keyPattern = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])([a-z][a-z0-9]{1,9}-[0-9]+)`)
+
+// Find returns the first ticket key in branch that a tracker handles,
+// uppercased, along with that tracker. Keys are matched in any case since
+// trackers such as Linear suggest lowercase branch names.
+func (t *Trackers) Find(branch string) (string, Tracker) {
+	for _, m := range keyPattern.FindAllStringSubmatch(branch, -1) {
+		key := strings.ToUpper(m[1])
+		project := key[:strings.LastIndex(key, "-")]
+		for _, e := range t.entries {
+			if len(e.projects) == 0 || contains(e.projects, project) {
+				return key, e.tracker
+			}
+		}
+	}
+	return "", nil
+}
+
+// Lookup returns the ticket named in branch, or nil if branch has no key
+// a tracker handles
+func (t *Trackers) Lookup(ctx context.Context, branch string) (*Ticket, error) {
+	key, tracker := t.Find(branch)
+	if tracker == nil {
+		return nil, nil
+	}
+	ticket, err := tracker.Ticket(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", tracker.Name(), key, err)
+	}
+	return ticket, nil
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
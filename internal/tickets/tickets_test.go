@@ -0,0 +1,78 @@
+package tickets
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeTracker struct {
+	name    string
+	tickets map[string]Ticket
+}
+
+func (f *fakeTracker) Name() string { return f.name }
+
+func (f *fakeTracker) Ticket(ctx context.Context, key string) (*Ticket, error) {
+	t, ok := f.tickets[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
+
+func TestTrackersFind(t *testing.T) {
+	linear := &fakeTracker{name: "linear"}
+	jira := &fakeTracker{name: "jira"}
+
+	trackers := &Trackers{}
+	trackers.Add(linear, "eng")
+	trackers.Add(jira, "PROJ", "OPS")
+
+	tests := []struct {
+		branch  string
+		key     string
+		tracker Tracker
+	}{
+		{"eng-123-fix-login", "ENG-123", linear},
+		{"alice/ENG-7-oauth", "ENG-7", linear},
+		{"feature-2/PROJ-456", "PROJ-456", jira},
+		{"ops-9", "OPS-9", jira},
+		{"feature-2", "", nil},
+		{"engine-12", "", nil},
+		{"main", "", nil},
+	}
+	for _, tt := range tests {
+		key, tracker := trackers.Find(tt.branch)
+		if key != tt.key || tracker != tt.tracker {
+			t.Errorf("Find(%q) = %q, %v, want %q, %v", tt.branch, key, tracker, tt.key, tt.tracker)
+		}
+	}
+
+	// A tracker without projects handles any key
+	catchAll := &Trackers{}
+	catchAll.Add(linear)
+	if key, _ := catchAll.Find("feature-2"); key != "FEATURE-2" {
+		t.Errorf("Find without projects = %q, want FEATURE-2", key)
+	}
+}
+
+func TestTrackersLookup(t *testing.T) {
+	trackers := &Trackers{}
+	trackers.Add(&fakeTracker{name: "linear", tickets: map[string]Ticket{
+		"ENG-1": {Key: "ENG-1", Title: "Fix login"},
+	}}, "ENG")
+
+	ticket, err := trackers.Lookup(t.Context(), "eng-1-fix-login")
+	if err != nil || ticket == nil || ticket.Title != "Fix login" {
+		t.Fatalf("Lookup = %+v, %v", ticket, err)
+	}
+
+	ticket, err = trackers.Lookup(t.Context(), "no-ticket")
+	if err != nil || ticket != nil {
+		t.Errorf("Lookup without a key = %+v, %v, want nil", ticket, err)
+	}
+
+	if _, err := trackers.Lookup(t.Context(), "eng-2"); err == nil {
+		t.Error("Lookup of a missing ticket succeeded")
+	}
+}
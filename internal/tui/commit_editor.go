@@ -0,0 +1,205 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+)
+
+var conventionalSubject = regexp.MustCompile(`^(feat|fix|docs|style|refactor|perf|test|build|ci|chore|revert)(\([\w\-./]+\))?!?: \S`)
+
+var (
+	editorLabelStyle = lipgloss.NewStyle().Bold(true)
+	editorDimStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	editorWarnStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	editorOkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+)
+
+// SplitMessage splits a commit message into its subject line and body
+func SplitMessage(message string) (subject, body string) {
+	message = strings.TrimSpace(message)
+	subject, body, _ = strings.Cut(message, "\n")
+	return strings.TrimSpace(subject), strings.TrimSpace(body)
+}
+
+// JoinMessage builds a commit message from a subject and optional body
+func JoinMessage(subject, body string) string {
+	subject = strings.TrimSpace(subject)
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return subject
+	}
+	return subject + "\n\n" + body
+}
+
+// ValidateSubject returns the problems found in a commit subject line
+// written in style
+func ValidateSubject(subject string, style commitmsg.Style) []string {
+	var problems []string
+
+	if strings.TrimSpace(subject) == "" {
+		return []string{"subject cannot be empty"}
+	}
+	if n, limit := len([]rune(subject)), style.SubjectLimit(); n > limit {
+		problems = append(problems, fmt.Sprintf("subject is %d characters (max %d)", n, limit))
+	}
+	if strings.HasSuffix(subject, ".") {
+		problems = append(problems, "subject should not end with a period")
+	}
+	if style.Emoji {
+		_, rest, ok := commitmsg.SplitGitmoji(subject)
+		if !ok {
+			problems = append(problems, "subject does not start with a gitmoji")
+		}
+		subject = rest
+	}
+	if style.Conventional && !conventionalSubject.MatchString(subject) {
+		problems = append(problems, "subject does not follow Conventional Commits (type(scope): description)")
+	}
+
+	return problems
+}
+
+// ValidateMessage returns the problems found in a whole commit message:
+// those of its subject line and the commitlint rules of style it breaks
+func ValidateMessage(message string, style commitmsg.Style) []string {
+	subject, _ := SplitMessage(message)
+	problems := ValidateSubject(subject, style)
+	for _, p := range style.Lint.Check(message, style.Emoji) {
+		problems = append(problems, p.String())
+	}
+	return problems
+}
+
+// CommitEditor is a Bubble Tea model for reviewing and editing a commit message
+type CommitEditor struct {
+	subject   textinput.Model
+	body      textarea.Model
+	style     commitmsg.Style
+	accepted  bool
+	cancelled bool
+}
+
+func NewCommitEditor(message string, style commitmsg.Style) *CommitEditor {
+	subjectText, bodyText := SplitMessage(message)
+
+	subject := textinput.New()
+	subject.Placeholder = "feat: describe the change"
+	subject.Prompt = ""
+	subject.CharLimit = 0
+	subject.SetValue(subjectText)
+	subject.Focus()
+
+	body := textarea.New()
+	body.Placeholder = "Explain what and why (optional)"
+	body.ShowLineNumbers = false
+	body.CharLimit = 0
+	body.SetWidth(commitmsg.DefaultMaxSubject + 2)
+	body.SetHeight(8)
+	body.SetValue(bodyText)
+	body.Blur()
+
+	return &CommitEditor{
+		subject: subject,
+		body:    body,
+		style:   style,
+	}
+}
+
+func (e *CommitEditor) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (e *CommitEditor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "ctrl+c", "esc":
+			e.cancelled = true
+			return e, tea.Quit
+		case "ctrl+s":
+			if strings.TrimSpace(e.subject.Value()) != "" {
+				e.accepted = true
+				return e, tea.Quit
+			}
+		case "tab", "shift+tab":
+			return e, e.toggleFocus()
+		case "enter":
+			if e.subject.Focused() {
+				return e, e.toggleFocus()
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	if e.subject.Focused() {
+		e.subject, cmd = e.subject.Update(msg)
+	} else {
+		e.body, cmd = e.body.Update(msg)
+	}
+	return e, cmd
+}
+
+func (e *CommitEditor) toggleFocus() tea.Cmd {
+	if e.subject.Focused() {
+		e.subject.Blur()
+		return e.body.Focus()
+	}
+	e.body.Blur()
+	return e.subject.Focus()
+}
+
+func (e *CommitEditor) View() string {
+	var b strings.Builder
+
+	subjectLen := len([]rune(e.subject.Value()))
+	limit := e.style.SubjectLimit()
+	counter := fmt.Sprintf("%d/%d", subjectLen, limit)
+	if subjectLen > limit {
+		counter = editorWarnStyle.Render(counter)
+	} else {
+		counter = editorDimStyle.Render(counter)
+	}
+
+	b.WriteString(editorLabelStyle.Render("Subject") + " " + counter + "\n")
+	b.WriteString(e.subject.View() + "\n\n")
+
+	bodyLen := len([]rune(e.body.Value()))
+	b.WriteString(editorLabelStyle.Render("Body") + " " + editorDimStyle.Render(fmt.Sprintf("%d chars", bodyLen)) + "\n")
+	b.WriteString(e.body.View() + "\n\n")
+
+	if problems := ValidateMessage(e.Message(), e.style); len(problems) > 0 {
+		for _, p := range problems {
+			b.WriteString(editorWarnStyle.Render("⚠ "+p) + "\n")
+		}
+	} else {
+		b.WriteString(editorOkStyle.Render("✓ message looks good") + "\n")
+	}
+
+	b.WriteString(editorDimStyle.Render("tab: switch field • ctrl+s: accept • esc: cancel") + "\n")
+
+	return b.String()
+}
+
+// Message returns the edited commit message
+func (e *CommitEditor) Message() string {
+	return JoinMessage(e.subject.Value(), e.body.Value())
+}
+
+// RunCommitEditor opens the editor and returns the edited message.
+// The boolean result is false when the user cancelled.
+func RunCommitEditor(message string, style commitmsg.Style) (string, bool, error) {
+	editor := NewCommitEditor(message, style)
+	if _, err := tea.NewProgram(editor).Run(); err != nil {
+		return "", false, err
+	}
+	if editor.cancelled || !editor.accepted {
+		return "", false, nil
+	}
+	return editor.Message(), true, nil
+}
@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/internal/commitmsg"
+)
+
+func TestSplitJoinMessage(t *testing.T) {
+	subject, body := SplitMessage("feat: add login\n\nAdds the login form.\nWith validation.\n")
+	if subject != "feat: add login" {
+		t.Errorf("subject = %q", subject)
+	}
+	if body != "Adds the login form.\nWith validation." {
+		t.Errorf("body = %q", body)
+	}
+
+	if got := JoinMessage(subject, body); got != "feat: add login\n\nAdds the login form.\nWith validation." {
+		t.Errorf("JoinMessage() = %q", got)
+	}
+	if got := JoinMessage("fix: typo", "  "); got != "fix: typo" {
+		t.Errorf("JoinMessage() without body = %q", got)
+	}
+}
+
+func TestValidateSubject(t *testing.T) {
+	tests := []struct {
+		subject      string
+		conventional bool
+		problems     int
+	}{
+		{"feat: add login", true, 0},
+		{"feat(auth): add login", true, 0},
+		{"feat(auth)!: drop sessions", true, 0},
+		{"Add login", false, 0},
+		{"Add login", true, 1},
+		{"fix: trailing period.", true, 1},
+		{"", true, 1},
+		{"feat: " + strings.Repeat("x", commitmsg.DefaultMaxSubject), true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			got := ValidateSubject(tt.subject, commitmsg.Style{Conventional: tt.conventional})
+			if len(got) != tt.problems {
+				t.Errorf("ValidateSubject(%q, %v) = %v, want %d problems", tt.subject, tt.conventional, got, tt.problems)
+			}
+		})
+	}
+}
+
+func TestValidateSubjectLimit(t *testing.T) {
+	style := commitmsg.Style{MaxSubject: 50}
+	if got := ValidateSubject(strings.Repeat("x", 50), style); len(got) != 0 {
+		t.Errorf("50 characters: %v", got)
+	}
+	if got := ValidateSubject(strings.Repeat("x", 51), style); len(got) != 1 || !strings.Contains(got[0], "max 50") {
+		t.Errorf("51 characters: %v", got)
+	}
+}
+
+func TestValidateSubjectGitmoji(t *testing.T) {
+	style := commitmsg.Style{Emoji: true, Conventional: true}
+	if got := ValidateSubject("✨ feat: add login", style); len(got) != 0 {
+		t.Errorf("valid gitmoji subject: %v", got)
+	}
+	if got := ValidateSubject("feat: add login", style); len(got) != 1 {
+		t.Errorf("missing gitmoji: %v", got)
+	}
+}
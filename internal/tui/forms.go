@@ -23,6 +23,18 @@ func BranchNameForm(name *string) *huh.Form {
 	).WithTheme(Theme())
 }
 
+// SecretForm prompts for a value without echoing it
+func SecretForm(title string, value *string) *huh.Form {
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(title).
+				EchoMode(huh.EchoModePassword).
+				Value(value),
+		),
+	).WithTheme(Theme())
+}
+
 func ConfirmForm(message string, confirmed *bool) *huh.Form {
 	return huh.NewForm(
 		huh.NewGroup(
@@ -33,6 +45,18 @@ func ConfirmForm(message string, confirmed *bool) *huh.Form {
 	).WithTheme(Theme())
 }
 
+// TextForm edits a multi-line value
+func TextForm(title string, value *string) *huh.Form {
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewText().
+				Title(title).
+				Lines(12).
+				Value(value),
+		),
+	).WithTheme(Theme())
+}
+
 func SelectForm(title string, options []string, selected *string) *huh.Form {
 	opts := make([]huh.Option[string], len(options))
 	for i, o := range options {
@@ -49,8 +73,15 @@ func SelectForm(title string, options []string, selected *string) *huh.Form {
 	).WithTheme(Theme())
 }
 
-// Returns the selected worktree name (basename of path)
-func WorktreeSelectForm(worktrees []git.Worktree, selected *string) *huh.Form {
+// Returns the selected worktree name (basename of path). notes, keyed by
+// worktree path, are appended to the option labels and may be nil.
+func WorktreeSelectForm(worktrees []git.Worktree, notes map[string]string, selected *string) *huh.Form {
+	return huh.NewForm(
+		huh.NewGroup(worktreeSelect(worktrees, notes, selected)),
+	).WithTheme(Theme())
+}
+
+func worktreeSelect(worktrees []git.Worktree, notes map[string]string, selected *string) *huh.Select[string] {
 	opts := make([]huh.Option[string], 0, len(worktrees))
 
 	for _, wt := range worktrees {
@@ -64,13 +95,52 @@ func WorktreeSelectForm(worktrees []git.Worktree, selected *string) *huh.Form {
 		}
 
 		label := fmt.Sprintf("%s (%s)", name, branch)
+		if note := notes[wt.Path]; note != "" {
+			label += " — " + note
+		}
 		opts = append(opts, huh.NewOption(label, name))
 	}
 
+	return huh.NewSelect[string]().
+		Title("Select worktree").
+		Options(opts...).
+		Value(selected)
+}
+
+// StashSelectForm returns the ref of the selected stash. notes, keyed by
+// ref, are appended to the option labels and may be nil.
+func StashSelectForm(stashes []git.StashEntry, notes map[string]string, selected *string) *huh.Form {
+	opts := make([]huh.Option[string], 0, len(stashes))
+
+	for _, s := range stashes {
+		label := fmt.Sprintf("%s (%s) %s", s.Ref, s.Branch, s.Message)
+		if note := notes[s.Ref]; note != "" {
+			label += " — " + note
+		}
+		opts = append(opts, huh.NewOption(label, s.Ref))
+	}
+
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Select stash").
+				Options(opts...).
+				Value(selected),
+		),
+	).WithTheme(Theme())
+}
+
+// CommitSelectForm returns the hash of the selected commit
+func CommitSelectForm(title string, commits []git.LogEntry, selected *string) *huh.Form {
+	opts := make([]huh.Option[string], 0, len(commits))
+	for _, c := range commits {
+		opts = append(opts, huh.NewOption(fmt.Sprintf("%.7s %s", c.Hash, c.Subject), c.Hash))
+	}
+
 	return huh.NewForm(
 		huh.NewGroup(
 			huh.NewSelect[string]().
-				Title("Select worktree").
+				Title(title).
 				Options(opts...).
 				Value(selected),
 		),
@@ -102,3 +172,47 @@ func CleanupConfirmForm(worktreeName string, confirmed *bool) *huh.Form {
 		),
 	).WithTheme(Theme())
 }
+
+// StaleCleanForm picks the stale worktrees to remove, all selected up
+// front. labels, keyed by path, describe each worktree.
+func StaleCleanForm(worktrees []git.Worktree, labels map[string]string, selected *[]string) *huh.Form {
+	opts := make([]huh.Option[string], 0, len(worktrees))
+	for _, wt := range worktrees {
+		opts = append(opts, huh.NewOption(labels[wt.Path], wt.Path).Selected(true))
+	}
+
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewMultiSelect[string]().
+				Title("Remove stale worktrees").
+				Description("space to toggle, enter to remove the selected worktrees").
+				Options(opts...).
+				Value(selected),
+		),
+	).WithTheme(Theme())
+}
+
+// BranchCleanForm picks the branches to delete. Merged branches are
+// selected up front; branches that are only gone from the remote are too
+// when force is set.
+func BranchCleanForm(branches []git.LocalBranch, force bool, selected *[]string) *huh.Form {
+	opts := make([]huh.Option[string], 0, len(branches))
+
+	for _, b := range branches {
+		label := b.Name + " (merged)"
+		if !b.Merged {
+			label = b.Name + " (upstream gone, not merged)"
+		}
+		opts = append(opts, huh.NewOption(label, b.Name).Selected(b.Merged || force))
+	}
+
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewMultiSelect[string]().
+				Title("Delete branches").
+				Description("space to toggle, enter to delete the selected branches").
+				Options(opts...).
+				Value(selected),
+		),
+	).WithTheme(Theme())
+}
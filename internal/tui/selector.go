@@ -0,0 +1,71 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/miltonparedes/lazywork/internal/git"
+)
+
+// ActionDiff is returned by RunWorktreeSelector when d was pressed on a
+// worktree instead of selecting it
+const ActionDiff = "diff"
+
+// selector wraps the worktree select form to add action keys
+type selector struct {
+	form   *huh.Form
+	field  *huh.Select[string]
+	action string
+	value  string
+}
+
+func (s *selector) Init() tea.Cmd {
+	return s.form.Init()
+}
+
+func (s *selector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Letters type into the filter while filtering
+	if key, ok := msg.(tea.KeyMsg); ok && key.String() == "d" && !s.field.GetFiltering() {
+		if value, ok := s.field.Hovered(); ok {
+			s.action, s.value = ActionDiff, value
+			return s, tea.Quit
+		}
+	}
+
+	model, cmd := s.form.Update(msg)
+	if form, ok := model.(*huh.Form); ok {
+		s.form = form
+	}
+	if s.form.State != huh.StateNormal {
+		return s, tea.Quit
+	}
+	return s, cmd
+}
+
+func (s *selector) View() string {
+	if s.action != "" || s.form.State != huh.StateNormal {
+		return ""
+	}
+	return s.form.View()
+}
+
+// RunWorktreeSelector asks for a worktree like WorktreeSelectForm, and also
+// lets the user press d to diff the highlighted worktree, in which case
+// ActionDiff is returned with its name
+func RunWorktreeSelector(worktrees []git.Worktree, notes map[string]string) (name, action string, err error) {
+	field := worktreeSelect(worktrees, notes, &name).Description("enter: select • d: diff against main")
+	s := &selector{
+		form:  huh.NewForm(huh.NewGroup(field)).WithTheme(Theme()),
+		field: field,
+	}
+
+	if _, err := tea.NewProgram(s).Run(); err != nil {
+		return "", "", err
+	}
+	if s.action != "" {
+		return s.value, s.action, nil
+	}
+	if s.form.State == huh.StateAborted {
+		return "", "", huh.ErrUserAborted
+	}
+	return name, "", nil
+}
@@ -0,0 +1,231 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/miltonparedes/lazywork/internal/git"
+)
+
+var (
+	stageAddStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	stageDelStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	stageCursorStyle = lipgloss.NewStyle().Bold(true)
+)
+
+// stageRow is a line of the stager's list: a file, or one of its units
+// (see git.FilePatch.Units) when unit is not -1
+type stageRow struct {
+	file, unit int
+}
+
+// Stager is a Bubble Tea model for picking the files and hunks to stage
+type Stager struct {
+	files    []git.FilePatch
+	selected [][]bool
+	rows     []stageRow
+	cursor   int
+	height   int
+	accepted bool
+}
+
+// NewStager lists files with nothing selected
+func NewStager(files []git.FilePatch) *Stager {
+	s := &Stager{files: files, height: 24}
+	for i, f := range files {
+		s.selected = append(s.selected, make([]bool, f.Units()))
+		s.rows = append(s.rows, stageRow{file: i, unit: -1})
+		for j := range f.Hunks {
+			s.rows = append(s.rows, stageRow{file: i, unit: j})
+		}
+	}
+	return s
+}
+
+func (s *Stager) Init() tea.Cmd {
+	return nil
+}
+
+func (s *Stager) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc", "q":
+			return s, tea.Quit
+		case "enter":
+			s.accepted = true
+			return s, tea.Quit
+		case "up", "k":
+			if s.cursor > 0 {
+				s.cursor--
+			}
+		case "down", "j":
+			if s.cursor < len(s.rows)-1 {
+				s.cursor++
+			}
+		case " ":
+			s.toggle(s.rows[s.cursor])
+		case "a":
+			// Select everything unless it all is, then nothing
+			all := s.allSelected()
+			for i := range s.selected {
+				for j := range s.selected[i] {
+					s.selected[i][j] = !all
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+// toggle flips the unit of row, or every unit of its file for a file row
+func (s *Stager) toggle(row stageRow) {
+	units := s.selected[row.file]
+	if row.unit >= 0 {
+		units[row.unit] = !units[row.unit]
+		return
+	}
+	all := s.fileState(row.file) == "x"
+	for j := range units {
+		units[j] = !all
+	}
+}
+
+func (s *Stager) allSelected() bool {
+	for i := range s.selected {
+		if s.fileState(i) != "x" {
+			return false
+		}
+	}
+	return true
+}
+
+// fileState is "x" when every unit of file i is selected, "~" when some
+// are and " " when none are
+func (s *Stager) fileState(i int) string {
+	n := 0
+	for _, sel := range s.selected[i] {
+		if sel {
+			n++
+		}
+	}
+	switch n {
+	case 0:
+		return " "
+	case len(s.selected[i]):
+		return "x"
+	}
+	return "~"
+}
+
+func (s *Stager) View() string {
+	if len(s.rows) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(editorLabelStyle.Render("Select changes to stage") + "\n\n")
+
+	// The list gets a third of the screen, the preview the rest
+	listHeight := max(s.height/3, 5)
+	first := max(0, min(s.cursor-listHeight/2, len(s.rows)-listHeight))
+	for r := first; r < len(s.rows) && r < first+listHeight; r++ {
+		b.WriteString(s.rowView(r) + "\n")
+	}
+
+	b.WriteString("\n")
+	previewHeight := max(s.height-listHeight-8, 5)
+	for _, line := range s.preview(s.rows[s.cursor], previewHeight) {
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n" + editorDimStyle.Render("space: toggle • a: toggle all • enter: stage • esc: cancel") + "\n")
+	return b.String()
+}
+
+func (s *Stager) rowView(r int) string {
+	row := s.rows[r]
+	f := s.files[row.file]
+	var line string
+	if row.unit < 0 {
+		label := f.Path
+		switch {
+		case f.Untracked:
+			label += " (new)"
+		case len(f.Hunks) == 0:
+			label += " (whole file)"
+		}
+		line = fmt.Sprintf("[%s] %s", s.fileState(row.file), label)
+	} else {
+		mark := " "
+		if s.selected[row.file][row.unit] {
+			mark = "x"
+		}
+		line = fmt.Sprintf("    [%s] %s", mark, strings.TrimSpace(f.Hunks[row.unit].Header))
+	}
+
+	if r == s.cursor {
+		return stageCursorStyle.Render("> " + line)
+	}
+	return "  " + line
+}
+
+// preview returns at most height lines showing what row changes
+func (s *Stager) preview(row stageRow, height int) []string {
+	f := s.files[row.file]
+	var lines []string
+	switch {
+	case f.Untracked:
+		lines = []string{"new file, staged as a whole"}
+	case row.unit >= 0:
+		lines = f.Hunks[row.unit].Lines
+	case len(f.Hunks) > 0:
+		for _, h := range f.Hunks {
+			lines = append(lines, h.Header)
+			lines = append(lines, h.Lines...)
+		}
+	default:
+		lines = f.Header
+	}
+
+	out := make([]string, 0, height)
+	for _, line := range lines {
+		if len(out) == height {
+			out[height-1] = editorDimStyle.Render("…")
+			break
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "+"):
+			line = stageAddStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			line = stageDelStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			line = editorDimStyle.Render(line)
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// Selected returns the selection in the shape git.BuildPatch takes
+func (s *Stager) Selected() [][]bool {
+	return s.selected
+}
+
+// RunStager lets the user pick the files and hunks to stage and returns
+// the selection, see git.BuildPatch. The boolean result is false when the
+// user cancelled.
+func RunStager(files []git.FilePatch) ([][]bool, bool, error) {
+	s := NewStager(files)
+	if _, err := tea.NewProgram(s, tea.WithAltScreen()).Run(); err != nil {
+		return nil, false, err
+	}
+	if !s.accepted {
+		return nil, false, nil
+	}
+	return s.Selected(), true, nil
+}
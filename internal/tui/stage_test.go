@@ -0,0 +1,44 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/miltonparedes/lazywork/internal/git"
+)
+
+func TestStagerToggle(t *testing.T) {
+	s := NewStager([]git.FilePatch{
+		{Path: "app.go", Hunks: []git.Hunk{{Header: "@@ -1 +1 @@\n"}, {Header: "@@ -9 +9 @@\n"}}},
+		{Path: "new.txt", Untracked: true},
+	})
+	press := func(keys ...string) {
+		for _, k := range keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			if k == " " {
+				msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(k)}
+			}
+			s.Update(msg)
+		}
+	}
+
+	// Rows: app.go, its two hunks, new.txt
+	press("j", "j", " ")
+	if want := [][]bool{{false, true}, {false}}; !reflect.DeepEqual(s.Selected(), want) {
+		t.Errorf("after toggling the second hunk: %v, want %v", s.Selected(), want)
+	}
+	if s.fileState(0) != "~" {
+		t.Errorf("fileState = %q, want partly selected", s.fileState(0))
+	}
+
+	press("k", "k", " ", "j", "j", "j", " ")
+	if want := [][]bool{{true, true}, {true}}; !reflect.DeepEqual(s.Selected(), want) {
+		t.Errorf("after toggling both files: %v, want %v", s.Selected(), want)
+	}
+
+	press("a")
+	if want := [][]bool{{false, false}, {false}}; !reflect.DeepEqual(s.Selected(), want) {
+		t.Errorf("after toggling all: %v, want %v", s.Selected(), want)
+	}
+}
@@ -0,0 +1,81 @@
+// Package workspace writes VS Code multi-root workspace files listing a
+// repository's worktrees.
+package workspace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+)
+
+// Ext is the extension of VS Code workspace files
+const Ext = ".code-workspace"
+
+// Folder is one root of a multi-root workspace
+type Folder struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path"`
+}
+
+// Folders returns a folder for every worktree that is checked out, with
+// paths relative to dir (the directory holding the workspace file) where
+// possible
+func Folders(worktrees []git.Worktree, dir string) []Folder {
+	folders := []Folder{}
+	for _, wt := range worktrees {
+		if wt.Bare {
+			continue
+		}
+		path := wt.Path
+		if rel, err := filepath.Rel(dir, wt.Path); err == nil {
+			path = filepath.ToSlash(rel)
+		}
+		name := filepath.Base(wt.Path)
+		if wt.Branch != "" && wt.Branch != name {
+			name += " (" + wt.Branch + ")"
+		}
+		folders = append(folders, Folder{Name: name, Path: path})
+	}
+	return folders
+}
+
+// Write sets the folders of the workspace file at path, creating it if
+// needed. Settings, extensions and other keys of an existing file are
+// kept; a file that is not plain JSON (VS Code allows comments) is left
+// alone and an error returned.
+func Write(path string, folders []Folder) error {
+	doc := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		doc["settings"] = json.RawMessage("{}")
+	case err != nil:
+		return fmt.Errorf("failed to read workspace file: %w", err)
+	default:
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("cannot update %s: it is not plain JSON (remove comments and trailing commas): %w", path, err)
+		}
+	}
+
+	raw, err := json.Marshal(folders)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workspace folders: %w", err)
+	}
+	doc["folders"] = raw
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "\t")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to marshal workspace file: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write workspace file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package workspace
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+)
+
+func TestFolders(t *testing.T) {
+	worktrees := []git.Worktree{
+		{Path: "/src/app/.bare", Bare: true},
+		{Path: "/src/app/main", Branch: "main"},
+		{Path: "/src/app/auth", Branch: "feature/auth"},
+		{Path: "/elsewhere/fix", Branch: "fix"},
+	}
+
+	got := Folders(worktrees, "/src/app")
+	want := []Folder{
+		{Name: "main", Path: "main"},
+		{Name: "auth (feature/auth)", Path: "auth"},
+		{Name: "fix", Path: "../../elsewhere/fix"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Folders = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Folders[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWriteKeepsSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app"+Ext)
+	if err := Write(path, []Folder{{Path: "."}}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	os.WriteFile(path, []byte(`{"folders": [], "settings": {"editor.tabSize": 2}}`), 0o644)
+	if err := Write(path, []Folder{{Name: "auth", Path: ".worktrees/auth"}}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var doc struct {
+		Folders  []Folder       `json:"folders"`
+		Settings map[string]any `json:"settings"`
+	}
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid workspace file: %v", err)
+	}
+	if len(doc.Folders) != 1 || doc.Folders[0].Path != ".worktrees/auth" || doc.Settings["editor.tabSize"] != 2.0 {
+		t.Errorf("workspace file = %s", data)
+	}
+
+	os.WriteFile(path, []byte("{\n  // comment\n  \"folders\": []\n}"), 0o644)
+	if err := Write(path, nil); err == nil || !strings.Contains(err.Error(), "plain JSON") {
+		t.Errorf("Write over JSONC = %v", err)
+	}
+}
@@ -1,16 +1,55 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/paths"
 )
 
 type Config struct {
-	DefaultProvider string              `json:"default_provider"`
-	WorktreeDir     string              `json:"worktree_dir,omitempty"`
-	Providers       map[string]Provider `json:"providers"`
+	DefaultProvider     string                  `json:"default_provider"`
+	DefaultModel        string                  `json:"default_model,omitempty"`
+	WorktreeDir         string                  `json:"worktree_dir,omitempty"`
+	MainBranch          string                  `json:"main_branch,omitempty"`
+	EnvrcTemplate       string                  `json:"envrc_template,omitempty"`
+	GitTimeout          string                  `json:"git_timeout,omitempty"`
+	WorktreeSubmodules  bool                    `json:"worktree_submodules,omitempty"`
+	WorktreeSparse      []string                `json:"worktree_sparse,omitempty"`
+	WorktreePush        bool                    `json:"worktree_push,omitempty"`
+	WorktreeIncludeMain bool                    `json:"worktree_include_main,omitempty"`
+	WorktreeArchive     bool                    `json:"worktree_archive,omitempty"`
+	WorktreeStaleAfter  string                  `json:"worktree_stale_after,omitempty"`
+	PortBase            int                     `json:"port_base,omitempty"`
+	PortBlockSize       int                     `json:"port_block_size,omitempty"`
+	AutoFetch           bool                    `json:"auto_fetch,omitempty"`
+	Hooks               map[string][]string     `json:"hooks,omitempty"`
+	Forge               string                  `json:"forge,omitempty"`
+	Forges              map[string]ForgeConfig  `json:"forges,omitempty"`
+	Tickets             map[string]TicketConfig `json:"tickets,omitempty"`
+	Providers           map[string]Provider     `json:"providers"`
+	Models              map[string]string       `json:"models,omitempty"`
+	Generation          map[string]Generation   `json:"generation,omitempty"`
+	Profiles            map[string]Profile      `json:"profiles,omitempty"`
+	Budget              Budget                  `json:"budget,omitzero"`
+	Redact              Redact                  `json:"redact,omitzero"`
+	Commit              CommitConfig            `json:"commit,omitzero"`
+	Context             ContextConfig           `json:"context,omitzero"`
+
+	// RepoConfigPath is the per-repository config merged into this config, if any
+	RepoConfigPath string `json:"-"`
+	// RepoHooks are the hooks of the per-repository config, kept apart
+	// from the user's as they only run once the user trusts them
+	RepoHooks map[string][]string `json:"-"`
+	// ActiveProfile is the profile applied with ApplyProfile, if any
+	ActiveProfile string `json:"-"`
+	// EnvOverrides lists the LAZYWORK_* variables applied with ApplyEnv
+	EnvOverrides []string `json:"-"`
 }
 
 // GetWorktreeDir returns the worktree directory, defaulting to ".worktrees"
@@ -21,12 +60,107 @@ func (c *Config) GetWorktreeDir() string {
 	return c.WorktreeDir
 }
 
+// GetGitTimeout returns the limit for a single git command, such as "30s"
+// or "2m"; zero means no limit
+func (c *Config) GetGitTimeout() (time.Duration, error) {
+	if c.GitTimeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.GitTimeout)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid git_timeout '%s': use a duration such as 30s or 2m", c.GitTimeout)
+	}
+	return d, nil
+}
+
+// DefaultStaleAfter is how long a worktree goes without commits or visits
+// before it is stale, unless worktree_stale_after says otherwise
+const DefaultStaleAfter = 30 * 24 * time.Hour
+
+// GetStaleAfter returns how long a worktree goes without commits or visits
+// before it is stale, such as "30d" or "2w"; zero turns stale warnings off
+func (c *Config) GetStaleAfter() (time.Duration, error) {
+	if c.WorktreeStaleAfter == "" {
+		return DefaultStaleAfter, nil
+	}
+	d, err := ParseAge(c.WorktreeStaleAfter)
+	if err != nil {
+		return DefaultStaleAfter, fmt.Errorf("invalid worktree_stale_after '%s': use e.g. 30d, 2w or 0 to turn it off", c.WorktreeStaleAfter)
+	}
+	return d, nil
+}
+
+// ParseAge parses an age such as "30d", "2w" or a Go duration like "12h"
+func ParseAge(s string) (time.Duration, error) {
+	days := 0
+	switch {
+	case strings.HasSuffix(s, "d"):
+		days = 1
+	case strings.HasSuffix(s, "w"):
+		days = 7
+	}
+	if days > 0 {
+		n, err := strconv.Atoi(strings.TrimSpace(s[:len(s)-1]))
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age '%s': use e.g. 30d, 2w or 12h", s)
+		}
+		return time.Duration(n*days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age '%s': use e.g. 30d, 2w or 12h", s)
+	}
+	return d, nil
+}
+
+// Default port blocks handed to worktrees: 3000-3009, 3010-3019, ...
+const (
+	DefaultPortBase      = 3000
+	DefaultPortBlockSize = 10
+)
+
+// GetPorts returns the first port of the first worktree's block and the
+// number of ports in each block
+func (c *Config) GetPorts() (base, size int) {
+	base, size = c.PortBase, c.PortBlockSize
+	if base <= 0 {
+		base = DefaultPortBase
+	}
+	if size <= 0 {
+		size = DefaultPortBlockSize
+	}
+	return base, size
+}
+
 type Provider struct {
 	Type      string  `json:"type"`
 	BaseURL   string  `json:"base_url,omitempty"`
 	APIKey    string  `json:"api_key,omitempty"`
 	Models    []Model `json:"models,omitempty"`
 	MaxTokens int     `json:"max_tokens,omitempty"`
+	// EmbeddingModel is the model used for embeddings, by providers that
+	// support them ('search --semantic')
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	// HTTP holds timeouts, proxy, TLS and extra headers for the provider
+	HTTP HTTPConfig `json:"http,omitzero"`
+	// RateLimit caps how fast lazywork calls the provider
+	RateLimit RateLimit `json:"rate_limit,omitzero"`
+	// MaxEventSize bounds one event of a streamed response, in bytes;
+	// zero means 4 MiB
+	MaxEventSize int `json:"max_event_size,omitempty"`
+	// Response is the text a mock provider answers with, as a Go template
+	// over the request ({{.Model}}, {{.System}}, {{.Prompt}})
+	Response string `json:"response,omitempty"`
+}
+
+// RateLimit caps requests to a provider across all calls made by one
+// lazywork process. Zero fields are unlimited.
+type RateLimit struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	// TokensPerMinute counts the estimated prompt tokens plus the
+	// requested max_tokens of each call
+	TokensPerMinute int `json:"tokens_per_minute,omitempty"`
+	MaxConcurrent   int `json:"max_concurrent,omitempty"`
 }
 
 type Model struct {
@@ -35,19 +169,155 @@ type Model struct {
 	ContextWindow int     `json:"context_window"`
 	MaxTokens     int     `json:"max_tokens,omitempty"`
 	Temperature   float64 `json:"temperature,omitempty"`
+	// InputPrice and OutputPrice are USD per million tokens, used to
+	// estimate spend in 'lazywork usage'
+	InputPrice  float64 `json:"input_price,omitempty"`
+	OutputPrice float64 `json:"output_price,omitempty"`
 }
 
-func DefaultConfigPath() string {
+// Budget actions
+const (
+	BudgetWarn  = "warn"
+	BudgetBlock = "block"
+)
+
+// Budget caps the estimated AI spend per calendar month. It is only read
+// from the user config.
+type Budget struct {
+	// Monthly is the limit in USD; zero disables the budget
+	Monthly float64 `json:"monthly,omitempty"`
+	// Action is "warn" (the default) or "block" once the limit is reached
+	Action string `json:"action,omitempty"`
+}
+
+// Blocks returns true if requests are refused once the budget is spent
+func (b Budget) Blocks() bool {
+	return b.Action == BudgetBlock
+}
+
+// CommitConfig is how generated commit messages are written
+type CommitConfig struct {
+	// Language is a language name or tag such as "es" or "pt-BR"; empty
+	// means English
+	Language string `json:"language,omitempty"`
+	// Tone is a free-form description such as "formal" or "concise"
+	Tone string `json:"tone,omitempty"`
+	// MaxSubjectLength bounds the subject line; zero means 72
+	MaxSubjectLength int `json:"max_subject_length,omitempty"`
+	// Emoji starts generated subjects with a gitmoji (https://gitmoji.dev)
+	Emoji bool `json:"emoji,omitempty"`
+	// Lint holds commitlint rules in commitlint's form, e.g.
+	// "header-max-length": [2, "always", 72]. They override the rules of
+	// a commitlint config in the repository.
+	Lint map[string][]interface{} `json:"lint,omitempty"`
+	// Examples is how many recent commit messages are shown to the model
+	// as style examples; zero means DefaultCommitExamples
+	Examples int `json:"examples,omitempty"`
+	// NoExamples leaves the style examples out
+	NoExamples bool `json:"no_examples,omitempty"`
+}
+
+// DefaultCommitExamples is how many recent commit messages are shown as
+// style examples unless commit.examples says otherwise
+const DefaultCommitExamples = 10
+
+// ExampleCount returns how many recent commit messages to show as style
+// examples, zero when they are disabled
+func (c CommitConfig) ExampleCount() int {
+	switch {
+	case c.NoExamples:
+		return 0
+	case c.Examples > 0:
+		return c.Examples
+	}
+	return DefaultCommitExamples
+}
+
+// DefaultContextTokens bounds the repository context added to prompts
+// unless context.max_tokens says otherwise
+const DefaultContextTokens = 1000
+
+// ContextConfig sets the repository context (file tree, recent commits
+// and README) added to commit and merge message prompts
+type ContextConfig struct {
+	// Disabled leaves the context out
+	Disabled bool `json:"disabled,omitempty"`
+	// MaxTokens bounds the context; zero means DefaultContextTokens
+	MaxTokens int `json:"max_tokens,omitempty"`
+}
+
+// GetMaxTokens returns the token budget of the repository context, zero
+// when it is disabled
+func (c ContextConfig) GetMaxTokens() int {
+	switch {
+	case c.Disabled:
+		return 0
+	case c.MaxTokens > 0:
+		return c.MaxTokens
+	}
+	return DefaultContextTokens
+}
+
+// Redact configures the secret redaction applied to everything sent to an
+// AI provider
+type Redact struct {
+	// Patterns are regular expressions masked in addition to the built-in
+	// secret patterns
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// DefaultConfigDir returns the directory holding the user config:
+// ~/.config/lazywork, or %APPDATA%\lazywork on Windows unless an earlier
+// ~/.config/lazywork exists there
+func DefaultConfigDir() string {
 	homeDir, _ := os.UserHomeDir()
-	return filepath.Join(homeDir, ".config", "lazywork", "config.json")
+	dir := paths.ConfigDir(runtime.GOOS, os.Getenv, homeDir)
+	if runtime.GOOS == paths.Windows {
+		legacy := filepath.Join(homeDir, ".config", "lazywork")
+		if _, err := os.Stat(legacy); err == nil {
+			return legacy
+		}
+	}
+	return dir
+}
+
+// DefaultConfigPath returns $LAZYWORK_CONFIG if set, otherwise the first
+// existing config.{json,yaml,yml,toml} in the config directory, or
+// config.json if none exists
+func DefaultConfigPath() string {
+	if path := os.Getenv("LAZYWORK_CONFIG"); path != "" {
+		return path
+	}
+	dir := DefaultConfigDir()
+	for _, name := range []string{"config.json", "config.yaml", "config.yml", "config.toml"} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, "config.json")
 }
 
 func Load() (*Config, error) {
 	return LoadFrom("")
 }
 
-// LoadFrom loads config from a custom path (empty string uses default)
+// LoadFrom loads config from a custom path (empty string uses default),
+// resolving $ENV and keyring: API key references
 func LoadFrom(customPath string) (*Config, error) {
+	cfg, err := LoadRaw(customPath)
+	if err != nil {
+		return nil, err
+	}
+
+	resolveEnvironmentVariables(cfg)
+
+	return cfg, nil
+}
+
+// LoadRaw loads config without resolving API key references. Use it when
+// the config will be saved again, so secrets are never written to disk.
+func LoadRaw(customPath string) (*Config, error) {
 	configPath := customPath
 	if configPath == "" {
 		configPath = DefaultConfigPath()
@@ -63,22 +333,47 @@ func LoadFrom(customPath string) (*Config, error) {
 	}
 
 	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	if err := unmarshalConfig(data, FormatFromPath(configPath), &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	resolveEnvironmentVariables(&cfg)
-
 	return &cfg, nil
 }
 
+// resolveEnvironmentVariables replaces API key, forge and tracker token
+// references with their values: "$NAME" reads the environment variable NAME and
+// "keyring:NAME" reads the secret stored in the OS keyring with
+// 'config set-key NAME'
 func resolveEnvironmentVariables(cfg *Config) {
 	for name, provider := range cfg.Providers {
-		if len(provider.APIKey) > 0 && provider.APIKey[0] == '$' {
-			envVar := provider.APIKey[1:]
-			provider.APIKey = os.Getenv(envVar)
-			cfg.Providers[name] = provider
+		provider.APIKey = ResolveAPIKey(provider.APIKey)
+		provider.HTTP.Headers = mapHeaders(provider.HTTP.Headers, ResolveAPIKey)
+		cfg.Providers[name] = provider
+	}
+	for name, forge := range cfg.Forges {
+		forge.Token = ResolveAPIKey(forge.Token)
+		cfg.Forges[name] = forge
+	}
+	for name, tracker := range cfg.Tickets {
+		tracker.Token = ResolveAPIKey(tracker.Token)
+		cfg.Tickets[name] = tracker
+	}
+}
+
+// ResolveAPIKey resolves a single API key reference. Unresolvable
+// references yield an empty string; plain values are returned unchanged.
+func ResolveAPIKey(value string) string {
+	switch {
+	case strings.HasPrefix(value, "$"):
+		return os.Getenv(value[1:])
+	case strings.HasPrefix(value, KeyringPrefix):
+		secret, err := GetKeyringSecret(strings.TrimPrefix(value, KeyringPrefix))
+		if err != nil {
+			return ""
 		}
+		return secret
+	default:
+		return value
 	}
 }
 
@@ -87,10 +382,11 @@ func getDefaultConfig() *Config {
 		DefaultProvider: "anthropic",
 		Providers: map[string]Provider{
 			"openai": {
-				Type:      "openai",
-				BaseURL:   "https://api.openai.com/v1",
-				APIKey:    "$OPENAI_API_KEY",
-				MaxTokens: 4000,
+				Type:           "openai",
+				BaseURL:        "https://api.openai.com/v1",
+				APIKey:         "$OPENAI_API_KEY",
+				MaxTokens:      4000,
+				EmbeddingModel: "text-embedding-3-small",
 				Models: []Model{
 					{
 						ID:            "gpt-5",
@@ -98,6 +394,8 @@ func getDefaultConfig() *Config {
 						ContextWindow: 272000,
 						MaxTokens:     128000,
 						Temperature:   0.3,
+						InputPrice:    1.25,
+						OutputPrice:   10,
 					},
 					{
 						ID:            "gpt-5-mini",
@@ -105,6 +403,8 @@ func getDefaultConfig() *Config {
 						ContextWindow: 272000,
 						MaxTokens:     128000,
 						Temperature:   0.3,
+						InputPrice:    0.25,
+						OutputPrice:   2,
 					},
 					{
 						ID:            "gpt-5-nano",
@@ -112,6 +412,8 @@ func getDefaultConfig() *Config {
 						ContextWindow: 272000,
 						MaxTokens:     128000,
 						Temperature:   0.3,
+						InputPrice:    0.05,
+						OutputPrice:   0.4,
 					},
 				},
 			},
@@ -127,6 +429,8 @@ func getDefaultConfig() *Config {
 						ContextWindow: 200000,
 						MaxTokens:     64000,
 						Temperature:   0.3,
+						InputPrice:    3,
+						OutputPrice:   15,
 					},
 					{
 						ID:            "claude-haiku-4-5",
@@ -134,6 +438,8 @@ func getDefaultConfig() *Config {
 						ContextWindow: 200000,
 						MaxTokens:     64000,
 						Temperature:   0.3,
+						InputPrice:    1,
+						OutputPrice:   5,
 					},
 					{
 						ID:            "claude-opus-4-1",
@@ -141,9 +447,34 @@ func getDefaultConfig() *Config {
 						ContextWindow: 200000,
 						MaxTokens:     64000,
 						Temperature:   0.3,
+						InputPrice:    15,
+						OutputPrice:   75,
 					},
 				},
 			},
+			// A local Ollama server; it needs no API key
+			"ollama": {
+				Type:           "ollama",
+				BaseURL:        "http://localhost:11434/v1",
+				MaxTokens:      4000,
+				EmbeddingModel: "nomic-embed-text",
+				Models: []Model{
+					{
+						ID:            "llama3.2",
+						Name:          "Llama 3.2",
+						ContextWindow: 128000,
+						Temperature:   0.3,
+					},
+				},
+			},
+			// Answers without network access or a key, for trying
+			// lazywork out with --provider mock
+			"mock": {
+				Type: "mock",
+				Models: []Model{
+					{ID: "mock", Name: "Mock"},
+				},
+			},
 		},
 	}
 }
@@ -164,7 +495,7 @@ func (c *Config) SaveTo(customPath string) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	data, err := marshalConfig(c, FormatFromPath(configPath))
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
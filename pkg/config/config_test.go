@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+		"0d":  0,
+	} {
+		if got, err := ParseAge(in); err != nil || got != want {
+			t.Errorf("ParseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "d", "-3d", "soon", "1.5w"} {
+		if _, err := ParseAge(in); err == nil {
+			t.Errorf("ParseAge(%q) succeeded", in)
+		}
+	}
+}
+
+func TestGetStaleAfter(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"":    DefaultStaleAfter,
+		"2w":  14 * 24 * time.Hour,
+		"0":   0,
+		"bad": DefaultStaleAfter,
+	} {
+		c := &Config{WorktreeStaleAfter: in}
+		if got, err := c.GetStaleAfter(); got != want || (err != nil) != (in == "bad") {
+			t.Errorf("GetStaleAfter(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of environment variables that override settings.
+//
+// Settings are applied in this order, later ones taking precedence:
+//
+//  1. built-in defaults
+//  2. user config file
+//  3. per-repository config (.lazywork.json)
+//  4. profile (--profile or LAZYWORK_PROFILE)
+//  5. LAZYWORK_* environment variables
+//  6. command-line flags
+const EnvPrefix = "LAZYWORK_"
+
+// envSettings maps environment variables to top-level settings
+var envSettings = []struct {
+	name string
+	set  func(c *Config, value string)
+}{
+	{"LAZYWORK_PROVIDER", func(c *Config, v string) { c.DefaultProvider = v }},
+	{"LAZYWORK_DEFAULT_PROVIDER", func(c *Config, v string) { c.DefaultProvider = v }},
+	{"LAZYWORK_DEFAULT_MODEL", func(c *Config, v string) { c.DefaultModel = v }},
+	{"LAZYWORK_WORKTREE_DIR", func(c *Config, v string) { c.WorktreeDir = v }},
+	{"LAZYWORK_MAIN_BRANCH", func(c *Config, v string) { c.MainBranch = v }},
+	{"LAZYWORK_ENVRC_TEMPLATE", func(c *Config, v string) { c.EnvrcTemplate = v }},
+	{"LAZYWORK_GIT_TIMEOUT", func(c *Config, v string) { c.GitTimeout = v }},
+	{"LAZYWORK_WORKTREE_SUBMODULES", func(c *Config, v string) {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.WorktreeSubmodules = b
+		}
+	}},
+	{"LAZYWORK_WORKTREE_PUSH", func(c *Config, v string) {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.WorktreePush = b
+		}
+	}},
+	{"LAZYWORK_WORKTREE_INCLUDE_MAIN", func(c *Config, v string) {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.WorktreeIncludeMain = b
+		}
+	}},
+	{"LAZYWORK_WORKTREE_ARCHIVE", func(c *Config, v string) {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.WorktreeArchive = b
+		}
+	}},
+	{"LAZYWORK_WORKTREE_STALE_AFTER", func(c *Config, v string) { c.WorktreeStaleAfter = v }},
+	{"LAZYWORK_PORT_BASE", func(c *Config, v string) {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.PortBase = n
+		}
+	}},
+	{"LAZYWORK_PORT_BLOCK_SIZE", func(c *Config, v string) {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.PortBlockSize = n
+		}
+	}},
+	{"LAZYWORK_AUTO_FETCH", func(c *Config, v string) {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.AutoFetch = b
+		}
+	}},
+	{"LAZYWORK_FORGE", func(c *Config, v string) { c.Forge = v }},
+	{"LAZYWORK_WORKTREE_SPARSE", func(c *Config, v string) { c.WorktreeSparse = strings.Split(v, ",") }},
+}
+
+var unsafeEnvChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// ProviderEnvName returns the environment variable overriding a provider
+// field, e.g. ProviderEnvName("anthropic", "api_key") is
+// LAZYWORK_ANTHROPIC_API_KEY
+func ProviderEnvName(provider, field string) string {
+	name := unsafeEnvChars.ReplaceAllString(strings.ToUpper(provider), "_")
+	return EnvPrefix + strings.Trim(name, "_") + "_" + strings.ToUpper(field)
+}
+
+// ApplyEnv overrides settings from LAZYWORK_* environment variables using
+// lookup (normally os.LookupEnv). Provider API keys and base URLs are read
+// from LAZYWORK_<PROVIDER>_API_KEY and LAZYWORK_<PROVIDER>_BASE_URL; API
+// keys may themselves be $ENV or keyring: references. The applied variable
+// names are recorded in EnvOverrides.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) {
+	for _, setting := range envSettings {
+		if value, ok := lookup(setting.name); ok && value != "" {
+			setting.set(c, value)
+			c.EnvOverrides = append(c.EnvOverrides, setting.name)
+		}
+	}
+
+	names := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		provider := c.Providers[name]
+		if value, ok := lookup(ProviderEnvName(name, "api_key")); ok && value != "" {
+			provider.APIKey = ResolveAPIKey(value)
+			c.EnvOverrides = append(c.EnvOverrides, ProviderEnvName(name, "api_key"))
+		}
+		if value, ok := lookup(ProviderEnvName(name, "base_url")); ok && value != "" {
+			provider.BaseURL = value
+			c.EnvOverrides = append(c.EnvOverrides, ProviderEnvName(name, "base_url"))
+		}
+		c.Providers[name] = provider
+	}
+}
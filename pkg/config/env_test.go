@@ -0,0 +1,65 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"LAZYWORK_WORKTREE_DIR":        "/tmp/wt",
+		"LAZYWORK_DEFAULT_MODEL":       "claude-haiku-4-5",
+		"LAZYWORK_ANTHROPIC_API_KEY":   "sk-env",
+		"LAZYWORK_OPENAI_BASE_URL":     "https://proxy.example.com/v1",
+		"LAZYWORK_MAIN_BRANCH":         "",
+		"LAZYWORK_UNKNOWN_API_KEY":     "ignored",
+		"LAZYWORK_DEFAULT_PROVIDER":    "openai",
+		"LAZYWORK_PROVIDER":            "anthropic",
+		"LAZYWORK_LOCAL_LLM_BASE_URL":  "http://localhost:8080",
+		"LAZYWORK_WORKTREE_SUBMODULES": "true",
+		"LAZYWORK_WORKTREE_SPARSE":     "apps/web,libs",
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	cfg := getDefaultConfig()
+	cfg.Providers["local-llm"] = Provider{Type: "openai"}
+	cfg.MainBranch = "develop"
+	cfg.ApplyEnv(lookup)
+
+	if cfg.WorktreeDir != "/tmp/wt" {
+		t.Errorf("WorktreeDir = %q, want /tmp/wt", cfg.WorktreeDir)
+	}
+	if cfg.DefaultModel != "claude-haiku-4-5" {
+		t.Errorf("DefaultModel = %q", cfg.DefaultModel)
+	}
+	if cfg.DefaultProvider != "openai" {
+		t.Errorf("DefaultProvider = %q, want LAZYWORK_DEFAULT_PROVIDER to win", cfg.DefaultProvider)
+	}
+	if !cfg.WorktreeSubmodules {
+		t.Error("WorktreeSubmodules not enabled by LAZYWORK_WORKTREE_SUBMODULES")
+	}
+	if want := []string{"apps/web", "libs"}; !reflect.DeepEqual(cfg.WorktreeSparse, want) {
+		t.Errorf("WorktreeSparse = %v, want %v", cfg.WorktreeSparse, want)
+	}
+	if cfg.MainBranch != "develop" {
+		t.Errorf("empty variable overrode MainBranch: %q", cfg.MainBranch)
+	}
+	if got := cfg.Providers["anthropic"].APIKey; got != "sk-env" {
+		t.Errorf("anthropic api key = %q, want sk-env", got)
+	}
+	if got := cfg.Providers["openai"].BaseURL; got != "https://proxy.example.com/v1" {
+		t.Errorf("openai base url = %q", got)
+	}
+	if got := cfg.Providers["local-llm"].BaseURL; got != "http://localhost:8080" {
+		t.Errorf("local-llm base url = %q", got)
+	}
+	if _, ok := cfg.Providers["unknown"]; ok {
+		t.Error("env var created an unknown provider")
+	}
+	if len(cfg.EnvOverrides) != 9 {
+		t.Errorf("EnvOverrides = %v, want 9 entries", cfg.EnvOverrides)
+	}
+}
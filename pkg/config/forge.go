@@ -0,0 +1,14 @@
+package config
+
+// ForgeTypes are the code hosting services understood by internal/forge
+var ForgeTypes = []string{"github", "gitlab"}
+
+// ForgeConfig holds the credentials for a code hosting service. Like
+// providers, forges are only read from the user config.
+type ForgeConfig struct {
+	// Token is an API token, or a $ENV or keyring: reference to one
+	Token string `json:"token,omitempty"`
+	// BaseURL overrides the API endpoint, e.g. for GitHub Enterprise or a
+	// self-managed GitLab
+	BaseURL string `json:"base_url,omitempty"`
+}
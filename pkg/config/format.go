@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported config file formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// Formats returns the supported config file formats
+func Formats() []string {
+	return []string{FormatJSON, FormatYAML, FormatTOML}
+}
+
+// FormatFromPath returns the config format implied by a file extension,
+// defaulting to JSON
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// Extension returns the file extension (with dot) for a format
+func Extension(format string) string {
+	return "." + format
+}
+
+// unmarshalConfig decodes data in the given format into v. YAML and TOML
+// are decoded generically and converted through JSON, so the json struct
+// tags remain the single definition of config keys.
+func unmarshalConfig(data []byte, format string, v interface{}) error {
+	if format == FormatJSON {
+		return json.Unmarshal(data, v)
+	}
+
+	var generic map[string]interface{}
+	switch format {
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return err
+		}
+	case FormatTOML:
+		if err := toml.Unmarshal(data, &generic); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported config format: %s", format)
+	}
+
+	jsonData, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+// marshalConfig encodes v in the given format
+func marshalConfig(v interface{}, format string) ([]byte, error) {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
+	if err != nil || format == FormatJSON {
+		return jsonData, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber()
+	var generic map[string]interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	normalized := normalizeNumbers(generic)
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatYAML:
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(normalized); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		if err := toml.NewEncoder(&buf).Encode(normalized); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeNumbers converts json.Number values into int64 or float64 so
+// YAML and TOML encoders emit them as numbers rather than strings
+func normalizeNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeNumbers(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeNumbers(item)
+		}
+		return val
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	tests := map[string]string{
+		"config.json": FormatJSON,
+		"config.yaml": FormatYAML,
+		"config.YML":  FormatYAML,
+		"config.toml": FormatTOML,
+		"config":      FormatJSON,
+	}
+	for path, want := range tests {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	for _, format := range Formats() {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config"+Extension(format))
+
+			cfg := getDefaultConfig()
+			cfg.WorktreeDir = "../worktrees"
+			if err := cfg.SaveTo(path); err != nil {
+				t.Fatalf("SaveTo failed: %v", err)
+			}
+
+			loaded, err := LoadFrom(path)
+			if err != nil {
+				t.Fatalf("LoadFrom failed: %v", err)
+			}
+			if loaded.WorktreeDir != "../worktrees" {
+				t.Errorf("WorktreeDir = %q", loaded.WorktreeDir)
+			}
+			model := loaded.Providers["anthropic"].Models[0]
+			if model.ContextWindow != 200000 || model.Temperature != 0.3 {
+				t.Errorf("model = %+v", model)
+			}
+		})
+	}
+}
+
+func TestLoadYAMLByHand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `# Hand-written config with comments
+default_provider: openai
+providers:
+  openai:
+    type: openai
+    base_url: https://example.com/v1
+    models:
+      - id: gpt-5
+        context_window: 272000
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if cfg.DefaultProvider != "openai" || cfg.Providers["openai"].Models[0].ContextWindow != 272000 {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
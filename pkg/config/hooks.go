@@ -0,0 +1,57 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// HookEvents are the supported keys of the hooks setting. Each maps to a
+// list of shell commands run before (pre_) or after (post_) the operation.
+var HookEvents = []string{
+	"pre_add", "post_add",
+	"pre_remove", "post_remove",
+	"pre_use", "post_use",
+	"pre_return", "post_return",
+	"pre_finish", "post_finish",
+}
+
+// IsHookEvent returns true if event is one of HookEvents
+func IsHookEvent(event string) bool {
+	for _, e := range HookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// HookCommands returns the commands the user configured for event
+func (c *Config) HookCommands(event string) []string {
+	return c.Hooks[event]
+}
+
+// RepoHookCommands returns the commands the per-repository config sets
+// for event. They run after the user's hooks once RepoHooksHash is
+// trusted.
+func (c *Config) RepoHookCommands(event string) []string {
+	return c.RepoHooks[event]
+}
+
+// RepoHooksHash identifies the hooks of the per-repository config, so
+// trusting them lapses when any command changes; it is empty when there
+// are none
+func (c *Config) RepoHooksHash() string {
+	if len(c.RepoHooks) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, event := range sortedKeys(c.RepoHooks) {
+		fmt.Fprintf(h, "%s\x00", event)
+		for _, command := range c.RepoHooks[event] {
+			fmt.Fprintf(h, "%s\x00", command)
+		}
+		h.Write([]byte{0xff})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
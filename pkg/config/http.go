@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HTTPConfig tunes the HTTP client used to reach a provider. The zero
+// value uses Go's defaults and the HTTPS_PROXY/NO_PROXY environment.
+type HTTPConfig struct {
+	// ConnectTimeout limits establishing the connection, e.g. "10s"
+	ConnectTimeout string `json:"connect_timeout,omitempty"`
+	// Timeout limits a whole request, including reading a streamed
+	// response, e.g. "2m"
+	Timeout string `json:"timeout,omitempty"`
+	// Proxy is the proxy URL, overriding HTTPS_PROXY
+	Proxy string `json:"proxy,omitempty"`
+	// CACert is a PEM file with certificates to trust in addition to the
+	// system ones, e.g. for a TLS-intercepting corporate proxy
+	CACert string `json:"ca_cert,omitempty"`
+	// Headers are sent with every request, e.g. OpenAI-Organization.
+	// Values may be $ENV or keyring: references like api_key.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Timeouts returns the parsed connect and request timeouts; zero means no
+// limit beyond Go's defaults
+func (h HTTPConfig) Timeouts() (connect, request time.Duration, err error) {
+	if h.ConnectTimeout != "" {
+		if connect, err = time.ParseDuration(h.ConnectTimeout); err != nil {
+			return 0, 0, fmt.Errorf("invalid connect_timeout '%s': use a duration such as 10s", h.ConnectTimeout)
+		}
+	}
+	if h.Timeout != "" {
+		if request, err = time.ParseDuration(h.Timeout); err != nil {
+			return 0, 0, fmt.Errorf("invalid timeout '%s': use a duration such as 2m", h.Timeout)
+		}
+	}
+	return connect, request, nil
+}
+
+// CACertPath returns CACert with a leading ~ expanded to the home directory
+func (h HTTPConfig) CACertPath() string {
+	if rest, ok := strings.CutPrefix(h.CACert, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return h.CACert
+}
+
+func overlayHTTP(base, override HTTPConfig) HTTPConfig {
+	if override.ConnectTimeout != "" {
+		base.ConnectTimeout = override.ConnectTimeout
+	}
+	if override.Timeout != "" {
+		base.Timeout = override.Timeout
+	}
+	if override.Proxy != "" {
+		base.Proxy = override.Proxy
+	}
+	if override.CACert != "" {
+		base.CACert = override.CACert
+	}
+	if len(override.Headers) > 0 {
+		headers := make(map[string]string, len(base.Headers)+len(override.Headers))
+		for k, v := range base.Headers {
+			headers[k] = v
+		}
+		for k, v := range override.Headers {
+			headers[k] = v
+		}
+		base.Headers = headers
+	}
+	return base
+}
+
+// mapHeaders returns a copy of headers with each value passed through fn
+func mapHeaders(headers map[string]string, fn func(string) string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	mapped := make(map[string]string, len(headers))
+	for k, v := range headers {
+		mapped[k] = fn(v)
+	}
+	return mapped
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Keys are dotted paths into the config using the file's key names, e.g.
+// "worktree_dir", "providers.anthropic.api_key" or
+// "providers.openai.models.0.id". Map entries are addressed by name and
+// list items by index. Key names match in any case; map entries, such as
+// provider and profile names, only in their own.
+
+// CanonicalKey returns key with its key names in the file's case, leaving
+// map entries as given. A key that names no field is returned unchanged.
+func CanonicalKey(key string) string {
+	parts, err := splitKey(key)
+	if err != nil {
+		return key
+	}
+	if _, err := fieldType(parts); err != nil {
+		return key
+	}
+	return strings.Join(parts, ".")
+}
+
+// Get returns the value at key, or nil if the key is valid but not set
+func (c *Config) Get(key string) (interface{}, error) {
+	parts, err := splitKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fieldType(parts); err != nil {
+		return nil, err
+	}
+
+	tree, err := c.toTree()
+	if err != nil {
+		return nil, err
+	}
+
+	var current interface{} = tree
+	for _, part := range parts {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[part]
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("invalid index '%s' in '%s'", part, key)
+			}
+			current = node[i]
+		default:
+			return nil, nil
+		}
+	}
+	return current, nil
+}
+
+// Set parses value according to the type of the field at key and stores
+// it, creating intermediate map entries (e.g. a new provider) as needed.
+// Struct, map and list values are given as JSON.
+func (c *Config) Set(key, value string) error {
+	parts, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+
+	t, err := fieldType(parts)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseValue(value, t)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	return c.update(parts, func(parent interface{}, last string) error {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[last] = parsed
+		case []interface{}:
+			i, _ := strconv.Atoi(last)
+			if i < 0 || i >= len(node) {
+				return fmt.Errorf("index %d out of range in '%s'", i, key)
+			}
+			node[i] = parsed
+		}
+		return nil
+	})
+}
+
+// Unset reverts key to its built-in default, removing it if there is no
+// default
+func (c *Config) Unset(key string) error {
+	parts, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+	if _, err := fieldType(parts); err != nil {
+		return err
+	}
+
+	def, _ := getDefaultConfig().Get(key)
+
+	return c.update(parts, func(parent interface{}, last string) error {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if def != nil {
+				node[last] = def
+			} else {
+				delete(node, last)
+			}
+		case []interface{}:
+			return fmt.Errorf("cannot unset list item '%s'; edit the list instead", key)
+		}
+		return nil
+	})
+}
+
+// Keys lists the dotted paths of the settable leaf values in c, used for
+// shell completion
+func (c *Config) Keys() []string {
+	tree, err := c.toTree()
+	if err != nil {
+		return nil
+	}
+
+	var keys []string
+	var walk func(prefix string, t reflect.Type, node interface{})
+	walk = func(prefix string, t reflect.Type, node interface{}) {
+		switch t.Kind() {
+		case reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				name := jsonName(t.Field(i))
+				if name == "" {
+					continue
+				}
+				var child interface{}
+				if m, ok := node.(map[string]interface{}); ok {
+					child = m[name]
+				}
+				walk(joinKey(prefix, name), t.Field(i).Type, child)
+			}
+		case reflect.Map:
+			if m, ok := node.(map[string]interface{}); ok {
+				for name, child := range m {
+					walk(joinKey(prefix, name), t.Elem(), child)
+				}
+			}
+		case reflect.Slice:
+			if items, ok := node.([]interface{}); ok {
+				for i, child := range items {
+					walk(joinKey(prefix, strconv.Itoa(i)), t.Elem(), child)
+				}
+			}
+		default:
+			keys = append(keys, prefix)
+		}
+	}
+	walk("", reflect.TypeOf(Config{}), tree)
+
+	sort.Strings(keys)
+	return keys
+}
+
+// update applies fn to the parent of the node at parts and decodes the
+// result back into c. Unknown struct keys are rejected by decoding with
+// DisallowUnknownFields.
+func (c *Config) update(parts []string, fn func(parent interface{}, last string) error) error {
+	tree, err := c.toTree()
+	if err != nil {
+		return err
+	}
+
+	var parent interface{} = tree
+	for _, part := range parts[:len(parts)-1] {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				if items, isList := node[part].([]interface{}); isList {
+					parent = items
+					continue
+				}
+				child = map[string]interface{}{}
+				node[part] = child
+			}
+			parent = child
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return fmt.Errorf("invalid index '%s'", part)
+			}
+			parent = node[i]
+		}
+	}
+
+	if err := fn(parent, parts[len(parts)-1]); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+
+	var updated Config
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&updated); err != nil {
+		return err
+	}
+
+	updated.RepoConfigPath = c.RepoConfigPath
+	updated.RepoHooks = c.RepoHooks
+	updated.ActiveProfile = c.ActiveProfile
+	updated.EnvOverrides = c.EnvOverrides
+	*c = updated
+	return nil
+}
+
+// toTree converts c into a generic map keyed by the json key names
+func (c *Config) toTree() (map[string]interface{}, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// fieldType returns the Go type of the field at parts, or an error if the
+// path does not name a config field. Key names are matched ignoring case
+// and rewritten in parts as the file spells them.
+func fieldType(parts []string) (reflect.Type, error) {
+	t := reflect.TypeOf(Config{})
+	for i, part := range parts {
+		switch t.Kind() {
+		case reflect.Struct:
+			name, field, ok := structField(t, part)
+			if !ok {
+				return nil, fmt.Errorf("unknown key '%s'", strings.Join(parts[:i+1], "."))
+			}
+			parts[i] = name
+			t = field
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Slice:
+			if _, err := strconv.Atoi(part); err != nil {
+				return nil, fmt.Errorf("'%s' is a list; expected an index", strings.Join(parts[:i], "."))
+			}
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("'%s' is not an object", strings.Join(parts[:i], "."))
+		}
+	}
+	return t, nil
+}
+
+// structField returns the key name and type of the field of t named name
+// in any case
+func structField(t reflect.Type, name string) (string, reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if field := jsonName(t.Field(i)); field != "" && strings.EqualFold(field, name) {
+			return field, t.Field(i).Type, true
+		}
+	}
+	return "", nil, false
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// parseValue converts a command-line string into a value of kind t
+func parseValue(value string, t reflect.Type) (interface{}, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer, got '%s'", value)
+		}
+		return i, nil
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got '%s'", value)
+		}
+		return f, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got '%s'", value)
+		}
+		return b, nil
+	default:
+		// A plain string sets a list of strings to that single item
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			return []interface{}{value}, nil
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return nil, fmt.Errorf("expected a JSON value: %w", err)
+		}
+		return v, nil
+	}
+}
+
+func splitKey(key string) ([]string, error) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("invalid key '%s'", key)
+		}
+	}
+	return parts, nil
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,93 @@
+package config
+
+import "testing"
+
+func TestConfigGetSetUnset(t *testing.T) {
+	cfg := getDefaultConfig()
+
+	if err := cfg.Set("worktree_dir", "wt"); err != nil {
+		t.Fatalf("Set worktree_dir failed: %v", err)
+	}
+	if cfg.WorktreeDir != "wt" {
+		t.Errorf("WorktreeDir = %q, want wt", cfg.WorktreeDir)
+	}
+
+	if err := cfg.Set("providers.openai.models.0.temperature", "0.5"); err != nil {
+		t.Fatalf("Set temperature failed: %v", err)
+	}
+	if got := cfg.Providers["openai"].Models[0].Temperature; got != 0.5 {
+		t.Errorf("temperature = %v, want 0.5", got)
+	}
+
+	if err := cfg.Set("providers.local.base_url", "http://localhost:8080"); err != nil {
+		t.Fatalf("Set new provider failed: %v", err)
+	}
+	if got, _ := cfg.Get("providers.local.base_url"); got != "http://localhost:8080" {
+		t.Errorf("Get base_url = %v", got)
+	}
+
+	if err := cfg.Set("hooks.post_add", "npm install"); err != nil {
+		t.Fatalf("Set hook failed: %v", err)
+	}
+	if got := cfg.HookCommands("post_add"); len(got) != 1 || got[0] != "npm install" {
+		t.Errorf("post_add hooks = %v, want [npm install]", got)
+	}
+	if err := cfg.Set("worktree_sparse", `["apps/web", "libs"]`); err != nil || len(cfg.WorktreeSparse) != 2 {
+		t.Errorf("Set worktree_sparse = %v, %v", cfg.WorktreeSparse, err)
+	}
+
+	if err := cfg.Set("providers.openai.max_tokens", "lots"); err == nil {
+		t.Error("expected error setting an int to a string")
+	}
+	if err := cfg.Set("providers.openai.modles", "x"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+	if _, err := cfg.Get("providers.openai.models.x"); err == nil {
+		t.Error("expected error for non-numeric list index")
+	}
+
+	cfg.Providers["anthropic"] = Provider{Type: "anthropic", BaseURL: "http://proxy"}
+	if err := cfg.Unset("providers.anthropic.base_url"); err != nil {
+		t.Fatalf("Unset failed: %v", err)
+	}
+	if got := cfg.Providers["anthropic"].BaseURL; got != "https://api.anthropic.com/v1" {
+		t.Errorf("base_url after unset = %q, want default", got)
+	}
+
+	if err := cfg.Unset("worktree_dir"); err != nil {
+		t.Fatalf("Unset worktree_dir failed: %v", err)
+	}
+	if got, _ := cfg.Get("worktree_dir"); got != nil {
+		t.Errorf("worktree_dir after unset = %v, want nil", got)
+	}
+}
+
+func TestMixedCaseProviderKey(t *testing.T) {
+	cfg := getDefaultConfig()
+
+	if err := cfg.Set("Providers.MyLocal.Type", "openai"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := cfg.Set("providers.MyLocal.base_url", "http://localhost:8080"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, ok := cfg.Providers["mylocal"]; ok {
+		t.Error("provider name was lowercased")
+	}
+	if p := cfg.Providers["MyLocal"]; p.Type != "openai" || p.BaseURL != "http://localhost:8080" {
+		t.Errorf("MyLocal = %+v", p)
+	}
+	if got, _ := cfg.Get("PROVIDERS.MyLocal.BASE_URL"); got != "http://localhost:8080" {
+		t.Errorf("Get base_url = %v", got)
+	}
+	if got := CanonicalKey("Providers.MyLocal.Base_URL"); got != "providers.MyLocal.base_url" {
+		t.Errorf("CanonicalKey = %q", got)
+	}
+
+	if err := cfg.Unset("providers.MyLocal.base_url"); err != nil {
+		t.Fatalf("Unset failed: %v", err)
+	}
+	if got := cfg.Providers["MyLocal"].BaseURL; got != "" {
+		t.Errorf("base_url after unset = %q", got)
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// KeyringPrefix marks an API key stored in the OS keyring, e.g.
+// "api_key": "keyring:anthropic"
+const KeyringPrefix = "keyring:"
+
+// keyringService is the service name secrets are stored under
+const keyringService = "lazywork"
+
+// KeyringRef returns the config reference for a keyring entry
+func KeyringRef(name string) string {
+	return KeyringPrefix + name
+}
+
+// SetKeyringSecret stores a secret in the OS keyring (macOS Keychain,
+// Secret Service on Linux, Windows Credential Manager)
+func SetKeyringSecret(name, secret string) error {
+	if err := keyring.Set(keyringService, name, secret); err != nil {
+		return fmt.Errorf("failed to store secret in keyring: %w", err)
+	}
+	return nil
+}
+
+// GetKeyringSecret reads a secret from the OS keyring
+func GetKeyringSecret(name string) (string, error) {
+	secret, err := keyring.Get(keyringService, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("no secret named '%s' in keyring", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from keyring: %w", err)
+	}
+	return secret, nil
+}
+
+// DeleteKeyringSecret removes a secret from the OS keyring
+func DeleteKeyringSecret(name string) error {
+	err := keyring.Delete(keyringService, name)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to delete secret from keyring: %w", err)
+	}
+	return nil
+}
+
+// MaskSecret hides all but the first few characters of a secret for
+// display. Unresolved references ($VAR, keyring:NAME) are not secret and
+// are returned unchanged.
+func MaskSecret(secret string) string {
+	if secret == "" || strings.HasPrefix(secret, "$") || strings.HasPrefix(secret, KeyringPrefix) {
+		return secret
+	}
+	if len(secret) <= 8 {
+		return "********"
+	}
+	return secret[:4] + "…" + "****"
+}
+
+// Redacted returns a copy of c with API keys and tokens masked, safe to
+// print
+func (c *Config) Redacted() *Config {
+	copied := *c
+	if c.Forges != nil {
+		copied.Forges = make(map[string]ForgeConfig, len(c.Forges))
+		for name, f := range c.Forges {
+			f.Token = MaskSecret(f.Token)
+			copied.Forges[name] = f
+		}
+	}
+	if c.Tickets != nil {
+		copied.Tickets = make(map[string]TicketConfig, len(c.Tickets))
+		for name, t := range c.Tickets {
+			t.Token = MaskSecret(t.Token)
+			copied.Tickets[name] = t
+		}
+	}
+	copied.Providers = make(map[string]Provider, len(c.Providers))
+	for name, p := range c.Providers {
+		p.APIKey = MaskSecret(p.APIKey)
+		p.HTTP.Headers = mapHeaders(p.HTTP.Headers, MaskSecret)
+		copied.Providers[name] = p
+	}
+	copied.Profiles = make(map[string]Profile, len(c.Profiles))
+	for name, profile := range c.Profiles {
+		providers := make(map[string]Provider, len(profile.Providers))
+		for pname, p := range profile.Providers {
+			p.APIKey = MaskSecret(p.APIKey)
+			p.HTTP.Headers = mapHeaders(p.HTTP.Headers, MaskSecret)
+			providers[pname] = p
+		}
+		profile.Providers = providers
+		copied.Profiles[name] = profile
+	}
+	return &copied
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestResolveAPIKeyFromKeyring(t *testing.T) {
+	keyring.MockInit()
+
+	if err := SetKeyringSecret("anthropic", "sk-ant-secret"); err != nil {
+		t.Fatalf("SetKeyringSecret failed: %v", err)
+	}
+
+	if got := ResolveAPIKey(KeyringRef("anthropic")); got != "sk-ant-secret" {
+		t.Errorf("ResolveAPIKey(keyring:anthropic) = %q, want sk-ant-secret", got)
+	}
+	if got := ResolveAPIKey(KeyringRef("missing")); got != "" {
+		t.Errorf("ResolveAPIKey(keyring:missing) = %q, want empty", got)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := &Config{Providers: map[string]Provider{
+		"a": {APIKey: "sk-1234567890"},
+		"b": {APIKey: "$OPENAI_API_KEY"},
+		"c": {APIKey: "keyring:c"},
+	}, Forges: map[string]ForgeConfig{
+		"github": {Token: "ghp_1234567890"},
+	}, Tickets: map[string]TicketConfig{
+		"linear": {Token: "lin_api_1234567890"},
+	}}
+
+	redacted := cfg.Redacted()
+	if got := redacted.Providers["a"].APIKey; got == "sk-1234567890" {
+		t.Errorf("expected key to be masked, got %q", got)
+	}
+	if got := redacted.Providers["b"].APIKey; got != "$OPENAI_API_KEY" {
+		t.Errorf("env reference = %q, want unchanged", got)
+	}
+	if got := redacted.Providers["c"].APIKey; got != "keyring:c" {
+		t.Errorf("keyring reference = %q, want unchanged", got)
+	}
+	if got := redacted.Forges["github"].Token; got == "ghp_1234567890" {
+		t.Errorf("expected forge token to be masked, got %q", got)
+	}
+	if got := redacted.Tickets["linear"].Token; got == "lin_api_1234567890" {
+		t.Errorf("expected tracker token to be masked, got %q", got)
+	}
+	if cfg.Providers["a"].APIKey != "sk-1234567890" || cfg.Forges["github"].Token != "ghp_1234567890" {
+		t.Error("Redacted modified the original config")
+	}
+}
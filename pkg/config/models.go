@@ -0,0 +1,58 @@
+package config
+
+import "strings"
+
+// AICommands are the supported keys of the models and generation settings:
+// the commands that ask an AI provider for text, with spaces written as
+// underscores
+var AICommands = []string{
+	"commit", "log", "release", "resolve", "standup", "wip",
+	"worktree_add", "worktree_diff", "worktree_finish",
+}
+
+// Generation overrides the request settings of the model for one AI
+// command, keyed like the models setting
+type Generation struct {
+	// Temperature overrides the model's temperature when set
+	Temperature *float64 `json:"temperature,omitempty"`
+	// MaxTokens overrides the model's and provider's max_tokens
+	MaxTokens int `json:"max_tokens,omitempty"`
+}
+
+// CommandModel returns the provider and model that the models setting
+// picks for command, such as "commit" or "worktree finish", or empty
+// strings if it picks none. The provider is the default provider when it
+// lists the model, else the first other provider by name that does, else
+// the default provider.
+func (c *Config) CommandModel(command string) (provider, model string) {
+	model = c.Models[strings.ReplaceAll(command, " ", "_")]
+	if model == "" {
+		return "", ""
+	}
+	if c.providerLists(c.DefaultProvider, model) {
+		return c.DefaultProvider, model
+	}
+	for _, name := range sortedKeys(c.Providers) {
+		if c.providerLists(name, model) {
+			return name, model
+		}
+	}
+	return c.DefaultProvider, model
+}
+
+// providerLists reports whether model is among the models of the provider
+// named name
+func (c *Config) providerLists(name, model string) bool {
+	for _, m := range c.Providers[name].Models {
+		if m.ID == model {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandGeneration returns the generation settings for command, such as
+// "commit" or "worktree finish"
+func (c *Config) CommandGeneration(command string) Generation {
+	return c.Generation[strings.ReplaceAll(command, " ", "_")]
+}
@@ -0,0 +1,33 @@
+package config
+
+import "testing"
+
+func TestCommandModel(t *testing.T) {
+	cfg := &Config{
+		DefaultProvider: "openai",
+		Providers: map[string]Provider{
+			"openai":    {Models: []Model{{ID: "gpt-4o-mini"}}},
+			"anthropic": {Models: []Model{{ID: "claude-haiku-4-5"}}},
+		},
+		Models: map[string]string{
+			"commit":          "claude-haiku-4-5",
+			"worktree_finish": "gpt-4o-mini",
+			"release":         "new-model",
+		},
+	}
+
+	tests := []struct {
+		command, provider, model string
+	}{
+		{"commit", "anthropic", "claude-haiku-4-5"},
+		{"worktree finish", "openai", "gpt-4o-mini"},
+		{"release", "openai", "new-model"},
+		{"standup", "", ""},
+	}
+	for _, tt := range tests {
+		provider, model := cfg.CommandModel(tt.command)
+		if provider != tt.provider || model != tt.model {
+			t.Errorf("CommandModel(%q) = %q, %q, want %q, %q", tt.command, provider, model, tt.provider, tt.model)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile is a named set of overrides, e.g. "work" pointing at a corporate
+// endpoint and "personal" using a personal API key
+type Profile struct {
+	DefaultProvider string              `json:"default_provider,omitempty"`
+	DefaultModel    string              `json:"default_model,omitempty"`
+	Providers       map[string]Provider `json:"providers,omitempty"`
+}
+
+// ProfileNames returns the configured profile names, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyProfile overlays the named profile onto c. Provider settings in the
+// profile override the matching fields of providers with the same name;
+// providers that only exist in the profile are added.
+func (c *Config) ApplyProfile(name string) error {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return fmt.Errorf("unknown profile '%s': no profiles configured", name)
+		}
+		return fmt.Errorf("unknown profile '%s'. Available profiles: %s", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	if c.Providers == nil {
+		c.Providers = map[string]Provider{}
+	}
+	for providerName, override := range profile.Providers {
+		c.Providers[providerName] = overlayProvider(c.Providers[providerName], override)
+	}
+
+	if profile.DefaultProvider != "" {
+		if _, ok := c.Providers[profile.DefaultProvider]; !ok {
+			return fmt.Errorf("profile '%s' uses unknown provider '%s'", name, profile.DefaultProvider)
+		}
+		c.DefaultProvider = profile.DefaultProvider
+	}
+	if profile.DefaultModel != "" {
+		c.DefaultModel = profile.DefaultModel
+	}
+
+	resolveEnvironmentVariables(c)
+	c.ActiveProfile = name
+
+	return nil
+}
+
+func overlayProvider(base, override Provider) Provider {
+	if override.Type != "" {
+		base.Type = override.Type
+	}
+	if override.BaseURL != "" {
+		base.BaseURL = override.BaseURL
+	}
+	if override.APIKey != "" {
+		base.APIKey = override.APIKey
+	}
+	if len(override.Models) > 0 {
+		base.Models = override.Models
+	}
+	if override.MaxTokens != 0 {
+		base.MaxTokens = override.MaxTokens
+	}
+	base.HTTP = overlayHTTP(base.HTTP, override.HTTP)
+	if override.RateLimit != (RateLimit{}) {
+		base.RateLimit = override.RateLimit
+	}
+	return base
+}
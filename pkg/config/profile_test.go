@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestApplyProfile(t *testing.T) {
+	os.Setenv("LAZYWORK_TEST_WORK_KEY", "work-secret")
+	defer os.Unsetenv("LAZYWORK_TEST_WORK_KEY")
+
+	cfg := getDefaultConfig()
+	cfg.Profiles = map[string]Profile{
+		"work": {
+			DefaultProvider: "azure",
+			DefaultModel:    "gpt-5",
+			Providers: map[string]Provider{
+				"azure":  {Type: "openai", BaseURL: "https://corp.example.com/v1", APIKey: "$LAZYWORK_TEST_WORK_KEY"},
+				"openai": {BaseURL: "https://proxy.example.com/v1"},
+			},
+		},
+	}
+
+	if err := cfg.ApplyProfile("work"); err != nil {
+		t.Fatalf("ApplyProfile failed: %v", err)
+	}
+
+	if cfg.DefaultProvider != "azure" || cfg.DefaultModel != "gpt-5" {
+		t.Errorf("defaults = %s/%s, want azure/gpt-5", cfg.DefaultProvider, cfg.DefaultModel)
+	}
+	if got := cfg.Providers["azure"].APIKey; got != "work-secret" {
+		t.Errorf("azure api key = %q, want resolved env value", got)
+	}
+
+	openai := cfg.Providers["openai"]
+	if openai.BaseURL != "https://proxy.example.com/v1" {
+		t.Errorf("openai base_url = %q, want profile override", openai.BaseURL)
+	}
+	if openai.Type != "openai" || len(openai.Models) == 0 {
+		t.Errorf("openai fields not preserved: %+v", openai)
+	}
+	if cfg.ActiveProfile != "work" {
+		t.Errorf("ActiveProfile = %q", cfg.ActiveProfile)
+	}
+}
+
+func TestApplyUnknownProfile(t *testing.T) {
+	cfg := getDefaultConfig()
+	if err := cfg.ApplyProfile("missing"); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+
+	cfg.Profiles = map[string]Profile{"bad": {DefaultProvider: "nope"}}
+	if err := cfg.ApplyProfile("bad"); err == nil {
+		t.Error("expected error for profile with unknown provider")
+	}
+}
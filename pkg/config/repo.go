@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RepoConfigFile is the per-repository config file, discovered at the
+// repository root and meant to be committed and shared by a team
+const RepoConfigFile = ".lazywork.json"
+
+// repoConfigFiles lists the accepted per-repository config names in
+// lookup order
+var repoConfigFiles = []string{RepoConfigFile, ".lazywork.yaml", ".lazywork.yml", ".lazywork.toml"}
+
+// LoadRepo reads the per-repository config from root. It returns nil
+// without error if the repository has no config file.
+func LoadRepo(root string) (*Config, error) {
+	for _, name := range repoConfigFiles {
+		path := filepath.Join(root, name)
+
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read repo config: %w", err)
+		}
+
+		var cfg Config
+		if err := unmarshalConfig(data, FormatFromPath(path), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		cfg.RepoConfigPath = path
+
+		return &cfg, nil
+	}
+
+	return nil, nil
+}
+
+// Merge overlays the settings of a per-repository config onto c.
+//
+// Providers, forges and ticket trackers are deliberately never taken from
+// the repository: a committed file must not be able to redirect a user's
+// API keys or tokens to another base URL. The repository may only pick
+// which of the user's providers is the default and which kind of forge it
+// is hosted on. Neither is envrc_template, whose .envrc 'worktree add'
+// allows with direnv, as that would run the repository's shell code in
+// the user's environment. worktree_dir is only taken when it stays inside
+// the repository, as 'worktree add' and 'worktree remove' create and
+// delete directories under it.
+func (c *Config) Merge(repo *Config) {
+	if repo == nil {
+		return
+	}
+
+	if repo.DefaultProvider != "" {
+		if _, ok := c.Providers[repo.DefaultProvider]; ok {
+			c.DefaultProvider = repo.DefaultProvider
+		}
+	}
+	if repo.WorktreeDir != "" && filepath.IsLocal(repo.WorktreeDir) {
+		c.WorktreeDir = repo.WorktreeDir
+	}
+	if repo.MainBranch != "" {
+		c.MainBranch = repo.MainBranch
+	}
+	if repo.Forge != "" {
+		c.Forge = repo.Forge
+	}
+	if repo.WorktreeSubmodules {
+		c.WorktreeSubmodules = true
+	}
+	if len(repo.WorktreeSparse) > 0 {
+		c.WorktreeSparse = repo.WorktreeSparse
+	}
+	if repo.WorktreePush {
+		c.WorktreePush = true
+	}
+	if repo.WorktreeIncludeMain {
+		c.WorktreeIncludeMain = true
+	}
+	if repo.WorktreeArchive {
+		c.WorktreeArchive = true
+	}
+	if repo.WorktreeStaleAfter != "" {
+		c.WorktreeStaleAfter = repo.WorktreeStaleAfter
+	}
+	if repo.PortBase > 0 {
+		c.PortBase = repo.PortBase
+	}
+	if repo.PortBlockSize > 0 {
+		c.PortBlockSize = repo.PortBlockSize
+	}
+	if repo.AutoFetch {
+		c.AutoFetch = true
+	}
+	// Repository hooks are kept apart: they run after the user's own hooks
+	// for the same event, and only once the user trusts them
+	for event, commands := range repo.Hooks {
+		if c.RepoHooks == nil {
+			c.RepoHooks = map[string][]string{}
+		}
+		c.RepoHooks[event] = append(c.RepoHooks[event], commands...)
+	}
+	if repo.Commit.Language != "" {
+		c.Commit.Language = repo.Commit.Language
+	}
+	if repo.Commit.Tone != "" {
+		c.Commit.Tone = repo.Commit.Tone
+	}
+	if repo.Commit.MaxSubjectLength > 0 {
+		c.Commit.MaxSubjectLength = repo.Commit.MaxSubjectLength
+	}
+	if repo.Commit.Emoji {
+		c.Commit.Emoji = true
+	}
+	if repo.Commit.Examples > 0 {
+		c.Commit.Examples = repo.Commit.Examples
+	}
+	if repo.Commit.NoExamples {
+		c.Commit.NoExamples = true
+	}
+	for name, rule := range repo.Commit.Lint {
+		if c.Commit.Lint == nil {
+			c.Commit.Lint = map[string][]interface{}{}
+		}
+		c.Commit.Lint[name] = rule
+	}
+	if repo.Context.Disabled {
+		c.Context.Disabled = true
+	}
+	if repo.Context.MaxTokens > 0 {
+		c.Context.MaxTokens = repo.Context.MaxTokens
+	}
+	// Repository redact patterns add to the user's; none can be removed
+	c.Redact.Patterns = append(c.Redact.Patterns, repo.Redact.Patterns...)
+
+	c.RepoConfigPath = repo.RepoConfigPath
+}
+
+// LoadWithRepo loads the user config (see LoadFrom) and merges the
+// per-repository config found in repoRoot over it. An empty repoRoot
+// skips the repository config.
+func LoadWithRepo(customPath, repoRoot string) (*Config, error) {
+	cfg, err := LoadFrom(customPath)
+	if err != nil {
+		return nil, err
+	}
+	if repoRoot == "" {
+		return cfg, nil
+	}
+
+	repo, err := LoadRepo(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Merge(repo)
+
+	return cfg, nil
+}
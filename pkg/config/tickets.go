@@ -0,0 +1,20 @@
+package config
+
+// TicketTrackers are the issue trackers understood by internal/tickets
+var TicketTrackers = []string{"linear", "jira"}
+
+// TicketConfig holds the settings of an issue tracker whose ticket keys,
+// such as ENG-123, appear in branch names. Like forges, trackers are only
+// read from the user config.
+type TicketConfig struct {
+	// Token is an API token, or a $ENV or keyring: reference to one
+	Token string `json:"token,omitempty"`
+	// Email is the account the Jira Cloud token belongs to; without it
+	// the token is sent as a bearer token (Jira Data Center)
+	Email string `json:"email,omitempty"`
+	// BaseURL is the Jira site, e.g. https://acme.atlassian.net, or
+	// overrides the Linear API endpoint
+	BaseURL string `json:"base_url,omitempty"`
+	// Projects limits the tracker to keys with these prefixes, e.g. ENG
+	Projects []string `json:"projects,omitempty"`
+}
@@ -0,0 +1,458 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a single problem found while validating a config
+type Issue struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
+// HasErrors returns true if any issue has error severity
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// providerTypes are the provider types understood by pkg/provider
+var providerTypes = []string{"openai", "anthropic", "ollama", "mock"}
+
+// ValidateFile checks the config file at path for unknown keys and invalid
+// settings. A missing file is not an error: the defaults are used instead.
+func ValidateFile(path string) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	format := FormatFromPath(path)
+
+	var raw map[string]interface{}
+	if err := unmarshalConfig(data, format, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	var cfg Config
+	if err := unmarshalConfig(data, format, &cfg); err != nil {
+		return []Issue{{SeverityError, "", fmt.Sprintf("invalid value: %v", err)}}, nil
+	}
+
+	issues := unknownKeys(raw, reflect.TypeOf(Config{}), "")
+	issues = append(issues, cfg.Validate()...)
+	return issues, nil
+}
+
+// Validate checks a loaded (unresolved) config for settings that would
+// fail at runtime or silently fall back to defaults
+func (c *Config) Validate() []Issue {
+	var issues []Issue
+	add := func(severity, path, format string, args ...interface{}) {
+		issues = append(issues, Issue{severity, path, fmt.Sprintf(format, args...)})
+	}
+
+	if len(c.Providers) == 0 {
+		add(SeverityError, "providers", "no providers configured")
+	}
+
+	if c.DefaultProvider == "" {
+		add(SeverityError, "default_provider", "not set")
+	} else if _, ok := c.Providers[c.DefaultProvider]; !ok {
+		add(SeverityError, "default_provider", "unknown provider '%s'", c.DefaultProvider)
+	} else if c.DefaultModel != "" && !hasModel(c.Providers[c.DefaultProvider], c.DefaultModel) {
+		add(SeverityWarning, "default_model", "model '%s' is not listed for provider '%s'", c.DefaultModel, c.DefaultProvider)
+	}
+
+	if _, err := c.GetGitTimeout(); err != nil {
+		add(SeverityError, "git_timeout", "not a valid duration such as 30s or 2m")
+	}
+	if _, err := c.GetStaleAfter(); err != nil {
+		add(SeverityError, "worktree_stale_after", "not a valid age such as 30d or 2w")
+	}
+
+	if c.Budget.Monthly < 0 {
+		add(SeverityError, "budget.monthly", "must not be negative")
+	}
+	if a := c.Budget.Action; a != "" && a != BudgetWarn && a != BudgetBlock {
+		add(SeverityError, "budget.action", "unknown action '%s' (%s, %s)", a, BudgetWarn, BudgetBlock)
+	}
+
+	if n := c.Commit.MaxSubjectLength; n < 0 {
+		add(SeverityError, "commit.max_subject_length", "must not be negative")
+	} else if n > 0 && n < 20 {
+		add(SeverityWarning, "commit.max_subject_length", "%d characters leaves little room for a useful subject", n)
+	}
+
+	if c.Commit.Examples < 0 {
+		add(SeverityError, "commit.examples", "must not be negative; set commit.no_examples to leave the examples out")
+	}
+
+	for _, name := range sortedKeys(c.Commit.Lint) {
+		rule := c.Commit.Lint[name]
+		if level, ok := firstNumber(rule); !ok || level < 0 || level > 2 {
+			add(SeverityError, "commit.lint."+name, "must start with a level: 0 (off), 1 (warning) or 2 (error)")
+		} else if len(rule) > 1 && rule[1] != "always" && rule[1] != "never" {
+			add(SeverityError, "commit.lint."+name, "second item must be \"always\" or \"never\"")
+		}
+	}
+
+	if c.Context.MaxTokens < 0 {
+		add(SeverityError, "context.max_tokens", "must not be negative; set context.disabled to leave the context out")
+	}
+
+	for _, command := range sortedKeys(c.Models) {
+		if !contains(AICommands, command) {
+			add(SeverityWarning, "models."+command, "unknown command; supported: %s", strings.Join(AICommands, ", "))
+		} else if c.Models[command] == "" {
+			add(SeverityError, "models."+command, "model must not be empty")
+		}
+	}
+
+	for _, command := range sortedKeys(c.Generation) {
+		g := c.Generation[command]
+		if !contains(AICommands, command) {
+			add(SeverityWarning, "generation."+command, "unknown command; supported: %s", strings.Join(AICommands, ", "))
+		}
+		if t := g.Temperature; t != nil && (*t < 0 || *t > 2) {
+			add(SeverityError, "generation."+command+".temperature", "must be between 0 and 2")
+		}
+		if g.MaxTokens < 0 {
+			add(SeverityError, "generation."+command+".max_tokens", "must not be negative")
+		}
+	}
+
+	for i, pattern := range c.Redact.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			add(SeverityError, fmt.Sprintf("redact.patterns[%d]", i), "invalid regular expression: %v", err)
+		}
+	}
+
+	for _, hooks := range []map[string][]string{c.Hooks, c.RepoHooks} {
+		for _, event := range sortedKeys(hooks) {
+			if !IsHookEvent(event) {
+				add(SeverityWarning, "hooks."+event, "unknown hook; supported: %s", strings.Join(HookEvents, ", "))
+			}
+		}
+	}
+
+	if c.Forge != "" && !contains(ForgeTypes, c.Forge) {
+		add(SeverityError, "forge", "unsupported forge '%s' (%s)", c.Forge, strings.Join(ForgeTypes, ", "))
+	}
+	for _, name := range sortedKeys(c.Forges) {
+		issues = append(issues, validateForge("forges."+name, name, c.Forges[name])...)
+	}
+	for _, name := range sortedKeys(c.Tickets) {
+		issues = append(issues, validateTicketTracker("tickets."+name, name, c.Tickets[name])...)
+	}
+
+	if c.PortBase < 0 || c.PortBase > 65535 {
+		add(SeverityError, "port_base", "must be a port between 1 and 65535")
+	}
+	if c.PortBlockSize < 0 {
+		add(SeverityError, "port_block_size", "must not be negative")
+	}
+
+	for i, dir := range c.WorktreeSparse {
+		clean := filepath.ToSlash(filepath.Clean(dir))
+		if filepath.IsAbs(dir) || clean == ".." || strings.HasPrefix(clean, "../") {
+			add(SeverityError, fmt.Sprintf("worktree_sparse[%d]", i), "'%s' must be a directory inside the repository", dir)
+		}
+	}
+
+	for _, name := range sortedKeys(c.Providers) {
+		issues = append(issues, validateProvider("providers."+name, c.Providers[name], true)...)
+	}
+
+	for _, name := range c.ProfileNames() {
+		profile := c.Profiles[name]
+		path := "profiles." + name
+
+		if profile.DefaultProvider != "" {
+			_, inBase := c.Providers[profile.DefaultProvider]
+			_, inProfile := profile.Providers[profile.DefaultProvider]
+			if !inBase && !inProfile {
+				add(SeverityError, path+".default_provider", "unknown provider '%s'", profile.DefaultProvider)
+			}
+		}
+
+		for _, pname := range sortedKeys(profile.Providers) {
+			_, overlay := c.Providers[pname]
+			issues = append(issues, validateProvider(path+".providers."+pname, profile.Providers[pname], !overlay)...)
+		}
+	}
+
+	return issues
+}
+
+// validateProvider checks a single provider. Profile providers that overlay
+// an existing provider only need the fields they override, so complete is
+// false for them.
+func validateProvider(path string, p Provider, complete bool) []Issue {
+	var issues []Issue
+	add := func(severity, field, format string, args ...interface{}) {
+		issues = append(issues, Issue{severity, path + field, fmt.Sprintf(format, args...)})
+	}
+
+	if p.Type == "" {
+		if complete {
+			add(SeverityError, ".type", "missing provider type (%s)", strings.Join(providerTypes, ", "))
+		}
+	} else if !contains(providerTypes, p.Type) {
+		add(SeverityError, ".type", "unsupported provider type '%s' (%s)", p.Type, strings.Join(providerTypes, ", "))
+	}
+
+	// Mock providers answer locally and need neither a URL nor a key
+	if p.Type == "mock" {
+		complete = false
+	}
+
+	if p.BaseURL == "" {
+		if complete {
+			add(SeverityError, ".base_url", "missing base URL")
+		}
+	} else if !strings.HasPrefix(p.BaseURL, "http://") && !strings.HasPrefix(p.BaseURL, "https://") {
+		add(SeverityError, ".base_url", "'%s' is not an http(s) URL", p.BaseURL)
+	}
+
+	switch {
+	case p.APIKey == "":
+		// Ollama runs locally without keys
+		if complete && p.Type != "ollama" {
+			add(SeverityWarning, ".api_key", "no API key configured")
+		}
+	case strings.HasPrefix(p.APIKey, "$"):
+		if _, ok := os.LookupEnv(p.APIKey[1:]); !ok {
+			add(SeverityError, ".api_key", "environment variable %s is not set", p.APIKey[1:])
+		}
+	case strings.HasPrefix(p.APIKey, KeyringPrefix):
+		if _, err := GetKeyringSecret(strings.TrimPrefix(p.APIKey, KeyringPrefix)); err != nil {
+			add(SeverityError, ".api_key", "%v", err)
+		}
+	default:
+		add(SeverityWarning, ".api_key", "stored in plaintext; consider $ENV or 'config set-key'")
+	}
+
+	for i, m := range p.Models {
+		if m.ID == "" {
+			add(SeverityError, fmt.Sprintf(".models[%d].id", i), "model has no id")
+		}
+	}
+
+	if p.HTTP.ConnectTimeout != "" {
+		if _, err := time.ParseDuration(p.HTTP.ConnectTimeout); err != nil {
+			add(SeverityError, ".http.connect_timeout", "not a valid duration such as 10s")
+		}
+	}
+	if p.HTTP.Timeout != "" {
+		if _, err := time.ParseDuration(p.HTTP.Timeout); err != nil {
+			add(SeverityError, ".http.timeout", "not a valid duration such as 2m")
+		}
+	}
+	if p.HTTP.Proxy != "" {
+		if u, err := url.Parse(p.HTTP.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			add(SeverityError, ".http.proxy", "'%s' is not a proxy URL such as http://proxy:8080", p.HTTP.Proxy)
+		}
+	}
+	if p.HTTP.CACert != "" {
+		if _, err := os.Stat(p.HTTP.CACertPath()); err != nil {
+			add(SeverityError, ".http.ca_cert", "cannot read %s", p.HTTP.CACert)
+		}
+	}
+	for _, limit := range []struct {
+		field string
+		n     int
+	}{
+		{"requests_per_minute", p.RateLimit.RequestsPerMinute},
+		{"tokens_per_minute", p.RateLimit.TokensPerMinute},
+		{"max_concurrent", p.RateLimit.MaxConcurrent},
+	} {
+		if limit.n < 0 {
+			add(SeverityError, ".rate_limit."+limit.field, "must not be negative")
+		}
+	}
+	if p.MaxEventSize < 0 {
+		add(SeverityError, ".max_event_size", "must not be negative")
+	}
+	for name, value := range p.HTTP.Headers {
+		if strings.HasPrefix(value, "$") {
+			if _, ok := os.LookupEnv(value[1:]); !ok {
+				add(SeverityError, ".http.headers."+name, "environment variable %s is not set", value[1:])
+			}
+		}
+	}
+
+	return issues
+}
+
+// validateForge checks the settings of a single forge
+func validateForge(path, name string, f ForgeConfig) []Issue {
+	var issues []Issue
+	add := func(severity, field, format string, args ...interface{}) {
+		issues = append(issues, Issue{severity, path + field, fmt.Sprintf(format, args...)})
+	}
+
+	if !contains(ForgeTypes, name) {
+		add(SeverityError, "", "unsupported forge '%s' (%s)", name, strings.Join(ForgeTypes, ", "))
+	}
+	if f.BaseURL != "" && !strings.HasPrefix(f.BaseURL, "http://") && !strings.HasPrefix(f.BaseURL, "https://") {
+		add(SeverityError, ".base_url", "'%s' is not an http(s) URL", f.BaseURL)
+	}
+
+	issues = append(issues, validateToken(path+".token", f.Token, "--forge")...)
+
+	return issues
+}
+
+// validateTicketTracker checks the settings of a single ticket tracker
+func validateTicketTracker(path, name string, t TicketConfig) []Issue {
+	var issues []Issue
+	add := func(severity, field, format string, args ...interface{}) {
+		issues = append(issues, Issue{severity, path + field, fmt.Sprintf(format, args...)})
+	}
+
+	if !contains(TicketTrackers, name) {
+		add(SeverityError, "", "unsupported ticket tracker '%s' (%s)", name, strings.Join(TicketTrackers, ", "))
+	}
+	switch {
+	case t.BaseURL == "":
+		if name == "jira" {
+			add(SeverityError, ".base_url", "missing Jira site URL, e.g. https://acme.atlassian.net")
+		}
+	case !strings.HasPrefix(t.BaseURL, "http://") && !strings.HasPrefix(t.BaseURL, "https://"):
+		add(SeverityError, ".base_url", "'%s' is not an http(s) URL", t.BaseURL)
+	}
+	issues = append(issues, validateToken(path+".token", t.Token, "--tracker")...)
+
+	return issues
+}
+
+// validateToken checks a token that may be a $ENV or keyring: reference;
+// flag is the 'config set-key' flag that stores it in the keyring
+func validateToken(path, token, flag string) []Issue {
+	switch {
+	case token == "":
+	case strings.HasPrefix(token, "$"):
+		if _, ok := os.LookupEnv(token[1:]); !ok {
+			return []Issue{{SeverityError, path, fmt.Sprintf("environment variable %s is not set", token[1:])}}
+		}
+	case strings.HasPrefix(token, KeyringPrefix):
+		if _, err := GetKeyringSecret(strings.TrimPrefix(token, KeyringPrefix)); err != nil {
+			return []Issue{{SeverityError, path, err.Error()}}
+		}
+	default:
+		return []Issue{{SeverityWarning, path, fmt.Sprintf("stored in plaintext; consider $ENV or 'config set-key %s'", flag)}}
+	}
+	return nil
+}
+
+// unknownKeys reports keys in raw that don't match a json tag of t,
+// recursing into nested structs, maps and slices
+func unknownKeys(raw map[string]interface{}, t reflect.Type, prefix string) []Issue {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+
+	var issues []Issue
+	for _, key := range sortedKeys(raw) {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		ft, ok := fields[key]
+		if !ok {
+			issues = append(issues, Issue{SeverityError, path, "unknown key"})
+			continue
+		}
+		issues = append(issues, unknownKeysIn(raw[key], ft, path)...)
+	}
+	return issues
+}
+
+func unknownKeysIn(value interface{}, t reflect.Type, path string) []Issue {
+	var issues []Issue
+	switch t.Kind() {
+	case reflect.Struct:
+		if m, ok := value.(map[string]interface{}); ok {
+			issues = append(issues, unknownKeys(m, t, path)...)
+		}
+	case reflect.Map:
+		if m, ok := value.(map[string]interface{}); ok {
+			for _, k := range sortedKeys(m) {
+				issues = append(issues, unknownKeysIn(m[k], t.Elem(), path+"."+k)...)
+			}
+		}
+	case reflect.Slice:
+		if items, ok := value.([]interface{}); ok {
+			for i, item := range items {
+				issues = append(issues, unknownKeysIn(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return issues
+}
+
+func firstNumber(items []interface{}) (float64, bool) {
+	if len(items) == 0 {
+		return 0, false
+	}
+	n, ok := items[0].(float64)
+	return n, ok
+}
+
+func hasModel(p Provider, id string) bool {
+	for _, m := range p.Models {
+		if m.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
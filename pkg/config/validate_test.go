@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{
+  "default_provider": "anthorpic",
+  "worktre_dir": "wt",
+  "git_timeout": "soon",
+  "worktree_stale_after": "a month",
+  "worktree_sparse": ["apps/web", "../shared"],
+  "hooks": {"post_add": ["npm install"], "post_merge": ["make"]},
+  "forge": "bitbucket",
+  "forges": {"github": {"token": "ghp_plaintext", "base_url": "api.github.com"}},
+  "tickets": {"jira": {"token": "$LAZYWORK_TEST_UNSET_KEY", "projects": ["PROJ"]}, "youtrack": {}},
+  "providers": {
+    "openai": {
+      "type": "openai",
+      "api_key": "$LAZYWORK_TEST_UNSET_KEY",
+      "http": {"timeout": "soon", "proxy": "proxy:8080", "headers": {"OpenAI-Organization": "org-1"}, "retries": 3},
+      "models": [{"name": "no id", "context_window": 1, "tempreature": 0.2}]
+    }
+  }
+}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("ValidateFile failed: %v", err)
+	}
+
+	want := map[string]bool{
+		"worktre_dir":                            true,
+		"git_timeout":                            true,
+		"worktree_stale_after":                   true,
+		"worktree_sparse[1]":                     true,
+		"hooks.post_merge":                       true,
+		"forge":                                  true,
+		"forges.github.token":                    true,
+		"forges.github.base_url":                 true,
+		"tickets.jira.base_url":                  true,
+		"tickets.jira.token":                     true,
+		"tickets.youtrack":                       true,
+		"default_provider":                       true,
+		"providers.openai.base_url":              true,
+		"providers.openai.api_key":               true,
+		"providers.openai.http.timeout":          true,
+		"providers.openai.http.proxy":            true,
+		"providers.openai.http.retries":          true,
+		"providers.openai.models[0].id":          true,
+		"providers.openai.models[0].tempreature": true,
+	}
+	for _, issue := range issues {
+		if !want[issue.Path] {
+			t.Errorf("unexpected issue %s", issue)
+		}
+		delete(want, issue.Path)
+	}
+	for path := range want {
+		t.Errorf("missing issue for %s", path)
+	}
+	if !HasErrors(issues) {
+		t.Error("expected HasErrors to be true")
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "x")
+	t.Setenv("ANTHROPIC_API_KEY", "y")
+
+	if issues := getDefaultConfig().Validate(); len(issues) != 0 {
+		t.Errorf("default config has issues: %v", issues)
+	}
+}
+
+func TestValidateMissingFile(t *testing.T) {
+	issues, err := ValidateFile(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil || len(issues) != 0 {
+		t.Errorf("ValidateFile(missing) = %v, %v; want no issues", issues, err)
+	}
+}
@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -19,24 +18,23 @@ type AnthropicProvider struct {
 	client *http.Client
 }
 
-func NewAnthropic(cfg config.Provider) *AnthropicProvider {
+// NewAnthropic returns a provider using client, or a default client if nil
+func NewAnthropic(cfg config.Provider, client *http.Client) *AnthropicProvider {
+	if client == nil {
+		client = &http.Client{}
+	}
 	return &AnthropicProvider{
 		config: cfg,
-		client: &http.Client{},
+		client: client,
 	}
 }
 
 func (p *AnthropicProvider) Complete(ctx context.Context, req types.CompletionRequest) (*types.CompletionResponse, error) {
-	messages := make([]anthropicMessage, len(req.Messages))
-	for i, msg := range req.Messages {
-		messages[i] = anthropicMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
-	}
+	system, messages := anthropicMessages(req.Messages)
 
 	payload := anthropicRequest{
 		Model:       req.Model,
+		System:      system,
 		Messages:    messages,
 		Temperature: req.Temperature,
 		MaxTokens:   req.MaxTokens,
@@ -89,16 +87,11 @@ func (p *AnthropicProvider) Complete(ctx context.Context, req types.CompletionRe
 }
 
 func (p *AnthropicProvider) Stream(ctx context.Context, req types.CompletionRequest) (<-chan types.StreamChunk, error) {
-	messages := make([]anthropicMessage, len(req.Messages))
-	for i, msg := range req.Messages {
-		messages[i] = anthropicMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
-	}
+	system, messages := anthropicMessages(req.Messages)
 
 	payload := anthropicRequest{
 		Model:       req.Model,
+		System:      system,
 		Messages:    messages,
 		Temperature: req.Temperature,
 		MaxTokens:   req.MaxTokens,
@@ -137,42 +130,65 @@ func (p *AnthropicProvider) Stream(ctx context.Context, req types.CompletionRequ
 	go func() {
 		defer close(chunks)
 		defer resp.Body.Close()
-
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := scanner.Text()
-
-			if !strings.HasPrefix(line, "data: ") {
-				continue
+		// Unblock a pending read as soon as the caller gives up
+		stop := context.AfterFunc(ctx, func() { resp.Body.Close() })
+		defer stop()
+		send := func(c types.StreamChunk) bool { return sendChunk(ctx, chunks, c) }
+
+		final := types.StreamChunk{Done: true}
+		var usage types.Usage
+
+		events := newSSEReader(resp.Body, p.config.MaxEventSize)
+		for {
+			ev, err := events.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				send(types.StreamChunk{Error: fmt.Errorf("stream reading error: %w", err)})
+				return
 			}
-
-			data := strings.TrimPrefix(line, "data: ")
 
 			var event anthropicStreamEvent
-			if err := json.Unmarshal([]byte(data), &event); err != nil {
+			if err := json.Unmarshal([]byte(ev.Data), &event); err != nil {
 				continue
 			}
+			eventType := ev.Event
+			if eventType == "" {
+				eventType = event.Type
+			}
 
-			switch event.Type {
+			switch eventType {
+			case "message_start":
+				usage.PromptTokens = event.Message.Usage.InputTokens
+				usage.CompletionTokens = event.Message.Usage.OutputTokens
 			case "content_block_delta":
 				if event.Delta.Text != "" {
-					chunks <- types.StreamChunk{Content: event.Delta.Text}
+					if !send(types.StreamChunk{Content: event.Delta.Text}) {
+						return
+					}
 				}
+			case "message_delta":
+				// Output tokens here are cumulative for the message
+				final.FinishReason = event.Delta.StopReason
+				usage.CompletionTokens = event.Usage.OutputTokens
+				usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+				final.Usage = &usage
 			case "message_stop":
-				chunks <- types.StreamChunk{Done: true}
+				send(final)
 				return
 			case "error":
-				chunks <- types.StreamChunk{
-					Error: fmt.Errorf("stream error: %s", data),
-					Done:  true,
+				msg := ev.Data
+				if event.Error.Message != "" {
+					msg = event.Error.Type + ": " + event.Error.Message
 				}
+				send(types.StreamChunk{
+					Error: fmt.Errorf("stream error: %s", msg),
+					Done:  true,
+				})
 				return
 			}
 		}
-
-		if err := scanner.Err(); err != nil {
-			chunks <- types.StreamChunk{Error: fmt.Errorf("stream reading error: %w", err)}
-		}
 	}()
 
 	return chunks, nil
@@ -190,6 +206,24 @@ func (p *AnthropicProvider) Models() []string {
 	return models
 }
 
+// anthropicMessages converts messages for the Messages API, which takes
+// system prompts in a separate field
+func anthropicMessages(msgs []types.Message) (string, []anthropicMessage) {
+	var system []string
+	messages := make([]anthropicMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+			continue
+		}
+		messages = append(messages, anthropicMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+	return strings.Join(system, "\n\n"), messages
+}
+
 type anthropicMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
@@ -197,6 +231,7 @@ type anthropicMessage struct {
 
 type anthropicRequest struct {
 	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
 	Messages    []anthropicMessage `json:"messages"`
 	Temperature float64            `json:"temperature"`
 	MaxTokens   int                `json:"max_tokens"`
@@ -207,16 +242,27 @@ type anthropicResponse struct {
 	Content []struct {
 		Text string `json:"text"`
 	} `json:"content"`
-	StopReason string `json:"stop_reason"`
-	Usage      struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	StopReason string         `json:"stop_reason"`
+	Usage      anthropicUsage `json:"usage"`
 }
 
 type anthropicStreamEvent struct {
 	Type  string `json:"type"`
 	Delta struct {
-		Text string `json:"text"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
 	} `json:"delta"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
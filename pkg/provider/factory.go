@@ -8,18 +8,47 @@ import (
 )
 
 func New(name string, cfg config.Provider) (types.Provider, error) {
-	if cfg.APIKey == "" {
+	if cfg.Type == "mock" {
+		p, err := NewMock(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+		return withRateLimit(name, cfg.RateLimit, p), nil
+	}
+
+	record, replay := recordDirs()
+	// Replays never reach the provider, so they work without a key, and
+	// neither does Ollama
+	if cfg.APIKey == "" && replay == "" && cfg.Type != "ollama" {
 		return nil, fmt.Errorf("API key is required for provider %s", name)
 	}
 
+	client, err := newHTTPClient(cfg.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+	switch {
+	case replay != "":
+		client.Transport = &replayTransport{dir: replay}
+	case record != "":
+		client.Transport = &recordTransport{base: client.Transport, dir: record}
+	}
+	if dir := traceDir(); dir != "" {
+		client.Transport = &traceTransport{base: client.Transport, file: traceFile(dir), apiKey: cfg.APIKey}
+	}
+
+	var p types.Provider
 	switch cfg.Type {
 	case "openai":
-		return NewOpenAI(cfg), nil
+		p = NewOpenAI(cfg, client)
 	case "anthropic":
-		return NewAnthropic(cfg), nil
+		p = NewAnthropic(cfg, client)
+	case "ollama":
+		p = NewOllama(cfg, client)
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", cfg.Type)
 	}
+	return withRateLimit(name, cfg.RateLimit, p), nil
 }
 
 func NewFromConfig(cfg *config.Config, providerName string) (types.Provider, error) {
@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// defaultMockResponse is what a mock provider without a configured
+// response answers
+const defaultMockResponse = `Mock response from {{.Model}}
+
+Generated locally by the mock provider for a {{len .Prompt}}-character
+prompt; no API was called.`
+
+// MockProvider answers every request by rendering a template, without
+// network access or an API key
+type MockProvider struct {
+	config   config.Provider
+	response *template.Template
+}
+
+// mockData is what the response template sees
+type mockData struct {
+	Model    string
+	System   string
+	Prompt   string
+	Messages []types.Message
+}
+
+func NewMock(cfg config.Provider) (*MockProvider, error) {
+	text := cfg.Response
+	if text == "" {
+		text = defaultMockResponse
+	}
+	tmpl, err := template.New("response").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mock response template: %w", err)
+	}
+	return &MockProvider{config: cfg, response: tmpl}, nil
+}
+
+func (p *MockProvider) Complete(ctx context.Context, req types.CompletionRequest) (*types.CompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	content, err := p.render(req)
+	if err != nil {
+		return nil, err
+	}
+	return &types.CompletionResponse{
+		Content:      content,
+		FinishReason: "stop",
+		Usage:        mockUsage(req, content),
+	}, nil
+}
+
+func (p *MockProvider) Stream(ctx context.Context, req types.CompletionRequest) (<-chan types.StreamChunk, error) {
+	content, err := p.render(req)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan types.StreamChunk)
+	go func() {
+		defer close(chunks)
+		for _, word := range strings.SplitAfter(content, " ") {
+			if !sendChunk(ctx, chunks, types.StreamChunk{Content: word}) {
+				return
+			}
+		}
+		usage := mockUsage(req, content)
+		sendChunk(ctx, chunks, types.StreamChunk{Done: true, FinishReason: "stop", Usage: &usage})
+	}()
+	return chunks, nil
+}
+
+// mockEmbeddingSize is the length of the mock provider's embeddings
+const mockEmbeddingSize = 64
+
+// Embed returns a bag-of-words vector per text: each lowercased word is
+// hashed into one of its dimensions. Texts sharing words are similar,
+// which is enough to try semantic features without a real model.
+func (p *MockProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, mockEmbeddingSize)
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			v[h.Sum32()%mockEmbeddingSize]++
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func (p *MockProvider) render(req types.CompletionRequest) (string, error) {
+	data := mockData{Model: req.Model, Messages: req.Messages}
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system":
+			data.System = m.Content
+		case "user":
+			data.Prompt = m.Content
+		}
+	}
+
+	var b bytes.Buffer
+	if err := p.response.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render mock response: %w", err)
+	}
+	return b.String(), nil
+}
+
+func mockUsage(req types.CompletionRequest, content string) types.Usage {
+	prompt := estimateTokens(types.CompletionRequest{Messages: req.Messages})
+	completion := len(content) / 4
+	return types.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) Models() []string {
+	if len(p.config.Models) == 0 {
+		return []string{"mock"}
+	}
+	models := make([]string, len(p.config.Models))
+	for i, model := range p.config.Models {
+		models[i] = model.ID
+	}
+	return models
+}
@@ -0,0 +1,22 @@
+package provider
+
+import (
+	"net/http"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+)
+
+// OllamaProvider talks to a local Ollama server through its
+// OpenAI-compatible API, base_url ending in /v1. It needs no API key.
+type OllamaProvider struct {
+	*OpenAIProvider
+}
+
+// NewOllama returns a provider using client, or a default client if nil
+func NewOllama(cfg config.Provider, client *http.Client) *OllamaProvider {
+	return &OllamaProvider{OpenAIProvider: NewOpenAI(cfg, client)}
+}
+
+func (p *OllamaProvider) Name() string {
+	return "ollama"
+}
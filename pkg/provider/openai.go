@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -19,10 +18,14 @@ type OpenAIProvider struct {
 	client *http.Client
 }
 
-func NewOpenAI(cfg config.Provider) *OpenAIProvider {
+// NewOpenAI returns a provider using client, or a default client if nil
+func NewOpenAI(cfg config.Provider, client *http.Client) *OpenAIProvider {
+	if client == nil {
+		client = &http.Client{}
+	}
 	return &OpenAIProvider{
 		config: cfg,
-		client: &http.Client{},
+		client: client,
 	}
 }
 
@@ -46,7 +49,7 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req types.CompletionReque
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.APIKey))
+	p.authorize(httpReq)
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -86,6 +89,8 @@ func (p *OpenAIProvider) Stream(ctx context.Context, req types.CompletionRequest
 		"temperature": req.Temperature,
 		"max_tokens":  req.MaxTokens,
 		"stream":      true,
+		// Ask for a last chunk with token usage, sent before [DONE]
+		"stream_options": map[string]bool{"include_usage": true},
 	}
 
 	body, err := json.Marshal(payload)
@@ -100,7 +105,7 @@ func (p *OpenAIProvider) Stream(ctx context.Context, req types.CompletionRequest
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.APIKey))
+	p.authorize(httpReq)
 	httpReq.Header.Set("Accept", "text/event-stream")
 
 	resp, err := p.client.Do(httpReq)
@@ -119,47 +124,135 @@ func (p *OpenAIProvider) Stream(ctx context.Context, req types.CompletionRequest
 	go func() {
 		defer close(chunks)
 		defer resp.Body.Close()
-
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if !strings.HasPrefix(line, "data: ") {
-				continue
+		// Unblock a pending read as soon as the caller gives up
+		stop := context.AfterFunc(ctx, func() { resp.Body.Close() })
+		defer stop()
+		send := func(c types.StreamChunk) bool { return sendChunk(ctx, chunks, c) }
+
+		final := types.StreamChunk{Done: true}
+
+		events := newSSEReader(resp.Body, p.config.MaxEventSize)
+		for {
+			ev, err := events.Next()
+			if err == io.EOF {
+				break
 			}
-
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				chunks <- types.StreamChunk{Done: true}
+			if err != nil {
+				send(types.StreamChunk{Error: fmt.Errorf("stream reading error: %w", err)})
+				return
+			}
+			if ev.Data == "[DONE]" {
+				send(final)
 				return
 			}
 
 			var streamResp openAIStreamResponse
-			if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
-				chunks <- types.StreamChunk{Error: fmt.Errorf("failed to decode stream chunk: %w", err)}
+			if err := json.Unmarshal([]byte(ev.Data), &streamResp); err != nil {
+				send(types.StreamChunk{Error: fmt.Errorf("failed to decode stream chunk: %w", err)})
+				return
+			}
+			if streamResp.Error != nil {
+				send(types.StreamChunk{
+					Error: fmt.Errorf("stream error: %s", streamResp.Error.Message),
+					Done:  true,
+				})
 				return
 			}
 
 			if len(streamResp.Choices) > 0 {
 				content := streamResp.Choices[0].Delta.Content
 				if content != "" {
-					chunks <- types.StreamChunk{Content: content}
+					if !send(types.StreamChunk{Content: content}) {
+						return
+					}
 				}
 
-				if streamResp.Choices[0].FinishReason != "" {
-					chunks <- types.StreamChunk{Done: true}
-					return
+				if reason := streamResp.Choices[0].FinishReason; reason != "" {
+					final.FinishReason = reason
+				}
+			}
+			if u := streamResp.Usage; u != nil {
+				final.Usage = &types.Usage{
+					PromptTokens:     u.PromptTokens,
+					CompletionTokens: u.CompletionTokens,
+					TotalTokens:      u.TotalTokens,
 				}
 			}
 		}
 
-		if err := scanner.Err(); err != nil {
-			chunks <- types.StreamChunk{Error: fmt.Errorf("stream reading error: %w", err)}
+		// Some compatible servers end the stream without [DONE]
+		if final.FinishReason != "" {
+			send(final)
 		}
 	}()
 
 	return chunks, nil
 }
 
+// Embed implements types.Embedder with the embeddings endpoint and the
+// provider's embedding_model
+func (p *OpenAIProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if p.config.EmbeddingModel == "" {
+		return nil, fmt.Errorf("no embedding_model configured")
+	}
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"model": p.config.EmbeddingModel,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/embeddings", strings.TrimSuffix(p.config.BaseURL, "/"))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	p.authorize(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// The data carries its own order
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index >= 0 && d.Index < len(vectors) {
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("no embedding for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// authorize adds the API key to req. Compatible servers such as Ollama
+// may have none.
+func (p *OpenAIProvider) authorize(req *http.Request) {
+	if p.config.APIKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.APIKey))
+	}
+}
+
 func (p *OpenAIProvider) Name() string {
 	return "openai"
 }
@@ -186,6 +279,13 @@ type openAIResponse struct {
 	} `json:"usage"`
 }
 
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
 type openAIStreamResponse struct {
 	Choices []struct {
 		Delta struct {
@@ -193,4 +293,12 @@ type openAIStreamResponse struct {
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
 }
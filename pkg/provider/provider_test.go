@@ -0,0 +1,286 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// sseServer serves body as an event stream
+func sseServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// collect reads a stream to the end and returns its text and final chunk
+func collect(t *testing.T, chunks <-chan types.StreamChunk) (string, types.StreamChunk) {
+	t.Helper()
+	var text strings.Builder
+	var last types.StreamChunk
+	for c := range chunks {
+		if c.Error != nil {
+			t.Fatalf("stream error: %v", c.Error)
+		}
+		text.WriteString(c.Content)
+		last = c
+	}
+	return text.String(), last
+}
+
+func TestOpenAIStreamUsage(t *testing.T) {
+	srv := sseServer(t, `data: {"choices":[{"delta":{"content":"Hel"}}]}
+
+data: {"choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}
+
+data: [DONE]
+
+`)
+	p := NewOpenAI(config.Provider{BaseURL: srv.URL, APIKey: "test"}, nil)
+	chunks, err := p.Stream(context.Background(), types.CompletionRequest{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, last := collect(t, chunks)
+	if text != "Hello" {
+		t.Errorf("text = %q", text)
+	}
+	if !last.Done || last.FinishReason != "stop" {
+		t.Errorf("final chunk = %+v", last)
+	}
+	if last.Usage == nil || last.Usage.TotalTokens != 12 {
+		t.Errorf("usage = %+v", last.Usage)
+	}
+}
+
+func TestAnthropicStreamUsage(t *testing.T) {
+	srv := sseServer(t, `event: message_start
+data: {"type":"message_start","message":{"usage":{"input_tokens":25,"output_tokens":1}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Hi"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`)
+	p := NewAnthropic(config.Provider{BaseURL: srv.URL, APIKey: "test"}, nil)
+	chunks, err := p.Stream(context.Background(), types.CompletionRequest{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, last := collect(t, chunks)
+	if text != "Hi" {
+		t.Errorf("text = %q", text)
+	}
+	if !last.Done || last.FinishReason != "end_turn" {
+		t.Errorf("final chunk = %+v", last)
+	}
+	want := types.Usage{PromptTokens: 25, CompletionTokens: 7, TotalTokens: 32}
+	if last.Usage == nil || *last.Usage != want {
+		t.Errorf("usage = %+v, want %+v", last.Usage, want)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":\"%d \"}}]}\n\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewOpenAI(config.Provider{BaseURL: srv.URL, APIKey: "test"}, nil)
+	chunks, err := p.Stream(ctx, types.CompletionRequest{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-chunks
+
+	// Abandon the stream: the goroutine must give up its pending send and
+	// close the channel instead of waiting for a reader
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	done := make(chan int)
+	go func() {
+		n := 0
+		for range chunks {
+			n++
+		}
+		done <- n
+	}()
+	select {
+	case n := <-done:
+		if n != 0 {
+			t.Errorf("got %d chunks after cancel, want none", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream was not closed after cancel")
+	}
+}
+
+func TestOpenAIEmbed(t *testing.T) {
+	var path, auth, model string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		model = req.Model
+		// Out of order, as the API allows
+		fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+	}))
+	defer srv.Close()
+
+	// Ollama needs no key and is reached through the same API
+	p, err := New("ollama", config.Provider{Type: "ollama", BaseURL: srv.URL + "/v1", EmbeddingModel: "nomic-embed-text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := types.AsEmbedder(p)
+	if !ok {
+		t.Fatal("ollama provider should support embeddings")
+	}
+	vectors, err := e.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vectors, [][]float32{{1, 0}, {0, 1}}) {
+		t.Errorf("vectors = %v, want them in input order", vectors)
+	}
+	if path != "/v1/embeddings" || auth != "" || model != "nomic-embed-text" {
+		t.Errorf("request to %s with model %q and Authorization %q", path, model, auth)
+	}
+
+	if _, err := NewOpenAI(config.Provider{BaseURL: srv.URL}, nil).Embed(context.Background(), []string{"a"}); err == nil {
+		t.Error("expected an error without embedding_model")
+	}
+}
+
+func TestHTTPSettings(t *testing.T) {
+	var org string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		org = r.Header.Get("OpenAI-Organization")
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	p, err := New("openai", config.Provider{
+		Type:    "openai",
+		BaseURL: srv.URL,
+		APIKey:  "test",
+		HTTP: config.HTTPConfig{
+			Timeout: "5s",
+			Headers: map[string]string{"OpenAI-Organization": "org-123"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Complete(context.Background(), types.CompletionRequest{Model: "m"}); err != nil {
+		t.Fatal(err)
+	}
+	if org != "org-123" {
+		t.Errorf("OpenAI-Organization = %q", org)
+	}
+
+	for _, bad := range []config.HTTPConfig{{Timeout: "soon"}, {Proxy: "not a url"}, {CACert: "/nonexistent.pem"}} {
+		if _, err := New("openai", config.Provider{Type: "openai", APIKey: "test", HTTP: bad}); err == nil {
+			t.Errorf("expected an error for %+v", bad)
+		}
+	}
+}
+
+func TestMockProvider(t *testing.T) {
+	p, err := New("mock", config.Provider{Type: "mock", Response: "echo: {{.Prompt}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := types.CompletionRequest{Model: "mock", Messages: []types.Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "hello there"},
+	}}
+
+	resp, err := p.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "echo: hello there" || resp.Usage.TotalTokens == 0 {
+		t.Errorf("response = %+v", resp)
+	}
+
+	chunks, err := p.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, last := collect(t, chunks)
+	if text != "echo: hello there" || last.Usage == nil {
+		t.Errorf("streamed %q, final %+v", text, last)
+	}
+
+	e, ok := types.AsEmbedder(p)
+	if !ok {
+		t.Fatal("mock provider should support embeddings")
+	}
+	vectors, err := e.Embed(context.Background(), []string{"fix login", "Login fix!"})
+	if err != nil || len(vectors) != 2 || !reflect.DeepEqual(vectors[0], vectors[1]) {
+		t.Errorf("embeddings of the same words differ: %v, %v", vectors, err)
+	}
+
+	if _, err := New("mock", config.Provider{Type: "mock", Response: "{{.Nope"}); err == nil {
+		t.Error("expected an invalid template to be rejected")
+	}
+}
+
+func TestStreamMaxEventSize(t *testing.T) {
+	srv := sseServer(t, `data: {"choices":[{"delta":{"content":"`+strings.Repeat("x", 2048)+`"}}]}
+
+data: [DONE]
+
+`)
+	p := NewOpenAI(config.Provider{BaseURL: srv.URL, APIKey: "test", MaxEventSize: 1024}, nil)
+	chunks, err := p.Stream(context.Background(), types.CompletionRequest{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamErr error
+	for c := range chunks {
+		if c.Error != nil {
+			streamErr = c.Error
+		}
+	}
+	if streamErr == nil || !strings.Contains(streamErr.Error(), "exceeds 1024 bytes") {
+		t.Errorf("stream error = %v, want the configured limit to apply", streamErr)
+	}
+}
@@ -0,0 +1,213 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// limiters are shared by every provider created with the same name, so
+// the limits hold across all calls a command makes
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*limiter{}
+)
+
+// limiterFor returns the limiter for the named provider, creating it or
+// replacing it if the limits changed
+func limiterFor(name string, limits config.RateLimit) *limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if l, ok := limiters[name]; ok && l.limits == limits {
+		return l
+	}
+	l := newLimiter(limits, time.Now)
+	limiters[name] = l
+	return l
+}
+
+// limiter applies a provider's rate limits: token buckets for requests and
+// tokens per minute, and a semaphore for concurrent requests
+type limiter struct {
+	limits config.RateLimit
+	now    func() time.Time
+
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+	slots    chan struct{}
+}
+
+func newLimiter(limits config.RateLimit, now func() time.Time) *limiter {
+	l := &limiter{limits: limits, now: now}
+	if limits.RequestsPerMinute > 0 {
+		l.requests = newBucket(limits.RequestsPerMinute, now())
+	}
+	if limits.TokensPerMinute > 0 {
+		l.tokens = newBucket(limits.TokensPerMinute, now())
+	}
+	if limits.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, limits.MaxConcurrent)
+	}
+	return l
+}
+
+// acquire waits until a request costing tokens may start, returning a
+// function that releases its concurrency slot
+func (l *limiter) acquire(ctx context.Context, tokens int) (func(), error) {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	for {
+		wait := l.reserve(tokens)
+		if wait == 0 {
+			return release, nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reserve takes from both buckets if they can cover the request, or
+// returns how long to wait before trying again
+func (l *limiter) reserve(tokens int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var wait time.Duration
+	if l.requests != nil {
+		wait = max(wait, l.requests.wait(1, now))
+	}
+	if l.tokens != nil {
+		wait = max(wait, l.tokens.wait(tokens, now))
+	}
+	if wait > 0 {
+		return wait
+	}
+	if l.requests != nil {
+		l.requests.take(1)
+	}
+	if l.tokens != nil {
+		l.tokens.take(tokens)
+	}
+	return 0
+}
+
+// bucket is a token bucket holding up to one minute's allowance
+type bucket struct {
+	capacity float64
+	level    float64
+	perSec   float64
+	last     time.Time
+}
+
+func newBucket(perMinute int, now time.Time) *bucket {
+	return &bucket{
+		capacity: float64(perMinute),
+		level:    float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		last:     now,
+	}
+}
+
+// wait refills the bucket and returns how long until it holds n, which is
+// capped at the capacity so a single large request can still run
+func (b *bucket) wait(n int, now time.Time) time.Duration {
+	b.level = min(b.capacity, b.level+now.Sub(b.last).Seconds()*b.perSec)
+	b.last = now
+	need := min(float64(n), b.capacity)
+	if b.level >= need {
+		return 0
+	}
+	return time.Duration((need - b.level) / b.perSec * float64(time.Second))
+}
+
+func (b *bucket) take(n int) {
+	b.level -= min(float64(n), b.capacity)
+}
+
+// limitedProvider waits for its limiter before each call
+type limitedProvider struct {
+	types.Provider
+	limiter *limiter
+}
+
+// withRateLimit wraps p so its calls respect limits; p is returned as is
+// when there are none
+func withRateLimit(name string, limits config.RateLimit, p types.Provider) types.Provider {
+	if limits == (config.RateLimit{}) {
+		return p
+	}
+	return &limitedProvider{Provider: p, limiter: limiterFor(name, limits)}
+}
+
+// Unwrap returns the limited provider. Its optional capabilities, such as
+// embeddings, are not rate limited.
+func (p *limitedProvider) Unwrap() types.Provider {
+	return p.Provider
+}
+
+func (p *limitedProvider) Complete(ctx context.Context, req types.CompletionRequest) (*types.CompletionResponse, error) {
+	release, err := p.limiter.acquire(ctx, estimateTokens(req))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.Provider.Complete(ctx, req)
+}
+
+func (p *limitedProvider) Stream(ctx context.Context, req types.CompletionRequest) (<-chan types.StreamChunk, error) {
+	release, err := p.limiter.acquire(ctx, estimateTokens(req))
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := p.Provider.Stream(ctx, req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	// Hold the concurrency slot until the stream ends
+	chunks := make(chan types.StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer release()
+		for c := range upstream {
+			if !sendChunk(ctx, chunks, c) {
+				types.Drain(upstream)
+				return
+			}
+		}
+	}()
+	return chunks, nil
+}
+
+// estimateTokens roughly counts a request's tokens as four characters of
+// prompt per token plus the tokens it may generate
+func estimateTokens(req types.CompletionRequest) int {
+	chars := 0
+	for _, m := range req.Messages {
+		chars += len(m.Content)
+	}
+	return chars/4 + req.MaxTokens
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newLimiter(config.RateLimit{RequestsPerMinute: 2, TokensPerMinute: 600}, func() time.Time { return now })
+
+	if wait := l.reserve(100); wait != 0 {
+		t.Fatalf("first request waited %s", wait)
+	}
+	if wait := l.reserve(100); wait != 0 {
+		t.Fatalf("second request waited %s", wait)
+	}
+	// The request bucket is empty and refills one request every 30s
+	if wait := l.reserve(100); wait != 30*time.Second {
+		t.Fatalf("third request wait = %s, want 30s", wait)
+	}
+
+	now = now.Add(30 * time.Second)
+	if wait := l.reserve(100); wait != 0 {
+		t.Fatalf("request after refill waited %s", wait)
+	}
+
+	// A request larger than the whole bucket runs once the bucket is full
+	// instead of waiting forever, and empties it
+	now = now.Add(60 * time.Second)
+	if wait := l.reserve(5000); wait != 0 {
+		t.Fatalf("oversized request waited %s", wait)
+	}
+	if wait := l.reserve(60); wait != 6*time.Second {
+		t.Fatalf("token wait = %s, want 6s", wait)
+	}
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := newLimiter(config.RateLimit{MaxConcurrent: 1}, time.Now)
+
+	release, err := l.acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, 0); err == nil {
+		t.Fatal("expected a second request to wait for the first")
+	}
+
+	release()
+	release, err = l.acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("slot not freed: %v", err)
+	}
+	release()
+}
@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Environment variables switching providers to recording or replaying
+// their HTTP exchanges. LAZYWORK_RECORD is "1" for the default directory
+// or a directory path; LAZYWORK_REPLAY is the directory to replay from.
+const (
+	RecordEnv = "LAZYWORK_RECORD"
+	ReplayEnv = "LAZYWORK_REPLAY"
+)
+
+// DefaultRecordDir is where LAZYWORK_RECORD=1 saves recordings
+func DefaultRecordDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "lazywork", "recordings")
+}
+
+// recordDirs returns the record and replay directories from the
+// environment; at most one is set, replay winning
+func recordDirs() (record, replay string) {
+	if dir := os.Getenv(ReplayEnv); dir != "" {
+		return "", dir
+	}
+	switch dir := os.Getenv(RecordEnv); dir {
+	case "", "0", "false":
+		return "", ""
+	case "1", "true":
+		return DefaultRecordDir(), ""
+	default:
+		return dir, ""
+	}
+}
+
+// recording is one request/response pair. Only bodies are kept, so API
+// keys and other headers never reach the disk.
+type recording struct {
+	Endpoint    string          `json:"endpoint"`
+	Request     json.RawMessage `json:"request"`
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Response    string          `json:"response"`
+}
+
+// recordingKey identifies a request by its endpoint and body, so replays
+// work against any base URL
+func recordingKey(endpoint string, body []byte) string {
+	sum := sha256.Sum256(append([]byte(endpoint+"\n"), body...))
+	return hex.EncodeToString(sum[:8])
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// recordTransport saves every exchange to dir as <key>.json
+type recordTransport struct {
+	base http.RoundTripper
+	dir  string
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := recording{
+		Endpoint:    path.Base(req.URL.Path),
+		Request:     json.RawMessage(body),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	file := filepath.Join(t.dir, recordingKey(rec.Endpoint, body)+".json")
+	// Save once the caller has read the response, so streams still
+	// arrive as they are produced
+	resp.Body = &recordingBody{ReadCloser: resp.Body, save: func(data []byte) {
+		rec.Response = string(data)
+		writeRecording(file, rec)
+	}}
+	return resp, nil
+}
+
+func writeRecording(file string, rec recording) {
+	if !json.Valid(rec.Request) {
+		rec.Request = nil
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return
+	}
+	os.WriteFile(file, data, 0o600)
+}
+
+// recordingBody passes a response body through, handing a copy to save at
+// EOF or on Close
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	save func([]byte)
+	once sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.save(b.buf.Bytes()) })
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.once.Do(func() { b.save(b.buf.Bytes()) })
+	return b.ReadCloser.Close()
+}
+
+// replayTransport answers requests from recordings in dir without touching
+// the network
+type replayTransport struct {
+	dir string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := path.Base(req.URL.Path)
+	key := recordingKey(endpoint, body)
+
+	data, err := os.ReadFile(filepath.Join(t.dir, key+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("no recording of this %s request in %s (key %s); record it with %s=1", endpoint, t.dir, key, RecordEnv)
+	}
+	var rec recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("invalid recording %s: %w", key, err)
+	}
+
+	header := http.Header{}
+	if rec.ContentType != "" {
+		header.Set("Content-Type", rec.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(rec.Response)),
+		ContentLength: int64(len(rec.Response)),
+		Request:       req,
+	}, nil
+}
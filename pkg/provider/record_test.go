@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	srv := sseServer(t, `data: {"choices":[{"delta":{"content":"recorded"},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`)
+	req := types.CompletionRequest{Model: "m", Messages: []types.Message{{Role: "user", Content: "hi"}}}
+
+	t.Setenv(RecordEnv, dir)
+	p, err := New("openai", config.Provider{Type: "openai", BaseURL: srv.URL, APIKey: "sk-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := p.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := collect(t, chunks); text != "recorded" {
+		t.Fatalf("recorded text = %q", text)
+	}
+	srv.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("got %d recordings, want 1", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if strings.Contains(string(data), "sk-secret") {
+		t.Error("recording contains the API key")
+	}
+
+	// Replay needs neither the server nor a key
+	t.Setenv(RecordEnv, "")
+	t.Setenv(ReplayEnv, dir)
+	p, err = New("openai", config.Provider{Type: "openai", BaseURL: "http://replay.invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err = p.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := collect(t, chunks); text != "recorded" {
+		t.Errorf("replayed text = %q", text)
+	}
+
+	req.Messages[0].Content = "something else"
+	if _, err := p.Stream(context.Background(), req); err == nil || !strings.Contains(err.Error(), "no recording") {
+		t.Errorf("err = %v, want a missing recording error", err)
+	}
+}
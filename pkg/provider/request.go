@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// DefaultMaxTokens bounds a completion when neither the command, the model
+// nor the provider sets max_tokens
+const DefaultMaxTokens = 1024
+
+// NewRequest returns a completion request of messages for model of the
+// provider named providerName. Its settings come from the config: the
+// model's temperature and max_tokens, else the provider's max_tokens, else
+// DefaultMaxTokens, overridden by the generation settings of command, such
+// as "commit" or "worktree finish".
+func NewRequest(cfg *config.Config, providerName, model, command string, messages []types.Message) types.CompletionRequest {
+	settings := cfg.Providers[providerName]
+	req := types.CompletionRequest{
+		Messages:  messages,
+		Model:     model,
+		MaxTokens: settings.MaxTokens,
+	}
+	for _, m := range settings.Models {
+		if m.ID == model {
+			req.Temperature = m.Temperature
+			if m.MaxTokens > 0 {
+				req.MaxTokens = m.MaxTokens
+			}
+		}
+	}
+
+	g := cfg.CommandGeneration(command)
+	if g.Temperature != nil {
+		req.Temperature = *g.Temperature
+	}
+	if g.MaxTokens > 0 {
+		req.MaxTokens = g.MaxTokens
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = DefaultMaxTokens
+	}
+	return req
+}
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+)
+
+func TestNewRequest(t *testing.T) {
+	zero := 0.0
+	cfg := &config.Config{
+		Providers: map[string]config.Provider{
+			"openai": {
+				MaxTokens: 2000,
+				Models: []config.Model{
+					{ID: "small", Temperature: 0.3},
+					{ID: "large", Temperature: 0.7, MaxTokens: 4000},
+				},
+			},
+			"bare": {},
+		},
+		Generation: map[string]config.Generation{
+			"worktree_finish": {Temperature: &zero, MaxTokens: 300},
+		},
+	}
+
+	tests := []struct {
+		provider, model, command string
+		temperature              float64
+		maxTokens                int
+	}{
+		{"openai", "small", "commit", 0.3, 2000},
+		{"openai", "large", "commit", 0.7, 4000},
+		{"openai", "large", "worktree finish", 0, 300},
+		{"bare", "any", "commit", 0, DefaultMaxTokens},
+	}
+	for _, tt := range tests {
+		req := NewRequest(cfg, tt.provider, tt.model, tt.command, nil)
+		if req.Model != tt.model || req.Temperature != tt.temperature || req.MaxTokens != tt.maxTokens {
+			t.Errorf("NewRequest(%s, %s, %s) = %s, %v, %d, want %v, %d", tt.provider, tt.model, tt.command,
+				req.Model, req.Temperature, req.MaxTokens, tt.temperature, tt.maxTokens)
+		}
+	}
+}
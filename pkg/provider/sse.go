@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// defaultMaxEventSize bounds a single server-sent event line and the data
+// of one event. Deltas are small, but some servers send whole tool
+// arguments or long texts in one event.
+const defaultMaxEventSize = 4 << 20
+
+// sseEvent is one server-sent event
+type sseEvent struct {
+	// Event is the event type, empty for the default "message" type
+	Event string
+	// Data is the event's data lines joined with newlines
+	Data string
+	ID   string
+}
+
+// sseReader reads events from a text/event-stream body
+type sseReader struct {
+	scanner *bufio.Scanner
+	max     int
+}
+
+// newSSEReader returns a reader accepting events up to maxEventSize bytes,
+// or defaultMaxEventSize if it is zero
+func newSSEReader(r io.Reader, maxEventSize int) *sseReader {
+	if maxEventSize <= 0 {
+		maxEventSize = defaultMaxEventSize
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	scanner.Split(scanSSELines)
+	return &sseReader{scanner: scanner, max: maxEventSize}
+}
+
+// Next returns the next event with data, or io.EOF at the end of the
+// stream. An event cut off by the end of the stream is still returned.
+func (r *sseReader) Next() (*sseEvent, error) {
+	var ev sseEvent
+	var data strings.Builder
+	hasData := false
+
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if hasData {
+				ev.Data = data.String()
+				return &ev, nil
+			}
+			ev = sseEvent{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment, used as keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+			if data.Len() > r.max {
+				return nil, fmt.Errorf("event data exceeds %d bytes", r.max)
+			}
+		case "id":
+			ev.ID = value
+		}
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return nil, fmt.Errorf("event line exceeds %d bytes", r.max)
+		}
+		return nil, err
+	}
+	if hasData {
+		ev.Data = data.String()
+		return &ev, nil
+	}
+	return nil, io.EOF
+}
+
+// scanSSELines splits on \n, \r\n or a lone \r, as the event stream
+// format allows all three
+func scanSSELines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// A \r at the end of the buffer may be the first half of \r\n
+		if i+1 == len(data) && !atEOF {
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, r *sseReader) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			return events
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, *ev)
+	}
+}
+
+func TestSSEReader(t *testing.T) {
+	stream := ": keep-alive\r\n" +
+		"event: delta\r\n" +
+		"data: first\r\n" +
+		"data:second\r\n" +
+		"\r\n" +
+		"id: 7\n" +
+		"data: {\"a\":1}\n" +
+		"\n" +
+		"event: ping\n" +
+		"\n" +
+		"data: [DONE]"
+
+	events := readAll(t, newSSEReader(strings.NewReader(stream), 0))
+	want := []sseEvent{
+		{Event: "delta", Data: "first\nsecond"},
+		{Data: `{"a":1}`, ID: "7"},
+		{Data: "[DONE]"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(events), events, len(want))
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestSSEReaderLongLines(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	events := readAll(t, newSSEReader(strings.NewReader("data: "+long+"\n\n"), 0))
+	if len(events) != 1 || events[0].Data != long {
+		t.Fatal("expected a line over 64KB to be read whole")
+	}
+
+	_, err := newSSEReader(strings.NewReader("data: "+long+"\n\n"), 1024).Next()
+	if err == nil || !strings.Contains(err.Error(), "exceeds 1024 bytes") {
+		t.Errorf("err = %v, want a size error", err)
+	}
+}
@@ -0,0 +1,18 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+// sendChunk delivers c unless ctx is done first, so a stream whose caller
+// stopped reading doesn't block forever. It reports whether c was sent.
+func sendChunk(ctx context.Context, chunks chan<- types.StreamChunk, c types.StreamChunk) bool {
+	select {
+	case chunks <- c:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceEnv makes providers write every HTTP exchange, headers and bodies,
+// to a trace file: "1" for the default directory or a directory path
+const TraceEnv = "LAZYWORK_TRACE_AI"
+
+// scrubbed replaces secrets in traces
+const scrubbed = "[SCRUBBED]"
+
+// DefaultTraceDir is where LAZYWORK_TRACE_AI=1 writes traces
+func DefaultTraceDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "lazywork", "trace")
+}
+
+// traceDir returns the trace directory from the environment, empty when
+// tracing is off
+func traceDir() string {
+	switch dir := os.Getenv(TraceEnv); dir {
+	case "", "0", "false":
+		return ""
+	case "1", "true":
+		return DefaultTraceDir()
+	default:
+		return dir
+	}
+}
+
+var (
+	traceMu   sync.Mutex
+	traceName string
+)
+
+// traceFile returns the file this process traces to, named after the
+// time of its first exchange so each run gets its own
+func traceFile(dir string) string {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if traceName == "" {
+		traceName = fmt.Sprintf("%s-%d.log", time.Now().Format("20060102-150405"), os.Getpid())
+	}
+	return filepath.Join(dir, traceName)
+}
+
+// traceTransport appends every exchange to file, with the API key and
+// credential headers scrubbed
+type traceTransport struct {
+	base   http.RoundTripper
+	file   string
+	apiKey string
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "=== %s %s %s\n", start.Format(time.RFC3339Nano), req.Method, t.scrubURL(req.URL))
+	t.writeHeaders(&b, req.Header)
+	b.WriteString("\n" + t.scrub(strings.TrimRight(string(body), "\n")) + "\n")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(&b, "--- error after %s: %s\n\n", time.Since(start).Round(time.Millisecond), t.scrub(err.Error()))
+		t.write(b.Bytes())
+		return nil, err
+	}
+
+	fmt.Fprintf(&b, "--- %s after %s\n", resp.Status, time.Since(start).Round(time.Millisecond))
+	t.writeHeaders(&b, resp.Header)
+	// Write once the caller has read the response, so streams still
+	// arrive as they are produced
+	resp.Body = &recordingBody{ReadCloser: resp.Body, save: func(data []byte) {
+		b.WriteString("\n" + t.scrub(strings.TrimRight(string(data), "\n")) + "\n\n")
+		t.write(b.Bytes())
+	}}
+	return resp, nil
+}
+
+func (t *traceTransport) writeHeaders(b *bytes.Buffer, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := t.scrub(strings.Join(header[name], ", "))
+		if isSecretName(name) {
+			value = scrubbed
+		}
+		fmt.Fprintf(b, "%s: %s\n", name, value)
+	}
+}
+
+func (t *traceTransport) scrubURL(u *url.URL) string {
+	scrubbedURL := *u
+	scrubbedURL.User = nil
+	query := scrubbedURL.Query()
+	for name := range query {
+		if isSecretName(name) {
+			query.Set(name, scrubbed)
+		}
+	}
+	scrubbedURL.RawQuery = query.Encode()
+	return t.scrub(scrubbedURL.String())
+}
+
+// scrub replaces the API key wherever it appears in s
+func (t *traceTransport) scrub(s string) string {
+	if t.apiKey == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, t.apiKey, scrubbed)
+}
+
+// write appends data to the trace file; tracing never fails a request
+func (t *traceTransport) write(data []byte) {
+	if err := os.MkdirAll(filepath.Dir(t.file), 0o700); err != nil {
+		return
+	}
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	f, err := os.OpenFile(t.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(data)
+}
+
+// isSecretName reports whether a header or query parameter named name
+// carries a credential
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"auth", "key", "token", "secret", "cookie", "signature"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/types"
+)
+
+func TestTrace(t *testing.T) {
+	dir := t.TempDir()
+	srv := sseServer(t, `data: {"choices":[{"delta":{"content":"traced"},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`)
+	t.Setenv(TraceEnv, dir)
+	p, err := New("openai", config.Provider{Type: "openai", BaseURL: srv.URL, APIKey: "sk-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := types.CompletionRequest{Model: "m", Messages: []types.Message{{Role: "user", Content: "hello sk-secret"}}}
+	chunks, err := p.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := collect(t, chunks); text != "traced" {
+		t.Fatalf("text = %q", text)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(files) != 1 {
+		t.Fatalf("got %d trace files, want 1", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	trace := string(data)
+	for _, want := range []string{"=== ", "POST ", `"content":"hello [SCRUBBED]"`, "Authorization: [SCRUBBED]", "--- 200 OK", `"content":"traced"`} {
+		if !strings.Contains(trace, want) {
+			t.Errorf("trace is missing %q:\n%s", want, trace)
+		}
+	}
+	if strings.Contains(trace, "sk-secret") {
+		t.Errorf("trace contains the API key:\n%s", trace)
+	}
+}
@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/githook"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/prompt"
+	"github.com/miltonparedes/lazywork/internal/shell"
+	"github.com/miltonparedes/lazywork/internal/state"
+)
+
+// ErrorName names the schema of the object a command prints instead of its
+// output when it fails
+const ErrorName = "error"
+
+// SchemaList is the output of 'schema' without a command
+type SchemaList struct {
+	Version  string   `json:"version"`
+	Commands []string `json:"commands"`
+}
+
+// outputs maps each command with --json output to the shapes it prints,
+// one per mode (e.g. 'worktree finish --check')
+var outputs = map[string][]interface{}{
+	"archive list":           {ArchiveList{}},
+	"archive restore":        {ArchiveRestore{}},
+	"branch clean":           {BranchClean{}},
+	"clone":                  {Clone{}},
+	"commit":                 {Commit{}, Message{}, StdinMessage{}},
+	"config get":             {ConfigGet{}},
+	"config init":            {ConfigInit{}},
+	"config path":            {ConfigPath{}},
+	"config set":             {ConfigSet{}},
+	"config set-key":         {ConfigKey{}, ConfigToken{}},
+	"config show":            {ConfigShow{}},
+	"config trust":           {ConfigTrust{}},
+	"config unset":           {ConfigUnset{}},
+	"config validate":        {ConfigValidate{}},
+	"debug profile":          {Profile{}},
+	"docs":                   {Docs{}},
+	"fixup":                  {Fixup{}},
+	"hook install":           {githook.InstallResult{}},
+	"hook uninstall":         {githook.UninstallResult{}},
+	"last":                   {state.Generation{}},
+	"log":                    {Log{}},
+	"pr create":              {PullRequest{}},
+	"prompt":                 {prompt.Info{}},
+	"redo":                   {Commit{}, Message{}},
+	"release":                {Release{}},
+	"resolve":                {Resolve{}, ResolveReport{}},
+	"schema":                 {SchemaList{}},
+	"search":                 {Search{}},
+	"serve":                  {Serve{}},
+	"shell install":          {shell.InstallResult{}},
+	"shell status":           {ShellStatus{}},
+	"shell uninstall":        {ShellUninstall{}},
+	"stage":                  {Stage{}, Commit{}},
+	"standup":                {Standup{}},
+	"stash apply":            {StashApply{}},
+	"stash drop":             {StashDrop{}},
+	"stash list":             {StashList{}},
+	"stash show":             {StashShow{}},
+	"undo":                   {Undo{}, UndoList{}},
+	"usage":                  {Usage{}},
+	"version":                {VersionInfo{}},
+	"wip":                    {Wip{}, WipSquash{}},
+	"workspace generate":     {Workspace{}},
+	"worktree add":           {WorktreeAdd{}},
+	"worktree clean":         {WorktreeClean{}},
+	"worktree diff":          {WorktreeDiff{}},
+	"worktree env":           {map[string]string{}},
+	"worktree exec":          {WorktreeExec{}},
+	"worktree finish":        {WorktreeFinish{}, FinishCheck{}, FinishPush{}, FinishPlan{}, FinishAllMerged{}},
+	"worktree go":            {WorktreeGo{}},
+	"worktree history":       {WorktreeHistory{}},
+	"worktree history prune": {HistoryPrune{}},
+	"worktree list":          {WorktreeList{}},
+	"worktree note":          {WorktreeNote{}},
+	"worktree pick":          {WorktreePick{}},
+	"worktree prune":         {WorktreePrune{}},
+	"worktree remove":        {WorktreeRemove{}},
+	"worktree return":        {WorktreeReturn{}},
+	"worktree status":        {WorktreeStatus{}},
+	"worktree use":           {WorktreeUse{}, UseStatus{}, UseRepair{}},
+}
+
+// Commands returns the commands with a --json output schema, sorted
+func Commands() []string {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// For returns the schema of the --json output of command, e.g. "worktree
+// add", or of ErrorName. Commands printing several shapes get one
+// alternative per shape.
+func For(command string) (*Schema, bool) {
+	var s *Schema
+	if command == ErrorName {
+		s = Reflect(lazyerr.Error{})
+	} else {
+		shapes, ok := outputs[command]
+		if !ok {
+			return nil, false
+		}
+		if len(shapes) == 1 {
+			s = Reflect(shapes[0])
+		} else {
+			s = &Schema{}
+			for _, shape := range shapes {
+				s.AnyOf = append(s.AnyOf, Reflect(shape))
+			}
+		}
+	}
+
+	s.Schema = Draft
+	s.ID = "urn:lazywork:schema:" + Version + ":" + strings.ReplaceAll(command, " ", "-")
+	s.Title = "lazywork " + command
+	return s, true
+}
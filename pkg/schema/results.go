@@ -0,0 +1,676 @@
+package schema
+
+import (
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/archive"
+	"github.com/miltonparedes/lazywork/internal/forge"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/shell"
+	"github.com/miltonparedes/lazywork/internal/standup"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/internal/tickets"
+	"github.com/miltonparedes/lazywork/internal/workspace"
+	"github.com/miltonparedes/lazywork/pkg/config"
+	"github.com/miltonparedes/lazywork/pkg/worktree"
+)
+
+// BranchClean is the output of 'branch clean'
+type BranchClean struct {
+	Base string `json:"base"`
+	// Branches are the branches merged into Base or gone from the remote
+	Branches []git.LocalBranch `json:"branches"`
+	Deleted  []string          `json:"deleted"`
+}
+
+// Clone is the output of 'clone'. The bare layout fields are only set with
+// --bare.
+type Clone struct {
+	Path     string `json:"path"`
+	Bare     bool   `json:"bare"`
+	BareDir  string `json:"bare_dir,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	Worktree string `json:"worktree,omitempty"`
+}
+
+// Message is a generated commit message that was not committed
+type Message struct {
+	Message string `json:"message"`
+	Amend   bool   `json:"amend"`
+}
+
+// StdinMessage is the output of 'commit --stdin'
+type StdinMessage struct {
+	Message string `json:"message"`
+}
+
+// Commit is the output of 'commit' and 'redo' once committed
+type Commit struct {
+	Commit  string `json:"commit"`
+	Message string `json:"message"`
+	Amend   bool   `json:"amend"`
+}
+
+// Wip is the output of 'wip'
+type Wip struct {
+	Commit  string `json:"commit"`
+	Message string `json:"message"`
+	Branch  string `json:"branch,omitempty"`
+	Pushed  bool   `json:"pushed"`
+}
+
+// WipSquash is the output of 'wip --squash'. Squashed is zero, and Commit
+// empty, when there were no wip commits.
+type WipSquash struct {
+	Commit   string `json:"commit,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Branch   string `json:"branch"`
+	Squashed int    `json:"squashed"`
+	Pushed   bool   `json:"pushed"`
+}
+
+// Fixup is the output of 'fixup'
+type Fixup struct {
+	// Commit is the fixup commit, or HEAD once rebased
+	Commit string `json:"commit"`
+	// Target is the full hash of the commit fixed up, before any rebase
+	Target  string `json:"target"`
+	Subject string `json:"subject"`
+	Rebased bool   `json:"rebased"`
+}
+
+// Stage is the output of 'stage --no-commit'; otherwise it is Commit
+type Stage struct {
+	Files []string `json:"files"`
+	// Hunks counts the hunks staged, a file without hunks counting as one
+	Hunks int `json:"hunks"`
+}
+
+// Log is the output of 'log'. Summary is set with --summarize.
+type Log struct {
+	Branch  string         `json:"branch"`
+	Base    string         `json:"base"`
+	Commits []git.LogEntry `json:"commits"`
+	Summary string         `json:"summary,omitempty"`
+}
+
+// ConfigShow is the output of 'config show'
+type ConfigShow struct {
+	Path            string   `json:"path"`
+	Exists          bool     `json:"exists"`
+	RepoConfig      string   `json:"repo_config"`
+	Profile         string   `json:"profile"`
+	Profiles        []string `json:"profiles"`
+	EnvOverrides    []string `json:"env_overrides"`
+	DefaultProvider string   `json:"default_provider"`
+	DefaultModel    string   `json:"default_model"`
+	WorktreeDir     string   `json:"worktree_dir"`
+	Providers       []string `json:"providers"`
+	// Config is the effective config with secrets redacted
+	Config *config.Config `json:"config"`
+}
+
+// ConfigPath is the output of 'config path'
+type ConfigPath struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+// ConfigInit is the output of 'config init'
+type ConfigInit struct {
+	Path    string `json:"path"`
+	Format  string `json:"format"`
+	Created bool   `json:"created"`
+}
+
+// ConfigSet is the output of 'config set'
+type ConfigSet struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Updated bool   `json:"updated"`
+}
+
+// ConfigGet is the output of 'config get'
+type ConfigGet struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// ConfigUnset is the output of 'config unset'
+type ConfigUnset struct {
+	Key   string `json:"key"`
+	Unset bool   `json:"unset"`
+}
+
+// ConfigValidate is the output of 'config validate'
+type ConfigValidate struct {
+	Path   string         `json:"path"`
+	Exists bool           `json:"exists"`
+	Valid  bool           `json:"valid"`
+	Issues []config.Issue `json:"issues"`
+}
+
+// ConfigTrust is the output of 'config trust'
+type ConfigTrust struct {
+	// Path is the repository config holding the hooks
+	Path    string              `json:"path"`
+	Hooks   map[string][]string `json:"hooks"`
+	Trusted bool                `json:"trusted"`
+}
+
+// ConfigKey is the output of 'config set-key' for a provider
+type ConfigKey struct {
+	Provider string `json:"provider"`
+	// APIKey is the keyring reference the config now holds, never the key
+	APIKey string `json:"api_key"`
+	Stored bool   `json:"stored"`
+}
+
+// ConfigToken is the output of 'config set-key' for a forge or tracker;
+// only one of Forge and Tracker is set
+type ConfigToken struct {
+	Forge   string `json:"forge,omitempty"`
+	Tracker string `json:"tracker,omitempty"`
+	// Token is the keyring reference the config now holds, never the token
+	Token  string `json:"token"`
+	Stored bool   `json:"stored"`
+}
+
+// ProfileTiming is the time one operation of 'debug profile' took
+type ProfileTiming struct {
+	Operation string        `json:"operation"`
+	Runs      int           `json:"runs"`
+	Total     time.Duration `json:"total_ns"`
+	Average   time.Duration `json:"average_ns"`
+}
+
+// Profile is the output of 'debug profile'
+type Profile struct {
+	Worktrees   int             `json:"worktrees"`
+	Timings     []ProfileTiming `json:"timings"`
+	CPUProfile  string          `json:"cpu_profile"`
+	HeapProfile string          `json:"heap_profile"`
+}
+
+// Docs is the output of 'docs'
+type Docs struct {
+	Dir   string   `json:"dir"`
+	Files []string `json:"files"`
+}
+
+// PullRequest is the output of 'pr create'
+type PullRequest struct {
+	Forge  string `json:"forge"`
+	Number int    `json:"number"`
+	Ref    string `json:"ref"`
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+	Branch string `json:"branch"`
+	Base   string `json:"base"`
+	Draft  bool   `json:"draft"`
+}
+
+// Release is the output of 'release': the release tagged, or with
+// --dry-run about to be
+type Release struct {
+	Version  string `json:"version"`
+	Previous string `json:"previous,omitempty"`
+	// Bump is empty when the version was given
+	Bump    string `json:"bump,omitempty"`
+	Commits int    `json:"commits"`
+	Notes   string `json:"notes"`
+	// Release is the forge release, with --publish
+	Release *forge.Release `json:"release,omitempty"`
+}
+
+// ResolvedFile is a file 'resolve' resolved conflicts in
+type ResolvedFile struct {
+	Path     string `json:"path"`
+	Hunks    int    `json:"hunks"`
+	Resolved int    `json:"resolved"`
+	Staged   bool   `json:"staged"`
+}
+
+// Resolve is the output of 'resolve --yes'
+type Resolve struct {
+	Files []ResolvedFile `json:"files"`
+	// Remaining are the files that still have conflicts
+	Remaining []string `json:"remaining"`
+	Committed bool     `json:"committed"`
+}
+
+// SuggestedHunk is a conflict and its suggested resolution
+type SuggestedHunk struct {
+	Ours       string `json:"ours"`
+	Base       string `json:"base,omitempty"`
+	Theirs     string `json:"theirs"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// ResolveReport is the output of 'resolve' without --yes: the conflicts of
+// each file and their suggestions, changing nothing
+type ResolveReport struct {
+	Files map[string][]SuggestedHunk `json:"files"`
+}
+
+// SearchResult is a commit found by 'search'. Match says how it was
+// found: "message", "diff" or "semantic"; Score is the similarity to the
+// query for "semantic".
+type SearchResult struct {
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Author  string    `json:"author"`
+	Subject string    `json:"subject"`
+	Match   string    `json:"match"`
+	Snippet string    `json:"snippet,omitempty"`
+	Score   float64   `json:"score,omitempty"`
+}
+
+// Search is the output of 'search'
+type Search struct {
+	Query    string         `json:"query"`
+	Semantic bool           `json:"semantic"`
+	Results  []SearchResult `json:"results"`
+}
+
+// Serve is the output of 'serve' once it listens
+type Serve struct {
+	URL       string `json:"url"`
+	TokenFile string `json:"token_file"`
+}
+
+// ShellStatus is the output of 'shell status'
+type ShellStatus struct {
+	Shell     string `json:"shell"`
+	RcFile    string `json:"rc_file"`
+	Installed bool   `json:"installed"`
+}
+
+// ShellUninstall is the output of 'shell uninstall'
+type ShellUninstall struct {
+	Results []shell.UninstallResult `json:"results"`
+}
+
+// Standup is the output of 'standup'
+type Standup struct {
+	Since   time.Time      `json:"since"`
+	Commits int            `json:"commits"`
+	Repos   []standup.Repo `json:"repos"`
+	Summary string         `json:"summary"`
+}
+
+// StashItem is a stash listed by 'stash list'
+type StashItem struct {
+	git.StashEntry
+	Auto bool `json:"auto"`
+	// State is set for stashes lazywork made: "pending" while 'worktree
+	// return' will restore it, else "orphaned"
+	State string `json:"state,omitempty"`
+}
+
+// StashList is the output of 'stash list'
+type StashList struct {
+	Stashes []StashItem `json:"stashes"`
+	Count   int         `json:"count"`
+}
+
+// StashShow is the output of 'stash show'
+type StashShow struct {
+	Stash git.StashEntry `json:"stash"`
+	Diff  string         `json:"diff"`
+}
+
+// StashApply is the output of 'stash apply'
+type StashApply struct {
+	Stash   git.StashEntry `json:"stash"`
+	Applied bool           `json:"applied"`
+	Dropped bool           `json:"dropped"`
+}
+
+// StashDrop is the output of 'stash drop'
+type StashDrop struct {
+	Stash   git.StashEntry `json:"stash"`
+	Dropped bool           `json:"dropped"`
+}
+
+// UsageSummary totals the AI requests of a period
+type UsageSummary struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	// CostComplete is false if some requests used models without prices
+	CostComplete bool `json:"cost_complete"`
+}
+
+// UsageRow is a row of the 'usage' breakdown; only the key chosen with
+// --by is set
+type UsageRow struct {
+	Day      string       `json:"day,omitempty"`
+	Provider string       `json:"provider,omitempty"`
+	Model    string       `json:"model,omitempty"`
+	Command  string       `json:"command,omitempty"`
+	Usage    UsageSummary `json:"usage"`
+}
+
+// Budget is the monthly budget and what is left of it
+type Budget struct {
+	Monthly   float64 `json:"monthly"`
+	Action    string  `json:"action"`
+	Remaining float64 `json:"remaining"`
+}
+
+// Usage is the output of 'usage'
+type Usage struct {
+	By    string       `json:"by"`
+	Days  int          `json:"days"`
+	Today UsageSummary `json:"today"`
+	Week  UsageSummary `json:"week"`
+	Month UsageSummary `json:"month"`
+	Rows  []UsageRow   `json:"rows"`
+	// Budget is set when a monthly budget is configured
+	Budget *Budget `json:"budget,omitempty"`
+}
+
+// VersionInfo is the output of 'version'
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	Go        string `json:"go"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// Workspace is the output of 'workspace generate'
+type Workspace struct {
+	Path    string             `json:"path"`
+	Folders []workspace.Folder `json:"folders"`
+}
+
+// WorktreeItem is a worktree listed by 'worktree list'
+type WorktreeItem struct {
+	git.Worktree
+	Meta *state.Meta `json:"meta,omitempty"`
+	// Status is set with --status
+	Status *git.Status `json:"status,omitempty"`
+	// Checks is set with --checks
+	Checks *forge.Checks   `json:"checks,omitempty"`
+	Ticket *tickets.Ticket `json:"ticket,omitempty"`
+	// LastActivity is the last commit or 'worktree go' visit, whichever is
+	// later. It is read with --stale, or to find stale worktrees unless
+	// worktree_stale_after is 0.
+	LastActivity *time.Time `json:"last_activity,omitempty"`
+	// Stale reports whether the worktree went without activity for
+	// worktree_stale_after
+	Stale bool `json:"stale,omitempty"`
+}
+
+// WorktreeList is the output of 'worktree list'
+type WorktreeList struct {
+	Worktrees []WorktreeItem `json:"worktrees"`
+	Count     int            `json:"count"`
+}
+
+// WorktreeAdd is the output of 'worktree add'
+type WorktreeAdd struct {
+	Path    string `json:"path"`
+	Branch  string `json:"branch"`
+	Created bool   `json:"created"`
+	// Envrc is the path of the generated .envrc, if any
+	Envrc      string   `json:"envrc"`
+	Submodules bool     `json:"submodules"`
+	Sparse     []string `json:"sparse"`
+	Issue      string   `json:"issue"`
+	Pushed     bool     `json:"pushed"`
+	// Stash is the stash applied with --from-stash=<stash>
+	Stash string `json:"stash,omitempty"`
+	// Moved reports whether --from-stash moved uncommitted changes
+	Moved bool `json:"moved_changes,omitempty"`
+	// Summary is the AI summary of the changes moved by --from-changes,
+	// saved as the worktree's note
+	Summary string `json:"summary,omitempty"`
+}
+
+// WorktreeRemove is the output of 'worktree remove'
+type WorktreeRemove struct {
+	Path    string `json:"path"`
+	Removed bool   `json:"removed"`
+	// Archive is the id of the archive made by --archive, if there was
+	// anything to keep
+	Archive string `json:"archive,omitempty"`
+}
+
+// WorktreePrune is the output of 'worktree prune'
+type WorktreePrune struct {
+	Pruned bool `json:"pruned"`
+}
+
+// WorktreeGo is the output of 'worktree go'
+type WorktreeGo struct {
+	Path string `json:"path"`
+	// CD is a shell command changing into Path
+	CD string `json:"cd"`
+}
+
+// WorktreeUse is the output of 'worktree use'
+type WorktreeUse struct {
+	Branch         string `json:"branch"`
+	PreviousBranch string `json:"previous_branch"`
+	Stashed        bool   `json:"stashed"`
+	// Depth is the number of frames on the use stack
+	Depth int `json:"depth"`
+}
+
+// UseStatus is the output of 'worktree use --status'
+type UseStatus struct {
+	// Frames is the use stack, oldest first
+	Frames []git.UseFrame `json:"frames"`
+	Depth  int            `json:"depth"`
+}
+
+// UseRepair is the output of 'worktree use --repair'
+type UseRepair struct {
+	// Frames is the use stack once repaired, oldest first
+	Frames []git.UseFrame `json:"frames"`
+	Depth  int            `json:"depth"`
+	// Actions describe what was fixed; it is empty if nothing was
+	Actions []string `json:"actions"`
+	// Restored reports whether the changes stashed by an interrupted use
+	// were put back
+	Restored bool `json:"restored"`
+}
+
+// WorktreeReturn is the output of 'worktree return'
+type WorktreeReturn struct {
+	Branch   string `json:"branch"`
+	Restored bool   `json:"restored"`
+	Depth    int    `json:"depth"`
+}
+
+// WorktreeFinish is the output of 'worktree finish' once merged
+type WorktreeFinish struct {
+	Merged  bool   `json:"merged"`
+	Branch  string `json:"branch"`
+	Cleanup bool   `json:"cleanup"`
+	// Message is the merge commit message, if one was generated
+	Message string `json:"message,omitempty"`
+	// Squash is set when the branch was squashed into a single commit
+	Squash bool `json:"squash,omitempty"`
+	// Pushed is set when the main branch was pushed (--push-base)
+	Pushed bool `json:"pushed,omitempty"`
+}
+
+// FinishPlan is the output of 'worktree finish --dry-run': the steps
+// finishing would run, in order
+type FinishPlan struct {
+	Branch string                `json:"branch"`
+	Into   string                `json:"into"`
+	Steps  []worktree.FinishStep `json:"steps"`
+}
+
+// FinishCheck is the output of 'worktree finish --check' for a clean merge;
+// conflicts are reported as a MERGE_CONFLICT error
+type FinishCheck struct {
+	Branch    string   `json:"branch"`
+	Into      string   `json:"into"`
+	Conflicts []string `json:"conflicts"`
+}
+
+// FinishPush is the output of 'worktree finish --push'
+type FinishPush struct {
+	Merged      bool              `json:"merged"`
+	Branch      string            `json:"branch"`
+	Cleanup     bool              `json:"cleanup"`
+	Pushed      bool              `json:"pushed"`
+	PullRequest forge.PullRequest `json:"pull_request"`
+}
+
+// MergedWorktree is a worktree found by 'worktree finish --all-merged'
+type MergedWorktree struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Branch string `json:"branch"`
+	// PullRequest is the merged pull request of the branch, if the forge
+	// has one
+	PullRequest *forge.PullRequest `json:"pull_request,omitempty"`
+	// Cleanup reports whether the worktree was removed and its branch
+	// deleted; it is false with --dry-run
+	Cleanup bool   `json:"cleanup"`
+	Error   string `json:"error,omitempty"`
+}
+
+// FinishAllMerged is the output of 'worktree finish --all-merged'
+type FinishAllMerged struct {
+	Into      string           `json:"into"`
+	Worktrees []MergedWorktree `json:"worktrees"`
+	Failed    int              `json:"failed"`
+}
+
+// StaleWorktree is a worktree found by 'worktree clean --stale'
+type StaleWorktree struct {
+	Name         string    `json:"name"`
+	Path         string    `json:"path"`
+	Branch       string    `json:"branch,omitempty"`
+	LastActivity time.Time `json:"last_activity"`
+	// Dirty reports uncommitted or untracked changes
+	Dirty bool `json:"dirty"`
+	// Removed is false with --dry-run, and for worktrees kept
+	Removed bool `json:"removed"`
+	// Archive is the id of the archive made with --archive, if any
+	Archive string `json:"archive,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// WorktreeClean is the output of 'worktree clean --stale'
+type WorktreeClean struct {
+	// StaleAfter is the age past which a worktree is stale, e.g. "720h0m0s"
+	StaleAfter string          `json:"stale_after"`
+	Worktrees  []StaleWorktree `json:"worktrees"`
+	Failed     int             `json:"failed"`
+}
+
+// ArchiveList is the output of 'archive list'
+type ArchiveList struct {
+	Archives []*archive.Archive `json:"archives"`
+	Count    int                `json:"count"`
+}
+
+// ArchiveRestore is the output of 'archive restore'
+type ArchiveRestore struct {
+	ID     string `json:"id"`
+	Path   string `json:"path"`
+	Branch string `json:"branch"`
+	// Deleted reports whether the archive was deleted once restored
+	Deleted bool `json:"deleted"`
+}
+
+// UndoList is the output of 'undo --list'
+type UndoList struct {
+	// Operations are newest first
+	Operations []state.Operation `json:"operations"`
+	Count      int               `json:"count"`
+}
+
+// Undo is the output of 'undo'
+type Undo struct {
+	Operation state.Operation `json:"operation"`
+	// Reset is the commit the base branch of a finish was reset to
+	Reset     string   `json:"reset,omitempty"`
+	Branches  []string `json:"branches"`
+	Worktrees []string `json:"worktrees"`
+	// Skipped are the parts that could not be undone, with the reason
+	Skipped []string `json:"skipped"`
+}
+
+// WorktreeDiff is the output of 'worktree diff'
+type WorktreeDiff struct {
+	Name  string         `json:"name"`
+	Head  string         `json:"head"`
+	Base  string         `json:"base"`
+	Files []git.FileStat `json:"files"`
+	// Patch is set unless --stat or --summary is given
+	Patch string `json:"patch,omitempty"`
+	// Summary is set with --summary
+	Summary string `json:"summary,omitempty"`
+}
+
+// ExecResult is the result of 'worktree exec' in one worktree
+type ExecResult struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+	// Output is what the command printed
+	Output  *string `json:"output,omitempty"`
+	Seconds float64 `json:"seconds"`
+}
+
+// WorktreeExec is the output of 'worktree exec'
+type WorktreeExec struct {
+	Command   []string     `json:"command"`
+	Worktrees []ExecResult `json:"worktrees"`
+	Failed    int          `json:"failed"`
+}
+
+// HistoryEntry is a worktree listed by 'worktree history', best first
+type HistoryEntry struct {
+	Path      string    `json:"path"`
+	Visits    int       `json:"visits"`
+	LastVisit time.Time `json:"last_visit"`
+	Score     float64   `json:"score"`
+}
+
+// WorktreeHistory is the output of 'worktree history'
+type WorktreeHistory struct {
+	Entries []HistoryEntry `json:"entries"`
+	Count   int            `json:"count"`
+}
+
+// HistoryPrune is the output of 'worktree history prune'
+type HistoryPrune struct {
+	Removed []string `json:"removed"`
+	Count   int      `json:"count"`
+}
+
+// WorktreeNote is the output of 'worktree note'
+type WorktreeNote struct {
+	Path  string     `json:"path"`
+	Name  string     `json:"name"`
+	Meta  state.Meta `json:"meta"`
+	Saved bool       `json:"saved"`
+}
+
+// WorktreePick is the output of 'worktree pick'
+type WorktreePick struct {
+	Commits []string `json:"commits"`
+	Branch  string   `json:"branch"`
+	Path    string   `json:"path"`
+	Head    string   `json:"head"`
+}
+
+// WorktreeStatus is the output of 'worktree status'
+type WorktreeStatus struct {
+	Worktrees []git.Status `json:"worktrees"`
+	Count     int          `json:"count"`
+}
@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Name string `json:"name"`
+}
+
+type sample struct {
+	inner
+	Count    int               `json:"count"`
+	Ratio    float64           `json:"ratio,omitempty"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	When     time.Time         `json:"when"`
+	Since    time.Time         `json:"since,omitzero"`
+	Parent   *inner            `json:"parent"`
+	Child    *inner            `json:"child,omitempty"`
+	Any      interface{}       `json:"any"`
+	Skipped  string            `json:"-"`
+	Untagged bool
+	hidden   string
+}
+
+func TestReflect(t *testing.T) {
+	s := Reflect(sample{})
+
+	if s.Type != "object" {
+		t.Fatalf("type = %q, want object", s.Type)
+	}
+	want := []string{"name", "count", "tags", "when", "parent", "any", "Untagged"}
+	if !reflect.DeepEqual(s.Required, want) {
+		t.Errorf("required = %v, want %v", s.Required, want)
+	}
+	for _, name := range []string{"Skipped", "-", "hidden", "inner"} {
+		if _, ok := s.Properties[name]; ok {
+			t.Errorf("property %q should not be described", name)
+		}
+	}
+
+	checks := map[string]*Schema{
+		"name":   {Type: "string"},
+		"count":  {Type: "integer"},
+		"ratio":  {Type: "number"},
+		"tags":   {Type: "array", Items: &Schema{Type: "string"}},
+		"labels": {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		"when":   {Type: "string", Format: "date-time"},
+		"parent": {OneOf: []*Schema{{Type: "object", Properties: map[string]*Schema{"name": {Type: "string"}}, Required: []string{"name"}}, {Type: "null"}}},
+		"child":  {Type: "object", Properties: map[string]*Schema{"name": {Type: "string"}}, Required: []string{"name"}},
+		"any":    {},
+	}
+	for name, want := range checks {
+		if got := s.Properties[name]; !reflect.DeepEqual(got, want) {
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(want)
+			t.Errorf("%s = %s, want %s", name, gotJSON, wantJSON)
+		}
+	}
+}
+
+// TestOutputsMatchSchemas checks that every key an output encodes is
+// described by its schema
+func TestOutputsMatchSchemas(t *testing.T) {
+	for name, shapes := range outputs {
+		for _, shape := range shapes {
+			s := Reflect(shape)
+			if s.Type != "object" {
+				continue
+			}
+			data, err := json.Marshal(shape)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			var keys map[string]interface{}
+			if err := json.Unmarshal(data, &keys); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			for key := range keys {
+				if _, ok := s.Properties[key]; !ok {
+					t.Errorf("%s: key %q of %T is not in its schema", name, key, shape)
+				}
+			}
+		}
+	}
+}
+
+func TestFor(t *testing.T) {
+	s, ok := For("worktree finish")
+	if !ok {
+		t.Fatal("no schema for worktree finish")
+	}
+	if s.Schema != Draft || s.ID != "urn:lazywork:schema:"+Version+":worktree-finish" || len(s.AnyOf) != 5 {
+		t.Errorf("schema = %+v, want a versioned one with an alternative per mode", s)
+	}
+
+	if s, ok := For(ErrorName); !ok || s.Properties["code"] == nil {
+		t.Errorf("error schema = %+v, want the lazyerr.Error object", s)
+	}
+	if _, ok := For("nope"); ok {
+		t.Error("For returned a schema for an unknown command")
+	}
+}
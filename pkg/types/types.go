@@ -30,11 +30,48 @@ type StreamChunk struct {
 	Content string
 	Done    bool
 	Error   error
+	// FinishReason and Usage are set on the final chunk when the provider
+	// reports them; Usage is nil otherwise
+	FinishReason string
+	Usage        *Usage
 }
 
 type Provider interface {
 	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
+	// Stream sends the response in chunks, ending with one that has Done
+	// or Error set, and then closes the channel. A caller that stops
+	// reading early must cancel ctx (or Drain the channel); the provider
+	// then closes the connection and the channel without blocking.
 	Stream(ctx context.Context, req CompletionRequest) (<-chan StreamChunk, error)
 	Name() string
 	Models() []string
 }
+
+// Drain discards the rest of a stream and waits for its channel to close
+func Drain(chunks <-chan StreamChunk) {
+	for range chunks {
+	}
+}
+
+// Embedder is implemented by providers that can turn texts into
+// embedding vectors. It is optional: find it with AsEmbedder.
+type Embedder interface {
+	// Embed returns one vector per text, in the same order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// AsEmbedder returns p as an Embedder, looking through wrappers that
+// have an Unwrap method returning the provider they wrap
+func AsEmbedder(p Provider) (Embedder, bool) {
+	for p != nil {
+		if e, ok := p.(Embedder); ok {
+			return e, true
+		}
+		u, ok := p.(interface{ Unwrap() Provider })
+		if !ok {
+			break
+		}
+		p = u.Unwrap()
+	}
+	return nil, false
+}
@@ -0,0 +1,238 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/miltonparedes/lazywork/internal/env"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+)
+
+// DefaultRemote is the remote Add pushes new branches to
+const DefaultRemote = "origin"
+
+// AddOptions configures Add
+type AddOptions struct {
+	// Name names the worktree directory and the new branch
+	Name string
+	// Branch checks out this existing branch instead of creating Name
+	Branch string
+	// Sparse only checks out these directories
+	Sparse []string
+	// Submodules initializes submodules in the new worktree
+	Submodules bool
+	// Push pushes the branch to Remote and sets its upstream
+	Push bool
+	// Remote is the remote Push uses; empty means DefaultRemote
+	Remote string
+	// NoEnvrc skips writing .envrc even if envrc_template is configured
+	NoEnvrc bool
+	// Issue is the URL of the issue the worktree works on, recorded in
+	// its metadata
+	Issue string
+	// Note is recorded as the worktree's note, as 'worktree note' does
+	Note string
+	// Stash applies this stash (e.g. "stash@{1}" or "1") in the new
+	// worktree. The stash is kept.
+	Stash string
+	// MoveChanges moves the uncommitted changes of the current checkout,
+	// untracked files included, into the new worktree
+	MoveChanges bool
+}
+
+// AddResult describes a worktree created by Add. Steps after creating
+// the worktree only warn when they fail, so their fields report what was
+// done.
+type AddResult struct {
+	Path       string
+	Branch     string
+	Sparse     []string
+	Submodules bool
+	Pushed     bool
+	Envrc      string
+	// Stash is the ref of the stash applied from AddOptions.Stash
+	Stash string
+	// Moved reports whether uncommitted changes were moved into the
+	// worktree
+	Moved bool
+}
+
+// moveChanges stashes the uncommitted changes of the current checkout and
+// pops them in the worktree at path. The changes stay in the stash if they
+// cannot be applied, so they are never lost.
+func (m *Manager) moveChanges(ctx context.Context, path, name string) bool {
+	r := m.reporter()
+
+	r.Progress("Moving uncommitted changes")
+	hash, err := git.StashChanges(ctx, git.AutoStashPrefix+" changes moved to worktree "+name)
+	if err != nil {
+		r.Warning(fmt.Sprintf("Could not stash changes: %v", err))
+		return false
+	}
+	if hash == "" {
+		r.Warning("No uncommitted changes to move")
+		return false
+	}
+
+	// Refer to the stash by its hash until it is applied; its index can
+	// change meanwhile
+	if err := git.StashApplyIn(ctx, path, hash); err != nil {
+		r.Warning(fmt.Sprintf("Could not apply the changes: %v; they are kept in the stash (see 'lazywork stash list')", err))
+		return false
+	}
+	stashes, _ := git.ListStashes(ctx)
+	if s := git.FindStash(stashes, hash); s != nil {
+		if err := git.StashDrop(ctx, s.Ref); err != nil {
+			r.Warning(fmt.Sprintf("Could not drop %s: %v", s.Ref, err))
+		}
+	}
+	return true
+}
+
+// Path returns the path Add creates the worktree called name at
+func (m *Manager) Path(ctx context.Context, name string) (string, error) {
+	ctx = m.context(ctx)
+	cfg := m.config()
+
+	baseDir := cfg.GetWorktreeDir()
+	if cfg.WorktreeDir == "" && isBareLayout(ctx) {
+		// Bare layouts keep every worktree next to .bare
+		baseDir = "."
+	}
+
+	path, err := git.GetWorktreePath(ctx, baseDir, name)
+	if err != nil {
+		return "", lazyerr.Wrap(lazyerr.PathError, err)
+	}
+	return path, nil
+}
+
+// Add creates a worktree with a new branch, or for an existing one, between
+// the pre_add and post_add hooks
+func (m *Manager) Add(ctx context.Context, opts AddOptions) (*AddResult, error) {
+	ctx = m.context(ctx)
+	cfg, r := m.config(), m.reporter()
+
+	if !git.IsInsideWorkTree(ctx) {
+		return nil, lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	if opts.Name == "" {
+		return nil, lazyerr.New(lazyerr.EmptyName, "branch name cannot be empty")
+	}
+
+	path, err := m.Path(ctx, opts.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := opts.Name
+	if opts.Branch != "" {
+		// Use existing branch
+		if !git.BranchExists(ctx, opts.Branch) {
+			return nil, lazyerr.New(lazyerr.BranchNotFound, "branch '%s' does not exist", opts.Branch).WithDetail("branch", opts.Branch)
+		}
+		branch = opts.Branch
+	} else if git.BranchExists(ctx, branch) {
+		// A new branch is created, so it must not exist yet
+		return nil, lazyerr.New(lazyerr.BranchExists, "branch '%s' already exists. Use --branch to checkout existing branch", branch)
+	}
+
+	var stash *git.StashEntry
+	if opts.Stash != "" {
+		stashes, err := git.ListStashes(ctx)
+		if err != nil {
+			return nil, lazyerr.Wrap(lazyerr.StashError, err)
+		}
+		ref := git.StashRef(opts.Stash)
+		if stash = git.FindStash(stashes, ref); stash == nil {
+			return nil, lazyerr.New(lazyerr.StashNotFound, "stash '%s' not found", ref).WithDetail("ref", ref)
+		}
+	}
+
+	hookWt := m.Env(ctx, path, branch)
+	if err := m.Hook(ctx, "pre_add", hookWt); err != nil {
+		return nil, err
+	}
+
+	sparse := git.SparseDirs(opts.Sparse)
+	newBranch := opts.Branch == ""
+	if newBranch {
+		r.Progress(fmt.Sprintf("Creating worktree %s with new branch %s", path, branch))
+	} else {
+		r.Progress(fmt.Sprintf("Creating worktree %s from branch %s", path, branch))
+	}
+	switch {
+	case len(sparse) > 0:
+		err = git.AddSparseWorktree(ctx, path, branch, newBranch, sparse)
+	case newBranch:
+		err = git.AddWorktree(ctx, path, branch)
+	default:
+		err = git.AddWorktreeFromBranch(ctx, path, branch)
+	}
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.WorktreeAddError, err)
+	}
+
+	result := &AddResult{Path: path, Branch: branch, Sparse: sparse}
+
+	if opts.Submodules && git.HasSubmodules(path) {
+		r.Progress("Initializing submodules")
+		if err := git.UpdateSubmodules(ctx, path); err != nil {
+			r.Warning(fmt.Sprintf("Could not initialize submodules: %v", err))
+		} else {
+			result.Submodules = true
+		}
+	}
+
+	if stash != nil {
+		r.Progress(fmt.Sprintf("Applying %s", stash.Ref))
+		if err := git.StashApplyIn(ctx, path, stash.Ref); err != nil {
+			r.Warning(fmt.Sprintf("Could not apply %s: %v", stash.Ref, err))
+		} else {
+			result.Stash = stash.Ref
+		}
+	}
+	if opts.MoveChanges {
+		result.Moved = m.moveChanges(ctx, path, opts.Name)
+	}
+
+	if opts.Push {
+		remote := opts.Remote
+		if remote == "" {
+			remote = DefaultRemote
+		}
+		stop := r.Spinner(fmt.Sprintf("Pushing %s to %s", branch, remote))
+		err := git.Push(ctx, remote, branch)
+		stop()
+		if err != nil {
+			r.Warning(fmt.Sprintf("Could not push %s: %v", branch, err))
+		} else {
+			result.Pushed = true
+		}
+	}
+
+	if cfg.EnvrcTemplate != "" && !opts.NoEnvrc {
+		w := hookWt
+		w.Name = opts.Name
+		result.Envrc, err = env.WriteEnvrc(cfg.EnvrcTemplate, w)
+		if err != nil {
+			r.Warning(fmt.Sprintf("Could not generate .envrc: %v", err))
+		} else if env.HasDirenv() {
+			if err := env.DirenvAllow(path); err != nil {
+				r.Warning(fmt.Sprintf("Could not run direnv allow: %v", err))
+			}
+		}
+	}
+
+	if opts.Issue != "" || opts.Note != "" {
+		if err := annotate(ctx, path, opts.Issue, opts.Note); err != nil {
+			r.Warning(fmt.Sprintf("Could not save the worktree's metadata: %v", err))
+		}
+	}
+
+	m.refreshWorkspace(ctx)
+	m.postHook(ctx, "post_add", hookWt)
+
+	return result, nil
+}
@@ -0,0 +1,516 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+)
+
+// FinishOptions configures Finish
+type FinishOptions struct {
+	// Fetch runs 'git fetch --prune' first; a failed fetch only warns
+	Fetch bool
+	// Conflicts is asked whether to merge although the merge will conflict
+	// in files, leaving them to resolve. Without it, or when it returns
+	// false, Finish stops with a MergeConflict error before changing
+	// anything.
+	Conflicts func(files []string) (bool, error)
+	// Message returns the message of the merge commit, or "" for git's
+	// default. It is called after the pre_finish hook.
+	Message func(ctx context.Context, branch, base string) string
+	// Squash commits the branch's changes as a single commit on base
+	// instead of merging it
+	Squash bool
+	// Push pushes base to origin once merged
+	Push bool
+	// Cleanup is asked, before anything changes, whether to also remove
+	// the worktree and delete its branch. Without it both are kept.
+	Cleanup func(wt Worktree) (bool, error)
+}
+
+// FinishResult describes a Finish
+type FinishResult struct {
+	Branch  string
+	Base    string
+	Message string
+	Squash  bool
+	Pushed  bool
+	// Cleanup reports whether the worktree was removed and its branch
+	// deleted
+	Cleanup bool
+}
+
+// FinishStep is a step of Finish, as listed by FinishPlan
+type FinishStep struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Names of the steps of Finish
+const (
+	StepFetch   = "fetch"
+	StepCheck   = "check"
+	StepMerge   = "merge"
+	StepSquash  = "squash"
+	StepPush    = "push"
+	StepCleanup = "cleanup"
+)
+
+// MainBranch returns the branch worktrees are finished into: main_branch
+// from the config, or else the repository's main or master branch
+func (m *Manager) MainBranch(ctx context.Context) string {
+	if b := m.config().MainBranch; b != "" {
+		return b
+	}
+	return git.GetMainBranch(m.context(ctx))
+}
+
+// FinishCandidates returns the worktrees Finish can merge into base. It
+// fails unless ctx is in the main checkout or, in a bare layout, any
+// worktree.
+func (m *Manager) FinishCandidates(ctx context.Context, base string) ([]Worktree, error) {
+	ctx = m.context(ctx)
+	if !git.IsInsideWorkTree(ctx) {
+		return nil, lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	// A bare repository has no main checkout; finish from the worktree that
+	// has the main branch checked out instead
+	if !git.IsMainWorktree(ctx) && !isBareLayout(ctx) {
+		return nil, lazyerr.New(lazyerr.NotMainWorktree, "must be in main repository, not a worktree")
+	}
+
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+	var candidates []Worktree
+	for _, wt := range git.SecondaryWorktrees(worktrees) {
+		// In a bare layout the main branch has its own worktree
+		if wt.Branch != base {
+			candidates = append(candidates, wt)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, lazyerr.New(lazyerr.NoWorktrees, "no worktrees found")
+	}
+	return candidates, nil
+}
+
+// FinishTarget returns the candidate called name (its directory name or
+// branch) to finish into base
+func (m *Manager) FinishTarget(ctx context.Context, name, base string) (*Worktree, error) {
+	candidates, err := m.FinishCandidates(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	target := match(candidates, name)
+	if target == nil {
+		return nil, lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", name).WithDetail("name", name)
+	}
+	if target.Branch == "" {
+		return nil, lazyerr.New(lazyerr.DetachedHead, "worktree is in detached HEAD state, cannot merge")
+	}
+	return target, nil
+}
+
+// CheckMergeable fails unless the current checkout is on base without
+// uncommitted changes, as Finish merges there
+func (m *Manager) CheckMergeable(ctx context.Context, base string) error {
+	ctx = m.context(ctx)
+	currentBranch, err := git.CurrentBranch(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	if currentBranch != base {
+		return lazyerr.New(lazyerr.NotMainBranch, "must be on %s branch to finish a worktree", base)
+	}
+	if git.HasUncommittedChanges(ctx) {
+		return lazyerr.New(lazyerr.UncommittedChanges, "uncommitted changes detected. Commit or stash them first")
+	}
+	return nil
+}
+
+// Conflicts returns the files merging branch into base would conflict on
+func (m *Manager) Conflicts(ctx context.Context, branch, base string) ([]string, error) {
+	return git.MergeConflicts(m.context(ctx), base, branch)
+}
+
+// Merged reports whether the branch of wt is merged into base: base has
+// all its commits, and it has commits of its own, so a worktree that was
+// just added isn't taken for a merged one
+func (m *Manager) Merged(ctx context.Context, wt Worktree, base string) bool {
+	ctx = m.context(ctx)
+	if wt.Branch == "" || !git.IsAncestor(ctx, wt.Branch, base) {
+		return false
+	}
+	tip, err := git.ResolveCommit(ctx, wt.Branch)
+	if err != nil {
+		return false
+	}
+	start := git.BranchStart(ctx, wt.Branch)
+	if start == "" {
+		// Without a reflog, a branch still at base is taken for a new one
+		start, _ = git.ResolveCommit(ctx, base)
+	}
+	return tip != start
+}
+
+// FinishMerged finishes wt, whose branch is already merged into base
+// locally or through a pull request: between the finish hooks, it removes
+// the worktree and deletes the branch, refusing if the worktree has
+// uncommitted changes. The main checkout is left alone.
+func (m *Manager) FinishMerged(ctx context.Context, wt Worktree, base string) (*FinishResult, error) {
+	ctx = m.context(ctx)
+
+	hookWt := m.Env(ctx, wt.Path, wt.Branch)
+	if err := m.Hook(ctx, "pre_finish", hookWt); err != nil {
+		return nil, err
+	}
+
+	f := &finish{m: m, wt: wt, base: base, hookWt: hookWt, merged: true,
+		result: &FinishResult{Branch: wt.Branch, Base: base, Cleanup: true}}
+	name := filepath.Base(wt.Path)
+	steps := []step{
+		{FinishStep: FinishStep{StepCheck, fmt.Sprintf("Check that %s has no uncommitted changes", name)}, run: f.checkClean},
+		{FinishStep: FinishStep{StepCleanup, fmt.Sprintf("Remove worktree %s and delete branch %s", name, wt.Branch)}, run: f.cleanup},
+	}
+	if err := m.runSteps(ctx, steps); err != nil {
+		return nil, err
+	}
+	f.record(ctx)
+
+	m.postHook(ctx, "post_finish", hookWt)
+
+	return f.result, nil
+}
+
+// Finish merges the branch of wt into base, which must be checked out (see
+// CheckMergeable), between the pre_finish and post_finish hooks. It runs
+// the steps listed by FinishPlan in order: fetch, check, merge (or
+// squash), push and cleanup. When a step fails, the steps before it are
+// undone back to the last one that can't be, a push, so the repository is
+// left either finished or as it was; the error's "step" and "undone"
+// details say how far it got.
+func (m *Manager) Finish(ctx context.Context, wt Worktree, base string, opts FinishOptions) (*FinishResult, error) {
+	ctx = m.context(ctx)
+
+	result := &FinishResult{Branch: wt.Branch, Base: base, Squash: opts.Squash}
+	if opts.Cleanup != nil {
+		var err error
+		if result.Cleanup, err = opts.Cleanup(wt); err != nil {
+			return nil, err
+		}
+	}
+
+	hookWt := m.Env(ctx, wt.Path, wt.Branch)
+	if err := m.Hook(ctx, "pre_finish", hookWt); err != nil {
+		return nil, err
+	}
+
+	f := &finish{m: m, wt: wt, base: base, opts: opts, result: result, hookWt: hookWt}
+	if err := m.runSteps(ctx, f.steps()); err != nil {
+		return nil, err
+	}
+	f.record(ctx)
+
+	m.postHook(ctx, "post_finish", hookWt)
+
+	return result, nil
+}
+
+// FinishPlan returns the steps Finish runs for wt with opts, without
+// running anything. cleanup is the answer opts.Cleanup would give.
+func (m *Manager) FinishPlan(wt Worktree, base string, opts FinishOptions, cleanup bool) []FinishStep {
+	f := &finish{wt: wt, base: base, opts: opts, result: &FinishResult{Cleanup: cleanup}}
+	var plan []FinishStep
+	for _, s := range f.steps() {
+		plan = append(plan, s.FinishStep)
+	}
+	return plan
+}
+
+// step is a step of an operation. undo reverts it once done, when a later
+// step fails; steps that change nothing have none. Nothing before a final
+// step, such as a push, is undone.
+type step struct {
+	FinishStep
+	run   func(ctx context.Context) error
+	undo  func(ctx context.Context) error
+	final bool
+}
+
+// runSteps runs steps in order. When one fails, the steps done before it
+// are undone in reverse order and its error gets "step" and "undone"
+// details.
+func (m *Manager) runSteps(ctx context.Context, steps []step) error {
+	for i, s := range steps {
+		err := s.run(ctx)
+		if err == nil {
+			continue
+		}
+
+		undone := []string{}
+		for j := i - 1; j >= 0 && !steps[j].final; j-- {
+			if steps[j].undo == nil {
+				continue
+			}
+			if uerr := steps[j].undo(ctx); uerr != nil {
+				m.reporter().Warning(fmt.Sprintf("Could not undo %s: %v", steps[j].Name, uerr))
+				break
+			}
+			undone = append(undone, steps[j].Name)
+		}
+		if len(undone) > 0 {
+			m.reporter().Warning("Undone: " + strings.Join(undone, ", "))
+		}
+
+		return lazyerr.From(err).WithDetail("step", s.Name).WithDetail("undone", undone)
+	}
+	return nil
+}
+
+// finish holds the state shared by the steps of one Finish
+type finish struct {
+	m      *Manager
+	wt     Worktree
+	base   string
+	opts   FinishOptions
+	result *FinishResult
+	hookWt Env
+
+	// head is the commit base pointed at before merging
+	head string
+	// conflicted is set when the user chose to merge despite conflicts
+	conflicted bool
+	// merged is set when the branch was merged before finishing, maybe
+	// through a pull request git can't tell about
+	merged bool
+}
+
+func (f *finish) steps() []step {
+	name := filepath.Base(f.wt.Path)
+	var steps []step
+
+	if f.opts.Fetch {
+		steps = append(steps, step{
+			FinishStep: FinishStep{StepFetch, "Fetch from the remote and prune deleted branches"},
+			run:        f.fetch,
+		})
+	}
+	steps = append(steps, step{
+		FinishStep: FinishStep{StepCheck, fmt.Sprintf("Check that %s merges cleanly into %s", f.wt.Branch, f.base)},
+		run:        f.check,
+	})
+	merge := step{
+		FinishStep: FinishStep{StepMerge, fmt.Sprintf("Merge %s into %s", f.wt.Branch, f.base)},
+		run:        f.merge,
+		undo:       f.resetBase,
+	}
+	if f.opts.Squash {
+		merge.FinishStep = FinishStep{StepSquash, fmt.Sprintf("Squash %s into a single commit on %s", f.wt.Branch, f.base)}
+	}
+	steps = append(steps, merge)
+	if f.opts.Push {
+		steps = append(steps, step{
+			FinishStep: FinishStep{StepPush, fmt.Sprintf("Push %s to origin", f.base)},
+			run:        f.push,
+			final:      true,
+		})
+	}
+	if f.result.Cleanup {
+		steps = append(steps, step{
+			FinishStep: FinishStep{StepCleanup, fmt.Sprintf("Remove worktree %s and delete branch %s", name, f.wt.Branch)},
+			run:        f.cleanup,
+		})
+	}
+	return steps
+}
+
+func (f *finish) fetch(ctx context.Context) error {
+	stop := f.m.reporter().Spinner("Fetching from remote")
+	err := git.FetchPrune(ctx)
+	stop()
+	if err != nil {
+		f.m.reporter().Warning(fmt.Sprintf("Fetch failed, remote data may be stale: %v", err))
+	}
+	return nil
+}
+
+// check stops before anything changes if the merge would conflict, or if
+// cleanup would fail on uncommitted changes in the worktree
+func (f *finish) check(ctx context.Context) error {
+	if f.result.Cleanup {
+		if err := f.checkClean(ctx); err != nil {
+			return err
+		}
+	}
+
+	conflicts, err := git.MergeConflicts(ctx, f.base, f.wt.Branch)
+	if err != nil {
+		f.m.reporter().Warning(fmt.Sprintf("Could not check for conflicts: %v", err))
+		return nil
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	conflictErr := lazyerr.New(lazyerr.MergeConflict, "merging %s into %s would conflict in %d file(s)", f.wt.Branch, f.base, len(conflicts)).
+		WithDetail("branch", f.wt.Branch).
+		WithDetail("into", f.base).
+		WithDetail("conflicts", conflicts).
+		WithHint("Nothing was merged; rebase the branch or merge by hand with: git merge " + f.wt.Branch)
+	if f.opts.Conflicts == nil {
+		return conflictErr
+	}
+	proceed, err := f.opts.Conflicts(conflicts)
+	if err != nil {
+		return err
+	}
+	if !proceed {
+		return conflictErr
+	}
+	f.conflicted = true
+	return nil
+}
+
+func (f *finish) merge(ctx context.Context) error {
+	r := f.m.reporter()
+
+	head, err := git.ResolveCommit(ctx, "HEAD")
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	f.head = head
+
+	if f.opts.Message != nil {
+		f.result.Message = f.opts.Message(ctx, f.wt.Branch, f.base)
+	}
+
+	if f.opts.Squash {
+		r.Progress(fmt.Sprintf("Squashing %s into %s", f.wt.Branch, f.base))
+		err = git.MergeSquash(ctx, f.wt.Branch)
+		if err == nil {
+			if f.result.Message != "" {
+				err = git.Commit(ctx, f.result.Message)
+			} else {
+				err = git.CommitMerge(ctx)
+			}
+		}
+	} else {
+		r.Progress(fmt.Sprintf("Merging %s into %s", f.wt.Branch, f.base))
+		if f.result.Message != "" {
+			err = git.MergeWithMessage(ctx, f.wt.Branch, f.result.Message)
+		} else {
+			err = git.Merge(ctx, f.wt.Branch)
+		}
+	}
+	if err != nil {
+		mergeErr := lazyerr.Wrap(lazyerr.MergeConflict, fmt.Errorf("merge failed: %w", err)).
+			WithDetail("branch", f.wt.Branch).
+			WithDetail("into", f.base)
+		if conflicts, _ := git.ConflictedFiles(ctx); f.conflicted && len(conflicts) > 0 {
+			// The user chose to resolve the conflicts; leave them
+			return mergeErr
+		}
+		if rerr := f.resetBase(ctx); rerr != nil {
+			r.Warning(fmt.Sprintf("Could not undo the failed merge: %v", rerr))
+		}
+		return mergeErr.WithHint("Nothing was merged; check the branch with: git log " + f.base + ".." + f.wt.Branch)
+	}
+
+	if f.opts.Squash {
+		r.Success(fmt.Sprintf("Squashed %s into %s", f.wt.Branch, f.base))
+	} else {
+		r.Success(fmt.Sprintf("Merged %s into %s", f.wt.Branch, f.base))
+	}
+	return nil
+}
+
+// checkClean fails if the worktree has uncommitted changes, which removing
+// it would lose
+func (f *finish) checkClean(ctx context.Context) error {
+	if s, err := git.WorktreeStatus(ctx, f.wt.Path, ""); err == nil && s.Dirty() {
+		return lazyerr.New(lazyerr.UncommittedChanges, "worktree %s has uncommitted changes, so it can't be removed", filepath.Base(f.wt.Path)).
+			WithDetail("worktree", f.wt.Path).
+			WithHint("Commit them in the worktree, or finish without removing it")
+	}
+	return nil
+}
+
+// record adds the finish to the undo journal
+func (f *finish) record(ctx context.Context) {
+	op := Operation{
+		Kind:    state.OpFinish,
+		Summary: fmt.Sprintf("finish %s into %s", f.wt.Branch, f.base),
+		Base:    f.base,
+		Pushed:  f.result.Pushed,
+	}
+	if f.merged {
+		op.Summary = fmt.Sprintf("finish %s, merged into %s", f.wt.Branch, f.base)
+	}
+	if after, err := git.ResolveCommit(ctx, f.base); err == nil && f.head != "" && after != f.head {
+		op.BaseBefore, op.BaseAfter = f.head, after
+	}
+	if f.result.Cleanup {
+		op.Branches = []state.BranchRef{{Name: f.wt.Branch, Commit: f.wt.Head}}
+		op.Worktrees = []state.WorktreeRef{{Path: f.wt.Path, Branch: f.wt.Branch, Head: f.wt.Head}}
+	}
+	if op.BaseBefore != "" || f.result.Cleanup {
+		f.m.Record(ctx, op)
+	}
+}
+
+// resetBase puts base back where it was before merging
+func (f *finish) resetBase(ctx context.Context) error {
+	if git.MergeInProgress(ctx) {
+		if err := git.MergeAbort(ctx); err != nil {
+			return err
+		}
+	}
+	if f.head == "" {
+		return nil
+	}
+	return git.ResetHard(ctx, f.head)
+}
+
+func (f *finish) push(ctx context.Context) error {
+	stop := f.m.reporter().Spinner(fmt.Sprintf("Pushing %s to origin", f.base))
+	err := git.Push(ctx, "origin", f.base)
+	stop()
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.PushError, err).WithDetail("branch", f.base)
+	}
+	f.result.Pushed = true
+	f.m.reporter().Success(fmt.Sprintf("Pushed %s to origin", f.base))
+	return nil
+}
+
+// cleanup removes the merged worktree and deletes its branch. If the
+// branch can't be deleted the worktree is checked out again, so neither is
+// left without the other.
+func (f *finish) cleanup(ctx context.Context) error {
+	r := f.m.reporter()
+	name := filepath.Base(f.wt.Path)
+
+	f.m.composeDown(ctx, f.hookWt)
+	if err := git.RemoveWorktree(ctx, f.wt.Path, false); err != nil {
+		return lazyerr.Wrap(lazyerr.WorktreeRemoveError, err).WithDetail("worktree", f.wt.Path)
+	}
+
+	// A squashed branch is not merged as far as git can tell, nor is one
+	// merged into base while another branch is checked out
+	if err := git.DeleteBranch(ctx, f.wt.Branch, f.opts.Squash || f.merged); err != nil {
+		if rerr := git.AddWorktreeFromBranch(ctx, f.wt.Path, f.wt.Branch); rerr != nil {
+			r.Warning(fmt.Sprintf("Could not restore worktree %s: %v", name, rerr))
+		}
+		return lazyerr.Wrap(lazyerr.BranchError, err).WithDetail("branch", f.wt.Branch)
+	}
+
+	f.m.forget(ctx, f.wt.Path)
+	r.Success(fmt.Sprintf("Removed worktree %s and deleted branch %s", name, f.wt.Branch))
+	return nil
+}
@@ -0,0 +1,46 @@
+package worktree
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+)
+
+// Remove removes the worktree called name (see Find) between the
+// pre_remove and post_remove hooks, taking its compose project down first.
+// force removes it even with uncommitted changes.
+func (m *Manager) Remove(ctx context.Context, name string, force bool) (*Worktree, error) {
+	ctx = m.context(ctx)
+
+	target, err := m.Find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	hookWt := m.Env(ctx, target.Path, target.Branch)
+	if err := m.Hook(ctx, "pre_remove", hookWt); err != nil {
+		return nil, err
+	}
+
+	// git refuses to remove a dirty worktree without force; leave its
+	// containers running in that case
+	if s, err := git.WorktreeStatus(ctx, target.Path, ""); force || (err == nil && !s.Dirty()) {
+		m.composeDown(ctx, hookWt)
+	}
+
+	if err := git.RemoveWorktree(ctx, target.Path, force); err != nil {
+		return nil, lazyerr.Wrap(lazyerr.WorktreeRemoveError, err)
+	}
+	m.forget(ctx, target.Path)
+	m.Record(ctx, Operation{
+		Kind:      state.OpRemove,
+		Summary:   "remove worktree " + filepath.Base(target.Path),
+		Worktrees: []state.WorktreeRef{{Path: target.Path, Branch: target.Branch, Head: target.Head}},
+	})
+	m.postHook(ctx, "post_remove", hookWt)
+
+	return target, nil
+}
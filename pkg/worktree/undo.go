@@ -0,0 +1,285 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+)
+
+// undoRefPrefix starts the refs that keep the commits of journaled
+// operations from being pruned by git gc until they are undone or leave
+// the journal
+const undoRefPrefix = "refs/lazywork/undo/"
+
+// UndoResult describes an Undo
+type UndoResult struct {
+	Operation Operation
+	// Reset is the commit the base branch of a finish was reset to
+	Reset string
+	// Branches are the branches recreated
+	Branches []string
+	// Worktrees are the paths of the worktrees recreated
+	Worktrees []string
+	// Skipped are the parts that could not be undone, with the reason
+	Skipped []string
+}
+
+// Journal returns the recorded operations, newest first
+func (m *Manager) Journal(ctx context.Context) ([]Operation, error) {
+	commonDir, err := git.GetCommonDir(m.context(ctx))
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.NotGitRepo, err)
+	}
+	j, err := state.LoadJournal(state.Dir(commonDir))
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.StateReadError, err)
+	}
+	ops := make([]Operation, 0, len(j.Operations))
+	for i := len(j.Operations) - 1; i >= 0; i-- {
+		ops = append(ops, j.Operations[i])
+	}
+	return ops, nil
+}
+
+// Record adds op to the undo journal, keeping the commits it refers to.
+// Operations of the Manager record themselves; it is for the ones done
+// outside it, such as deleting merged branches. Failing to record is only
+// a warning.
+func (m *Manager) Record(ctx context.Context, op Operation) {
+	ctx = m.context(ctx)
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return
+	}
+
+	var dropped []Operation
+	_, err = state.UpdateJournal(state.Dir(commonDir), func(j *state.Journal) error {
+		op, dropped = j.Add(op, time.Now())
+		return nil
+	})
+	if err != nil {
+		m.reporter().Warning(fmt.Sprintf("Could not record the operation for undo: %v", err))
+		return
+	}
+
+	for i, commit := range keptCommits(op) {
+		if err := git.UpdateRef(ctx, undoRef(op.ID, i), commit); err != nil {
+			m.reporter().Warning(fmt.Sprintf("Could not keep commit %s for undo: %v", shortHash(commit), err))
+		}
+	}
+	for _, d := range dropped {
+		deleteUndoRefs(ctx, d)
+	}
+}
+
+// Undo reverts the recorded operation with the given ID, or the last one
+// not undone yet if id is 0: the base branch of a finish is reset to where
+// it was, as long as nothing was committed on it since, then deleted
+// branches and removed worktrees are recreated. Worktrees come back
+// without the uncommitted changes they had.
+func (m *Manager) Undo(ctx context.Context, id int) (*UndoResult, error) {
+	ctx = m.context(ctx)
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.NotGitRepo, err)
+	}
+	dir := state.Dir(commonDir)
+
+	j, err := state.LoadJournal(dir)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.StateReadError, err)
+	}
+	var op *Operation
+	var ok bool
+	if id == 0 {
+		if op, ok = j.Last(); !ok {
+			return nil, lazyerr.New(lazyerr.NothingToUndo, "nothing to undo")
+		}
+	} else {
+		if op, ok = j.Get(id); !ok {
+			return nil, lazyerr.New(lazyerr.NothingToUndo, "no operation %d in the journal", id).WithDetail("id", id)
+		}
+		if op.Undone {
+			return nil, lazyerr.New(lazyerr.NothingToUndo, "operation %d was already undone", id).WithDetail("id", id)
+		}
+	}
+
+	u := &undo{m: m, op: *op, result: &UndoResult{Operation: *op, Branches: []string{}, Worktrees: []string{}, Skipped: []string{}}}
+	// Check everything that can stop the undo before changing anything
+	if err := u.checkBase(ctx); err != nil {
+		return nil, err
+	}
+	if err := u.resetBase(ctx); err != nil {
+		return nil, err
+	}
+	u.restoreBranches(ctx)
+	u.restoreWorktrees(ctx)
+
+	if _, err := state.UpdateJournal(dir, func(j *state.Journal) error {
+		if op, ok := j.Get(u.op.ID); ok {
+			op.Undone = true
+		}
+		return nil
+	}); err != nil {
+		m.reporter().Warning(fmt.Sprintf("Could not mark operation %d as undone: %v", u.op.ID, err))
+	}
+	deleteUndoRefs(ctx, u.op)
+	u.result.Operation.Undone = true
+
+	return u.result, nil
+}
+
+// undo holds the state of one Undo
+type undo struct {
+	m      *Manager
+	op     Operation
+	result *UndoResult
+
+	// baseWorktree is the worktree that has the base branch checked out,
+	// if any
+	baseWorktree string
+}
+
+func (u *undo) skip(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	u.result.Skipped = append(u.result.Skipped, msg)
+	u.m.reporter().Warning(msg)
+}
+
+// checkBase fails if the base branch of a finish moved since, or can't be
+// reset without losing uncommitted changes
+func (u *undo) checkBase(ctx context.Context) error {
+	if u.op.BaseBefore == "" {
+		return nil
+	}
+	current, err := git.ResolveCommit(ctx, u.op.Base)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.BranchNotFound, err).WithDetail("branch", u.op.Base)
+	}
+	if current != u.op.BaseAfter {
+		return lazyerr.New(lazyerr.UndoError, "%s has changed since operation %d", u.op.Base, u.op.ID).
+			WithDetail("branch", u.op.Base).
+			WithDetail("before", u.op.BaseBefore).
+			WithHint(fmt.Sprintf("Nothing was undone; reset it by hand with: git reset --keep %s", shortHash(u.op.BaseBefore)))
+	}
+
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+	for _, wt := range worktrees {
+		if wt.Branch == u.op.Base {
+			u.baseWorktree = wt.Path
+		}
+	}
+	if u.baseWorktree != "" && git.HasUncommittedChangesIn(ctx, u.baseWorktree) {
+		return lazyerr.New(lazyerr.UncommittedChanges, "uncommitted changes on %s. Commit or stash them first", u.op.Base).
+			WithDetail("worktree", u.baseWorktree)
+	}
+	return nil
+}
+
+// resetBase puts the base branch of a finish back where it was
+func (u *undo) resetBase(ctx context.Context) error {
+	if u.op.BaseBefore == "" {
+		return nil
+	}
+	var err error
+	if u.baseWorktree != "" {
+		err = git.ResetKeep(ctx, u.baseWorktree, u.op.BaseBefore)
+	} else {
+		err = git.MoveBranch(ctx, u.op.Base, u.op.BaseBefore)
+	}
+	if err != nil {
+		return lazyerr.Wrap(lazyerr.UndoError, err).WithDetail("branch", u.op.Base)
+	}
+	u.result.Reset = u.op.BaseBefore
+	u.m.reporter().Success(fmt.Sprintf("Reset %s to %s", u.op.Base, shortHash(u.op.BaseBefore)))
+	if u.op.Pushed {
+		u.m.reporter().Warning(fmt.Sprintf("origin still has the merge; undo it there with: git push --force-with-lease origin %s", u.op.Base))
+	}
+	return nil
+}
+
+func (u *undo) restoreBranches(ctx context.Context) {
+	for _, b := range u.op.Branches {
+		if git.BranchExists(ctx, b.Name) {
+			if commit, _ := git.ResolveCommit(ctx, b.Name); commit != b.Commit {
+				u.skip("Branch %s exists at another commit; left alone (it was at %s)", b.Name, shortHash(b.Commit))
+			}
+			continue
+		}
+		if err := git.CreateBranch(ctx, b.Name, b.Commit); err != nil {
+			u.skip("Could not recreate branch %s at %s: %v", b.Name, shortHash(b.Commit), err)
+			continue
+		}
+		u.result.Branches = append(u.result.Branches, b.Name)
+		u.m.reporter().Success(fmt.Sprintf("Recreated branch %s at %s", b.Name, shortHash(b.Commit)))
+	}
+}
+
+func (u *undo) restoreWorktrees(ctx context.Context) {
+	for _, wt := range u.op.Worktrees {
+		name := filepath.Base(wt.Path)
+		if state.DirExists(wt.Path) {
+			u.skip("Not recreating worktree %s: %s exists", name, wt.Path)
+			continue
+		}
+		var err error
+		if wt.Branch != "" && git.BranchExists(ctx, wt.Branch) {
+			err = git.AddWorktreeFromBranch(ctx, wt.Path, wt.Branch)
+		} else {
+			err = git.AddDetachedWorktree(ctx, wt.Path, wt.Head)
+		}
+		if err != nil {
+			u.skip("Could not recreate worktree %s: %v", name, err)
+			continue
+		}
+		u.result.Worktrees = append(u.result.Worktrees, wt.Path)
+		u.m.reporter().Success(fmt.Sprintf("Recreated worktree %s", name))
+	}
+	if len(u.result.Worktrees) > 0 {
+		u.m.refreshWorkspace(ctx)
+	}
+}
+
+// keptCommits returns the commits of op that only it may still reference
+func keptCommits(op Operation) []string {
+	seen := map[string]bool{}
+	var commits []string
+	add := func(c string) {
+		if c != "" && !seen[c] {
+			seen[c] = true
+			commits = append(commits, c)
+		}
+	}
+	for _, b := range op.Branches {
+		add(b.Commit)
+	}
+	for _, wt := range op.Worktrees {
+		add(wt.Head)
+	}
+	return commits
+}
+
+func undoRef(id, i int) string {
+	return fmt.Sprintf("%s%d/%d", undoRefPrefix, id, i)
+}
+
+func deleteUndoRefs(ctx context.Context, op Operation) {
+	for i := range keptCommits(op) {
+		_ = git.DeleteRef(ctx, undoRef(op.ID, i))
+	}
+}
+
+func shortHash(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,390 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+)
+
+// useStashMessage is the message of the auto-stash Use creates
+const useStashMessage = git.AutoStashPrefix + " auto-stash before worktree use"
+
+// UseOptions configures Use
+type UseOptions struct {
+	// Name is the directory name or branch of the worktree to use
+	Name string
+	// Stash stashes uncommitted changes in the main checkout, to be
+	// restored by Return
+	Stash bool
+	// ConfirmStash is asked whether to stash uncommitted changes when Stash
+	// is false. Without it, uncommitted changes are an error.
+	ConfirmStash func() (bool, error)
+}
+
+// UseResult describes a Use
+type UseResult struct {
+	Branch         string
+	PreviousBranch string
+	Stashed        bool
+	// Depth is the number of frames on the use stack, this one included
+	Depth int
+}
+
+// ReturnResult describes a Return
+type ReturnResult struct {
+	Branch string
+	// Restored reports whether the changes stashed by Use were restored
+	Restored bool
+	// Depth is the number of frames left on the use stack
+	Depth int
+	// Next is the branch another Return goes back to, if any
+	Next string
+}
+
+// UseRepairResult describes a RepairUse
+type UseRepairResult struct {
+	// Frames is the use stack once repaired, oldest first
+	Frames []UseFrame
+	// Actions describe what was changed; it is empty if the state was sound
+	Actions []string
+	// Restored reports whether the changes stashed by an interrupted Use
+	// were put back
+	Restored bool
+}
+
+// checkMain fails unless ctx is in the main checkout, where use and return
+// switch branches
+func checkMain(ctx context.Context) error {
+	if !git.IsInsideWorkTree(ctx) {
+		return lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	if !git.IsMainWorktree(ctx) {
+		return lazyerr.New(lazyerr.NotMainWorktree, "must be in main repository, not a worktree")
+	}
+	return nil
+}
+
+// UseStack returns the use stack, oldest frame first. It is kept in the
+// git common dir, so it is the same from every worktree.
+func (m *Manager) UseStack(ctx context.Context) ([]UseFrame, error) {
+	ctx = m.context(ctx)
+	if !git.IsInsideWorkTree(ctx) {
+		return nil, lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	stack, err := git.LoadUseStack(ctx)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.StateReadError, err)
+	}
+	return stack, nil
+}
+
+// UseCandidates returns the worktrees whose branch Use can check out
+func (m *Manager) UseCandidates(ctx context.Context) ([]Worktree, error) {
+	worktrees, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	secondary := git.SecondaryWorktrees(worktrees)
+	if len(secondary) == 0 {
+		return nil, lazyerr.New(lazyerr.NoWorktrees, "no worktrees found")
+	}
+	return secondary, nil
+}
+
+// Use checks out the branch of a worktree in the main checkout, between
+// the pre_use and post_use hooks, and pushes a frame on the use stack so
+// Return can go back. The worktree's directory is detached from the
+// branch meanwhile.
+func (m *Manager) Use(ctx context.Context, opts UseOptions) (*UseResult, error) {
+	ctx = m.context(ctx)
+	if err := checkMain(ctx); err != nil {
+		return nil, err
+	}
+
+	stack, err := m.UseStack(ctx)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := m.UseCandidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	target := match(candidates, opts.Name)
+	if target == nil {
+		return nil, lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", opts.Name).WithDetail("name", opts.Name)
+	}
+	if target.Branch == "" {
+		return nil, lazyerr.New(lazyerr.DetachedHead, "worktree is in detached HEAD state")
+	}
+
+	currentBranch, err := git.CurrentBranch(ctx)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.BranchError, err)
+	}
+	if currentBranch == target.Branch {
+		return nil, lazyerr.New(lazyerr.StateExists, "already using branch '%s'", currentBranch).WithDetail("branch", currentBranch)
+	}
+
+	hookWt := m.Env(ctx, target.Path, target.Branch)
+	if err := m.Hook(ctx, "pre_use", hookWt); err != nil {
+		return nil, err
+	}
+
+	var stashHash string
+	if git.HasUncommittedChanges(ctx) {
+		doStash := opts.Stash
+		if !doStash && opts.ConfirmStash != nil {
+			if doStash, err = opts.ConfirmStash(); err != nil {
+				return nil, err
+			}
+			if !doStash {
+				return nil, lazyerr.New(lazyerr.Cancelled, "cancelled: uncommitted changes would be lost")
+			}
+		}
+		if !doStash {
+			return nil, lazyerr.New(lazyerr.UncommittedChanges, "uncommitted changes detected. Commit or stash them first")
+		}
+
+		stashHash, err = git.Stash(ctx, useStashMessage)
+		if err != nil {
+			return nil, lazyerr.Wrap(lazyerr.StashError, err)
+		}
+	}
+
+	frame := UseFrame{
+		Branch:  currentBranch,
+		Target:  target.Branch,
+		Stash:   stashHash,
+		Created: time.Now(),
+	}
+	// The frame goes on the stack as it is now, in case another worktree
+	// changed it meanwhile
+	stack, err = git.UpdateUseStack(ctx, func(frames []UseFrame) ([]UseFrame, error) {
+		return append(frames, frame), nil
+	})
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.StateSaveError, err)
+	}
+
+	if err := git.Checkout(ctx, target.Branch); err != nil {
+		// Roll back even if the checkout failed because ctx was cancelled
+		rollback := context.WithoutCancel(ctx)
+		git.UpdateUseStack(rollback, func(frames []UseFrame) ([]UseFrame, error) {
+			return removeFrame(frames, frame), nil
+		})
+		if stashHash != "" {
+			git.StashPop(rollback)
+		}
+		return nil, lazyerr.Wrap(lazyerr.CheckoutError, err)
+	}
+
+	m.postHook(ctx, "post_use", hookWt)
+
+	return &UseResult{
+		Branch:         target.Branch,
+		PreviousBranch: currentBranch,
+		Stashed:        stashHash != "",
+		Depth:          len(stack),
+	}, nil
+}
+
+// Return pops the last frame of the use stack: it checks out the branch
+// the main checkout had before and restores the changes Use stashed,
+// between the pre_return and post_return hooks
+func (m *Manager) Return(ctx context.Context) (*ReturnResult, error) {
+	ctx = m.context(ctx)
+	r := m.reporter()
+	if err := checkMain(ctx); err != nil {
+		return nil, err
+	}
+
+	stack, err := m.UseStack(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(stack) == 0 {
+		return nil, lazyerr.New(lazyerr.NoState, "no previous state found. Did you run 'worktree use' first?")
+	}
+	frame := stack[len(stack)-1]
+	remaining := stack[:len(stack)-1]
+
+	if git.HasUncommittedChanges(ctx) {
+		return nil, lazyerr.New(lazyerr.UncommittedChanges, "you have uncommitted changes. Commit or stash them before returning")
+	}
+
+	root, _ := git.GetRepoRoot(ctx)
+	hookWt := m.Env(ctx, root, frame.Branch)
+	if err := m.Hook(ctx, "pre_return", hookWt); err != nil {
+		return nil, err
+	}
+
+	if err := git.Checkout(ctx, frame.Branch); err != nil {
+		return nil, lazyerr.Wrap(lazyerr.CheckoutError, err).
+			WithDetail("branch", frame.Branch).
+			WithHint("If the saved state is stale, fix it with: lazywork worktree use --repair")
+	}
+
+	result := &ReturnResult{Branch: frame.Branch}
+	if frame.Stash != "" {
+		stashes, _ := git.ListStashes(ctx)
+		if stash := git.FindStash(stashes, frame.Stash); stash == nil {
+			r.Warning("The auto-stash from 'worktree use' no longer exists; nothing to restore")
+		} else if err := git.StashApply(ctx, stash.Ref, true); err != nil {
+			r.Warning(fmt.Sprintf("Could not restore stash %s: %v", stash.Ref, err))
+		} else {
+			result.Restored = true
+		}
+	}
+
+	popped, err := git.UpdateUseStack(ctx, func(frames []UseFrame) ([]UseFrame, error) {
+		return removeFrame(frames, frame), nil
+	})
+	if err != nil {
+		r.Warning(fmt.Sprintf("Could not update state: %v", err))
+	} else {
+		remaining = popped
+	}
+	result.Depth = len(remaining)
+	if len(remaining) > 0 {
+		result.Next = remaining[len(remaining)-1].Branch
+	}
+
+	m.postHook(ctx, "post_return", hookWt)
+
+	return result, nil
+}
+
+// RepairUse checks the use stack against the branches, the stash list and
+// HEAD, for when a Use was interrupted or the state was corrupted and
+// Return fails. Frames returning to a deleted branch are dropped and
+// references to stashes that are gone cleared. A Use interrupted before
+// the checkout is rolled back, restoring its auto-stash into a clean
+// checkout. An unreadable stack is discarded, keeping the frame of the
+// latest auto-stash if HEAD moved off its branch. Stashes are only
+// removed once restored.
+func (m *Manager) RepairUse(ctx context.Context) (*UseRepairResult, error) {
+	ctx = m.context(ctx)
+	if err := checkMain(ctx); err != nil {
+		return nil, err
+	}
+
+	result := &UseRepairResult{Actions: []string{}}
+	changed := false
+	act := func(format string, args ...interface{}) {
+		result.Actions = append(result.Actions, fmt.Sprintf(format, args...))
+		changed = true
+	}
+
+	stack, err := git.LoadUseStack(ctx)
+	corrupted := err != nil
+	if corrupted {
+		act("Discarded the unreadable use state (%v)", err)
+	}
+	stashes, err := git.ListStashes(ctx)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.StashError, err)
+	}
+	current, _ := git.CurrentBranch(ctx)
+
+	var frames []UseFrame
+	for _, f := range stack {
+		if !git.BranchExists(ctx, f.Branch) {
+			act("Dropped the frame returning to %s, which no longer exists", f.Branch)
+			continue
+		}
+		if f.Stash != "" && git.FindStash(stashes, f.Stash) == nil {
+			act("Cleared the auto-stash of the frame returning to %s, which no longer exists", f.Branch)
+			f.Stash = ""
+		}
+		frames = append(frames, f)
+	}
+
+	// The latest stash, if it is an auto-stash of Use newer than every
+	// frame, belongs to a Use that stopped before saving its frame, or
+	// whose frame was lost with the state
+	var orphan *git.StashEntry
+	if len(stashes) > 0 && stashes[0].Message == useStashMessage && !referenced(frames, stashes[0]) &&
+		(len(frames) == 0 || !stashes[0].Created.Before(frames[len(frames)-1].Created.Truncate(time.Second))) {
+		orphan = &stashes[0]
+	}
+
+	switch {
+	case orphan != nil && corrupted && current != orphan.Branch && current != "":
+		frames = append(frames, UseFrame{Branch: orphan.Branch, Target: current, Stash: orphan.Hash, Created: orphan.Created})
+		act("Recovered the frame returning to %s from auto-stash %s", orphan.Branch, orphan.Ref)
+	case orphan != nil && current == orphan.Branch:
+		// The checkout never happened
+		if m.restoreStash(ctx, orphan.Ref) {
+			act("Restored the changes stashed by an interrupted use on %s", current)
+			result.Restored = true
+		}
+	case len(frames) > 0:
+		top := frames[len(frames)-1]
+		if top.Target == "" || current != top.Branch || current == top.Target {
+			break
+		}
+		frames = frames[:len(frames)-1]
+		act("Dropped the frame of a use of %s interrupted before the checkout", top.Target)
+		if top.Stash != "" {
+			if m.restoreStash(ctx, top.Stash) {
+				result.Restored = true
+				act("Restored the changes it stashed")
+			}
+		}
+	}
+
+	if changed {
+		if err := git.SaveUseStack(ctx, frames); err != nil {
+			return nil, lazyerr.Wrap(lazyerr.StateSaveError, err)
+		}
+	}
+	if frames == nil {
+		frames = []UseFrame{}
+	}
+	result.Frames = frames
+	return result, nil
+}
+
+// restoreStash pops the stash identified by id into a clean main checkout.
+// The stash is kept, with a warning, if that can't be done safely.
+func (m *Manager) restoreStash(ctx context.Context, id string) bool {
+	stashes, _ := git.ListStashes(ctx)
+	stash := git.FindStash(stashes, id)
+	if stash == nil {
+		return false
+	}
+	if git.HasUncommittedChanges(ctx) {
+		m.reporter().Warning(fmt.Sprintf("Not restoring %s over uncommitted changes; apply it with: lazywork stash apply %s", stash.Ref, stash.Ref))
+		return false
+	}
+	if err := git.StashApply(ctx, stash.Ref, true); err != nil {
+		m.reporter().Warning(fmt.Sprintf("Could not restore stash %s: %v", stash.Ref, err))
+		return false
+	}
+	return true
+}
+
+// removeFrame returns frames without the last frame equal to frame
+func removeFrame(frames []UseFrame, frame UseFrame) []UseFrame {
+	for i := len(frames) - 1; i >= 0; i-- {
+		if frames[i].Branch == frame.Branch && frames[i].Target == frame.Target &&
+			frames[i].Stash == frame.Stash && frames[i].Created.Equal(frame.Created) {
+			return append(frames[:i:i], frames[i+1:]...)
+		}
+	}
+	return frames
+}
+
+// referenced reports whether a frame of the stack refers to stash
+func referenced(frames []UseFrame, stash git.StashEntry) bool {
+	for _, f := range frames {
+		if stash.Matches(f.Stash) {
+			return true
+		}
+	}
+	return false
+}
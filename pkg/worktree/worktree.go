@@ -0,0 +1,361 @@
+// Package worktree runs lazywork's worktree workflows (add and remove with
+// hooks, the use/return stack and finish) so other Go tools can embed
+// them. The lazywork commands are a thin layer over it that adds prompts
+// and output.
+//
+// Operations act on the repository of the current working directory,
+// like the git commands they run.
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/env"
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/hooks"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/internal/workspace"
+	"github.com/miltonparedes/lazywork/pkg/config"
+)
+
+type (
+	// Worktree is a git worktree as listed by 'git worktree list'
+	Worktree = git.Worktree
+	// Runner runs git commands; see Manager.Git
+	Runner = git.Runner
+	// UseFrame is one entry of the 'use' stack
+	UseFrame = git.UseFrame
+	// Env describes a worktree to hooks and dev tools (LW_* variables)
+	Env = env.Worktree
+	// Error is the error returned by every operation, with a stable code
+	Error = lazyerr.Error
+	// Operation is a destructive operation recorded for undo
+	Operation = state.Operation
+)
+
+// Reporter receives progress and non-fatal problems. *output.Output from
+// the lazywork CLI implements it.
+type Reporter interface {
+	// Progress reports a step that is starting
+	Progress(msg string)
+	// Spinner reports a slow step; the returned func is called when it ends
+	Spinner(msg string) (stop func())
+	// Success reports a step that is done
+	Success(msg string)
+	// Warning reports a problem that did not stop the operation
+	Warning(msg string)
+}
+
+// Manager runs worktree operations. The zero value uses the default
+// config, the git binary in PATH and no reporting.
+type Manager struct {
+	// Config supplies hooks, worktree_dir, ports and the main branch; nil
+	// means the defaults
+	Config *config.Config
+	// Git runs every git command of an operation; nil runs git from PATH
+	Git Runner
+	// Reporter receives progress and warnings; nil discards them
+	Reporter Reporter
+	// HookOutput receives the output of hook commands; nil discards it
+	HookOutput io.Writer
+	// NoHooks skips the configured hooks
+	NoHooks bool
+
+	// warnedUntrusted is set once the user was told the repository's
+	// hooks are skipped
+	warnedUntrusted bool
+}
+
+// New returns a Manager using cfg
+func New(cfg *config.Config) *Manager {
+	return &Manager{Config: cfg}
+}
+
+// context threads the Manager's git runner through ctx
+func (m *Manager) context(ctx context.Context) context.Context {
+	if m.Git != nil {
+		return git.WithRunner(ctx, m.Git)
+	}
+	return ctx
+}
+
+func (m *Manager) config() *config.Config {
+	if m.Config != nil {
+		return m.Config
+	}
+	return &config.Config{}
+}
+
+func (m *Manager) reporter() Reporter {
+	if m.Reporter != nil {
+		return m.Reporter
+	}
+	return nopReporter{}
+}
+
+type nopReporter struct{}
+
+func (nopReporter) Progress(string)              {}
+func (nopReporter) Spinner(string) (stop func()) { return func() {} }
+func (nopReporter) Success(string)               {}
+func (nopReporter) Warning(string)               {}
+
+// List returns every worktree of the repository, the main one first
+func (m *Manager) List(ctx context.Context) ([]Worktree, error) {
+	ctx = m.context(ctx)
+	if !git.IsInsideWorkTree(ctx) {
+		return nil, lazyerr.New(lazyerr.NotGitRepo, "not inside a git repository")
+	}
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return nil, lazyerr.Wrap(lazyerr.WorktreeListError, err)
+	}
+	return worktrees, nil
+}
+
+// LastActivity returns, for each worktree in order, when it was last
+// committed to or visited with 'worktree go', whichever is later. It is
+// zero if neither is known.
+func (m *Manager) LastActivity(ctx context.Context, worktrees []Worktree) []time.Time {
+	ctx = m.context(ctx)
+	visits := map[string]time.Time{}
+	if commonDir, err := git.GetCommonDir(ctx); err == nil {
+		if h, err := state.LoadHistory(state.Dir(commonDir)); err == nil {
+			for _, e := range h.Entries {
+				visits[e.Path] = e.LastVisit
+			}
+		}
+	}
+
+	activity := make([]time.Time, len(worktrees))
+	for i, wt := range worktrees {
+		activity[i] = visits[wt.Path]
+		if wt.Bare || wt.Head == "" {
+			continue
+		}
+		if t, err := git.LastCommitTime(ctx, wt.Head); err == nil && t.After(activity[i]) {
+			activity[i] = t
+		}
+	}
+	return activity
+}
+
+// Stale reports whether a worktree last active at activity has gone
+// without commits or visits for at least after. Unknown activity is never
+// stale, and nothing is when after is zero.
+func Stale(activity time.Time, after time.Duration, now time.Time) bool {
+	return after > 0 && !activity.IsZero() && now.Sub(activity) >= after
+}
+
+// Find returns the worktree called name: its directory name, its full path
+// or the part after the last "-" of its directory name
+func (m *Manager) Find(ctx context.Context, name string) (*Worktree, error) {
+	worktrees, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, wt := range worktrees {
+		if filepath.Base(wt.Path) == name || wt.Path == name {
+			return &wt, nil
+		}
+		// Also match by suffix pattern (repo-name)
+		if matched, _ := filepath.Match("*-"+name, filepath.Base(wt.Path)); matched {
+			return &wt, nil
+		}
+	}
+	return nil, lazyerr.New(lazyerr.WorktreeNotFound, "worktree '%s' not found", name).WithDetail("name", name)
+}
+
+// Env describes the worktree at path for hooks and dev tools, reserving
+// its port block if it has none
+func (m *Manager) Env(ctx context.Context, path, branch string) Env {
+	ctx = m.context(ctx)
+	repoRoot, _ := git.MainWorktreePath(ctx)
+	port, count := m.ports(ctx, path)
+	return Env{
+		Name:      filepath.Base(path),
+		Branch:    branch,
+		Path:      path,
+		RepoRoot:  repoRoot,
+		Port:      port,
+		PortCount: count,
+	}
+}
+
+// ports returns the first port of the block reserved for the worktree at
+// path and the block size, reserving a block if it has none. port is 0
+// when no block can be reserved.
+func (m *Manager) ports(ctx context.Context, path string) (port, count int) {
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return 0, 0
+	}
+	block, err := state.AllocatePorts(state.Dir(commonDir), path)
+	if err != nil {
+		return 0, 0
+	}
+	base, size := m.config().GetPorts()
+	port = base + block*size
+	if port+size-1 > 65535 {
+		return 0, 0
+	}
+	return port, size
+}
+
+// Hook runs the commands configured for event (e.g. "pre_add") with w in
+// their environment. Operations abort on a failed pre_ hook and only warn
+// about a failed post_ hook, as the work is done. The hooks of the
+// per-repository config only run once the user trusted them with 'config
+// trust'; until then they are skipped with a warning.
+func (m *Manager) Hook(ctx context.Context, event string, w Env) error {
+	if m.NoHooks {
+		return nil
+	}
+	cfg := m.config()
+	commands := cfg.HookCommands(event)
+	if repo := cfg.RepoHookCommands(event); len(repo) > 0 {
+		if m.repoHooksTrusted(ctx) {
+			commands = append(commands[:len(commands):len(commands)], repo...)
+		} else if !m.warnedUntrusted {
+			m.warnedUntrusted = true
+			m.reporter().Warning(fmt.Sprintf("Skipping the hooks of %s; review them and run 'lazywork config trust' to allow them", cfg.RepoConfigPath))
+		}
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+
+	m.reporter().Progress("Running " + event + " hooks")
+	stdout := m.HookOutput
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	h := hooks.Hook{
+		Event:    event,
+		Commands: commands,
+		Worktree: w,
+		Stdout:   stdout,
+		Stderr:   stdout,
+	}
+	if err := h.Run(ctx); err != nil {
+		return lazyerr.Wrap(lazyerr.HookFailed, err).WithDetail("hook", event)
+	}
+	return nil
+}
+
+// repoHooksTrusted returns true if the user trusted the current hooks of
+// the per-repository config
+func (m *Manager) repoHooksTrusted(ctx context.Context) bool {
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return false
+	}
+	return state.HooksTrusted(state.UserDir(), commonDir, m.config().RepoHooksHash())
+}
+
+// postHook runs a post_ hook, only warning if it fails
+func (m *Manager) postHook(ctx context.Context, event string, w Env) {
+	if err := m.Hook(ctx, event, w); err != nil {
+		m.reporter().Warning(err.Error())
+	}
+}
+
+// forget drops the metadata and port block of a removed worktree and
+// refreshes the editor workspace
+func (m *Manager) forget(ctx context.Context, path string) {
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return
+	}
+	dir := state.Dir(commonDir)
+
+	if meta, err := state.LoadMeta(dir); err == nil && !meta.Get(path).IsEmpty() {
+		_, _ = state.UpdateMeta(dir, func(s *state.MetaStore) error {
+			s.Delete(path)
+			return nil
+		})
+	}
+	_ = state.ReleasePorts(dir, path)
+	m.refreshWorkspace(ctx)
+}
+
+// refreshWorkspace rewrites the workspace file generated for the
+// repository, if any, after worktrees were added or removed
+func (m *Manager) refreshWorkspace(ctx context.Context) {
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return
+	}
+	ws, err := state.LoadWorkspace(state.Dir(commonDir))
+	if err != nil || ws == nil {
+		return
+	}
+	// A deleted file means the user no longer wants it
+	if _, err := os.Stat(ws.Path); err != nil {
+		return
+	}
+	worktrees, err := git.ListWorktrees(ctx)
+	if err == nil {
+		err = workspace.Write(ws.Path, workspace.Folders(worktrees, filepath.Dir(ws.Path)))
+	}
+	if err != nil {
+		m.reporter().Warning("Could not refresh workspace: " + err.Error())
+	}
+}
+
+// composeDown takes down the compose project of the worktree at path
+// before it is removed. It does nothing without a compose file or docker,
+// and a failure only warns.
+func (m *Manager) composeDown(ctx context.Context, w Env) {
+	if env.ComposeFile(w.Path) == "" || !env.HasDocker() {
+		return
+	}
+	m.reporter().Progress("Stopping compose project " + w.ProjectName())
+	if err := env.ComposeDown(ctx, w); err != nil {
+		m.reporter().Warning("Could not stop compose project " + w.ProjectName() + ": " + err.Error())
+	}
+}
+
+// isBareLayout reports whether the repository is a bare clone whose
+// branches are all checked out as worktrees
+func isBareLayout(ctx context.Context) bool {
+	worktrees, err := git.ListWorktrees(ctx)
+	return err == nil && git.IsBareLayout(worktrees)
+}
+
+// match returns the worktree whose directory name or branch is name
+func match(worktrees []Worktree, name string) *Worktree {
+	for _, wt := range worktrees {
+		if filepath.Base(wt.Path) == name || wt.Branch == name {
+			return &wt
+		}
+	}
+	return nil
+}
+
+// annotate records issueURL as the issue of the worktree at path and note
+// as its note; empty values leave the metadata as it was
+func annotate(ctx context.Context, path, issueURL, note string) error {
+	commonDir, err := git.GetCommonDir(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = state.UpdateMeta(state.Dir(commonDir), func(s *state.MetaStore) error {
+		meta := s.Get(path)
+		if issueURL != "" {
+			meta.Issue = issueURL
+		}
+		if note != "" {
+			meta.Note = note
+		}
+		s.Set(path, meta, time.Now())
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,594 @@
+package worktree
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/lazywork/internal/git"
+	"github.com/miltonparedes/lazywork/internal/lazyerr"
+	"github.com/miltonparedes/lazywork/internal/state"
+	"github.com/miltonparedes/lazywork/pkg/config"
+)
+
+// newRepo creates a repository with one commit on main and changes into it
+func newRepo(t *testing.T) string {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@test.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@test.com")
+
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"commit", "--allow-empty", "-m", "Initial commit"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "info", "exclude"), []byte("/.worktrees/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// recorder is a Runner that runs git and records the commands
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) Run(ctx context.Context, args ...string) (string, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, strings.Join(args, " "))
+	r.mu.Unlock()
+	return git.ExecRunner{}.Run(ctx, args...)
+}
+
+func TestAddAndRemove(t *testing.T) {
+	dir := newRepo(t)
+	runner := &recorder{}
+	m := &Manager{
+		Config: &config.Config{Hooks: map[string][]string{
+			"post_add":    {"echo $LW_BRANCH $LW_PORT > ../added"},
+			"post_remove": {"touch removed"},
+		}},
+		Git: runner,
+	}
+
+	added, err := m.Add(t.Context(), AddOptions{Name: "feature"})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	want := filepath.Join(dir, ".worktrees", "feature")
+	if added.Path != want || added.Branch != "feature" {
+		t.Errorf("Add = %+v, want path %s on branch feature", added, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ".worktrees", "added")); err != nil || string(data) != "feature 3000\n" {
+		t.Errorf("post_add hook wrote %q (%v), want its branch and port", data, err)
+	}
+	if len(runner.calls) == 0 {
+		t.Error("Add did not run git through the injected runner")
+	}
+
+	if _, err := m.Add(t.Context(), AddOptions{Name: "feature"}); !hasCode(err, lazyerr.BranchExists) {
+		t.Errorf("adding an existing branch: err = %v, want %s", err, lazyerr.BranchExists)
+	}
+
+	removed, err := m.Remove(t.Context(), "feature", false)
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if removed.Path != want {
+		t.Errorf("Remove = %s, want %s", removed.Path, want)
+	}
+	if _, err := os.Stat(want); !os.IsNotExist(err) {
+		t.Errorf("worktree still exists after Remove: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "removed")); err != nil {
+		t.Errorf("post_remove hook did not run in the repository root: %v", err)
+	}
+}
+
+func TestAddFromStash(t *testing.T) {
+	dir := newRepo(t)
+	m := New(nil)
+	for _, name := range []string{"wip.txt", "kept.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := exec.Command("git", "stash", "push", "--include-untracked", "-m", "kept", "--", "kept.txt").CombinedOutput(); err != nil {
+		t.Fatalf("git stash: %v\n%s", err, out)
+	}
+
+	moved, err := m.Add(t.Context(), AddOptions{Name: "moved", MoveChanges: true})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if !moved.Moved {
+		t.Error("changes were not reported as moved")
+	}
+	if _, err := os.Stat(filepath.Join(moved.Path, "wip.txt")); err != nil {
+		t.Errorf("untracked change not moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wip.txt")); !os.IsNotExist(err) {
+		t.Errorf("change still in the main checkout: %v", err)
+	}
+
+	applied, err := m.Add(t.Context(), AddOptions{Name: "applied", Stash: "0"})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if applied.Stash != "stash@{0}" {
+		t.Errorf("Stash = %q, want stash@{0}", applied.Stash)
+	}
+	if _, err := os.Stat(filepath.Join(applied.Path, "kept.txt")); err != nil {
+		t.Errorf("stash not applied: %v", err)
+	}
+	if stashes, _ := git.ListStashes(t.Context()); len(stashes) != 1 {
+		t.Errorf("stash list = %v, want the applied stash kept and the moved one dropped", stashes)
+	}
+
+	if _, err := m.Add(t.Context(), AddOptions{Name: "missing", Stash: "5"}); !hasCode(err, lazyerr.StashNotFound) {
+		t.Errorf("unknown stash: err = %v, want %s", err, lazyerr.StashNotFound)
+	}
+}
+
+func TestPreHookAborts(t *testing.T) {
+	newRepo(t)
+	m := New(&config.Config{Hooks: map[string][]string{"pre_add": {"exit 1"}}})
+
+	if _, err := m.Add(t.Context(), AddOptions{Name: "feature"}); !hasCode(err, lazyerr.HookFailed) {
+		t.Fatalf("err = %v, want %s", err, lazyerr.HookFailed)
+	}
+	if git.BranchExists(t.Context(), "feature") {
+		t.Error("branch was created although pre_add failed")
+	}
+
+	m.NoHooks = true
+	if _, err := m.Add(t.Context(), AddOptions{Name: "feature"}); err != nil {
+		t.Errorf("Add with NoHooks failed: %v", err)
+	}
+}
+
+// warnings is a Reporter keeping the warnings
+type warnings []string
+
+func (w *warnings) Progress(string)              {}
+func (w *warnings) Spinner(string) (stop func()) { return func() {} }
+func (w *warnings) Success(string)               {}
+func (w *warnings) Warning(msg string)           { *w = append(*w, msg) }
+
+func TestAddPush(t *testing.T) {
+	newRepo(t)
+	remote := filepath.Join(t.TempDir(), "origin.git")
+	for _, args := range [][]string{
+		{"init", "--bare", "-b", "main", remote},
+		{"remote", "add", "origin", remote},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	var warned warnings
+	m := &Manager{Reporter: &warned}
+
+	added, err := m.Add(t.Context(), AddOptions{Name: "feature", Push: true})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if !added.Pushed || len(warned) > 0 {
+		t.Errorf("pushed = %v, warnings = %q, want pushed without warnings", added.Pushed, warned)
+	}
+	upstream, err := exec.Command("git", "-C", added.Path, "rev-parse", "--abbrev-ref", "feature@{upstream}").Output()
+	if err != nil || strings.TrimSpace(string(upstream)) != "origin/feature" {
+		t.Errorf("upstream = %q (%v), want origin/feature", upstream, err)
+	}
+
+	// A failed push only warns, as the worktree is ready
+	added, err = m.Add(t.Context(), AddOptions{Name: "offline", Push: true, Remote: "missing"})
+	if err != nil {
+		t.Fatalf("Add with a failing push failed: %v", err)
+	}
+	if added.Pushed || len(warned) != 1 || !strings.Contains(warned[0], "Could not push offline") {
+		t.Errorf("pushed = %v, warnings = %q, want one push warning", added.Pushed, warned)
+	}
+}
+
+func TestRepoHooksNeedTrust(t *testing.T) {
+	newRepo(t)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	cfg := &config.Config{
+		RepoConfigPath: ".lazywork.json",
+		RepoHooks:      map[string][]string{"pre_add": {"exit 1"}},
+	}
+	var warned warnings
+	m := &Manager{Config: cfg, Reporter: &warned}
+
+	// Untrusted hooks are skipped with a warning
+	if _, err := m.Add(t.Context(), AddOptions{Name: "untrusted"}); err != nil {
+		t.Fatalf("Add with untrusted hooks failed: %v", err)
+	}
+	if len(warned) != 1 || !strings.Contains(warned[0], "config trust") {
+		t.Errorf("warnings = %q, want one about 'config trust'", warned)
+	}
+
+	commonDir, err := git.GetCommonDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.TrustHooks(state.UserDir(), commonDir, cfg.RepoHooksHash()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Add(t.Context(), AddOptions{Name: "trusted"}); !hasCode(err, lazyerr.HookFailed) {
+		t.Fatalf("trusted hooks: err = %v, want %s", err, lazyerr.HookFailed)
+	}
+
+	// Changed hooks need trusting again
+	cfg.RepoHooks["pre_add"] = []string{"exit 2"}
+	if _, err := m.Add(t.Context(), AddOptions{Name: "changed"}); err != nil {
+		t.Errorf("Add with changed hooks failed: %v", err)
+	}
+}
+
+func TestUseAndReturn(t *testing.T) {
+	dir := newRepo(t)
+	m := New(nil)
+	if _, err := m.Add(t.Context(), AddOptions{Name: "feature"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "wip.txt"), []byte("wip\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "add", "wip.txt").CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, out)
+	}
+
+	if _, err := m.Use(t.Context(), UseOptions{Name: "feature"}); !hasCode(err, lazyerr.UncommittedChanges) {
+		t.Fatalf("Use with changes: err = %v, want %s", err, lazyerr.UncommittedChanges)
+	}
+
+	used, err := m.Use(t.Context(), UseOptions{
+		Name:         "feature",
+		ConfirmStash: func() (bool, error) { return true, nil },
+	})
+	if err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+	if used.Branch != "feature" || used.PreviousBranch != "main" || !used.Stashed || used.Depth != 1 {
+		t.Errorf("Use = %+v", used)
+	}
+	if branch, _ := git.CurrentBranch(t.Context()); branch != "feature" {
+		t.Errorf("current branch = %s, want feature", branch)
+	}
+
+	returned, err := m.Return(t.Context())
+	if err != nil {
+		t.Fatalf("Return failed: %v", err)
+	}
+	if returned.Branch != "main" || !returned.Restored || returned.Depth != 0 || returned.Next != "" {
+		t.Errorf("Return = %+v", returned)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wip.txt")); err != nil {
+		t.Errorf("stashed change not restored: %v", err)
+	}
+
+	if _, err := m.Return(t.Context()); !hasCode(err, lazyerr.NoState) {
+		t.Errorf("Return with an empty stack: err = %v, want %s", err, lazyerr.NoState)
+	}
+}
+
+func TestRepairUse(t *testing.T) {
+	dir := newRepo(t)
+	m := New(nil)
+	wip := filepath.Join(dir, "wip.txt")
+	stashWip := func() string {
+		t.Helper()
+		if err := os.WriteFile(wip, []byte("wip\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command("git", "add", "wip.txt").CombinedOutput(); err != nil {
+			t.Fatalf("git add: %v\n%s", err, out)
+		}
+		hash, err := git.Stash(t.Context(), useStashMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	for _, branch := range []string{"feature", "gone"} {
+		if out, err := exec.Command("git", "branch", branch).CombinedOutput(); err != nil {
+			t.Fatalf("git branch: %v\n%s", err, out)
+		}
+	}
+
+	// A use stopped after saving its frame, before the checkout, under a
+	// frame returning to a branch deleted since
+	stash := stashWip()
+	if err := git.SaveUseStack(t.Context(), []UseFrame{
+		{Branch: "gone", Target: "main"},
+		{Branch: "main", Target: "feature", Stash: stash, Created: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "branch", "-D", "gone").CombinedOutput(); err != nil {
+		t.Fatalf("git branch -D: %v\n%s", err, out)
+	}
+
+	result, err := m.RepairUse(t.Context())
+	if err != nil {
+		t.Fatalf("RepairUse failed: %v", err)
+	}
+	if len(result.Frames) != 0 || !result.Restored || len(result.Actions) != 3 {
+		t.Errorf("RepairUse = %+v", result)
+	}
+	if _, err := os.Stat(wip); err != nil {
+		t.Errorf("stashed change not restored: %v", err)
+	}
+	if result, err := m.RepairUse(t.Context()); err != nil || len(result.Actions) != 0 {
+		t.Errorf("RepairUse on a sound state = %+v, %v", result, err)
+	}
+
+	// Unreadable state after a use that switched branches
+	stashWip()
+	if out, err := exec.Command("git", "checkout", "-q", "feature").CombinedOutput(); err != nil {
+		t.Fatalf("git checkout: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "lazywork", "use-stack.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Return(t.Context()); !hasCode(err, lazyerr.StateReadError) {
+		t.Fatalf("Return with corrupt state: err = %v, want %s", err, lazyerr.StateReadError)
+	}
+	result, err = m.RepairUse(t.Context())
+	if err != nil {
+		t.Fatalf("RepairUse failed: %v", err)
+	}
+	if len(result.Frames) != 1 || result.Frames[0].Branch != "main" || result.Frames[0].Target != "feature" {
+		t.Fatalf("RepairUse = %+v", result)
+	}
+	if returned, err := m.Return(t.Context()); err != nil || !returned.Restored {
+		t.Errorf("Return after the repair = %+v, %v", returned, err)
+	}
+}
+
+func TestFinish(t *testing.T) {
+	dir := newRepo(t)
+	m := New(nil)
+	added, err := m.Add(t.Context(), AddOptions{Name: "feature"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Diverge so the merge is not a fast-forward
+	for _, path := range []string{added.Path, dir} {
+		if out, err := exec.Command("git", "-C", path, "commit", "--allow-empty", "-m", "Change "+filepath.Base(path)).CombinedOutput(); err != nil {
+			t.Fatalf("git commit: %v\n%s", err, out)
+		}
+	}
+
+	base := m.MainBranch(t.Context())
+	target, err := m.FinishTarget(t.Context(), "feature", base)
+	if err != nil {
+		t.Fatalf("FinishTarget failed: %v", err)
+	}
+	if err := m.CheckMergeable(t.Context(), base); err != nil {
+		t.Fatalf("CheckMergeable failed: %v", err)
+	}
+
+	result, err := m.Finish(t.Context(), *target, base, FinishOptions{
+		Message: func(ctx context.Context, branch, base string) string { return "Merge " + branch },
+		Cleanup: func(Worktree) (bool, error) { return true, nil },
+	})
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	if result.Base != "main" || result.Message != "Merge feature" || !result.Cleanup {
+		t.Errorf("Finish = %+v", result)
+	}
+
+	out, err := exec.Command("git", "-C", dir, "log", "-1", "--format=%s").Output()
+	if err != nil || strings.TrimSpace(string(out)) != "Merge feature" {
+		t.Errorf("last commit = %q (%v), want the merge", out, err)
+	}
+	if git.BranchExists(t.Context(), "feature") {
+		t.Error("branch still exists after cleanup")
+	}
+	if _, err := os.Stat(added.Path); !os.IsNotExist(err) {
+		t.Errorf("worktree still exists after cleanup: %v", err)
+	}
+}
+
+func TestFinishRollback(t *testing.T) {
+	dir := newRepo(t)
+	m := New(nil)
+	added, err := m.Add(t.Context(), AddOptions{Name: "feature"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(added.Path, "feature.txt"), []byte("feature\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"add", "feature.txt"}, {"commit", "-m", "Add feature"}} {
+		if out, err := exec.Command("git", append([]string{"-C", added.Path}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	head := func() string {
+		out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	before := head()
+	target, err := m.FinishTarget(t.Context(), "feature", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := FinishOptions{Squash: true, Push: true}
+	plan := m.FinishPlan(*target, "main", opts, true)
+	var names []string
+	for _, s := range plan {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, " "); got != "check squash push cleanup" {
+		t.Errorf("FinishPlan = %s", got)
+	}
+
+	// There is no origin, so the push fails and the squash is undone
+	_, err = m.Finish(t.Context(), *target, "main", opts)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != lazyerr.PushError {
+		t.Fatalf("err = %v, want %s", err, lazyerr.PushError)
+	}
+	if e.Details["step"] != StepPush || strings.Join(e.Details["undone"].([]string), " ") != StepSquash {
+		t.Errorf("details = %v, want the squash undone", e.Details)
+	}
+	if got := head(); got != before {
+		t.Errorf("HEAD = %s after the rollback, want %s", got, before)
+	}
+
+	result, err := m.Finish(t.Context(), *target, "main", FinishOptions{
+		Squash:  true,
+		Cleanup: func(Worktree) (bool, error) { return true, nil },
+	})
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	if !result.Squash || !result.Cleanup || head() == before {
+		t.Errorf("Finish = %+v", result)
+	}
+	if git.BranchExists(t.Context(), "feature") {
+		t.Error("squashed branch still exists after cleanup")
+	}
+}
+
+func TestFinishMerged(t *testing.T) {
+	dir := newRepo(t)
+	m := New(nil)
+	worktrees := map[string]*AddResult{}
+	for _, name := range []string{"merged", "dirty", "fresh"} {
+		added, err := m.Add(t.Context(), AddOptions{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		worktrees[name] = added
+	}
+	for _, name := range []string{"merged", "dirty"} {
+		if out, err := exec.Command("git", "-C", worktrees[name].Path, "commit", "--allow-empty", "-m", "Work on "+name).CombinedOutput(); err != nil {
+			t.Fatalf("git commit: %v\n%s", err, out)
+		}
+		if out, err := exec.Command("git", "-C", dir, "merge", "--no-ff", "-m", "Merge "+name, name).CombinedOutput(); err != nil {
+			t.Fatalf("git merge: %v\n%s", err, out)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(worktrees["dirty"].Path, "wip.txt"), []byte("wip\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates, err := m.FinishCandidates(t.Context(), "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var merged []Worktree
+	for _, wt := range candidates {
+		if m.Merged(t.Context(), wt, "main") {
+			merged = append(merged, wt)
+		}
+	}
+	if len(merged) != 2 {
+		t.Fatalf("merged = %v, want merged and dirty but not the fresh worktree", merged)
+	}
+
+	for _, wt := range merged {
+		_, err := m.FinishMerged(t.Context(), wt, "main")
+		switch wt.Branch {
+		case "dirty":
+			if !hasCode(err, lazyerr.UncommittedChanges) {
+				t.Errorf("dirty worktree: err = %v, want %s", err, lazyerr.UncommittedChanges)
+			}
+		default:
+			if err != nil {
+				t.Errorf("FinishMerged(%s) failed: %v", wt.Branch, err)
+			}
+		}
+	}
+	if git.BranchExists(t.Context(), "merged") || !git.BranchExists(t.Context(), "dirty") {
+		t.Error("want only the clean merged branch deleted")
+	}
+}
+
+func TestUndoFinish(t *testing.T) {
+	dir := newRepo(t)
+	m := New(nil)
+	added, err := m.Add(t.Context(), AddOptions{Name: "feature"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", added.Path, "commit", "--allow-empty", "-m", "Add feature").CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v\n%s", err, out)
+	}
+	head := func(rev string) string {
+		out, err := exec.Command("git", "-C", dir, "rev-parse", rev).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	before, tip := head("main"), head("feature")
+
+	target, err := m.FinishTarget(t.Context(), "feature", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Finish(t.Context(), *target, "main", FinishOptions{
+		Cleanup: func(Worktree) (bool, error) { return true, nil },
+	}); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	// The deleted branch's commit is kept from gc
+	if got := head(undoRef(1, 0)); got != tip {
+		t.Errorf("undo ref = %s, want %s", got, tip)
+	}
+
+	result, err := m.Undo(t.Context(), 0)
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if result.Reset != before || len(result.Branches) != 1 || len(result.Worktrees) != 1 || len(result.Skipped) != 0 {
+		t.Errorf("Undo = %+v", result)
+	}
+	if head("main") != before || head("feature") != tip {
+		t.Error("main and feature are not back where they were")
+	}
+	if wt, err := m.Find(t.Context(), "feature"); err != nil || wt.Branch != "feature" {
+		t.Errorf("worktree not recreated: %v", err)
+	}
+
+	if _, err := m.Undo(t.Context(), 0); !hasCode(err, lazyerr.NothingToUndo) {
+		t.Errorf("second Undo: err = %v, want %s", err, lazyerr.NothingToUndo)
+	}
+	ops, err := m.Journal(t.Context())
+	if err != nil || len(ops) != 1 || !ops[0].Undone {
+		t.Errorf("Journal = %+v, %v", ops, err)
+	}
+}
+
+func hasCode(err error, code lazyerr.Code) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
+}